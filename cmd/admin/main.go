@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/armsnyder/othelgo/pkg/server"
+)
+
+const usage = `Usage: admin [flags] <command>
+
+Commands:
+  maintenance on [message]  Freeze gameplay and show players a maintenance notice.
+  maintenance off           Resume gameplay.
+  calibrate-ai              Adjust the AI difficulties to match how often humans beat them.
+  run-data-jobs             Export or delete the data of players who asked for it.
+  disputes                  List the disputed game results that are waiting for review.
+  resolve-dispute <id> <outcome>
+                            Settle a dispute. The outcome is uphold, void, draw, or the
+                            nickname of the player to give the game to.
+  register-bot <nickname>   Let a bot join the bot ladder.
+  unregister-bot <nickname> Take a bot off the bot ladder.
+  run-bot-ladder            Start a game between each pair of bots waiting on the ladder.
+  run-heat-maps             Count where each player plays and loses disks in the archive.
+  run-webhooks              Post the events of tournaments to their webhooks.
+
+Flags:
+`
+
+// admin performs administrative actions by writing directly to the server's DynamoDB table, so it
+// requires AWS credentials with access to the table. It asks for no second factor of its own; that
+// is left to the IAM policy of the credentials, as the README explains.
+func main() {
+	tableName := flag.String("table", "Othelgo", "Name of the DynamoDB table.")
+	endpoint := flag.String("dynamodb-endpoint", "", "DynamoDB endpoint. Leave empty to use AWS.")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := server.Args{
+		DB:        server.NewDB(*endpoint),
+		TableName: *tableName,
+	}
+
+	if err := run(context.Background(), args, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, args server.Args, command []string) error {
+	if len(command) >= 2 && command[0] == "maintenance" {
+		switch command[1] {
+		case "on":
+			return server.SetMaintenance(ctx, args, true, strings.Join(command[2:], " "))
+		case "off":
+			return server.SetMaintenance(ctx, args, false, "")
+		}
+	}
+
+	if len(command) == 1 && command[0] == "calibrate-ai" {
+		calibrations, err := server.CalibrateAI(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range calibrations {
+			fmt.Println(c)
+		}
+
+		return nil
+	}
+
+	if len(command) == 1 && command[0] == "run-data-jobs" {
+		results, err := server.RunDataJobs(ctx, args)
+		for _, r := range results {
+			fmt.Println(r)
+		}
+
+		return err
+	}
+
+	if len(command) == 1 && command[0] == "disputes" {
+		disputes, err := server.ListDisputes(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range disputes {
+			fmt.Println(d)
+		}
+
+		return nil
+	}
+
+	if len(command) == 3 && command[0] == "resolve-dispute" {
+		return server.ResolveDispute(ctx, args, command[1], command[2])
+	}
+
+	if len(command) == 2 && command[0] == "register-bot" {
+		return server.RegisterBot(ctx, args, strings.ToLower(command[1]))
+	}
+
+	if len(command) == 2 && command[0] == "unregister-bot" {
+		return server.UnregisterBot(ctx, args, strings.ToLower(command[1]))
+	}
+
+	if len(command) == 1 && command[0] == "run-bot-ladder" {
+		games, err := server.RunBotLadder(ctx, args)
+		for _, g := range games {
+			fmt.Println(g)
+		}
+
+		return err
+	}
+
+	if len(command) == 1 && command[0] == "run-heat-maps" {
+		result, err := server.RunHeatMaps(ctx, args)
+		fmt.Println(result)
+
+		return err
+	}
+
+	if len(command) == 1 && command[0] == "run-webhooks" {
+		posted, err := server.RunWebhooks(ctx, args)
+		fmt.Printf("Posted %d events\n", posted)
+
+		return err
+	}
+
+	flag.Usage()
+	os.Exit(2)
+
+	return nil
+}
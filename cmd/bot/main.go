@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/armsnyder/othelgo/pkg/client"
+)
+
+// version is set at build time using ldflags.
+var version = "0.0.0"
+
+func main() {
+	local := flag.Bool("local", false, "If true, connect to a local server.")
+	server := flag.String("server", "", "Websocket address of a standalone server to connect to, e.g. ws://192.168.1.5:9000.")
+	nickname := flag.String("nickname", "", "Nickname of the bot.")
+	token := flag.String("token", "", "Token of the bot's nickname, if it is claimed.")
+	host := flag.String("host", "", "Nickname of a host whose games the bot joins. If empty, the bot hosts its own games.")
+	games := flag.Int("games", 1, "Number of games to play. Ignored with -ladder.")
+	ladder := flag.Bool("ladder", false, "If true, wait on the bot ladder for games until stopped. The nickname must be claimed and registered for the ladder.")
+	boardSize := flag.Int("size", 0, "Board size of the games the bot hosts.")
+	variant := flag.String("variant", "", "Variant of the games the bot hosts, classic or anti.")
+	depth := flag.Int("depth", 4, "How many turns ahead the built-in AI looks, if no engine command is given.")
+	flag.Usage = func() {
+		log.Printf("Usage: %s [flags] [engine command...]\n\nWithout an engine command, the bot plays with the built-in AI.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *nickname == "" {
+		log.Fatal("-nickname is required")
+	}
+
+	opts := client.Options{
+		Addr:    *server,
+		Local:   *local,
+		Version: version,
+	}
+
+	botOpts := client.BotOptions{
+		Nickname:  strings.ToLower(*nickname),
+		Token:     *token,
+		Host:      strings.ToLower(*host),
+		Games:     *games,
+		Ladder:    *ladder,
+		BoardSize: *boardSize,
+		Variant:   *variant,
+	}
+
+	if flag.NArg() == 0 {
+		if err := client.RunBot(opts, botOpts, &client.AIEngine{Depth: *depth}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := runWithEngine(opts, botOpts, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runWithEngine starts an engine program and plays with it over its standard input and output.
+func runWithEngine(opts client.Options, botOpts client.BotOptions, command []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	err = client.RunBot(opts, botOpts, client.NewTextEngine(stdout, stdin))
+
+	// Closing the engine's input tells it that there are no more games.
+	_ = stdin.Close()
+	if waitErr := cmd.Wait(); err == nil {
+		err = waitErr
+	}
+
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/client/scenes"
+	"github.com/armsnyder/othelgo/pkg/lan"
+	"github.com/armsnyder/othelgo/pkg/server"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+// lanDiscoverTimeout is how long -lan waits for servers on the local network to answer.
+const lanDiscoverTimeout = 2 * time.Second
+
+// hostLAN runs a standalone server for a LAN game in the background and advertises it on the local
+// network, until ctx is done. It returns the address that the hosting client connects to. The
+// server keeps its table in memory, so the game is gone when the host quits.
+func hostLAN(ctx context.Context, port int) (string, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return "", err
+	}
+
+	name, _ := scenes.LoadCredentials()
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	// The server shares the terminal with the game, so its request logs and metrics are dropped.
+	args := server.Args{
+		DB:         memdb.New(),
+		TableName:  "Othelgo",
+		Metrics:    server.NopMetrics{},
+		RequestLog: io.Discard,
+	}
+
+	go func() {
+		// A LAN game has no use for data jobs, the bot ladder, heat maps, or webhooks.
+		if err := server.Serve(ctx, ln, args, server.ServeOptions{NoBackgroundJobs: true}); err != nil {
+			log.Printf("LAN server stopped: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := lan.Advertise(ctx, name, port); err != nil {
+			log.Printf("Stopped advertising the LAN server: %v", err)
+		}
+	}()
+
+	return fmt.Sprintf("ws://127.0.0.1:%d", port), nil
+}
+
+// findLAN returns the address of a server on the local network. If there are several, the first
+// by name is used.
+func findLAN(ctx context.Context) (string, error) {
+	fmt.Println("Looking for games on the local network...")
+
+	servers, err := lan.Discover(ctx, lanDiscoverTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	if len(servers) == 0 {
+		return "", fmt.Errorf("no games found on the local network; is the host running with -host-lan?")
+	}
+
+	for _, s := range servers[1:] {
+		log.Printf("Also found %s at %s", s.Name, s.Addr)
+	}
+
+	fmt.Printf("Joining %s at %s\n", servers[0].Name, servers[0].Addr)
+
+	return servers[0].Addr, nil
+}
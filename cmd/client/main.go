@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/armsnyder/othelgo/pkg/client"
 )
@@ -13,7 +17,17 @@ var version = "0.0.0"
 
 func main() {
 	local := flag.Bool("local", false, "If true, connect to a local server.")
+	serverAddr := flag.String("server", "", "Websocket address of a standalone server to connect to, e.g. ws://192.168.1.5:9000.")
+	discordAppID := flag.String("discord-app-id", "", "Discord application ID. If set, your Discord profile shows what you are playing, and friends can join or watch your game from it.")
+	lite := flag.Bool("lite", false, "If true, use as little bandwidth as possible, for slow connections.")
+	compact := flag.Bool("compact", false, "If true, always draw the board with one character per square, such as for a tmux pane.")
 	printVersion := flag.Bool("version", false, "Print the client version.")
+	exportReplay := flag.String("export-replay", "", "Nickname of a host whose latest finished game is saved as an asciinema cast, or as an animated GIF if -o ends in .gif, instead of playing.")
+	script := flag.String("script", "", "Script of keys and server messages to run the client with, instead of a terminal and a server.")
+	output := flag.String("o", "", "Output file for -export-replay, which defaults to <nickname>.cast, or for the cast of a -script run.")
+	hostLANGame := flag.Bool("host-lan", false, "If true, host a server that players on the local network can join with -lan, with no internet.")
+	joinLANGame := flag.Bool("lan", false, "If true, join a server on the local network that is hosted with -host-lan.")
+	lanPort := flag.Int("lan-port", 9000, "Port of the server hosted with -host-lan.")
 	flag.Parse()
 
 	if *printVersion {
@@ -21,7 +35,62 @@ func main() {
 		return
 	}
 
-	if err := client.Run(*local, version); err != nil {
+	// The LAN server runs until the client exits.
+	ctx := context.Background()
+
+	switch {
+	case *hostLANGame:
+		addr, err := hostLAN(ctx, *lanPort)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*serverAddr = addr
+	case *joinLANGame:
+		addr, err := findLAN(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*serverAddr = addr
+	}
+
+	opts := client.Options{
+		Addr:         *serverAddr,
+		Local:        *local,
+		Version:      version,
+		DiscordAppID: *discordAppID,
+		Lite:         *lite,
+		Compact:      *compact,
+	}
+
+	if *exportReplay != "" {
+		path := *output
+		if path == "" {
+			path = *exportReplay + ".cast"
+		}
+		if err := client.ExportReplay(opts, strings.ToLower(*exportReplay), path); err != nil {
+			log.Fatal(err)
+		}
+		if strings.EqualFold(filepath.Ext(path), ".gif") {
+			fmt.Printf("Saved %s.\n", path)
+			return
+		}
+		fmt.Printf("Saved %s. Play it with: asciinema play %s\n", path, path)
+		return
+	}
+
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := client.RunScript(opts, f, *output); err != nil {
+			log.Fatal(err) //nolint:gocritic
+		}
+		return
+	}
+
+	if err := client.Run(opts); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/armsnyder/othelgo/pkg/server"
+)
+
+// jobs is the Lambda function that EventBridge rules invoke to run the server's scheduled jobs,
+// such as the nightly bot ladder.
+func main() {
+	lambda.Start(server.DefaultScheduledHandler)
+}
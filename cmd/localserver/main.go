@@ -2,38 +2,53 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"net/http"
-	"time"
-
-	"github.com/aws/aws-lambda-go/events"
+	"os"
+	"os/signal"
 
+	"github.com/armsnyder/othelgo/pkg/messages"
 	"github.com/armsnyder/othelgo/pkg/server"
-	"github.com/armsnyder/othelgo/pkg/server/gatewayadapter"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
 )
 
+// localserver runs the othelgo server as a standalone websocket server, which is useful for local
+// development and for playing over a LAN without deploying to AWS. By default it keeps its table in
+// memory, so it needs nothing else running.
 func main() {
-	var adapter gatewayadapter.GatewayAdapter
+	addr := flag.String("addr", ":9000", "Address to listen on.")
+	memory := flag.Bool("memory", true, "Keep the table in memory, so that no DynamoDB is needed. Games are lost when the server stops.")
+	tableName := flag.String("table", "Othelgo", "Name of the DynamoDB table.")
+	endpoint := flag.String("dynamodb-endpoint", server.LocalDBEndpoint, "DynamoDB endpoint, if the table isn't in memory. Set to an empty string to use AWS.")
+	aiTimeBudget := flag.Duration("ai-time-budget", 0, "How long the AI may think about each move. Zero means the server default.")
+	var branding messages.Branding
+	flag.StringVar(&branding.Name, "name", "", "Name of the server, which is shown on the main menu of clients.")
+	flag.StringVar(&branding.MOTD, "motd", "", "Message of the day, which is shown on the main menu of clients.")
+	flag.StringVar(&branding.AccentColor, "accent-color", "", "Color of the server name, such as cyan.")
+	flag.Parse()
 
 	args := server.Args{
-		DB:        server.LocalDB(),
-		TableName: "Othelgo",
-		APIGatewayManagementAPIClientFactory: func(_ events.APIGatewayWebsocketProxyRequestContext) server.APIGatewayManagementAPIClient {
-			return &adapter
-		},
+		DB:           server.NewDB(*endpoint),
+		TableName:    *tableName,
+		AITimeBudget: *aiTimeBudget,
+		Branding:     branding,
 	}
 
-	adapter.LambdaHandler = func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
-		return server.Handle(ctx, req, args)
+	if *memory {
+		args.DB = memdb.New()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	if err := server.EnsureTable(ctx, args.DB, args.TableName); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	if err := server.ListenAndServe(ctx, *addr, args); err != nil {
 		log.Fatal(err)
 	}
-	cancel()
-
-	addr := ":9000"
-	log.Print("Listening on ", addr)
-	log.Fatal(http.ListenAndServe(addr, &adapter))
 }
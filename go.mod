@@ -1,6 +1,6 @@
 module github.com/armsnyder/othelgo
 
-go 1.15
+go 1.16
 
 require (
 	github.com/aws/aws-lambda-go v1.19.1
@@ -14,4 +14,5 @@ require (
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/perf v0.0.0-20200918155509-d949658356f9
 	golang.org/x/sync v0.0.0-20201008141435-b3e1573b7520
+	gopkg.in/yaml.v2 v2.3.0
 )
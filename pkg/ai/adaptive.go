@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Adaptive play. Instead of a difficulty, an adaptive AI has a strength, which is a rung on a
+// ladder of levels from the weakest, which plays half of its moves at random, to the strongest,
+// which looks as far ahead as the hard difficulty. During a game the AI plays a rung or two
+// weaker while it is winning by a lot, and stronger while it is losing by a lot, to keep the game
+// close.
+
+const (
+	// MaxStrength is the strongest rung of the adaptive ladder. The weakest is 0.
+	MaxStrength = 10
+	// DefaultStrength is the strength of a player with no adaptive games, which plays like the
+	// normal difficulty.
+	DefaultStrength = 8
+
+	// randomRungs is how many rungs at the bottom of the ladder play random moves.
+	randomRungs = 5
+	// maxLeadRungs is how many rungs the AI may move away from its strength during a game.
+	maxLeadRungs = 2
+)
+
+// StrengthLevel returns the level of a rung of the adaptive ladder. The bottom rungs look one turn
+// ahead and play fewer random moves as they go up, and the rungs above them look further ahead.
+func StrengthLevel(strength int) Level {
+	strength = clampStrength(strength)
+
+	if strength < randomRungs {
+		return Level{Depth: 1, Randomness: float64(randomRungs-strength) / 10}
+	}
+
+	return Level{Depth: strength - randomRungs + 1}
+}
+
+// Adapt returns the level that an adaptive AI of a strength plays its next move at, as player 2 in
+// a game of a variant. For every row's worth of disks that the AI is ahead by, it plays a rung
+// weaker, and for every row's worth that it is behind by, a rung stronger.
+func Adapt(strength int, board common.Board, variant string) Level {
+	p1, p2 := common.KeepScore(board)
+	lead := p2 - p1
+	if variant == common.VariantAnti {
+		lead = -lead
+	}
+
+	rungs := lead / board.Size
+	if rungs > maxLeadRungs {
+		rungs = maxLeadRungs
+	} else if rungs < -maxLeadRungs {
+		rungs = -maxLeadRungs
+	}
+
+	return StrengthLevel(strength - rungs)
+}
+
+func clampStrength(strength int) int {
+	if strength < 0 {
+		return 0
+	}
+	if strength > MaxStrength {
+		return MaxStrength
+	}
+	return strength
+}
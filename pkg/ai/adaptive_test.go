@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestStrengthLevel(t *testing.T) {
+	tests := []struct {
+		strength int
+		want     Level
+	}{
+		{-1, Level{Depth: 1, Randomness: 0.5}},
+		{0, Level{Depth: 1, Randomness: 0.5}},
+		{4, Level{Depth: 1, Randomness: 0.1}},
+		{5, Level{Depth: 1}},
+		{DefaultStrength, DefaultLevels[1]},
+		{MaxStrength, DefaultLevels[2]},
+		{MaxStrength + 1, DefaultLevels[2]},
+	}
+
+	for _, tt := range tests {
+		if got := StrengthLevel(tt.strength); got != tt.want {
+			t.Errorf("StrengthLevel(%d) = %+v, want %+v", tt.strength, got, tt.want)
+		}
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	// Fill the top rows with the leader's disks.
+	leading := func(player common.Disk, rows int) common.Board {
+		board := common.NewBoard(common.DefaultBoardSize)
+		for x := 0; x < board.Size; x++ {
+			for y := 0; y < rows; y++ {
+				board.Squares[x][y] = player
+			}
+		}
+		return board
+	}
+
+	tests := []struct {
+		name    string
+		board   common.Board
+		variant string
+		want    Level
+	}{
+		{"even", common.NewBoard(common.DefaultBoardSize), "", StrengthLevel(DefaultStrength)},
+		{"AI ahead", leading(common.Player2, 1), "", StrengthLevel(DefaultStrength - 1)},
+		{"AI far ahead", leading(common.Player2, 5), "", StrengthLevel(DefaultStrength - 2)},
+		{"AI behind", leading(common.Player1, 2), "", StrengthLevel(DefaultStrength + 2)},
+		{"AI ahead in anti-reversi", leading(common.Player1, 1), common.VariantAnti, StrengthLevel(DefaultStrength - 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Adapt(DefaultStrength, tt.board, tt.variant); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
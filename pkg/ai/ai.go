@@ -0,0 +1,232 @@
+// Package ai is the Othelgo AI player. It is used by the server for solo games, and by the client
+// for solo games played offline.
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Level configures how well the AI plays.
+type Level struct {
+	// Depth is how many turns ahead the AI looks.
+	Depth int
+	// Randomness is the chance that the AI plays a random move instead of its best move.
+	Randomness float64
+}
+
+// DefaultLevels are the levels of the AI difficulties before any calibration, indexed by
+// difficulty.
+var DefaultLevels = []Level{
+	{Depth: 1},
+	{Depth: 4},
+	{Depth: 6},
+}
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Move takes a turn as player 2 in a game of a variant, playing at the specified level. It returns
+// the board after the move, and the square that was played. The AI looks fewer turns ahead if it
+// would otherwise take longer than the time budget to move. A budget of 0 means there is no time
+// limit.
+func Move(board common.Board, variant string, level Level, budget time.Duration) (common.Board, [2]int) {
+	state := &gameState{
+		board:            board,
+		variant:          variant,
+		maximizingPlayer: 2,
+		turn:             2,
+	}
+
+	var move int
+	if level.Randomness > 0 && rand.Float64() < level.Randomness {
+		move = rand.Intn(state.MoveCount())
+	} else {
+		move, _ = findMoveUsingMinimax(state, level.Depth, budget)
+	}
+
+	return state.moves[move], state.moveLocations[move]
+}
+
+// Analysis is the AI's opinion of a position.
+type Analysis struct {
+	// Score is how favorable the position is for player 1. It is positive when player 1 is ahead,
+	// and infinite when the game is over or the AI can see the end of it.
+	Score float64
+	// Best is the best move for the player whose turn it is, if they have a move.
+	Best    [2]int
+	HasMove bool
+}
+
+// Analyze evaluates a position of a variant where it is the specified player's turn, looking depth
+// turns ahead.
+func Analyze(board common.Board, variant string, player common.Disk, depth int) Analysis {
+	state := &gameState{
+		board:            board,
+		variant:          variant,
+		maximizingPlayer: player,
+		turn:             player,
+	}
+
+	var analysis Analysis
+
+	if state.MoveCount() == 0 {
+		analysis.Score = state.Score()
+	} else {
+		move, score := findMoveUsingMinimax(state, depth, 0)
+		analysis.Score = score
+		analysis.Best = state.moveLocations[move]
+		analysis.HasMove = true
+	}
+
+	if player == common.Player2 {
+		analysis.Score = -analysis.Score
+	}
+
+	return analysis
+}
+
+// gameState implements the othelgo domain-specific logic needed by the AI.
+type gameState struct {
+	board            common.Board
+	variant          string
+	turn             common.Disk
+	maximizingPlayer common.Disk
+	moves            []common.Board
+	moveLocations    [][2]int
+}
+
+func (a *gameState) Hash() uint64 {
+	return zobristHash(a.board, a.turn)
+}
+
+func (a *gameState) Score() float64 {
+	// Anti-reversi is won by having fewer disks, and the squares that are valuable in the classic
+	// game are the ones to avoid, so its score is the classic score turned around.
+	if a.variant == common.VariantAnti {
+		return -a.classicScore()
+	}
+
+	return a.classicScore()
+}
+
+func (a *gameState) classicScore() float64 {
+	// p2 is the maximizing player and p1 is their opponent.
+	p1, p2 := common.KeepScore(a.board)
+	opponent := common.Disk(1)
+
+	if a.maximizingPlayer == 1 {
+		p1, p2 = p2, p1
+		opponent = 2
+	}
+
+	if common.GameOver(a.board) {
+		switch {
+		case p2 > p1:
+			return math.Inf(1)
+		case p2 < p1:
+			return math.Inf(-1)
+		default:
+			return 0
+		}
+	}
+
+	trueScoreDelta := float64(p2 - p1)
+	scoreModifier := a.scoreModifier(a.maximizingPlayer) - a.scoreModifier(opponent)
+
+	// Modifier strength decreases as the board fills up.
+	scoreModifier *= a.percentFull()
+
+	return trueScoreDelta + scoreModifier
+}
+
+func (a *gameState) scoreModifier(player common.Disk) (score float64) {
+	endIndex := a.board.Size - 1
+
+	// Edges are valuable.
+	edgeScore := 0.5
+	for i := 1; i < endIndex; i++ {
+		if a.board.Squares[i][0] == player {
+			score += edgeScore
+		}
+		if a.board.Squares[0][i] == player {
+			score += edgeScore
+		}
+		if a.board.Squares[i][endIndex] == player {
+			score += edgeScore
+		}
+		if a.board.Squares[endIndex][i] == player {
+			score += edgeScore
+		}
+	}
+
+	// Corners are highly valuable.
+	cornerScore := float64(2)
+	if a.board.Squares[0][0] == player {
+		score += cornerScore
+	}
+	if a.board.Squares[0][endIndex] == player {
+		score += cornerScore
+	}
+	if a.board.Squares[endIndex][0] == player {
+		score += cornerScore
+	}
+	if a.board.Squares[endIndex][endIndex] == player {
+		score += cornerScore
+	}
+
+	return score
+}
+
+func (a *gameState) percentFull() float64 {
+	freeCells := 0
+	for x := 0; x < a.board.Size; x++ {
+		for y := 0; y < a.board.Size; y++ {
+			if a.board.Squares[x][y] == 0 {
+				freeCells++
+			}
+		}
+	}
+	return float64(freeCells) / float64(a.board.Size*a.board.Size)
+}
+
+func (a *gameState) AITurn() bool {
+	return a.turn == a.maximizingPlayer
+}
+
+func (a *gameState) MoveCount() int {
+	if a.moves == nil {
+		a.moves = []common.Board{}
+		for x := 0; x < a.board.Size; x++ {
+			for y := 0; y < a.board.Size; y++ {
+				if board, updated := common.ApplyMove(a.board, x, y, a.turn); updated {
+					a.moves = append(a.moves, board)
+					a.moveLocations = append(a.moveLocations, [2]int{x, y})
+				}
+			}
+		}
+	}
+
+	return len(a.moves)
+}
+
+func (a *gameState) Move(i int) State {
+	a.MoveCount() // Lazy initialize moves
+
+	nextState := &gameState{
+		board:            a.moves[i],
+		variant:          a.variant,
+		turn:             a.turn,
+		maximizingPlayer: a.maximizingPlayer,
+	}
+
+	if common.HasMoves(a.moves[i], a.turn%2+1) {
+		nextState.turn = a.turn%2 + 1
+	}
+
+	return nextState
+}
@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func BenchmarkMiniMax(b *testing.B) {
+	for _, depth := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			// Enable memory allocation stats for this benchmark test.
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				var state gameState
+
+				state.board = common.NewBoard(common.DefaultBoardSize)
+
+				// Player 1 made the first move.
+				state.board.Squares[2][4] = 1
+
+				// Now it's player 2's turn (the AI player).
+				state.turn = 2
+
+				// Do the thing being benchmarked.
+				s := &search{table: make(map[uint64]tableEntry)}
+				s.minimax(&state, depth, math.Inf(-1), math.Inf(1))
+			}
+		})
+	}
+}
+
+func TestAnalyzeFindsCorner(t *testing.T) {
+	board := common.NewBoard(common.DefaultBoardSize)
+	board.Squares[1][1] = 2
+	board.Squares[2][2] = 1
+
+	analysis := Analyze(board, common.VariantClassic, 1, 1)
+
+	if !analysis.HasMove || analysis.Best != [2]int{0, 0} {
+		t.Errorf("expected best move to be the corner, got %v", analysis.Best)
+	}
+}
+
+func TestAnalyzeGameOver(t *testing.T) {
+	var board common.Board
+	board.Size = common.MinBoardSize
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			board.Squares[x][y] = 2
+		}
+	}
+	board.Squares[0][0] = 1
+
+	for _, player := range []common.Disk{1, 2} {
+		analysis := Analyze(board, common.VariantClassic, player, 2)
+
+		if analysis.HasMove || !math.IsInf(analysis.Score, -1) {
+			t.Errorf("player %d: expected player 2 to have won, got %+v", player, analysis)
+		}
+	}
+}
+
+func TestAnalyzeAntiGameOver(t *testing.T) {
+	var board common.Board
+	board.Size = common.MinBoardSize
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			board.Squares[x][y] = 2
+		}
+	}
+	board.Squares[0][0] = 1
+
+	analysis := Analyze(board, common.VariantAnti, 1, 2)
+
+	if !math.IsInf(analysis.Score, 1) {
+		t.Errorf("expected player 1 to have won with fewer disks, got %+v", analysis)
+	}
+}
+
+func TestAnalyzeAntiAvoidsCorner(t *testing.T) {
+	board := common.NewBoard(common.DefaultBoardSize)
+	board.Squares[1][1] = 2
+	board.Squares[2][2] = 1
+
+	analysis := Analyze(board, common.VariantAnti, 1, 1)
+
+	if !analysis.HasMove || analysis.Best == [2]int{0, 0} {
+		t.Errorf("expected best move not to be the corner, got %v", analysis.Best)
+	}
+}
+
+func TestMoveWithinTimeBudget(t *testing.T) {
+	board := common.NewBoard(common.MaxBoardSize)
+	board.Squares[3][5] = 1
+	board.Squares[4][5] = 1
+
+	start := time.Now()
+	_, move := Move(board, common.VariantClassic, Level{Depth: 20}, 100*time.Millisecond)
+
+	// Allow for the time it takes to notice that the budget has run out.
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected move within the time budget, took %v", elapsed)
+	}
+
+	if _, updated := common.ApplyMove(board, move[0], move[1], 2); !updated {
+		t.Errorf("expected a legal move, got %v", move)
+	}
+}
+
+func TestZobristHash(t *testing.T) {
+	board := common.NewBoard(common.DefaultBoardSize)
+
+	if zobristHash(board, 1) == zobristHash(board, 2) {
+		t.Error("expected whose turn it is to change the hash")
+	}
+
+	moved, _ := common.ApplyMove(board, 2, 4, 1)
+	if zobristHash(board, 1) == zobristHash(moved, 1) {
+		t.Error("expected a move to change the hash")
+	}
+}
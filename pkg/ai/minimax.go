@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"log"
+	"math"
+	"time"
+)
+
+// State represents the state of a game and implements game domain-specific logic.
+type State interface {
+	// Score evaluates the desirability of a state from the perspective of the AI player.
+	Score() float64
+
+	// AITurn returns true if the next move will be performed by the AI player.
+	AITurn() bool
+
+	// MoveCount returns the number of moves possible in the current state.
+	MoveCount() int
+
+	// Move performs the move at the given index and returns the next state after the move.
+	Move(int) State
+
+	// Hash identifies the state in the transposition table. States with the same hash are assumed
+	// to be the same position.
+	Hash() uint64
+}
+
+const (
+	// maxTableSize caps the number of states remembered by the transposition table, to bound the
+	// memory used by deep searches.
+	maxTableSize = 1 << 20
+
+	// deadlineCheckInterval is how many states are searched between checks of the time budget.
+	deadlineCheckInterval = 1024
+)
+
+// bound says how a remembered score relates to the true score of a state, since alpha-beta pruning
+// may stop searching a state before its true score is known.
+type bound uint8
+
+const (
+	exact bound = iota
+	lowerBound
+	upperBound
+)
+
+type tableEntry struct {
+	depth int
+	score float64
+	bound bound
+}
+
+// search holds what is learned while choosing a single move, which carries over between the
+// increasingly deep iterations of the search.
+type search struct {
+	table    map[uint64]tableEntry
+	deadline time.Time
+	visited  int
+	timedOut bool
+}
+
+// findMoveUsingMinimax searches increasingly deep, up to the specified depth, and returns the best
+// AI move and its score from the deepest search that finished within the time budget. A budget of
+// 0 means there is no time limit. The shallowest search always finishes, so there is always a move.
+func findMoveUsingMinimax(state State, depth int, budget time.Duration) (int, float64) {
+	log.Printf("Running findMoveUsingMinimax using depth=%d, budget=%v", depth, budget)
+
+	s := &search{table: make(map[uint64]tableEntry)}
+	if budget > 0 {
+		s.deadline = time.Now().Add(budget)
+	}
+
+	bestMove, bestScore := s.searchMoves(state, 0, 0)
+	searched := 0
+
+	for d := 1; d <= depth; d++ {
+		move, score := s.searchMoves(state, d, bestMove)
+		if s.timedOut {
+			break
+		}
+
+		bestMove, bestScore = move, score
+		searched = d
+	}
+
+	log.Printf("findMoveUsingMinimax bestMove=%d, bestScore=%f, depth=%d", bestMove, bestScore, searched)
+
+	return bestMove, bestScore
+}
+
+// searchMoves scores each AI move to the specified depth, starting with the best move of the
+// previous iteration, and returns the best one. Ties go to the first move.
+func (s *search) searchMoves(state State, depth, firstMove int) (int, float64) {
+	bestMove := firstMove
+	bestScore := s.minimax(state.Move(firstMove), depth, math.Inf(-1), math.Inf(1))
+
+	for i := 0; i < state.MoveCount(); i++ {
+		if i == firstMove {
+			continue
+		}
+
+		moveScore := s.minimax(state.Move(i), depth, math.Inf(-1), math.Inf(1))
+
+		if moveScore > bestScore || (moveScore == bestScore && i < bestMove) {
+			bestMove = i
+			bestScore = moveScore
+		}
+	}
+
+	return bestMove, bestScore
+}
+
+// minimax is the minimax adversarial search algorithm. It returns the score for an State
+// after performing minimax up to the specified depth n. Once the time budget runs out, it returns
+// meaningless scores as quickly as possible.
+func (s *search) minimax(state State, depth int, alpha, beta float64) float64 {
+	if depth <= 0 || state.MoveCount() <= 0 {
+		return state.Score()
+	}
+
+	if s.expired() {
+		return 0
+	}
+
+	key := state.Hash()
+	if entry, ok := s.table[key]; ok && entry.depth >= depth {
+		switch entry.bound {
+		case exact:
+			return entry.score
+		case lowerBound:
+			alpha = math.Max(alpha, entry.score)
+		case upperBound:
+			beta = math.Min(beta, entry.score)
+		}
+
+		if alpha >= beta {
+			return entry.score
+		}
+	}
+
+	originalAlpha, originalBeta := alpha, beta
+
+	var (
+		result          float64
+		comparator      func(float64, float64) float64
+		alphaBetaUpdate func(float64)
+		alphaBetaBreak  func() bool
+	)
+
+	if state.AITurn() {
+		result = math.Inf(-1)
+		comparator = math.Max
+		alphaBetaUpdate = func(v float64) { alpha = math.Max(alpha, v) }
+		alphaBetaBreak = func() bool { return alpha >= beta }
+	} else {
+		result = math.Inf(1)
+		comparator = math.Min
+		alphaBetaUpdate = func(v float64) { beta = math.Min(beta, v) }
+		alphaBetaBreak = func() bool { return beta <= alpha }
+	}
+
+	for i := 0; i < state.MoveCount(); i++ {
+		moveScore := s.minimax(state.Move(i), depth-1, alpha, beta)
+		result = comparator(result, moveScore)
+		alphaBetaUpdate(moveScore)
+		if alphaBetaBreak() {
+			break
+		}
+	}
+
+	if s.timedOut {
+		return result
+	}
+
+	entry := tableEntry{depth: depth, score: result, bound: exact}
+	switch {
+	case result <= originalAlpha:
+		entry.bound = upperBound
+	case result >= originalBeta:
+		entry.bound = lowerBound
+	}
+
+	if _, ok := s.table[key]; ok || len(s.table) < maxTableSize {
+		s.table[key] = entry
+	}
+
+	return result
+}
+
+// expired returns true once the time budget has run out.
+func (s *search) expired() bool {
+	if s.timedOut || s.deadline.IsZero() {
+		return s.timedOut
+	}
+
+	s.visited++
+	if s.visited%deadlineCheckInterval == 0 && time.Now().After(s.deadline) {
+		s.timedOut = true
+	}
+
+	return s.timedOut
+}
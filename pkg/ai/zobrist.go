@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"math/rand"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Zobrist hashing gives each position a practically unique key by combining a random number for
+// each disk on the board, and another for whose turn it is.
+
+var (
+	zobristDisks [common.MaxBoardSize][common.MaxBoardSize][2]uint64
+	zobristTurn  uint64
+)
+
+func init() {
+	// A fixed seed keeps hashes the same from run to run, which makes searches repeatable.
+	r := rand.New(rand.NewSource(1))
+
+	for x := range zobristDisks {
+		for y := range zobristDisks[x] {
+			for i := range zobristDisks[x][y] {
+				zobristDisks[x][y][i] = r.Uint64()
+			}
+		}
+	}
+
+	zobristTurn = r.Uint64()
+}
+
+// zobristHash returns the Zobrist hash of a board when it is the specified player's turn.
+func zobristHash(board common.Board, turn common.Disk) uint64 {
+	var hash uint64
+
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			if disk := board.Squares[x][y]; disk != 0 {
+				hash ^= zobristDisks[x][y][disk-1]
+			}
+		}
+	}
+
+	if turn == common.Player2 {
+		hash ^= zobristTurn
+	}
+
+	return hash
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Bots play multiplayer games without a terminal, taking their moves from an Engine. A bot either
+// hosts its own games and waits for opponents, or joins another player's games, and plays a number
+// of games in a row before it stops. A bot on the bot ladder instead waits for the server to pair
+// it, and plays until it is stopped.
+
+// BotOptions configure a bot.
+type BotOptions struct {
+	Nickname string
+
+	// Token proves that the bot owns Nickname, if the nickname is claimed.
+	Token string
+
+	// Host is the player whose games the bot joins. The bot hosts its own games if it is empty.
+	Host string
+
+	// Games is how many games the bot plays before it stops.
+	Games int
+
+	// Ladder makes the bot wait on the bot ladder for its games, instead of hosting or joining
+	// them. The bot's nickname has to be claimed and registered for the ladder.
+	Ladder bool
+
+	// BoardSize and Variant configure the games the bot hosts.
+	BoardSize int
+	Variant   string
+}
+
+const (
+	// joinRetryDelay is how long a bot waits before trying again to join a host who is not ready.
+	joinRetryDelay = time.Second
+
+	// maxJoinAttempts is how many times in a row a bot tries to join a host before it gives up.
+	maxJoinAttempts = 30
+)
+
+// RunBot connects a bot to the server and plays games with an engine until it has played
+// botOpts.Games of them.
+func RunBot(opts Options, botOpts BotOptions, engine Engine) error {
+	if botOpts.Games < 1 && !botOpts.Ladder {
+		return errors.New("a bot must play at least one game")
+	}
+
+	c, finish, err := setupWebsocket(opts.Addr, opts.Local, messages.Hello{
+		Version:       opts.Version,
+		Nickname:      botOpts.Nickname,
+		Token:         botOpts.Token,
+		BoardEncoding: common.BoardEncodingCompact,
+	})
+	if err != nil {
+		return err
+	}
+	defer finish()
+
+	b := &bot{BotOptions: botOpts, engine: engine, c: c}
+
+	done := make(chan struct{})
+	defer close(done)
+	go b.keepalive(done)
+
+	if err := b.start(); err != nil {
+		return err
+	}
+
+	for b.Ladder || b.played < b.Games {
+		var wrapper messages.Wrapper
+		if err := c.ReadJSON(&wrapper); err != nil {
+			return err
+		}
+		if err := b.handle(wrapper.Message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type bot struct {
+	BotOptions
+	engine Engine
+
+	c       *websocket.Conn
+	writeMu sync.Mutex
+
+	// The game being played. The bot is between games while disk is zero.
+	host    string
+	variant string
+	disk    common.Disk
+	board   common.Board
+	player  common.Disk
+
+	played       int
+	joinAttempts int
+
+	// starting is whether a ladder game has started and its board has yet to arrive.
+	starting bool
+
+	// waiting is 1 while the bot waits on the ladder. It is read by keepalive, so it is accessed
+	// atomically.
+	waiting int32
+}
+
+// start hosts the bot's next game, or joins the host's.
+func (b *bot) start() error {
+	b.disk = 0
+
+	if b.Ladder {
+		atomic.StoreInt32(&b.waiting, 1)
+		return b.send(messages.JoinBotLadder{Nickname: b.Nickname})
+	}
+
+	if b.Host == "" {
+		return b.send(messages.HostGame{Nickname: b.Nickname, BoardSize: b.BoardSize, Variant: b.Variant})
+	}
+
+	b.joinAttempts++
+	return b.send(messages.JoinGame{Nickname: b.Nickname, Host: b.Host})
+}
+
+// retryJoin tries again to join a host who has not hosted their next game yet.
+func (b *bot) retryJoin(reason string) error {
+	if b.joinAttempts >= maxJoinAttempts {
+		return fmt.Errorf("failed to join %s: %s", b.Host, reason)
+	}
+	time.Sleep(joinRetryDelay)
+	return b.start()
+}
+
+func (b *bot) handle(message interface{}) error {
+	switch m := message.(type) {
+	case *messages.UpdateBoard:
+		if m.Rules != nil {
+			b.variant = common.VariantClassic
+			if m.Rules.Scoring == messages.ScoringFewestDisks {
+				b.variant = common.VariantAnti
+			}
+		}
+		b.board, b.player = m.Board, m.Player
+		if b.starting {
+			b.starting = false
+			return b.newGame()
+		}
+		return b.play()
+
+	case *messages.GameStarted:
+		if !b.Ladder && common.GameOver(b.board) {
+			// This is the game that just ended, because the host has not hosted their next one yet.
+			return b.retryJoin("the game is over")
+		}
+		b.host, b.disk = m.Host, m.Disk
+		b.joinAttempts = 0
+		log.Printf("%s: %s is playing %s as player %d", b.gameName(), b.Nickname, m.Opponent, m.Disk)
+		if b.Ladder {
+			// The board of a ladder game comes after it starts.
+			atomic.StoreInt32(&b.waiting, 0)
+			b.starting = true
+			return nil
+		}
+		return b.newGame()
+
+	case *messages.BotLadderJoined:
+		log.Printf("%s is waiting on the bot ladder, rated %d after %d games", b.Nickname, m.Rating, m.Games)
+
+	case *messages.GameOver:
+		return b.endGame()
+
+	case *messages.Error:
+		if b.disk == 0 && b.Host != "" {
+			return b.retryJoin(m.Error)
+		}
+		return fmt.Errorf("server error: %s", m.Error)
+	}
+
+	return nil
+}
+
+// newGame tells the engine that a game has started, and plays if it is the bot's turn.
+func (b *bot) newGame() error {
+	if err := b.engine.NewGame(b.board.Size, b.variant, b.disk); err != nil {
+		return err
+	}
+	return b.play()
+}
+
+// gameName names the game the bot is playing for its log.
+func (b *bot) gameName() string {
+	if b.Ladder {
+		return fmt.Sprintf("Ladder game %d", b.played+1)
+	}
+	return fmt.Sprintf("Game %d of %d", b.played+1, b.Games)
+}
+
+// play asks the engine for a move if it is the bot's turn.
+func (b *bot) play() error {
+	if b.disk == 0 {
+		return nil
+	}
+
+	if common.GameOver(b.board) {
+		return b.endGame()
+	}
+
+	if b.player != b.disk || !common.HasMoves(b.board, b.disk) {
+		return nil
+	}
+
+	move, err := b.engine.Move(b.board, b.disk)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := common.ApplyMove(b.board, move[0], move[1], b.disk); !ok {
+		return fmt.Errorf("engine played an illegal move: %s", common.Notation(move[0], move[1]))
+	}
+
+	return b.send(messages.PlaceDisk{Nickname: b.Nickname, Host: b.host, X: move[0], Y: move[1]})
+}
+
+// endGame tells the engine that the game is over and starts the next one.
+func (b *bot) endGame() error {
+	if b.disk == 0 {
+		return nil
+	}
+
+	if err := b.engine.GameOver(b.board); err != nil {
+		return err
+	}
+
+	p1, p2 := common.KeepScore(b.board)
+	log.Printf("%s is over: %d-%d", b.gameName(), p1, p2)
+	b.played++
+
+	b.disk = 0
+	if b.Ladder || b.played < b.Games {
+		return b.start()
+	}
+	return nil
+}
+
+func (b *bot) send(message interface{}) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	return b.c.WriteJSON(messages.Wrapper{Message: message})
+}
+
+// keepalive pings the server until done is closed.
+func (b *bot) keepalive(done <-chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			var message interface{} = messages.Ping{}
+			if atomic.LoadInt32(&b.waiting) == 1 {
+				// Joining again keeps the bot's place on the ladder from expiring.
+				message = messages.JoinBotLadder{Nickname: b.Nickname}
+			}
+			if err := b.send(message); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Engine chooses a bot's moves.
+type Engine interface {
+	// NewGame starts a game of a variant on a board of a size, in which the bot plays disk.
+	NewGame(size int, variant string, disk common.Disk) error
+
+	// Move returns the bot's move on a board. It is only asked when the bot has a legal move.
+	Move(board common.Board, disk common.Disk) ([2]int, error)
+
+	// GameOver ends the game with its final board.
+	GameOver(board common.Board) error
+}
+
+// AIEngine plays with the built-in AI, looking Depth turns ahead.
+type AIEngine struct {
+	Depth   int
+	variant string
+}
+
+func (e *AIEngine) NewGame(_ int, variant string, _ common.Disk) error {
+	e.variant = variant
+	return nil
+}
+
+func (e *AIEngine) Move(board common.Board, disk common.Disk) ([2]int, error) {
+	analysis := ai.Analyze(board, e.variant, disk, e.Depth)
+	if !analysis.HasMove {
+		return [2]int{}, errors.New("the AI has no move")
+	}
+	return analysis.Best, nil
+}
+
+func (e *AIEngine) GameOver(common.Board) error {
+	return nil
+}
+
+// TextEngine talks to an engine, usually another program, in a line-based text protocol. The bot
+// writes one command per line, and the engine answers each move command with a line of its own:
+//
+//	newgame <size> <variant> <disk>    a game starts, such as "newgame 8 classic 1"
+//	move <disk> <board>                the engine answers with its move, such as "d3"
+//	gameover <p1 score> <p2 score>     the game is over
+//
+// Disk 1 is black, which moves first. A board is its rows from top to bottom separated by slashes,
+// with "." for an empty square and "1" or "2" for a disk. Moves are in algebraic notation, which
+// names the column with a letter and the row with a number, so "a1" is the top-left square.
+type TextEngine struct {
+	r *bufio.Scanner
+	w io.Writer
+}
+
+// NewTextEngine returns an engine that reads the engine's answers from r and writes commands to w.
+func NewTextEngine(r io.Reader, w io.Writer) *TextEngine {
+	return &TextEngine{r: bufio.NewScanner(r), w: w}
+}
+
+func (e *TextEngine) NewGame(size int, variant string, disk common.Disk) error {
+	return e.command("newgame %d %s %d", size, variant, disk)
+}
+
+func (e *TextEngine) Move(board common.Board, disk common.Disk) ([2]int, error) {
+	if err := e.command("move %d %s", disk, formatBoard(board)); err != nil {
+		return [2]int{}, err
+	}
+
+	if !e.r.Scan() {
+		if err := e.r.Err(); err != nil {
+			return [2]int{}, err
+		}
+		return [2]int{}, io.ErrUnexpectedEOF
+	}
+
+	x, y, err := common.ParseNotation(strings.TrimSpace(e.r.Text()))
+	if err != nil {
+		return [2]int{}, fmt.Errorf("engine: %w", err)
+	}
+
+	return [2]int{x, y}, nil
+}
+
+func (e *TextEngine) GameOver(board common.Board) error {
+	p1, p2 := common.KeepScore(board)
+	return e.command("gameover %d %d", p1, p2)
+}
+
+func (e *TextEngine) command(format string, a ...interface{}) error {
+	_, err := fmt.Fprintf(e.w, format+"\n", a...)
+	return err
+}
+
+// formatBoard writes a board for the text protocol.
+func formatBoard(board common.Board) string {
+	rows := make([]string, board.Size)
+	for y := range rows {
+		var sb strings.Builder
+		for x := 0; x < board.Size; x++ {
+			switch disk := board.Squares[x][y]; disk {
+			case 0:
+				sb.WriteByte('.')
+			default:
+				fmt.Fprint(&sb, disk)
+			}
+		}
+		rows[y] = sb.String()
+	}
+	return strings.Join(rows, "/")
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestTextEngine(t *testing.T) {
+	board := common.NewBoard(6)
+	var commands bytes.Buffer
+	engine := NewTextEngine(strings.NewReader(" c2 \n"), &commands)
+
+	if err := engine.NewGame(6, common.VariantAnti, common.Player1); err != nil {
+		t.Fatal(err)
+	}
+
+	move, err := engine.Move(board, common.Player1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move != [2]int{2, 1} {
+		t.Errorf("got move %v, want [2 1]", move)
+	}
+
+	if err := engine.GameOver(board); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "newgame 6 anti 1\n" +
+		"move 1 ....../....../..12../..21../....../......\n" +
+		"gameover 2 2\n"
+	if got := commands.String(); got != want {
+		t.Errorf("got commands:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTextEngineBadMove(t *testing.T) {
+	engine := NewTextEngine(strings.NewReader("pass\n"), &bytes.Buffer{})
+	if _, err := engine.Move(common.NewBoard(8), common.Player1); err == nil {
+		t.Error("expected an error for a move that isn't a square")
+	}
+}
+
+func TestTextEngineClosed(t *testing.T) {
+	engine := NewTextEngine(strings.NewReader(""), &bytes.Buffer{})
+	if _, err := engine.Move(common.NewBoard(8), common.Player1); err == nil {
+		t.Error("expected an error when the engine stops answering")
+	}
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/armsnyder/othelgo/pkg/client/scenes"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Delays between attempts to reconnect to the server. The delay doubles after each failed attempt.
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+var errNotConnected = errors.New("not connected to the server")
+
+// connection manages the websocket connection to the server. When the connection drops, it
+// reconnects in the background and says hello again. Changes in connectivity are sent on
+// statuses so that scenes can restore their session.
+type connection struct {
+	// addr is guarded by mu, since the player can switch servers.
+	addr  string
+	local bool
+	// hello returns the hello to send, which changes when the player claims a nickname.
+	hello func() messages.Hello
+
+	mu sync.Mutex
+	c  *websocket.Conn
+
+	messages chan messages.Wrapper
+	statuses chan scenes.ConnectionStatus
+	closed   chan struct{}
+}
+
+// connect opens a connection to the server. If the server can't be reached, the client starts
+// offline and keeps trying to connect in the background, so that games that don't need the server
+// can still be played.
+func connect(addr string, local bool, hello func() messages.Hello) *connection {
+	c, _, err := setupWebsocket(addr, local, hello())
+	if err != nil {
+		log.Printf("Starting offline: %v", err)
+	}
+
+	conn := &connection{
+		addr:     addr,
+		local:    local,
+		hello:    hello,
+		c:        c,
+		messages: make(chan messages.Wrapper),
+		statuses: make(chan scenes.ConnectionStatus),
+		closed:   make(chan struct{}),
+	}
+
+	go conn.run(c)
+
+	return conn
+}
+
+// Send sends a message to the server. It fails while the client is reconnecting.
+func (conn *connection) Send(message interface{}) error {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.c == nil {
+		return errNotConnected
+	}
+
+	return conn.c.WriteJSON(messages.Wrapper{Message: message})
+}
+
+// Close closes the connection and stops reconnecting.
+func (conn *connection) Close() {
+	close(conn.closed)
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.c != nil {
+		conn.c.Close()
+		conn.c = nil
+	}
+}
+
+// run receives messages until the connection drops, and then reconnects, for as long as the
+// connection is open. It starts by reconnecting if c is nil.
+func (conn *connection) run(c *websocket.Conn) {
+	for {
+		for c != nil {
+			var wrapper messages.Wrapper
+			if err := c.ReadJSON(&wrapper); err != nil {
+				log.Printf("Failed to read message from websocket: %v", err)
+				break
+			}
+
+			select {
+			case conn.messages <- wrapper:
+			case <-conn.closed:
+				return
+			}
+		}
+
+		if c != nil {
+			conn.mu.Lock()
+			c.Close()
+			conn.c = nil
+			conn.mu.Unlock()
+		}
+
+		if !conn.setStatus(scenes.Reconnecting) {
+			return
+		}
+
+		if c = conn.reconnect(); c == nil {
+			return
+		}
+
+		if !conn.setStatus(scenes.Connected) {
+			return
+		}
+	}
+}
+
+// reconnect dials the server until it succeeds, backing off exponentially. It returns nil if the
+// connection is closed first.
+func (conn *connection) reconnect() *websocket.Conn {
+	delay := reconnectMinDelay
+
+	for {
+		select {
+		case <-time.After(delay):
+		case <-conn.closed:
+			return nil
+		}
+
+		log.Println("Reconnecting")
+
+		conn.mu.Lock()
+		addr := conn.addr
+		conn.mu.Unlock()
+
+		c, _, err := setupWebsocket(addr, conn.local, conn.hello())
+		if err != nil {
+			log.Printf("Failed to reconnect: %v", err)
+
+			if delay *= 2; delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+
+			continue
+		}
+
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+
+		// The connection may have been closed while dialing.
+		select {
+		case <-conn.closed:
+			c.Close()
+			return nil
+		default:
+		}
+
+		conn.c = c
+
+		return c
+	}
+}
+
+// switchServer connects to another server. The current connection is closed, so that run
+// reconnects to the new address.
+func (conn *connection) switchServer(addr string) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if addr == conn.addr {
+		return
+	}
+
+	conn.addr = addr
+
+	if conn.c != nil {
+		conn.c.Close()
+	}
+}
+
+func (conn *connection) setStatus(status scenes.ConnectionStatus) bool {
+	select {
+	case conn.statuses <- status:
+		return true
+	case <-conn.closed:
+		return false
+	}
+}
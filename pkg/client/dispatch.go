@@ -0,0 +1,123 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/scenes"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// registerEngineHandlers registers the engine's handlers for the messages that matter whatever
+// the scene, such as the server's branding and maintenance notices.
+func registerEngineHandlers(h *scenes.Handlers, changeGameBorderDecoration, changeMaintenanceNotice func(string)) {
+	h.OnDecorate(func(m *messages.Decorate) error {
+		changeGameBorderDecoration(m.Decoration)
+		return nil
+	})
+
+	h.OnPong(func(m *messages.Pong) error {
+		changeMaintenanceNotice(m.Maintenance)
+		if offset, ok := serverClock.ponged(m.ServerTime, time.Now()); ok {
+			scenes.SetServerOffset(offset)
+		}
+		return nil
+	})
+
+	h.OnHelloAck(func(m *messages.HelloAck) error {
+		log.Printf("Server version: %s, features: %v", m.Version, m.Features)
+		changeMaintenanceNotice(m.Maintenance)
+		setBranding(m.Branding)
+		return nil
+	})
+
+	h.OnNicknameClaimed(func(m *messages.NicknameClaimed) error {
+		if m.Token != "" {
+			if err := scenes.SaveToken(m.Nickname, m.Token); err != nil {
+				log.Printf("Failed to save the token for %q: %v", m.Nickname, err)
+			}
+		}
+		return nil
+	})
+
+	h.OnDataJobDone(func(m *messages.DataJobDone) error {
+		switch {
+		case m.Export != nil:
+			if err := scenes.SaveExport(*m.Export); err != nil {
+				log.Printf("Failed to save the exported data for %q: %v", m.Nickname, err)
+			}
+		case m.Kind == messages.DataJobDelete:
+			if err := scenes.ForgetToken(m.Nickname); err != nil {
+				log.Printf("Failed to forget the token for %q: %v", m.Nickname, err)
+			}
+		}
+		return nil
+	})
+
+	h.OnError(func(m *messages.Error) error {
+		switch m.Code {
+		case messages.ErrorUpgradeRequired:
+			termbox.Interrupt()
+			return errors.New(m.Error)
+		case messages.ErrorMaintenance:
+			changeMaintenanceNotice(m.Error)
+		}
+		return nil
+	})
+}
+
+// messageScenes are the scenes that handle messages from the server. They are listed so that
+// message types that nothing handles can be found when the client starts.
+var messageScenes = []scenes.Scene{
+	&scenes.Dashboard{},
+	&scenes.Game{},
+	&scenes.Join{},
+	&scenes.Nickname{},
+	&scenes.Profile{},
+	&scenes.Replay{},
+}
+
+// ignoredMessages are messages from the server that the terminal client has no use for.
+var ignoredMessages = []interface{}{
+	// The terminal client doesn't create or join tournaments.
+	(*messages.TournamentUpdate)(nil),
+
+	// Only bots join the bot ladder, and the bot handles this itself.
+	(*messages.BotLadderJoined)(nil),
+}
+
+// unhandledMessages returns the names of the types of messages from the server that neither the
+// engine nor any scene handles, and that aren't ignored on purpose.
+func unhandledMessages() []string {
+	handlers := []*scenes.Handlers{new(scenes.Handlers)}
+	registerEngineHandlers(handlers[0], func(string) {}, func(string) {})
+	for _, scene := range messageScenes {
+		handlers = append(handlers, scenes.HandlersOf(scene))
+	}
+
+	ignored := make(map[reflect.Type]bool)
+	for _, message := range ignoredMessages {
+		ignored[reflect.TypeOf(message)] = true
+	}
+
+	var unhandled []string
+
+	for _, typ := range scenes.MessageTypes() {
+		handled := ignored[typ]
+		for _, h := range handlers {
+			handled = handled || h.Handles(typ)
+		}
+		if !handled {
+			unhandled = append(unhandled, typ.Elem().Name())
+		}
+	}
+
+	sort.Strings(unhandled)
+
+	return unhandled
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/client/scenes"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestEveryMessageIsHandled(t *testing.T) {
+	assert.Contains(t, scenes.MessageTypes(), reflect.TypeOf(&messages.UpdateBoard{}))
+	assert.Empty(t, unhandledMessages(), "handle the messages in a scene, or add them to ignoredMessages")
+}
+
+func TestHandlersDispatch(t *testing.T) {
+	var h scenes.Handlers
+
+	var got *messages.Pong
+	h.OnPong(func(m *messages.Pong) error {
+		got = m
+		return nil
+	})
+
+	pong := &messages.Pong{Maintenance: "soon"}
+	assert.NoError(t, h.Dispatch(pong))
+	assert.Same(t, pong, got)
+
+	// Messages without a handler are ignored.
+	assert.NoError(t, h.Dispatch(&messages.Decorate{}))
+}
@@ -3,6 +3,7 @@ package draw
 import (
 	"fmt"
 	"math"
+	"os"
 	"strings"
 	"unicode/utf8"
 
@@ -23,7 +24,7 @@ func Draw(anchor Anchor, color Color, text interface{}) {
 	case rune:
 		positionX, positionY, _, _ := anchor()
 		fg, bg := color()
-		termbox.SetCell(positionX, positionY, t, fg, bg)
+		screen.SetCell(positionX, positionY, t, fg, bg)
 
 	case string:
 		rows := strings.Split(t, "\n")
@@ -48,7 +49,7 @@ func Draw(anchor Anchor, color Color, text interface{}) {
 // conjunction with Draw to place the cursor.
 func SetCursor(anchor Anchor) {
 	x, y, _, _ := anchor()
-	termbox.SetCursor(x, y-1)
+	screen.SetCursor(x, y-1)
 }
 
 // Anchor defines a position offset and direction that can be used for drawing.
@@ -61,7 +62,7 @@ func Origin() (positionX, positionY int, drawDirectionX, drawDirectionY float64)
 
 // TopLeft is an Anchor on the top-left corner of the game window.
 func TopLeft() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 	leftX := (termWidth - gameBoyWidth) / 2
 	topY := (termHeight - gameBoyHeight) / 2
 	// Add an inner margin while also ensuring the text is always on-screen.
@@ -70,25 +71,42 @@ func TopLeft() (positionX, positionY int, drawDirectionX, drawDirectionY float64
 
 // TopRight is an Anchor on the top-right corner of the game window.
 func TopRight() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 	rightX := (termWidth + gameBoyWidth) / 2
 	topY := (termHeight - gameBoyHeight) / 2
 	// Add an inner margin while also ensuring the text is always on-screen.
 	return min(termWidth, rightX-marginX), max(0, topY+marginY+1), -1, 0
 }
 
+// TopCenter is an Anchor on the top edge of the game window, just inside the border, that draws
+// from the center outward.
+func TopCenter() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
+	termWidth, termHeight := Size()
+	topY := (termHeight - gameBoyHeight) / 2
+	return termWidth / 2, max(0, topY+marginY), -0.5, 0
+}
+
 // BotRight is an Anchor on the bottom-right corner of the game window.
 func BotRight() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 	rightX := (termWidth + gameBoyWidth) / 2
 	botY := (termHeight + gameBoyHeight) / 2
 	// Add an inner margin while also ensuring the text is always on-screen.
 	return min(termWidth, rightX-marginX), min(termHeight, botY-marginY) - 1, -1, 0
 }
 
+// BotLeft is an Anchor on the bottom-left corner of the game window.
+func BotLeft() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
+	termWidth, termHeight := Size()
+	leftX := (termWidth - gameBoyWidth) / 2
+	botY := (termHeight + gameBoyHeight) / 2
+	// Add an inner margin while also ensuring the text is always on-screen.
+	return max(0, leftX+marginX+2), min(termHeight, botY-marginY) - 1, 0, 0
+}
+
 // Center is an Anchor in the center-middle of the terminal that draws from the center outward.
 func Center() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 	centerX := termWidth / 2
 	centerY := termHeight / 2
 	return centerX, centerY, -0.5, -0.5
@@ -114,7 +132,7 @@ func CenterTop() (positionX, positionY int, drawDirectionX, drawDirectionY float
 
 // MiddleRight is an Anchor that is vertically centered and on the right edge of the game window.
 func MiddleRight() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 	rightX := (termWidth + gameBoyWidth) / 2
 	centerY := termHeight / 2
 	// Add an inner margin while also ensuring the text is always on-screen.
@@ -123,7 +141,7 @@ func MiddleRight() (positionX, positionY int, drawDirectionX, drawDirectionY flo
 
 // MiddleLeft is an Anchor that is vertically centered and on the left edge of the game window.
 func MiddleLeft() (positionX, positionY int, drawDirectionX, drawDirectionY float64) {
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 	leftX := (termWidth - gameBoyWidth) / 2
 	centerY := termHeight / 2
 	// Add an inner margin while also ensuring the text is always on-screen.
@@ -145,22 +163,63 @@ type Color func() (fg, bg termbox.Attribute)
 
 // Normal is the default Color.
 func Normal() (fg, bg termbox.Attribute) {
-	return termbox.ColorDefault, termbox.ColorDefault
+	if Monochrome {
+		return termbox.ColorDefault, termbox.ColorDefault
+	}
+	return theme.text[0], theme.text[1]
 }
 
 // Inverted is a Color that inverts the text and background color to appear as a highlight.
 func Inverted() (fg, bg termbox.Attribute) {
-	return termbox.ColorBlack, termbox.ColorWhite
+	if Monochrome {
+		return termbox.ColorBlack, termbox.ColorWhite
+	}
+	return theme.highlight[0], theme.highlight[1]
+}
+
+// Accent is a Color that stands out from Normal text, which is chosen by the server with SetAccent.
+func Accent() (fg, bg termbox.Attribute) {
+	if Monochrome || accent == nil {
+		return Normal()
+	}
+	return *accent, theme.text[1]
 }
 
-// Magenta is a magenta Color.
-func Magenta() (fg, bg termbox.Attribute) {
-	return termbox.ColorMagenta, termbox.ColorDefault
+// Monochrome is true when the terminal should be drawn without colors, either because the
+// NO_COLOR environment variable is set (see https://no-color.org) or the terminal is dumb.
+var Monochrome = os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+
+// Player1 is the Color of player 1's disks, which is magenta unless the theme changes it.
+func Player1() (fg, bg termbox.Attribute) {
+	if Monochrome {
+		return Normal()
+	}
+	return theme.player1[0], theme.player1[1]
 }
 
-// Green is not a creative Color.
-func Green() (fg, bg termbox.Attribute) {
-	return termbox.ColorGreen, termbox.ColorDefault
+// Player2 is the Color of player 2's disks, which is green unless the theme changes it.
+func Player2() (fg, bg termbox.Attribute) {
+	if Monochrome {
+		return Normal()
+	}
+	return theme.player2[0], theme.player2[1]
+}
+
+// heatColors are the colors of a heat map, coldest first. They are the same in every theme.
+var heatColors = []termbox.Attribute{termbox.ColorBlue, termbox.ColorCyan, termbox.ColorGreen, termbox.ColorYellow, termbox.ColorRed}
+
+// HeatLevels is how many colors a heat map has.
+var HeatLevels = len(heatColors)
+
+// Heat is the Color of a level of a heat map, from 0 for the coldest to HeatLevels-1 for the
+// hottest.
+func Heat(level int) Color {
+	return func() (fg, bg termbox.Attribute) {
+		if Monochrome {
+			return Normal()
+		}
+		return heatColors[min(max(level, 0), len(heatColors)-1)], theme.text[1]
+	}
 }
 
 func Border(decoration string) {
@@ -168,7 +227,7 @@ func Border(decoration string) {
 		return
 	}
 
-	termWidth, termHeight := termbox.Size()
+	termWidth, termHeight := Size()
 
 	topY := (termHeight - gameBoyHeight) / 2
 	bottomY := (termHeight + gameBoyHeight) / 2
@@ -0,0 +1,178 @@
+package draw
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Screen is what the game is drawn on. It is the terminal, unless the client is scripted, in which
+// case it is a Buffer.
+type Screen interface {
+	SetCell(x, y int, ch rune, fg, bg termbox.Attribute)
+	SetCursor(x, y int)
+	HideCursor()
+	Size() (width, height int)
+	Clear(fg, bg termbox.Attribute) error
+	Flush() error
+}
+
+var screen Screen = terminal{}
+
+// SetScreen changes what the game is drawn on.
+func SetScreen(s Screen) {
+	screen = s
+}
+
+// Size returns the size of the screen.
+func Size() (width, height int) {
+	return screen.Size()
+}
+
+// HideCursor hides the cursor until SetCursor is called.
+func HideCursor() {
+	screen.HideCursor()
+}
+
+// Clear clears the screen with a color, which is the background of everything drawn after.
+func Clear(color Color) error {
+	return screen.Clear(color())
+}
+
+// Flush shows what was drawn since the last Flush.
+func Flush() error {
+	return screen.Flush()
+}
+
+// terminal draws with termbox.
+type terminal struct{}
+
+func (terminal) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+
+func (terminal) SetCursor(x, y int) {
+	termbox.SetCursor(x, y)
+}
+
+func (terminal) HideCursor() {
+	termbox.HideCursor()
+}
+
+func (terminal) Size() (width, height int) {
+	return termbox.Size()
+}
+
+func (terminal) Clear(fg, bg termbox.Attribute) error {
+	return termbox.Clear(fg, bg)
+}
+
+func (terminal) Flush() error {
+	return termbox.Flush()
+}
+
+// Buffer is a Screen of a fixed size in memory, which is drawn the same way every time.
+type Buffer struct {
+	width, height int
+	cells         []termbox.Cell
+}
+
+// NewBuffer returns an empty Buffer.
+func NewBuffer(width, height int) *Buffer {
+	b := &Buffer{width: width, height: height, cells: make([]termbox.Cell, width*height)}
+	_ = b.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	return b
+}
+
+func (b *Buffer) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return
+	}
+	b.cells[y*b.width+x] = termbox.Cell{Ch: ch, Fg: fg, Bg: bg}
+}
+
+// SetCursor does nothing, since screenshots don't show the cursor.
+func (b *Buffer) SetCursor(int, int) {}
+
+func (b *Buffer) HideCursor() {}
+
+func (b *Buffer) Size() (width, height int) {
+	return b.width, b.height
+}
+
+func (b *Buffer) Clear(fg, bg termbox.Attribute) error {
+	for i := range b.cells {
+		b.cells[i] = termbox.Cell{Ch: ' ', Fg: fg, Bg: bg}
+	}
+	return nil
+}
+
+func (b *Buffer) Flush() error {
+	return nil
+}
+
+// String returns the text of the buffer without colors, with trailing spaces trimmed.
+func (b *Buffer) String() string {
+	var s strings.Builder
+	for y := 0; y < b.height; y++ {
+		var row strings.Builder
+		for x := 0; x < b.width; x++ {
+			row.WriteRune(b.cells[y*b.width+x].Ch)
+		}
+		s.WriteString(strings.TrimRight(row.String(), " "))
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+// ANSI returns the buffer as text with ANSI escape codes for its colors, starting from a cleared
+// terminal, such as for a frame of an asciinema cast.
+func (b *Buffer) ANSI() string {
+	var s strings.Builder
+	s.WriteString("\x1b[2J\x1b[H")
+
+	for y := 0; y < b.height; y++ {
+		if y > 0 {
+			s.WriteString("\r\n")
+		}
+
+		var last termbox.Cell
+		for x := 0; x < b.width; x++ {
+			cell := b.cells[y*b.width+x]
+			if x == 0 || cell.Fg != last.Fg || cell.Bg != last.Bg {
+				s.WriteString(sgr(cell.Fg, cell.Bg))
+			}
+			s.WriteRune(cell.Ch)
+			last = cell
+		}
+		s.WriteString("\x1b[0m")
+	}
+
+	return s.String()
+}
+
+// sgr returns the escape code that sets the terminal's colors to termbox attributes.
+func sgr(fg, bg termbox.Attribute) string {
+	codes := []string{"0"}
+
+	if fg&termbox.AttrBold != 0 {
+		codes = append(codes, "1")
+	}
+	if fg&termbox.AttrUnderline != 0 {
+		codes = append(codes, "4")
+	}
+	if fg&termbox.AttrReverse != 0 {
+		codes = append(codes, "7")
+	}
+
+	// Colors count up from ColorBlack, which is 1, while the codes count up from 30 or 40.
+	if color := fg & 0xff; color != termbox.ColorDefault {
+		codes = append(codes, fmt.Sprint(29+int(color)))
+	}
+	if color := bg & 0xff; color != termbox.ColorDefault {
+		codes = append(codes, fmt.Sprint(39+int(color)))
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
@@ -0,0 +1,208 @@
+package draw
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+	"gopkg.in/yaml.v2"
+)
+
+// Themes set the colors that the game is drawn with. They can be loaded from YAML files, so that
+// players can share themes without changing the code. The built-in themes are YAML files in the
+// themes directory, which are embedded in the client.
+
+// Theme is a set of colors for drawing the game.
+type Theme struct {
+	// Text is the color of most of the game.
+	Text ThemeColor `yaml:"text"`
+	// Highlight is the color of selected buttons and banners.
+	Highlight ThemeColor `yaml:"highlight"`
+	Player1   ThemeColor `yaml:"player1"`
+	Player2   ThemeColor `yaml:"player2"`
+}
+
+// ThemeColor is the foreground and background of a color in a theme. Each is a color name, such
+// as "magenta", optionally preceded by attributes, such as "bold magenta". An empty value is the
+// terminal's default color.
+type ThemeColor struct {
+	Fg string `yaml:"fg"`
+	Bg string `yaml:"bg"`
+}
+
+// DefaultTheme is the theme that is used unless another is chosen.
+var DefaultTheme = Theme{
+	Highlight: ThemeColor{Fg: "black", Bg: "white"},
+	Player1:   ThemeColor{Fg: "magenta"},
+	Player2:   ThemeColor{Fg: "green"},
+}
+
+// DefaultThemeName is the name of the default theme among the built-in themes.
+const DefaultThemeName = "default"
+
+//go:embed themes/*.yaml
+var builtinThemes embed.FS
+
+var colorNames = map[string]termbox.Attribute{
+	"default": termbox.ColorDefault,
+	"black":   termbox.ColorBlack,
+	"red":     termbox.ColorRed,
+	"green":   termbox.ColorGreen,
+	"yellow":  termbox.ColorYellow,
+	"blue":    termbox.ColorBlue,
+	"magenta": termbox.ColorMagenta,
+	"cyan":    termbox.ColorCyan,
+	"white":   termbox.ColorWhite,
+}
+
+var attributeNames = map[string]termbox.Attribute{
+	"bold":      termbox.AttrBold,
+	"underline": termbox.AttrUnderline,
+	"reverse":   termbox.AttrReverse,
+}
+
+// colors are the termbox attributes of a Theme.
+type colors struct {
+	text, highlight, player1, player2 [2]termbox.Attribute
+}
+
+// theme holds the colors of the current theme.
+var theme colors
+
+// accent is the foreground of Accent, or nil if it is drawn like Normal.
+var accent *termbox.Attribute
+
+func init() {
+	if err := SetTheme(DefaultTheme); err != nil {
+		panic(err)
+	}
+}
+
+// BuiltinThemeNames returns the names of the built-in themes, starting with the default theme.
+func BuiltinThemeNames() []string {
+	files, err := builtinThemes.ReadDir("themes")
+	if err != nil {
+		panic(err)
+	}
+
+	var names []string
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(f.Name(), path.Ext(f.Name())))
+	}
+
+	sort.Strings(names)
+
+	return append([]string{DefaultThemeName}, names...)
+}
+
+// BuiltinTheme returns a built-in theme by name.
+func BuiltinTheme(name string) (Theme, bool) {
+	if name == DefaultThemeName {
+		return DefaultTheme, true
+	}
+
+	data, err := builtinThemes.ReadFile(path.Join("themes", name+".yaml"))
+	if err != nil {
+		return Theme{}, false
+	}
+
+	t, err := ParseTheme(data)
+	if err != nil {
+		panic(fmt.Errorf("built-in theme %s: %w", name, err))
+	}
+
+	return t, true
+}
+
+// ParseTheme decodes a theme from YAML. Colors that are left out of the YAML are taken from the
+// default theme.
+func ParseTheme(data []byte) (Theme, error) {
+	t := DefaultTheme
+
+	if err := yaml.UnmarshalStrict(data, &t); err != nil {
+		return Theme{}, err
+	}
+
+	if _, err := t.colors(); err != nil {
+		return Theme{}, err
+	}
+
+	return t, nil
+}
+
+// SetTheme changes the colors that the game is drawn with.
+func SetTheme(t Theme) error {
+	c, err := t.colors()
+	if err != nil {
+		return err
+	}
+
+	theme = c
+
+	return nil
+}
+
+// SetAccent changes the color that Accent draws with, which is a color name as in a ThemeColor. An
+// empty name draws the accent like Normal.
+func SetAccent(name string) error {
+	if name == "" {
+		accent = nil
+		return nil
+	}
+
+	color, err := parseColor(name)
+	if err != nil {
+		return err
+	}
+
+	accent = &color
+
+	return nil
+}
+
+func (t Theme) colors() (c colors, err error) {
+	for _, field := range []struct {
+		name  string
+		color ThemeColor
+		dest  *[2]termbox.Attribute
+	}{
+		{"text", t.Text, &c.text},
+		{"highlight", t.Highlight, &c.highlight},
+		{"player1", t.Player1, &c.player1},
+		{"player2", t.Player2, &c.player2},
+	} {
+		if field.dest[0], err = parseColor(field.color.Fg); err != nil {
+			return colors{}, fmt.Errorf("%s fg: %w", field.name, err)
+		}
+		if field.dest[1], err = parseColor(field.color.Bg); err != nil {
+			return colors{}, fmt.Errorf("%s bg: %w", field.name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func parseColor(s string) (termbox.Attribute, error) {
+	words := strings.Fields(strings.ToLower(s))
+	if len(words) == 0 {
+		return termbox.ColorDefault, nil
+	}
+
+	color, ok := colorNames[words[len(words)-1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown color %q", words[len(words)-1])
+	}
+
+	for _, word := range words[:len(words)-1] {
+		attribute, ok := attributeNames[word]
+		if !ok {
+			return 0, fmt.Errorf("unknown attribute %q", word)
+		}
+		color |= attribute
+	}
+
+	return color, nil
+}
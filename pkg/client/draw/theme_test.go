@@ -0,0 +1,113 @@
+package draw
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme([]byte(`
+player1:
+  fg: bold blue
+text:
+  bg: black
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := DefaultTheme
+	want.Player1.Fg = "bold blue"
+	want.Text.Bg = "black"
+
+	if theme != want {
+		t.Errorf("expected %+v, got %+v", want, theme)
+	}
+
+	c, err := theme.colors()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.player1[0] != termbox.ColorBlue|termbox.AttrBold {
+		t.Errorf("expected bold blue, got %v", c.player1[0])
+	}
+}
+
+func TestParseThemeInvalid(t *testing.T) {
+	for _, data := range []string{
+		"player1: {fg: mauve}",
+		"player1: {fg: sparkly red}",
+		"board: {fg: red}",
+	} {
+		if _, err := ParseTheme([]byte(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	names := BuiltinThemeNames()
+	if names[0] != DefaultThemeName {
+		t.Errorf("expected the default theme first, got %v", names)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			theme, ok := BuiltinTheme(name)
+			if !ok {
+				t.Fatal("not found")
+			}
+			if _, err := theme.colors(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestHighContrastTheme(t *testing.T) {
+	theme, ok := BuiltinTheme("high-contrast")
+	if !ok {
+		t.Fatal("not found")
+	}
+
+	c, err := theme.colors()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.player1 == c.player2 {
+		t.Errorf("expected the players to have different colors, got %v", c.player1)
+	}
+}
+
+func TestBuiltinThemeMissing(t *testing.T) {
+	if _, ok := BuiltinTheme("mauve"); ok {
+		t.Error("expected no theme")
+	}
+}
+
+func TestSetAccent(t *testing.T) {
+	defer func() { accent = nil }()
+
+	if err := SetAccent("bold cyan"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fg, _ := Accent(); !Monochrome && fg != termbox.ColorCyan|termbox.AttrBold {
+		t.Errorf("expected bold cyan, got %v", fg)
+	}
+
+	if err := SetAccent("mauve"); err == nil {
+		t.Error("expected an error for mauve")
+	}
+
+	if err := SetAccent(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if fg, _ := Accent(); fg != termbox.ColorDefault {
+		t.Errorf("expected the normal color, got %v", fg)
+	}
+}
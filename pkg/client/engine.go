@@ -1,11 +1,13 @@
 package client
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"reflect"
+	"strings"
 	"time"
 	"unicode"
 
@@ -13,12 +15,44 @@ import (
 	"github.com/nsf/termbox-go"
 
 	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/client/richpresence"
 	"github.com/armsnyder/othelgo/pkg/client/scenes"
 
+	"github.com/armsnyder/othelgo/pkg/common"
 	"github.com/armsnyder/othelgo/pkg/messages"
 )
 
-func Run(local bool, version string) (err error) {
+// Options configure the client.
+type Options struct {
+	// Addr is the websocket address of the server. If empty, the client connects to the public
+	// server, or to a local server if Local is true.
+	Addr string
+
+	// Local is true when playing against a local development server.
+	Local bool
+
+	// Version is the client version sent to the server.
+	Version string
+
+	// DiscordAppID enables Discord rich presence using a Discord application ID, if set.
+	DiscordAppID string
+
+	// Lite is true on slow connections. The server is asked for the smallest updates, and the
+	// screen is redrawn as little as possible.
+	Lite bool
+
+	// Compact always draws the board with one character per square, as when the terminal is too
+	// small for the full board.
+	Compact bool
+}
+
+// keepaliveInterval is how often the client pings the server.
+const keepaliveInterval = time.Minute
+
+// tickInterval is how often Tick is called on the scene, so that it can animate.
+const tickInterval = time.Second / 12
+
+func Run(opts Options) (err error) {
 	// Setup log file.
 	finish, err := setupFileLogger()
 	if err != nil {
@@ -26,12 +60,39 @@ func Run(local bool, version string) (err error) {
 	}
 	defer finish(err)
 
+	if unhandled := unhandledMessages(); len(unhandled) > 0 {
+		log.Printf("Nothing handles these messages from the server: %v", unhandled)
+	}
+
+	// Pick the server to play on at startup, unless one was given. Until the player picks, the
+	// client connects to the server that was picked last time. We always want to prompt for a
+	// nickname when running locally because there will be more than one client.
+	var firstScene scenes.Scene = &scenes.Nickname{ChangeNickname: opts.Local}
+	switch {
+	case opts.Local:
+		scenes.SetServer(scenes.Server{Name: "local", Addr: serverAddr(opts.Addr, true)})
+	case opts.Addr != "":
+		scenes.SetServer(scenes.Server{Name: opts.Addr, Addr: opts.Addr})
+	default:
+		scenes.SetServer(scenes.LoadLastServer())
+		opts.Addr = scenes.ActiveServer().Addr
+		firstScene = &scenes.Servers{}
+	}
+
 	// Setup websocket.
-	c, finish2, err := setupWebsocket(local, version)
-	if err != nil {
-		return err
+	conn := connect(opts.Addr, opts.Local, func() messages.Hello {
+		nickname, token := scenes.LoadCredentials()
+		return messages.Hello{Version: opts.Version, Lite: opts.Lite, Nickname: nickname, Token: token, BoardEncoding: common.BoardEncodingCompact}
+	})
+	defer conn.Close()
+
+	// Setup Discord rich presence. Friends can join or watch games from the player's profile.
+	presence := setupRichPresence(opts.DiscordAppID)
+	var invites <-chan richpresence.Invite
+	if presence != nil {
+		defer presence.Close()
+		invites = presence.Invites()
 	}
-	defer finish2()
 
 	// Setup terminal.
 	log.Println("Initializing terminal")
@@ -40,14 +101,20 @@ func Run(local bool, version string) (err error) {
 	}
 	defer termbox.Close()
 
+	if err := scenes.LoadTheme(); err != nil {
+		log.Printf("Using the default theme: %v", err)
+	}
+
 	// Setup a handler for changing scenes, and start the first scene.
 	var currentScene scenes.Scene
-	var gameBorderDecoration string
-	// We always want to prompt for a nickname when running locally because there will be more than
-	// one client.
-	firstScene := &scenes.Nickname{ChangeNickname: local}
-	drawAndFlush := func() error { return drawAndFlushScene(currentScene, gameBorderDecoration) }
-	if err := setupChangeSceneHandler(&currentScene, firstScene, drawAndFlush, c); err != nil {
+	var gameBorderDecoration, maintenanceNotice, themeNotice string
+	var connectionStatus scenes.ConnectionStatus
+	drawAndFlush := func() error {
+		updateRichPresence(presence, currentScene)
+		return drawAndFlushScene(currentScene, gameBorderDecoration, maintenanceNotice, themeNotice, connectionStatus)
+	}
+	changeScene, err := setupChangeSceneHandler(&currentScene, firstScene, drawAndFlush, conn, &connectionStatus)
+	if err != nil {
 		return err
 	}
 
@@ -55,15 +122,23 @@ func Run(local bool, version string) (err error) {
 	terminalEvents := make(chan termbox.Event)
 	go receiveTerminalEvents(terminalEvents)
 
-	// Listen for websocket messages.
-	messageQueue := make(chan interface{})
-	messageErrors := make(chan error)
-	go receiveMessages(c, messageQueue, messageErrors)
-
-	// Setup a ticker for calling Tick on the scene.
-	ticker := time.NewTicker(time.Second / 12)
+	// Setup a ticker for calling Tick on the scene. Lite clients only tick often enough for the
+	// clocks, and reduce motion so that animations don't redraw the screen.
+	interval := tickInterval
+	if opts.Lite {
+		interval = time.Second
+		scenes.Lite = true
+	}
+	scenes.Compact = opts.Compact
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// Ping the server regularly to keep the connection open, and so the server can tell when the
+	// connection is lost. The first ping compares the clocks right away.
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	ping(conn)
+
 	// Run an event loop and call handlers on the current scene.
 	for {
 		select {
@@ -72,18 +147,39 @@ func Run(local bool, version string) (err error) {
 				return err
 			}
 
+		case <-keepalive.C:
+			ping(conn)
+
 		case event := <-terminalEvents:
+			if notice, ok := handleThemeKey(event); ok {
+				themeNotice = notice
+				if err := drawAndFlush(); err != nil {
+					return err
+				}
+				continue
+			}
+			themeNotice = ""
 			if err := handleTerminalEvent(event, currentScene, drawAndFlush); err != nil {
 				return err
 			}
 
-		case message := <-messageQueue:
-			if err := handleMessage(message, func(decoration string) { gameBorderDecoration = decoration }, currentScene, drawAndFlush); err != nil {
+		case wrapper := <-conn.messages:
+			setDecoration := func(decoration string) { gameBorderDecoration = decoration }
+			setMaintenance := func(notice string) { maintenanceNotice = notice }
+			if err := handleMessage(wrapper, setDecoration, setMaintenance, currentScene, drawAndFlush); err != nil {
+				return err
+			}
+
+		case status := <-conn.statuses:
+			connectionStatus = status
+			if err := handleConnectionStatus(status, currentScene, drawAndFlush); err != nil {
 				return err
 			}
 
-		case err := <-messageErrors:
-			log.Printf("error reading message from server: %v", err)
+		case invite := <-invites:
+			if err := handleInvite(invite, currentScene, changeScene); err != nil {
+				return err
+			}
 		}
 	}
 }
@@ -116,38 +212,147 @@ func setupFileLogger() (finish func(err error), err error) {
 	return finish, nil
 }
 
-func setupWebsocket(local bool, version string) (*websocket.Conn, func(), error) {
-	addr := "wss://1y9vcb5geb.execute-api.us-west-2.amazonaws.com/development"
+// serverAddr returns the websocket address of a server. An empty address is the public server, or
+// the local development server if local is true.
+func serverAddr(addr string, local bool) string {
+	if addr != "" {
+		return addr
+	}
+
 	if local {
-		addr = "ws://127.0.0.1:9000"
+		return "ws://127.0.0.1:9000"
 	}
+
+	return "wss://1y9vcb5geb.execute-api.us-west-2.amazonaws.com/development"
+}
+
+func setupWebsocket(addr string, local bool, hello messages.Hello) (*websocket.Conn, func(), error) {
+	addr = serverAddr(addr, local)
 	log.Printf("Dialing websocket %q", addr)
 	c, _, err := websocket.DefaultDialer.Dial(addr, nil)
 	if err != nil {
 		return nil, nil, err
 	}
-	err = c.WriteJSON(messages.Wrapper{Message: messages.Hello{Version: version}})
+	err = c.WriteJSON(messages.Wrapper{Message: hello})
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Ping the server regularly to keep the connection open.
-	go func() {
-		for {
-			if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Printf("Failed to ping server: %v", err)
+	return c, func() { c.Close() }, nil
+}
+
+// setupRichPresence connects to Discord if an application ID is configured. Rich presence is
+// optional, so failures are only logged.
+func setupRichPresence(appID string) *richpresence.Client {
+	if appID == "" {
+		return nil
+	}
+
+	log.Println("Connecting to Discord")
+
+	presence, err := richpresence.Connect(appID)
+	if err != nil {
+		log.Printf("Rich presence is disabled: %v", err)
+		return nil
+	}
+
+	return presence
+}
+
+func updateRichPresence(presence *richpresence.Client, scene scenes.Scene) {
+	if presence == nil {
+		return
+	}
+
+	activity := richpresence.Activity{Details: "In the menu"}
+	if describer, ok := scene.(scenes.Describer); ok {
+		activity.Details, activity.State = describer.Describe()
+	}
+
+	if inviter, ok := scene.(scenes.Inviter); ok {
+		if host, joinable, ok := inviter.Invitation(); ok {
+			activity.PartyID = host
+			activity.PartySize, activity.PartyMax = 2, 2
+			activity.SpectateSecret = encodeInvite(host, true)
+			if joinable {
+				activity.PartySize = 1
+				activity.JoinSecret = encodeInvite(host, false)
 			}
-			time.Sleep(time.Minute)
 		}
-	}()
+	}
 
-	return c, func() { c.Close() }, nil
+	presence.SetActivity(activity)
+}
+
+// invite is the secret of a Discord join or spectate button. Friends can only join games on the
+// server they are connected to. Join and spectate secrets differ because Discord requires it.
+type invite struct {
+	Addr     string `json:"a"`
+	Host     string `json:"h"`
+	Spectate bool   `json:"s,omitempty"`
+}
+
+func encodeInvite(host string, spectate bool) string {
+	data, _ := json.Marshal(invite{Addr: scenes.ActiveServer().Addr, Host: host, Spectate: spectate})
+	return string(data)
 }
 
-func setupChangeSceneHandler(currentScene *scenes.Scene, firstScene scenes.Scene, drawAndFlush func() error, c *websocket.Conn) error {
+// handleInvite joins or watches the game of a friend who the player picked on Discord.
+func handleInvite(discordInvite richpresence.Invite, currentScene scenes.Scene, changeScene scenes.ChangeScene) error {
+	var inv invite
+	if err := json.Unmarshal([]byte(discordInvite.Secret), &inv); err != nil {
+		log.Printf("Ignoring a malformed discord invite: %v", err)
+		return nil
+	}
+
+	if inv.Addr != scenes.ActiveServer().Addr {
+		log.Printf("Ignoring a discord invite to a game on another server %q", inv.Addr)
+		return nil
+	}
+
+	scene, ok := scenes.AcceptInvite(currentScene, inv.Host, discordInvite.Spectate)
+	if !ok {
+		log.Printf("Ignoring a discord invite to %s's game during a game", inv.Host)
+		return nil
+	}
+
+	log.Println("Quitting scene")
+	currentScene.OnQuit()
+
+	return changeScene(scene)
+}
+
+// sender sends messages to the server. It is a connection, unless the client is scripted.
+type sender interface {
+	Send(message interface{}) error
+}
+
+// serverSwitcher is a sender that can connect to another server, which scenes ask for with
+// scenes.SwitchServer.
+type serverSwitcher interface {
+	switchServer(addr string)
+}
+
+// setupChangeSceneHandler starts the first scene, and returns the function that changes scenes.
+func setupChangeSceneHandler(currentScene *scenes.Scene, firstScene scenes.Scene, drawAndFlush func() error, conn sender, connectionStatus *scenes.ConnectionStatus) (scenes.ChangeScene, error) {
 	sendMessage := func(v interface{}) error {
+		if s, ok := v.(scenes.SwitchServer); ok {
+			log.Printf("Switching to server %q", s.Addr)
+			if switcher, ok := conn.(serverSwitcher); ok {
+				switcher.switchServer(s.Addr)
+			}
+			return nil
+		}
+
 		log.Printf("Sending message %T", v)
-		return c.WriteJSON(messages.Wrapper{Message: v})
+
+		// Messages sent while reconnecting are dropped rather than ending the session. Scenes
+		// resend their state once the connection is restored.
+		if err := conn.Send(v); err != nil && !errors.Is(err, errNotConnected) {
+			return err
+		}
+
+		return nil
 	}
 
 	var changeScene scenes.ChangeScene
@@ -158,16 +363,23 @@ func setupChangeSceneHandler(currentScene *scenes.Scene, firstScene scenes.Scene
 
 		*currentScene = scene
 
-		termbox.HideCursor()
+		draw.HideCursor()
 
 		if err := scene.Setup(changeScene, sendMessage); err != nil {
 			return err
 		}
 
+		// Scenes that start while offline are told right away.
+		if observer, ok := scene.(scenes.ConnectionObserver); ok && *connectionStatus != scenes.Connected {
+			if err := observer.OnConnectionStatus(*connectionStatus); err != nil {
+				return err
+			}
+		}
+
 		return drawAndFlush()
 	}
 
-	return changeScene(firstScene)
+	return changeScene, changeScene(firstScene)
 }
 
 func receiveTerminalEvents(ch chan<- termbox.Event) {
@@ -177,16 +389,6 @@ func receiveTerminalEvents(ch chan<- termbox.Event) {
 	}
 }
 
-func receiveMessages(c *websocket.Conn, messageQueue chan<- interface{}, messageErrors chan<- error) {
-	for {
-		var wrapper messages.Wrapper
-		if err := c.ReadJSON(&wrapper); err != nil {
-			messageErrors <- fmt.Errorf("failed to read message from websocket: %w", err)
-		}
-		messageQueue <- wrapper.Message
-	}
-}
-
 func shouldInterrupt(event termbox.Event, scene scenes.Scene) bool {
 	if unicode.ToLower(event.Ch) == 'q' && !scene.HasFreeKeyboardInput() {
 		return true
@@ -195,18 +397,36 @@ func shouldInterrupt(event termbox.Event, scene scenes.Scene) bool {
 	return event.Key == termbox.KeyCtrlC || event.Key == termbox.KeyEsc
 }
 
-func drawAndFlushScene(scene scenes.Scene, decoration string) error {
+func drawAndFlushScene(scene scenes.Scene, decoration, maintenanceNotice, themeNotice string, connectionStatus scenes.ConnectionStatus) error {
 	log.Println("Drawing")
 
-	if err := termbox.Clear(termbox.ColorDefault, termbox.ColorDefault); err != nil {
+	// Clearing with the theme's text color gives the whole window the theme's background.
+	if err := draw.Clear(draw.Normal); err != nil {
 		return err
 	}
 
+	if scenes.TooSmall(scene) {
+		draw.HideCursor()
+		scenes.DrawTooSmall()
+		return draw.Flush()
+	}
+
 	scene.Draw()
 
 	draw.Border(decoration)
 
-	return termbox.Flush()
+	switch {
+	case connectionStatus == scenes.Reconnecting:
+		draw.Draw(draw.TopCenter, draw.Inverted, " OFFLINE, RECONNECTING... ")
+	case maintenanceNotice != "":
+		draw.Draw(draw.TopCenter, draw.Inverted, fmt.Sprintf(" MAINTENANCE: %s ", maintenanceNotice))
+	case themeNotice != "":
+		draw.Draw(draw.TopCenter, draw.Inverted, fmt.Sprintf(" %s ", themeNotice))
+	default:
+		draw.Draw(draw.TopCenter, draw.Normal, fmt.Sprintf(" %s ", strings.ToUpper(scenes.ActiveServer().Name)))
+	}
+
+	return draw.Flush()
 }
 
 func handleTick(currentScene scenes.Scene, drawAndFlush func() error) error {
@@ -238,14 +458,84 @@ func handleTerminalEvent(event termbox.Event, currentScene scenes.Scene, drawAnd
 	return drawAndFlush()
 }
 
-func handleMessage(message interface{}, changeGameBorderDecoration func(string), currentScene scenes.Scene, drawAndFlush func() error) error {
+// handleThemeKey switches to the next theme on Ctrl+T, and reloads the current theme on Ctrl+R so
+// that changes to a theme file can be previewed. It returns a notice to show, and whether the key
+// was handled.
+func handleThemeKey(event termbox.Event) (notice string, ok bool) {
+	switch event.Key {
+	case termbox.KeyCtrlT:
+		name, err := scenes.NextTheme()
+		if err != nil {
+			log.Printf("Failed to change theme: %v", err)
+			return strings.ToUpper(fmt.Sprintf("THEME ERROR: %v", err)), true
+		}
+		return fmt.Sprintf("THEME: %s", strings.ToUpper(name)), true
+
+	case termbox.KeyCtrlR:
+		if err := scenes.LoadTheme(); err != nil {
+			log.Printf("Failed to reload theme: %v", err)
+			return strings.ToUpper(fmt.Sprintf("THEME ERROR: %v", err)), true
+		}
+		return "THEME RELOADED", true
+	}
+
+	return "", false
+}
+
+func handleConnectionStatus(status scenes.ConnectionStatus, currentScene scenes.Scene, drawAndFlush func() error) error {
+	log.Printf("Connection status changed (status=%d)", status)
+
+	if observer, ok := currentScene.(scenes.ConnectionObserver); ok {
+		if err := observer.OnConnectionStatus(status); err != nil {
+			return err
+		}
+	}
+
+	return drawAndFlush()
+}
+
+// setBranding shows the server's branding on the main menu, or none if branding is nil.
+func setBranding(branding *messages.Branding) {
+	scenes.Branding = messages.Branding{}
+	if branding != nil {
+		scenes.Branding = *branding
+	}
+
+	if err := draw.SetAccent(scenes.Branding.AccentColor); err != nil {
+		log.Printf("Ignoring the server's accent color: %v", err)
+	}
+}
+
+// serverClock estimates the server's clock from the pings that the client sends.
+var serverClock timeSync
+
+// ping pings the server, and notes when, to compare the clocks when the pong arrives.
+func ping(conn sender) {
+	serverClock.pinged(time.Now())
+	if err := conn.Send(messages.Ping{}); err != nil {
+		log.Printf("Failed to ping server: %v", err)
+	}
+}
+
+func handleMessage(wrapper messages.Wrapper, changeGameBorderDecoration, changeMaintenanceNotice func(string), currentScene scenes.Scene, drawAndFlush func() error) error {
+	message := wrapper.Message
+
 	log.Printf("Received message %T", message)
 
-	if m, ok := message.(*messages.Decorate); ok {
-		changeGameBorderDecoration(m.Decoration)
+	if observer, ok := currentScene.(scenes.SequenceObserver); ok && wrapper.Seq != 0 && !observer.OnSequence(wrapper.Seq) {
+		log.Printf("Ignoring message %d, which was already received", wrapper.Seq)
+		return nil
+	}
+
+	// The engine handles the messages that matter whatever the scene, and then the scene handles
+	// the message too.
+	engine := new(scenes.Handlers)
+	registerEngineHandlers(engine, changeGameBorderDecoration, changeMaintenanceNotice)
+	if err := engine.Dispatch(message); err != nil {
+		return err
 	}
 
-	if err := currentScene.OnMessage(message); err != nil {
+	if err := scenes.HandlersOf(currentScene).Dispatch(message); err != nil {
 		return err
 	}
 
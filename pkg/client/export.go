@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/client/recording"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// ExportReplay downloads the latest replay of a host's game and writes it to path as an asciinema
+// cast, or as an animated GIF if path ends in .gif, without starting the interactive client.
+func ExportReplay(opts Options, host, path string) error {
+	c, finish, err := setupWebsocket(opts.Addr, opts.Local, messages.Hello{Version: opts.Version})
+	if err != nil {
+		return err
+	}
+	defer finish()
+
+	if err := c.WriteJSON(messages.Wrapper{Message: messages.GetReplay{Host: host}}); err != nil {
+		return err
+	}
+
+	if err := c.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return err
+	}
+
+	var replay *messages.Replay
+	for replay == nil {
+		var wrapper messages.Wrapper
+		if err := c.ReadJSON(&wrapper); err != nil {
+			return fmt.Errorf("failed to read replay: %w", err)
+		}
+
+		switch m := wrapper.Message.(type) {
+		case *messages.Replay:
+			replay = m
+		case *messages.Error:
+			return errors.New(m.Error)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	write := recording.WriteCast
+	if strings.EqualFold(filepath.Ext(path), ".gif") {
+		write = recording.WriteGIF
+	}
+
+	if err := write(f, *replay, recording.DefaultFrameDelay); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
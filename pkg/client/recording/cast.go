@@ -0,0 +1,179 @@
+// Package recording renders finished games as terminal recordings or animated GIFs that can be
+// shared.
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// DefaultFrameDelay is the time each move is shown for when playing back a recording.
+const DefaultFrameDelay = 800 * time.Millisecond
+
+const (
+	clearScreen = "\x1b[2J\x1b[H"
+	resetColor  = "\x1b[0m"
+)
+
+var playerColors = map[common.Disk]string{common.Player1: "\x1b[35m", common.Player2: "\x1b[32m"}
+
+// castHeader is the first line of an asciinema v2 cast file.
+// See https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md.
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// WriteCast writes a replay to w as an asciinema cast, showing each move of the game for
+// frameDelay. The final board is held for a few extra frames so that the result can be seen.
+func WriteCast(w io.Writer, replay messages.Replay, frameDelay time.Duration) error {
+	boards, err := replayBoards(replay)
+	if err != nil {
+		return err
+	}
+	size := boards[0].Size
+
+	host, opponent := strings.ToUpper(replay.Host), strings.ToUpper(replay.Opponent)
+	if opponent == "" {
+		opponent = "AI"
+	}
+
+	// The host played black unless the replay says otherwise.
+	p1Name, p2Name := host, opponent
+	if replay.HostDisk == common.Player2 {
+		p1Name, p2Name = opponent, host
+	}
+
+	header := castHeader{
+		Version:   2,
+		Width:     size*4 + 1,
+		Height:    size*2 + 5,
+		Timestamp: time.Now().Unix(),
+		Title:     fmt.Sprintf("Othelgo: %s vs. %s", host, opponent),
+	}
+	if width := len(header.Title) + 1; width > header.Width {
+		header.Width = width
+	}
+
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	for i, board := range boards {
+		lastMove := [2]int{-1, -1}
+		if i > 0 {
+			lastMove = replay.Moves[i-1]
+		}
+
+		frame := renderFrame(board, lastMove, header.Title, p1Name, p2Name, i, len(boards)-1)
+		event := []interface{}{frameDelay.Seconds() * float64(i), "o", frame}
+
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	// An empty event at the end keeps the final board on screen before the recording loops.
+	end := []interface{}{frameDelay.Seconds() * float64(len(boards)+3), "o", ""}
+
+	return enc.Encode(end)
+}
+
+// replayBoards returns the board before the first move of a replay, and after each move.
+func replayBoards(replay messages.Replay) ([]common.Board, error) {
+	size := replay.BoardSize
+	if size == 0 {
+		size = common.DefaultBoardSize
+	}
+
+	boards, err := common.ReplayMoves(common.NewBoardWithOpening(size, replay.Opening), replay.Moves)
+	if err != nil {
+		return nil, fmt.Errorf("replay is corrupt: %w", err)
+	}
+
+	return boards, nil
+}
+
+// Cast writes what a terminal shows over time as an asciinema cast, such as the frames of a
+// scripted client.
+type Cast struct {
+	enc *json.Encoder
+}
+
+// NewCast writes the header of a cast of a terminal of a size to w. The header has no timestamp,
+// so that the same frames always make the same cast.
+func NewCast(w io.Writer, width, height int, title string) (*Cast, error) {
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(castHeader{Version: 2, Width: width, Height: height, Title: title}); err != nil {
+		return nil, err
+	}
+
+	return &Cast{enc: enc}, nil
+}
+
+// Frame writes what the terminal shows at a time since the cast started.
+func (c *Cast) Frame(at time.Duration, data string) error {
+	return c.enc.Encode([]interface{}{at.Seconds(), "o", data})
+}
+
+func renderFrame(board common.Board, lastMove [2]int, title, p1Name, p2Name string, move, moves int) string {
+	var sb strings.Builder
+
+	writeLine := func(s string) {
+		sb.WriteString(s)
+		sb.WriteString("\r\n")
+	}
+
+	writeRule := func(left, middle, right string) {
+		writeLine(left + strings.Repeat("───"+middle, board.Size-1) + "───" + right)
+	}
+
+	sb.WriteString(clearScreen)
+	writeLine(title)
+	writeLine(fmt.Sprintf("Move %d/%d", move, moves))
+
+	for y := 0; y < board.Size; y++ {
+		if y == 0 {
+			writeRule("┌", "┬", "┐")
+		} else {
+			writeRule("├", "┼", "┤")
+		}
+
+		sb.WriteString("│")
+		for x := 0; x < board.Size; x++ {
+			left, right := " ", " "
+			if x == lastMove[0] && y == lastMove[1] {
+				left, right = "[", "]"
+			}
+
+			disk := " "
+			if player := board.Squares[x][y]; player != 0 {
+				disk = playerColors[player] + "●" + resetColor
+			}
+
+			sb.WriteString(left + disk + right + "│")
+		}
+		sb.WriteString("\r\n")
+	}
+
+	writeRule("└", "┴", "┘")
+
+	p1Score, p2Score := common.KeepScore(board)
+	sb.WriteString(fmt.Sprintf("%s●%s %s: %d  %s●%s %s: %d",
+		playerColors[common.Player1], resetColor, p1Name, p1Score,
+		playerColors[common.Player2], resetColor, p2Name, p2Score))
+
+	return sb.String()
+}
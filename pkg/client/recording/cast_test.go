@@ -0,0 +1,78 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestWriteCast(t *testing.T) {
+	replay := messages.Replay{Host: "flame", Moves: [][2]int{{2, 4}, {2, 5}}}
+
+	var buf bytes.Buffer
+	if err := WriteCast(&buf, replay, time.Second); err != nil {
+		t.Fatalf("WriteCast() error = %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	// A header, a frame for the starting board and each move, and a final empty event.
+	if len(lines) != 5 {
+		t.Fatalf("WriteCast() wrote %d lines, want 5", len(lines))
+	}
+
+	var header castHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("invalid header: %v", err)
+	}
+	if header.Version != 2 || header.Title != "Othelgo: FLAME vs. AI" {
+		t.Errorf("unexpected header %+v", header)
+	}
+
+	var event []interface{}
+	if err := json.Unmarshal([]byte(lines[3]), &event); err != nil {
+		t.Fatalf("invalid event: %v", err)
+	}
+	if event[0] != float64(2) || event[1] != "o" {
+		t.Errorf("unexpected event %v", event[:2])
+	}
+	if frame := event[2].(string); !strings.Contains(frame, "Move 2/2") || !strings.Contains(frame, "FLAME: 3") {
+		t.Errorf("unexpected frame %q", frame)
+	}
+}
+
+func TestWriteCastCorruptReplay(t *testing.T) {
+	replay := messages.Replay{Host: "flame", Moves: [][2]int{{0, 0}}}
+
+	if err := WriteCast(&bytes.Buffer{}, replay, time.Second); err == nil {
+		t.Error("WriteCast() expected error for illegal move")
+	}
+}
+
+func TestCast(t *testing.T) {
+	var buf bytes.Buffer
+
+	cast, err := NewCast(&buf, 100, 30, "Othelgo")
+	if err != nil {
+		t.Fatalf("NewCast() error = %v", err)
+	}
+	if err := cast.Frame(1500*time.Millisecond, "hello"); err != nil {
+		t.Fatalf("Frame() error = %v", err)
+	}
+
+	want := `{"version":2,"width":100,"height":30,"title":"Othelgo"}` + "\n" + `[1.5,"o","hello"]` + "\n"
+	if buf.String() != want {
+		t.Errorf("cast = %q, want %q", buf.String(), want)
+	}
+}
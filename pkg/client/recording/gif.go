@@ -0,0 +1,126 @@
+package recording
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+const (
+	// cellSize is the width and height in pixels of a square of the board in a GIF.
+	cellSize = 40
+
+	// scoreHeight is the height in pixels of the bar under the board that shows the score.
+	scoreHeight = 12
+)
+
+// The GIF palette. Black plays first, as on a real board.
+var gifPalette = color.Palette{
+	color.RGBA{0x1b, 0x5e, 0x20, 0xff}, // board
+	color.RGBA{0x0d, 0x2e, 0x10, 0xff}, // grid
+	color.RGBA{0x11, 0x11, 0x11, 0xff}, // black disk
+	color.RGBA{0xf5, 0xf5, 0xf5, 0xff}, // white disk
+	color.RGBA{0xff, 0xc1, 0x07, 0xff}, // last move
+}
+
+const (
+	gifBoard = iota
+	gifGrid
+	gifBlack
+	gifWhite
+	gifLastMove
+)
+
+var gifDisks = map[common.Disk]uint8{common.Player1: gifBlack, common.Player2: gifWhite}
+
+// WriteGIF writes a replay to w as an animated GIF, showing each move of the game for frameDelay.
+// The last move is marked, and a bar under the board shows the share of the disks each player
+// has. The final board is held for a few extra frames, like in WriteCast.
+func WriteGIF(w io.Writer, replay messages.Replay, frameDelay time.Duration) error {
+	boards, err := replayBoards(replay)
+	if err != nil {
+		return err
+	}
+
+	// GIF delays are in hundredths of a second.
+	delay := int(frameDelay / (10 * time.Millisecond))
+
+	anim := &gif.GIF{}
+
+	for i, board := range boards {
+		lastMove := [2]int{-1, -1}
+		if i > 0 {
+			lastMove = replay.Moves[i-1]
+		}
+
+		anim.Image = append(anim.Image, renderImage(board, lastMove))
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	anim.Delay[len(anim.Delay)-1] = delay * 4
+
+	return gif.EncodeAll(w, anim)
+}
+
+func renderImage(board common.Board, lastMove [2]int) *image.Paletted {
+	width := board.Size*cellSize + 1
+	img := image.NewPaletted(image.Rect(0, 0, width, width+scoreHeight), gifPalette)
+
+	for y := 0; y < width; y++ {
+		for x := 0; x < width; x++ {
+			if x%cellSize == 0 || y%cellSize == 0 {
+				img.SetColorIndex(x, y, gifGrid)
+			}
+		}
+	}
+
+	for y := 0; y < board.Size; y++ {
+		for x := 0; x < board.Size; x++ {
+			player := board.Squares[x][y]
+			if player == 0 {
+				continue
+			}
+
+			cx, cy := x*cellSize+cellSize/2, y*cellSize+cellSize/2
+			fillCircle(img, cx, cy, cellSize/2-4, gifDisks[player])
+
+			if x == lastMove[0] && y == lastMove[1] {
+				fillCircle(img, cx, cy, cellSize/8, gifLastMove)
+			}
+		}
+	}
+
+	// The bar is black from the left for black's share of the disks, and white for the rest.
+	p1Score, p2Score := common.KeepScore(board)
+	split := width / 2
+	if total := p1Score + p2Score; total > 0 {
+		split = width * p1Score / total
+	}
+
+	for y := width; y < width+scoreHeight; y++ {
+		for x := 0; x < width; x++ {
+			if x < split {
+				img.SetColorIndex(x, y, gifBlack)
+			} else {
+				img.SetColorIndex(x, y, gifWhite)
+			}
+		}
+	}
+
+	return img
+}
+
+func fillCircle(img *image.Paletted, cx, cy, r int, index uint8) {
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if x*x+y*y <= r*r {
+				img.SetColorIndex(cx+x, cy+y, index)
+			}
+		}
+	}
+}
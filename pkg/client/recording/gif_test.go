@@ -0,0 +1,50 @@
+package recording
+
+import (
+	"bytes"
+	"image/gif"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestWriteGIF(t *testing.T) {
+	replay := messages.Replay{Host: "flame", BoardSize: 6, Moves: [][2]int{{1, 3}, {1, 4}}}
+
+	var buf bytes.Buffer
+	if err := WriteGIF(&buf, replay, time.Second); err != nil {
+		t.Fatalf("WriteGIF() error = %v", err)
+	}
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("invalid GIF: %v", err)
+	}
+
+	// A frame for the starting board and each move, with the final board held longer.
+	if len(anim.Image) != 3 {
+		t.Fatalf("WriteGIF() wrote %d frames, want 3", len(anim.Image))
+	}
+	if want := []int{100, 100, 400}; !reflect.DeepEqual(anim.Delay, want) {
+		t.Errorf("delays = %v, want %v", anim.Delay, want)
+	}
+
+	if bounds := anim.Image[0].Bounds(); bounds.Dx() != 6*cellSize+1 || bounds.Dy() != 6*cellSize+1+scoreHeight {
+		t.Errorf("unexpected frame size %v", bounds)
+	}
+
+	// The first move is at the center of the second column, and is marked.
+	if got := anim.Image[1].ColorIndexAt(1*cellSize+cellSize/2, 3*cellSize+cellSize/2); got != gifLastMove {
+		t.Errorf("last move color index = %d, want %d", got, gifLastMove)
+	}
+}
+
+func TestWriteGIFCorruptReplay(t *testing.T) {
+	replay := messages.Replay{Host: "flame", Moves: [][2]int{{0, 0}}}
+
+	if err := WriteGIF(&bytes.Buffer{}, replay, time.Second); err == nil {
+		t.Error("WriteGIF() expected error for illegal move")
+	}
+}
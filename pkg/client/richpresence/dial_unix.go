@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package richpresence
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// dial connects to the first Discord IPC socket that accepts a connection.
+func dial() (io.ReadWriteCloser, error) {
+	dir := "/tmp"
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if v := os.Getenv(env); v != "" {
+			dir = v
+			break
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		conn, err := net.Dial("unix", filepath.Join(dir, fmt.Sprintf("discord-ipc-%d", i)))
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no discord ipc socket found in %s", dir)
+}
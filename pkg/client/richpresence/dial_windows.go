@@ -0,0 +1,11 @@
+package richpresence
+
+import (
+	"errors"
+	"io"
+)
+
+// dial is not supported on Windows, where Discord uses named pipes.
+func dial() (io.ReadWriteCloser, error) {
+	return nil, errors.New("discord rich presence is not supported on windows")
+}
@@ -0,0 +1,284 @@
+// Package richpresence shows what the player is doing in their Discord profile, using the local
+// Discord client's IPC socket. Friends can join or watch the player's game from the profile.
+package richpresence
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Discord rejects activity updates sent more often than this.
+const updateInterval = 15 * time.Second
+
+const (
+	opHandshake = 0
+	opFrame     = 1
+)
+
+// Activity is what is shown on the player's Discord profile.
+type Activity struct {
+	Details string
+	State   string
+
+	// PartyID identifies the game, and PartySize and PartyMax are how many players are in it out
+	// of how many it can have. Discord only shows the join button for a party.
+	PartyID   string
+	PartySize int
+	PartyMax  int
+
+	// JoinSecret and SpectateSecret are given back to whoever presses the join or spectate button
+	// on the profile, if set.
+	JoinSecret     string
+	SpectateSecret string
+}
+
+// Invite is a secret from an activity, which the player's Discord client hands over after the
+// player pressed the join or spectate button on a friend's profile.
+type Invite struct {
+	Secret   string
+	Spectate bool
+}
+
+// Client sends activity updates to Discord. Updates are throttled, and only the most recent
+// activity is sent.
+type Client struct {
+	conn    io.ReadWriteCloser
+	start   time.Time
+	invites chan Invite
+
+	// writeMu keeps frames written by the update loop and by the reader from interleaving.
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending *Activity
+	last    Activity
+	closed  chan struct{}
+}
+
+// Connect connects to the local Discord client using the application ID of a Discord application.
+func Connect(appID string) (*Client, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to discord: %w", err)
+	}
+
+	c := newClient(conn)
+
+	if err := c.send(opHandshake, map[string]interface{}{"v": 1, "client_id": appID}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("discord handshake failed: %w", err)
+	}
+
+	go c.read()
+	go c.loop()
+
+	return c, nil
+}
+
+func newClient(conn io.ReadWriteCloser) *Client {
+	return &Client{conn: conn, start: time.Now(), invites: make(chan Invite, 1), closed: make(chan struct{})}
+}
+
+// Invites receives the secrets of the games that the player asked to join or watch from Discord.
+func (c *Client) Invites() <-chan Invite {
+	return c.invites
+}
+
+// SetActivity queues an activity update. It does nothing if the activity has not changed.
+func (c *Client) SetActivity(activity Activity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if activity == c.last {
+		c.pending = nil
+		return
+	}
+
+	c.pending = &activity
+}
+
+// Close disconnects from Discord, which clears the activity.
+func (c *Client) Close() error {
+	close(c.closed)
+	return c.conn.Close()
+}
+
+func (c *Client) loop() {
+	ticker := time.NewTicker(updateInterval)
+	defer ticker.Stop()
+
+	for {
+		c.flush()
+
+		select {
+		case <-ticker.C:
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Client) flush() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	if pending != nil {
+		c.last = *pending
+	}
+	c.mu.Unlock()
+
+	if pending == nil {
+		return
+	}
+
+	activity := map[string]interface{}{
+		"details":    pending.Details,
+		"state":      pending.State,
+		"timestamps": map[string]interface{}{"start": c.start.Unix()},
+	}
+
+	if pending.PartyID != "" {
+		activity["party"] = map[string]interface{}{"id": pending.PartyID, "size": []int{pending.PartySize, pending.PartyMax}}
+	}
+
+	secrets := make(map[string]interface{})
+	if pending.JoinSecret != "" {
+		secrets["join"] = pending.JoinSecret
+	}
+	if pending.SpectateSecret != "" {
+		secrets["spectate"] = pending.SpectateSecret
+	}
+	if len(secrets) > 0 {
+		activity["secrets"] = secrets
+	}
+
+	err := c.command("SET_ACTIVITY", map[string]interface{}{"pid": os.Getpid(), "activity": activity}, "")
+	if err != nil {
+		log.Printf("Failed to update discord activity: %v", err)
+	}
+}
+
+// command sends a command, or subscribes to an event if evt is set.
+func (c *Client) command(cmd string, args interface{}, evt string) error {
+	frame := map[string]interface{}{
+		"cmd":   cmd,
+		"nonce": fmt.Sprint(time.Now().UnixNano()),
+		"args":  args,
+	}
+	if evt != "" {
+		frame["evt"] = evt
+	}
+
+	return c.send(opFrame, frame)
+}
+
+// frame is a command response or an event sent by Discord.
+type frame struct {
+	Cmd  string `json:"cmd"`
+	Evt  string `json:"evt"`
+	Data struct {
+		Secret string `json:"secret"`
+		User   struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"user"`
+	} `json:"data"`
+}
+
+// read handles the frames sent by Discord until the connection is closed. Discord sends READY in
+// answer to the handshake, after which the client can subscribe to the join and spectate buttons.
+func (c *Client) read() {
+	for {
+		data, err := c.receive()
+		if err != nil {
+			select {
+			case <-c.closed:
+			default:
+				log.Printf("Stopped reading from discord: %v", err)
+			}
+			return
+		}
+
+		log.Printf("Discord response: %s", data)
+
+		var f frame
+		if err := json.Unmarshal(data, &f); err != nil {
+			log.Printf("Failed to decode discord response: %v", err)
+			continue
+		}
+
+		if err := c.handle(f); err != nil {
+			log.Printf("Failed to handle discord %s: %v", f.Evt, err)
+		}
+	}
+}
+
+func (c *Client) handle(f frame) error {
+	if f.Cmd != "DISPATCH" {
+		return nil
+	}
+
+	switch f.Evt {
+	case "READY":
+		for _, evt := range []string{"ACTIVITY_JOIN", "ACTIVITY_SPECTATE", "ACTIVITY_JOIN_REQUEST"} {
+			if err := c.command("SUBSCRIBE", map[string]interface{}{}, evt); err != nil {
+				return err
+			}
+		}
+
+	case "ACTIVITY_JOIN_REQUEST":
+		// Games waiting for an opponent are listed in the lounge for anybody to join anyway, so
+		// friends who ask are let in without asking the player.
+		log.Printf("Inviting %s from discord", f.Data.User.Username)
+		return c.command("SEND_ACTIVITY_JOIN_INVITE", map[string]interface{}{"user_id": f.Data.User.ID}, "")
+
+	case "ACTIVITY_JOIN", "ACTIVITY_SPECTATE":
+		select {
+		case c.invites <- Invite{Secret: f.Data.Secret, Spectate: f.Evt == "ACTIVITY_SPECTATE"}:
+		default:
+			log.Printf("Dropped discord %s because the last one was not handled yet", f.Evt)
+		}
+	}
+
+	return nil
+}
+
+// send writes a frame. Responses are handled by read.
+func (c *Client) send(opcode uint32, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header[0:4], opcode)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(data)))
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err = c.conn.Write(append(header, data...))
+
+	return err
+}
+
+// receive reads the payload of a frame.
+func (c *Client) receive() ([]byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.LittleEndian.Uint32(header[4:8]))
+	if _, err := io.ReadFull(c.conn, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,73 @@
+package richpresence
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDiscord is the Discord client's end of the IPC socket.
+type fakeDiscord struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (d *fakeDiscord) write(payload string) {
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header[0:4], opFrame)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(payload)))
+	_, err := d.conn.Write(append(header, payload...))
+	require.NoError(d.t, err)
+}
+
+func (d *fakeDiscord) read() map[string]interface{} {
+	header := make([]byte, 8)
+	_, err := io.ReadFull(d.conn, header)
+	require.NoError(d.t, err)
+
+	data := make([]byte, binary.LittleEndian.Uint32(header[4:8]))
+	_, err = io.ReadFull(d.conn, data)
+	require.NoError(d.t, err)
+
+	var payload map[string]interface{}
+	require.NoError(d.t, json.Unmarshal(data, &payload))
+
+	return payload
+}
+
+func TestInvites(t *testing.T) {
+	clientConn, discordConn := net.Pipe()
+	discord := &fakeDiscord{t: t, conn: discordConn}
+
+	c := newClient(clientConn)
+	go c.read()
+	defer c.Close()
+
+	// The client subscribes to the buttons once Discord is ready.
+	discord.write(`{"cmd":"DISPATCH","evt":"READY","data":{}}`)
+	for _, evt := range []string{"ACTIVITY_JOIN", "ACTIVITY_SPECTATE", "ACTIVITY_JOIN_REQUEST"} {
+		frame := discord.read()
+		assert.Equal(t, "SUBSCRIBE", frame["cmd"])
+		assert.Equal(t, evt, frame["evt"])
+	}
+
+	// Friends who ask to join are let in.
+	discord.write(`{"cmd":"DISPATCH","evt":"ACTIVITY_JOIN_REQUEST","data":{"user":{"id":"42","username":"andy"}}}`)
+	frame := discord.read()
+	assert.Equal(t, "SEND_ACTIVITY_JOIN_INVITE", frame["cmd"])
+	assert.Equal(t, map[string]interface{}{"user_id": "42"}, frame["args"])
+
+	discord.write(`{"cmd":"DISPATCH","evt":"ACTIVITY_SPECTATE","data":{"secret":"s3cret"}}`)
+	select {
+	case invite := <-c.Invites():
+		assert.Equal(t, Invite{Secret: "s3cret", Spectate: true}, invite)
+	case <-time.After(time.Second):
+		t.Fatal("no invite")
+	}
+}
@@ -0,0 +1,118 @@
+package scenes
+
+import (
+	"fmt"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// The compact board is drawn with one character per square and no outline, so that even the largest
+// board fits in a 10x10 area. It is used when the terminal is too small for the full board, such as
+// in a tmux pane or a status bar, or always when Compact is set.
+
+// Compact is true when the compact board should be drawn regardless of the terminal size.
+var Compact bool
+
+// compactOrigin returns the anchor of the top-left square of a compact board in the middle of the
+// terminal, leaving two rows below the board.
+func compactOrigin(size int) draw.Anchor {
+	width, height := draw.Size()
+	return draw.Offset(draw.Origin, (width-size)/2, (height-size-2)/2)
+}
+
+// drawCompactBoard draws a board from its top-left square, each square cellWidth columns apart.
+func drawCompactBoard(origin draw.Anchor, board common.Board, o orientation, g glyphs, cellWidth int) {
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			disk := board.Squares[x][y]
+			color := draw.Normal
+			if disk != 0 {
+				color = playerColors[disk]
+			}
+			draw.Draw(compactSquare(origin, board.Size, o, x, y, cellWidth), color, g.miniature[disk])
+		}
+	}
+}
+
+// compactSquare returns the anchor of a square of a compact board.
+func compactSquare(origin draw.Anchor, size int, o orientation, x, y, cellWidth int) draw.Anchor {
+	vx, vy := o.transform(size, x, y)
+	return draw.Offset(origin, vx*cellWidth, vy)
+}
+
+// drawCompact draws the game on a compact board, with the score and the latest notice below it.
+func (g *Game) drawCompact() {
+	size := g.size()
+	origin := compactOrigin(size)
+	glyphs := g.disks.glyphs()
+
+	drawCompactBoard(origin, g.board, g.orientation, glyphs, 1)
+
+	if g.player == g.whoseTurn && g.alert() == "" && !g.mustPass {
+		for _, move := range common.LegalMoves(g.board, g.player) {
+			draw.Draw(compactSquare(origin, size, g.orientation, move[0], move[1], 1), playerColors[g.player], glyphs.legalMove)
+		}
+	}
+
+	if g.p1Score+g.p2Score > 4 && g.prevX >= 0 && g.prevY >= 0 {
+		disk := g.board.Squares[g.prevX][g.prevY]
+		draw.Draw(compactSquare(origin, size, g.orientation, g.prevX, g.prevY, 1), draw.Inverted, glyphs.miniature[disk])
+	}
+
+	// The score of the player whose turn it is is highlighted.
+	scoreColors := map[common.Disk]draw.Color{1: playerColors[1], 2: playerColors[2]}
+	if !common.GameOver(g.board) {
+		scoreColors[g.whoseTurn] = draw.Inverted
+	}
+	p1Score := fmt.Sprintf("%s%d", glyphs.miniature[1], g.p1Score)
+	draw.Draw(draw.Offset(origin, 0, size), scoreColors[1], p1Score)
+	draw.Draw(draw.Offset(origin, len([]rune(p1Score))+1, size), scoreColors[2], fmt.Sprintf("%s%d", glyphs.miniature[2], g.p2Score))
+
+	var status string
+	switch {
+	case g.alert() != "":
+		status = g.alert()
+	case g.undoRequest != "":
+		status = "[Y/N] TAKEBACK?"
+	case g.mustPass && g.player == g.whoseTurn:
+		status = g.passPrompt(true)
+	case g.notice != "":
+		status = g.notice
+	case g.won():
+		status = g.winText()
+	}
+	draw.Draw(draw.Offset(origin, 0, size+1), draw.Normal, status)
+
+	if common.GameOver(g.board) || g.whoseTurn != g.player || g.alert() != "" {
+		draw.HideCursor()
+	} else {
+		// SetCursor draws a row above its anchor.
+		draw.SetCursor(draw.Offset(origin, g.curSquareX, g.curSquareY+1))
+	}
+}
+
+func (g *Game) minSize() (width, height int) {
+	return g.size(), g.size() + 2
+}
+
+// drawCompact draws the replay on a compact board, with the move number below it.
+func (r *Replay) drawCompact() {
+	board := r.boards[r.step]
+	status := fmt.Sprintf("MOVE %d/%d", r.step, len(r.boards)-1)
+	if r.analysis != nil {
+		board = r.analysis.board
+		status = fmt.Sprintf("ANALYSIS FROM %d", r.analysis.start)
+	}
+
+	origin := compactOrigin(r.size)
+	drawCompactBoard(origin, board, r.orientation, r.disks.glyphs(), 1)
+	draw.Draw(draw.Offset(origin, 0, r.size), draw.Normal, status)
+}
+
+func (r *Replay) minSize() (width, height int) {
+	if r.size == 0 {
+		return common.DefaultBoardSize, common.DefaultBoardSize + 2
+	}
+	return r.size, r.size + 2
+}
@@ -39,7 +39,7 @@ func (c *confetti) tick() {
 		p.x = p.x - 1 + rand.Intn(3) //nolint:gosec
 	}
 
-	width, height := termbox.Size()
+	width, height := draw.Size()
 
 	// Destroy off-screen paper.
 	oldPaper := *c
@@ -0,0 +1,413 @@
+package scenes
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+const (
+	// maxWatchedGames is the number of games that fit on the dashboard at once.
+	maxWatchedGames = 4
+
+	// kibitzLines is the number of lines of kibitz shown about the focused game.
+	kibitzLines = 5
+)
+
+// Dashboard shows up to four live games at once, such as for watching a tournament. Each game is
+// a separate spectate subscription, and one board at a time has focus and shows its details,
+// including what the spectators are saying about it.
+type Dashboard struct {
+	scene
+	nickname  string
+	games     []*watchedGame
+	focus     int
+	adding    bool
+	kibitzing bool
+	draft     string
+	notice    string
+
+	// invited is a game to watch as soon as the dashboard starts, such as from a Discord invite.
+	invited string
+
+	// glyphs draw the miniature boards, which are too small for full-size disks.
+	glyphs glyphs
+}
+
+type watchedGame struct {
+	host   string
+	update *messages.SpectatorUpdate
+	rules  *messages.Rules
+	kibitz []messages.KibitzLine
+}
+
+func (d *Dashboard) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
+	if err := d.scene.Setup(changeScene, sendMessage); err != nil {
+		return err
+	}
+
+	d.glyphs = loadDiskStyle().glyphs()
+
+	return d.add(d.invited)
+}
+
+func (d *Dashboard) RegisterHandlers(h *Handlers) {
+	h.OnSpectatorUpdate(func(m *messages.SpectatorUpdate) error {
+		for _, g := range d.games {
+			if g.host == m.Host {
+				// Games that end early are sent without a board, so keep showing the last one.
+				if m.Board.Size == 0 && g.update != nil {
+					m.Board, m.Opponent = g.update.Board, g.update.Opponent
+					m.P1Score, m.P2Score = g.update.P1Score, g.update.P2Score
+				}
+				g.update = m
+				if m.Rules != nil {
+					g.rules = m.Rules
+				}
+			}
+		}
+		return nil
+	})
+
+	h.OnKibitz(func(m *messages.Kibitz) error {
+		for _, g := range d.games {
+			if g.host == m.Host {
+				g.kibitz = append(g.kibitz, m.Lines...)
+				if len(g.kibitz) > kibitzLines {
+					g.kibitz = g.kibitz[len(g.kibitz)-kibitzLines:]
+				}
+			}
+		}
+		return nil
+	})
+
+	h.OnError(func(m *messages.Error) error {
+		d.notice = strings.ToUpper(m.Error)
+		// The error is about the most recently added game if it never got an update.
+		if i := len(d.games) - 1; i >= 0 && d.games[i].update == nil {
+			d.remove(i)
+		}
+		return nil
+	})
+}
+
+func (d *Dashboard) OnConnectionStatus(status ConnectionStatus) error {
+	if status != Connected {
+		return nil
+	}
+
+	// Subscriptions don't survive the connection, so spectate the games again. The kibitz backlog
+	// is sent again too.
+	for _, g := range d.games {
+		g.kibitz = nil
+		if err := d.SendMessage(messages.Spectate{Host: g.host, Nickname: d.nickname}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Dashboard) OnTerminalEvent(event termbox.Event) error {
+	if d.adding {
+		return d.onAddingEvent(event)
+	}
+
+	if d.kibitzing {
+		return d.onKibitzingEvent(event)
+	}
+
+	if event.Key == termbox.KeyTab && len(d.games) > 0 {
+		d.focus = (d.focus + 1) % len(d.games)
+		return nil
+	}
+
+	// The boards are laid out two by two, so moving sideways or up and down changes focus in the grid.
+	dx, dy := getDirectionPressed(event)
+	if next := d.focus + dx + dy*2; (dx != 0 || dy != 0) && next >= 0 && next < len(d.games) {
+		d.focus = next
+	}
+
+	switch unicode.ToUpper(event.Ch) {
+	case 'N':
+		if len(d.games) < maxWatchedGames {
+			d.adding = true
+			d.notice = ""
+		}
+	case 'K':
+		if len(d.games) > 0 {
+			d.kibitzing = true
+			d.notice = ""
+		}
+	case 'X':
+		if len(d.games) > 0 {
+			host := d.games[d.focus].host
+			d.remove(d.focus)
+			return d.SendMessage(messages.StopSpectating{Host: host})
+		}
+	case 'M':
+		if err := d.stopAll(); err != nil {
+			return err
+		}
+		return d.ChangeScene(&Menu{nickname: d.nickname})
+	}
+
+	return nil
+}
+
+func (d *Dashboard) onAddingEvent(event termbox.Event) error {
+	switch event.Key {
+	case termbox.KeyTab:
+		d.adding = false
+		d.draft = ""
+	case termbox.KeyEnter:
+		host := strings.ToLower(strings.TrimSpace(d.draft))
+		d.adding = false
+		d.draft = ""
+		return d.add(host)
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if d.draft != "" {
+			runes := []rune(d.draft)
+			d.draft = string(runes[:len(runes)-1])
+		}
+	case termbox.KeySpace:
+		if len(d.draft) < maxNicknameLen {
+			d.draft += " "
+		}
+	default:
+		if (unicode.IsLetter(event.Ch) || unicode.IsDigit(event.Ch)) && len(d.draft) < maxNicknameLen {
+			d.draft += string(event.Ch)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dashboard) onKibitzingEvent(event termbox.Event) error {
+	switch event.Key {
+	case termbox.KeyTab:
+		d.kibitzing = false
+		d.draft = ""
+	case termbox.KeyEnter:
+		text := strings.TrimSpace(d.draft)
+		d.kibitzing = false
+		d.draft = ""
+		if text == "" || len(d.games) == 0 {
+			return nil
+		}
+		return d.SendMessage(messages.SendKibitz{Host: d.games[d.focus].host, Text: text})
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if d.draft != "" {
+			runes := []rune(d.draft)
+			d.draft = string(runes[:len(runes)-1])
+		}
+	case termbox.KeySpace:
+		d.draft += " "
+	default:
+		if unicode.IsPrint(event.Ch) && len(d.draft) < maxChatLen {
+			d.draft += string(event.Ch)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dashboard) add(host string) error {
+	if host == "" {
+		return nil
+	}
+
+	for _, g := range d.games {
+		if g.host == host {
+			d.notice = fmt.Sprintf("ALREADY WATCHING %s", strings.ToUpper(host))
+			return nil
+		}
+	}
+
+	d.games = append(d.games, &watchedGame{host: host})
+	d.focus = len(d.games) - 1
+
+	return d.SendMessage(messages.Spectate{Host: host, Nickname: d.nickname})
+}
+
+func (d *Dashboard) remove(i int) {
+	d.games = append(d.games[:i], d.games[i+1:]...)
+	if d.focus >= len(d.games) && d.focus > 0 {
+		d.focus--
+	}
+}
+
+func (d *Dashboard) stopAll() error {
+	for _, g := range d.games {
+		if err := d.SendMessage(messages.StopSpectating{Host: g.host}); err != nil {
+			return err
+		}
+	}
+
+	d.games = nil
+
+	return nil
+}
+
+func (d *Dashboard) OnQuit() {
+	_ = d.stopAll()
+}
+
+func (d *Dashboard) HasFreeKeyboardInput() bool {
+	return d.adding || d.kibitzing
+}
+
+func (d *Dashboard) Describe() (details, state string) {
+	return "Watching Othelgo", fmt.Sprintf("Watching %d games", len(d.games))
+}
+
+func (d *Dashboard) Draw() {
+	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Your name is %s!", strings.ToUpper(d.nickname)))
+	draw.Draw(draw.BotRight, draw.Normal, "[M] MENU  [Q] QUIT")
+
+	switch {
+	case d.adding:
+		input := "HOST TO WATCH: " + strings.ToUpper(d.draft)
+		draw.Draw(draw.BotLeft, draw.Normal, input)
+		draw.SetCursor(draw.Offset(draw.BotLeft, len([]rune(input)), 1))
+		draw.Draw(draw.Offset(draw.BotLeft, 0, -1), draw.Normal, "[ENTER] WATCH  [TAB] CANCEL")
+	case d.kibitzing:
+		input := "KIBITZ: " + d.draft
+		draw.Draw(draw.BotLeft, draw.Normal, input)
+		draw.SetCursor(draw.Offset(draw.BotLeft, len([]rune(input)), 1))
+		draw.Draw(draw.Offset(draw.BotLeft, 0, -1), draw.Normal, "[ENTER] SEND  [TAB] CANCEL")
+	case d.notice != "":
+		draw.Draw(draw.BotLeft, draw.Normal, d.notice)
+	case len(d.games) == 0:
+		draw.Draw(draw.BotLeft, draw.Normal, "[N] WATCH A GAME")
+	case len(d.games) < maxWatchedGames:
+		draw.Draw(draw.BotLeft, draw.Normal, "[N] WATCH A GAME  [K] KIBITZ")
+	default:
+		draw.Draw(draw.BotLeft, draw.Normal, "[K] KIBITZ")
+	}
+
+	if len(d.games) == 0 {
+		draw.Draw(draw.CenterTop, draw.Normal, "NOT WATCHING ANY GAMES")
+		return
+	}
+
+	draw.Draw(draw.TopLeft, draw.Normal, "[TAB] FOCUS  [X] STOP WATCHING")
+
+	for i, g := range d.games {
+		d.drawMiniature(i, g)
+	}
+
+	d.drawDetails(d.games[d.focus])
+}
+
+// Each miniature board cell is two columns wide, and the grid of boards is centered with a gap
+// between the boards.
+const (
+	miniatureCellWidth = 2
+	miniatureGap       = 4
+	miniatureMaxSize   = common.MaxBoardSize
+)
+
+// miniatureOrigin returns the anchor of the top-left square of the i-th miniature board.
+func miniatureOrigin(i int) draw.Anchor {
+	width := miniatureMaxSize*miniatureCellWidth + miniatureGap
+	// Leave a row for the title above the board and the score below it.
+	height := miniatureMaxSize + 2
+	col, row := i%2, i/2
+	return draw.Offset(draw.CenterRight, (col-1)*width+miniatureGap/2, (row-1)*height+1)
+}
+
+func (d *Dashboard) drawMiniature(i int, g *watchedGame) {
+	origin := miniatureOrigin(i)
+
+	titleColor := draw.Normal
+	if i == d.focus {
+		titleColor = draw.Inverted
+	}
+
+	title := strings.ToUpper(g.host)
+	if g.update != nil && g.update.Opponent != "" {
+		title += " VS " + strings.ToUpper(g.update.Opponent)
+	}
+	draw.Draw(draw.Offset(origin, 0, -1), titleColor, " "+truncate(title, miniatureMaxSize*miniatureCellWidth-2)+" ")
+
+	if g.update == nil {
+		draw.Draw(draw.Offset(origin, 1, 1), draw.Normal, "LOADING...")
+		return
+	}
+
+	board := g.update.Board
+	drawCompactBoard(draw.Offset(origin, 1, 0), board, orientationNormal, d.glyphs, miniatureCellWidth)
+
+	status := fmt.Sprintf("%d - %d", g.update.P1Score, g.update.P2Score)
+	if g.update.Over {
+		status += " OVER"
+	}
+	draw.Draw(draw.Offset(origin, 1, board.Size), draw.Normal, status)
+}
+
+// drawDetails describes the focused game beside the boards.
+func (d *Dashboard) drawDetails(g *watchedGame) {
+	anchor := draw.Offset(draw.MiddleLeft, 0, -3)
+	title := strings.ToUpper(g.host)
+	if g.rules != nil {
+		title += " (" + strings.ToUpper(g.rules.Preset) + ")"
+		if g.rules.Scoring == messages.ScoringFewestDisks {
+			title += " ANTI"
+		}
+	}
+	draw.Draw(anchor, draw.Normal, title)
+
+	if g.update == nil {
+		return
+	}
+
+	u := g.update
+	opponent := "AI"
+	if u.Opponent != "" {
+		opponent = strings.ToUpper(u.Opponent)
+	}
+
+	p1Name, p2Name := strings.ToUpper(u.Host), opponent
+	if u.HostDisk == common.Player2 {
+		p1Name, p2Name = p2Name, p1Name
+	}
+
+	draw.Draw(draw.Offset(anchor, 0, 1), playerColors[1], fmt.Sprintf("%s %s %d", d.glyphs.miniature[1], p1Name, u.P1Score))
+	draw.Draw(draw.Offset(anchor, 0, 2), playerColors[2], fmt.Sprintf("%s %s %d", d.glyphs.miniature[2], p2Name, u.P2Score))
+
+	switch {
+	case u.Over && u.Message != "":
+		draw.Draw(draw.Offset(anchor, 0, 4), draw.Normal, truncate(strings.ToUpper(u.Message), 20))
+	case u.Over:
+		// In anti-reversi, the player with fewer disks won.
+		p1Score, p2Score := u.P1Score, u.P2Score
+		if g.rules != nil && g.rules.Scoring == messages.ScoringFewestDisks {
+			p1Score, p2Score = p2Score, p1Score
+		}
+		draw.Draw(draw.Offset(anchor, 0, 4), draw.Normal, winnerText(p1Name, p2Name, p1Score, p2Score))
+	case u.Player != 0:
+		draw.Draw(draw.Offset(anchor, 0, 4), draw.Normal, "LAST: "+squareName([2]int{u.X, u.Y}))
+	}
+
+	for i, line := range g.kibitz {
+		draw.Draw(draw.Offset(anchor, 0, 6+i), draw.Normal, truncate(strings.ToUpper(line.Nickname)+": "+line.Text, 20))
+	}
+}
+
+func winnerText(p1Name, p2Name string, p1Score, p2Score int) string {
+	switch {
+	case p1Score > p2Score:
+		return p1Name + " WON"
+	case p2Score > p1Score:
+		return p2Name + " WON"
+	default:
+		return "DRAW"
+	}
+}
@@ -0,0 +1,62 @@
+package scenes
+
+import (
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// flips animates the disks captured by the latest move. The frames of the glyphs are drawn in turn
+// over the captured disks, so that they appear to turn over from the color they were to the color
+// they are now. Each frame lasts one tick, and the disk is edge-on in the middle frame.
+type flips struct {
+	squares [][2]int
+	frame   int
+}
+
+// flipFrameCount is the number of frames of the animation, which is the same for all glyphs.
+const flipFrameCount = 3
+
+// newFlips returns the animation of the disks that changed color between two boards. Disks that
+// were placed or removed don't flip.
+func newFlips(before, after common.Board) flips {
+	var f flips
+	for x := 0; x < after.Size; x++ {
+		for y := 0; y < after.Size; y++ {
+			if b, a := before.Squares[x][y], after.Squares[x][y]; b != 0 && a != 0 && b != a {
+				f.squares = append(f.squares, [2]int{x, y})
+			}
+		}
+	}
+	return f
+}
+
+// tick moves the animation to its next frame. It returns false once the animation is over.
+func (f *flips) tick() bool {
+	if f.frame >= flipFrameCount || len(f.squares) == 0 {
+		return false
+	}
+
+	f.frame++
+	return true
+}
+
+// draw draws the current frame over the flipping disks of the board.
+func (f *flips) draw(board common.Board, o orientation, g glyphs) {
+	if f.frame >= flipFrameCount {
+		return
+	}
+
+	for _, square := range f.squares {
+		disk := board.Squares[square[0]][square[1]]
+
+		color := draw.Normal
+		switch middle := flipFrameCount / 2; {
+		case f.frame < middle:
+			color = playerColors[disk%2+1]
+		case f.frame > middle:
+			color = playerColors[disk]
+		}
+
+		draw.Draw(squareAnchor(board.Size, o, square[0], square[1]), color, g.flip[f.frame])
+	}
+}
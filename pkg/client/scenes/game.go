@@ -3,9 +3,12 @@ package scenes
 import (
 	"fmt"
 	"log"
+	"math"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/armsnyder/othelgo/pkg/ai"
 	"github.com/armsnyder/othelgo/pkg/common"
 
 	"github.com/nsf/termbox-go"
@@ -28,10 +31,51 @@ type Game struct {
 	opponent     string
 	whoseTurn    common.Disk
 	multiplayer  bool
+	hotseat      bool
+	offline      bool
+	mustPass     bool
+	passAt       time.Time
+	moves        [][2]int
 	difficulty   int
+	adaptive     bool
 	alertMessage string
 	prevX        int
 	prevY        int
+	preset       string
+	boardSize    int
+	color        string
+	variant      string
+	opening      string
+	rules        *messages.Rules
+	orientation  orientation
+	disks        diskStyle
+	reduceMotion bool
+	undoRequest  string
+	notice       string
+	p1Clock      time.Duration
+	p2Clock      time.Duration
+	clockUpdated time.Time
+
+	// matched is whether the server started the game by matchmaking, rather than it being hosted
+	// and joined.
+	matched bool
+
+	// ended is whether the server ended the game before the board was finished, such as on time.
+	ended bool
+
+	// startsAt is when the countdown to the start of a multiplayer game ends, by this terminal's
+	// clock. Moves wait until then.
+	startsAt time.Time
+
+	// lastSeq is the number of the last numbered message received for the game.
+	lastSeq int
+
+	// flips animates the disks captured by the latest move.
+	flips flips
+
+	// ratingRange is how far above or below the host an opponent may be rated to join a hosted
+	// game, or zero to let anybody join.
+	ratingRange int
 }
 
 func (g *Game) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
@@ -39,45 +83,196 @@ func (g *Game) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
 		return err
 	}
 
-	if g.multiplayer && g.player == 1 {
+	if g.hosting() && !g.matched {
 		g.alertMessage = "Waiting for opponent"
 	}
 
+	g.orientation = loadOrientation()
+	g.disks = loadDiskStyle()
+	g.reduceMotion = loadReducedMotion()
+
+	if g.hotseat {
+		// Hot-seat games are played entirely on this terminal, without the server.
+		g.setBoard(g.startingBoard(), common.Player1)
+		return nil
+	}
+
+	if g.matched {
+		return nil
+	}
+
 	var message interface{}
 	if g.multiplayer {
-		if g.player == 1 {
-			message = messages.HostGame{Nickname: g.nickname}
+		if g.hosting() {
+			message = messages.HostGame{Nickname: g.nickname, Preset: g.preset, BoardSize: g.boardSize, Color: g.color, Variant: g.variant, Opening: g.opening, RatingRange: g.ratingRange}
 		} else {
-			message = messages.JoinGame{Nickname: g.nickname, Host: g.host}
+			message = messages.JoinGame{Nickname: g.nickname, Host: g.host, Color: g.color}
 		}
 	} else {
-		message = messages.StartSoloGame{Nickname: g.nickname, Difficulty: g.difficulty}
+		message = messages.StartSoloGame{Nickname: g.nickname, Difficulty: g.difficulty, Adaptive: g.adaptive, BoardSize: g.boardSize, Variant: g.variant, Opening: g.opening}
 	}
 
 	return sendMessage(message)
 }
 
-func (g *Game) OnMessage(message interface{}) error {
-	switch m := message.(type) {
-	case *messages.UpdateBoard:
+func (g *Game) RegisterHandlers(h *Handlers) {
+	h.OnUpdateBoard(func(m *messages.UpdateBoard) error {
+		if m.Delta != nil {
+			// Lite updates only have the squares that changed. If they don't add up to the server's
+			// score, an update was missed, so the whole game is fetched instead.
+			m.Board = applyDelta(g.board, *m.Delta)
+			if p1, p2 := common.KeepScore(m.Board); g.board.Size == 0 || p1 != m.P1Score || p2 != m.P2Score {
+				return g.SendMessage(messages.GetGameState{Host: g.host})
+			}
+			g.moves = append(g.moves, [2]int{m.X, m.Y})
+		} else {
+			g.setMoves(m.Moves)
+		}
+		if m.X >= 0 && m.Y >= 0 {
+			g.startFlips(g.board, m.Board)
+		}
 		g.board = m.Board
 		g.whoseTurn = m.Player
 		g.p1Score = m.P1Score
 		g.p2Score = m.P2Score
+		g.p1Clock = time.Duration(m.P1Clock) * time.Millisecond
+		g.p2Clock = time.Duration(m.P2Clock) * time.Millisecond
+		g.clockUpdated = localTime(m.ClockTime)
+		g.notice = ""
+		if m.Rules != nil {
+			g.rules = m.Rules
+		}
 		if m.X >= 0 && m.Y >= 0 {
 			g.prevX = m.X
 			g.prevY = m.Y
+
+			// The server skips players who can't move, so the same player moving again means that
+			// the other player passed.
+			if mover := m.Board.Squares[m.X][m.Y]; mover == m.Player && !common.GameOver(m.Board) {
+				g.notice = fmt.Sprintf("%s HAS NO LEGAL MOVES AND PASSES", g.playerName(mover%2+1))
+			}
+		}
+		return nil
+	})
+
+	h.OnGameState(func(m *messages.GameState) error {
+		if m.Host != g.host {
+			return nil
+		}
+		g.board = m.Board
+		g.whoseTurn = m.Player
+		g.p1Score = m.P1Score
+		g.p2Score = m.P2Score
+		g.p1Clock = time.Duration(m.P1Clock) * time.Millisecond
+		g.p2Clock = time.Duration(m.P2Clock) * time.Millisecond
+		g.clockUpdated = localTime(m.ClockTime)
+		g.prevX, g.prevY = m.X, m.Y
+		g.rules = m.Rules
+		g.setMoves(m.MoveList)
+		return nil
+	})
+
+	h.OnRequestUndo(func(m *messages.RequestUndo) error {
+		g.undoRequest = m.Nickname
+		return nil
+	})
+
+	h.OnRespondUndo(func(m *messages.RespondUndo) error {
+		g.undoRequest = ""
+		if !m.Accept && m.Nickname != g.nickname {
+			g.notice = "TAKEBACK DECLINED"
 		}
-	case *messages.GameOver:
+		return nil
+	})
+
+	h.OnError(func(m *messages.Error) error {
+		g.notice = strings.ToUpper(m.Error)
+		return nil
+	})
+
+	h.OnGameOver(func(m *messages.GameOver) error {
 		g.alertMessage = m.Message
-	case *messages.Joined:
+		g.ended = true
+		return nil
+	})
+
+	h.OnDisputeFiled(func(*messages.DisputeFiled) error {
+		g.notice = "RESULT DISPUTED, AN ADMIN WILL REVIEW IT"
+		return nil
+	})
+
+	h.OnJoined(func(m *messages.Joined) error {
 		g.alertMessage = ""
+		g.notice = ""
 		if g.nickname == g.host {
 			g.opponent = m.Nickname
 		}
+		return nil
+	})
+
+	h.OnOpponentDisconnected(func(m *messages.OpponentDisconnected) error {
+		if m.Grace == 0 {
+			g.notice = fmt.Sprintf("%s DISCONNECTED. THEY CAN COME BACK TO THE GAME LATER", strings.ToUpper(m.Nickname))
+			return nil
+		}
+		g.notice = fmt.Sprintf("%s DISCONNECTED. WAITING %d SECONDS FOR THEM TO COME BACK", strings.ToUpper(m.Nickname), m.Grace)
+		return nil
+	})
+
+	h.OnGameStarted(func(m *messages.GameStarted) error {
+		// Servers that don't send this have the host play black.
+		if m.Host == g.host {
+			g.player = m.Disk
+		}
+		return nil
+	})
+
+	h.OnGameStarting(func(m *messages.GameStarting) error {
+		// The countdown is measured by the server's clock, which may not agree with this one.
+		if m.Host == g.host {
+			g.startsAt = time.Now().Add(time.Duration(m.StartsAt-m.ServerTime) * time.Millisecond)
+		}
+		return nil
+	})
+}
+
+func (g *Game) OnConnectionStatus(status ConnectionStatus) error {
+	if g.hotseat || g.offline {
+		return nil
 	}
 
-	return nil
+	// A solo game that the server never started is played against the local AI instead.
+	if status == Reconnecting && !g.multiplayer && g.board.Size == 0 {
+		g.offline = true
+		g.opponent += " (OFFLINE)"
+		g.setBoard(g.startingBoard(), common.Player1)
+		return nil
+	}
+
+	if status != Connected || common.GameOver(g.board) {
+		return nil
+	}
+
+	// The server kept our place in the game while we were away.
+	if err := g.SendMessage(messages.ResumeGame{Nickname: g.nickname, Host: g.host}); err != nil {
+		return err
+	}
+
+	// Servers that number their messages can send the ones that were missed while we were away.
+	if g.lastSeq == 0 {
+		return nil
+	}
+
+	return g.SendMessage(messages.ResumeFrom{Nickname: g.nickname, Host: g.host, LastSeq: g.lastSeq})
+}
+
+func (g *Game) OnSequence(seq int) bool {
+	if seq <= g.lastSeq {
+		return false
+	}
+
+	g.lastSeq = seq
+	return true
 }
 
 func (g *Game) OnTerminalEvent(event termbox.Event) error {
@@ -86,23 +281,84 @@ func (g *Game) OnTerminalEvent(event termbox.Event) error {
 		return g.ChangeScene(&Menu{nickname: g.nickname})
 	}
 
-	if g.alertMessage != "" {
+	if unicode.ToUpper(event.Ch) == 'R' && common.GameOver(g.board) && !g.local() {
+		g.OnQuit()
+		return g.ChangeScene(&Replay{nickname: g.nickname, host: g.host})
+	}
+
+	if unicode.ToUpper(event.Ch) == 'D' && g.multiplayer && (common.GameOver(g.board) || g.ended) {
+		return g.SendMessage(messages.DisputeResult{Nickname: g.nickname})
+	}
+
+	switch unicode.ToUpper(event.Ch) {
+	case 'O':
+		return g.rotate()
+	case 'G':
+		g.disks.shapes = !g.disks.shapes
+		return saveShapes(g.disks.shapes)
+	case 'F':
+		g.reduceMotion = !g.reduceMotion
+		g.confetti = nil
+		g.flips = flips{}
+		g.notice = "REDUCED MOTION OFF"
+		if g.reduceMotion {
+			g.notice = "REDUCED MOTION ON"
+		}
+		return saveReducedMotion(g.reduceMotion)
+	}
+
+	if g.alert() != "" {
 		return nil
 	}
 
+	switch unicode.ToUpper(event.Ch) {
+	case 'U':
+		if g.local() {
+			return g.undoLocal()
+		}
+		if !g.takebacks() {
+			g.notice = "TAKEBACKS ARE NOT ALLOWED IN THIS GAME"
+			return nil
+		}
+		if !common.GameOver(g.board) {
+			g.notice = ""
+			return g.SendMessage(messages.RequestUndo{Nickname: g.nickname, Host: g.host})
+		}
+	case 'Y', 'N':
+		if g.undoRequest != "" {
+			g.undoRequest = ""
+			return g.SendMessage(messages.RespondUndo{Nickname: g.nickname, Host: g.host, Accept: unicode.ToUpper(event.Ch) == 'Y'})
+		}
+	}
+
 	dx, dy := getDirectionPressed(event)
-	g.curSquareX = clamp(g.curSquareX+dx, 0, common.BoardSize)
-	g.curSquareY = clamp(g.curSquareY+dy, 0, common.BoardSize)
+	g.curSquareX = clamp(g.curSquareX+dx, 0, g.size())
+	g.curSquareY = clamp(g.curSquareY+dy, 0, g.size())
+
+	if event.Key == termbox.KeyEnter && g.whoseTurn == g.player && g.mustPass {
+		g.pass()
+		return nil
+	}
 
 	if event.Key == termbox.KeyEnter && g.whoseTurn == g.player {
-		board, updated := common.ApplyMove(g.board, g.curSquareX, g.curSquareY, g.player)
-		if updated {
+		x, y := g.orientation.transform(g.size(), g.curSquareX, g.curSquareY)
+		board, updated := common.ApplyMove(g.board, x, y, g.player)
+		if !updated && !common.GameOver(g.board) {
+			g.notice = "YOU CAN'T PLACE A DISK THERE"
+		} else if updated && g.local() {
+			g.moves = append(g.moves, [2]int{x, y})
+			g.prevX, g.prevY = x, y
+			g.startFlips(g.board, board)
+			g.setBoard(board, g.player%2+1)
+		} else if updated {
+			g.prevX, g.prevY = x, y
+			g.startFlips(g.board, board)
 			g.board = board
 			message := messages.PlaceDisk{
 				Nickname: g.nickname,
 				Host:     g.host,
-				X:        g.curSquareX,
-				Y:        g.curSquareY,
+				X:        x,
+				Y:        y,
 			}
 			if err := g.SendMessage(message); err != nil {
 				return err
@@ -113,22 +369,206 @@ func (g *Game) OnTerminalEvent(event termbox.Event) error {
 	return nil
 }
 
+// applyDelta returns the board with the squares changed by a move.
+func applyDelta(board common.Board, delta messages.BoardDelta) common.Board {
+	for _, square := range delta.Squares {
+		board.Squares[square[0]][square[1]] = delta.Disk
+	}
+	return board
+}
+
+// rotate switches to the next board orientation. The cursor stays on the same square.
+func (g *Game) rotate() error {
+	x, y := g.orientation.transform(g.size(), g.curSquareX, g.curSquareY)
+	g.orientation = g.orientation.next()
+	g.curSquareX, g.curSquareY = g.orientation.transform(g.size(), x, y)
+
+	return saveOrientation(g.orientation)
+}
+
+// local returns whether the game is played on this terminal, without the server.
+func (g *Game) local() bool {
+	return g.hotseat || g.offline
+}
+
+// setBoard shows a board that was played locally. In a hot-seat game, the terminal is passed to
+// the player whose turn it is. Unlike on the server, a player with no legal moves has to pass.
+func (g *Game) setBoard(board common.Board, whoseTurn common.Disk) {
+	g.board = board
+	g.whoseTurn = whoseTurn
+	g.mustPass = !common.GameOver(board) && !common.HasMoves(board, whoseTurn)
+	g.passAt = time.Time{}
+	if g.mustPass {
+		g.passAt = time.Now().Add(passDelay)
+	}
+	g.notice = ""
+	g.p1Score, g.p2Score = common.KeepScore(board)
+	if g.hotseat {
+		g.player = whoseTurn
+	}
+}
+
+// passDelay is how long a player with no legal moves in a local game has to see why before they
+// pass without pressing enter.
+const passDelay = 5 * time.Second
+
+// passPrompt tells a player with no legal moves in a local game that they must pass, and how long
+// until they do.
+func (g *Game) passPrompt(short bool) string {
+	// Round up, so that the countdown reaches zero as the player passes.
+	seconds := int((time.Until(g.passAt) + time.Second - 1) / time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+	if short {
+		return fmt.Sprintf("[ENTER] PASS (%d)", seconds)
+	}
+	return fmt.Sprintf("YOU MUST PASS  [ENTER] OK (AUTO IN %d)", seconds)
+}
+
+// pass gives the turn to the other player in a local game.
+func (g *Game) pass() {
+	name := g.playerName(g.whoseTurn)
+	g.setBoard(g.board, g.whoseTurn%2+1)
+	g.notice = fmt.Sprintf("%s HAS NO LEGAL MOVES AND PASSES", name)
+}
+
+// undoLocal takes back the last move of a local game. Against the AI, the AI's replies are taken
+// back too, so that it is the player's turn again.
+func (g *Game) undoLocal() error {
+	for len(g.moves) > 0 {
+		g.moves = g.moves[:len(g.moves)-1]
+
+		board, whoseTurn, err := common.ReplayTurn(g.startingBoard(), g.moves)
+		if err != nil {
+			return err
+		}
+
+		if g.hotseat || whoseTurn == g.player {
+			g.prevX, g.prevY = -1, -1
+			if len(g.moves) > 0 {
+				g.prevX, g.prevY = g.moves[len(g.moves)-1][0], g.moves[len(g.moves)-1][1]
+			}
+
+			g.setBoard(board, whoseTurn)
+			g.confetti = nil
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// localAITimeBudget is how long the local AI may think, since the terminal doesn't respond while it
+// does.
+const localAITimeBudget = time.Second
+
+// moveAI takes a turn as the local AI. The local adaptive AI doesn't know the player's results on
+// the server, so it adapts from the default strength.
+func (g *Game) moveAI() {
+	level := ai.DefaultLevels[g.difficulty]
+	if g.adaptive {
+		level = ai.Adapt(ai.DefaultStrength, g.board, g.objective())
+	}
+
+	board, coordinates := ai.Move(g.board, g.objective(), level, localAITimeBudget)
+
+	g.moves = append(g.moves, coordinates)
+	g.prevX, g.prevY = coordinates[0], coordinates[1]
+	g.startFlips(g.board, board)
+	g.setBoard(board, common.Player1)
+}
+
+// startFlips animates the disks captured by a move, unless motion is reduced. The server's copy of
+// a move that was already shown doesn't change any disks, so it doesn't restart the animation.
+func (g *Game) startFlips(before, after common.Board) {
+	if g.reduceMotion {
+		return
+	}
+
+	if f := newFlips(before, after); len(f.squares) > 0 {
+		g.flips = f
+	}
+}
+
 func (g *Game) OnQuit() {
+	if g.local() {
+		return
+	}
+
 	if err := g.SendMessage(messages.LeaveGame{Nickname: g.nickname, Host: g.host}); err != nil {
 		log.Print(err)
 	}
 }
 
+func (g *Game) Describe() (details, state string) {
+	if g.hosting() && g.alertMessage != "" && g.opponent == "[OPPONENT]" {
+		return "Playing Othelgo", "Waiting for an opponent"
+	}
+
+	if g.hotseat {
+		return "Playing Othelgo", "Hot-seat game"
+	}
+
+	if common.GameOver(g.board) {
+		return "Playing Othelgo", fmt.Sprintf("Finished a game vs. %s", g.opponent)
+	}
+
+	move := g.p1Score + g.p2Score - 3
+	if move < 1 {
+		move = 1
+	}
+
+	return "Playing Othelgo", fmt.Sprintf("Move %d vs. %s", move, g.opponent)
+}
+
+// Invitation lets friends join a game that is waiting for an opponent, and watch one that is
+// underway.
+func (g *Game) Invitation() (host string, joinable, ok bool) {
+	if !g.multiplayer || common.GameOver(g.board) || g.ended {
+		return "", false, false
+	}
+
+	return g.host, g.hosting() && g.opponent == "[OPPONENT]", true
+}
+
 func (g *Game) Tick() bool {
+	// Captured disks finish turning over before anything else happens.
+	if g.flips.tick() {
+		return true
+	}
+
+	// The AI moves on the tick after the player, so that the player's move is drawn first.
+	if g.offline && g.whoseTurn == common.Player2 && !common.GameOver(g.board) {
+		if g.mustPass {
+			g.pass()
+		} else {
+			g.moveAI()
+		}
+		return true
+	}
+
+	// A player who can't move passes by themselves after a while, and the countdown is redrawn
+	// until then.
+	if g.mustPass && g.whoseTurn == g.player {
+		if !time.Now().Before(g.passAt) {
+			g.pass()
+		}
+		return true
+	}
+
+	if g.countdown() != "" {
+		return true
+	}
+
 	if !common.GameOver(g.board) {
-		return false
+		// Redraw the clocks while they are running.
+		return g.timed() && g.alertMessage == ""
 	}
 
-	p1, p2 := common.KeepScore(g.board)
-	switch {
-	case g.player == 1 && p2 > p1:
-		return false
-	case g.player == 2 && p1 > p2:
+	// With reduced motion, the win is shown once by Draw instead of with falling confetti.
+	if !g.won() || g.reduceMotion {
 		return false
 	}
 
@@ -136,55 +576,105 @@ func (g *Game) Tick() bool {
 	return true
 }
 
+// won returns whether the game is over and was won at this terminal. In a hot-seat game,
+// somebody at the terminal has won unless it was a draw.
+func (g *Game) won() bool {
+	if !common.GameOver(g.board) {
+		return false
+	}
+
+	winner := common.Winner(g.board, g.objective())
+	if g.hotseat {
+		return winner != 0
+	}
+	return winner == g.player
+}
+
 func (g *Game) Draw() {
+	if !fitBoard(g.size()) {
+		g.drawCompact()
+		return
+	}
+
 	g.drawScore()
 	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Your name is %s!", strings.ToUpper(g.nickname)))
-	draw.Draw(draw.BotRight, draw.Normal, "[M] MENU  [Q] QUIT")
-	drawBoardOutline()
-	g.drawDisks()
+	if g.objective() == common.VariantAnti {
+		draw.Draw(draw.TopLeft, draw.Normal, "ANTI-REVERSI: FEWEST DISKS WINS")
+	}
+	draw.Draw(draw.BotRight, draw.Normal, fmt.Sprintf("[O] VIEW: %s  [G] SHAPES  [F] MOTION  [M] MENU  [Q] QUIT", orientationLabels[g.orientation]))
+	drawBoardOutline(g.size())
+	drawDisks(g.board, g.orientation, g.disks)
+	g.flips.draw(g.board, g.orientation, g.disks.glyphs())
+	if g.player == g.whoseTurn && g.alert() == "" && !g.mustPass {
+		drawLegalMoves(g.board, g.player, g.orientation, g.disks.glyphs())
+	}
 	g.drawCursor()
 	g.confetti.draw()
-	g.drawAlert()
-	if g.player == g.whoseTurn && (g.p1Score+g.p2Score > 4) {
-		g.highlightMove(g.prevX, g.prevY)
+	if g.reduceMotion && g.won() {
+		draw.Draw(draw.Offset(draw.CenterTop, 0, 1), draw.Inverted, g.winText())
+	}
+	g.drawMoveList()
+	drawAlert(g.alert())
+	if g.p1Score+g.p2Score > 4 {
+		highlightMove(g.board, g.orientation, g.prevX, g.prevY, g.disks)
+	}
+	switch {
+	case g.undoRequest != "":
+		draw.Draw(draw.BotLeft, draw.Normal, fmt.Sprintf("%s WANTS A TAKEBACK  [Y] ACCEPT  [N] DECLINE", strings.ToUpper(g.undoRequest)))
+	case g.mustPass && g.player == g.whoseTurn:
+		draw.Draw(draw.BotLeft, draw.Normal, g.passPrompt(false))
+	case g.notice != "":
+		draw.Draw(draw.BotLeft, draw.Normal, g.notice)
+	case common.GameOver(g.board) && g.multiplayer:
+		draw.Draw(draw.BotLeft, draw.Normal, "[R] REPLAY  [D] DISPUTE RESULT")
+	case common.GameOver(g.board) && !g.local():
+		draw.Draw(draw.BotLeft, draw.Normal, "[R] REPLAY")
+	case g.ended && g.multiplayer:
+		draw.Draw(draw.BotLeft, draw.Normal, "[D] DISPUTE RESULT")
+	case g.alert() == "" && g.takebacks():
+		draw.Draw(draw.BotLeft, draw.Normal, "[U] UNDO")
 	}
 }
 
-var playerColors = map[common.Disk]draw.Color{1: draw.Magenta, 2: draw.Green}
+var playerColors = map[common.Disk]draw.Color{1: draw.Player1, 2: draw.Player2}
 
-func drawDisk(anchor draw.Anchor, player common.Disk) {
-	// The extra space prevents a half-circle on some terminals.
-	draw.Draw(anchor, playerColors[player], "⬤ ")
+func drawDisk(anchor draw.Anchor, player common.Disk, style diskStyle) {
+	draw.Draw(anchor, playerColors[player], style.glyph(player))
 }
 
-func (g *Game) highlightMove(x, y int) {
-	draw.Draw(draw.Offset(draw.Center, ((x+1-common.BoardSize/2)*squareWidth)-4, (y+1-common.BoardSize/2)*squareHeight), draw.Normal, "[")
-	draw.Draw(draw.Offset(draw.Center, ((x+1-common.BoardSize/2)*squareWidth)-1, (y+1-common.BoardSize/2)*squareHeight), draw.Normal, "]")
-}
-
-var (
-	squareWidth  = 5
-	squareHeight = 2
-)
-
 func (g *Game) drawScore() {
-	var p1Name, p2Name string
-	if g.player == 1 {
-		p1Name = strings.ToUpper(g.nickname)
-		p2Name = strings.ToUpper(g.opponent)
-	} else {
-		p1Name = strings.ToUpper(g.opponent)
-		p2Name = strings.ToUpper(g.nickname)
-	}
+	p1Name, p2Name := g.playerName(common.Player1), g.playerName(common.Player2)
 
 	// P1 Name and Score
-	drawDisk(draw.Offset(draw.MiddleLeft, 4, -1), 1)
+	drawDisk(draw.Offset(draw.MiddleLeft, 4, -1), 1, g.disks)
 	draw.Draw(draw.Offset(draw.MiddleLeft, 7, -1), draw.Normal, fmt.Sprintf("%s: %-2d", p1Name, g.p1Score))
 
 	// P2 Name and Score
-	drawDisk(draw.Offset(draw.MiddleLeft, 4, 1), 2)
+	drawDisk(draw.Offset(draw.MiddleLeft, 4, 1), 2, g.disks)
 	draw.Draw(draw.Offset(draw.MiddleLeft, 7, 1), draw.Normal, fmt.Sprintf("%s: %-2d", p2Name, g.p2Score))
 
+	// Clocks
+	if g.timed() {
+		p1Clock, p2Clock := g.p1Clock, g.p2Clock
+		if g.alertMessage == "" {
+			elapsed := time.Since(g.clockUpdated)
+			if !g.startsAt.IsZero() && g.startsAt.After(g.clockUpdated) {
+				elapsed = time.Since(g.startsAt)
+			}
+			if elapsed < 0 {
+				elapsed = 0
+			}
+			if g.whoseTurn == 1 {
+				p1Clock -= elapsed
+			} else {
+				p2Clock -= elapsed
+			}
+		}
+		draw.Draw(draw.Offset(draw.MiddleLeft, 7, -3), draw.Normal, formatClock(p1Clock))
+		draw.Draw(draw.Offset(draw.MiddleLeft, 7, 3), draw.Normal, formatClock(p2Clock))
+		g.drawDeadline()
+	}
+
 	// Current turn indicator
 	if !common.GameOver(g.board) {
 		var yOffset int
@@ -197,10 +687,172 @@ func (g *Game) drawScore() {
 	}
 }
 
-func drawBoardOutline() {
+// setMoves replaces the moves played so far with the moves sent by the server.
+func (g *Game) setMoves(notation []string) {
+	moves, err := common.ParseMoves(notation)
+	if err != nil {
+		log.Printf("Ignoring moves from the server: %v", err)
+		return
+	}
+	g.moves = moves
+}
+
+// moveListLength is the number of most recent moves shown beside the board.
+const moveListLength = 8
+
+// drawMoveList shows the most recent moves in algebraic notation, with the last move highlighted.
+func (g *Game) drawMoveList() {
+	if len(g.moves) == 0 {
+		return
+	}
+
+	draw.Draw(draw.Offset(draw.MiddleRight, 0, -moveListLength/2-2), draw.Normal, "MOVES")
+
+	start := len(g.moves) - moveListLength
+	if start < 0 {
+		start = 0
+	}
+
+	for i := start; i < len(g.moves); i++ {
+		color := draw.Normal
+		if i == len(g.moves)-1 {
+			color = draw.Inverted
+		}
+		line := fmt.Sprintf("%2d. %-3s", i+1, common.Notation(g.moves[i][0], g.moves[i][1]))
+		draw.Draw(draw.Offset(draw.MiddleRight, 0, i-start-moveListLength/2), color, line)
+	}
+}
+
+// winText announces the winner without animation.
+func (g *Game) winText() string {
+	if !g.hotseat {
+		return " YOU WON! "
+	}
+
+	return fmt.Sprintf(" %s WON! ", g.playerName(common.Winner(g.board, g.objective())))
+}
+
+// objective returns the variant that decides who wins. Games on the server say so in their rules.
+func (g *Game) objective() string {
+	switch {
+	case g.rules != nil && g.rules.Scoring == messages.ScoringFewestDisks:
+		return common.VariantAnti
+	case g.rules != nil:
+		return common.VariantClassic
+	default:
+		return g.variant
+	}
+}
+
+// startingBoard returns the board that a game played on this terminal starts with.
+func (g *Game) startingBoard() common.Board {
+	if g.rules != nil {
+		return g.rules.Board
+	}
+	return common.NewBoardWithOpening(g.size(), g.opening)
+}
+
+// hosting returns whether this terminal is the host of a multiplayer game.
+func (g *Game) hosting() bool {
+	return g.multiplayer && g.nickname == g.host
+}
+
+// playerName returns the name shown for a player.
+func (g *Game) playerName(player common.Disk) string {
+	if (player == common.Player1) == (g.player == common.Player1 || g.hotseat) {
+		return strings.ToUpper(g.nickname)
+	}
+	return strings.ToUpper(g.opponent)
+}
+
+// size returns the size of the board being played. Until the server sends the board, this is the
+// size that was requested.
+func (g *Game) size() int {
+	switch {
+	case g.board.Size > 0:
+		return g.board.Size
+	case g.boardSize > 0:
+		return g.boardSize
+	default:
+		return common.DefaultBoardSize
+	}
+}
+
+// drawDeadline shows when the last move was made in a correspondence game, where each move has a
+// deadline of its own, and when the next move is due.
+func (g *Game) drawDeadline() {
+	if g.rules == nil || g.rules.Clock == nil || !g.rules.Clock.PerMove || common.GameOver(g.board) || g.alert() != "" {
+		return
+	}
+
+	// Each player's time is reset after they move, so the turn started when the clock of the player
+	// to move was full.
+	remaining := g.p1Clock
+	if g.whoseTurn == 2 {
+		remaining = g.p2Clock
+	}
+	initial := time.Duration(g.rules.Clock.Initial) * time.Millisecond
+	turnStarted := g.clockUpdated.Add(remaining - initial)
+	if g.startsAt.After(turnStarted) {
+		turnStarted = g.startsAt
+	}
+
+	now := time.Now()
+	if len(g.moves) > 0 {
+		draw.Draw(draw.Offset(draw.MiddleLeft, 4, 5), draw.Normal, "MOVED "+formatAgo(now.Sub(turnStarted)))
+	}
+	draw.Draw(draw.Offset(draw.MiddleLeft, 4, 6), draw.Normal, "DUE "+formatDeadline(turnStarted.Add(initial), now))
+}
+
+// alert returns the message that covers the board, such as while waiting for an opponent or
+// counting down to the start, if any. Players can't move while there is one.
+func (g *Game) alert() string {
+	if g.alertMessage != "" {
+		return g.alertMessage
+	}
+	return g.countdown()
+}
+
+// countdown returns the countdown to the start of the game, or an empty string once it started.
+func (g *Game) countdown() string {
+	left := time.Until(g.startsAt)
+	if left <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Game starts in %d", int(math.Ceil(left.Seconds())))
+}
+
+// timed returns whether the game has a clock. Servers that don't send rules only send clocks in
+// timed games.
+func (g *Game) timed() bool {
+	if g.rules != nil {
+		return g.rules.Clock != nil
+	}
+	return g.p1Clock > 0 || g.p2Clock > 0
+}
+
+// takebacks returns whether the player may take back a move. Servers that don't send rules are
+// asked anyway.
+func (g *Game) takebacks() bool {
+	return g.local() || g.rules == nil || g.rules.Takebacks
+}
+
+func formatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func drawBoardOutline(size int) {
 	var (
-		boardWidth  = common.BoardSize * squareWidth
-		boardHeight = common.BoardSize * squareHeight
+		boardWidth  = size * squareWidth
+		boardHeight = size * squareHeight
 	)
 
 	// Outline
@@ -259,35 +911,36 @@ func drawBoardOutline() {
 	}
 }
 
-func (g *Game) drawDisks() {
-	for i := 0; i < common.BoardSize; i++ {
-		for j := 0; j < common.BoardSize; j++ {
-			player := g.board[i][j]
+func drawDisks(board common.Board, o orientation, style diskStyle) {
+	for i := 0; i < board.Size; i++ {
+		for j := 0; j < board.Size; j++ {
+			player := board.Squares[i][j]
 			if player == 0 {
 				continue
 			}
 
-			x := (i+1-common.BoardSize/2)*squareWidth - 2
-			y := (j + 1 - common.BoardSize/2) * squareHeight
-
-			drawDisk(draw.Offset(draw.Center, x, y), player)
+			drawDisk(squareAnchor(board.Size, o, i, j), player, style)
 		}
 	}
 }
 
+// drawLegalMoves marks the squares where the player can place a disk.
+func drawLegalMoves(board common.Board, player common.Disk, o orientation, g glyphs) {
+	for _, move := range common.LegalMoves(board, player) {
+		draw.Draw(squareAnchor(board.Size, o, move[0], move[1]), playerColors[player], g.legalMove)
+	}
+}
+
 func (g *Game) drawCursor() {
-	if common.GameOver(g.board) || g.whoseTurn != g.player || g.alertMessage != "" {
-		termbox.HideCursor()
+	if common.GameOver(g.board) || g.whoseTurn != g.player || g.alert() != "" {
+		draw.HideCursor()
 	} else {
-		x := (g.curSquareX+1-common.BoardSize/2)*squareWidth - 3
-		y := (g.curSquareY + 1 - common.BoardSize/2) * squareHeight
-
-		draw.SetCursor(draw.Offset(draw.Center, x, y))
+		draw.SetCursor(cursorAnchor(g.size(), g.curSquareX, g.curSquareY))
 	}
 }
 
-func (g *Game) drawAlert() {
-	if g.alertMessage == "" {
+func drawAlert(alertMessage string) {
+	if alertMessage == "" {
 		return
 	}
 
@@ -301,7 +954,7 @@ func (g *Game) drawAlert() {
 	}
 
 	fillLine := func(first, ch, last rune) {
-		content := make([]rune, len(g.alertMessage)+4)
+		content := make([]rune, len(alertMessage)+4)
 		for i := 0; i < len(content); i++ {
 			content[i] = ch
 		}
@@ -310,7 +963,7 @@ func (g *Game) drawAlert() {
 
 	fillLine('╔', '═', '╗')
 	fillLine('║', ' ', '║')
-	writeLine('║', fmt.Sprintf("  %s  ", g.alertMessage), '║')
+	writeLine('║', fmt.Sprintf("  %s  ", alertMessage), '║')
 	fillLine('║', ' ', '║')
 	fillLine('╚', '═', '╝')
 
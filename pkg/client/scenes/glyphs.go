@@ -0,0 +1,59 @@
+package scenes
+
+import "github.com/armsnyder/othelgo/pkg/common"
+
+// Disks are drawn with Unicode glyphs, unless the player chooses ASCII for a terminal or font that
+// can't show them.
+
+// glyphs are the characters that disks are drawn with. Disk glyphs are followed by a space, which
+// prevents a half-circle on some terminals.
+type glyphs struct {
+	disk string
+	// shapes are used instead of identical disks when the players should be told apart by shape.
+	shapes    map[common.Disk]string
+	flip      []string
+	legalMove string
+	// miniature are one column wide, for small boards. Zero is an empty square.
+	miniature map[common.Disk]string
+}
+
+var unicodeGlyphs = glyphs{
+	disk:      "⬤ ",
+	shapes:    map[common.Disk]string{1: "⬤ ", 2: "◯ "},
+	flip:      []string{"◑ ", "┃ ", "◐ "},
+	legalMove: "·",
+	miniature: map[common.Disk]string{0: "·", 1: "●", 2: "○"},
+}
+
+var asciiGlyphs = glyphs{
+	disk:      "O ",
+	shapes:    map[common.Disk]string{1: "X ", 2: "O "},
+	flip:      []string{"( ", "| ", ") "},
+	legalMove: ".",
+	miniature: map[common.Disk]string{0: ".", 1: "X", 2: "O"},
+}
+
+// diskStyle is how the player has chosen to have disks drawn.
+type diskStyle struct {
+	shapes bool
+	ascii  bool
+}
+
+func loadDiskStyle() diskStyle {
+	return diskStyle{shapes: loadShapes(), ascii: loadASCII()}
+}
+
+func (s diskStyle) glyphs() glyphs {
+	if s.ascii {
+		return asciiGlyphs
+	}
+	return unicodeGlyphs
+}
+
+// glyph returns the glyph of a player's disk.
+func (s diskStyle) glyph(player common.Disk) string {
+	if s.shapes {
+		return s.glyphs().shapes[player]
+	}
+	return s.glyphs().disk
+}
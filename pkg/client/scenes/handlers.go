@@ -0,0 +1,187 @@
+package scenes
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// MessageHandler is implemented by scenes that handle messages from the server. RegisterHandlers
+// registers a handler for each type of message that the scene handles. It is called for every
+// message, and also when the client starts, on a zero scene, to find message types that nothing
+// handles, so it should only register handlers.
+type MessageHandler interface {
+	RegisterHandlers(h *Handlers)
+}
+
+// Handlers are handlers for messages from the server, by message type. Handlers are registered
+// with the On method for the message type, so that each handler gets the message as its own type.
+type Handlers struct {
+	byType map[reflect.Type]func(interface{}) error
+}
+
+// HandlersOf returns the handlers that a scene registers, which are none if it isn't a
+// MessageHandler.
+func HandlersOf(scene Scene) *Handlers {
+	h := new(Handlers)
+	if handler, ok := scene.(MessageHandler); ok {
+		handler.RegisterHandlers(h)
+	}
+	return h
+}
+
+// Dispatch calls the handler for the type of message, if there is one.
+func (h *Handlers) Dispatch(message interface{}) error {
+	if handler, ok := h.byType[reflect.TypeOf(message)]; ok {
+		return handler(message)
+	}
+	return nil
+}
+
+// Handles returns whether a handler is registered for a message type, such as
+// reflect.TypeOf(&messages.Pong{}).
+func (h *Handlers) Handles(typ reflect.Type) bool {
+	_, ok := h.byType[typ]
+	return ok
+}
+
+// MessageTypes returns the types of the messages that the server sends, which are the ones that
+// Handlers has an On method for.
+func MessageTypes() []reflect.Type {
+	var types []reflect.Type
+
+	handlersType := reflect.TypeOf(&Handlers{})
+	for i := 0; i < handlersType.NumMethod(); i++ {
+		method := handlersType.Method(i)
+		if strings.HasPrefix(method.Name, "On") {
+			// The method's argument, after the receiver, is a func whose argument is the message.
+			types = append(types, method.Type.In(1).In(0))
+		}
+	}
+
+	return types
+}
+
+func (h *Handlers) on(message interface{}, handler func(interface{}) error) {
+	if h.byType == nil {
+		h.byType = make(map[reflect.Type]func(interface{}) error)
+	}
+	h.byType[reflect.TypeOf(message)] = handler
+}
+
+func (h *Handlers) OnHelloAck(handler func(*messages.HelloAck) error) {
+	h.on((*messages.HelloAck)(nil), func(m interface{}) error { return handler(m.(*messages.HelloAck)) })
+}
+
+func (h *Handlers) OnPong(handler func(*messages.Pong) error) {
+	h.on((*messages.Pong)(nil), func(m interface{}) error { return handler(m.(*messages.Pong)) })
+}
+
+func (h *Handlers) OnDecorate(handler func(*messages.Decorate) error) {
+	h.on((*messages.Decorate)(nil), func(m interface{}) error { return handler(m.(*messages.Decorate)) })
+}
+
+func (h *Handlers) OnError(handler func(*messages.Error) error) {
+	h.on((*messages.Error)(nil), func(m interface{}) error { return handler(m.(*messages.Error)) })
+}
+
+func (h *Handlers) OnNicknameClaimed(handler func(*messages.NicknameClaimed) error) {
+	h.on((*messages.NicknameClaimed)(nil), func(m interface{}) error { return handler(m.(*messages.NicknameClaimed)) })
+}
+
+func (h *Handlers) OnOpenGames(handler func(*messages.OpenGames) error) {
+	h.on((*messages.OpenGames)(nil), func(m interface{}) error { return handler(m.(*messages.OpenGames)) })
+}
+
+func (h *Handlers) OnMatchQueued(handler func(*messages.MatchQueued) error) {
+	h.on((*messages.MatchQueued)(nil), func(m interface{}) error { return handler(m.(*messages.MatchQueued)) })
+}
+
+func (h *Handlers) OnLeaderboard(handler func(*messages.Leaderboard) error) {
+	h.on((*messages.Leaderboard)(nil), func(m interface{}) error { return handler(m.(*messages.Leaderboard)) })
+}
+
+func (h *Handlers) OnLoungeChat(handler func(*messages.LoungeChat) error) {
+	h.on((*messages.LoungeChat)(nil), func(m interface{}) error { return handler(m.(*messages.LoungeChat)) })
+}
+
+func (h *Handlers) OnPresenceUpdate(handler func(*messages.PresenceUpdate) error) {
+	h.on((*messages.PresenceUpdate)(nil), func(m interface{}) error { return handler(m.(*messages.PresenceUpdate)) })
+}
+
+func (h *Handlers) OnJoined(handler func(*messages.Joined) error) {
+	h.on((*messages.Joined)(nil), func(m interface{}) error { return handler(m.(*messages.Joined)) })
+}
+
+func (h *Handlers) OnGameStarted(handler func(*messages.GameStarted) error) {
+	h.on((*messages.GameStarted)(nil), func(m interface{}) error { return handler(m.(*messages.GameStarted)) })
+}
+
+func (h *Handlers) OnGameStarting(handler func(*messages.GameStarting) error) {
+	h.on((*messages.GameStarting)(nil), func(m interface{}) error { return handler(m.(*messages.GameStarting)) })
+}
+
+func (h *Handlers) OnUpdateBoard(handler func(*messages.UpdateBoard) error) {
+	h.on((*messages.UpdateBoard)(nil), func(m interface{}) error { return handler(m.(*messages.UpdateBoard)) })
+}
+
+func (h *Handlers) OnGameState(handler func(*messages.GameState) error) {
+	h.on((*messages.GameState)(nil), func(m interface{}) error { return handler(m.(*messages.GameState)) })
+}
+
+func (h *Handlers) OnRequestUndo(handler func(*messages.RequestUndo) error) {
+	h.on((*messages.RequestUndo)(nil), func(m interface{}) error { return handler(m.(*messages.RequestUndo)) })
+}
+
+func (h *Handlers) OnRespondUndo(handler func(*messages.RespondUndo) error) {
+	h.on((*messages.RespondUndo)(nil), func(m interface{}) error { return handler(m.(*messages.RespondUndo)) })
+}
+
+func (h *Handlers) OnOpponentDisconnected(handler func(*messages.OpponentDisconnected) error) {
+	h.on((*messages.OpponentDisconnected)(nil), func(m interface{}) error { return handler(m.(*messages.OpponentDisconnected)) })
+}
+
+func (h *Handlers) OnGameOver(handler func(*messages.GameOver) error) {
+	h.on((*messages.GameOver)(nil), func(m interface{}) error { return handler(m.(*messages.GameOver)) })
+}
+
+func (h *Handlers) OnDisputeFiled(handler func(*messages.DisputeFiled) error) {
+	h.on((*messages.DisputeFiled)(nil), func(m interface{}) error { return handler(m.(*messages.DisputeFiled)) })
+}
+
+func (h *Handlers) OnSpectatorUpdate(handler func(*messages.SpectatorUpdate) error) {
+	h.on((*messages.SpectatorUpdate)(nil), func(m interface{}) error { return handler(m.(*messages.SpectatorUpdate)) })
+}
+
+func (h *Handlers) OnKibitz(handler func(*messages.Kibitz) error) {
+	h.on((*messages.Kibitz)(nil), func(m interface{}) error { return handler(m.(*messages.Kibitz)) })
+}
+
+func (h *Handlers) OnReplay(handler func(*messages.Replay) error) {
+	h.on((*messages.Replay)(nil), func(m interface{}) error { return handler(m.(*messages.Replay)) })
+}
+
+func (h *Handlers) OnProfile(handler func(*messages.Profile) error) {
+	h.on((*messages.Profile)(nil), func(m interface{}) error { return handler(m.(*messages.Profile)) })
+}
+
+func (h *Handlers) OnPrivacy(handler func(*messages.Privacy) error) {
+	h.on((*messages.Privacy)(nil), func(m interface{}) error { return handler(m.(*messages.Privacy)) })
+}
+
+func (h *Handlers) OnDataJobQueued(handler func(*messages.DataJobQueued) error) {
+	h.on((*messages.DataJobQueued)(nil), func(m interface{}) error { return handler(m.(*messages.DataJobQueued)) })
+}
+
+func (h *Handlers) OnDataJobDone(handler func(*messages.DataJobDone) error) {
+	h.on((*messages.DataJobDone)(nil), func(m interface{}) error { return handler(m.(*messages.DataJobDone)) })
+}
+
+func (h *Handlers) OnTournamentUpdate(handler func(*messages.TournamentUpdate) error) {
+	h.on((*messages.TournamentUpdate)(nil), func(m interface{}) error { return handler(m.(*messages.TournamentUpdate)) })
+}
+
+func (h *Handlers) OnBotLadderJoined(handler func(*messages.BotLadderJoined) error) {
+	h.on((*messages.BotLadderJoined)(nil), func(m interface{}) error { return handler(m.(*messages.BotLadderJoined)) })
+}
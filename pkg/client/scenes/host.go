@@ -0,0 +1,100 @@
+package scenes
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// presets are the speed presets offered when hosting a game or finding a match, in display order.
+// The server maps each one to a time control, a takeback policy, and how closely matchmaking pairs
+// ratings.
+var presets = []struct{ name, label, description string }{
+	{"", "CASUAL", "No clock, takebacks allowed"},
+	{"bullet", "BULLET", "1 minute, no takebacks"},
+	{"blitz", "BLITZ", "3 minutes + 2 seconds per move"},
+	{"rapid", "RAPID", "10 minutes + 5 seconds per move"},
+	{"correspondence", "CORRESPONDENCE", "3 days per move"},
+}
+
+// hostColors are the color choices offered when hosting a game. Black moves first.
+var hostColors = []struct{ name, label string }{
+	{"", "BLACK"},
+	{messages.ColorWhite, "WHITE"},
+	{messages.ColorRandom, "RANDOM"},
+	{messages.ColorJoiner, "OPPONENT PICKS"},
+}
+
+// ratingRanges are the choices offered for how far from the host's rating an opponent may be.
+var ratingRanges = []struct {
+	value int
+	label string
+}{
+	{0, "ANYONE"},
+	{100, "±100"},
+	{200, "±200"},
+	{400, "±400"},
+}
+
+// Host lets the player choose a speed preset and a color before hosting a game.
+type Host struct {
+	scene
+	nickname  string
+	boardSize int
+	variant   int
+	selected  int
+	color     int
+
+	// ratingRange indexes ratingRanges.
+	ratingRange int
+}
+
+func (h *Host) OnTerminalEvent(event termbox.Event) error {
+	if unicode.ToUpper(event.Ch) == 'M' {
+		return h.ChangeScene(&Menu{nickname: h.nickname, boardSize: h.boardSize, variant: h.variant})
+	}
+
+	if event.Key == termbox.KeyEnter {
+		return h.ChangeScene(&Game{player: 1, multiplayer: true, nickname: h.nickname, host: h.nickname, opponent: "[OPPONENT]", preset: presets[h.selected].name, boardSize: h.boardSize, color: hostColors[h.color].name, variant: gameVariants[h.variant].variant, opening: gameVariants[h.variant].opening, ratingRange: ratingRanges[h.ratingRange].value})
+	}
+
+	if unicode.ToUpper(event.Ch) == 'C' {
+		h.color = (h.color + 1) % len(hostColors)
+		return nil
+	}
+
+	if unicode.ToUpper(event.Ch) == 'R' {
+		h.ratingRange = (h.ratingRange + 1) % len(ratingRanges)
+		return nil
+	}
+
+	_, dy := getDirectionPressed(event)
+	h.selected = clamp(h.selected+dy, 0, len(presets))
+
+	return nil
+}
+
+func (h *Host) Draw() {
+	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Your name is %s!", strings.ToUpper(h.nickname)))
+	draw.Draw(draw.BotRight, draw.Normal, "[M] MENU  [Q] QUIT")
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, -len(presets)), draw.Normal, "=== GAME SPEED ===")
+
+	for i, p := range presets {
+		color := draw.Normal
+		if i == h.selected {
+			color = draw.Inverted
+		}
+		draw.Draw(draw.Offset(draw.CenterTop, 0, i*2-len(presets)+3), color, fmt.Sprintf("[ %s ]", p.label))
+	}
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, len(presets)+4), draw.Normal, presets[h.selected].description)
+	draw.Draw(draw.Offset(draw.CenterTop, 0, len(presets)+6), draw.Normal, "[C] COLOR: "+hostColors[h.color].label)
+	draw.Draw(draw.Offset(draw.CenterTop, 0, len(presets)+8), draw.Normal, "VARIANT: "+gameVariants[h.variant].label)
+	draw.Draw(draw.Offset(draw.CenterTop, 0, len(presets)+10), draw.Normal, "[R] OPPONENT RATING: "+ratingRanges[h.ratingRange].label)
+}
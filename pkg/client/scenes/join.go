@@ -11,11 +11,52 @@ import (
 	"github.com/nsf/termbox-go"
 )
 
+const (
+	// loungeLines is the number of lounge chat lines shown in the lounge pane.
+	loungeLines = 10
+
+	// loungeWidth is the maximum width of a line in the lounge pane.
+	loungeWidth = 40
+
+	maxChatLen = 200
+)
+
+var statusLabels = map[string]string{
+	messages.StatusAvailable:    "available",
+	messages.StatusPlaying:      "playing",
+	messages.StatusAway:         "away",
+	messages.StatusDoNotDisturb: "do not disturb",
+	messages.StatusOffline:      "offline",
+}
+
 type Join struct {
 	scene
 	nickname string
 	hosts    []string
+	statuses map[string]string
+	ratings  map[string]int
 	selected int
+	status   string
+
+	// color is the color to play if the host lets the opponent pick. Joiners play white by default.
+	color string
+
+	// findingMatch is whether the player is waiting in the matchmaking queue.
+	findingMatch bool
+	rating       int
+
+	// matchPreset indexes presets, and is the speed of game to find a match for.
+	matchPreset int
+
+	// B cycles between the leaderboard, the bot ladder's leaderboard, and neither.
+	showLeaderboard bool
+	botLeaderboard  bool
+	leaderboard     []messages.LeaderboardEntry
+
+	inLounge   bool
+	loungeChat []messages.ChatLine
+	typing     bool
+	draft      string
 }
 
 func (j *Join) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
@@ -26,20 +67,77 @@ func (j *Join) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
 	return sendMessage(messages.ListOpenGames{})
 }
 
-func (j *Join) OnMessage(message interface{}) error {
-	if m, ok := message.(*messages.OpenGames); ok {
+func (j *Join) RegisterHandlers(h *Handlers) {
+	h.OnOpenGames(func(m *messages.OpenGames) error {
 		j.hosts = m.Hosts
-	}
-	if len(j.hosts) > 0 {
-		j.selected = 0
-	}
+		j.statuses = m.Statuses
+		j.ratings = m.Ratings
+		if len(j.hosts) > 0 {
+			j.selected = 0
+		}
+		return nil
+	})
 
-	return nil
+	h.OnMatchQueued(func(m *messages.MatchQueued) error {
+		j.findingMatch = true
+		j.rating = m.Rating
+		return nil
+	})
+
+	h.OnGameStarted(func(m *messages.GameStarted) error {
+		// Only matchmaking starts a game for a player who is still choosing one.
+		if err := j.leaveLounge(); err != nil {
+			return err
+		}
+		return j.ChangeScene(&Game{player: m.Disk, multiplayer: true, matched: true, nickname: j.nickname, host: m.Host, opponent: otherNickname(m, j.nickname)})
+	})
+
+	h.OnLeaderboard(func(m *messages.Leaderboard) error {
+		if m.Bots == j.botLeaderboard {
+			j.leaderboard = m.Entries
+		}
+		return nil
+	})
+
+	h.OnLoungeChat(func(m *messages.LoungeChat) error {
+		j.addLoungeLines(m.Lines...)
+		return nil
+	})
+
+	h.OnPresenceUpdate(func(m *messages.PresenceUpdate) error {
+		if m.Nickname == j.nickname {
+			j.status = m.Status
+		}
+		if j.statuses[m.Nickname] != "" {
+			j.statuses[m.Nickname] = m.Status
+		}
+		if j.inLounge && m.Nickname != j.nickname {
+			j.addLoungeLines(messages.ChatLine{Text: fmt.Sprintf("%s is %s", strings.ToUpper(m.Nickname), statusLabels[m.Status])})
+		}
+		return nil
+	})
+}
+
+func (j *Join) addLoungeLines(lines ...messages.ChatLine) {
+	j.loungeChat = append(j.loungeChat, lines...)
+	if len(j.loungeChat) > loungeLines {
+		j.loungeChat = j.loungeChat[len(j.loungeChat)-loungeLines:]
+	}
 }
 
 func (j *Join) OnTerminalEvent(event termbox.Event) error {
+	if j.typing {
+		return j.onTypingEvent(event)
+	}
+
 	if event.Key == termbox.KeyEnter && len(j.hosts) > 0 {
-		return j.ChangeScene(&Game{player: 2, multiplayer: true, nickname: j.nickname, host: j.hosts[j.selected], opponent: j.hosts[j.selected]})
+		if err := j.leaveLounge(); err != nil {
+			return err
+		}
+		if err := j.cancelFindMatch(); err != nil {
+			return err
+		}
+		return j.ChangeScene(&Game{player: 2, multiplayer: true, nickname: j.nickname, host: j.hosts[j.selected], opponent: j.hosts[j.selected], color: j.color})
 	}
 	_, dy := getDirectionPressed(event)
 	switch {
@@ -49,15 +147,173 @@ func (j *Join) OnTerminalEvent(event termbox.Event) error {
 		j.selected++
 	}
 
-	if unicode.ToUpper(event.Ch) == 'M' {
+	switch unicode.ToUpper(event.Ch) {
+	case 'M':
+		if err := j.leaveLounge(); err != nil {
+			return err
+		}
+		if err := j.cancelFindMatch(); err != nil {
+			return err
+		}
 		return j.ChangeScene(&Menu{nickname: j.nickname})
+	case 'F':
+		if j.findingMatch {
+			return j.cancelFindMatch()
+		}
+		return j.SendMessage(messages.FindMatch{Nickname: j.nickname, Preset: presets[j.matchPreset].name})
+	case 'G':
+		// A player who is already waiting moves to the queue for the new speed.
+		j.matchPreset = (j.matchPreset + 1) % len(presets)
+		if j.findingMatch {
+			return j.SendMessage(messages.FindMatch{Nickname: j.nickname, Preset: presets[j.matchPreset].name})
+		}
+	case 'B':
+		switch {
+		case !j.showLeaderboard:
+			j.showLeaderboard = true
+			if err := j.leaveLounge(); err != nil {
+				return err
+			}
+			return j.SendMessage(messages.GetLeaderboard{})
+		case !j.botLeaderboard:
+			j.botLeaderboard = true
+			j.leaderboard = nil
+			return j.SendMessage(messages.GetLeaderboard{Bots: true})
+		default:
+			j.showLeaderboard = false
+			j.botLeaderboard = false
+		}
+	case 'S':
+		return j.cycleStatus()
+	case 'C':
+		if j.color == messages.ColorBlack {
+			j.color = ""
+		} else {
+			j.color = messages.ColorBlack
+		}
+	case 'V':
+		if err := j.leaveLounge(); err != nil {
+			return err
+		}
+		if err := j.cancelFindMatch(); err != nil {
+			return err
+		}
+		return j.ChangeScene(&Dashboard{nickname: j.nickname})
+	case 'L':
+		if j.inLounge {
+			return j.leaveLounge()
+		}
+		j.inLounge = true
+		j.showLeaderboard = false
+		j.botLeaderboard = false
+		return j.SendMessage(messages.JoinLounge{Nickname: j.nickname})
+	}
+
+	if event.Key == termbox.KeyTab && j.inLounge {
+		j.typing = true
 	}
+
 	return nil
 }
 
+func (j *Join) onTypingEvent(event termbox.Event) error {
+	switch event.Key {
+	case termbox.KeyTab:
+		j.typing = false
+	case termbox.KeyEnter:
+		if strings.TrimSpace(j.draft) == "" {
+			return nil
+		}
+		text := j.draft
+		j.draft = ""
+		return j.SendMessage(messages.SendLoungeChat{Text: text})
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if j.draft != "" {
+			runes := []rune(j.draft)
+			j.draft = string(runes[:len(runes)-1])
+		}
+	case termbox.KeySpace:
+		j.draft += " "
+	default:
+		if unicode.IsPrint(event.Ch) && len(j.draft) < maxChatLen {
+			j.draft += string(event.Ch)
+		}
+	}
+
+	return nil
+}
+
+// cycleStatus changes the player's own status to the next one they can choose.
+func (j *Join) cycleStatus() error {
+	next := messages.StatusAway
+	switch j.status {
+	case messages.StatusAway:
+		next = messages.StatusDoNotDisturb
+	case messages.StatusDoNotDisturb:
+		next = messages.StatusAvailable
+	}
+
+	j.status = next
+
+	return j.SendMessage(messages.SetStatus{Nickname: j.nickname, Status: next})
+}
+
+func (j *Join) cancelFindMatch() error {
+	if !j.findingMatch {
+		return nil
+	}
+
+	j.findingMatch = false
+
+	return j.SendMessage(messages.CancelFindMatch{})
+}
+
+// otherNickname returns the player of a started game who isn't nickname.
+func otherNickname(m *messages.GameStarted, nickname string) string {
+	if m.Host == nickname {
+		return m.Opponent
+	}
+	return m.Host
+}
+
+func (j *Join) leaveLounge() error {
+	if !j.inLounge {
+		return nil
+	}
+
+	j.inLounge = false
+	j.typing = false
+	j.loungeChat = nil
+
+	return j.SendMessage(messages.LeaveLounge{})
+}
+
+func (j *Join) HasFreeKeyboardInput() bool {
+	return j.typing
+}
+
 func (j *Join) Draw() {
 	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Your name is %s!", strings.ToUpper(j.nickname)))
-	draw.Draw(draw.BotRight, draw.Normal, "[M] MENU  [Q] QUIT")
+	draw.Draw(draw.BotRight, draw.Normal, "[V] WATCH GAMES  [M] MENU  [Q] QUIT")
+
+	status := j.status
+	if status == "" {
+		status = messages.StatusAvailable
+	}
+	draw.Draw(draw.Offset(draw.TopRight, 0, 2), draw.Normal, fmt.Sprintf("[S] STATUS: %s", strings.ToUpper(statusLabels[status])))
+
+	color := "WHITE"
+	if j.color == messages.ColorBlack {
+		color = "BLACK"
+	}
+	draw.Draw(draw.Offset(draw.TopRight, 0, 4), draw.Normal, "[C] IF ASKED, PLAY: "+color)
+
+	if j.findingMatch {
+		draw.Draw(draw.Offset(draw.TopRight, 0, 6), draw.Normal, fmt.Sprintf("[F] STOP LOOKING (RATING %d)", j.rating))
+	} else {
+		draw.Draw(draw.Offset(draw.TopRight, 0, 6), draw.Normal, "[F] FIND A MATCH")
+	}
+	draw.Draw(draw.Offset(draw.TopRight, 0, 8), draw.Normal, "[G] MATCH SPEED: "+presets[j.matchPreset].label)
 
 	if len(j.hosts) > 0 {
 		buttonColors := [6]draw.Color{}
@@ -67,10 +323,82 @@ func (j *Join) Draw() {
 		buttonColors[j.selected] = draw.Inverted
 		draw.Draw(draw.Offset(draw.CenterRight, -9, 0), draw.Normal, "=== OPEN GAMES ===")
 		for i, h := range j.hosts {
-			os := -(len(h) + 4) / 2
-			draw.Draw(draw.Offset(draw.CenterRight, os, i*2+2), buttonColors[i], fmt.Sprintf("[ %s ]", strings.ToUpper(h)))
+			label := strings.ToUpper(h)
+			if rating, ok := j.ratings[h]; ok {
+				label = fmt.Sprintf("%s %d", label, rating)
+			}
+			os := -(len(label) + 4) / 2
+			draw.Draw(draw.Offset(draw.CenterRight, os, i*2+2), buttonColors[i], fmt.Sprintf("[ %s ]", label))
+			if status := j.statuses[h]; status != "" && status != messages.StatusAvailable {
+				draw.Draw(draw.Offset(draw.CenterRight, -os+1, i*2+2), draw.Normal, statusLabels[status])
+			}
 		}
 	} else {
 		draw.Draw(draw.CenterTop, draw.Normal, "MORE LIKE \"NO GAME\"")
 	}
+
+	j.drawLounge()
+}
+
+func (j *Join) drawLeaderboard() {
+	title := "=== LEADERBOARD ==="
+	if j.botLeaderboard {
+		title = "=== BOT LADDER ==="
+	}
+	draw.Draw(draw.Offset(draw.MiddleLeft, 2, -loungeLines/2-2), draw.Normal, title)
+
+	for i, entry := range j.leaderboard {
+		if i == loungeLines {
+			break
+		}
+		text := truncate(fmt.Sprintf("%2d. %-10s %d", i+1, strings.ToUpper(entry.Nickname), entry.Rating), loungeWidth)
+		draw.Draw(draw.Offset(draw.MiddleLeft, 2, i-loungeLines/2), draw.Normal, text)
+	}
+}
+
+func (j *Join) drawLounge() {
+	if !j.inLounge {
+		switch {
+		case j.botLeaderboard:
+			j.drawLeaderboard()
+			draw.Draw(draw.BotLeft, draw.Normal, "[L] JOIN LOUNGE  [B] HIDE LEADERBOARD")
+		case j.showLeaderboard:
+			j.drawLeaderboard()
+			draw.Draw(draw.BotLeft, draw.Normal, "[L] JOIN LOUNGE  [B] BOT LADDER")
+		default:
+			draw.Draw(draw.BotLeft, draw.Normal, "[L] JOIN LOUNGE  [B] LEADERBOARD")
+		}
+		return
+	}
+
+	draw.Draw(draw.Offset(draw.MiddleLeft, 2, -loungeLines/2-2), draw.Normal, "=== LOUNGE ===")
+
+	for i, line := range j.loungeChat {
+		text := "* " + line.Text
+		if line.Nickname != "" {
+			text = fmt.Sprintf("%s: %s", strings.ToUpper(line.Nickname), line.Text)
+		}
+		text = truncate(text, loungeWidth)
+		draw.Draw(draw.Offset(draw.MiddleLeft, 2, i-loungeLines/2), draw.Normal, text)
+	}
+
+	if j.typing {
+		input := "> " + j.draft
+		if len([]rune(input)) > loungeWidth {
+			input = string([]rune(input)[len([]rune(input))-loungeWidth:])
+		}
+		draw.Draw(draw.Offset(draw.MiddleLeft, 2, loungeLines/2+1), draw.Normal, input)
+		draw.SetCursor(draw.Offset(draw.MiddleLeft, 2+len([]rune(input)), loungeLines/2+2))
+		draw.Draw(draw.BotLeft, draw.Normal, "[ENTER] SEND  [TAB] DONE")
+	} else {
+		draw.Draw(draw.BotLeft, draw.Normal, "[TAB] CHAT  [L] LEAVE LOUNGE")
+	}
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
 }
@@ -0,0 +1,100 @@
+package scenes
+
+import (
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Boards are laid out to fit the terminal. The squares are narrowed when the full board and the
+// panels beside it don't fit, and the board is drawn compactly when even the narrowest squares
+// don't fit. Below a minimum size, scenes are replaced by a message asking for a bigger terminal.
+
+const (
+	fullSquareWidth = 5
+	// minSquareWidth is the narrowest square that a disk fits in.
+	minSquareWidth = 3
+
+	// sidePanelWidth is the room that the score and the move list need on either side of the board.
+	sidePanelWidth = 21
+
+	// The smallest terminal that scenes without a board can be drawn in.
+	minTerminalWidth  = 60
+	minTerminalHeight = 20
+)
+
+var (
+	squareWidth  = fullSquareWidth
+	squareHeight = 2
+)
+
+// fitBoard narrows the squares as little as needed for a board of a size to fit the terminal. It
+// returns false if the board should be drawn compactly instead.
+func fitBoard(size int) bool {
+	width, height := draw.Size()
+	if Compact || height < size*squareHeight+3 {
+		return false
+	}
+
+	for w := fullSquareWidth; w >= minSquareWidth; w-- {
+		if width >= size*w+1+2*sidePanelWidth {
+			squareWidth = w
+			return true
+		}
+	}
+
+	return false
+}
+
+// squareAnchor returns where the disk on a square is drawn.
+func squareAnchor(size int, o orientation, i, j int) draw.Anchor {
+	vi, vj := o.transform(size, i, j)
+	x := (vi+1-size/2)*squareWidth - diskInset()
+	y := (vj + 1 - size/2) * squareHeight
+
+	return draw.Offset(draw.Center, x, y)
+}
+
+// cursorAnchor returns where the cursor is placed on a square, in screen coordinates.
+func cursorAnchor(size, x, y int) draw.Anchor {
+	return draw.Offset(draw.Center, (x+1-size/2)*squareWidth-diskInset()-1, (y+1-size/2)*squareHeight)
+}
+
+// diskInset is how far left of a square's right edge its disk is anchored, so that the disk is
+// centered in the square.
+func diskInset() int {
+	return squareWidth - 2 - (squareWidth-3)/2
+}
+
+// highlightMove marks the square of a move with brackets, or by inverting its disk if the squares
+// are too narrow for brackets.
+func highlightMove(board common.Board, o orientation, x, y int, style diskStyle) {
+	if squareWidth < fullSquareWidth {
+		draw.Draw(squareAnchor(board.Size, o, x, y), draw.Inverted, style.glyph(board.Squares[x][y]))
+		return
+	}
+
+	vx, vy := o.transform(board.Size, x, y)
+	draw.Draw(draw.Offset(draw.Center, ((vx+1-board.Size/2)*squareWidth)-4, (vy+1-board.Size/2)*squareHeight), draw.Normal, "[")
+	draw.Draw(draw.Offset(draw.Center, ((vx+1-board.Size/2)*squareWidth)-1, (vy+1-board.Size/2)*squareHeight), draw.Normal, "]")
+}
+
+// minSizer is implemented by scenes that can be drawn in a smaller terminal than other scenes.
+type minSizer interface {
+	minSize() (width, height int)
+}
+
+// TooSmall returns whether the terminal is too small to draw a scene.
+func TooSmall(scene Scene) bool {
+	minWidth, minHeight := minTerminalWidth, minTerminalHeight
+	if s, ok := scene.(minSizer); ok {
+		minWidth, minHeight = s.minSize()
+	}
+
+	width, height := draw.Size()
+	return width < minWidth || height < minHeight
+}
+
+// DrawTooSmall asks for a bigger terminal.
+func DrawTooSmall() {
+	draw.Draw(draw.Center, draw.Normal, "TERMINAL\nTOO SMALL")
+}
@@ -3,10 +3,13 @@ package scenes
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/nsf/termbox-go"
 
 	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
 )
 
 const (
@@ -16,15 +19,52 @@ const (
 	buttonHostGame
 	buttonJoinGame
 	buttonChangeName
+	buttonHotSeat
+	buttonAdaptive
 )
 
+// boardSizes are the board sizes that can be chosen from the menu, in the order they are cycled.
+var boardSizes = []int{common.DefaultBoardSize, common.MaxBoardSize, common.MinBoardSize}
+
+// gameVariants are the variants and openings that can be chosen from the menu, in the order they
+// are cycled.
+var gameVariants = []struct{ variant, opening, label string }{
+	{"", "", "CLASSIC"},
+	{common.VariantAnti, "", "ANTI-REVERSI"},
+	{"", common.OpeningParallel, "CLASSIC, PARALLEL OPENING"},
+	{common.VariantAnti, common.OpeningParallel, "ANTI-REVERSI, PARALLEL OPENING"},
+}
+
+// Branding is the branding of the server, which is shown on the main menu.
+var Branding messages.Branding
+
 type Menu struct {
 	scene
-	button   int
-	nickname string
+	button    int
+	nickname  string
+	boardSize int
+	variant   int
 }
 
 func (m *Menu) OnTerminalEvent(event termbox.Event) error {
+	if unicode.ToUpper(event.Ch) == 'B' {
+		m.cycleBoardSize()
+		return nil
+	}
+
+	if unicode.ToUpper(event.Ch) == 'S' {
+		return m.ChangeScene(&Settings{nickname: m.nickname, boardSize: m.boardSize, variant: m.variant})
+	}
+
+	if unicode.ToUpper(event.Ch) == 'P' {
+		return m.ChangeScene(&Profile{nickname: m.nickname})
+	}
+
+	if unicode.ToUpper(event.Ch) == 'V' {
+		m.variant = (m.variant + 1) % len(gameVariants)
+		return nil
+	}
+
 	dx, dy := getDirectionPressed(event)
 
 	switch {
@@ -32,14 +72,14 @@ func (m *Menu) OnTerminalEvent(event termbox.Event) error {
 		switch m.button {
 		case buttonChangeName:
 			m.button = buttonHostGame
-		case buttonHostGame, buttonJoinGame:
+		case buttonHostGame, buttonJoinGame, buttonHotSeat:
 			m.button = buttonNormal
 		}
 	case dx == 1:
 		switch m.button {
-		case buttonEasy, buttonNormal, buttonHard:
+		case buttonEasy, buttonNormal, buttonHard, buttonAdaptive:
 			m.button = buttonHostGame
-		case buttonHostGame, buttonJoinGame:
+		case buttonHostGame, buttonJoinGame, buttonHotSeat:
 			m.button = buttonChangeName
 		}
 	case dy == -1:
@@ -48,8 +88,12 @@ func (m *Menu) OnTerminalEvent(event termbox.Event) error {
 			m.button = buttonEasy
 		case buttonHard:
 			m.button = buttonNormal
+		case buttonAdaptive:
+			m.button = buttonHard
 		case buttonJoinGame:
 			m.button = buttonHostGame
+		case buttonHotSeat:
+			m.button = buttonJoinGame
 		default:
 			m.button = buttonChangeName
 		}
@@ -59,26 +103,35 @@ func (m *Menu) OnTerminalEvent(event termbox.Event) error {
 			m.button = buttonNormal
 		case buttonNormal:
 			m.button = buttonHard
+		case buttonHard:
+			m.button = buttonAdaptive
 		case buttonHostGame:
 			m.button = buttonJoinGame
+		case buttonJoinGame:
+			m.button = buttonHotSeat
 		case buttonChangeName:
 			m.button = buttonHostGame
 		}
 	}
 
 	if event.Key == termbox.KeyEnter {
+		v := gameVariants[m.variant]
 		switch m.button {
 		case buttonEasy:
-			return m.ChangeScene(&Game{player: 1, difficulty: 0, nickname: m.nickname, host: m.nickname, opponent: "AI EASY"})
+			return m.ChangeScene(&Game{player: 1, difficulty: 0, nickname: m.nickname, host: m.nickname, opponent: "AI EASY", boardSize: m.boardSize, variant: v.variant, opening: v.opening})
 		case buttonNormal:
-			return m.ChangeScene(&Game{player: 1, difficulty: 1, nickname: m.nickname, host: m.nickname, opponent: "AI NORMAL"})
+			return m.ChangeScene(&Game{player: 1, difficulty: 1, nickname: m.nickname, host: m.nickname, opponent: "AI NORMAL", boardSize: m.boardSize, variant: v.variant, opening: v.opening})
 		case buttonHard:
-			return m.ChangeScene(&Game{player: 1, difficulty: 2, nickname: m.nickname, host: m.nickname, opponent: "AI HARD"})
+			return m.ChangeScene(&Game{player: 1, difficulty: 2, nickname: m.nickname, host: m.nickname, opponent: "AI HARD", boardSize: m.boardSize, variant: v.variant, opening: v.opening})
+		case buttonAdaptive:
+			return m.ChangeScene(&Game{player: 1, adaptive: true, nickname: m.nickname, host: m.nickname, opponent: "AI ADAPTIVE", boardSize: m.boardSize, variant: v.variant, opening: v.opening})
 		case buttonHostGame:
-			return m.ChangeScene(&Game{player: 1, multiplayer: true, nickname: m.nickname, host: m.nickname, opponent: "[OPPONENT]"})
+			return m.ChangeScene(&Host{nickname: m.nickname, boardSize: m.boardSize, variant: m.variant})
 		case buttonJoinGame:
 			// return m.ChangeScene(&Game{player: 2, multiplayer: true, nickname: m.nickname})
 			return m.ChangeScene(&Join{nickname: m.nickname})
+		case buttonHotSeat:
+			return m.ChangeScene(&Game{player: 1, hotseat: true, nickname: m.nickname, host: m.nickname, opponent: "GUEST", boardSize: m.boardSize, variant: v.variant, opening: v.opening})
 		case buttonChangeName:
 			return m.ChangeScene(&Nickname{ChangeNickname: true})
 		}
@@ -87,32 +140,60 @@ func (m *Menu) OnTerminalEvent(event termbox.Event) error {
 	return nil
 }
 
+func (m *Menu) cycleBoardSize() {
+	for i, size := range boardSizes {
+		if size == m.size() {
+			m.boardSize = boardSizes[(i+1)%len(boardSizes)]
+			return
+		}
+	}
+}
+
+func (m *Menu) size() int {
+	if m.boardSize == 0 {
+		return common.DefaultBoardSize
+	}
+	return m.boardSize
+}
+
 func (m *Menu) Draw() {
 	drawSplash()
 
+	if Branding.Name != "" {
+		draw.Draw(draw.Offset(draw.Center, 0, 1), draw.Accent, strings.ToUpper(Branding.Name))
+	}
+	if Branding.MOTD != "" {
+		draw.Draw(draw.Offset(draw.Center, 0, 2), draw.Normal, Branding.MOTD)
+	}
+
 	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Did you know? Your name is %s!", strings.ToUpper(m.nickname)))
 
-	buttonColors := [6]draw.Color{draw.Normal, draw.Normal, draw.Normal, draw.Normal, draw.Normal, draw.Normal}
+	buttonColors := [8]draw.Color{draw.Normal, draw.Normal, draw.Normal, draw.Normal, draw.Normal, draw.Normal, draw.Normal, draw.Normal}
 	buttonColors[m.button] = draw.Inverted
 
 	multiplayerButtonColor := draw.Normal
 	multiplayerOffset := draw.Offset(draw.CenterRight, 1, 3)
-	if m.button == buttonHostGame || m.button == buttonJoinGame {
+	if m.button == buttonHostGame || m.button == buttonJoinGame || m.button == buttonHotSeat {
 		multiplayerButtonColor = draw.Inverted
 		draw.Draw(draw.Offset(multiplayerOffset, 1, 2), buttonColors[buttonHostGame], "[ HOST GAME ]")
 		draw.Draw(draw.Offset(multiplayerOffset, 1, 4), buttonColors[buttonJoinGame], "[ JOIN GAME ]")
+		draw.Draw(draw.Offset(multiplayerOffset, 2, 6), buttonColors[buttonHotSeat], "[ HOT SEAT ]")
 	}
 
 	singleplayerButtonColor := draw.Normal
 	singleplayerOffset := draw.Offset(draw.CenterLeft, -1, 3)
-	if m.button == buttonEasy || m.button == buttonNormal || m.button == buttonHard {
+	if m.button == buttonEasy || m.button == buttonNormal || m.button == buttonHard || m.button == buttonAdaptive {
 		singleplayerButtonColor = draw.Inverted
 		draw.Draw(draw.Offset(singleplayerOffset, -4, 2), buttonColors[buttonEasy], "[ EASY ]")
 		draw.Draw(draw.Offset(singleplayerOffset, -3, 4), buttonColors[buttonNormal], "[ NORMAL ]")
 		draw.Draw(draw.Offset(singleplayerOffset, -4, 6), buttonColors[buttonHard], "[ HARD ]")
+		draw.Draw(draw.Offset(singleplayerOffset, -2, 8), buttonColors[buttonAdaptive], "[ ADAPTIVE ]")
 	}
 
 	draw.Draw(draw.Offset(draw.CenterLeft, -1, 3), singleplayerButtonColor, "[ SINGLEPLAYER ]")
 	draw.Draw(multiplayerOffset, multiplayerButtonColor, "[ MULTIPLAYER ]")
 	draw.Draw(draw.Offset(draw.TopRight, 0, 2), buttonColors[buttonChangeName], "[ CHANGE NAME ]")
+	draw.Draw(draw.Offset(draw.BotLeft, 0, -4), draw.Normal, "[S] SETTINGS  [P] PROFILE")
+	draw.Draw(draw.Offset(draw.BotLeft, 0, -2), draw.Normal, "[V] VARIANT: "+gameVariants[m.variant].label)
+	draw.Draw(draw.BotLeft, draw.Normal, fmt.Sprintf("[B] BOARD SIZE: %dx%d", m.size(), m.size()))
 }
@@ -1,23 +1,30 @@
 package scenes
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"log"
 	"os"
-	"path"
 	"strings"
 	"unicode"
 
 	"github.com/nsf/termbox-go"
 
 	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/messages"
 )
 
 const maxNicknameLen = 10
 
+// Nickname asks for the player's name, and claims it on the server so that nobody else can play as
+// it.
 type Nickname struct {
 	scene
 	nickname       string
 	ChangeNickname bool
+	claiming       bool
+	offline        bool
+	notice         string
 }
 
 func (n *Nickname) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
@@ -30,24 +37,71 @@ func (n *Nickname) Setup(changeScene ChangeScene, sendMessage SendMessage) error
 	}
 
 	if n.nickname != "" && !n.ChangeNickname {
+		// Names chosen before they could be claimed are claimed now. The server answers after the
+		// menu has started, so the token is saved by the engine.
+		if loadTokens()[n.nickname] == "" {
+			if err := n.SendMessage(messages.ClaimNickname{Nickname: n.nickname}); err != nil {
+				return err
+			}
+		}
 		return n.ChangeScene(&Menu{nickname: n.nickname})
 	}
 
 	return nil
 }
 
+func (n *Nickname) RegisterHandlers(h *Handlers) {
+	h.OnNicknameClaimed(func(m *messages.NicknameClaimed) error {
+		if n.claiming && m.Nickname == n.nickname {
+			n.claiming = false
+			return n.done()
+		}
+		return nil
+	})
+
+	h.OnError(func(m *messages.Error) error {
+		if !n.claiming {
+			return nil
+		}
+		n.claiming = false
+		if m.Code == messages.ErrorNicknameTaken {
+			n.notice = strings.ToUpper(m.Error)
+			return nil
+		}
+		// Older servers can't claim names, so the name is used without a claim.
+		return n.done()
+	})
+}
+
+func (n *Nickname) OnConnectionStatus(status ConnectionStatus) error {
+	n.offline = status != Connected
+	if n.offline {
+		n.claiming = false
+	}
+	return nil
+}
+
 func (n *Nickname) OnTerminalEvent(event termbox.Event) error {
+	if n.claiming {
+		return nil
+	}
+
+	n.notice = ""
+
 	// Handle change scene.
 	if event.Key == termbox.KeyEnter {
 		if n.nickname == "" {
 			return nil
 		}
 
-		if err := n.save(); err != nil {
-			return err
+		// Offline, the name can only be claimed once the server is reachable again.
+		if n.offline {
+			return n.done()
 		}
 
-		return n.ChangeScene(&Menu{nickname: n.nickname})
+		n.claiming = true
+
+		return n.SendMessage(messages.ClaimNickname{Nickname: n.nickname, Token: loadTokens()[n.nickname]})
 	}
 
 	// Handle typing.
@@ -109,6 +163,22 @@ func (n *Nickname) Draw() {
 
 	cursorX := min(len(n.nickname), maxNicknameLen-1) - maxNicknameLen/2
 	draw.SetCursor(draw.Offset(draw.Center, cursorX, 4))
+
+	switch {
+	case n.claiming:
+		draw.Draw(draw.Offset(draw.Center, 0, 6), draw.Normal, "CLAIMING YOUR NAME...")
+	case n.notice != "":
+		draw.Draw(draw.Offset(draw.Center, 0, 6), draw.Normal, n.notice)
+	}
+}
+
+// done saves the nickname and goes to the menu.
+func (n *Nickname) done() error {
+	if err := n.save(); err != nil {
+		return err
+	}
+
+	return n.ChangeScene(&Menu{nickname: n.nickname})
 }
 
 func (n *Nickname) HasFreeKeyboardInput() bool {
@@ -116,23 +186,27 @@ func (n *Nickname) HasFreeKeyboardInput() bool {
 }
 
 func (n *Nickname) load() error {
-	configPath, err := n.configPath()
+	var err error
+	n.nickname, err = loadNickname()
+	return err
+}
+
+func loadNickname() (string, error) {
+	configPath, err := configFilePath(credentialSetting("nickname"))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	nicknameBytes, err := ioutil.ReadFile(configPath)
 	if os.IsNotExist(err) {
-		return nil
+		return "", nil
 	}
 
-	n.nickname = strings.ToLower(string(nicknameBytes))
-
-	return err
+	return strings.ToLower(string(nicknameBytes)), err
 }
 
 func (n *Nickname) save() error {
-	configPath, err := n.configPath()
+	configPath, err := configFilePath(credentialSetting("nickname"))
 	if err != nil {
 		return err
 	}
@@ -140,18 +214,54 @@ func (n *Nickname) save() error {
 	return ioutil.WriteFile(configPath, []byte(n.nickname), 0600)
 }
 
-func (n *Nickname) configPath() (string, error) {
-	homedir, err := os.UserHomeDir()
+// LoadCredentials returns the saved nickname, and the token that proves it was claimed, to send to
+// the server when connecting. Either may be empty.
+func LoadCredentials() (nickname, token string) {
+	nickname, err := loadNickname()
 	if err != nil {
-		return "", err
+		log.Printf("Failed to load the nickname: %v", err)
+		return "", ""
 	}
 
-	dirPath := path.Join(homedir, ".othelgo")
-	filePath := path.Join(dirPath, "nickname")
+	return nickname, loadTokens()[nickname]
+}
 
-	if err := os.MkdirAll(dirPath, 0700); err != nil {
-		return "", err
+// SaveToken saves the token for a claimed nickname. The tokens of every nickname that was claimed
+// are kept, so that switching back to an old nickname still works.
+func SaveToken(nickname, token string) error {
+	tokens := loadTokens()
+	tokens[nickname] = token
+
+	b, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	return saveSetting(credentialSetting("tokens"), string(b))
+}
+
+// ForgetToken forgets the token for a nickname that is no longer claimed, such as because the
+// player's data was deleted.
+func ForgetToken(nickname string) error {
+	tokens := loadTokens()
+	delete(tokens, nickname)
+
+	b, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	return saveSetting(credentialSetting("tokens"), string(b))
+}
+
+func loadTokens() map[string]string {
+	tokens := map[string]string{}
+
+	if value := loadSetting(credentialSetting("tokens")); value != "" {
+		if err := json.Unmarshal([]byte(value), &tokens); err != nil {
+			log.Printf("Ignoring the saved tokens: %v", err)
+		}
 	}
 
-	return filePath, nil
+	return tokens
 }
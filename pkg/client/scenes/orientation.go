@@ -0,0 +1,56 @@
+package scenes
+
+import (
+	"strconv"
+)
+
+// orientation is how the board is shown on screen. It is a local preference, so moves are always
+// converted back to the server's coordinates before they are sent.
+type orientation int
+
+const (
+	orientationNormal orientation = iota
+	orientationRotated
+	orientationMirrored
+	orientationFlipped
+	orientationCount
+)
+
+var orientationLabels = map[orientation]string{
+	orientationNormal:   "NORMAL",
+	orientationRotated:  "ROTATED",
+	orientationMirrored: "MIRRORED",
+	orientationFlipped:  "FLIPPED",
+}
+
+// transform converts between board coordinates and screen coordinates. Each orientation is its own
+// inverse, so the same function converts in both directions.
+func (o orientation) transform(size, x, y int) (int, int) {
+	switch o {
+	case orientationRotated:
+		return size - 1 - x, size - 1 - y
+	case orientationMirrored:
+		return size - 1 - x, y
+	case orientationFlipped:
+		return x, size - 1 - y
+	default:
+		return x, y
+	}
+}
+
+func (o orientation) next() orientation {
+	return (o + 1) % orientationCount
+}
+
+func loadOrientation() orientation {
+	o, err := strconv.Atoi(loadSetting("orientation"))
+	if err != nil || o < 0 || orientation(o) >= orientationCount {
+		return orientationNormal
+	}
+
+	return orientation(o)
+}
+
+func saveOrientation(o orientation) error {
+	return saveSetting("orientation", strconv.Itoa(int(o)))
+}
@@ -0,0 +1,274 @@
+package scenes
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Profile shows a player's rating and how the quality of their moves in rated games is trending,
+// or a heat map of where they tend to play and lose disks. On their own profile, players can change
+// what the others see of them.
+type Profile struct {
+	scene
+	nickname     string
+	player       string
+	profile      *messages.Profile
+	alertMessage string
+
+	// privacy is the player's privacy settings, which are only loaded on their own profile.
+	privacy       *messages.Privacy
+	notice        string
+	confirmDelete bool
+
+	// heatMap is which heat map is shown instead of the quality of moves, if any.
+	heatMap heatMapView
+}
+
+type heatMapView int
+
+const (
+	heatMapOff heatMapView = iota
+	heatMapPlays
+	heatMapLosses
+)
+
+func (p *Profile) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
+	if err := p.scene.Setup(changeScene, sendMessage); err != nil {
+		return err
+	}
+
+	if p.player == "" {
+		p.player = p.nickname
+	}
+
+	p.alertMessage = "Loading profile"
+
+	if p.player == p.nickname {
+		if err := sendMessage(messages.GetPrivacy{Nickname: p.nickname}); err != nil {
+			return err
+		}
+	}
+
+	return sendMessage(messages.GetProfile{Player: p.player})
+}
+
+func (p *Profile) RegisterHandlers(h *Handlers) {
+	h.OnProfile(func(m *messages.Profile) error {
+		if m.Player == p.player {
+			p.profile = m
+			p.alertMessage = ""
+		}
+		return nil
+	})
+
+	h.OnPrivacy(func(m *messages.Privacy) error {
+		if m.Nickname == p.nickname {
+			p.privacy = m
+			p.notice = ""
+		}
+		return nil
+	})
+
+	h.OnDataJobQueued(func(m *messages.DataJobQueued) error {
+		p.notice = fmt.Sprintf("YOUR DATA WILL BE %sD SOON", strings.ToUpper(m.Kind))
+		return nil
+	})
+
+	h.OnDataJobDone(func(m *messages.DataJobDone) error {
+		switch m.Kind {
+		case messages.DataJobExport:
+			p.notice = "YOUR DATA WAS SAVED IN " + strings.ToUpper(exportFileName(m.Nickname))
+		case messages.DataJobDelete:
+			p.notice = "YOUR DATA WAS DELETED"
+		}
+		return nil
+	})
+
+	h.OnError(func(m *messages.Error) error {
+		if p.profile == nil {
+			p.alertMessage = m.Error
+		} else {
+			p.notice = strings.ToUpper(m.Error)
+		}
+		return nil
+	})
+}
+
+func (p *Profile) OnTerminalEvent(event termbox.Event) error {
+	if unicode.ToUpper(event.Ch) == 'M' {
+		return p.ChangeScene(&Menu{nickname: p.nickname})
+	}
+
+	if unicode.ToUpper(event.Ch) == 'T' && p.profile != nil && p.profile.HeatMap != nil {
+		p.heatMap = (p.heatMap + 1) % 3
+		return nil
+	}
+
+	if p.privacy == nil {
+		return nil
+	}
+
+	settings := messages.SetPrivacy{
+		Nickname:      p.nickname,
+		HideHistory:   p.privacy.HideHistory,
+		HideRating:    p.privacy.HideRating,
+		AppearOffline: p.privacy.AppearOffline,
+	}
+
+	switch unicode.ToUpper(event.Ch) {
+	case 'H':
+		settings.HideHistory = !settings.HideHistory
+	case 'R':
+		settings.HideRating = !settings.HideRating
+	case 'O':
+		settings.AppearOffline = !settings.AppearOffline
+	case 'E':
+		return p.SendMessage(messages.ExportData{Nickname: p.nickname})
+	case 'D':
+		// Deleting is permanent, so it takes a second press.
+		if !p.confirmDelete {
+			p.confirmDelete = true
+			p.notice = "PRESS [D] AGAIN TO DELETE ALL YOUR DATA"
+			return nil
+		}
+		p.confirmDelete = false
+		return p.SendMessage(messages.DeleteData{Nickname: p.nickname})
+	default:
+		return nil
+	}
+
+	p.confirmDelete = false
+	return p.SendMessage(settings)
+}
+
+func (p *Profile) Draw() {
+	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Your name is %s!", strings.ToUpper(p.nickname)))
+	draw.Draw(draw.BotRight, draw.Normal, "[M] MENU  [Q] QUIT")
+
+	if p.profile == nil {
+		drawAlert(p.alertMessage)
+		return
+	}
+
+	if p.profile.HeatMap != nil && !p.profile.HistoryHidden {
+		draw.Draw(draw.Offset(draw.BotRight, 0, -2), draw.Normal, "[T] HEAT MAP")
+	}
+
+	draw.Draw(draw.BotLeft, draw.Normal, p.notice)
+	if p.privacy != nil {
+		draw.Draw(draw.Offset(draw.BotLeft, 0, -2), draw.Normal, fmt.Sprintf("HIDE  [H] HISTORY: %s  [R] RATING: %s  [O] ONLINE: %s",
+			onOff(p.privacy.HideHistory), onOff(p.privacy.HideRating), onOff(p.privacy.AppearOffline)))
+		draw.Draw(draw.Offset(draw.BotLeft, 0, -3), draw.Normal, "DATA  [E] EXPORT  [D] DELETE")
+	}
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, -4), draw.Normal, fmt.Sprintf("=== %s ===", strings.ToUpper(p.player)))
+
+	// The heat map takes the place of the rating too, so that the board fits.
+	if p.heatMap != heatMapOff && p.profile.HeatMap != nil && !p.profile.HistoryHidden {
+		p.drawHeatMap()
+		return
+	}
+
+	if p.profile.RatingHidden {
+		draw.Draw(draw.Offset(draw.CenterTop, 0, -2), draw.Normal, "RATING IS PRIVATE")
+	} else {
+		draw.Draw(draw.Offset(draw.CenterTop, 0, -2), draw.Normal, fmt.Sprintf("RATING %d AFTER %d RATED GAMES", p.profile.Rating, p.profile.Games))
+	}
+
+	if p.profile.HistoryHidden {
+		draw.Draw(draw.Offset(draw.CenterTop, 0, 1), draw.Normal, "GAME HISTORY IS PRIVATE")
+		return
+	}
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, 1), draw.Inverted, fmt.Sprintf(" %-8s %-17s %-17s %-8s ", "", "RECENT GAMES", "EARLIER GAMES", ""))
+	draw.Draw(draw.Offset(draw.CenterTop, 0, 2), draw.Normal, fmt.Sprintf(" %-8s %-8s %-8s %-8s %-8s %-8s ", "PHASE", "LOSS", "BLUNDERS", "LOSS", "BLUNDERS", "TREND"))
+
+	for i, phase := range p.profile.Phases {
+		draw.Draw(draw.Offset(draw.CenterTop, 0, 4+i*2), draw.Normal, fmt.Sprintf(" %-8s %-8s %-8s %-8s %-8s %-8s ",
+			strings.ToUpper(phase.Phase),
+			averageLoss(phase.Recent), blunderRate(phase.Recent),
+			averageLoss(phase.Earlier), blunderRate(phase.Earlier),
+			trend(phase)))
+	}
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, 11), draw.Normal, "LOSS IS HOW MANY HUNDREDTHS OF A DISK WORSE THAN THE")
+	draw.Draw(draw.Offset(draw.CenterTop, 0, 12), draw.Normal, "BEST MOVE THE AVERAGE MOVE WAS. LOWER IS BETTER.")
+}
+
+// drawHeatMap draws a grid of the board's squares, colored by how often the player moved on each
+// square or lost disks on it. Hotter squares are also drawn with denser shades, which show without
+// colors too.
+func (p *Profile) drawHeatMap() {
+	heatMap := p.profile.HeatMap
+	counts, title := heatMap.Plays, "MOVES IN %d GAMES, AT MOST %d ON A SQUARE"
+	if p.heatMap == heatMapLosses {
+		counts, title = heatMap.Losses, "DISKS LOST IN %d GAMES, AT MOST %d ON A SQUARE"
+	}
+
+	most := 0
+	for _, column := range counts {
+		for _, n := range column {
+			if n > most {
+				most = n
+			}
+		}
+	}
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, -2), draw.Normal, fmt.Sprintf(title, heatMap.Games, most))
+
+	shades := []rune{'·', '░', '▒', '▓', '█'}
+	size := len(counts)
+
+	for x, column := range counts {
+		for y, n := range column {
+			level := 0
+			if most > 0 {
+				level = n * (draw.HeatLevels - 1) / most
+			}
+			cell := strings.Repeat(string(shades[level*(len(shades)-1)/(draw.HeatLevels-1)]), 3)
+			draw.Draw(draw.Offset(draw.CenterTop, (x-size/2)*4+2, y), draw.Heat(level), cell)
+		}
+	}
+}
+
+func averageLoss(stats messages.MoveStats) string {
+	if stats.Moves == 0 {
+		return "-"
+	}
+	return fmt.Sprint(stats.AverageLoss)
+}
+
+func blunderRate(stats messages.MoveStats) string {
+	if stats.Moves == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", stats.Blunders*100/stats.Moves)
+}
+
+// trend says whether a player's moves in a phase have been better in their recent games than in the
+// games before.
+func trend(phase messages.PhaseQuality) string {
+	switch {
+	case phase.Recent.Moves == 0 || phase.Earlier.Moves == 0:
+		return "-"
+	case phase.Recent.AverageLoss < phase.Earlier.AverageLoss:
+		return "BETTER"
+	case phase.Recent.AverageLoss > phase.Earlier.AverageLoss:
+		return "WORSE"
+	default:
+		return "SAME"
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
@@ -0,0 +1,342 @@
+package scenes
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+var aiNames = map[int]string{0: "AI EASY", 1: "AI NORMAL", 2: "AI HARD"}
+
+// Replay steps through the moves of a finished game.
+type Replay struct {
+	scene
+	nickname     string
+	host         string
+	opponent     string
+	hostDisk     common.Disk
+	size         int
+	variant      string
+	opening      string
+	orientation  orientation
+	disks        diskStyle
+	moves        [][2]int
+	boards       []common.Board
+	kibitz       []messages.KibitzLine
+	step         int
+	alertMessage string
+
+	// analysis is a scratch game that branches off the replay at the current step, if any.
+	analysis *analysisBoard
+}
+
+// analysisBoard is a scratch board for trying moves other than the ones that were played.
+type analysisBoard struct {
+	start      int
+	moves      [][2]int
+	board      common.Board
+	whoseTurn  common.Disk
+	evaluation ai.Analysis
+	cursorX    int
+	cursorY    int
+}
+
+// analysisDepth is how many turns ahead the engine looks when evaluating the analysis board.
+const analysisDepth = 4
+
+func (r *Replay) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
+	if err := r.scene.Setup(changeScene, sendMessage); err != nil {
+		return err
+	}
+
+	r.alertMessage = "Loading replay"
+	r.orientation = loadOrientation()
+	r.disks = loadDiskStyle()
+
+	return sendMessage(messages.GetReplay{Host: r.host})
+}
+
+func (r *Replay) RegisterHandlers(h *Handlers) {
+	h.OnReplay(func(m *messages.Replay) error {
+		r.size = m.BoardSize
+		if r.size == 0 {
+			r.size = common.DefaultBoardSize
+		}
+
+		r.variant, r.opening = m.Variant, m.Opening
+
+		boards, err := common.ReplayMoves(r.startingBoard(), m.Moves)
+		if err != nil {
+			log.Printf("Replay is corrupt: %v", err)
+		}
+
+		r.opponent = m.Opponent
+		if r.opponent == "" {
+			r.opponent = aiNames[m.Difficulty]
+			if m.Adaptive {
+				r.opponent = "AI ADAPTIVE"
+			}
+		}
+
+		r.hostDisk = m.HostDisk
+		r.moves = m.Moves
+		r.boards = boards
+		r.kibitz = m.Kibitz
+		r.step = 0
+		r.alertMessage = ""
+		return nil
+	})
+
+	h.OnError(func(m *messages.Error) error {
+		r.alertMessage = m.Error
+		return nil
+	})
+}
+
+func (r *Replay) OnTerminalEvent(event termbox.Event) error {
+	switch unicode.ToUpper(event.Ch) {
+	case 'M':
+		return r.ChangeScene(&Menu{nickname: r.nickname})
+	case 'O':
+		r.orientation = r.orientation.next()
+		return saveOrientation(r.orientation)
+	case 'G':
+		r.disks.shapes = !r.disks.shapes
+		return saveShapes(r.disks.shapes)
+	}
+
+	if len(r.boards) == 0 {
+		return nil
+	}
+
+	if unicode.ToUpper(event.Ch) == 'A' {
+		if r.analysis != nil {
+			// Snap back to the recorded game.
+			r.analysis = nil
+			return nil
+		}
+		return r.analyze(nil)
+	}
+
+	if r.analysis != nil {
+		return r.onAnalysisEvent(event)
+	}
+
+	dx, _ := getDirectionPressed(event)
+	r.step = clamp(r.step+dx, 0, len(r.boards))
+
+	switch event.Key {
+	case termbox.KeyHome:
+		r.step = 0
+	case termbox.KeyEnd:
+		r.step = len(r.boards) - 1
+	}
+
+	return nil
+}
+
+// analyze starts the analysis board at the current step of the replay, with some moves played
+// after it.
+func (r *Replay) analyze(moves [][2]int) error {
+	all := append(append([][2]int{}, r.moves[:r.step]...), moves...)
+
+	board, whoseTurn, err := common.ReplayTurn(r.startingBoard(), all)
+	if err != nil {
+		return err
+	}
+
+	a := &analysisBoard{
+		start:     r.step,
+		moves:     moves,
+		board:     board,
+		whoseTurn: whoseTurn,
+	}
+
+	if r.analysis != nil {
+		a.cursorX, a.cursorY = r.analysis.cursorX, r.analysis.cursorY
+	}
+
+	a.evaluation = ai.Analyze(board, r.variant, whoseTurn, analysisDepth)
+
+	r.analysis = a
+
+	return nil
+}
+
+func (r *Replay) onAnalysisEvent(event termbox.Event) error {
+	a := r.analysis
+
+	if unicode.ToUpper(event.Ch) == 'U' && len(a.moves) > 0 {
+		return r.analyze(a.moves[:len(a.moves)-1])
+	}
+
+	dx, dy := getDirectionPressed(event)
+	a.cursorX = clamp(a.cursorX+dx, 0, r.size)
+	a.cursorY = clamp(a.cursorY+dy, 0, r.size)
+
+	if event.Key == termbox.KeyEnter && !common.GameOver(a.board) {
+		x, y := r.orientation.transform(r.size, a.cursorX, a.cursorY)
+		if _, updated := common.ApplyMove(a.board, x, y, a.whoseTurn); updated {
+			return r.analyze(append(append([][2]int{}, a.moves...), [2]int{x, y}))
+		}
+	}
+
+	return nil
+}
+
+func (r *Replay) Describe() (details, state string) {
+	return "Watching a replay", fmt.Sprintf("%s vs. %s", r.host, r.opponent)
+}
+
+func (r *Replay) Draw() {
+	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Replay of %s's game", strings.ToUpper(r.host)))
+	if r.variant == common.VariantAnti {
+		draw.Draw(draw.TopLeft, draw.Normal, "ANTI-REVERSI: FEWEST DISKS WINS")
+	}
+	draw.Draw(draw.BotRight, draw.Normal, fmt.Sprintf("[←/→] STEP  [O] VIEW: %s  [M] MENU  [Q] QUIT", orientationLabels[r.orientation]))
+
+	if len(r.boards) == 0 {
+		if !fitBoard(common.DefaultBoardSize) {
+			draw.Draw(draw.Center, draw.Normal, r.alertMessage)
+			return
+		}
+		drawBoardOutline(common.DefaultBoardSize)
+		drawAlert(r.alertMessage)
+		return
+	}
+
+	if !fitBoard(r.size) {
+		r.drawCompact()
+		return
+	}
+
+	if r.analysis != nil {
+		r.drawAnalysis()
+		return
+	}
+
+	board := r.boards[r.step]
+	drawBoardOutline(r.size)
+	drawDisks(board, r.orientation, r.disks)
+
+	if r.step > 0 {
+		move := r.moves[r.step-1]
+		highlightMove(board, r.orientation, move[0], move[1], r.disks)
+	}
+
+	r.drawScore(board)
+	r.drawKibitz()
+
+	draw.Draw(draw.BotLeft, draw.Normal, fmt.Sprintf("MOVE %d/%d  [A] ANALYZE", r.step, len(r.boards)-1))
+}
+
+// replayKibitzLines is the number of lines of kibitz shown at each step of a replay.
+const replayKibitzLines = 3
+
+// drawKibitz shows what the spectators said while the board was at the current step, above the
+// move counter.
+func (r *Replay) drawKibitz() {
+	var lines []messages.KibitzLine
+	for _, line := range r.kibitz {
+		if line.Move == r.step {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > replayKibitzLines {
+		lines = lines[len(lines)-replayKibitzLines:]
+	}
+
+	for i, line := range lines {
+		text := fmt.Sprintf("%s: %s", strings.ToUpper(line.Nickname), line.Text)
+		draw.Draw(draw.Offset(draw.BotLeft, 0, i-len(lines)), draw.Normal, truncate(text, minTerminalWidth))
+	}
+}
+
+func (r *Replay) drawAnalysis() {
+	a := r.analysis
+
+	drawBoardOutline(r.size)
+	drawDisks(a.board, r.orientation, r.disks)
+
+	if len(a.moves) > 0 {
+		move := a.moves[len(a.moves)-1]
+		highlightMove(a.board, r.orientation, move[0], move[1], r.disks)
+	}
+
+	r.drawScore(a.board)
+
+	if common.GameOver(a.board) {
+		draw.HideCursor()
+	} else {
+		// Current turn indicator
+		yOffset := 0
+		if a.whoseTurn == common.Player2 {
+			yOffset = 2
+		}
+		draw.Draw(draw.Offset(draw.MiddleLeft, 4, yOffset), draw.Normal, "﹌")
+
+		draw.SetCursor(cursorAnchor(r.size, a.cursorX, a.cursorY))
+	}
+
+	status := fmt.Sprintf("ANALYSIS FROM MOVE %d  EVAL: %s", a.start, r.formatEvaluation(a.evaluation.Score))
+	if a.evaluation.HasMove {
+		status += fmt.Sprintf("  BEST: %s", squareName(a.evaluation.Best))
+	}
+
+	draw.Draw(draw.Offset(draw.BotLeft, 0, -1), draw.Normal, status)
+	draw.Draw(draw.BotLeft, draw.Normal, "[ENTER] PLAY  [U] UNDO  [A] BACK TO GAME")
+}
+
+func (r *Replay) drawScore(board common.Board) {
+	p1Score, p2Score := common.KeepScore(board)
+	p1Name, p2Name := r.playerNames()
+
+	drawDisk(draw.Offset(draw.MiddleLeft, 4, -1), 1, r.disks)
+	draw.Draw(draw.Offset(draw.MiddleLeft, 7, -1), draw.Normal, fmt.Sprintf("%s: %-2d", p1Name, p1Score))
+	drawDisk(draw.Offset(draw.MiddleLeft, 4, 1), 2, r.disks)
+	draw.Draw(draw.Offset(draw.MiddleLeft, 7, 1), draw.Normal, fmt.Sprintf("%s: %-2d", p2Name, p2Score))
+}
+
+// startingBoard returns the board that the replayed game started with.
+func (r *Replay) startingBoard() common.Board {
+	return common.NewBoardWithOpening(r.size, r.opening)
+}
+
+// playerNames returns the names of the players of each disk. The host played black unless the
+// replay says otherwise.
+func (r *Replay) playerNames() (p1Name, p2Name string) {
+	if r.hostDisk == common.Player2 {
+		return strings.ToUpper(r.opponent), strings.ToUpper(r.host)
+	}
+	return strings.ToUpper(r.host), strings.ToUpper(r.opponent)
+}
+
+// formatEvaluation describes an engine score from black's point of view.
+func (r *Replay) formatEvaluation(score float64) string {
+	p1Name, p2Name := r.playerNames()
+
+	switch {
+	case math.IsInf(score, 1):
+		return fmt.Sprintf("%s WINS", p1Name)
+	case math.IsInf(score, -1):
+		return fmt.Sprintf("%s WINS", p2Name)
+	default:
+		return fmt.Sprintf("%+.1f", score)
+	}
+}
+
+// squareName names a square like a chess square, with a letter for the column and a number for the
+// row.
+func squareName(square [2]int) string {
+	return fmt.Sprintf("%c%d", 'A'+square[0], square[1]+1)
+}
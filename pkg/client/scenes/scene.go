@@ -2,13 +2,14 @@ package scenes
 
 import (
 	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/common"
 )
 
 // Scene is responsible for the logic and view of a particular page of the application.
-// It can handle websocket messages and terminal events.
+// It can handle terminal events, and websocket messages if it is also a MessageHandler.
 type Scene interface {
 	Setup(changeScene ChangeScene, sendMessage SendMessage) error
-	OnMessage(message interface{}) error
 	OnTerminalEvent(event termbox.Event) error
 	Tick() bool
 	Draw()
@@ -16,6 +17,57 @@ type Scene interface {
 	OnQuit()
 }
 
+// Describer is implemented by scenes that can describe what the player is doing, for display
+// outside of the game such as Discord rich presence.
+type Describer interface {
+	Describe() (details, state string)
+}
+
+// Inviter is implemented by scenes with a game that friends can join or watch from outside the
+// game, such as from the player's Discord profile. joinable is false if the game can only be
+// watched, and ok is false if there is no such game right now.
+type Inviter interface {
+	Invitation() (host string, joinable, ok bool)
+}
+
+// AcceptInvite returns the scene for joining or watching a host's game that the player was invited
+// to from outside the game. It is not ok while the player is in a multiplayer game, which leaving
+// would forfeit.
+func AcceptInvite(current Scene, host string, spectate bool) (Scene, bool) {
+	if g, ok := current.(*Game); ok && g.multiplayer && !common.GameOver(g.board) && !g.ended {
+		return nil, false
+	}
+
+	nickname, _ := LoadCredentials()
+
+	if spectate {
+		return &Dashboard{nickname: nickname, invited: host}, true
+	}
+
+	return &Game{player: 2, multiplayer: true, nickname: nickname, host: host, opponent: host}, true
+}
+
+// ConnectionStatus is the state of the connection to the server.
+type ConnectionStatus int
+
+const (
+	Connected ConnectionStatus = iota
+	Reconnecting
+)
+
+// ConnectionObserver is implemented by scenes that need to know when the connection to the server
+// is lost and restored, such as to pick up where they left off.
+type ConnectionObserver interface {
+	OnConnectionStatus(status ConnectionStatus) error
+}
+
+// SequenceObserver is implemented by scenes that keep track of the numbered messages of a game, so
+// that they can ask for the ones they missed. OnSequence is called before the handler with the
+// message's number, and returns false if the message was already received and should be ignored.
+type SequenceObserver interface {
+	OnSequence(seq int) bool
+}
+
 // types for Scene setup method.
 type (
 	ChangeScene func(Scene) error
@@ -35,11 +87,6 @@ func (s *scene) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
 	return nil
 }
 
-func (s *scene) OnMessage(_ interface{}) error {
-	// Default implementation is a no-op.
-	return nil
-}
-
 func (s *scene) Tick() bool {
 	// Default implementation is a no-op.
 	return false
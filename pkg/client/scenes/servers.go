@@ -0,0 +1,288 @@
+package scenes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/lan"
+)
+
+// Server is a server that the client can play on.
+type Server struct {
+	Name string `json:"name"`
+
+	// Addr is the websocket address of the server, which is empty for the public server.
+	Addr string `json:"addr"`
+
+	// LAN is true for servers found on the local network, which aren't saved in the servers file.
+	LAN bool `json:"-"`
+}
+
+// publicServer is the server that the client plays on unless another one is picked.
+var publicServer = Server{Name: "public"}
+
+// activeServer is the server that the client plays on. Nicknames and tokens are saved separately
+// for each server, since a name claimed on one server means nothing on another.
+var activeServer = publicServer
+
+// SetServer sets the server that the client plays on, which also switches to the nickname and
+// tokens saved for it.
+func SetServer(server Server) {
+	activeServer = server
+}
+
+// ActiveServer returns the server that the client plays on.
+func ActiveServer() Server {
+	return activeServer
+}
+
+// SwitchServer is sent by a scene to connect to another server. The engine handles it instead of
+// sending it to the server.
+type SwitchServer struct {
+	Addr string
+}
+
+// LoadLastServer returns the server that was picked last time, or the public server.
+func LoadLastServer() Server {
+	server := publicServer
+
+	if value := loadSetting("server"); value != "" {
+		if err := json.Unmarshal([]byte(value), &server); err != nil {
+			log.Printf("Ignoring the last server: %v", err)
+			return publicServer
+		}
+	}
+
+	return server
+}
+
+func saveLastServer(server Server) error {
+	b, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+
+	return saveSetting("server", string(b))
+}
+
+// loadServers returns the public server, followed by the servers in the servers file, which is a
+// JSON list of servers such as [{"name": "work", "addr": "ws://10.0.0.5:9000"}].
+func loadServers() []Server {
+	servers := []Server{publicServer}
+
+	value := loadSetting("servers")
+	if value == "" {
+		return servers
+	}
+
+	var configured []Server
+	if err := json.Unmarshal([]byte(value), &configured); err != nil {
+		log.Printf("Ignoring the servers file: %v", err)
+		return servers
+	}
+
+	for _, s := range configured {
+		if s.Addr == "" {
+			continue
+		}
+		if s.Name == "" {
+			s.Name = s.Addr
+		}
+		servers = append(servers, s)
+	}
+
+	return servers
+}
+
+// credentialSetting returns the name of the setting that a credential, such as the nickname, is
+// saved in for the active server. The public server keeps the names from before servers could be
+// picked, so that players don't lose their names.
+func credentialSetting(name string) string {
+	if activeServer.Addr == "" {
+		return name
+	}
+
+	addr := activeServer.Addr
+	for _, scheme := range []string{"ws://", "wss://"} {
+		addr = strings.TrimPrefix(addr, scheme)
+	}
+
+	return name + "@" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, addr)
+}
+
+// lanDiscoverTimeout is how long the server picker waits for servers on the local network.
+const lanDiscoverTimeout = 2 * time.Second
+
+// maxServersShown is how many servers fit on the screen at once.
+const maxServersShown = 6
+
+// Servers lets the player pick a server to play on: the public server, the servers in the servers
+// file, or a server found on the local network.
+type Servers struct {
+	scene
+	servers  []Server
+	selected int
+	found    chan []lan.Server
+}
+
+func (s *Servers) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
+	if err := s.scene.Setup(changeScene, sendMessage); err != nil {
+		return err
+	}
+
+	s.servers = loadServers()
+
+	for i, server := range s.servers {
+		if server.Addr == activeServer.Addr {
+			s.selected = i
+		}
+	}
+
+	// A last server that isn't configured was found on the local network. It stays listed until
+	// the scan is done, in case it is found again.
+	if s.servers[s.selected].Addr != activeServer.Addr {
+		last := activeServer
+		last.LAN = true
+		s.servers = append(s.servers, last)
+		s.selected = len(s.servers) - 1
+	}
+
+	s.scan()
+
+	return nil
+}
+
+// scan looks for servers on the local network in the background. Tick lists them once found.
+func (s *Servers) scan() {
+	found := make(chan []lan.Server, 1)
+	s.found = found
+
+	go func() {
+		servers, err := lan.Discover(context.Background(), lanDiscoverTimeout)
+		if err != nil {
+			log.Printf("Failed to look for servers on the local network: %v", err)
+		}
+		found <- servers
+	}()
+}
+
+func (s *Servers) Tick() bool {
+	select {
+	case found := <-s.found:
+		s.found = nil
+		s.listLAN(found)
+		return true
+	default:
+		return false
+	}
+}
+
+// listLAN replaces the listed servers from the local network with the ones that were just found,
+// keeping the selected server selected if it is still listed.
+func (s *Servers) listLAN(found []lan.Server) {
+	selected := s.servers[s.selected]
+
+	var servers []Server
+	for _, server := range s.servers {
+		if !server.LAN {
+			servers = append(servers, server)
+		}
+	}
+
+	for _, f := range found {
+		server := Server{Name: f.Name, Addr: f.Addr, LAN: true}
+		if !contains(servers, server.Addr) {
+			servers = append(servers, server)
+		}
+	}
+
+	s.servers = servers
+	s.selected = 0
+
+	for i, server := range s.servers {
+		if server.Addr == selected.Addr {
+			s.selected = i
+		}
+	}
+}
+
+func contains(servers []Server, addr string) bool {
+	for _, s := range servers {
+		if s.Addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Servers) OnTerminalEvent(event termbox.Event) error {
+	if unicode.ToUpper(event.Ch) == 'R' && s.found == nil {
+		s.scan()
+		return nil
+	}
+
+	if event.Key == termbox.KeyEnter {
+		return s.pick()
+	}
+
+	_, dy := getDirectionPressed(event)
+	s.selected = clamp(s.selected+dy, 0, len(s.servers))
+
+	return nil
+}
+
+// pick connects to the selected server, and asks for the nickname to play as on it.
+func (s *Servers) pick() error {
+	server := s.servers[s.selected]
+
+	if err := saveLastServer(server); err != nil {
+		log.Printf("Failed to save the last server: %v", err)
+	}
+
+	SetServer(server)
+
+	if err := s.SendMessage(SwitchServer{Addr: server.Addr}); err != nil {
+		return err
+	}
+
+	return s.ChangeScene(&Nickname{})
+}
+
+func (s *Servers) Draw() {
+	drawSplash()
+
+	draw.Draw(draw.BotRight, draw.Normal, "[ENTER] PLAY  [R] SCAN LAN  [Q] QUIT")
+	if s.found != nil {
+		draw.Draw(draw.BotLeft, draw.Normal, "SCANNING THE LOCAL NETWORK...")
+	}
+
+	draw.Draw(draw.Offset(draw.Center, 0, 2), draw.Normal, "Pick a server:")
+
+	first := clamp(s.selected-maxServersShown+1, 0, len(s.servers))
+	for i := first; i < len(s.servers) && i < first+maxServersShown; i++ {
+		label := strings.ToUpper(s.servers[i].Name)
+		if s.servers[i].LAN {
+			label += " (LAN)"
+		}
+
+		color := draw.Normal
+		if i == s.selected {
+			color = draw.Inverted
+		}
+
+		draw.Draw(draw.Offset(draw.Center, 0, 4+i-first), color, fmt.Sprintf("[ %s ]", label))
+	}
+}
@@ -0,0 +1,61 @@
+package scenes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Times that the server sends are read by its clock, which may not agree with this terminal's. The
+// engine estimates how far apart the clocks are from pings, and scenes convert the server's times
+// to this terminal's clock before measuring from them or showing them.
+
+var (
+	serverOffset      time.Duration
+	serverOffsetKnown bool
+)
+
+// SetServerOffset sets how far the server's clock is ahead of this terminal's.
+func SetServerOffset(offset time.Duration) {
+	serverOffset, serverOffsetKnown = offset, true
+}
+
+// localTime converts a time that the server sent, in milliseconds since the Unix epoch by its
+// clock, to this terminal's clock. Until the clocks are compared, or if the server didn't send the
+// time, the time is taken to be now, when the message was received.
+func localTime(serverMillis int64) time.Time {
+	if serverMillis == 0 || !serverOffsetKnown {
+		return time.Now()
+	}
+	return time.Unix(0, serverMillis*int64(time.Millisecond)).Add(-serverOffset)
+}
+
+// formatAgo describes how long ago something happened, such as "3 MIN AGO".
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "JUST NOW"
+	case d < time.Hour:
+		return fmt.Sprintf("%d MIN AGO", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d HR AGO", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%d DAYS AGO", int(d/(24*time.Hour)))
+	}
+}
+
+// formatDeadline describes a time in the terminal's time zone, with the day if it isn't today, such
+// as "WED 15:04".
+func formatDeadline(t, now time.Time) string {
+	t, now = t.Local(), now.Local()
+
+	layout := "15:04"
+	switch {
+	case t.Sub(now) >= 6*24*time.Hour:
+		layout = "Jan 2 15:04"
+	case t.YearDay() != now.YearDay() || t.Year() != now.Year():
+		layout = "Mon 15:04"
+	}
+
+	return strings.ToUpper(t.Format(layout))
+}
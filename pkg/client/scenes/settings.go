@@ -0,0 +1,250 @@
+package scenes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Local settings are stored as one small file each in the ~/.othelgo directory.
+
+// configFilePath returns the path of a file in the local settings directory, creating the
+// directory if needed.
+func configFilePath(name string) (string, error) {
+	homedir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	dirPath := filepath.Join(homedir, ".othelgo")
+	filePath := filepath.Join(dirPath, name)
+
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// loadSetting returns the value of a local setting, or an empty string if it has not been saved.
+func loadSetting(name string) string {
+	configPath, err := configFilePath(name)
+	if err != nil {
+		return ""
+	}
+
+	b, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
+
+func saveSetting(name, value string) error {
+	configPath, err := configFilePath(name)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configPath, []byte(value), 0600)
+}
+
+// loadShapes returns whether disks should be drawn with a different shape for each player, so
+// that the game can be played without telling the colors apart.
+func loadShapes() bool {
+	return draw.Monochrome || loadSetting("shapes") == "on"
+}
+
+func saveShapes(shapes bool) error {
+	value := "off"
+	if shapes {
+		value = "on"
+	}
+
+	return saveSetting("shapes", value)
+}
+
+// loadASCII returns whether disks should be drawn with ASCII characters, for terminals and fonts
+// that can't show the Unicode ones.
+func loadASCII() bool {
+	return loadSetting("glyphs") == "ascii"
+}
+
+func saveASCII(ascii bool) error {
+	value := "unicode"
+	if ascii {
+		value = "ascii"
+	}
+
+	return saveSetting("glyphs", value)
+}
+
+// Lite is true when the client is running on a slow connection, which always reduces motion.
+var Lite bool
+
+// loadReducedMotion returns whether animations should be replaced by static state changes, for
+// players who are sensitive to motion or are playing over a slow connection.
+func loadReducedMotion() bool {
+	return Lite || loadSetting("motion") == "reduced"
+}
+
+func saveReducedMotion(reduced bool) error {
+	value := "full"
+	if reduced {
+		value = "reduced"
+	}
+
+	return saveSetting("motion", value)
+}
+
+const (
+	settingTheme = iota
+	settingGlyphs
+	settingShapes
+	settingMotion
+	settingCount
+)
+
+// Settings lets the player choose how the game is drawn. Each choice is saved as soon as it is
+// made.
+type Settings struct {
+	scene
+	nickname     string
+	boardSize    int
+	variant      int
+	selected     int
+	theme        string
+	disks        diskStyle
+	reduceMotion bool
+	notice       string
+}
+
+func (s *Settings) Setup(changeScene ChangeScene, sendMessage SendMessage) error {
+	if err := s.scene.Setup(changeScene, sendMessage); err != nil {
+		return err
+	}
+
+	s.theme = currentThemeName()
+	s.disks = loadDiskStyle()
+	s.reduceMotion = loadReducedMotion()
+
+	return nil
+}
+
+func (s *Settings) OnTerminalEvent(event termbox.Event) error {
+	if unicode.ToUpper(event.Ch) == 'M' {
+		return s.ChangeScene(&Menu{nickname: s.nickname, boardSize: s.boardSize, variant: s.variant})
+	}
+
+	dx, dy := getDirectionPressed(event)
+	s.selected = clamp(s.selected+dy, 0, settingCount)
+
+	if event.Key == termbox.KeyEnter || event.Key == termbox.KeySpace || dx != 0 {
+		s.notice = ""
+		return s.change()
+	}
+
+	return nil
+}
+
+// change cycles the selected setting to its next choice.
+func (s *Settings) change() error {
+	switch s.selected {
+	case settingTheme:
+		name, err := NextTheme()
+		if err != nil {
+			log.Printf("Failed to change theme: %v", err)
+			s.notice = strings.ToUpper(fmt.Sprintf("THEME ERROR: %v", err))
+		}
+		if name != "" {
+			s.theme = name
+		}
+		return nil
+
+	case settingGlyphs:
+		s.disks.ascii = !s.disks.ascii
+		return saveASCII(s.disks.ascii)
+
+	case settingShapes:
+		if draw.Monochrome {
+			s.notice = "SHAPES ARE ALWAYS ON WITHOUT COLORS"
+			return nil
+		}
+		s.disks.shapes = !s.disks.shapes
+		return saveShapes(s.disks.shapes)
+
+	case settingMotion:
+		if Lite {
+			s.notice = "MOTION IS ALWAYS REDUCED IN LITE MODE"
+			return nil
+		}
+		s.reduceMotion = !s.reduceMotion
+		return saveReducedMotion(s.reduceMotion)
+	}
+
+	return nil
+}
+
+func (s *Settings) Draw() {
+	draw.Draw(draw.TopRight, draw.Normal, fmt.Sprintf("Your name is %s!", strings.ToUpper(s.nickname)))
+	draw.Draw(draw.BotRight, draw.Normal, "[ENTER] CHANGE  [M] MENU  [Q] QUIT")
+	draw.Draw(draw.BotLeft, draw.Normal, s.notice)
+
+	draw.Draw(draw.Offset(draw.CenterTop, 0, -settingCount), draw.Normal, "=== SETTINGS ===")
+
+	glyphs, shapes, motion := "UNICODE", "OFF", "FULL"
+	if s.disks.ascii {
+		glyphs = "ASCII"
+	}
+	if s.disks.shapes {
+		shapes = "ON"
+	}
+	if s.reduceMotion {
+		motion = "REDUCED"
+	}
+
+	for i, label := range [settingCount]string{
+		"THEME: " + strings.ToUpper(s.theme),
+		"DISKS: " + glyphs,
+		"SHAPES: " + shapes,
+		"MOTION: " + motion,
+	} {
+		color := draw.Normal
+		if i == s.selected {
+			color = draw.Inverted
+		}
+		draw.Draw(draw.Offset(draw.CenterTop, 0, i*2-settingCount+3), color, fmt.Sprintf("[ %s ]", label))
+	}
+
+	// A preview of the disks, in the theme's colors.
+	drawDisk(draw.Offset(draw.CenterTop, -2, settingCount+4), common.Player1, s.disks)
+	drawDisk(draw.Offset(draw.CenterTop, 2, settingCount+4), common.Player2, s.disks)
+}
+
+// exportFileName is the name of the file in the local settings directory that a player's exported
+// data is saved in.
+func exportFileName(nickname string) string {
+	return nickname + "-data.json"
+}
+
+// SaveExport saves a player's exported data in the local settings directory.
+func SaveExport(export messages.DataExport) error {
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return saveSetting(exportFileName(export.Nickname), string(b))
+}
@@ -0,0 +1,118 @@
+package scenes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+)
+
+// Themes are either built into the client or YAML files in the ~/.othelgo/themes directory, named
+// after the theme. A theme file with the same name as a built-in theme is ignored. The chosen theme
+// is saved as a local setting.
+
+// LoadTheme applies the chosen theme. The theme file is read again each time, so that changes to
+// it can be seen without restarting. The default theme is used if the file can't be loaded.
+func LoadTheme() error {
+	name := currentThemeName()
+	if theme, ok := draw.BuiltinTheme(name); ok {
+		return draw.SetTheme(theme)
+	}
+
+	if err := loadThemeFile(name); err != nil {
+		_ = draw.SetTheme(draw.DefaultTheme)
+		return err
+	}
+
+	return nil
+}
+
+// NextTheme chooses the next theme, in alphabetical order after the built-in themes, and returns its
+// name.
+func NextTheme() (string, error) {
+	names, err := themeNames()
+	if err != nil {
+		return "", err
+	}
+
+	current := currentThemeName()
+	next := names[0]
+	for i, name := range names {
+		if name == current && i+1 < len(names) {
+			next = names[i+1]
+		}
+	}
+
+	if err := saveSetting("theme", next); err != nil {
+		return "", err
+	}
+
+	return next, LoadTheme()
+}
+
+// currentThemeName returns the name of the chosen theme.
+func currentThemeName() string {
+	if name := loadSetting("theme"); name != "" {
+		return name
+	}
+	return draw.DefaultThemeName
+}
+
+// themeNames returns the built-in themes followed by the themes in the themes directory.
+func themeNames() ([]string, error) {
+	dir, err := themesDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		name := strings.TrimSuffix(f.Name(), ext)
+		if _, ok := draw.BuiltinTheme(name); !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return append(draw.BuiltinThemeNames(), names...), nil
+}
+
+func loadThemeFile(name string) error {
+	dir, err := themesDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, name+".yaml"))
+	if os.IsNotExist(err) {
+		data, err = ioutil.ReadFile(filepath.Join(dir, name+".yml"))
+	}
+	if err != nil {
+		return err
+	}
+
+	theme, err := draw.ParseTheme(data)
+	if err != nil {
+		return fmt.Errorf("theme %s: %w", name, err)
+	}
+
+	return draw.SetTheme(theme)
+}
+
+func themesDir() (string, error) {
+	return configFilePath("themes")
+}
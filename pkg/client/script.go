@@ -0,0 +1,320 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/client/draw"
+	"github.com/armsnyder/othelgo/pkg/client/recording"
+	"github.com/armsnyder/othelgo/pkg/client/scenes"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Scripted clients run without a terminal or a server. A script types keys and plays the server's
+// side by giving the client messages, and the client draws on a draw.Buffer of a fixed size with
+// the default theme, so that the same script always draws the same screens. Scripts make the
+// screenshots and casts for releases, and smoke test the whole client. Each line of a script is
+// one step, and lines that are blank or start with # are skipped:
+//
+//   size <width> <height>   the size of the screen, which must come first (default 100 30)
+//   key <key>               press a key, such as a, enter, tab, space, backspace, up, or esc
+//   type <text>             type each character of the text
+//   recv <json>             receive a message from the server, such as {"action":"pong"}
+//   tick [n]                let n ticks of animation pass (default 1)
+//   screenshot <path>       save the screen's text to a file
+//   expect <text>           fail unless the screen shows the text
+//   sent <action>           fail unless the client has sent a message with the action
+//
+// Pressing a key that quits the client ends the script. Clocks count down in real time, so scenes
+// that show them may differ between runs.
+
+const (
+	defaultScriptWidth  = 100
+	defaultScriptHeight = 30
+
+	// scriptStepDelay is how long a key or a message shows for in a cast, besides ticks.
+	scriptStepDelay = 300 * time.Millisecond
+)
+
+// scriptStep is one line of a script.
+type scriptStep struct {
+	Line    int
+	Command string
+	Events  []termbox.Event
+	Message messages.Wrapper
+	Count   int
+	Arg     string
+}
+
+var scriptKeys = map[string]termbox.Event{
+	"enter":     {Type: termbox.EventKey, Key: termbox.KeyEnter},
+	"tab":       {Type: termbox.EventKey, Key: termbox.KeyTab},
+	"space":     {Type: termbox.EventKey, Key: termbox.KeySpace, Ch: ' '},
+	"backspace": {Type: termbox.EventKey, Key: termbox.KeyBackspace2},
+	"up":        {Type: termbox.EventKey, Key: termbox.KeyArrowUp},
+	"down":      {Type: termbox.EventKey, Key: termbox.KeyArrowDown},
+	"left":      {Type: termbox.EventKey, Key: termbox.KeyArrowLeft},
+	"right":     {Type: termbox.EventKey, Key: termbox.KeyArrowRight},
+	"esc":       {Type: termbox.EventKey, Key: termbox.KeyEsc},
+}
+
+// parseScript reads a script, and returns the size of its screen and its steps.
+func parseScript(r io.Reader) (width, height int, steps []scriptStep, err error) {
+	width, height = defaultScriptWidth, defaultScriptHeight
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		command, arg := text, ""
+		if i := strings.IndexByte(text, ' '); i >= 0 {
+			command, arg = text[:i], strings.TrimSpace(text[i+1:])
+		}
+
+		step := scriptStep{Line: line, Command: command, Arg: arg}
+
+		switch command {
+		case "size":
+			if len(steps) > 0 {
+				return 0, 0, nil, fmt.Errorf("line %d: size must come before the other steps", line)
+			}
+			if _, err := fmt.Sscan(arg, &width, &height); err != nil || width < 1 || height < 1 {
+				return 0, 0, nil, fmt.Errorf("line %d: invalid size %q", line, arg)
+			}
+			continue
+
+		case "key":
+			event, ok := scriptKeys[arg]
+			if !ok {
+				runes := []rune(arg)
+				if len(runes) != 1 {
+					return 0, 0, nil, fmt.Errorf("line %d: unknown key %q", line, arg)
+				}
+				event = termbox.Event{Type: termbox.EventKey, Ch: runes[0]}
+			}
+			step.Events = []termbox.Event{event}
+
+		case "type":
+			// Spaces at the end of the text are kept.
+			for _, ch := range strings.TrimPrefix(strings.TrimLeft(scanner.Text(), " \t"), "type ") {
+				step.Events = append(step.Events, termbox.Event{Type: termbox.EventKey, Ch: ch})
+			}
+
+		case "recv":
+			if err := json.Unmarshal([]byte(arg), &step.Message); err != nil {
+				return 0, 0, nil, fmt.Errorf("line %d: invalid message: %w", line, err)
+			}
+
+		case "tick":
+			step.Count = 1
+			if arg != "" {
+				if step.Count, err = strconv.Atoi(arg); err != nil || step.Count < 1 {
+					return 0, 0, nil, fmt.Errorf("line %d: invalid number of ticks %q", line, arg)
+				}
+			}
+
+		case "screenshot", "expect", "sent":
+			if arg == "" {
+				return 0, 0, nil, fmt.Errorf("line %d: %s needs an argument", line, command)
+			}
+
+		default:
+			return 0, 0, nil, fmt.Errorf("line %d: unknown step %q", line, command)
+		}
+
+		steps = append(steps, step)
+	}
+
+	return width, height, steps, scanner.Err()
+}
+
+// RunScript runs the client with a script instead of a terminal and a server. If castPath is set,
+// every screen that the client draws is saved there as an asciinema cast.
+func RunScript(opts Options, script io.Reader, castPath string) (err error) {
+	width, height, steps, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+
+	finish, err := setupFileLogger()
+	if err != nil {
+		return err
+	}
+	defer finish(err)
+
+	// Settings are kept in a home of the script's own, so that scripts don't depend on the saved
+	// nickname or theme, and don't change them.
+	home, err := ioutil.TempDir("", "othelgo-script")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+	for _, name := range []string{"HOME", "USERPROFILE"} {
+		if err := os.Setenv(name, home); err != nil {
+			return err
+		}
+	}
+
+	buf := draw.NewBuffer(width, height)
+	draw.SetScreen(buf)
+
+	// Confetti is random, so the same seed makes the same confetti.
+	rand.Seed(1)
+
+	scenes.Lite = opts.Lite
+	scenes.Compact = opts.Compact
+
+	var cast *recording.Cast
+	if castPath != "" {
+		f, err := os.Create(castPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if cast, err = recording.NewCast(f, width, height, "Othelgo"); err != nil {
+			return err
+		}
+	}
+
+	s := &scriptRun{buf: buf, cast: cast}
+
+	if _, err := setupChangeSceneHandler(&s.scene, &scenes.Nickname{ChangeNickname: true}, s.drawAndFlush, &s.outbox, new(scenes.ConnectionStatus)); err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		quit, err := s.run(step)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", step.Line, err)
+		}
+		if quit {
+			log.Println("Quitting scene")
+			s.scene.OnQuit()
+			break
+		}
+	}
+
+	return nil
+}
+
+// scriptRun is the state of a running script.
+type scriptRun struct {
+	scene      scenes.Scene
+	buf        *draw.Buffer
+	cast       *recording.Cast
+	outbox     scriptOutbox
+	decoration string
+	notice     string
+
+	// at is how long the script has run for in the cast.
+	at time.Duration
+}
+
+func (s *scriptRun) drawAndFlush() error {
+	if err := drawAndFlushScene(s.scene, s.decoration, s.notice, "", scenes.Connected); err != nil {
+		return err
+	}
+
+	if s.cast == nil {
+		return nil
+	}
+
+	return s.cast.Frame(s.at, s.buf.ANSI())
+}
+
+// run runs one step of a script, and returns whether the client quit.
+func (s *scriptRun) run(step scriptStep) (quit bool, err error) {
+	switch step.Command {
+	case "key", "type":
+		for _, event := range step.Events {
+			if shouldInterrupt(event, s.scene) {
+				return true, nil
+			}
+			s.at += scriptStepDelay
+			if err := handleTerminalEvent(event, s.scene, s.drawAndFlush); err != nil {
+				return false, err
+			}
+		}
+
+	case "recv":
+		s.at += scriptStepDelay
+		setDecoration := func(decoration string) { s.decoration = decoration }
+		setMaintenance := func(notice string) { s.notice = notice }
+		if err := handleMessage(step.Message, setDecoration, setMaintenance, s.scene, s.drawAndFlush); err != nil {
+			return false, err
+		}
+
+	case "tick":
+		for i := 0; i < step.Count; i++ {
+			s.at += tickInterval
+			if err := handleTick(s.scene, s.drawAndFlush); err != nil {
+				return false, err
+			}
+		}
+
+	case "screenshot":
+		return false, os.WriteFile(step.Arg, []byte(s.buf.String()), 0644) //nolint:gosec
+
+	case "expect":
+		if !strings.Contains(s.buf.String(), step.Arg) {
+			return false, fmt.Errorf("the screen doesn't show %q:\n%s", step.Arg, s.buf)
+		}
+
+	case "sent":
+		if !s.outbox.take(step.Arg) {
+			return false, fmt.Errorf("the client hasn't sent %s", step.Arg)
+		}
+	}
+
+	return false, nil
+}
+
+// scriptOutbox keeps the actions of the messages that a scripted client sends.
+type scriptOutbox struct {
+	actions []string
+}
+
+func (o *scriptOutbox) Send(message interface{}) error {
+	data, err := json.Marshal(messages.Wrapper{Message: message})
+	if err != nil {
+		return err
+	}
+
+	var wrapper struct{ Action string }
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+
+	o.actions = append(o.actions, wrapper.Action)
+
+	return nil
+}
+
+// take removes the first sent message with an action, and the ones sent before it. It returns
+// whether there was one.
+func (o *scriptOutbox) take(action string) bool {
+	for i, a := range o.actions {
+		if a == action {
+			o.actions = o.actions[i+1:]
+			return true
+		}
+	}
+	return false
+}
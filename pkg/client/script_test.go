@@ -0,0 +1,90 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestParseScript(t *testing.T) {
+	script := "# A comment\n" +
+		"size 40 10\n" +
+		"\n" +
+		"type ab \n" +
+		"key enter\n" +
+		"key q\n" +
+		"recv {\"action\":\"pong\"}\n" +
+		"tick 3\n" +
+		"expect Enter your name:\n"
+
+	width, height, steps, err := parseScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 40 || height != 10 {
+		t.Errorf("got size %dx%d, want 40x10", width, height)
+	}
+	if len(steps) != 6 {
+		t.Fatalf("got %d steps, want 6", len(steps))
+	}
+
+	if got := steps[0].Events; len(got) != 3 || got[0].Ch != 'a' || got[2].Ch != ' ' {
+		t.Errorf("got typed events %v, want a, b, and a space", got)
+	}
+	if got := steps[1].Events; len(got) != 1 || got[0].Key != termbox.KeyEnter {
+		t.Errorf("got key events %v, want enter", got)
+	}
+	if got := steps[2].Events; len(got) != 1 || got[0].Ch != 'q' {
+		t.Errorf("got key events %v, want q", got)
+	}
+	if _, ok := steps[3].Message.Message.(*messages.Pong); !ok {
+		t.Errorf("got message %T, want *messages.Pong", steps[3].Message.Message)
+	}
+	if steps[4].Count != 3 {
+		t.Errorf("got %d ticks, want 3", steps[4].Count)
+	}
+	if steps[5].Arg != "Enter your name:" || steps[5].Line != 9 {
+		t.Errorf("got expect %q on line %d, want \"Enter your name:\" on line 9", steps[5].Arg, steps[5].Line)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	for _, script := range []string{
+		"key enter\nsize 40 10\n",
+		"size 40\n",
+		"key nope\n",
+		"recv {\n",
+		"tick 0\n",
+		"expect\n",
+		"jump\n",
+	} {
+		if _, _, _, err := parseScript(strings.NewReader(script)); err == nil {
+			t.Errorf("got no error for script %q", script)
+		}
+	}
+}
+
+func TestScriptOutbox(t *testing.T) {
+	var outbox scriptOutbox
+	for _, message := range []interface{}{messages.Ping{}, messages.ClaimNickname{Nickname: "flame"}, messages.Ping{}} {
+		if err := outbox.Send(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !outbox.take("claimNickname") {
+		t.Error("claimNickname wasn't sent")
+	}
+	if outbox.take("claimNickname") {
+		t.Error("claimNickname was taken twice")
+	}
+	if !outbox.take("ping") {
+		t.Error("the second ping wasn't sent")
+	}
+	if outbox.take("ping") {
+		t.Error("the first ping was taken after the claim")
+	}
+}
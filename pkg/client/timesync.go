@@ -0,0 +1,59 @@
+package client
+
+import (
+	"time"
+)
+
+// Estimating the server's clock. The client notes when it sends each Ping, and the Pong that answers
+// it says what time the server's clock read. Assuming that the ping and the pong took as long, the
+// server's clock read that time halfway through the round trip. Round trips vary with the network,
+// so of the latest few estimates, the one from the fastest round trip is the most accurate.
+
+// timeSyncSamples is how many of the latest estimates are kept.
+const timeSyncSamples = 8
+
+// timeSync estimates how far the server's clock is ahead of this one.
+type timeSync struct {
+	// sentAt is when the ping waiting for a pong was sent, or zero if none is.
+	sentAt  time.Time
+	samples []timeSample
+}
+
+type timeSample struct {
+	offset, roundTrip time.Duration
+}
+
+// pinged notes that a ping was sent.
+func (s *timeSync) pinged(now time.Time) {
+	s.sentAt = now
+}
+
+// ponged takes the server time of a pong received at now, and returns the best estimate of the
+// server's clock offset. It is not ok if the pong doesn't answer a noted ping, or is from a server
+// that doesn't send its time.
+func (s *timeSync) ponged(serverTime int64, now time.Time) (offset time.Duration, ok bool) {
+	if s.sentAt.IsZero() || serverTime == 0 {
+		return 0, false
+	}
+
+	roundTrip := now.Sub(s.sentAt)
+	midpoint := s.sentAt.Add(roundTrip / 2)
+	s.sentAt = time.Time{}
+
+	s.samples = append(s.samples, timeSample{
+		offset:    time.Unix(0, serverTime*int64(time.Millisecond)).Sub(midpoint),
+		roundTrip: roundTrip,
+	})
+	if len(s.samples) > timeSyncSamples {
+		s.samples = s.samples[len(s.samples)-timeSyncSamples:]
+	}
+
+	best := s.samples[0]
+	for _, sample := range s.samples[1:] {
+		if sample.roundTrip < best.roundTrip {
+			best = sample
+		}
+	}
+
+	return best.offset, true
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeSync(t *testing.T) {
+	var s timeSync
+	start := time.Unix(1000, 0)
+	serverMillis := func(t time.Time) int64 { return t.Add(5*time.Second).UnixNano() / int64(time.Millisecond) }
+
+	if _, ok := s.ponged(serverMillis(start), start); ok {
+		t.Error("got an offset from a pong that answers no ping")
+	}
+
+	// A slow round trip whose ping took longer than its pong skews the estimate.
+	s.pinged(start)
+	offset, ok := s.ponged(serverMillis(start.Add(3*time.Second)), start.Add(4*time.Second))
+	if !ok || offset != 6*time.Second {
+		t.Errorf("got offset %v, %t, want 6s, true", offset, ok)
+	}
+
+	// A fast round trip is more accurate, and is kept over the slow one.
+	s.pinged(start.Add(time.Minute))
+	offset, ok = s.ponged(serverMillis(start.Add(time.Minute+50*time.Millisecond)), start.Add(time.Minute+100*time.Millisecond))
+	if !ok || offset != 5*time.Second {
+		t.Errorf("got offset %v, %t, want 5s, true", offset, ok)
+	}
+
+	s.pinged(start.Add(2 * time.Minute))
+	offset, ok = s.ponged(serverMillis(start.Add(2*time.Minute+time.Second)), start.Add(2*time.Minute+time.Second))
+	if !ok || offset != 5*time.Second {
+		t.Errorf("got offset %v, %t, want the fast sample's 5s, true", offset, ok)
+	}
+
+	// Older servers don't send their time.
+	s.pinged(start.Add(3 * time.Minute))
+	if _, ok := s.ponged(0, start.Add(3*time.Minute)); ok {
+		t.Error("got an offset from a pong without a server time")
+	}
+}
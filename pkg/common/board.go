@@ -1,8 +1,19 @@
 package common
 
-import "strings"
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
-const BoardSize = 8
+// Supported board sizes.
+const (
+	MinBoardSize     = 6
+	DefaultBoardSize = 8
+	MaxBoardSize     = 10
+)
 
 type Disk uint8
 
@@ -11,16 +22,21 @@ const (
 	Player2 = Disk(2)
 )
 
-type Board [BoardSize][BoardSize]Disk
+// Board holds the disks of a game. Squares are indexed by x and then y. Boards of any size use the
+// same fixed-size array, and only the squares within Size are in play.
+type Board struct {
+	Size    int
+	Squares [MaxBoardSize][MaxBoardSize]Disk
+}
 
 func (b Board) String() string {
 	// This function makes Board implement fmt.Stringer so that it renders visually in test outputs.
 	var sb strings.Builder
-	for y := 0; y < BoardSize; y++ {
+	for y := 0; y < b.Size; y++ {
 		sb.WriteRune('\n')
-		for x := 0; x < BoardSize; x++ {
+		for x := 0; x < b.Size; x++ {
 			var ch rune
-			switch b[x][y] {
+			switch b.Squares[x][y] {
 			case 0:
 				ch = '_'
 			case 1:
@@ -34,3 +50,205 @@ func (b Board) String() string {
 
 	return sb.String()
 }
+
+// MarshalJSON encodes the board as a Size x Size array, so that the default board has the same
+// encoding it had before other board sizes were supported. Boards are encoded with every move, so
+// the array is written by hand rather than by reflection.
+func (b Board) MarshalJSON() ([]byte, error) {
+	return b.AppendJSON(make([]byte, 0, 2+b.Size*(2*b.Size+2))), nil
+}
+
+// AppendJSON appends the encoding of MarshalJSON to dst.
+func (b Board) AppendJSON(dst []byte) []byte {
+	dst = append(dst, '[')
+	for x := 0; x < b.Size; x++ {
+		if x > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, '[')
+		for y := 0; y < b.Size; y++ {
+			if y > 0 {
+				dst = append(dst, ',')
+			}
+			dst = strconv.AppendUint(dst, uint64(b.Squares[x][y]), 10)
+		}
+		dst = append(dst, ']')
+	}
+	return append(dst, ']')
+}
+
+// UnmarshalJSON decodes a board encoded by MarshalJSON, inferring its size. Like MarshalJSON, it
+// reads the array by hand.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	d := boardDecoder{data: data}
+
+	if d.skipSpace(); bytes.HasPrefix(d.data[d.pos:], []byte("null")) {
+		d.pos += len("null")
+		*b = Board{}
+		return d.end()
+	}
+
+	var board Board
+
+	if err := d.expect('['); err != nil {
+		return err
+	}
+
+	// The board is square, so every column must be as long as there are columns. Until they have
+	// all been read, the columns are checked against the first.
+	columnLen := 0
+
+	for !d.skip(']') {
+		x := board.Size
+		if x > 0 {
+			if err := d.expect(','); err != nil {
+				return err
+			}
+		}
+		if x >= MaxBoardSize {
+			return fmt.Errorf("board size is larger than the maximum %d", MaxBoardSize)
+		}
+
+		if err := d.expect('['); err != nil {
+			return err
+		}
+
+		y := 0
+		for ; !d.skip(']'); y++ {
+			if y > 0 {
+				if err := d.expect(','); err != nil {
+					return err
+				}
+			}
+			if y >= MaxBoardSize {
+				return fmt.Errorf("board column %d is longer than the maximum board size %d", x, MaxBoardSize)
+			}
+
+			disk, err := d.disk()
+			if err != nil {
+				return err
+			}
+			board.Squares[x][y] = disk
+		}
+
+		if x == 0 {
+			columnLen = y
+		} else if y != columnLen {
+			return fmt.Errorf("board column %d has length %d, but column 0 has length %d", x, y, columnLen)
+		}
+
+		board.Size++
+	}
+
+	if columnLen != board.Size {
+		return fmt.Errorf("board columns have length %d, but the board size is %d", columnLen, board.Size)
+	}
+
+	if err := d.end(); err != nil {
+		return err
+	}
+
+	*b = board
+	return nil
+}
+
+// boardDecoder reads the JSON encoding of a board.
+type boardDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *boardDecoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// skip skips over c if it is next, and reports whether it was.
+func (d *boardDecoder) skip(c byte) bool {
+	d.skipSpace()
+	if d.pos == len(d.data) || d.data[d.pos] != c {
+		return false
+	}
+	d.pos++
+	return true
+}
+
+func (d *boardDecoder) expect(c byte) error {
+	if !d.skip(c) {
+		return d.errorf("expected %q", c)
+	}
+	return nil
+}
+
+// disk reads a disk, which is an integer from 0 to 255.
+func (d *boardDecoder) disk() (Disk, error) {
+	d.skipSpace()
+
+	start, n := d.pos, 0
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' && n <= math.MaxUint8 {
+		n = n*10 + int(d.data[d.pos]-'0')
+		d.pos++
+	}
+
+	if d.pos == start || n > math.MaxUint8 {
+		d.pos = start
+		return 0, d.errorf("expected a disk")
+	}
+
+	return Disk(n), nil
+}
+
+// end checks that nothing but space is left.
+func (d *boardDecoder) end() error {
+	d.skipSpace()
+	if d.pos < len(d.data) {
+		return d.errorf("unexpected data after the board")
+	}
+	return nil
+}
+
+func (d *boardDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid board at offset %d: %s", d.pos, fmt.Sprintf(format, args...))
+}
+
+// Starting layouts of the four disks in the center of the board.
+const (
+	// OpeningStandard puts each player's disks on a diagonal.
+	OpeningStandard = "standard"
+
+	// OpeningParallel puts each player's disks side by side.
+	OpeningParallel = "parallel"
+)
+
+// NewBoard returns a board of the specified size, set up with the four disks of a new game.
+func NewBoard(size int) Board {
+	return NewBoardWithOpening(size, OpeningStandard)
+}
+
+// NewBoardWithOpening returns a new game's board with the disks in one of the starting layouts. An
+// unknown opening, including an empty one, is the standard opening.
+func NewBoardWithOpening(size int, opening string) Board {
+	board := Board{Size: size}
+
+	c := size / 2
+	board.Squares[c-1][c-1] = Player1
+
+	switch opening {
+	case OpeningParallel:
+		board.Squares[c][c-1] = Player1
+		board.Squares[c-1][c] = Player2
+		board.Squares[c][c] = Player2
+	default:
+		board.Squares[c-1][c] = Player2
+		board.Squares[c][c-1] = Player2
+		board.Squares[c][c] = Player1
+	}
+
+	return board
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Board encodings, which a client picks for the boards it is sent.
+const (
+	// BoardEncodingJSON encodes a board as a JSON array of columns. It is the default.
+	BoardEncodingJSON = "json"
+
+	// BoardEncodingCompact encodes a board with EncodeBoard.
+	BoardEncodingCompact = "compact"
+)
+
+// EncodeBoard encodes a board compactly, as unpadded URL-safe base64 of a byte holding the size
+// followed by the squares packed 2 bits each, four to a byte. Squares are ordered by x and then y,
+// starting from the highest bits of each byte. An 8x8 board encodes to 23 characters.
+func EncodeBoard(board Board) string {
+	data := make([]byte, encodedBoardLen(board.Size))
+	data[0] = byte(board.Size)
+
+	for i := 0; i < board.Size*board.Size; i++ {
+		disk := board.Squares[i/board.Size][i%board.Size]
+		data[1+i/4] |= byte(disk) << (6 - 2*(i%4))
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeBoard decodes a board encoded by EncodeBoard.
+func DecodeBoard(s string) (Board, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Board{}, fmt.Errorf("invalid board encoding: %w", err)
+	}
+
+	if len(data) == 0 {
+		return Board{}, fmt.Errorf("invalid board encoding: missing size")
+	}
+
+	size := int(data[0])
+	if size > MaxBoardSize {
+		return Board{}, fmt.Errorf("board size %d is larger than the maximum %d", size, MaxBoardSize)
+	}
+
+	if len(data) != encodedBoardLen(size) {
+		return Board{}, fmt.Errorf("invalid board encoding: %d bytes for board size %d", len(data), size)
+	}
+
+	board := Board{Size: size}
+
+	for i := 0; i < 4*(len(data)-1); i++ {
+		disk := Disk(data[1+i/4]>>(6-2*(i%4))) & 3
+
+		if i >= size*size {
+			// The bits after the last square must be empty, so that each board has one encoding.
+			if disk != 0 {
+				return Board{}, fmt.Errorf("invalid board encoding: padding is not empty")
+			}
+			continue
+		}
+
+		if disk > Player2 {
+			return Board{}, fmt.Errorf("invalid disk %d at %s", disk, Notation(i/size, i%size))
+		}
+
+		board.Squares[i/size][i%size] = disk
+	}
+
+	return board, nil
+}
+
+// encodedBoardLen is the number of bytes of an encoded board of a size.
+func encodedBoardLen(size int) int {
+	return 1 + (size*size+3)/4
+}
@@ -0,0 +1,102 @@
+package common_test
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestEncodeBoard(t *testing.T) {
+	got := EncodeBoard(NewBoard(DefaultBoardSize))
+	if want := "CAAAAAAAAAGAAkAAAAAAAAA"; got != want {
+		t.Errorf("EncodeBoard() = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeBoardRoundTripAllSmallBoards(t *testing.T) {
+	// Every board up to 3x3, with each square empty or holding either disk.
+	for size := 0; size <= 3; size++ {
+		squares := size * size
+		count := 1
+		for i := 0; i < squares; i++ {
+			count *= 3
+		}
+
+		for n := 0; n < count; n++ {
+			board := Board{Size: size}
+			for i, rest := 0, n; i < squares; i, rest = i+1, rest/3 {
+				board.Squares[i/size][i%size] = Disk(rest % 3)
+			}
+			assertRoundTrip(t, board)
+		}
+	}
+}
+
+func TestEncodeBoardRoundTripEverySquare(t *testing.T) {
+	for size := 1; size <= MaxBoardSize; size++ {
+		for x := 0; x < size; x++ {
+			for y := 0; y < size; y++ {
+				for _, disk := range []Disk{Player1, Player2} {
+					board := Board{Size: size}
+					board.Squares[x][y] = disk
+					assertRoundTrip(t, board)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeBoardRoundTripRandomBoards(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{MinBoardSize, DefaultBoardSize, MaxBoardSize} {
+		assertRoundTrip(t, NewBoard(size))
+		assertRoundTrip(t, NewBoardWithOpening(size, OpeningParallel))
+
+		for n := 0; n < 1000; n++ {
+			board := Board{Size: size}
+			for x := 0; x < size; x++ {
+				for y := 0; y < size; y++ {
+					board.Squares[x][y] = Disk(rng.Intn(3))
+				}
+			}
+			assertRoundTrip(t, board)
+		}
+	}
+}
+
+func TestDecodeBoardInvalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":           "",
+		"not base64":      "CAAAAAAAAAGA*kAAAAAAAAA",
+		"too large":       "CwAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		"too short":       "CAAAAAAAAAGAAkAAAAAAAA",
+		"too long":        "CAAAAAAAAAGAAkAAAAAAAAAA",
+		"invalid disk":    "CMAAAAAAAAGAAkAAAAAAAAA",
+		"padding not set": "AwAAAQ",
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			if board, err := DecodeBoard(s); err == nil {
+				t.Errorf("DecodeBoard(%q) = %v, want an error", s, board)
+			}
+		})
+	}
+}
+
+func assertRoundTrip(t *testing.T, board Board) {
+	t.Helper()
+
+	s := EncodeBoard(board)
+
+	got, err := DecodeBoard(s)
+	if err != nil {
+		t.Fatalf("DecodeBoard(%q) error = %v for board %v", s, err, board)
+	}
+
+	if got != board {
+		t.Fatalf("DecodeBoard(EncodeBoard()) = %v, want %v", got, board)
+	}
+}
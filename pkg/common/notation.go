@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// Squares are written in algebraic notation, which names the column with a letter and the row with
+// a number counting from the top, so the square at x=3, y=2 is "d3".
+
+// Notation returns the algebraic notation of a square.
+func Notation(x, y int) string {
+	return fmt.Sprintf("%c%d", 'a'+x, y+1)
+}
+
+// ParseNotation returns the square named in algebraic notation. The letter may be either case.
+func ParseNotation(s string) (x, y int, err error) {
+	if len(s) < 2 {
+		return 0, 0, fmt.Errorf("invalid square %q", s)
+	}
+
+	x = int(unicode.ToLower(rune(s[0])) - 'a')
+
+	y, err = strconv.Atoi(s[1:])
+	y--
+
+	if err != nil || x < 0 || x >= MaxBoardSize || y < 0 || y >= MaxBoardSize {
+		return 0, 0, fmt.Errorf("invalid square %q", s)
+	}
+
+	return x, y, nil
+}
+
+// MovesNotation returns the algebraic notation of each move.
+func MovesNotation(moves [][2]int) []string {
+	notation := make([]string, len(moves))
+	for i, move := range moves {
+		notation[i] = Notation(move[0], move[1])
+	}
+	return notation
+}
+
+// ParseMoves returns the moves written in algebraic notation.
+func ParseMoves(notation []string) ([][2]int, error) {
+	moves := make([][2]int, len(notation))
+	for i, s := range notation {
+		x, y, err := ParseNotation(s)
+		if err != nil {
+			return nil, err
+		}
+		moves[i] = [2]int{x, y}
+	}
+	return moves, nil
+}
@@ -0,0 +1,70 @@
+package common_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestNotation(t *testing.T) {
+	tests := []struct {
+		x, y int
+		want string
+	}{
+		{x: 0, y: 0, want: "a1"},
+		{x: 3, y: 2, want: "d3"},
+		{x: 7, y: 7, want: "h8"},
+		{x: 9, y: 9, want: "j10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Notation(tt.x, tt.y); got != tt.want {
+				t.Errorf("Notation() = %q, want %q", got, tt.want)
+			}
+
+			x, y, err := ParseNotation(tt.want)
+			if err != nil {
+				t.Fatalf("ParseNotation() error = %v", err)
+			}
+			if x != tt.x || y != tt.y {
+				t.Errorf("ParseNotation() = %d, %d, want %d, %d", x, y, tt.x, tt.y)
+			}
+		})
+	}
+}
+
+func TestParseNotationUppercase(t *testing.T) {
+	x, y, err := ParseNotation("C5")
+	if err != nil || x != 2 || y != 4 {
+		t.Errorf("ParseNotation() = %d, %d, %v, want 2, 4, nil", x, y, err)
+	}
+}
+
+func TestParseNotationInvalid(t *testing.T) {
+	for _, s := range []string{"", "d", "3d", "k1", "a0", "a11", "a1x"} {
+		t.Run(s, func(t *testing.T) {
+			if _, _, err := ParseNotation(s); err == nil {
+				t.Errorf("ParseNotation(%q) should fail", s)
+			}
+		})
+	}
+}
+
+func TestParseMoves(t *testing.T) {
+	moves := [][2]int{{3, 2}, {2, 4}, {5, 5}}
+
+	notation := MovesNotation(moves)
+	if want := []string{"d3", "c5", "f6"}; !reflect.DeepEqual(notation, want) {
+		t.Errorf("MovesNotation() = %v, want %v", notation, want)
+	}
+
+	got, err := ParseMoves(notation)
+	if err != nil {
+		t.Fatalf("ParseMoves() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, moves) {
+		t.Errorf("ParseMoves() = %v, want %v", got, moves)
+	}
+}
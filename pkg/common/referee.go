@@ -1,17 +1,19 @@
 package common
 
+import "fmt"
+
 func ApplyMove(board Board, x int, y int, player Disk) (Board, bool) {
 	updated := false
 	vectors := [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
 
-	if isInBounds(x, y) && board[x][y] != 0 {
+	if !isInBounds(board, x, y) || board.Squares[x][y] != 0 {
 		return board, false
 	}
 
 	for _, v := range vectors {
 		if flipAlongVector(&board, x+v[0], y+v[1], player, v, 0) {
 			updated = true
-			board[x][y] = player
+			board.Squares[x][y] = player
 		}
 	}
 
@@ -19,11 +21,11 @@ func ApplyMove(board Board, x int, y int, player Disk) (Board, bool) {
 }
 
 func flipAlongVector(board *Board, x int, y int, player Disk, v [2]int, depth int) bool {
-	if !isInBounds(x, y) {
+	if !isInBounds(*board, x, y) {
 		return false
 	}
 
-	disk := board[x][y]
+	disk := board.Squares[x][y]
 
 	switch disk {
 	case 0:
@@ -33,21 +35,21 @@ func flipAlongVector(board *Board, x int, y int, player Disk, v [2]int, depth in
 	}
 
 	if flipAlongVector(board, x+v[0], y+v[1], player, v, depth+1) {
-		board[x][y] = player
+		board.Squares[x][y] = player
 		return true
 	}
 
 	return false
 }
 
-func isInBounds(x int, y int) bool {
-	return x >= 0 && x < BoardSize && y >= 0 && y < BoardSize
+func isInBounds(board Board, x int, y int) bool {
+	return x >= 0 && x < board.Size && y >= 0 && y < board.Size
 }
 
 func KeepScore(board Board) (p1 int, p2 int) {
-	for i := 0; i < BoardSize; i++ {
-		for j := 0; j < BoardSize; j++ {
-			switch board[i][j] {
+	for i := 0; i < board.Size; i++ {
+		for j := 0; j < board.Size; j++ {
+			switch board.Squares[i][j] {
 			case 1:
 				p1++
 			case 2:
@@ -59,6 +61,33 @@ func KeepScore(board Board) (p1 int, p2 int) {
 	return p1, p2
 }
 
+// Variants of the game, which differ in who wins.
+const (
+	// VariantClassic is won by the player with the most disks.
+	VariantClassic = "classic"
+
+	// VariantAnti, or anti-reversi, is won by the player with the fewest disks.
+	VariantAnti = "anti"
+)
+
+// Winner returns the player who wins a finished game of a variant, or 0 for a draw. An empty
+// variant is classic.
+func Winner(board Board, variant string) Disk {
+	p1, p2 := KeepScore(board)
+	if variant == VariantAnti {
+		p1, p2 = p2, p1
+	}
+
+	switch {
+	case p1 > p2:
+		return Player1
+	case p2 > p1:
+		return Player2
+	default:
+		return 0
+	}
+}
+
 func GameOver(board Board) bool {
 	if (board == Board{}) {
 		return false
@@ -67,8 +96,8 @@ func GameOver(board Board) bool {
 }
 
 func HasMoves(board Board, player Disk) bool {
-	for i := 0; i < BoardSize; i++ {
-		for j := 0; j < BoardSize; j++ {
+	for i := 0; i < board.Size; i++ {
+		for j := 0; j < board.Size; j++ {
 			if _, updated := ApplyMove(board, i, j, player); updated {
 				return true
 			}
@@ -76,3 +105,56 @@ func HasMoves(board Board, player Disk) bool {
 	}
 	return false
 }
+
+// LegalMoves returns all of the squares where the player can place a disk.
+func LegalMoves(board Board, player Disk) [][2]int {
+	var moves [][2]int
+	for i := 0; i < board.Size; i++ {
+		for j := 0; j < board.Size; j++ {
+			if _, updated := ApplyMove(board, i, j, player); updated {
+				moves = append(moves, [2]int{i, j})
+			}
+		}
+	}
+	return moves
+}
+
+// WhoseTurn returns the player who should move next, given the player who just moved. A player
+// with no legal moves is skipped.
+func WhoseTurn(board Board, lastPlayer Disk) Disk {
+	if HasMoves(board, lastPlayer%2+1) {
+		return lastPlayer%2 + 1
+	}
+	return lastPlayer
+}
+
+// ReplayMoves plays back a list of moves from a new game's starting board, and returns the board
+// after each move. The first board in the result is the starting position.
+func ReplayMoves(start Board, moves [][2]int) ([]Board, error) {
+	boards, _, err := replay(start, moves)
+	return boards, err
+}
+
+// ReplayTurn plays back a list of moves like ReplayMoves, and returns only the final board along
+// with the player whose turn it is.
+func ReplayTurn(start Board, moves [][2]int) (Board, Disk, error) {
+	boards, player, err := replay(start, moves)
+	return boards[len(boards)-1], player, err
+}
+
+func replay(start Board, moves [][2]int) ([]Board, Disk, error) {
+	boards := []Board{start}
+	player := Player1
+
+	for i, move := range moves {
+		board, updated := ApplyMove(boards[len(boards)-1], move[0], move[1], player)
+		if !updated {
+			return boards, player, fmt.Errorf("move %d (%d,%d) is illegal for player %d", i, move[0], move[1], player)
+		}
+
+		boards = append(boards, board)
+		player = WhoseTurn(board, player)
+	}
+
+	return boards, player, nil
+}
@@ -1,6 +1,9 @@
 package common_test
 
 import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
 	"testing"
 
 	. "github.com/armsnyder/othelgo/pkg/common"
@@ -9,11 +12,12 @@ import (
 type move [2]int
 
 func buildTestBoard(p1, p2 []move) (board Board) {
+	board.Size = DefaultBoardSize
 	for i, moves := range [][]move{p1, p2} {
 		player := i + 1
 		for _, move := range moves {
 			x, y := move[0], move[1]
-			board[x][y] = Disk(player)
+			board.Squares[x][y] = Disk(player)
 		}
 	}
 	return board
@@ -265,3 +269,232 @@ func BenchmarkApplyMove(b *testing.B) {
 		})
 	}
 }
+
+func TestWhoseTurn(t *testing.T) {
+	board := NewBoard(DefaultBoardSize)
+	board, _ = ApplyMove(board, 2, 4, Player1)
+	if got := WhoseTurn(board, Player1); got != Player2 {
+		t.Errorf("WhoseTurn() = %d, want %d", got, Player2)
+	}
+
+	// Player 2 has no disks left, so they cannot move and player 1 goes again.
+	board = buildTestBoard([]move{{0, 0}, {0, 1}}, nil)
+	if got := WhoseTurn(board, Player1); got != Player1 {
+		t.Errorf("WhoseTurn() = %d, want %d", got, Player1)
+	}
+}
+
+func TestLegalMoves(t *testing.T) {
+	want := [][2]int{{2, 4}, {3, 5}, {4, 2}, {5, 3}}
+	if got := LegalMoves(NewBoard(DefaultBoardSize), Player1); !reflect.DeepEqual(got, want) {
+		t.Errorf("LegalMoves() = %v, want %v", got, want)
+	}
+
+	// Player 2 has no disks left, so they have no moves.
+	board := buildTestBoard([]move{{0, 0}, {0, 1}}, nil)
+	if got := LegalMoves(board, Player2); len(got) != 0 {
+		t.Errorf("LegalMoves() = %v, want none", got)
+	}
+}
+
+func TestReplayMoves(t *testing.T) {
+	boards, err := ReplayMoves(NewBoard(DefaultBoardSize), [][2]int{{2, 4}, {2, 5}, {2, 6}})
+	if err != nil {
+		t.Fatalf("ReplayMoves() error = %v", err)
+	}
+	if len(boards) != 4 {
+		t.Fatalf("ReplayMoves() got %d boards, want 4", len(boards))
+	}
+	if boards[0] != NewBoard(DefaultBoardSize) {
+		t.Errorf("ReplayMoves() first board = %v, want new board", boards[0])
+	}
+	wantBoard := buildTestBoard(
+		[]move{{3, 3}, {4, 4}, {2, 4}, {2, 5}, {2, 6}},
+		[]move{{3, 4}, {4, 3}},
+	)
+	if boards[3] != wantBoard {
+		t.Errorf("ReplayMoves() last board = %v, want %v", boards[3], wantBoard)
+	}
+
+	if _, err := ReplayMoves(NewBoard(DefaultBoardSize), [][2]int{{0, 0}}); err == nil {
+		t.Error("ReplayMoves() expected error for illegal move")
+	}
+}
+
+func TestReplayTurn(t *testing.T) {
+	board, player, err := ReplayTurn(NewBoard(DefaultBoardSize), [][2]int{{2, 4}})
+	if err != nil {
+		t.Fatalf("ReplayTurn() error = %v", err)
+	}
+	if player != Player2 {
+		t.Errorf("ReplayTurn() player = %d, want %d", player, Player2)
+	}
+	if p1, p2 := KeepScore(board); p1 != 4 || p2 != 1 {
+		t.Errorf("ReplayTurn() score = %d, %d, want 4, 1", p1, p2)
+	}
+
+	board, player, err = ReplayTurn(NewBoard(DefaultBoardSize), nil)
+	if err != nil || player != Player1 || board != NewBoard(DefaultBoardSize) {
+		t.Errorf("ReplayTurn() of no moves = %v, %d, %v", board, player, err)
+	}
+}
+
+func TestApplyMoveSmallBoard(t *testing.T) {
+	board := NewBoard(6)
+
+	// The starting disks are centred on the smaller board.
+	if p1, p2 := KeepScore(board); p1 != 2 || p2 != 2 {
+		t.Fatalf("KeepScore() = %d, %d, want 2, 2", p1, p2)
+	}
+
+	board, updated := ApplyMove(board, 1, 3, Player1)
+	if !updated {
+		t.Fatal("ApplyMove() expected legal move to update board")
+	}
+	if board.Squares[2][3] != Player1 {
+		t.Errorf("ApplyMove() did not flip disk, got board %v", board)
+	}
+
+	// Squares beyond the edge of a small board are out of bounds.
+	if _, updated := ApplyMove(board, 6, 2, Player2); updated {
+		t.Error("ApplyMove() expected move off the board to be rejected")
+	}
+}
+
+func TestBoardJSON(t *testing.T) {
+	for _, size := range []int{MinBoardSize, DefaultBoardSize, MaxBoardSize} {
+		board := NewBoard(size)
+
+		data, err := json.Marshal(board)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+
+		var got Board
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if got != board {
+			t.Errorf("Unmarshal() = %v, want %v", got, board)
+		}
+	}
+
+	// The default board is encoded the same way as before boards had a size.
+	data, _ := json.Marshal(buildTestBoard([]move{{0, 1}}, []move{{7, 7}}))
+	want := `[[0,1,0,0,0,0,0,0],[0,0,0,0,0,0,0,0],[0,0,0,0,0,0,0,0],[0,0,0,0,0,0,0,0],` +
+		`[0,0,0,0,0,0,0,0],[0,0,0,0,0,0,0,0],[0,0,0,0,0,0,0,0],[0,0,0,0,0,0,0,2]]`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
+func TestBoardJSONRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, MinBoardSize, DefaultBoardSize, MaxBoardSize} {
+		board := Board{Size: size}
+		for x := 0; x < size; x++ {
+			for y := 0; y < size; y++ {
+				board.Squares[x][y] = Disk(r.Intn(3))
+			}
+		}
+
+		b, err := json.Marshal(board)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got Board
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if got != board {
+			t.Errorf("size %d: got %v, want %v", size, got, board)
+		}
+	}
+}
+
+func TestBoardUnmarshalJSONWhitespace(t *testing.T) {
+	var got Board
+	if err := json.Unmarshal([]byte(" [ [1, 0] ,\n[0 ,2] ] "), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Board{Size: 2}
+	want.Squares[0][0], want.Squares[1][1] = Player1, Player2
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBoardUnmarshalJSONInvalid(t *testing.T) {
+	for _, data := range []string{
+		`[[0,0],[0]]`,
+		`[[0,0],[0,0,0]]`,
+		`[[0,256],[0,0]]`,
+		`[[0,1.5],[0,0]]`,
+		`[[0,-1],[0,0]]`,
+		`[[0,0],[0,0]`,
+		`[[0,0][0,0]]`,
+		`[["0",0],[0,0]]`,
+		`[[0,0,0,0,0,0,0,0,0,0,0],[],[],[],[],[],[],[],[],[],[]]`,
+		`[[],[],[],[],[],[],[],[],[],[],[]]`,
+		`[[0,0],[0,0]] x`,
+	} {
+		var b Board
+		if err := json.Unmarshal([]byte(data), &b); err == nil {
+			t.Errorf("got no error for %s", data)
+		}
+	}
+}
+
+func BenchmarkBoardMarshalJSON(b *testing.B) {
+	board := NewBoard(DefaultBoardSize)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := board.MarshalJSON(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBoardUnmarshalJSON(b *testing.B) {
+	data, err := json.Marshal(NewBoard(DefaultBoardSize))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var board Board
+		if err := board.UnmarshalJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReplayMovesFromParallelOpening(t *testing.T) {
+	boards, err := ReplayMoves(NewBoardWithOpening(DefaultBoardSize, OpeningParallel), [][2]int{{3, 5}})
+	if err != nil {
+		t.Fatalf("ReplayMoves() error = %v", err)
+	}
+	if p1, p2 := KeepScore(boards[1]); p1 != 4 || p2 != 1 {
+		t.Errorf("ReplayMoves() score = %d, %d, want 4, 1", p1, p2)
+	}
+}
+
+func TestWinner(t *testing.T) {
+	board := buildTestBoard([]move{{0, 0}, {0, 1}}, []move{{1, 0}})
+
+	if got := Winner(board, VariantClassic); got != Player1 {
+		t.Errorf("Winner() classic = %d, want %d", got, Player1)
+	}
+	if got := Winner(board, ""); got != Player1 {
+		t.Errorf("Winner() default = %d, want %d", got, Player1)
+	}
+	if got := Winner(board, VariantAnti); got != Player2 {
+		t.Errorf("Winner() anti = %d, want %d", got, Player2)
+	}
+	if got := Winner(NewBoard(DefaultBoardSize), VariantAnti); got != 0 {
+		t.Errorf("Winner() draw = %d, want 0", got)
+	}
+}
@@ -0,0 +1,266 @@
+package lan
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNS message encoding, as in RFC 1035 and RFC 6762. Names are written without compression, but
+// compressed names are read, since other responders on the network use them.
+
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+	typeANY = 255
+
+	classIN = 1
+
+	// The top bit of the class asks for a unicast answer in questions, and flushes caches in
+	// answers.
+	classMask  = 0x7fff
+	cacheFlush = 0x8000
+
+	flagResponse      = 0x8000
+	flagAuthoritative = 0x0400
+
+	headerSize = 12
+)
+
+var errMalformed = errors.New("malformed dns message")
+
+type header struct {
+	ID, Flags                                   uint16
+	Questions, Answers, Authorities, Additional uint16
+}
+
+// buildQuery returns a query for instances of the othelgo service.
+func buildQuery() []byte {
+	b := appendHeader(nil, header{Questions: 1})
+	b = appendName(b, service)
+	b = appendUint16(b, typePTR)
+	return appendUint16(b, classIN)
+}
+
+// parseQuery returns the ID of a query if it asks for instances of the othelgo service.
+func parseQuery(msg []byte) (id uint16, ok bool) {
+	h, off, err := readHeader(msg)
+	if err != nil || h.Flags&flagResponse != 0 {
+		return 0, false
+	}
+
+	for i := 0; i < int(h.Questions); i++ {
+		var name string
+		name, off, err = readName(msg, off)
+		if err != nil || off+4 > len(msg) {
+			return 0, false
+		}
+
+		qtype := binary.BigEndian.Uint16(msg[off:])
+		qclass := binary.BigEndian.Uint16(msg[off+2:]) & classMask
+		off += 4
+
+		if strings.EqualFold(name, service) && (qtype == typePTR || qtype == typeANY) && qclass == classIN {
+			return h.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// buildResponse returns an answer to a query for instances of the othelgo service. The answer
+// points to the instance, and additional records give its host, port, and addresses.
+func buildResponse(id uint16, instance, hostname string, port int, ips []net.IP) []byte {
+	instanceFQDN := instance + "." + service
+
+	b := appendHeader(nil, header{
+		ID:         id,
+		Flags:      flagResponse | flagAuthoritative,
+		Answers:    1,
+		Additional: uint16(2 + len(ips)),
+	})
+
+	b = appendRecord(b, service, typePTR, classIN, appendName(nil, instanceFQDN))
+
+	var srv []byte
+	srv = appendUint16(srv, 0) // priority
+	srv = appendUint16(srv, 0) // weight
+	srv = appendUint16(srv, uint16(port))
+	srv = appendName(srv, hostname)
+	b = appendRecord(b, instanceFQDN, typeSRV, classIN|cacheFlush, srv)
+
+	// DNS-SD requires a TXT record, even if it is empty, which is written as one empty string.
+	b = appendRecord(b, instanceFQDN, typeTXT, classIN|cacheFlush, []byte{0})
+
+	for _, ip := range ips {
+		b = appendRecord(b, hostname, typeA, classIN|cacheFlush, ip.To4())
+	}
+
+	return b
+}
+
+// parseResponse returns the othelgo servers in a response that was sent from src. The address of
+// each server is taken from src rather than the A records, since src is known to be reachable.
+func parseResponse(msg []byte, src net.IP) []Server {
+	h, off, err := readHeader(msg)
+	if err != nil || h.Flags&flagResponse == 0 {
+		return nil
+	}
+
+	for i := 0; i < int(h.Questions); i++ {
+		if _, off, err = readName(msg, off); err != nil || off+4 > len(msg) {
+			return nil
+		}
+		off += 4
+	}
+
+	var instances []string
+	ports := make(map[string]uint16)
+
+	records := int(h.Answers) + int(h.Authorities) + int(h.Additional)
+	for i := 0; i < records; i++ {
+		var name string
+		name, off, err = readName(msg, off)
+		if err != nil || off+10 > len(msg) {
+			return nil
+		}
+
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		length := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+length > len(msg) {
+			return nil
+		}
+
+		switch rtype {
+		case typePTR:
+			if strings.EqualFold(name, service) {
+				if target, _, err := readName(msg, off); err == nil {
+					instances = append(instances, target)
+				}
+			}
+		case typeSRV:
+			if length >= 6 {
+				ports[strings.ToLower(name)] = binary.BigEndian.Uint16(msg[off+4:])
+			}
+		}
+
+		off += length
+	}
+
+	var servers []Server
+	for _, instance := range instances {
+		port, ok := ports[strings.ToLower(instance)]
+		if !ok {
+			continue
+		}
+
+		servers = append(servers, Server{
+			Name: strings.SplitN(instance, ".", 2)[0],
+			Addr: fmt.Sprintf("ws://%s", net.JoinHostPort(src.String(), strconv.Itoa(int(port)))),
+		})
+	}
+
+	return servers
+}
+
+func appendHeader(b []byte, h header) []byte {
+	b = appendUint16(b, h.ID)
+	b = appendUint16(b, h.Flags)
+	b = appendUint16(b, h.Questions)
+	b = appendUint16(b, h.Answers)
+	b = appendUint16(b, h.Authorities)
+	return appendUint16(b, h.Additional)
+}
+
+func appendRecord(b []byte, name string, rtype, class uint16, data []byte) []byte {
+	b = appendName(b, name)
+	b = appendUint16(b, rtype)
+	b = appendUint16(b, class)
+	b = append(b, 0, 0, byte(ttl>>8), byte(ttl&0xff))
+	b = appendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func readHeader(msg []byte) (header, int, error) {
+	if len(msg) < headerSize {
+		return header{}, 0, errMalformed
+	}
+
+	return header{
+		ID:          binary.BigEndian.Uint16(msg[0:]),
+		Flags:       binary.BigEndian.Uint16(msg[2:]),
+		Questions:   binary.BigEndian.Uint16(msg[4:]),
+		Answers:     binary.BigEndian.Uint16(msg[6:]),
+		Authorities: binary.BigEndian.Uint16(msg[8:]),
+		Additional:  binary.BigEndian.Uint16(msg[10:]),
+	}, headerSize, nil
+}
+
+// readName reads a name at off, and returns it with a trailing dot and the offset after it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+
+	// Each pointer must point backwards, so there can't be more pointers than bytes.
+	for jumps := 0; jumps < len(msg); jumps++ {
+		if off >= len(msg) {
+			return "", 0, errMalformed
+		}
+
+		length := int(msg[off])
+
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+
+		case length&0xc0 == 0xc0:
+			if off+2 > len(msg) {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			pointer := int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			if pointer >= off {
+				return "", 0, errMalformed
+			}
+			off = pointer
+
+		case length&0xc0 != 0:
+			return "", 0, errMalformed
+
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+
+	return "", 0, errMalformed
+}
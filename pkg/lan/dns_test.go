@@ -0,0 +1,63 @@
+package lan
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	id, ok := parseQuery(buildQuery())
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0), id)
+
+	// Queries for other services are ignored.
+	other := appendHeader(nil, header{ID: 7, Questions: 1})
+	other = appendName(other, "_printer._tcp.local.")
+	other = appendUint16(other, typePTR)
+	other = appendUint16(other, classIN)
+	_, ok = parseQuery(other)
+	assert.False(t, ok)
+
+	// So are responses, and garbage.
+	_, ok = parseQuery(buildResponse(0, "andy", "box.local.", 9000, nil))
+	assert.False(t, ok)
+	_, ok = parseQuery([]byte{1, 2, 3})
+	assert.False(t, ok)
+}
+
+func TestResponse(t *testing.T) {
+	response := buildResponse(0, "andy", "box.local.", 9000, []net.IP{net.IPv4(192, 168, 1, 5)})
+
+	servers := parseResponse(response, net.IPv4(192, 168, 1, 5))
+	assert.Equal(t, []Server{{Name: "andy", Addr: "ws://192.168.1.5:9000"}}, servers)
+
+	// A query is not a response.
+	assert.Empty(t, parseResponse(buildQuery(), net.IPv4(192, 168, 1, 5)))
+
+	// Truncated responses are ignored.
+	for i := 0; i < len(response); i++ {
+		parseResponse(response[:i], net.IPv4(192, 168, 1, 5))
+	}
+}
+
+func TestReadNameCompressed(t *testing.T) {
+	// "local." at 0, and "box" followed by a pointer to it at 7.
+	msg := []byte{5, 'l', 'o', 'c', 'a', 'l', 0, 3, 'b', 'o', 'x', 0xc0, 0}
+
+	name, next, err := readName(msg, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "box.local.", name)
+	assert.Equal(t, len(msg), next)
+
+	// Pointers that loop are malformed.
+	_, _, err = readName([]byte{0xc0, 0}, 0)
+	assert.Error(t, err)
+}
+
+func TestInstanceName(t *testing.T) {
+	assert.Equal(t, "othelgo", instanceName(""))
+	assert.Equal(t, "a b", instanceName("a.b"))
+	assert.Len(t, instanceName(string(make([]byte, 100))), 63)
+}
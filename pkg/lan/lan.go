@@ -0,0 +1,193 @@
+// Package lan finds othelgo servers on the local network, so that players can play each other with
+// no internet. A server is advertised with multicast DNS as an instance of the _othelgo._tcp
+// service, and clients discover it by asking for instances of the service.
+//
+// Only the small part of multicast DNS that othelgo needs is implemented: servers answer queries
+// for the service, and clients send one query and collect the answers.
+package lan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// service is the DNS-SD service name of othelgo servers.
+const service = "_othelgo._tcp.local."
+
+// ttl is how long, in seconds, answers may be cached.
+const ttl = 120
+
+// maxPacketSize is the largest multicast DNS packet that is read.
+const maxPacketSize = 9000
+
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Server is an othelgo server found on the local network.
+type Server struct {
+	// Name is the name that the server is advertised with, such as the host player's nickname.
+	Name string
+
+	// Addr is the websocket address of the server.
+	Addr string
+}
+
+// Advertise answers queries for othelgo servers with a server named name listening on port, until
+// ctx is done.
+func Advertise(ctx context.Context, name string, port int) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	hostname, err := localHostname()
+	if err != nil {
+		return err
+	}
+
+	instance := instanceName(name)
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		id, ok := parseQuery(buf[:n])
+		if !ok {
+			continue
+		}
+
+		ips := localIPv4s()
+
+		// Queries from port 5353 come from full multicast DNS responders, which expect answers to
+		// be multicast. Other queries, such as from Discover, are answered directly.
+		dst := group
+		if src.Port != group.Port {
+			dst = src
+		} else {
+			id = 0
+		}
+
+		response := buildResponse(id, instance, hostname, port, ips)
+		if _, err := conn.WriteToUDP(response, dst); err != nil {
+			log.Printf("Failed to answer a LAN query from %s: %v", src, err)
+		}
+	}
+}
+
+// Discover asks for othelgo servers on the local network and returns the ones that answer within
+// wait, sorted by name.
+func Discover(ctx context.Context, wait time.Duration) ([]Server, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP(buildQuery(), group); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]Server)
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+
+		for _, s := range parseResponse(buf[:n], src.IP) {
+			found[s.Addr] = s
+		}
+	}
+
+	servers := make([]Server, 0, len(found))
+	for _, s := range found {
+		servers = append(servers, s)
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].Name != servers[j].Name {
+			return servers[i].Name < servers[j].Name
+		}
+		return servers[i].Addr < servers[j].Addr
+	})
+
+	return servers, nil
+}
+
+// instanceName returns a DNS label for a server name. Labels are at most 63 bytes, and dots would
+// split the label in two.
+func instanceName(name string) string {
+	name = strings.ReplaceAll(name, ".", " ")
+	if name == "" {
+		name = "othelgo"
+	}
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// localHostname returns the multicast DNS name of this host.
+func localHostname() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	hostname = strings.SplitN(hostname, ".", 2)[0]
+
+	return fmt.Sprintf("%s.local.", instanceName(hostname)), nil
+}
+
+// localIPv4s returns the IPv4 addresses of this host, other than loopback addresses.
+func localIPv4s() []net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Printf("Failed to list the network addresses: %v", err)
+		return nil
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
@@ -0,0 +1,54 @@
+package messages
+
+// Acting is implemented by the messages that a client sends on behalf of a player, so that the
+// server can check that the connection may act as that player before handling them. Messages that
+// only prove a nickname, such as Hello and ClaimNickname, are not Acting.
+type Acting interface {
+	// ActingNickname returns the nickname of the player, or an empty string if the message isn't
+	// sent as any player, such as a Spectate without a nickname.
+	ActingNickname() string
+}
+
+func (m HostGame) ActingNickname() string { return m.Nickname }
+
+func (m StartSoloGame) ActingNickname() string { return m.Nickname }
+
+func (m JoinGame) ActingNickname() string { return m.Nickname }
+
+func (m LeaveGame) ActingNickname() string { return m.Nickname }
+
+func (m ResumeGame) ActingNickname() string { return m.Nickname }
+
+func (m ResumeFrom) ActingNickname() string { return m.Nickname }
+
+func (m PlaceDisk) ActingNickname() string { return m.Nickname }
+
+func (m RequestUndo) ActingNickname() string { return m.Nickname }
+
+func (m RespondUndo) ActingNickname() string { return m.Nickname }
+
+func (m JoinLounge) ActingNickname() string { return m.Nickname }
+
+func (m SetStatus) ActingNickname() string { return m.Nickname }
+
+func (m CreateTournament) ActingNickname() string { return m.Nickname }
+
+func (m JoinTournament) ActingNickname() string { return m.Nickname }
+
+func (m WithdrawTournament) ActingNickname() string { return m.Nickname }
+
+func (m FindMatch) ActingNickname() string { return m.Nickname }
+
+func (m SetPrivacy) ActingNickname() string { return m.Nickname }
+
+func (m GetPrivacy) ActingNickname() string { return m.Nickname }
+
+func (m ExportData) ActingNickname() string { return m.Nickname }
+
+func (m DeleteData) ActingNickname() string { return m.Nickname }
+
+func (m Spectate) ActingNickname() string { return m.Nickname }
+
+func (m DisputeResult) ActingNickname() string { return m.Nickname }
+
+func (m JoinBotLadder) ActingNickname() string { return m.Nickname }
@@ -0,0 +1,53 @@
+package messages
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestActing checks every message type in the manifest that has a nickname, so that a new message
+// sent on behalf of a player can't be handled without checking that the connection may act as them.
+func TestActing(t *testing.T) {
+	// These carry a nickname, but aren't sent as the player.
+	notActing := map[reflect.Type]bool{
+		// Hello and ClaimNickname prove the nickname, rather than act as it.
+		reflect.TypeOf(Hello{}):         true,
+		reflect.TypeOf(ClaimNickname{}): true,
+
+		// The server sends these.
+		reflect.TypeOf(Joined{}):               true,
+		reflect.TypeOf(PresenceUpdate{}):       true,
+		reflect.TypeOf(NicknameClaimed{}):      true,
+		reflect.TypeOf(OpponentDisconnected{}): true,
+		reflect.TypeOf(Privacy{}):              true,
+		reflect.TypeOf(DataJobQueued{}):        true,
+		reflect.TypeOf(DataJobDone{}):          true,
+	}
+
+	acting := reflect.TypeOf((*Acting)(nil)).Elem()
+
+	for _, message := range manifest {
+		typ := reflect.TypeOf(message).Elem()
+
+		field, ok := typ.FieldByName("Nickname")
+		if !ok || field.Type.Kind() != reflect.String {
+			assert.False(t, typ.Implements(acting), "%s has no nickname to act as", typ.Name())
+			continue
+		}
+
+		if notActing[typ] {
+			assert.False(t, typ.Implements(acting), "%s is not sent as a player", typ.Name())
+			continue
+		}
+
+		if !assert.True(t, typ.Implements(acting), "%s has a nickname but is not Acting", typ.Name()) {
+			continue
+		}
+
+		value := reflect.New(typ).Elem()
+		value.FieldByName("Nickname").SetString("flame")
+		assert.Equal(t, "flame", value.Interface().(Acting).ActingNickname(), typ.Name())
+	}
+}
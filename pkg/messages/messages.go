@@ -3,51 +3,244 @@ package messages
 import "github.com/armsnyder/othelgo/pkg/common"
 
 // To add a new message type, declare a new struct in this file and add it to the manifest variable.
+// If the server sends it, also add an On method for it to the client's scenes.Handlers. If a client
+// sends it on behalf of a player, make it Acting.
 
 // manifest must contain all message types.
 var manifest = []interface{}{
 	(*Hello)(nil),
+	(*HelloAck)(nil),
+	(*Ping)(nil),
+	(*Pong)(nil),
 	(*HostGame)(nil),
 	(*StartSoloGame)(nil),
 	(*JoinGame)(nil),
 	(*Joined)(nil),
 	(*LeaveGame)(nil),
+	(*ResumeGame)(nil),
 	(*GameOver)(nil),
 	(*ListOpenGames)(nil),
 	(*OpenGames)(nil),
 	(*PlaceDisk)(nil),
 	(*UpdateBoard)(nil),
+	(*RequestUndo)(nil),
+	(*RespondUndo)(nil),
+	(*Spectate)(nil),
+	(*StopSpectating)(nil),
+	(*SpectatorUpdate)(nil),
+	(*SendKibitz)(nil),
+	(*Kibitz)(nil),
 	(*Error)(nil),
 	(*Decorate)(nil),
+	(*GetReplay)(nil),
+	(*Replay)(nil),
+	(*JoinLounge)(nil),
+	(*LeaveLounge)(nil),
+	(*SendLoungeChat)(nil),
+	(*LoungeChat)(nil),
+	(*SetStatus)(nil),
+	(*PresenceUpdate)(nil),
+	(*ClaimNickname)(nil),
+	(*NicknameClaimed)(nil),
+	(*GetGameState)(nil),
+	(*GameState)(nil),
+	(*CreateTournament)(nil),
+	(*JoinTournament)(nil),
+	(*TournamentUpdate)(nil),
+	(*GameStarted)(nil),
+	(*GameStarting)(nil),
+	(*WithdrawTournament)(nil),
+	(*FindMatch)(nil),
+	(*CancelFindMatch)(nil),
+	(*MatchQueued)(nil),
+	(*GetLeaderboard)(nil),
+	(*Leaderboard)(nil),
+	(*OpponentDisconnected)(nil),
+	(*ResumeFrom)(nil),
+	(*GetProfile)(nil),
+	(*Profile)(nil),
+	(*SetPrivacy)(nil),
+	(*GetPrivacy)(nil),
+	(*Privacy)(nil),
+	(*ExportData)(nil),
+	(*DeleteData)(nil),
+	(*DataJobQueued)(nil),
+	(*DataJobDone)(nil),
+	(*DisputeResult)(nil),
+	(*DisputeFiled)(nil),
+	(*JoinBotLadder)(nil),
+	(*BotLadderJoined)(nil),
 }
 
+// Presence statuses. They are shown to everybody in the lounge and the list of open games, since
+// there are no friends lists. There are no direct challenges either, so StatusDoNotDisturb is only
+// shown, and does not decline anything.
+const (
+	StatusAvailable    = "available"
+	StatusPlaying      = "playing"
+	StatusAway         = "away"
+	StatusDoNotDisturb = "dnd"
+	StatusOffline      = "offline"
+)
+
+// Color choices for a hosted game. Black is common.Player1, and moves first.
+const (
+	ColorBlack  = "black"
+	ColorWhite  = "white"
+	ColorRandom = "random"
+	ColorJoiner = "joiner"
+)
+
+// Tournament statuses.
+const (
+	TournamentRegistering = "registering"
+	TournamentPlaying     = "playing"
+	TournamentFinished    = "finished"
+)
+
+// Events of a TournamentEvent.
+const (
+	TournamentRoundStarted     = "roundStarted"
+	TournamentBoardFinished    = "boardFinished"
+	TournamentStandingsUpdated = "standingsUpdated"
+	TournamentEnded            = "tournamentFinished"
+)
+
 type Hello struct {
 	Version string `json:"version" validate:"semver"`
+
+	// Lite asks for the smallest updates the server can send, for clients on slow connections. Lite
+	// clients are sent moves as a Delta instead of the whole board, and no decorations or chat
+	// backlog.
+	Lite bool `json:"lite,omitempty"`
+
+	// Nickname and Token prove that the client owns a claimed nickname. See ClaimNickname.
+	Nickname string `json:"nickname,omitempty" validate:"omitempty,max=10,alphanumspace,lowercase"`
+	Token    string `json:"token,omitempty" validate:"max=100"`
+
+	// BoardEncoding is how the client would like boards to be encoded, which is
+	// common.BoardEncodingJSON by default. Clients that pick common.BoardEncodingCompact are sent
+	// moves with a CompactBoard instead of the board, if they aren't lite.
+	BoardEncoding string `json:"boardEncoding,omitempty" validate:"omitempty,oneof=json compact"`
+}
+
+// HelloAck is the server's reply to a Hello from a supported client. Maintenance is a notice that
+// is set while the server is in maintenance mode. Branding is set by self-hosted deployments that
+// want to be told apart from the public server.
+type HelloAck struct {
+	Version     string    `json:"version"`
+	Features    []string  `json:"features"`
+	Maintenance string    `json:"maintenance,omitempty"`
+	Branding    *Branding `json:"branding,omitempty"`
+}
+
+// Branding is how a deployment presents itself on the client's main menu. AccentColor is the color
+// that Name is drawn in, which is one of the basic terminal colors, such as "cyan".
+type Branding struct {
+	Name        string `json:"name,omitempty" validate:"max=30"`
+	MOTD        string `json:"motd,omitempty" validate:"max=60"`
+	AccentColor string `json:"accentColor,omitempty" validate:"omitempty,oneof=red green yellow blue magenta cyan white"`
+}
+
+// Ping is sent regularly by clients to keep their connection open, and is answered with Pong.
+type Ping struct{}
+
+// Pong answers a Ping. Maintenance is a notice that is set while the server is in maintenance mode.
+// ServerTime is when it was sent, in milliseconds since the Unix epoch by the server's clock, which
+// clients compare with the round trip of the ping to estimate how far their clock is from the
+// server's.
+type Pong struct {
+	Maintenance string `json:"maintenance,omitempty"`
+	ServerTime  int64  `json:"serverTime,omitempty"`
 }
 
 type HostGame struct {
-	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Nickname  string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Preset    string `json:"preset" validate:"omitempty,oneof=bullet blitz rapid correspondence"`
+	BoardSize int    `json:"boardSize" validate:"omitempty,oneof=6 8 10"`
+
+	// Color is the host's color, or ColorRandom or ColorJoiner to leave it to chance or to the
+	// opponent. The host plays black by default.
+	Color string `json:"color,omitempty" validate:"omitempty,oneof=black white random joiner"`
+
+	// Variant decides who wins, and is common.VariantClassic by default. Opening is the starting
+	// layout, and is common.OpeningStandard by default.
+	Variant string `json:"variant,omitempty" validate:"omitempty,oneof=classic anti"`
+	Opening string `json:"opening,omitempty" validate:"omitempty,oneof=standard parallel"`
+
+	// RatingRange turns away opponents rated more than this above or below the host. Zero lets
+	// anybody join.
+	RatingRange int `json:"ratingRange,omitempty" validate:"min=0"`
 }
 
 type StartSoloGame struct {
 	Nickname   string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
 	Difficulty int    `json:"difficulty" validate:"oneof=0 1 2"`
+	BoardSize  int    `json:"boardSize" validate:"omitempty,oneof=6 8 10"`
+
+	// Variant and Opening are like HostGame's.
+	Variant string `json:"variant,omitempty" validate:"omitempty,oneof=classic anti"`
+	Opening string `json:"opening,omitempty" validate:"omitempty,oneof=standard parallel"`
+
+	// Adaptive plays against an AI that adjusts to the player instead of one of the difficulties,
+	// and Difficulty is ignored. The AI starts from the player's results in their earlier adaptive
+	// games, and plays weaker while it is winning by a lot and stronger while it is losing by a lot.
+	Adaptive bool `json:"adaptive,omitempty"`
 }
 
 type JoinGame struct {
 	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase,nefield=Host"`
 	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+
+	// Color is the joiner's pick, which is used if the host left the color to the opponent. The
+	// joiner plays white by default.
+	Color string `json:"color,omitempty" validate:"omitempty,oneof=black white"`
 }
 
 type Joined struct {
 	Nickname string `json:"nickname"`
 }
 
+// GameStarted is sent to each player when a multiplayer game gets an opponent, and when a player
+// resumes the game. Disk is the disk that the receiving player plays, and HostDisk is the host's.
+type GameStarted struct {
+	Host     string      `json:"host"`
+	Opponent string      `json:"opponent"`
+	Disk     common.Disk `json:"disk"`
+	HostDisk common.Disk `json:"hostDisk"`
+}
+
+// GameStarting counts down to the start of a multiplayer game, and is sent to both players after
+// GameStarted when the game gets its opponent. StartsAt is when the game starts, and ServerTime is
+// when the message was sent, both in milliseconds since the Unix epoch by the server's clock, so
+// that clients whose clocks are off can count down from StartsAt-ServerTime. Clocks start at
+// StartsAt, and moves made before it take no time.
+type GameStarting struct {
+	Host       string `json:"host"`
+	StartsAt   int64  `json:"startsAt"`
+	ServerTime int64  `json:"serverTime"`
+}
+
 type LeaveGame struct {
 	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
 	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
 }
 
+// ResumeGame moves a player's place in a game to a new connection, such as after the client
+// reconnects. The server replies with an UpdateBoard, or with a GameOver if the game has ended.
+type ResumeGame struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// ResumeFrom asks the server to send again the messages of a game that were numbered after
+// LastSeq, which the client may have missed while it was reconnecting. It is sent after ResumeGame.
+type ResumeFrom struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+	LastSeq  int    `json:"lastSeq" validate:"min=0"`
+}
+
 type GameOver struct {
 	Message string `json:"message"`
 }
@@ -56,13 +249,19 @@ type ListOpenGames struct{}
 
 type OpenGames struct {
 	Hosts []string `json:"hosts"`
+
+	// Statuses maps each host to their presence status.
+	Statuses map[string]string `json:"statuses"`
+
+	// Ratings maps each host to their rating. Hosts who hide their rating are left out.
+	Ratings map[string]int `json:"ratings,omitempty"`
 }
 
 type PlaceDisk struct {
 	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
 	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
-	X        int    `json:"x" validate:"min=0,max=7"`
-	Y        int    `json:"y" validate:"min=0,max=7"`
+	X        int    `json:"x" validate:"min=0,max=9"`
+	Y        int    `json:"y" validate:"min=0,max=9"`
 }
 
 type UpdateBoard struct {
@@ -72,12 +271,644 @@ type UpdateBoard struct {
 	Y       int          `json:"y"`
 	P1Score int          `json:"p1score"`
 	P2Score int          `json:"p2score"`
+
+	// Remaining time on each player's clock in milliseconds. Both are zero in untimed games.
+	P1Clock int `json:"p1clock,omitempty"`
+	P2Clock int `json:"p2clock,omitempty"`
+
+	// ClockTime is when the clocks were read, in milliseconds since the Unix epoch by the server's
+	// clock. It is zero in untimed games.
+	ClockTime int64 `json:"clockTime,omitempty"`
+
+	// Rules are sent when a player starts, joins, or resumes a game.
+	Rules *Rules `json:"rules,omitempty"`
+
+	// Delta is sent to lite clients instead of the board after a move. The board is then empty, and
+	// the client applies the delta to the board it already has.
+	Delta *BoardDelta `json:"delta,omitempty"`
+
+	// Moves are the moves played so far in algebraic notation, such as "d3". They are left out of
+	// delta updates, where the client adds the move at X, Y to the moves it already has.
+	Moves []string `json:"moves,omitempty"`
+
+	// CompactBoard is the board encoded with common.EncodeBoard, which is sent instead of the board
+	// to clients that asked for compact boards. It is decoded into Board when the message is
+	// unmarshaled.
+	CompactBoard string `json:"compactBoard,omitempty"`
 }
 
+// BoardDelta lists the squares changed by a move, which are the placed disk and the disks it
+// flipped. They all now hold the disk of the player who moved.
+type BoardDelta struct {
+	Disk    common.Disk `json:"disk"`
+	Squares [][2]int    `json:"squares"`
+}
+
+// Scoring methods.
+const (
+	// ScoringDisks means the player with the most disks when the game ends wins.
+	ScoringDisks = "disks"
+
+	// ScoringFewestDisks means the player with the fewest disks when the game ends wins.
+	ScoringFewestDisks = "fewestDisks"
+)
+
+// Rules describe how a game is played, so that clients and bots can configure themselves without
+// knowing what each variant means.
+type Rules struct {
+	// Preset names the preset that the game was hosted with, such as a speed preset, or is
+	// "casual" or "solo". It is informational only.
+	Preset string `json:"preset"`
+
+	// Variant decides who wins, such as common.VariantAnti, and Opening is the starting layout,
+	// such as common.OpeningParallel. Scoring and Board say the same for clients that don't know
+	// them.
+	Variant string `json:"variant"`
+	Opening string `json:"opening"`
+
+	// Board is the board that the game started with.
+	Board common.Board `json:"board"`
+
+	Scoring string `json:"scoring"`
+
+	// Clock is nil in untimed games.
+	Clock *ClockRules `json:"clock,omitempty"`
+
+	// Takebacks is whether a player may take back a move.
+	Takebacks bool `json:"takebacks"`
+}
+
+// ClockRules are the time control of a timed game, in milliseconds.
+type ClockRules struct {
+	// Initial is the time each player starts with.
+	Initial int `json:"initial"`
+
+	// Increment is added to a player's remaining time after each of their moves.
+	Increment int `json:"increment,omitempty"`
+
+	// PerMove means a player's remaining time is reset to Initial after each of their moves.
+	PerMove bool `json:"perMove,omitempty"`
+}
+
+// RequestUndo asks to take back the player's last move. In a multiplayer game, the server forwards
+// it to the opponent, who answers with RespondUndo.
+type RequestUndo struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// RespondUndo answers a RequestUndo. The server forwards it to both players.
+type RespondUndo struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Host     string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+	Accept   bool   `json:"accept"`
+}
+
+// Spectate subscribes to a game in progress. The server replies with a SpectatorUpdate of the
+// current state of the game, and sends another after every move until the game ends or
+// StopSpectating is sent. A connection may spectate several games at once.
+type Spectate struct {
+	Host string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+
+	// Nickname is the spectator's nickname, which is needed to kibitz. See SendKibitz.
+	Nickname string `json:"nickname,omitempty" validate:"omitempty,max=10,alphanumspace,lowercase"`
+}
+
+type StopSpectating struct {
+	Host string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// SpectatorUpdate is the state of a spectated game, identified by its host. Opponent is empty in a
+// solo game, or while the host is waiting for an opponent. Over is true when the game has ended,
+// in which case Message says why, and no more updates are sent.
+type SpectatorUpdate struct {
+	Host     string       `json:"host"`
+	Opponent string       `json:"opponent,omitempty"`
+	Board    common.Board `json:"board"`
+	Player   common.Disk  `json:"player"`
+	X        int          `json:"x"`
+	Y        int          `json:"y"`
+	P1Score  int          `json:"p1score"`
+	P2Score  int          `json:"p2score"`
+	Over     bool         `json:"over,omitempty"`
+	Message  string       `json:"message,omitempty"`
+
+	// HostDisk is set when the host doesn't play common.Player1.
+	HostDisk common.Disk `json:"hostDisk,omitempty"`
+
+	// Rules are sent in the first update after spectating.
+	Rules *Rules `json:"rules,omitempty"`
+}
+
+// SendKibitz sends a comment about a game to its other spectators. Kibitz is hidden from the
+// players until the game has ended, when it is kept with the game's replay. Only spectators who
+// spectated with a nickname, and who aren't playing the game, can kibitz.
+type SendKibitz struct {
+	Host string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+	Text string `json:"text" validate:"required,max=200"`
+}
+
+// Kibitz carries lines of kibitz about a game. It holds the backlog when spectating with a
+// nickname, and a single line after that.
+type Kibitz struct {
+	Host  string       `json:"host"`
+	Lines []KibitzLine `json:"lines"`
+}
+
+// KibitzLine is a spectator's comment. Move is the number of moves that had been played when it
+// was sent.
+type KibitzLine struct {
+	Nickname string `json:"nickname"`
+	Text     string `json:"text"`
+	Move     int    `json:"move"`
+}
+
+// GetGameState asks for the current state of a game, which is answered with GameState. It may be
+// sent by the players and spectators of the game, such as to catch up after missing an update.
+type GetGameState struct {
+	Host string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// GameState is the authoritative state of a game. X and Y are the last move, or -1 if nobody has
+// moved yet. Opponent is empty while the host is waiting for one, and in solo games, whose rules
+// have the solo variant.
+type GameState struct {
+	Host     string       `json:"host"`
+	Opponent string       `json:"opponent"`
+	Board    common.Board `json:"board"`
+	Player   common.Disk  `json:"player"`
+	X        int          `json:"x"`
+	Y        int          `json:"y"`
+	P1Score  int          `json:"p1score"`
+	P2Score  int          `json:"p2score"`
+	Moves    int          `json:"moves"`
+	Over     bool         `json:"over,omitempty"`
+
+	// HostDisk is set when the host doesn't play common.Player1.
+	HostDisk common.Disk `json:"hostDisk,omitempty"`
+
+	// Remaining time on each player's clock in milliseconds. Both are zero in untimed games.
+	P1Clock int `json:"p1clock,omitempty"`
+	P2Clock int `json:"p2clock,omitempty"`
+
+	// ClockTime is when the clocks were read, like in UpdateBoard.
+	ClockTime int64 `json:"clockTime,omitempty"`
+
+	Rules *Rules `json:"rules"`
+
+	// MoveList is the moves played so far in algebraic notation, such as "d3".
+	MoveList []string `json:"moveList,omitempty"`
+}
+
+// Error codes, which let clients react to particular errors.
+const (
+	ErrorUpgradeRequired = "upgradeRequired"
+	ErrorMaintenance     = "maintenance"
+	ErrorRateLimited     = "rateLimited"
+	ErrorMessageTooLarge = "messageTooLarge"
+	ErrorNicknameTaken   = "nicknameTaken"
+	ErrorInvalidToken    = "invalidToken"
+
+	// Limits on what one player or connection can use at once.
+	ErrorTooManyGames         = "tooManyGames"
+	ErrorTooManySpectators    = "tooManySpectators"
+	ErrorTooManySubscriptions = "tooManySubscriptions"
+
+	// ErrorWrongPhase refuses a request that the game doesn't accept yet or anymore, such as a
+	// move after the game is over.
+	ErrorWrongPhase = "wrongPhase"
+)
+
 type Error struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
 type Decorate struct {
 	Decoration string `json:"decoration"`
 }
+
+type GetReplay struct {
+	Host string `json:"host" validate:"required,max=10,alphanumspace,lowercase"`
+
+	// Game is one of the host's games, as listed in their Profile. It is empty for their latest.
+	Game string `json:"game,omitempty" validate:"omitempty,numeric,max=20"`
+}
+
+// Replay is the move list of a finished game. An empty Opponent means the game was against the AI.
+type Replay struct {
+	// Game tells the host's games apart, and EndedAt is when the game ended in Unix milliseconds.
+	// Both are empty for games that ended before each game was kept.
+	Game    string `json:"game,omitempty"`
+	EndedAt int64  `json:"endedAt,omitempty"`
+
+	Host       string   `json:"host"`
+	Opponent   string   `json:"opponent"`
+	Difficulty int      `json:"difficulty"`
+	BoardSize  int      `json:"boardSize,omitempty"`
+	Moves      [][2]int `json:"moves"`
+
+	// Adaptive is set when a solo game was against the adaptive AI, and Difficulty is ignored.
+	Adaptive bool `json:"adaptive,omitempty"`
+
+	// HostDisk is set when the host didn't play common.Player1.
+	HostDisk common.Disk `json:"hostDisk,omitempty"`
+
+	// Variant and Opening are set when the game wasn't classic or didn't have the standard opening.
+	Variant string `json:"variant,omitempty"`
+	Opening string `json:"opening,omitempty"`
+
+	// Ending is why the game ended, such as that the board is full or that a player ran out of
+	// time or left. It is empty for games that ended before it was kept.
+	Ending string `json:"ending,omitempty"`
+
+	// Kibitz is what the spectators said during the game.
+	Kibitz []KibitzLine `json:"kibitz,omitempty"`
+}
+
+type JoinLounge struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+type LeaveLounge struct{}
+
+type SendLoungeChat struct {
+	Text string `json:"text" validate:"required,max=200"`
+}
+
+// LoungeChat carries lines of lounge chat. It holds the recent backlog when joining the lounge, and
+// a single line after that.
+type LoungeChat struct {
+	Lines []ChatLine `json:"lines"`
+}
+
+type ChatLine struct {
+	Nickname string `json:"nickname"`
+	Text     string `json:"text"`
+}
+
+// SetStatus sets the presence status that other players see. The playing status is set by the
+// server while a player is in a multiplayer game.
+type SetStatus struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Status   string `json:"status" validate:"oneof=available away dnd"`
+}
+
+type PresenceUpdate struct {
+	Nickname string `json:"nickname"`
+	Status   string `json:"status"`
+}
+
+// ClaimNickname registers a nickname to the client, so that nobody else can play as it. The server
+// answers with NicknameClaimed, or with an Error whose code is ErrorNicknameTaken. Token is sent
+// when switching back to a nickname that the client claimed before.
+type ClaimNickname struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Token    string `json:"token,omitempty" validate:"max=100"`
+}
+
+// NicknameClaimed confirms a claim. Token is a secret that the client keeps and sends in every
+// Hello to prove that it owns the nickname. It is empty if the connection already owned the
+// nickname.
+type NicknameClaimed struct {
+	Nickname string `json:"nickname"`
+	Token    string `json:"token,omitempty"`
+}
+
+// CreateTournament opens a Swiss-system tournament for a number of players, which starts as soon
+// as enough players have joined. Rounds defaults to enough rounds to find a single winner. The
+// organizer is not registered as a player, and can join like anybody else.
+type CreateTournament struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Name     string `json:"name" validate:"required,max=20,alphanumspace,lowercase"`
+	Players  int    `json:"players" validate:"min=2,max=16"`
+	Rounds   int    `json:"rounds,omitempty" validate:"min=0,max=15"`
+
+	// Points default to 2 for a win or a bye, 1 for a draw, and nothing for a loss or a forfeit.
+	Points *TournamentPoints `json:"points,omitempty"`
+
+	// Webhook is an HTTP URL that the server posts a TournamentEvent to whenever something happens
+	// in the tournament, so that broadcasting tools can follow it. It is not shown to the players.
+	Webhook string `json:"webhook,omitempty" validate:"omitempty,max=200,url,startswith=https://|startswith=http://"`
+}
+
+// TournamentPoints are what each result is worth in a tournament's standings. The winner of a
+// forfeited game gets Win, and the player who forfeited gets Forfeit.
+type TournamentPoints struct {
+	Win     int `json:"win" validate:"min=0,max=10"`
+	Draw    int `json:"draw" validate:"min=0,max=10"`
+	Loss    int `json:"loss" validate:"min=0,max=10"`
+	Bye     int `json:"bye" validate:"min=0,max=10"`
+	Forfeit int `json:"forfeit" validate:"min=0,max=10"`
+}
+
+// JoinTournament registers a player in a tournament. Players that have already joined may join
+// again to follow the tournament after reconnecting.
+type JoinTournament struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Name     string `json:"name" validate:"required,max=20,alphanumspace,lowercase"`
+}
+
+// WithdrawTournament takes a player out of a tournament. A player who withdraws after the
+// tournament has started forfeits their game in the current round, and isn't paired again.
+type WithdrawTournament struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Name     string `json:"name" validate:"required,max=20,alphanumspace,lowercase"`
+}
+
+// TournamentUpdate is sent to everybody following a tournament whenever it changes. Pairings are
+// the games of the current round, in which the host hosts a game and the guest joins it. A pairing
+// without a guest is a bye.
+type TournamentUpdate struct {
+	Name      string               `json:"name"`
+	Organizer string               `json:"organizer"`
+	Status    string               `json:"status"`
+	Players   []string             `json:"players"`
+	Size      int                  `json:"size"`
+	Round     int                  `json:"round"`
+	Rounds    int                  `json:"rounds"`
+	Points    TournamentPoints     `json:"points"`
+	Pairings  []TournamentPairing  `json:"pairings"`
+	Standings []TournamentStanding `json:"standings"`
+}
+
+// TournamentEvent is posted as JSON to a tournament's webhook. It is not sent over the websocket.
+// Round is the tournament's current round, except in a roundStarted event, where it is the round
+// that started. Board is the game of a boardFinished event, Pairings are the games of a
+// roundStarted event, and Standings are set in standingsUpdated and tournamentFinished events.
+// Time is when the event happened, in milliseconds since the Unix epoch.
+type TournamentEvent struct {
+	Event      string               `json:"event"`
+	Tournament string               `json:"tournament"`
+	Round      int                  `json:"round"`
+	Board      *TournamentPairing   `json:"board,omitempty"`
+	Pairings   []TournamentPairing  `json:"pairings,omitempty"`
+	Standings  []TournamentStanding `json:"standings,omitempty"`
+	Time       int64                `json:"time"`
+}
+
+// TournamentPairing is a game in a round of a tournament. Winner is empty for a draw. Forfeit means
+// that the player who isn't the winner forfeited the game.
+type TournamentPairing struct {
+	Host    string `json:"host"`
+	Guest   string `json:"guest,omitempty"`
+	Done    bool   `json:"done,omitempty"`
+	Winner  string `json:"winner,omitempty"`
+	Forfeit bool   `json:"forfeit,omitempty"`
+}
+
+// TournamentStanding is a player's record in a tournament. Byes and forfeits are counted apart
+// from wins and losses, but a win by forfeit is a win. Withdrawn players aren't paired again.
+type TournamentStanding struct {
+	Nickname  string `json:"nickname"`
+	Points    int    `json:"points"`
+	Wins      int    `json:"wins"`
+	Draws     int    `json:"draws"`
+	Losses    int    `json:"losses"`
+	Byes      int    `json:"byes"`
+	Forfeits  int    `json:"forfeits"`
+	Withdrawn bool   `json:"withdrawn,omitempty"`
+}
+
+// FindMatch puts a player in the matchmaking queue. The server pairs them with a queued player of a
+// similar rating who asked for the same speed preset, replying with a MatchQueued while they wait
+// and sending both players a GameStarted once they are paired. The player who waited longer hosts.
+// How similar the ratings must be depends on the preset.
+type FindMatch struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	Preset   string `json:"preset,omitempty" validate:"omitempty,oneof=bullet blitz rapid correspondence"`
+}
+
+// CancelFindMatch takes the player out of the matchmaking queue.
+type CancelFindMatch struct{}
+
+// MatchQueued tells a player that they are waiting in the matchmaking queue.
+type MatchQueued struct {
+	Rating int `json:"rating"`
+}
+
+// GetLeaderboard asks for the leaderboard of players, or of the bot ladder if Bots is set.
+type GetLeaderboard struct {
+	Bots bool `json:"bots,omitempty"`
+}
+
+// Leaderboard lists the highest rated players, or the highest rated bots on the bot ladder if Bots
+// is set, best first.
+type Leaderboard struct {
+	Bots    bool               `json:"bots,omitempty"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+type LeaderboardEntry struct {
+	Nickname string `json:"nickname"`
+	Rating   int    `json:"rating"`
+	Games    int    `json:"games"`
+}
+
+// OpponentDisconnected tells a player that their opponent's connection closed. The opponent has
+// Grace seconds to reconnect and resume the game, and is announced with Joined if they do.
+// Otherwise the game ends. The game is paused until then, so the player's moves are refused with
+// ErrorWrongPhase, and their clock is stopped. Grace is zero in correspondence games, which go on
+// without the opponent until they resume it or run out of time.
+type OpponentDisconnected struct {
+	Nickname string `json:"nickname"`
+	Grace    int    `json:"grace"`
+}
+
+// GetProfile asks for a player's rating and the quality of their moves in rated games.
+type GetProfile struct {
+	Player string `json:"player" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+type Profile struct {
+	Player string `json:"player"`
+	Rating int    `json:"rating"`
+	Games  int    `json:"games"`
+
+	// Phases are the quality of the player's moves in the opening, the midgame, and the endgame.
+	Phases []PhaseQuality `json:"phases"`
+
+	// HeatMap is where the player tends to play and lose disks, if they have games in the archive.
+	HeatMap *HeatMap `json:"heatMap,omitempty"`
+
+	// Replays are the games that the player hosted and that are still kept, newest first. Any of
+	// them can be asked for with GetReplay.
+	Replays []string `json:"replays,omitempty"`
+
+	// RatingHidden and HistoryHidden are true when the player keeps their rating or the history of
+	// their games private, which leaves the rating, or the phases, the heat map, and the replays,
+	// out. See SetPrivacy.
+	RatingHidden  bool `json:"ratingHidden,omitempty"`
+	HistoryHidden bool `json:"historyHidden,omitempty"`
+}
+
+// PhaseQuality compares the quality of a player's moves in one phase of their recent rated games
+// with the games before those.
+type PhaseQuality struct {
+	Phase   string    `json:"phase"`
+	Recent  MoveStats `json:"recent"`
+	Earlier MoveStats `json:"earlier"`
+}
+
+// HeatMap counts the squares that a player moved on, and the squares where their disks were
+// flipped, in their recent games on the default board. Squares are indexed by x then y, like a
+// board.
+type HeatMap struct {
+	Games  int     `json:"games"`
+	Plays  [][]int `json:"plays"`
+	Losses [][]int `json:"losses"`
+}
+
+// MoveStats sums up the quality of a player's moves. AverageLoss is how much worse than the best
+// move their moves were on average, in hundredths of a disk.
+type MoveStats struct {
+	Games       int `json:"games"`
+	Moves       int `json:"moves"`
+	Blunders    int `json:"blunders"`
+	AverageLoss int `json:"averageLoss"`
+}
+
+// SetPrivacy changes what other players can see of a player. HideHistory hides the replays of
+// their games and the quality of their moves, HideRating leaves them out of the leaderboard and
+// hides their rating elsewhere, and AppearOffline shows them as offline. Only a claimed nickname
+// can have privacy settings. The server answers with Privacy.
+type SetPrivacy struct {
+	Nickname      string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	HideHistory   bool   `json:"hideHistory"`
+	HideRating    bool   `json:"hideRating"`
+	AppearOffline bool   `json:"appearOffline"`
+}
+
+// GetPrivacy asks for a player's own privacy settings, which are answered with Privacy.
+type GetPrivacy struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// Privacy is a player's privacy settings. See SetPrivacy.
+type Privacy struct {
+	Nickname      string `json:"nickname"`
+	HideHistory   bool   `json:"hideHistory"`
+	HideRating    bool   `json:"hideRating"`
+	AppearOffline bool   `json:"appearOffline"`
+}
+
+// Kinds of data jobs.
+const (
+	DataJobExport = "export"
+	DataJobDelete = "delete"
+)
+
+// ExportData asks for a copy of everything that the server keeps about a player. Only a claimed
+// nickname's data can be exported. The export is made in the background, so the server answers
+// with DataJobQueued, and later sends DataJobDone with the export.
+type ExportData struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// DeleteData asks for everything that the server keeps about a player to be deleted, including the
+// claim to their nickname. Their games stay in the archive for their opponents, with their name
+// removed. Like ExportData, it is answered with DataJobQueued and later DataJobDone.
+type DeleteData struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// DataJobQueued confirms an ExportData or DeleteData.
+type DataJobQueued struct {
+	Nickname string `json:"nickname"`
+	Kind     string `json:"kind"`
+}
+
+// DataJobDone is sent when a data job is done, right away if the connection that asked for it is
+// still open, or else after the player's next Hello with their token. Export is set for export
+// jobs.
+type DataJobDone struct {
+	Nickname string      `json:"nickname"`
+	Kind     string      `json:"kind"`
+	Export   *DataExport `json:"export,omitempty"`
+}
+
+// DataExport is everything that the server keeps about a player.
+type DataExport struct {
+	Nickname    string        `json:"nickname"`
+	Privacy     Privacy       `json:"privacy"`
+	Rating      int           `json:"rating"`
+	RatedGames  int           `json:"ratedGames"`
+	MoveQuality []GameQuality `json:"moveQuality"`
+	HeatMap     *HeatMap      `json:"heatMap,omitempty"`
+	Replays     []Replay      `json:"replays"`
+	LoungeChat  []string      `json:"loungeChat"`
+	Kibitz      []KibitzLine  `json:"kibitz"`
+
+	// Results are how the player's rated games ended, oldest first, for the ones still kept for
+	// disputes.
+	Results []RatedResult `json:"results"`
+
+	// Disputes are the disputes that the player filed which are waiting to be reviewed.
+	Disputes []FiledDispute `json:"disputes"`
+
+	// BotRating and BotRatedGames are the player's bot ladder rating and how many ladder games it
+	// was rated on, if the player has played on the bot ladder.
+	BotRating     *int `json:"botRating,omitempty"`
+	BotRatedGames int  `json:"botRatedGames,omitempty"`
+
+	// AdaptiveStrength is the strength of the player's adaptive AI, from 0 to 10, if they have
+	// played it.
+	AdaptiveStrength *int `json:"adaptiveStrength,omitempty"`
+}
+
+// GameQuality is the quality of a player's moves in one rated game, which ended at EndedAt in
+// seconds since the Unix epoch. Phases are the opening, the midgame, and the endgame.
+type GameQuality struct {
+	EndedAt int64       `json:"endedAt"`
+	Phases  []MoveStats `json:"phases"`
+}
+
+// RatedResult is how a rated game ended, at EndedAt in seconds since the Unix epoch. The winner is
+// empty for a draw. Ratings are the host's and the opponent's before and after the game.
+type RatedResult struct {
+	Host          string `json:"host"`
+	Opponent      string `json:"opponent"`
+	Winner        string `json:"winner,omitempty"`
+	Reason        string `json:"reason"`
+	EndedAt       int64  `json:"endedAt"`
+	RatingsBefore [2]int `json:"ratingsBefore"`
+	RatingsAfter  [2]int `json:"ratingsAfter"`
+}
+
+// FiledDispute is a dispute of a rated game's result, filed at FiledAt in seconds since the Unix
+// epoch.
+type FiledDispute struct {
+	ID      string      `json:"id"`
+	Reason  string      `json:"reason,omitempty"`
+	FiledAt int64       `json:"filedAt"`
+	Result  RatedResult `json:"result"`
+}
+
+// DisputeResult disputes the result of one of a player's rated games, such as when they lost on
+// time because of a server outage. EndedAt picks the game by when it ended, as in RatedResult, and
+// the latest one is disputed if it is zero. Only a claimed nickname can dispute, and only for three
+// days after the game. An admin reviews the dispute, and may overturn the result and the rating
+// changes it made.
+type DisputeResult struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+	EndedAt  int64  `json:"endedAt,omitempty" validate:"min=0"`
+	Reason   string `json:"reason,omitempty" validate:"max=200"`
+}
+
+// DisputeFiled confirms a DisputeResult. ID identifies the dispute to admins.
+type DisputeFiled struct {
+	ID string `json:"id"`
+}
+
+// JoinBotLadder enters a bot in the nightly bot ladder, where it is paired with another bot of a
+// similar ladder rating and sent a GameStarted. Only a bot that an admin registered for the ladder
+// can join, and only with its claimed nickname. The bot stays on the ladder until it disconnects.
+type JoinBotLadder struct {
+	Nickname string `json:"nickname" validate:"required,max=10,alphanumspace,lowercase"`
+}
+
+// BotLadderJoined confirms a JoinBotLadder with the bot's ladder rating.
+type BotLadderJoined struct {
+	Rating int `json:"rating"`
+	Games  int `json:"games"`
+}
@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/armsnyder/othelgo/pkg/common"
 )
 
 var (
@@ -29,11 +33,16 @@ func init() {
 
 type Wrapper struct {
 	Message interface{}
+
+	// Seq numbers the messages that the server sends to the players of a game, so that a client
+	// can ask for the ones it missed with ResumeFrom. It is zero for messages that aren't numbered.
+	Seq int
 }
 
 func (w *Wrapper) UnmarshalJSON(data []byte) error {
 	var actionWrapper struct {
 		Action string `json:"action"`
+		Seq    int    `json:"seq"`
 	}
 
 	if err := json.Unmarshal(data, &actionWrapper); err != nil {
@@ -57,29 +66,60 @@ func (w *Wrapper) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// Compact boards are decoded here, so that receivers always find the board in Board.
+	if update, ok := message.(*UpdateBoard); ok && update.CompactBoard != "" {
+		board, err := common.DecodeBoard(update.CompactBoard)
+		if err != nil {
+			return err
+		}
+		update.Board = board
+	}
+
 	w.Message = message
+	w.Seq = actionWrapper.Seq
 
 	return nil
 }
 
 func (w Wrapper) MarshalJSON() ([]byte, error) {
-	var fields map[string]interface{}
+	return Marshal(w)
+}
 
-	// Marshal and unmarshal message as a map, so we can add a field to it.
+// Marshal encodes a wrapped message, like json.Marshal, but without the extra pass that json.Marshal
+// makes over the output of MarshalJSON. The server encodes every message it sends with it.
+func Marshal(w Wrapper) ([]byte, error) {
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
 
-	payload, err := json.Marshal(w.Message)
+	data, err := w.appendJSON((*buf)[:0])
 	if err != nil {
 		return nil, err
 	}
+	*buf = data
 
-	if err := json.Unmarshal(payload, &fields); err != nil {
-		return nil, err
-	}
+	return append([]byte(nil), data...), nil
+}
 
-	// Add the "action" field.
+// bufferPool holds the buffers that messages are encoded into before they are copied out, so that
+// each message allocates only its own bytes.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+// fieldAppender is implemented by messages that are sent often enough to be encoded by hand
+// instead of by reflection. appendFields appends each field that json.Marshal would encode, in the
+// same order and encoding, each preceded by a comma.
+type fieldAppender interface {
+	appendFields(dst []byte) ([]byte, error)
+}
 
+// appendJSON appends the message as a JSON object, with the "action" and "seq" fields first.
+func (w Wrapper) appendJSON(dst []byte) ([]byte, error) {
 	typ := reflect.TypeOf(w.Message)
-	if typ.Kind() == reflect.Ptr {
+	if typ != nil && typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
 	}
 
@@ -88,7 +128,141 @@ func (w Wrapper) MarshalJSON() ([]byte, error) {
 		return nil, fmt.Errorf("message type %v is not listed in the manifest", typ)
 	}
 
-	fields["action"] = action
+	dst = append(dst, `{"action":`...)
+	dst = appendString(dst, action)
+
+	if w.Seq != 0 {
+		dst = append(dst, `,"seq":`...)
+		dst = strconv.AppendInt(dst, int64(w.Seq), 10)
+	}
+
+	if appender, ok := w.Message.(fieldAppender); ok {
+		var err error
+		if dst, err = appender.appendFields(dst); err != nil {
+			return nil, err
+		}
+		return append(dst, '}'), nil
+	}
+
+	// Other messages are encoded by json.Marshal, and their fields are spliced in after the action.
+	payload, err := json.Marshal(w.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < 2 || payload[0] != '{' {
+		return nil, fmt.Errorf("message type %v is not encoded as a JSON object", typ)
+	}
+
+	if len(payload) > 2 {
+		dst = append(dst, ',')
+	}
+
+	return append(dst, payload[1:]...), nil
+}
+
+func (m PlaceDisk) appendFields(dst []byte) ([]byte, error) {
+	dst = append(dst, `,"nickname":`...)
+	dst = appendString(dst, m.Nickname)
+	dst = append(dst, `,"host":`...)
+	dst = appendString(dst, m.Host)
+	dst = appendIntField(dst, "x", m.X)
+	dst = appendIntField(dst, "y", m.Y)
+	return dst, nil
+}
+
+func (m UpdateBoard) appendFields(dst []byte) ([]byte, error) {
+	dst = append(dst, `,"board":`...)
+	dst = m.Board.AppendJSON(dst)
+	dst = appendIntField(dst, "player", int(m.Player))
+	dst = appendIntField(dst, "x", m.X)
+	dst = appendIntField(dst, "y", m.Y)
+	dst = appendIntField(dst, "p1score", m.P1Score)
+	dst = appendIntField(dst, "p2score", m.P2Score)
+
+	if m.P1Clock != 0 {
+		dst = appendIntField(dst, "p1clock", m.P1Clock)
+	}
+	if m.P2Clock != 0 {
+		dst = appendIntField(dst, "p2clock", m.P2Clock)
+	}
+	if m.ClockTime != 0 {
+		dst = append(dst, `,"clockTime":`...)
+		dst = strconv.AppendInt(dst, m.ClockTime, 10)
+	}
+
+	// Rules are only sent when a game starts, so they are left to json.Marshal.
+	if m.Rules != nil {
+		rules, err := json.Marshal(m.Rules)
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, `,"rules":`...)
+		dst = append(dst, rules...)
+	}
+
+	if m.Delta != nil {
+		dst = append(dst, `,"delta":{"disk":`...)
+		dst = strconv.AppendInt(dst, int64(m.Delta.Disk), 10)
+		dst = append(dst, `,"squares":`...)
+		if m.Delta.Squares == nil {
+			dst = append(dst, "null"...)
+		} else {
+			dst = append(dst, '[')
+			for i, square := range m.Delta.Squares {
+				if i > 0 {
+					dst = append(dst, ',')
+				}
+				dst = append(dst, '[')
+				dst = strconv.AppendInt(dst, int64(square[0]), 10)
+				dst = append(dst, ',')
+				dst = strconv.AppendInt(dst, int64(square[1]), 10)
+				dst = append(dst, ']')
+			}
+			dst = append(dst, ']')
+		}
+		dst = append(dst, '}')
+	}
+
+	if len(m.Moves) > 0 {
+		dst = append(dst, `,"moves":[`...)
+		for i, move := range m.Moves {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendString(dst, move)
+		}
+		dst = append(dst, ']')
+	}
+
+	if m.CompactBoard != "" {
+		dst = append(dst, `,"compactBoard":`...)
+		dst = appendString(dst, m.CompactBoard)
+	}
+
+	return dst, nil
+}
+
+// appendIntField appends an integer field, preceded by a comma.
+func appendIntField(dst []byte, name string, value int) []byte {
+	dst = append(dst, ',', '"')
+	dst = append(dst, name...)
+	dst = append(dst, '"', ':')
+	return strconv.AppendInt(dst, int64(value), 10)
+}
+
+// appendString appends a string as json.Marshal would encode it. Strings of printable ASCII that
+// need no escaping, which are all of the strings in the hand-encoded messages, are copied as they
+// are, and others are left to json.Marshal.
+func appendString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			quoted, _ := json.Marshal(s)
+			return append(dst, quoted...)
+		}
+	}
 
-	return json.Marshal(&fields)
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+	return append(dst, '"')
 }
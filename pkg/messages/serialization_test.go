@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/common"
 )
 
 func TestMarshalPointerPointer(t *testing.T) {
@@ -45,3 +47,105 @@ func TestUnmarshal(t *testing.T) {
 		assert.Equal(t, "0.0.0", w.Message.(*Hello).Version)
 	}
 }
+
+func TestMarshalSeq(t *testing.T) {
+	b, err := json.Marshal(Wrapper{Message: Joined{Nickname: "andy"}, Seq: 3})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"action":"joined","nickname":"andy","seq":3}`, string(b))
+}
+
+func TestUnmarshalSeq(t *testing.T) {
+	var w Wrapper
+	err := json.Unmarshal([]byte(`{"action":"joined","nickname":"andy","seq":3}`), &w)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, w.Seq)
+	assert.IsType(t, &Joined{}, w.Message)
+}
+
+func TestUnmarshalCompactBoard(t *testing.T) {
+	board := common.NewBoard(common.DefaultBoardSize)
+	b, err := json.Marshal(Wrapper{Message: UpdateBoard{CompactBoard: common.EncodeBoard(board), Player: common.Player1}})
+	assert.NoError(t, err)
+
+	var w Wrapper
+	assert.NoError(t, json.Unmarshal(b, &w))
+	if assert.IsType(t, &UpdateBoard{}, w.Message) {
+		assert.Equal(t, board, w.Message.(*UpdateBoard).Board)
+	}
+}
+
+func TestUnmarshalInvalidCompactBoard(t *testing.T) {
+	var w Wrapper
+	err := json.Unmarshal([]byte(`{"action":"updateBoard","board":[],"compactBoard":"???"}`), &w)
+	assert.Error(t, err)
+}
+
+// The hand-encoded messages are encoded exactly as json.Marshal would encode them.
+func TestAppendFieldsMatchesEncodingJSON(t *testing.T) {
+	board := common.NewBoard(common.MaxBoardSize)
+	board.Squares[9][9] = common.Player2
+
+	for _, message := range []fieldAppender{
+		PlaceDisk{Nickname: "flame", Host: "zinger", X: 2, Y: 4},
+		PlaceDisk{Nickname: "<a&b>", Host: "zïnger\n\"", X: 9},
+		UpdateBoard{},
+		UpdateBoard{Board: board, Player: common.Player1, X: -1, Y: -1, P1Score: 2, P2Score: 3, Moves: []string{"c4", "e3"}},
+		UpdateBoard{P1Clock: 1000, P2Clock: 2000, ClockTime: 1760000000000, Rules: &Rules{Preset: "blitz", Variant: common.VariantClassic}},
+		UpdateBoard{Delta: &BoardDelta{Disk: common.Player2, Squares: [][2]int{{2, 4}, {3, 4}}}, Moves: []string{}},
+		UpdateBoard{Delta: &BoardDelta{}, CompactBoard: common.EncodeBoard(board)},
+	} {
+		want, err := json.Marshal(message)
+		assert.NoError(t, err)
+
+		got, err := message.appendFields([]byte("{\"action\":\"x\""))
+		assert.NoError(t, err)
+		got = append(got, '}')
+
+		assert.Equal(t, `{"action":"x",`+string(want[1:]), string(got))
+	}
+}
+
+func TestMarshalEmptyMessage(t *testing.T) {
+	b, err := Marshal(Wrapper{Message: &Ping{}, Seq: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"action":"ping","seq":2}`, string(b))
+}
+
+func TestMarshalUnlisted(t *testing.T) {
+	_, err := Marshal(Wrapper{Message: struct{}{}})
+	assert.Error(t, err)
+
+	_, err = Marshal(Wrapper{})
+	assert.Error(t, err)
+}
+
+func BenchmarkMarshalUpdateBoard(b *testing.B) {
+	update := UpdateBoard{
+		Board:   common.NewBoard(common.DefaultBoardSize),
+		Player:  common.Player2,
+		X:       2,
+		Y:       4,
+		P1Score: 4,
+		P2Score: 1,
+		Moves:   []string{"c5"},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(Wrapper{Message: update, Seq: 7}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalPlaceDisk(b *testing.B) {
+	data := []byte(`{"action":"placeDisk","nickname":"flame","host":"zinger","x":2,"y":4}`)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var w Wrapper
+		if err := json.Unmarshal(data, &w); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
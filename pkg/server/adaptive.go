@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Adaptive solo games. Instead of picking a difficulty, a player may play the adaptive AI, whose
+// strength is remembered across their games. Winning an adaptive game moves the strength a rung up
+// the ladder of ai.StrengthLevel, and losing moves it a rung down, so that it settles where the
+// player wins about half of the time. Within a game, ai.Adapt keeps the score close. Adaptive games
+// are not counted in the calibration of the difficulties.
+
+// adaptiveStrength returns the strength that a player's next adaptive game starts at.
+func adaptiveStrength(ctx context.Context, args Args, nickname string) (int, error) {
+	strength, ok, err := getStrength(ctx, args, nickname)
+	if err != nil || !ok {
+		return ai.DefaultStrength, err
+	}
+
+	return strength, nil
+}
+
+// recordAdaptiveResult moves the strength of a player's adaptive AI after an adaptive game ends.
+func recordAdaptiveResult(ctx context.Context, args Args, nickname string, game game) error {
+	strength := nextStrength(game.Strength, common.Winner(game.Board, game.Objective))
+	if strength == game.Strength {
+		return nil
+	}
+
+	if err := updateStrength(ctx, args, nickname, strength); err != nil {
+		return fmt.Errorf("failed to record adaptive result: %w", err)
+	}
+
+	return nil
+}
+
+// nextStrength returns the strength after a game that the human played as common.Player1. A draw
+// leaves it as it was.
+func nextStrength(strength int, winner common.Disk) int {
+	switch {
+	case winner == common.Player1 && strength < ai.MaxStrength:
+		return strength + 1
+	case winner == common.Player2 && strength > 0:
+		return strength - 1
+	default:
+		return strength
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestNextStrength(t *testing.T) {
+	assert.Equal(t, 5, nextStrength(4, common.Player1))
+	assert.Equal(t, 3, nextStrength(4, common.Player2))
+	assert.Equal(t, 4, nextStrength(4, 0))
+	assert.Equal(t, ai.MaxStrength, nextStrength(ai.MaxStrength, common.Player1))
+	assert.Equal(t, 0, nextStrength(0, common.Player2))
+}
+
+func TestAILevelAdapts(t *testing.T) {
+	g := &game{Adaptive: true, Strength: ai.DefaultStrength, Board: common.NewBoard(common.DefaultBoardSize), AI: ai.Level{Depth: 6}}
+	assert.Equal(t, ai.StrengthLevel(ai.DefaultStrength), g.aiLevel())
+}
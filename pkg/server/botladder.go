@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Running the bot ladder, a standing benchmark for engine authors. Admins register community bots
+// with RegisterBot, and a registered bot joins the ladder with JoinBotLadder, which subscribes it
+// to botLadderTopic until it is paired. RunBotLadder, which is meant to be run nightly, pairs the
+// waiting bots by their ladder ratings, best with second best and so on, and starts an ordinary
+// multiplayer game for each pair. Ladder games are rated on the bot ladder instead of the players'
+// ratings, and a bot joins the ladder again when its game is over.
+
+const botLadderTopic = "botLadder"
+
+// LadderGame describes a game that RunBotLadder started.
+type LadderGame struct {
+	Host           string
+	Opponent       string
+	HostRating     int
+	OpponentRating int
+}
+
+func (g LadderGame) String() string {
+	return fmt.Sprintf("%s (%d) vs. %s (%d)", g.Host, g.HostRating, g.Opponent, g.OpponentRating)
+}
+
+// RegisterBot registers a bot for the bot ladder. The bot's nickname has to be claimed for it to
+// join.
+func RegisterBot(ctx context.Context, args Args, nickname string) error {
+	return registerLadderBot(ctx, args, nickname)
+}
+
+// UnregisterBot takes a bot off the bot ladder. Its ladder rating is kept.
+func UnregisterBot(ctx context.Context, args Args, nickname string) error {
+	return deleteItem(ctx, args, ladderBotKey(nickname))
+}
+
+// RunBotLadder starts a game between each pair of registered bots waiting on the ladder. If an odd
+// number of bots are waiting, the lowest rated one waits for the next run.
+func RunBotLadder(ctx context.Context, args Args) ([]LadderGame, error) {
+	if args.APIGatewayManagementAPIClientFactory == nil {
+		args.APIGatewayManagementAPIClientFactory = defaultAPIGatewayManagementAPIClientFactory()
+	}
+
+	waiting, err := getSubscribers(ctx, args, botLadderTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	bots := make(map[string]ladderBot, len(waiting))
+	var ready []subscriber
+
+	for _, sub := range waiting {
+		if _, ok := bots[sub.Nickname]; ok {
+			continue
+		}
+
+		// The bot may have been unregistered, or joined again on another connection.
+		bot, ok, err := getLadderBot(ctx, args, sub.Nickname)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || bot.ConnectionID != sub.ConnectionID {
+			continue
+		}
+
+		bots[sub.Nickname] = bot
+		ready = append(ready, sub)
+	}
+
+	nicknames := make([]string, len(ready))
+	for i, sub := range ready {
+		nicknames[i] = sub.Nickname
+	}
+
+	ratings, err := botRatingsOf(ctx, args, nicknames)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []LadderGame
+
+	for _, pair := range ladderPairings(ready, ratings) {
+		host, opponent := pair[0], pair[1]
+
+		started, err := startLadderGame(ctx, args, bots[host.Nickname], host, opponent)
+		if err != nil {
+			return games, fmt.Errorf("failed to start a ladder game between %q and %q: %w", host.Nickname, opponent.Nickname, err)
+		}
+		if !started {
+			continue
+		}
+
+		games = append(games, LadderGame{
+			Host:           host.Nickname,
+			Opponent:       opponent.Nickname,
+			HostRating:     ratings[host.Nickname].Rating,
+			OpponentRating: ratings[opponent.Nickname].Rating,
+		})
+	}
+
+	return games, nil
+}
+
+// ladderPairings pairs waiting bots by their ladder ratings, best first. The better rated bot of
+// each pair hosts.
+func ladderPairings(waiting []subscriber, ratings map[string]rating) [][2]subscriber {
+	ranked := append([]subscriber(nil), waiting...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ratings[ranked[i].Nickname].Rating > ratings[ranked[j].Nickname].Rating
+	})
+
+	var pairs [][2]subscriber
+	for i := 0; i+1 < len(ranked); i += 2 {
+		pairs = append(pairs, [2]subscriber{ranked[i], ranked[i+1]})
+	}
+
+	return pairs
+}
+
+// startLadderGame starts a game between two waiting bots. It is not ok if either bot left the
+// ladder in the meantime, and the other bot is then back on the ladder with its next
+// JoinBotLadder. The bots' connections are addressed by the host's registration, since there is no
+// request to reply to.
+func startLadderGame(ctx context.Context, args Args, hostBot ladderBot, host, opponent subscriber) (bool, error) {
+	for _, sub := range []subscriber{host, opponent} {
+		ok, err := takeSubscription(ctx, args, botLadderTopic, sub.ConnectionID)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	log.Printf("Starting a ladder game between bot %q and bot %q", host.Nickname, opponent.Nickname)
+
+	reqCtx := events.APIGatewayWebsocketProxyRequestContext{DomainName: hostBot.DomainName, Stage: hostBot.Stage}
+
+	if _, _, err := updateInGame(ctx, args, host.ConnectionID, host.Nickname, host.Nickname); err != nil {
+		return false, err
+	}
+
+	if _, _, err := updateInGame(ctx, args, opponent.ConnectionID, opponent.Nickname, host.Nickname); err != nil {
+		return false, err
+	}
+
+	game := newGame(0)
+	game.Color = messages.ColorRandom
+	game.chooseColors("", rand.Intn(2) == 0)
+	game.Ladder = true
+
+	if err := createGame(ctx, args, host.Nickname, game, opponent.Nickname, host.Nickname, host.ConnectionID); err != nil {
+		return false, fmt.Errorf("failed to save new game state: %w", err)
+	}
+
+	if _, _, err := updateOpponentConnectionGetGameConnectionIDs(ctx, args, host.Nickname, opponent.Nickname, opponent.Nickname, opponent.ConnectionID, [2]string{opponent.Nickname, opponent.Nickname}); err != nil {
+		return false, err
+	}
+
+	board := messages.UpdateBoard{
+		Board:   game.Board,
+		Player:  game.Player,
+		X:       -1,
+		Y:       -1,
+		P1Score: 2,
+		P2Score: 2,
+		Rules:   game.rules(),
+	}
+
+	now := time.Now()
+	countdown := gameStarting(host.Nickname, now.Add(startCountdown), now)
+
+	// GameStarted comes first, so that bots waiting on the ladder know which game the board is for.
+	for _, sub := range []subscriber{host, opponent} {
+		if err := sendMessage(ctx, reqCtx, args, sub.ConnectionID, gameStarted(host.Nickname, opponent.Nickname, sub.Nickname, game))(); err != nil {
+			return false, err
+		}
+
+		if err := sendMessage(ctx, reqCtx, args, sub.ConnectionID, board)(); err != nil {
+			return false, err
+		}
+
+		if err := sendMessage(ctx, reqCtx, args, sub.ConnectionID, countdown)(); err != nil {
+			return false, err
+		}
+	}
+
+	return true, setPlaying(ctx, reqCtx, args, true, host.Nickname, opponent.Nickname)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLadderPairings(t *testing.T) {
+	waiting := []subscriber{
+		{ConnectionID: "1", Nickname: "andy"},
+		{ConnectionID: "2", Nickname: "bob"},
+		{ConnectionID: "3", Nickname: "carl"},
+		{ConnectionID: "4", Nickname: "dana"},
+		{ConnectionID: "5", Nickname: "eve"},
+	}
+	ratings := map[string]rating{
+		"andy": {Nickname: "andy", Rating: 1200},
+		"bob":  {Nickname: "bob", Rating: 1350},
+		"carl": {Nickname: "carl", Rating: 1100},
+		"dana": {Nickname: "dana", Rating: 1280},
+		"eve":  {Nickname: "eve", Rating: 1200},
+	}
+
+	got := ladderPairings(waiting, ratings)
+
+	// Carl is rated lowest of an odd number of bots, so they wait for the next run.
+	assert.Equal(t, [][2]subscriber{
+		{waiting[1], waiting[3]},
+		{waiting[0], waiting[4]},
+	}, got)
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// The AI difficulties are calibrated against how often humans beat them. The results of solo games
+// are recorded, and CalibrateAI adjusts each difficulty's level so that the human win rate falls
+// within the difficulty's target band. This keeps the difficulties meaningful as the AI improves.
+
+// humanWinRateTargets are the acceptable ranges of the human win rate at each difficulty.
+var humanWinRateTargets = [][2]float64{
+	{0.7, 0.9},
+	{0.4, 0.6},
+	{0.1, 0.3},
+}
+
+const (
+	// minCalibrationGames is how many games must be played at a difficulty before it is calibrated.
+	minCalibrationGames = 50
+
+	maxAIDepth      = 10
+	maxAIRandomness = 0.5
+	randomnessStep  = 0.1
+)
+
+// AICalibration describes the outcome of calibrating one AI difficulty.
+type AICalibration struct {
+	Difficulty   int
+	Games        int
+	HumanWinRate float64
+	Depth        int
+	Randomness   float64
+	Changed      bool
+}
+
+func (c AICalibration) String() string {
+	change := "unchanged"
+	if c.Changed {
+		change = "changed"
+	}
+
+	return fmt.Sprintf("difficulty %d: %d games, humans won %.0f%%, depth %d, randomness %.1f (%s)",
+		c.Difficulty, c.Games, c.HumanWinRate*100, c.Depth, c.Randomness, change)
+}
+
+// CalibrateAI adjusts the level of each AI difficulty that has enough recorded games, and starts
+// recording afresh for the difficulties that changed.
+func CalibrateAI(ctx context.Context, args Args) ([]AICalibration, error) {
+	levels, err := currentAILevels(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	calibrations := make([]AICalibration, len(levels))
+	changed := false
+
+	for difficulty, level := range levels {
+		stats, err := getAIStats(ctx, args, difficulty)
+		if err != nil {
+			return nil, err
+		}
+
+		calibrated := calibrate(level, stats, humanWinRateTargets[difficulty])
+
+		calibrations[difficulty] = AICalibration{
+			Difficulty:   difficulty,
+			Games:        stats.Games,
+			HumanWinRate: stats.humanWinRate(),
+			Depth:        calibrated.Depth,
+			Randomness:   calibrated.Randomness,
+			Changed:      calibrated != level,
+		}
+
+		if calibrated == level {
+			continue
+		}
+
+		log.Printf("Calibrating AI difficulty %d from %+v to %+v", difficulty, level, calibrated)
+
+		levels[difficulty] = calibrated
+		changed = true
+
+		// Discard the results that were measured with the old level.
+		if err := addAIStats(ctx, args, difficulty, aiStats{Games: -stats.Games, HumanWins: -stats.HumanWins}); err != nil {
+			return nil, err
+		}
+	}
+
+	if changed {
+		if err := updateAILevels(ctx, args, levels); err != nil {
+			return nil, err
+		}
+	}
+
+	return calibrations, nil
+}
+
+// currentAILevels returns the level of each difficulty, falling back to the defaults for
+// difficulties that have not been calibrated.
+func currentAILevels(ctx context.Context, args Args) ([]ai.Level, error) {
+	saved, err := getAILevels(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([]ai.Level, len(ai.DefaultLevels))
+	copy(levels, ai.DefaultLevels)
+	copy(levels, saved)
+
+	return levels, nil
+}
+
+// recordAIResult records the result of a host's solo game that has ended. Draws count as games
+// that the human did not win.
+func recordAIResult(ctx context.Context, args Args, host string, game game) error {
+	if !common.GameOver(game.Board) {
+		return nil
+	}
+
+	if game.Adaptive {
+		return recordAdaptiveResult(ctx, args, host, game)
+	}
+
+	stats := aiStats{Games: 1}
+	if common.Winner(game.Board, game.Objective) == common.Player1 {
+		stats.HumanWins = 1
+	}
+
+	if err := addAIStats(ctx, args, game.Difficulty, stats); err != nil {
+		return fmt.Errorf("failed to record AI result: %w", err)
+	}
+
+	return nil
+}
+
+// aiLevel returns how well the AI plays its next move in a solo game. Games that started before
+// levels were stored in the game use the default level.
+func (g *game) aiLevel() ai.Level {
+	if g.Adaptive {
+		return ai.Adapt(g.Strength, g.Board, g.Objective)
+	}
+
+	if g.AI.Depth == 0 {
+		return ai.DefaultLevels[g.Difficulty]
+	}
+
+	return g.AI
+}
+
+func (s aiStats) humanWinRate() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+
+	return float64(s.HumanWins) / float64(s.Games)
+}
+
+// calibrate returns the level adjusted one step toward the target human win rate. The AI is made
+// weaker by looking fewer turns ahead, and once it only looks one turn ahead, by playing more
+// random moves. It is made stronger by undoing the same steps in reverse.
+func calibrate(l ai.Level, stats aiStats, target [2]float64) ai.Level {
+	if stats.Games < minCalibrationGames {
+		return l
+	}
+
+	switch rate := stats.humanWinRate(); {
+	case rate < target[0]:
+		// Humans are losing too often.
+		if l.Depth > 1 {
+			l.Depth--
+		} else {
+			l.Randomness = math.Min(l.Randomness+randomnessStep, maxAIRandomness)
+		}
+	case rate > target[1]:
+		// Humans are winning too often.
+		if l.Randomness > 0 {
+			l.Randomness = math.Max(l.Randomness-randomnessStep, 0)
+		} else if l.Depth < maxAIDepth {
+			l.Depth++
+		}
+	}
+
+	// Avoid accumulating floating point error over many steps.
+	l.Randomness = math.Round(l.Randomness*10) / 10
+
+	return l
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+)
+
+func TestCalibrate(t *testing.T) {
+	target := [2]float64{0.4, 0.6}
+
+	tests := []struct {
+		name  string
+		level ai.Level
+		stats aiStats
+		want  ai.Level
+	}{
+		{"too few games", ai.Level{Depth: 4}, aiStats{Games: 10, HumanWins: 10}, ai.Level{Depth: 4}},
+		{"on target", ai.Level{Depth: 4}, aiStats{Games: 100, HumanWins: 50}, ai.Level{Depth: 4}},
+		{"too hard", ai.Level{Depth: 4}, aiStats{Games: 100, HumanWins: 20}, ai.Level{Depth: 3}},
+		{"too hard at depth 1", ai.Level{Depth: 1, Randomness: 0.2}, aiStats{Games: 100, HumanWins: 20}, ai.Level{Depth: 1, Randomness: 0.3}},
+		{"too hard at most random", ai.Level{Depth: 1, Randomness: 0.5}, aiStats{Games: 100, HumanWins: 20}, ai.Level{Depth: 1, Randomness: 0.5}},
+		{"too easy", ai.Level{Depth: 4}, aiStats{Games: 100, HumanWins: 80}, ai.Level{Depth: 5}},
+		{"too easy with randomness", ai.Level{Depth: 1, Randomness: 0.3}, aiStats{Games: 100, HumanWins: 80}, ai.Level{Depth: 1, Randomness: 0.2}},
+		{"too easy at most depth", ai.Level{Depth: maxAIDepth}, aiStats{Games: 100, HumanWins: 80}, ai.Level{Depth: maxAIDepth}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calibrate(tt.level, tt.stats, target))
+		})
+	}
+}
+
+func TestAILevelDefaultsToDifficulty(t *testing.T) {
+	assert.Equal(t, ai.Level{Depth: 4}, (&game{Difficulty: 1}).aiLevel())
+	assert.Equal(t, ai.Level{Depth: 2, Randomness: 0.1}, (&game{Difficulty: 1, AI: ai.Level{Depth: 2, Randomness: 0.1}}).aiLevel())
+}
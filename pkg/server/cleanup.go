@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Cleaning up after closed connections. When a connection closes, its subscriptions are removed,
+// its player goes offline, and a game that nobody has joined yet is taken off the list of open
+// games. A player in the middle of a game has reconnectGrace to come back and resume it, and their
+// opponent is told that they are waiting. The game is paused meanwhile. Correspondence games are
+// neither paused nor ended, since their players are expected to come and go between moves. Clients that have never pinged can't resume, so they
+// leave their game straight away.
+
+// reconnectGrace is how long a player whose connection closed has to resume their game.
+const reconnectGrace = time.Minute
+
+func cleanUpConnection(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, now time.Time) error {
+	connID := req.RequestContext.ConnectionID
+
+	conn, _, err := getConnection(ctx, args, connID)
+	if err != nil {
+		return err
+	}
+
+	// The record of a connection is removed when its game ends, taking its topics with it, so the
+	// lounge and matchmaking are always left.
+	topics := append([]string{loungeTopic, matchmakingTopic}, conn.Topics...)
+	for _, topic := range topics {
+		if err := unsubscribe(ctx, args, topic, connID); err != nil {
+			return err
+		}
+	}
+
+	if conn.InGame != "" {
+		if err := leaveOrHoldGame(ctx, req, args, conn, now); err != nil {
+			return err
+		}
+	}
+
+	nickname := conn.Nickname
+	if nickname == "" {
+		nickname = conn.Authenticated
+	}
+
+	if nickname == "" {
+		return nil
+	}
+
+	return setOffline(ctx, req.RequestContext, args, nickname)
+}
+
+// leaveOrHoldGame takes a closed connection's player out of their game, or keeps the game for them
+// to resume.
+func leaveOrHoldGame(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, conn connection, now time.Time) error {
+	game, opponent, connections, err := getGame(ctx, args, conn.InGame)
+	if errors.Is(err, errNoGame) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if opponent == waiting || conn.LastSeen == 0 {
+		return handleLeaveGame(ctx, req, args, &messages.LeaveGame{
+			Nickname: conn.Nickname,
+			Host:     conn.InGame,
+		})
+	}
+
+	log.Printf("Keeping user %q's game open for them to resume", conn.Nickname)
+
+	if err := updateDisconnectedAt(ctx, args, req.RequestContext.ConnectionID, now); err != nil {
+		return err
+	}
+
+	// Correspondence games go on without the player, who can resume them whenever they like. Other
+	// games are paused, unless they changed in the meantime, such as by ending.
+	grace := reconnectGrace
+	if game.Clock.PerMove {
+		grace = 0
+	} else if opponent != "" && game.phase(opponent).underway() {
+		game.pause(game.diskOf(conn.InGame, conn.Nickname), now)
+		err := updateGame(ctx, args, conn.InGame, game, conn.Nickname, req.RequestContext.ConnectionID)
+		if err != nil && !errors.Is(err, errGameChanged) {
+			return err
+		}
+	}
+
+	delete(connections, conn.Nickname)
+
+	return broadcastToGame(ctx, req.RequestContext, args, conn.InGame, messages.OpponentDisconnected{
+		Nickname: conn.Nickname,
+		Grace:    int(grace / time.Second),
+	}, connections)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+func TestConnectionStale(t *testing.T) {
+	now := time.Unix(10000, 0)
+
+	tests := []struct {
+		name string
+		conn connection
+		want bool
+	}{
+		{name: "never pinged", conn: connection{}, want: false},
+		{name: "pinged recently", conn: connection{LastSeen: now.Add(-time.Minute).Unix()}, want: false},
+		{name: "stopped pinging", conn: connection{LastSeen: now.Add(-staleAfter).Unix()}, want: true},
+		{name: "closed recently", conn: connection{LastSeen: now.Add(-staleAfter).Unix(), DisconnectedAt: now.Add(-time.Second).Unix()}, want: false},
+		{name: "closed past the grace", conn: connection{LastSeen: now.Add(-time.Minute).Unix(), DisconnectedAt: now.Add(-reconnectGrace).Unix()}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.conn.stale(now))
+		})
+	}
+}
+
+func TestEndGameIfOpponentStale(t *testing.T) {
+	ctx := context.Background()
+
+	var recorder postRecorder
+	args := Args{
+		DB:        memdb.New(),
+		TableName: "Othelgo",
+		APIGatewayManagementAPIClientFactory: func(events.APIGatewayWebsocketProxyRequestContext) APIGatewayManagementAPIClient {
+			return &recorder
+		},
+	}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	now := time.Now()
+
+	// Flame is still connected, and zinger stopped pinging.
+	require.NoError(t, createGame(ctx, args, "flame", newGame(0), waiting, "flame", "a"))
+	_, _, err := updateOpponentConnectionGetGameConnectionIDs(ctx, args, "flame", "zinger", "zinger", "b", [2]string{waiting, waiting})
+	require.NoError(t, err)
+	for connID, nickname := range map[string]string{"a": "flame", "b": "zinger"} {
+		_, _, err := updateInGame(ctx, args, connID, nickname, "flame")
+		require.NoError(t, err)
+	}
+	_, err = updateItem(ctx, args, "a", expression.Set(expression.Name(attribAuthenticated), expression.Value("flame")), false)
+	require.NoError(t, err)
+	_, _, err = touchConnection(ctx, args, "b", now.Add(-staleAfter))
+	require.NoError(t, err)
+
+	req := events.APIGatewayWebsocketProxyRequest{RequestContext: events.APIGatewayWebsocketProxyRequestContext{ConnectionID: "a"}}
+	require.NoError(t, endGameIfOpponentStale(ctx, req, args, "flame", "flame", now))
+
+	_, _, _, err = getGame(ctx, args, "flame")
+	assert.True(t, errors.Is(err, errNoGame))
+
+	// Flame's connection only leaves the game, and zinger's is forgotten.
+	conn, ok, err := getConnection(ctx, args, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, connection{Nickname: "flame", Authenticated: "flame"}, conn)
+
+	_, ok, err = getConnection(ctx, args, "b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
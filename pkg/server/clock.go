@@ -0,0 +1,186 @@
+package server
+
+import (
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+// Game clocks and the speed presets that configure them.
+
+// startCountdown is how long players are counted down before a multiplayer game starts, so that
+// both clients start the game together. Clocks start when the countdown ends.
+const startCountdown = 3 * time.Second
+
+// clock is the time control of a game. A zero clock means the game is untimed.
+type clock struct {
+	// Initial is the time each player starts with.
+	Initial time.Duration
+
+	// Increment is added to a player's remaining time after each of their moves.
+	Increment time.Duration
+
+	// PerMove resets a player's remaining time to Initial after each of their moves, rather than
+	// carrying it over.
+	PerMove bool
+}
+
+// preset bundles the settings that a host, or a player looking for a match, picks with a single
+// choice.
+type preset struct {
+	Clock     clock
+	Takebacks bool
+
+	// MatchRatingGap is the most that the ratings of players paired by matchmaking can differ by.
+	// Fewer players look for the slowest and fastest games at once, so those are paired more
+	// loosely, so that nobody waits long.
+	MatchRatingGap int
+}
+
+// presets are keyed by the preset name sent in HostGame and FindMatch. The empty name is a casual,
+// untimed game.
+var presets = map[string]preset{
+	"":               {Takebacks: true, MatchRatingGap: 200},
+	"bullet":         {Clock: clock{Initial: time.Minute}, MatchRatingGap: 300},
+	"blitz":          {Clock: clock{Initial: 3 * time.Minute, Increment: 2 * time.Second}, MatchRatingGap: 200},
+	"rapid":          {Clock: clock{Initial: 10 * time.Minute, Increment: 5 * time.Second}, Takebacks: true, MatchRatingGap: 200},
+	"correspondence": {Clock: clock{Initial: 72 * time.Hour, PerMove: true}, Takebacks: true, MatchRatingGap: 400},
+}
+
+func (c clock) timed() bool {
+	return c.Initial > 0
+}
+
+// applyPreset configures a new game using a preset.
+func (g *game) applyPreset(p preset) {
+	g.Clock = p.Clock
+	g.Takebacks = p.Takebacks
+	g.Remaining = [2]time.Duration{p.Clock.Initial, p.Clock.Initial}
+}
+
+// startClock starts the clock of the player to move at startsAt. It is called once both players
+// have joined, with the end of the countdown.
+func (g *game) startClock(startsAt time.Time) {
+	if g.Clock.timed() {
+		g.TurnStartedAt = startsAt
+	}
+}
+
+// elapsed returns how long the player to move has taken, which is nothing before the clock starts.
+// A stopped clock counts until it stopped.
+func (g *game) elapsed(now time.Time) time.Duration {
+	if g.clockStopped() && now.After(g.PausedAt) {
+		now = g.PausedAt
+	}
+	if now.Before(g.TurnStartedAt) {
+		return 0
+	}
+	return now.Sub(g.TurnStartedAt)
+}
+
+// clockStopped returns whether the clock of the player to move is stopped because the game is
+// paused for their opponent. The clock of a player who is away keeps running on their own turn.
+func (g *game) clockStopped() bool {
+	return !g.PausedAt.IsZero() && g.Player != g.PausedFor
+}
+
+// chargeClock deducts the time taken by a player for the move they just made.
+func (g *game) chargeClock(player common.Disk, now time.Time) {
+	if !g.Clock.timed() || g.TurnStartedAt.IsZero() {
+		return
+	}
+
+	if g.Clock.PerMove {
+		g.Remaining[player-1] = g.Clock.Initial
+	} else {
+		g.Remaining[player-1] += g.Clock.Increment - g.elapsed(now)
+	}
+
+	// A move during the countdown leaves the next player's clock starting when it ends.
+	if now.After(g.TurnStartedAt) {
+		g.TurnStartedAt = now
+	}
+}
+
+// outOfTime returns true if the player to move has used up their remaining time.
+func (g *game) outOfTime(now time.Time) bool {
+	if !g.Clock.timed() || g.TurnStartedAt.IsZero() || g.TimedOut != 0 {
+		return false
+	}
+
+	return g.elapsed(now) >= g.Remaining[g.Player-1]
+}
+
+// expiresAt returns when a game that nobody saves or touches again should expire, which is itemTTL
+// after the player to move would run out of time. Correspondence games would otherwise expire while
+// their players are away between moves.
+func (g *game) expiresAt(now time.Time) time.Time {
+	expires := now.Add(itemTTL)
+	if !g.Clock.timed() || g.TimedOut != 0 {
+		return expires
+	}
+
+	left := g.Remaining[g.Player-1]
+	if !g.TurnStartedAt.IsZero() {
+		left -= g.elapsed(now)
+	}
+
+	if left > 0 {
+		expires = expires.Add(left)
+	}
+
+	return expires
+}
+
+// clockTime returns when clocks read at now were read, as it is sent to clients, or zero if the game
+// is untimed.
+func (g *game) clockTime(now time.Time) int64 {
+	if !g.Clock.timed() {
+		return 0
+	}
+	return millis(now)
+}
+
+// millis returns a time in milliseconds since the Unix epoch, as times are sent to clients.
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// clocks returns each player's remaining time in milliseconds, as it should be shown to clients.
+func (g *game) clocks(now time.Time) (p1, p2 int) {
+	if !g.Clock.timed() {
+		return 0, 0
+	}
+
+	remaining := g.Remaining
+	if !g.TurnStartedAt.IsZero() && g.TimedOut == 0 {
+		remaining[g.Player-1] -= g.elapsed(now)
+	}
+
+	for i := range remaining {
+		if remaining[i] < 0 {
+			remaining[i] = 0
+		}
+	}
+
+	return int(remaining[0].Milliseconds()), int(remaining[1].Milliseconds())
+}
+
+// pauseClock gives back the time that passed during a finished maintenance window, since players
+// could not move while it was on.
+func (g *game) pauseClock(m maintenance) {
+	if !g.Clock.timed() || g.TurnStartedAt.IsZero() || m.Enabled || !m.EndedAt.After(g.PausedThrough) {
+		return
+	}
+
+	pausedFrom := m.StartedAt
+	if g.TurnStartedAt.After(pausedFrom) {
+		pausedFrom = g.TurnStartedAt
+	}
+
+	if m.EndedAt.After(pausedFrom) {
+		g.TurnStartedAt = g.TurnStartedAt.Add(m.EndedAt.Sub(pausedFrom))
+	}
+
+	g.PausedThrough = m.EndedAt
+}
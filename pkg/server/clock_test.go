@@ -0,0 +1,156 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var g game
+	g.Player = 1
+	g.applyPreset(presets["blitz"])
+	g.startClock(start)
+
+	// Player 1 moves after 10 seconds, and earns the increment.
+	g.chargeClock(1, start.Add(10*time.Second))
+	g.Player = 2
+	p1, p2 := g.clocks(start.Add(10 * time.Second))
+	assert.Equal(t, 172000, p1)
+	assert.Equal(t, 180000, p2)
+
+	// Player 2 is thinking, so their clock is running.
+	p1, p2 = g.clocks(start.Add(70 * time.Second))
+	assert.Equal(t, 172000, p1)
+	assert.Equal(t, 120000, p2)
+	assert.False(t, g.outOfTime(start.Add(70*time.Second)))
+
+	// Player 2 thinks for too long.
+	assert.True(t, g.outOfTime(start.Add(190*time.Second)))
+	_, p2 = g.clocks(start.Add(190 * time.Second))
+	assert.Zero(t, p2)
+}
+
+func TestClockCountdown(t *testing.T) {
+	now := time.Unix(1000, 0)
+	start := now.Add(startCountdown)
+
+	var g game
+	g.Player = 1
+	g.applyPreset(presets["bullet"])
+	g.startClock(start)
+
+	// The clocks don't run during the countdown.
+	p1, p2 := g.clocks(now.Add(time.Second))
+	assert.Equal(t, 60000, p1)
+	assert.Equal(t, 60000, p2)
+
+	// Player 1 moves before the game starts, which takes no time, and player 2's clock still starts
+	// when the countdown ends.
+	g.chargeClock(1, now.Add(time.Second))
+	g.Player = 2
+	p1, p2 = g.clocks(start.Add(10 * time.Second))
+	assert.Equal(t, 60000, p1)
+	assert.Equal(t, 50000, p2)
+}
+
+func TestClockPerMove(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var g game
+	g.Player = 1
+	g.applyPreset(presets["correspondence"])
+	g.startClock(start)
+
+	g.chargeClock(1, start.Add(48*time.Hour))
+	g.Player = 2
+	p1, _ := g.clocks(start.Add(48 * time.Hour))
+	assert.Equal(t, int((72 * time.Hour).Milliseconds()), p1)
+}
+
+func TestExpiresAt(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var g game
+	g.Player = 1
+	g.applyPreset(presets["correspondence"])
+	g.startClock(start)
+
+	// The game is kept for as long as the player to move has left to think, and then some.
+	assert.Equal(t, start.Add(72*time.Hour+itemTTL), g.expiresAt(start))
+	assert.Equal(t, start.Add(72*time.Hour+itemTTL), g.expiresAt(start.Add(48*time.Hour)))
+
+	// Untimed games are kept only while somebody plays them.
+	g.applyPreset(presets[""])
+	assert.Equal(t, start.Add(itemTTL), g.expiresAt(start))
+}
+
+func TestClockUntimed(t *testing.T) {
+	var g game
+	g.Player = 1
+	g.applyPreset(presets[""])
+	g.startClock(time.Now())
+
+	assert.False(t, g.outOfTime(time.Now().Add(time.Hour)))
+	p1, p2 := g.clocks(time.Now())
+	assert.Zero(t, p1)
+	assert.Zero(t, p2)
+}
+
+func TestPauseClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var g game
+	g.Player = 1
+	g.applyPreset(presets["blitz"])
+	g.startClock(start)
+
+	// Maintenance ran from 10 to 70 seconds into player 1's turn.
+	m := maintenance{StartedAt: start.Add(10 * time.Second), EndedAt: start.Add(70 * time.Second)}
+
+	g.pauseClock(m)
+	p1, _ := g.clocks(start.Add(100 * time.Second))
+	assert.Equal(t, 140000, p1)
+
+	// The same window is not paused twice.
+	g.pauseClock(m)
+	p1, _ = g.clocks(start.Add(100 * time.Second))
+	assert.Equal(t, 140000, p1)
+
+	// Nothing is paused while maintenance is still on.
+	var on game
+	on.Player = 1
+	on.applyPreset(presets["blitz"])
+	on.startClock(start)
+	on.pauseClock(maintenance{Enabled: true, StartedAt: start})
+	assert.Equal(t, start, on.TurnStartedAt)
+}
+
+func TestPauseForReconnect(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var g game
+	g.Player = 1
+	g.applyPreset(presets["blitz"])
+	g.startClock(start)
+
+	// Player 2 drops out on player 1's turn, so player 1's clock stops until player 2 is back.
+	g.pause(2, start.Add(10*time.Second))
+	assert.True(t, g.clockStopped())
+	p1, _ := g.clocks(start.Add(40 * time.Second))
+	assert.Equal(t, 170000, p1)
+
+	g.resume(start.Add(40 * time.Second))
+	assert.False(t, g.clockStopped())
+	p1, _ = g.clocks(start.Add(50 * time.Second))
+	assert.Equal(t, 160000, p1)
+
+	// The clock of a player who drops out on their own turn keeps running.
+	g.pause(1, start.Add(50*time.Second))
+	assert.False(t, g.clockStopped())
+	p1, _ = g.clocks(start.Add(60 * time.Second))
+	assert.Equal(t, 150000, p1)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Choosing which disk each player plays. Black is common.Player1 and moves first. The host's choice
+// is kept in the game until an opponent joins, so that a random color is not known in advance.
+
+// hostDisk returns the disk that the host plays. Games from before colors could be chosen, and games
+// still waiting for an opponent, are hosted as black.
+func (g *game) hostDisk() common.Disk {
+	if g.HostDisk == 0 {
+		return common.Player1
+	}
+	return g.HostDisk
+}
+
+// diskOf returns which disk a nickname plays in a host's game.
+func (g *game) diskOf(host, nickname string) common.Disk {
+	if (nickname == host) == (g.hostDisk() == common.Player1) {
+		return common.Player1
+	}
+	return common.Player2
+}
+
+// nicknameOf returns which of a game's players plays a disk.
+func (g *game) nicknameOf(disk common.Disk, host, opponent string) string {
+	if disk == g.hostDisk() {
+		return host
+	}
+	return opponent
+}
+
+// chooseColors settles the host's disk once an opponent joins, from the host's choice and the
+// joiner's pick. Heads decides a random color. If the host has already moved as black, the host
+// stays black.
+func (g *game) chooseColors(joinerColor string, heads bool) {
+	if g.HostDisk != 0 || len(g.Moves) > 0 {
+		return
+	}
+
+	color := g.Color
+	if color == messages.ColorJoiner {
+		color = messages.ColorBlack
+		if joinerColor == messages.ColorBlack {
+			color = messages.ColorWhite
+		}
+	}
+
+	switch {
+	case color == messages.ColorWhite, color == messages.ColorRandom && heads:
+		g.HostDisk = common.Player2
+	default:
+		g.HostDisk = common.Player1
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestChooseColors(t *testing.T) {
+	tests := []struct {
+		name        string
+		color       string
+		joinerColor string
+		heads       bool
+		want        common.Disk
+	}{
+		{name: "default", want: common.Player1},
+		{name: "black", color: messages.ColorBlack, joinerColor: messages.ColorBlack, want: common.Player1},
+		{name: "white", color: messages.ColorWhite, want: common.Player2},
+		{name: "random tails", color: messages.ColorRandom, want: common.Player1},
+		{name: "random heads", color: messages.ColorRandom, heads: true, want: common.Player2},
+		{name: "joiner picks black", color: messages.ColorJoiner, joinerColor: messages.ColorBlack, want: common.Player2},
+		{name: "joiner picks white", color: messages.ColorJoiner, joinerColor: messages.ColorWhite, want: common.Player1},
+		{name: "joiner doesn't pick", color: messages.ColorJoiner, want: common.Player1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGame(0)
+			g.Color = tt.color
+			g.chooseColors(tt.joinerColor, tt.heads)
+			assert.Equal(t, tt.want, g.HostDisk)
+		})
+	}
+}
+
+func TestChooseColorsAfterTheHostMoved(t *testing.T) {
+	g := newGame(0)
+	g.Color = messages.ColorWhite
+	g.Moves = [][2]int{{2, 3}}
+
+	g.chooseColors("", false)
+
+	assert.Equal(t, common.Player1, g.hostDisk())
+}
+
+func TestDiskOf(t *testing.T) {
+	g := newGame(0)
+	assert.Equal(t, common.Player1, g.diskOf("flame", "flame"))
+	assert.Equal(t, common.Player2, g.diskOf("flame", "zinger"))
+	assert.Equal(t, "flame", g.nicknameOf(common.Player1, "flame", "zinger"))
+
+	g.HostDisk = common.Player2
+	assert.Equal(t, common.Player2, g.diskOf("flame", "flame"))
+	assert.Equal(t, common.Player1, g.diskOf("flame", "zinger"))
+	assert.Equal(t, "zinger", g.nicknameOf(common.Player1, "flame", "zinger"))
+}
@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Exporting and deleting players' data. A player who asks for either has a data job queued, which
+// RunDataJobs carries out later, because finding a player's games in the archive means reading the
+// whole table. A deleted player's games stay in the archive for their opponents with deletedPlayer
+// in place of their name, as does their kibitz, except for the games they hosted, whose replays are
+// kept under the host's name and so are deleted. Disputes of their games that their opponents filed
+// are still reviewed, with deletedPlayer in place of their name too. The player is told that the
+// job is done on the connection that asked for it if it is still open, or else after their next
+// hello with their token.
+
+// deletedPlayer replaces the name of a deleted player. It isn't a valid nickname, so nobody can
+// play as it.
+const deletedPlayer = "(deleted)"
+
+// DataJobResult describes a data job that RunDataJobs carried out.
+type DataJobResult struct {
+	Nickname  string
+	Kind      string
+	Delivered bool
+}
+
+func (r DataJobResult) String() string {
+	delivery := "waiting for the player to connect"
+	if r.Delivered {
+		delivery = "delivered"
+	}
+
+	return fmt.Sprintf("%s %s (%s)", r.Kind, r.Nickname, delivery)
+}
+
+// RunDataJobs carries out the data jobs that are waiting to run. It reads the whole table once,
+// however many jobs there are, so it is meant to be run from time to time, such as hourly.
+func RunDataJobs(ctx context.Context, args Args) ([]DataJobResult, error) {
+	nicknames, err := getPending(ctx, args, dataJobsKey)
+	if err != nil || len(nicknames) == 0 {
+		return nil, err
+	}
+
+	jobs := make(map[string]dataJob, len(nicknames))
+	for _, nickname := range nicknames {
+		job, ok, err := getDataJob(ctx, args, nickname)
+		if err != nil {
+			return nil, err
+		}
+		if ok && !job.Done {
+			jobs[nickname] = job
+		}
+	}
+
+	replays, err := scanReplays(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []DataJobResult
+
+	for _, nickname := range nicknames {
+		job, ok := jobs[nickname]
+		if ok {
+			log.Printf("Running the %s data job of user %q", job.Kind, nickname)
+
+			switch job.Kind {
+			case messages.DataJobExport:
+				err = exportData(ctx, args, &job, replays)
+			case messages.DataJobDelete:
+				err = deleteData(ctx, args, nickname, replays)
+			}
+			if err != nil {
+				return results, fmt.Errorf("failed to %s the data of %q: %w", job.Kind, nickname, err)
+			}
+
+			job.Done = true
+			if err := putDataJob(ctx, args, job, time.Now()); err != nil {
+				return results, err
+			}
+
+			results = append(results, DataJobResult{
+				Nickname:  nickname,
+				Kind:      job.Kind,
+				Delivered: notifyDataJobDone(ctx, args, job),
+			})
+		}
+
+		if err := removePending(ctx, args, dataJobsKey, nickname); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// exportData gathers everything kept about a player into their job.
+func exportData(ctx context.Context, args Args, job *dataJob, replays []replay) error {
+	nickname := job.Nickname
+	export := messages.DataExport{Nickname: nickname, MoveQuality: []messages.GameQuality{}, Replays: []messages.Replay{}, LoungeChat: []string{}, Disputes: []messages.FiledDispute{}}
+
+	privacies, err := getPrivacies(ctx, args, []string{nickname}, time.Now())
+	if err != nil {
+		return err
+	}
+	export.Privacy = privacyMessage(nickname, privacies[nickname])
+
+	ratings, err := ratingsOf(ctx, args, []string{nickname})
+	if err != nil {
+		return err
+	}
+	export.Rating, export.RatedGames = ratings[nickname].Rating, ratings[nickname].Games
+
+	botRatings, err := getBotRatings(ctx, args, []string{nickname})
+	if err != nil {
+		return err
+	}
+	if len(botRatings) > 0 {
+		export.BotRating, export.BotRatedGames = &botRatings[0].Rating, botRatings[0].Games
+	}
+
+	results, err := keptResults(ctx, args, nickname, time.Now())
+	if err != nil {
+		return err
+	}
+	export.Results = make([]messages.RatedResult, len(results))
+	for i, result := range results {
+		export.Results[i] = resultMessage(result)
+	}
+
+	disputes, err := disputesOf(ctx, args, nickname)
+	if err != nil {
+		return err
+	}
+	for _, d := range disputes {
+		if d.Nickname == nickname {
+			export.Disputes = append(export.Disputes, messages.FiledDispute{
+				ID:      d.ID,
+				Reason:  d.Reason,
+				FiledAt: d.FiledAt.Unix(),
+				Result:  resultMessage(d.Result),
+			})
+		}
+	}
+
+	games, err := getMoveQuality(ctx, args, nickname)
+	if err != nil {
+		return err
+	}
+	for _, g := range games {
+		quality := messages.GameQuality{EndedAt: g.EndedAt.Unix()}
+		for phase := 0; phase < phaseCount; phase++ {
+			quality.Phases = append(quality.Phases, moveStats([]gameQuality{g}, phase))
+		}
+		export.MoveQuality = append(export.MoveQuality, quality)
+	}
+
+	heatMap, err := getHeatMap(ctx, args, nickname)
+	if err != nil {
+		return err
+	}
+	export.HeatMap = heatMap.message()
+
+	strength, ok, err := getStrength(ctx, args, nickname)
+	if err != nil {
+		return err
+	}
+	if ok {
+		export.AdaptiveStrength = &strength
+	}
+
+	for _, r := range replays {
+		if r.Host == nickname || r.Opponent == nickname {
+			export.Replays = append(export.Replays, replayMessage(r))
+		}
+		for _, line := range r.Kibitz {
+			if line.Nickname == nickname {
+				export.Kibitz = append(export.Kibitz, messages.KibitzLine{Nickname: line.Nickname, Text: line.Text, Move: line.Move})
+			}
+		}
+	}
+
+	chat, err := getChat(ctx, args, loungeKey)
+	if err != nil {
+		return err
+	}
+	for _, line := range chat {
+		if line.Nickname == nickname {
+			export.LoungeChat = append(export.LoungeChat, line.Text)
+		}
+	}
+
+	job.Export, err = json.Marshal(export)
+
+	return err
+}
+
+// deleteData deletes everything kept about a player, and takes their name out of their opponents'
+// replays and disputes.
+func deleteData(ctx context.Context, args Args, nickname string, replays []replay) error {
+	for _, key := range []string{claimKey(nickname), ratingKey(nickname), botRatingKey(nickname), ladderBotKey(nickname), moveQualityKey(nickname), heatMapKey(nickname), adaptiveKey(nickname), replaysKey(nickname), replayKey(nickname, "")} {
+		if err := deleteItem(ctx, args, key); err != nil {
+			return err
+		}
+	}
+
+	if err := deleteResults(ctx, args, nickname); err != nil {
+		return err
+	}
+
+	if _, err := deletePresence(ctx, args, nickname); err != nil {
+		return err
+	}
+
+	disputes, err := disputesOf(ctx, args, nickname)
+	if err != nil {
+		return err
+	}
+	for _, d := range disputes {
+		if err := deleteItem(ctx, args, disputeKey(d.ID)); err != nil {
+			return err
+		}
+		if err := removePending(ctx, args, disputesKey, d.ID); err != nil {
+			return err
+		}
+
+		// The player's opponent may still have the result reviewed.
+		if d.Nickname != nickname {
+			d = d.without(nickname)
+			if _, err := createDispute(ctx, args, d, d.FiledAt); err != nil {
+				return err
+			}
+			if err := addPending(ctx, args, disputesKey, d.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, r := range replays {
+		if r.Host == nickname {
+			if err := deleteItem(ctx, args, replayKey(r.Host, r.ID)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		changed := false
+		if r.Opponent == nickname {
+			r.Opponent = deletedPlayer
+			changed = true
+		}
+		for i := range r.Kibitz {
+			if r.Kibitz[i].Nickname == nickname {
+				r.Kibitz[i].Nickname = deletedPlayer
+				changed = true
+			}
+		}
+
+		if changed {
+			if err := putReplay(ctx, args, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := dropFromLeaderboard(ctx, args, nickname, getLeaderboard, updateLeaderboard); err != nil {
+		return err
+	}
+	if err := dropFromLeaderboard(ctx, args, nickname, getBotLeaderboard, updateBotLeaderboard); err != nil {
+		return err
+	}
+
+	chat, err := getChat(ctx, args, loungeKey)
+	if err != nil {
+		return err
+	}
+	keptChat := chat[:0]
+	for _, line := range chat {
+		if line.Nickname != nickname {
+			keptChat = append(keptChat, line)
+		}
+	}
+	if len(keptChat) < len(chat) {
+		return putChat(ctx, args, loungeKey, keptChat)
+	}
+
+	return nil
+}
+
+// dropFromLeaderboard takes a player off the leaderboard that get and update read and write.
+func dropFromLeaderboard(ctx context.Context, args Args, nickname string, get func(context.Context, Args) ([]rating, error), update func(context.Context, Args, []rating) error) error {
+	leaderboard, err := get(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	kept := leaderboard[:0]
+	for _, r := range leaderboard {
+		if r.Nickname != nickname {
+			kept = append(kept, r)
+		}
+	}
+
+	if len(kept) < len(leaderboard) {
+		return update(ctx, args, kept)
+	}
+
+	return nil
+}
+
+// disputesOf returns the disputes waiting to be reviewed that a player filed or that are of their
+// games.
+func disputesOf(ctx context.Context, args Args, nickname string) ([]dispute, error) {
+	ids, err := getPending(ctx, args, disputesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var disputes []dispute
+
+	for _, id := range ids {
+		d, ok, err := getDispute(ctx, args, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok && (d.Nickname == nickname || d.Result.Host == nickname || d.Result.Opponent == nickname) {
+			disputes = append(disputes, d)
+		}
+	}
+
+	return disputes, nil
+}
+
+// notifyDataJobDone tells the connection that asked for a data job that it is done, and returns
+// whether it could. Jobs that were delivered are forgotten.
+func notifyDataJobDone(ctx context.Context, args Args, job dataJob) bool {
+	factory := args.APIGatewayManagementAPIClientFactory
+	if factory == nil {
+		factory = defaultAPIGatewayManagementAPIClientFactory()
+	}
+	args.APIGatewayManagementAPIClientFactory = factory
+
+	message, err := dataJobDoneMessage(job)
+	if err == nil {
+		reqCtx := events.APIGatewayWebsocketProxyRequestContext{DomainName: job.DomainName, Stage: job.Stage}
+		err = sendMessage(ctx, reqCtx, args, job.ConnectionID, message)()
+	}
+
+	var gone *apigatewaymanagementapi.GoneException
+	if err != nil {
+		if !errors.As(err, &gone) {
+			log.Printf("Failed to tell user %q that their data job is done: %v", job.Nickname, err)
+		}
+		return false
+	}
+
+	if err := deleteItem(ctx, args, dataJobKey(job.Nickname)); err != nil {
+		log.Printf("Failed to delete the data job of user %q: %v", job.Nickname, err)
+	}
+
+	return true
+}
+
+func dataJobDoneMessage(job dataJob) (messages.DataJobDone, error) {
+	message := messages.DataJobDone{Nickname: job.Nickname, Kind: job.Kind}
+
+	if job.Export != nil {
+		message.Export = new(messages.DataExport)
+		if err := json.Unmarshal(job.Export, message.Export); err != nil {
+			return message, err
+		}
+	}
+
+	return message, nil
+}
@@ -3,17 +3,22 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/armsnyder/othelgo/pkg/ai"
 	"github.com/armsnyder/othelgo/pkg/common"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 )
 
@@ -28,21 +33,299 @@ const (
 	attribOpponent    = "Opponent"
 	attribGame        = "Game"
 	attribConnections = "Connections"
+	attribTimedOut    = "TimedOut"
+	attribPhase       = "Phase"
 
-	attribNickname = "Nickname"
-	attribInGame   = "InGame"
+	attribNickname      = "Nickname"
+	attribInGame        = "InGame"
+	attribLastSeen      = "LastSeen"
+	attribProtocol      = "Protocol"
+	attribLite          = "Lite"
+	attribBoardEncoding = "BoardEncoding"
+
+	attribDomainName = "DomainName"
+	attribStage      = "Stage"
+
+	attribTopics         = "Topics"
+	attribDisconnectedAt = "DisconnectedAt"
+
+	attribTokenHash     = "TokenHash"
+	attribAuthenticated = "Authenticated"
+	attribPrivacy       = "Privacy"
+
+	attribReplay  = "Replay"
+	attribReplays = "Replays"
+
+	attribSeq        = "Seq"
+	attribRecipients = "Recipients"
+
+	attribTopic        = "Topic"
+	attribConnectionID = "ConnectionID"
+	attribChat         = "Chat"
+
+	attribStatus  = "Status"
+	attribPlaying = "Playing"
+
+	attribEnabled   = "Enabled"
+	attribMessage   = "Message"
+	attribStartedAt = "StartedAt"
+	attribEndedAt   = "EndedAt"
+
+	attribTokens     = "Tokens"
+	attribTokensAt   = "TokensAt"
+	attribViolations = "Violations"
+
+	attribLevels    = "Levels"
+	attribGames     = "Games"
+	attribHumanWins = "HumanWins"
+
+	attribRating      = "Rating"
+	attribLeaderboard = "Leaderboard"
+	attribResult      = "Result"
+	attribResults     = "Results"
+	attribLog         = "Log"
+	attribDispute     = "Dispute"
+
+	attribMoveQuality = "MoveQuality"
+	attribHeatMap     = "HeatMap"
+	attribStrength    = "Strength"
+
+	attribPending = "Pending"
+
+	attribWebhook = "Webhook"
+	attribEvents  = "Events"
 
 	attribTTL = "TTL"
 )
 
-const indexByOpponent = "ByOpponent"
+// itemTTL is how long an item is kept after it was last updated. Games and connections are
+// refreshed by every move and ping, so only abandoned ones expire.
+const itemTTL = time.Hour
+
+// claimTTL is how long a nickname stays claimed after its owner last said hello.
+const claimTTL = 90 * 24 * time.Hour
+
+// replayTTL is how long a finished game's replay is kept.
+const replayTTL = 30 * 24 * time.Hour
+
+// dataJobTTL is how long a data job is kept, such as while its result waits to be delivered.
+const dataJobTTL = 30 * 24 * time.Hour
+
+// resultTTL is how long the result of a rated game is kept, which is how long its players have to
+// dispute it.
+const resultTTL = 3 * 24 * time.Hour
+
+// disputeTTL is how long a dispute is kept while it waits to be reviewed.
+const disputeTTL = 30 * 24 * time.Hour
+
+// webhookQueueTTL is how long a tournament's events wait to be posted, which is as long as the
+// tournament itself is kept after it last changed.
+const webhookQueueTTL = 7 * 24 * time.Hour
+
+const (
+	indexByOpponent = "ByOpponent"
+	indexByTopic    = "ByTopic"
+)
 
 type game struct {
+	// Preset is the name of the preset that the game was hosted with, or presetSolo.
+	Preset     string
 	Board      common.Board
 	Difficulty int
-	Player     common.Disk
+	// AI is how well the AI plays in a solo game. It is fixed when the game starts, so that
+	// calibration does not change a game in progress.
+	AI ai.Level
+	// Adaptive is set in a solo game against the adaptive AI, which plays at Strength adapted to
+	// the score, instead of at AI.
+	Adaptive bool
+	Strength int
+
+	Player common.Disk
+	Moves  [][2]int
+
+	Clock         clock
+	Takebacks     bool
+	Remaining     [2]time.Duration
+	TurnStartedAt time.Time
+	TimedOut      common.Disk
+
+	// UndoRequest is the player who asked to take back their last move, if any.
+	UndoRequest common.Disk
+
+	// PausedThrough is the end of the last maintenance window that the clock was paused for.
+	PausedThrough time.Time
+
+	// PausedAt is when the connection of the player PausedFor closed, while the game waits for
+	// them to resume it.
+	PausedAt  time.Time
+	PausedFor common.Disk
+
+	// Abandoned is set when a player left the game or dropped out of it while it was underway. The
+	// game is deleted right after it is saved that way.
+	Abandoned bool
+
+	// Color is the host's color choice, which settles HostDisk when an opponent joins.
+	Color    string
+	HostDisk common.Disk
+
+	// Objective is the variant that decides who wins, such as common.VariantAnti. It is empty for
+	// classic games. Opening is the starting layout, which is empty for the standard opening.
+	Objective string
+	Opening   string
+
+	// RatingRange is how far above or below the host an opponent may be rated to join, or zero to
+	// let anybody join.
+	RatingRange int
+
+	// Ladder is whether RunBotLadder started the game, which rates it on the bot ladder.
+	Ladder bool
+
+	// Phase is what the game is waiting for, which decides what players may do in it.
+	Phase phase
+}
+
+type subscriber struct {
+	ConnectionID string
+	Nickname     string
+}
+
+// connection is what is recorded about a websocket connection. LastSeen and DisconnectedAt are Unix
+// times, and are zero if the connection has never pinged or has not closed.
+type connection struct {
+	Nickname       string
+	InGame         string
+	Authenticated  string
+	LastSeen       int64
+	DisconnectedAt int64
+	Topics         []string
+}
+
+// topicSet is stored as a DynamoDB string set, so that topics can be added to it.
+type topicSet []string
+
+func (t topicSet) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	av.SS = aws.StringSlice(t)
+	return nil
+}
+
+// outbound is a message that was sent to the players of a game, which is kept for a while in case
+// they missed it. Message is the encoded messages.Wrapper.
+type outbound struct {
+	Seq        int
+	Recipients []string
+	Message    []byte
+}
+
+type chatLine struct {
+	Nickname string
+	Text     string
+
+	// Move is the number of moves that had been played when a line of kibitz was sent.
+	Move int `json:",omitempty"`
+}
+
+type presence struct {
+	Nickname string
+	Status   string
+	Playing  bool
+}
+
+// privacy is what a player hides from everyone else.
+type privacy struct {
+	HideHistory   bool
+	HideRating    bool
+	AppearOffline bool
+}
+
+// maintenance is the state of the server's maintenance mode. StartedAt and EndedAt describe the
+// most recent maintenance window.
+type maintenance struct {
+	Enabled   bool
+	Message   string
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// aiStats are the results of solo games at one difficulty since it was last calibrated.
+type aiStats struct {
+	Games     int
+	HumanWins int
+}
+
+// rating is a player's Elo rating and how many rated games they have played.
+type rating struct {
+	Nickname string
+	Rating   int
+	Games    int
+}
+
+// gameResult is how a rated game ended and how it changed the players' ratings. Before and After
+// are the ratings of the host and the opponent. The winner is empty for a draw.
+type gameResult struct {
+	Host     string
+	Opponent string
+	Winner   string
+	Reason   string
+	EndedAt  time.Time
+	Before   [2]rating
+	After    [2]rating
+}
+
+// dispute is a player's claim that the result of a rated game is wrong. Log is the messages that
+// were sent to the players of the game, as they were kept for ResumeFrom.
+type dispute struct {
+	ID       string
+	Nickname string
+	Reason   string
+	FiledAt  time.Time
+	Result   gameResult
+	Log      []json.RawMessage
+}
+
+type replay struct {
+	// ID tells the host's games apart. It is the time that the game ended in Unix milliseconds,
+	// and is empty for replays saved before each game had its own.
+	ID         string
+	Host       string
+	Opponent   string
+	Difficulty int
+	Adaptive   bool
+	BoardSize  int
+	Moves      [][2]int
+	HostDisk   common.Disk
+	Variant    string
+	Opening    string
+	Ending     string
+	Kibitz     []chatLine
 }
 
+// ladderBot is a bot that is registered for the bot ladder. ConnectionID, DomainName, and Stage
+// address the connection that the bot last joined the ladder on, and are empty until it joins.
+type ladderBot struct {
+	Nickname     string
+	ConnectionID string
+	DomainName   string
+	Stage        string
+}
+
+// dataJob is a player's request to export or delete their data. ConnectionID, DomainName, and
+// Stage address the connection that asked, so that it can be told when the job is done. TokenHash
+// is the hash of the token of the player's claim, which still proves who the result belongs to
+// after the claim is deleted.
+type dataJob struct {
+	Nickname     string
+	Kind         string
+	ConnectionID string
+	DomainName   string
+	Stage        string
+	TokenHash    string
+	Done         bool
+	Export       []byte
+}
+
+// errNoGame is returned when a host has no game.
+var errNoGame = errors.New("game not found")
+
 func getGame(ctx context.Context, args Args, host string) (game, string, map[string]string, error) {
 	// Get the whole item from DynamoDB.
 	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
@@ -53,6 +336,10 @@ func getGame(ctx context.Context, args Args, host string) (game, string, map[str
 		return game{}, "", nil, err
 	}
 
+	if output.Item == nil {
+		return game{}, "", nil, errNoGame
+	}
+
 	// Read the attributes into a struct.
 	var item struct {
 		Game        []byte
@@ -73,19 +360,77 @@ func getGame(ctx context.Context, args Args, host string) (game, string, map[str
 }
 
 func updateGame(ctx context.Context, args Args, host string, game game, connName, connID string) error {
+	condition := expression.Name(attribConnections + "." + connName).Equal(expression.Value(connID))
+	return saveGame(ctx, args, host, game, expression.UpdateBuilder{}, condition)
+}
+
+// timeOutGame saves a game that ended because the player to move ran out of time. Any request
+// about the game may notice that, so only the first one saves it, and it is not ok for the rest.
+func timeOutGame(ctx context.Context, args Args, host string, game game) (bool, error) {
+	update := expression.Set(expression.Name(attribTimedOut), expression.Value(game.TimedOut))
+	condition := expression.Name(attribGame).AttributeExists().And(expression.Name(attribTimedOut).AttributeNotExists())
+
+	err := saveGame(ctx, args, host, game, update, condition)
+	if errors.Is(err, errGameChanged) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// abandonGame saves a game that a player left or dropped out of while it was underway, before it
+// is deleted, so that requests that loaded the game before can't save it anymore. It returns
+// errGameChanged if the game's phase changed since it was loaded.
+func abandonGame(ctx context.Context, args Args, host string, game game) error {
+	game.Abandoned = true
+	return saveGame(ctx, args, host, game, expression.UpdateBuilder{}, expression.Name(attribGame).AttributeExists())
+}
+
+// errGameChanged is returned when a game can't be saved because another request changed it since
+// it was loaded, such as by moving it to another phase.
+var errGameChanged = errors.New("game changed")
+
+// saveGame saves a game along with its phase, on a condition and only if its phase is still the
+// one it was loaded in. It returns errGameChanged if either check fails.
+func saveGame(ctx context.Context, args Args, host string, game game, update expression.UpdateBuilder, condition expression.ConditionBuilder) error {
+	loaded := game.Phase
+	if err := game.advance(); err != nil {
+		return err
+	}
+
 	gameBytes, err := json.Marshal(&game)
 	if err != nil {
 		return err
 	}
 
-	update := expression.Set(expression.Name(attribGame), expression.Value(gameBytes))
-	condition := expression.Name(attribConnections + "." + connName).Equal(expression.Value(connID))
+	update = update.
+		Set(expression.Name(attribGame), expression.Value(gameBytes)).
+		Set(expression.Name(attribPhase), expression.Value(game.Phase)).
+		Set(expression.Name(attribTTL), expression.Value(game.expiresAt(time.Now()).Unix()))
+
+	// Games saved before their phase was kept outside of the game JSON have none to compare.
+	condition = condition.And(expression.Or(
+		expression.Name(attribPhase).Equal(expression.Value(loaded)),
+		expression.Name(attribPhase).AttributeNotExists(),
+	))
+
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+	_, err = updateItemWithBuilder(ctx, args, host, builder, false)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return errGameChanged
+	}
 
-	_, err = updateItemWithCondition(ctx, args, host, update, condition, false)
 	return err
 }
 
 func createGame(ctx context.Context, args Args, host string, game game, opponent, connName, connID string) error {
+	game.Phase = phaseAwaitingOpponent
+	if opponent != waiting {
+		game.Phase = game.settle()
+	}
+
 	gameBytes, err := json.Marshal(&game)
 	if err != nil {
 		return err
@@ -93,16 +438,22 @@ func createGame(ctx context.Context, args Args, host string, game game, opponent
 
 	update := expression.
 		Set(expression.Name(attribGame), expression.Value(gameBytes)).
-		Set(expression.Name(attribConnections), expression.Value(map[string]string{connName: connID}))
+		Set(expression.Name(attribPhase), expression.Value(game.Phase)).
+		Set(expression.Name(attribConnections), expression.Value(map[string]string{connName: connID})).
+		Set(expression.Name(attribTTL), expression.Value(game.expiresAt(time.Now()).Unix()))
 
 	if opponent != "" {
 		update = update.Set(expression.Name(attribOpponent), expression.Value(opponent))
 	}
 
-	condition := expression.Name(attribHost).AttributeNotExists()
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(expression.Name(attribHost).AttributeNotExists())
 
-	_, err = updateItemWithCondition(ctx, args, host, update, condition, false)
-	return err
+	if _, err := updateItemWithBuilder(ctx, args, host, builder, false); err != nil {
+		return err
+	}
+
+	// Kibitz about the host's last game may be left over if it didn't finish.
+	return deleteItem(ctx, args, kibitzKey(host))
 }
 
 func updateOpponentConnectionGetGameConnectionIDs(ctx context.Context, args Args, host, opponent, connName, connID string, expectedOpponents [2]string) (game, []string, error) {
@@ -140,6 +491,39 @@ func updateOpponentConnectionGetGameConnectionIDs(ctx context.Context, args Args
 	return game, connectionIDs, err
 }
 
+// updateConnectionGetGame replaces a player's connection to a game. It is not ok if the game is
+// over or the player is not in it. The connections are the ones from before the update.
+func updateConnectionGetGame(ctx context.Context, args Args, host, connName, connID string) (game, map[string]string, bool, error) {
+	update := expression.Set(expression.Name(attribConnections+"."+connName), expression.Value(connID))
+	condition := expression.Name(attribConnections + "." + connName).AttributeExists()
+
+	output, err := updateItemWithCondition(ctx, args, host, update, condition, true)
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return game{}, nil, false, nil
+		}
+		return game{}, nil, false, err
+	}
+
+	// Read the attributes into a struct.
+	var item struct {
+		Game        []byte
+		Connections map[string]string
+	}
+	if err := dynamodbattribute.UnmarshalMap(output.Attributes, &item); err != nil {
+		return game{}, nil, false, err
+	}
+
+	// Unmarshal the game JSON.
+	var game game
+	if err := json.Unmarshal(item.Game, &game); err != nil {
+		return game, nil, false, err
+	}
+
+	return game, item.Connections, true, nil
+}
+
 func getHostsByOpponent(ctx context.Context, args Args, opponent string) ([]string, error) {
 	output, err := args.DB.QueryWithContext(ctx, &dynamodb.QueryInput{
 		TableName: aws.String(args.TableName),
@@ -163,7 +547,48 @@ func getHostsByOpponent(ctx context.Context, args Args, opponent string) ([]stri
 	return hosts, nil
 }
 
-func deleteGameGetConnectionIDs(ctx context.Context, args Args, host, connName, connID string) ([]string, error) {
+// getUnexpiredHosts returns the hosts whose game items have not expired. DynamoDB can take days to
+// delete expired items, so abandoned games would otherwise still be found. The hosts keep their
+// order.
+func getUnexpiredHosts(ctx context.Context, args Args, hosts []string, now time.Time) ([]string, error) {
+	if len(hosts) == 0 {
+		return hosts, nil
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(hosts))
+	for i, host := range hosts {
+		keys[i] = hostKey(host)
+	}
+
+	output, err := batchGetItems(ctx, args, keys, aws.String("#host, #ttl"), map[string]*string{"#host": aws.String(attribHost), "#ttl": aws.String(attribTTL)})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		Host string
+		TTL  int64
+	}
+	if err := dynamodbattribute.UnmarshalListOfMaps(output, &items); err != nil {
+		return nil, err
+	}
+
+	unexpired := make(map[string]bool, len(items))
+	for _, item := range items {
+		unexpired[item.Host] = item.TTL == 0 || item.TTL > now.Unix()
+	}
+
+	filtered := []string{}
+	for _, host := range hosts {
+		if unexpired[host] {
+			filtered = append(filtered, host)
+		}
+	}
+
+	return filtered, nil
+}
+
+func deleteGameGetConnections(ctx context.Context, args Args, host, connName, connID string) (game, string, map[string]string, error) {
 	exp, err := expression.NewBuilder().
 		WithCondition(expression.Or(
 			expression.Name(attribConnections+"."+connName).Equal(expression.Value(connID)),
@@ -171,7 +596,7 @@ func deleteGameGetConnectionIDs(ctx context.Context, args Args, host, connName,
 		)).
 		Build()
 	if err != nil {
-		return nil, err
+		return game{}, "", nil, err
 	}
 
 	output, err := args.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
@@ -183,22 +608,28 @@ func deleteGameGetConnectionIDs(ctx context.Context, args Args, host, connName,
 		ReturnValues:              aws.String(dynamodb.ReturnValueAllOld),
 	})
 	if err != nil {
-		return nil, err
+		return game{}, "", nil, err
 	}
 
 	// Read the attributes into a struct.
-	var item struct{ Connections map[string]string }
+	var item struct {
+		Game        []byte
+		Opponent    string
+		Connections map[string]string
+	}
 	if err := dynamodbattribute.UnmarshalMap(output.Attributes, &item); err != nil {
-		return nil, err
+		return game{}, "", nil, err
 	}
 
-	// Get just the connection ID values.
-	var connectionIDs []string
-	for _, v := range item.Connections {
-		connectionIDs = append(connectionIDs, v)
+	// A game that was already deleted has nothing to unmarshal.
+	var game game
+	if item.Game != nil {
+		if err := json.Unmarshal(item.Game, &game); err != nil {
+			return game, "", nil, err
+		}
 	}
 
-	return connectionIDs, err
+	return game, item.Opponent, item.Connections, nil
 }
 
 func getInGame(ctx context.Context, args Args, host string) (nickname, inGame string, err error) {
@@ -245,116 +676,1714 @@ func updateInGame(ctx context.Context, args Args, host, nickname, inGame string)
 	return item.Nickname, item.InGame, err
 }
 
-func deleteItem(ctx context.Context, args Args, host string) error {
-	_, err := args.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(args.TableName),
-		Key:       hostKey(host),
-	})
-	return err
-}
+// removeInGame forgets the game that a connection was playing. The rest of the connection, such as
+// the nickname it has proved it owns, is kept. It does nothing if the connection is gone.
+func removeInGame(ctx context.Context, args Args, connID string) error {
+	update := expression.Remove(expression.Name(attribInGame))
+	condition := expression.Name(attribHost).AttributeExists()
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
 
-// updateItem wraps dynamodb.UpdateItemWithContext.
-func updateItem(ctx context.Context, args Args, host string, update expression.UpdateBuilder, returnOldValues bool) (*dynamodb.UpdateItemOutput, error) {
-	update = update.Set(expression.Name(attribTTL), expression.Value(time.Now().Add(time.Hour).Unix()))
-	builder := expression.NewBuilder().WithUpdate(update)
-	return updateItemWithBuilder(ctx, args, host, builder, returnOldValues)
-}
+	_, err := updateItemWithBuilder(ctx, args, connID, builder, false)
 
-// updateItemWithCondition wraps dynamodb.UpdateItemWithContext.
-func updateItemWithCondition(ctx context.Context, args Args, host string, update expression.UpdateBuilder, condition expression.ConditionBuilder, returnOldValues bool) (*dynamodb.UpdateItemOutput, error) {
-	update = update.Set(expression.Name(attribTTL), expression.Value(time.Now().Add(time.Hour).Unix()))
-	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
-	return updateItemWithBuilder(ctx, args, host, builder, returnOldValues)
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+
+	return err
 }
 
-// updateItemWithBuilder wraps dynamodb.UpdateItemWithContext.
-func updateItemWithBuilder(ctx context.Context, args Args, host string, builder expression.Builder, returnOldValues bool) (*dynamodb.UpdateItemOutput, error) {
-	exp, err := builder.Build()
+// touchConnection records that a connection is still alive, and returns the connection's nickname
+// and the game it is in.
+func touchConnection(ctx context.Context, args Args, connID string, now time.Time) (nickname, inGame string, err error) {
+	update := expression.Set(expression.Name(attribLastSeen), expression.Value(now.Unix()))
+
+	output, err := updateItem(ctx, args, connID, update, true)
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	input := &dynamodb.UpdateItemInput{
-		TableName:                 aws.String(args.TableName),
-		Key:                       hostKey(host),
-		UpdateExpression:          exp.Update(),
-		ConditionExpression:       exp.Condition(),
-		ExpressionAttributeNames:  exp.Names(),
-		ExpressionAttributeValues: exp.Values(),
+	var item struct {
+		Nickname string
+		InGame   string
 	}
 
-	if returnOldValues {
-		input.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
-	}
+	err = dynamodbattribute.UnmarshalMap(output.Attributes, &item)
 
-	return args.DB.UpdateItemWithContext(ctx, input)
+	return item.Nickname, item.InGame, err
 }
 
-func hostKey(host string) map[string]*dynamodb.AttributeValue {
-	return map[string]*dynamodb.AttributeValue{attribHost: {S: aws.String(host)}}
+// updateHello records what a connection said in its hello: the protocol version that it uses,
+// whether it asked for lite updates, and how it would like boards to be encoded.
+func updateHello(ctx context.Context, args Args, connID string, protocol int, lite bool, boardEncoding string) error {
+	update := expression.
+		Set(expression.Name(attribProtocol), expression.Value(protocol)).
+		Set(expression.Name(attribLite), expression.Value(lite)).
+		Set(expression.Name(attribBoardEncoding), expression.Value(boardEncoding))
+	_, err := updateItem(ctx, args, connID, update, false)
+	return err
 }
 
-// EnsureTable creates the DynamoDB table if it does not exist. It is useful in test environments.
-func EnsureTable(ctx context.Context, db *dynamodb.DynamoDB, name string) error {
-	_, err := db.CreateTableWithContext(ctx, &dynamodb.CreateTableInput{
-		TableName: aws.String(name),
-		AttributeDefinitions: []*dynamodb.AttributeDefinition{
-			{AttributeName: aws.String(attribHost), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
-			{AttributeName: aws.String(attribOpponent), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
-		},
-		KeySchema: []*dynamodb.KeySchemaElement{
-			{AttributeName: aws.String(attribHost), KeyType: aws.String(dynamodb.KeyTypeHash)},
-		},
-		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
-			{
-				IndexName: aws.String(indexByOpponent),
-				KeySchema: []*dynamodb.KeySchemaElement{
-					{AttributeName: aws.String(attribOpponent), KeyType: aws.String(dynamodb.KeyTypeHash)},
-					{AttributeName: aws.String(attribHost), KeyType: aws.String(dynamodb.KeyTypeRange)},
-				},
-				Projection: &dynamodb.Projection{
-					ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly),
-				},
-				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-					ReadCapacityUnits:  aws.Int64(2),
-					WriteCapacityUnits: aws.Int64(2),
-				},
-			},
-		},
-		BillingMode: aws.String(dynamodb.BillingModeProvisioned),
-		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(2),
-			WriteCapacityUnits: aws.Int64(2),
-		},
-	})
-
-	if err != nil && !strings.HasPrefix(err.Error(), "ResourceInUseException") {
-		return err
-	}
+// updatePrefs are how a connection asked for updates to be sent in its hello.
+type updatePrefs struct {
+	Lite          bool
+	BoardEncoding string
+}
 
-	_, err = db.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
-		TableName: aws.String(name),
-		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
-			AttributeName: aws.String(attribTTL),
-			Enabled:       aws.Bool(true),
-		},
+// getUpdatePrefs returns how a connection asked for updates to be sent.
+func getUpdatePrefs(ctx context.Context, args Args, connID string) (updatePrefs, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(args.TableName),
+		Key:                  hostKey(connID),
+		ProjectionExpression: aws.String(attribLite + ", " + attribBoardEncoding),
 	})
-
-	if err != nil && !strings.Contains(err.Error(), "TimeToLive is already enabled") {
-		return err
+	if err != nil {
+		return updatePrefs{}, err
 	}
 
-	return nil
+	var prefs updatePrefs
+	err = dynamodbattribute.UnmarshalMap(output.Item, &prefs)
+
+	return prefs, err
 }
 
-func defaultDB() *dynamodb.DynamoDB {
-	return dynamodb.New(session.Must(session.NewSession(aws.NewConfig().
-		WithRegion(os.Getenv("AWS_REGION")))))
+// getLite returns whether a connection asked for lite updates.
+func getLite(ctx context.Context, args Args, connID string) (bool, error) {
+	prefs, err := getUpdatePrefs(ctx, args, connID)
+	return prefs.Lite, err
 }
 
-func LocalDB() *dynamodb.DynamoDB {
+// getProtocol returns the protocol version of a connection. It is not ok if the connection has
+// not said hello.
+func getProtocol(ctx context.Context, args Args, connID string) (protocol int, ok bool, err error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(args.TableName),
+		Key:                  hostKey(connID),
+		ProjectionExpression: aws.String(attribProtocol),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	var item struct{ Protocol *int }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return 0, false, err
+	}
+
+	if item.Protocol == nil {
+		return 0, false, nil
+	}
+
+	return *item.Protocol, true, nil
+}
+
+// getRateLimit returns the token bucket of a connection. It is zero if the connection has not sent
+// a message yet.
+func getRateLimit(ctx context.Context, args Args, connID string) (rateLimit, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(args.TableName),
+		Key:                  hostKey(connID),
+		ProjectionExpression: aws.String(strings.Join([]string{attribTokens, attribTokensAt, attribViolations}, ", ")),
+	})
+	if err != nil {
+		return rateLimit{}, err
+	}
+
+	var limit rateLimit
+	err = dynamodbattribute.UnmarshalMap(output.Item, &limit)
+
+	return limit, err
+}
+
+// updateRateLimit saves the token bucket of a connection. It is not ok if the bucket was changed
+// since it was read, such as by a message that was handled concurrently.
+func updateRateLimit(ctx context.Context, args Args, connID string, old, limit rateLimit) (bool, error) {
+	update := expression.
+		Set(expression.Name(attribTokens), expression.Value(limit.Tokens)).
+		Set(expression.Name(attribTokensAt), expression.Value(limit.TokensAt)).
+		Set(expression.Name(attribViolations), expression.Value(limit.Violations))
+
+	condition := expression.Name(attribTokensAt).AttributeNotExists()
+	if old.TokensAt != 0 {
+		condition = expression.Name(attribTokensAt).Equal(expression.Value(old.TokensAt))
+	}
+
+	if _, err := updateItemWithCondition(ctx, args, connID, update, condition, false); err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// touchGame keeps a game from expiring while its players are connected, even if nobody moves. It
+// does nothing if the game has ended, or if the game is already kept for longer, such as for its
+// clock.
+func touchGame(ctx context.Context, args Args, host string, now time.Time) error {
+	ttl := now.Add(itemTTL).Unix()
+	update := expression.Set(expression.Name(attribTTL), expression.Value(ttl))
+	condition := expression.Name(attribGame).AttributeExists().And(expression.Name(attribTTL).LessThan(expression.Value(ttl)))
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+
+	_, err := updateItemWithBuilder(ctx, args, host, builder, false)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+
+	return err
+}
+
+// createClaim claims a nickname with the hash of its token. It is not ok if the nickname is
+// already claimed. Claims that have expired may be taken over before DynamoDB deletes them, so the
+// previous owner's privacy settings are removed.
+func createClaim(ctx context.Context, args Args, nickname, tokenHash string, now time.Time) (bool, error) {
+	update := expression.
+		Set(expression.Name(attribTokenHash), expression.Value(tokenHash)).
+		Set(expression.Name(attribTTL), expression.Value(now.Add(claimTTL).Unix())).
+		Remove(expression.Name(attribPrivacy))
+	condition := expression.Or(
+		expression.Name(attribHost).AttributeNotExists(),
+		expression.Name(attribTTL).LessThan(expression.Value(now.Unix())),
+	)
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+
+	_, err := updateItemWithBuilder(ctx, args, claimKey(nickname), builder, false)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// getClaim returns the token hash of a claimed nickname. It is not ok if the nickname is not
+// claimed, or the claim has expired.
+func getClaim(ctx context.Context, args Args, nickname string, now time.Time) (tokenHash string, ok bool, err error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(claimKey(nickname)),
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	var item struct {
+		TokenHash string
+		TTL       int64
+	}
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return "", false, err
+	}
+
+	if item.TokenHash == "" || item.TTL <= now.Unix() {
+		return "", false, nil
+	}
+
+	return item.TokenHash, true, nil
+}
+
+// refreshClaim keeps a nickname claimed for another claimTTL.
+func refreshClaim(ctx context.Context, args Args, nickname string, now time.Time) error {
+	update := expression.Set(expression.Name(attribTTL), expression.Value(now.Add(claimTTL).Unix()))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, claimKey(nickname), builder, false)
+	return err
+}
+
+// updatePrivacy saves the privacy settings of a claimed nickname, which are kept with the claim.
+func updatePrivacy(ctx context.Context, args Args, nickname string, p privacy) error {
+	update := expression.Set(expression.Name(attribPrivacy), expression.Value(p))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, claimKey(nickname), builder, false)
+	return err
+}
+
+// getPrivacies returns the privacy settings of claimed nicknames. Nicknames without settings,
+// including those that aren't claimed, are left out.
+func getPrivacies(ctx context.Context, args Args, nicknames []string, now time.Time) (map[string]privacy, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(nicknames))
+	for i, nickname := range nicknames {
+		keys[i] = hostKey(claimKey(nickname))
+	}
+
+	output, err := batchGetItems(ctx, args, keys, aws.String("#host, #privacy, #ttl"), map[string]*string{"#host": aws.String(attribHost), "#privacy": aws.String(attribPrivacy), "#ttl": aws.String(attribTTL)})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		Host    string
+		Privacy *privacy
+		TTL     int64
+	}
+	if err := dynamodbattribute.UnmarshalListOfMaps(output, &items); err != nil {
+		return nil, err
+	}
+
+	privacies := make(map[string]privacy, len(items))
+	for _, item := range items {
+		if item.Privacy != nil && item.TTL > now.Unix() {
+			privacies[strings.TrimPrefix(item.Host, claimKey(""))] = *item.Privacy
+		}
+	}
+
+	return privacies, nil
+}
+
+// updateAuthenticated records the nickname that a connection has proved it owns.
+func updateAuthenticated(ctx context.Context, args Args, connID, nickname string) error {
+	update := expression.Set(expression.Name(attribAuthenticated), expression.Value(nickname))
+	_, err := updateItem(ctx, args, connID, update, false)
+	return err
+}
+
+// getAuthenticated returns the nickname that a connection has proved it owns, if any.
+func getAuthenticated(ctx context.Context, args Args, connID string) (string, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(args.TableName),
+		Key:                  hostKey(connID),
+		ProjectionExpression: aws.String(attribAuthenticated),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var item struct{ Authenticated string }
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Authenticated, err
+}
+
+// getConnection returns what is recorded about a connection. It is not ok if there is no record of
+// the connection.
+func getConnection(ctx context.Context, args Args, connID string) (connection, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(connID),
+	})
+	if err != nil {
+		return connection{}, false, err
+	}
+
+	if output.Item == nil {
+		return connection{}, false, nil
+	}
+
+	var conn connection
+	err = dynamodbattribute.UnmarshalMap(output.Item, &conn)
+
+	return conn, true, err
+}
+
+// updateDisconnectedAt records when a connection was closed, while its record is kept for the
+// player to resume their game.
+func updateDisconnectedAt(ctx context.Context, args Args, connID string, now time.Time) error {
+	update := expression.Set(expression.Name(attribDisconnectedAt), expression.Value(now.Unix()))
+	_, err := updateItem(ctx, args, connID, update, false)
+	return err
+}
+
+func putReplay(ctx context.Context, args Args, replay replay) error {
+	replayBytes, err := json.Marshal(&replay)
+	if err != nil {
+		return err
+	}
+
+	_, err = args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attribHost:   {S: aws.String(replayKey(replay.Host, replay.ID))},
+			attribReplay: {B: replayBytes},
+			attribTTL:    {N: aws.String(strconv.FormatInt(time.Now().Add(replayTTL).Unix(), 10))},
+		},
+	})
+
+	return err
+}
+
+func getReplay(ctx context.Context, args Args, host, id string) (replay, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(replayKey(host, id)),
+	})
+	if err != nil {
+		return replay{}, false, err
+	}
+
+	if output.Item == nil {
+		return replay{}, false, nil
+	}
+
+	var item struct{ Replay []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return replay{}, false, err
+	}
+
+	var replay replay
+	err = json.Unmarshal(item.Replay, &replay)
+
+	return replay, true, err
+}
+
+// addReplayID adds the ID of a replay to the IDs of a host's replays, which are kept for as long
+// as the replay.
+func addReplayID(ctx context.Context, args Args, host, id string) error {
+	update := expression.
+		Add(expression.Name(attribReplays), expression.Value(&dynamodb.AttributeValue{SS: []*string{aws.String(id)}})).
+		Set(expression.Name(attribTTL), expression.Value(time.Now().Add(replayTTL).Unix()))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, replaysKey(host), builder, false)
+	return err
+}
+
+// removeReplayIDs removes IDs from the IDs of a host's replays.
+func removeReplayIDs(ctx context.Context, args Args, host string, ids []string) error {
+	update := expression.Delete(expression.Name(attribReplays), expression.Value(&dynamodb.AttributeValue{SS: aws.StringSlice(ids)}))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, replaysKey(host), builder, false)
+	return err
+}
+
+// getReplayIDs returns the IDs of a host's replays, in no particular order.
+func getReplayIDs(ctx context.Context, args Args, host string) ([]string, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(replaysKey(host)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct {
+		Replays []string `dynamodbav:",stringset"`
+	}
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Replays, err
+}
+
+// putMoveQuality saves the quality of a player's moves in their latest games. It does not expire.
+func putMoveQuality(ctx context.Context, args Args, nickname string, games []gameQuality) error {
+	gamesBytes, err := json.Marshal(games)
+	if err != nil {
+		return err
+	}
+
+	_, err = args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attribHost:        {S: aws.String(moveQualityKey(nickname))},
+			attribMoveQuality: {B: gamesBytes},
+		},
+	})
+
+	return err
+}
+
+// getMoveQuality returns the quality of a player's moves in their latest games, oldest first.
+func getMoveQuality(ctx context.Context, args Args, nickname string) ([]gameQuality, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(moveQualityKey(nickname)),
+	})
+	if err != nil || output.Item == nil {
+		return nil, err
+	}
+
+	var item struct{ MoveQuality []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return nil, err
+	}
+
+	var games []gameQuality
+	err = json.Unmarshal(item.MoveQuality, &games)
+
+	return games, err
+}
+
+// putHeatMap saves a player's heat map, replacing the one they had before.
+func putHeatMap(ctx context.Context, args Args, nickname string, m heatMap) error {
+	mapBytes, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attribHost:    {S: aws.String(heatMapKey(nickname))},
+			attribHeatMap: {B: mapBytes},
+		},
+	})
+
+	return err
+}
+
+// getHeatMap returns a player's heat map, which is empty if RunHeatMaps hasn't counted any of their
+// games.
+func getHeatMap(ctx context.Context, args Args, nickname string) (heatMap, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(heatMapKey(nickname)),
+	})
+	if err != nil || output.Item == nil {
+		return heatMap{}, err
+	}
+
+	var item struct{ HeatMap []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return heatMap{}, err
+	}
+
+	var m heatMap
+	err = json.Unmarshal(item.HeatMap, &m)
+
+	return m, err
+}
+
+// getStrength returns the strength of a player's adaptive AI, and whether they have played it.
+func getStrength(ctx context.Context, args Args, nickname string) (int, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(adaptiveKey(nickname)),
+	})
+	if err != nil || output.Item == nil {
+		return 0, false, err
+	}
+
+	var item struct{ Strength int }
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Strength, true, err
+}
+
+// updateStrength saves the strength of a player's adaptive AI. Like ratings, it does not expire.
+func updateStrength(ctx context.Context, args Args, nickname string, strength int) error {
+	update := expression.Set(expression.Name(attribStrength), expression.Value(strength))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, adaptiveKey(nickname), builder, false)
+	return err
+}
+
+// putDataJob saves a player's data job, replacing any job they had before.
+func putDataJob(ctx context.Context, args Args, job dataJob, now time.Time) error {
+	item, err := dynamodbattribute.MarshalMap(job)
+	if err != nil {
+		return err
+	}
+
+	item[attribHost] = &dynamodb.AttributeValue{S: aws.String(dataJobKey(job.Nickname))}
+	item[attribTTL] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(now.Add(dataJobTTL).Unix(), 10))}
+
+	_, err = args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item:      item,
+	})
+
+	return err
+}
+
+// getDataJob returns a player's data job. It is not ok if the player has none.
+func getDataJob(ctx context.Context, args Args, nickname string) (dataJob, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(dataJobKey(nickname)),
+	})
+	if err != nil || output.Item == nil {
+		return dataJob{}, false, err
+	}
+
+	var job dataJob
+	err = dynamodbattribute.UnmarshalMap(output.Item, &job)
+
+	return job, true, err
+}
+
+// addPending adds a member to the set of work waiting to be done that is stored under key, such as
+// the nicknames whose data jobs are waiting to run.
+func addPending(ctx context.Context, args Args, key, member string) error {
+	update := expression.Add(expression.Name(attribPending), expression.Value(&dynamodb.AttributeValue{SS: []*string{aws.String(member)}}))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, key, builder, false)
+	return err
+}
+
+// removePending removes a member from the set of work waiting to be done that is stored under key.
+func removePending(ctx context.Context, args Args, key, member string) error {
+	update := expression.Delete(expression.Name(attribPending), expression.Value(&dynamodb.AttributeValue{SS: []*string{aws.String(member)}}))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, key, builder, false)
+	return err
+}
+
+// getPending returns the members of the set of work waiting to be done that is stored under key.
+func getPending(ctx context.Context, args Args, key string) ([]string, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct {
+		Pending []string `dynamodbav:",stringset"`
+	}
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Pending, err
+}
+
+// appendWebhookEvents adds JSON events to the ones waiting to be posted to a tournament's webhook.
+func appendWebhookEvents(ctx context.Context, args Args, name, webhook string, events []string) error {
+	update := expression.
+		Set(expression.Name(attribWebhook), expression.Value(webhook)).
+		Set(expression.Name(attribEvents), expression.ListAppend(
+			// An empty []string would be stored as NULL, which list_append can't append to.
+			expression.IfNotExists(expression.Name(attribEvents), expression.Value(&dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}})),
+			expression.Value(events),
+		)).
+		Set(expression.Name(attribTTL), expression.Value(time.Now().Add(webhookQueueTTL).Unix()))
+
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, webhookQueueKey(name), builder, false)
+	return err
+}
+
+// takeWebhookEvents deletes and returns the webhook of a tournament and the JSON events waiting to
+// be posted to it.
+func takeWebhookEvents(ctx context.Context, args Args, name string) (string, []string, error) {
+	output, err := args.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(args.TableName),
+		Key:          hostKey(webhookQueueKey(name)),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var item struct {
+		Webhook string
+		Events  []string
+	}
+	err = dynamodbattribute.UnmarshalMap(output.Attributes, &item)
+
+	return item.Webhook, item.Events, err
+}
+
+// scanReplays returns every stored replay. It reads the whole table, so it is only for background
+// jobs.
+func scanReplays(ctx context.Context, args Args) ([]replay, error) {
+	filter := expression.Name(attribHost).BeginsWith(replayKey("", ""))
+	exp, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var replays []replay
+	var pageErr error
+
+	err = args.DB.ScanPagesWithContext(ctx, &dynamodb.ScanInput{
+		TableName:                 aws.String(args.TableName),
+		FilterExpression:          exp.Filter(),
+		ExpressionAttributeNames:  exp.Names(),
+		ExpressionAttributeValues: exp.Values(),
+	}, func(page *dynamodb.ScanOutput, _ bool) bool {
+		var items []struct{ Replay []byte }
+		if pageErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); pageErr != nil {
+			return false
+		}
+
+		for _, item := range items {
+			var r replay
+			if pageErr = json.Unmarshal(item.Replay, &r); pageErr != nil {
+				return false
+			}
+			replays = append(replays, r)
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replays, pageErr
+}
+
+// nextSeq returns the sequence number of the next message sent to the players of a host's game.
+func nextSeq(ctx context.Context, args Args, host string) (int, error) {
+	update := expression.Add(expression.Name(attribSeq), expression.Value(1))
+
+	output, err := updateItem(ctx, args, outboxKey(host), update, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var item struct{ Seq int }
+	err = dynamodbattribute.UnmarshalMap(output.Attributes, &item)
+
+	return item.Seq + 1, err
+}
+
+// getSeq returns the sequence number of the last message sent to the players of a host's game.
+func getSeq(ctx context.Context, args Args, host string) (int, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(outboxKey(host)),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var item struct{ Seq int }
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Seq, err
+}
+
+func putOutbound(ctx context.Context, args Args, host string, message outbound) error {
+	_, err := args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attribHost:       {S: aws.String(outboundKey(host, message.Seq))},
+			attribSeq:        {N: aws.String(strconv.Itoa(message.Seq))},
+			attribRecipients: {SS: aws.StringSlice(message.Recipients)},
+			attribMessage:    {B: message.Message},
+			attribTTL:        {N: aws.String(strconv.FormatInt(time.Now().Add(itemTTL).Unix(), 10))},
+		},
+	})
+
+	return err
+}
+
+// getOutbound returns the messages sent to the players of a host's game that are numbered from
+// first to last. Messages that have expired are left out.
+func getOutbound(ctx context.Context, args Args, host string, first, last int) ([]outbound, error) {
+	if first > last {
+		return nil, nil
+	}
+
+	var keys []map[string]*dynamodb.AttributeValue
+	for seq := first; seq <= last; seq++ {
+		keys = append(keys, hostKey(outboundKey(host, seq)))
+	}
+
+	output, err := batchGetItems(ctx, args, keys, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var outbox []outbound
+	err = dynamodbattribute.UnmarshalListOfMaps(output, &outbox)
+
+	return outbox, err
+}
+
+func subscribe(ctx context.Context, args Args, topic, connID, nickname string) error {
+	update := expression.
+		Set(expression.Name(attribTopic), expression.Value(topic)).
+		Set(expression.Name(attribConnectionID), expression.Value(connID)).
+		Set(expression.Name(attribNickname), expression.Value(nickname))
+
+	if _, err := updateItem(ctx, args, subscriptionKey(topic, connID), update, false); err != nil {
+		return err
+	}
+
+	// The connection remembers its topics, so that its subscriptions can be removed when it closes.
+	update = expression.Add(expression.Name(attribTopics), expression.Value(topicSet{topic}))
+	_, err := updateItem(ctx, args, connID, update, false)
+	return err
+}
+
+func unsubscribe(ctx context.Context, args Args, topic, connID string) error {
+	if err := deleteItem(ctx, args, subscriptionKey(topic, connID)); err != nil {
+		return err
+	}
+
+	// The connection forgets the topic, so that its topics are the ones it is subscribed to. The
+	// record of a closed connection may already be gone, and isn't brought back.
+	update := expression.Delete(expression.Name(attribTopics), expression.Value(topicSet{topic}))
+	condition := expression.Name(attribHost).AttributeExists()
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+
+	_, err := updateItemWithBuilder(ctx, args, connID, builder, false)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+
+	return err
+}
+
+func getSubscription(ctx context.Context, args Args, topic, connID string) (subscriber, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(subscriptionKey(topic, connID)),
+	})
+	if err != nil {
+		return subscriber{}, false, err
+	}
+
+	if output.Item == nil {
+		return subscriber{}, false, nil
+	}
+
+	var sub subscriber
+	err = dynamodbattribute.UnmarshalMap(output.Item, &sub)
+
+	return sub, true, err
+}
+
+// takeSubscription removes a connection's subscription to a topic. It is not ok if the subscription
+// was already gone, such as when somebody else took it first.
+func takeSubscription(ctx context.Context, args Args, topic, connID string) (bool, error) {
+	output, err := args.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(args.TableName),
+		Key:          hostKey(subscriptionKey(topic, connID)),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(output.Attributes) > 0, nil
+}
+
+func getSubscribers(ctx context.Context, args Args, topic string) ([]subscriber, error) {
+	output, err := args.DB.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName: aws.String(args.TableName),
+		IndexName: aws.String(indexByTopic),
+		KeyConditions: map[string]*dynamodb.Condition{
+			attribTopic: {
+				ComparisonOperator: aws.String(dynamodb.ComparisonOperatorEq),
+				AttributeValueList: []*dynamodb.AttributeValue{{S: aws.String(topic)}},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var subscribers []subscriber
+	err = dynamodbattribute.UnmarshalListOfMaps(output.Items, &subscribers)
+
+	return subscribers, err
+}
+
+// appendChat adds a line to the chat backlog stored under key, keeping at most limit lines.
+func appendChat(ctx context.Context, args Args, key string, line chatLine, limit int) error {
+	chat, err := getChat(ctx, args, key)
+	if err != nil {
+		return err
+	}
+
+	chat = append(chat, line)
+	if len(chat) > limit {
+		chat = chat[len(chat)-limit:]
+	}
+
+	return putChat(ctx, args, key, chat)
+}
+
+// putChat replaces the chat backlog stored under key.
+func putChat(ctx context.Context, args Args, key string, chat []chatLine) error {
+	chatBytes, err := json.Marshal(chat)
+	if err != nil {
+		return err
+	}
+
+	_, err = updateItem(ctx, args, key, expression.Set(expression.Name(attribChat), expression.Value(chatBytes)), false)
+	return err
+}
+
+func getChat(ctx context.Context, args Args, key string) ([]chatLine, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct{ Chat []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return nil, err
+	}
+
+	if item.Chat == nil {
+		return nil, nil
+	}
+
+	var chat []chatLine
+	err = json.Unmarshal(item.Chat, &chat)
+
+	return chat, err
+}
+
+func updatePresenceStatus(ctx context.Context, args Args, nickname, status string) (presence, error) {
+	update := expression.
+		Set(expression.Name(attribNickname), expression.Value(nickname)).
+		Set(expression.Name(attribStatus), expression.Value(status))
+
+	return updatePresence(ctx, args, nickname, update)
+}
+
+func updatePresencePlaying(ctx context.Context, args Args, nickname string, playing bool) (presence, error) {
+	update := expression.
+		Set(expression.Name(attribNickname), expression.Value(nickname)).
+		Set(expression.Name(attribPlaying), expression.Value(playing))
+
+	return updatePresence(ctx, args, nickname, update)
+}
+
+func updatePresence(ctx context.Context, args Args, nickname string, update expression.UpdateBuilder) (presence, error) {
+	update = update.Set(expression.Name(attribTTL), expression.Value(time.Now().Add(itemTTL).Unix()))
+
+	exp, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return presence{}, err
+	}
+
+	output, err := args.DB.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(args.TableName),
+		Key:                       hostKey(presenceKey(nickname)),
+		UpdateExpression:          exp.Update(),
+		ExpressionAttributeNames:  exp.Names(),
+		ExpressionAttributeValues: exp.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		return presence{}, err
+	}
+
+	var p presence
+	err = dynamodbattribute.UnmarshalMap(output.Attributes, &p)
+
+	return p, err
+}
+
+// deletePresence removes a player's presence, which makes them offline. It is not ok if the player
+// had no presence.
+func deletePresence(ctx context.Context, args Args, nickname string) (bool, error) {
+	output, err := args.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(args.TableName),
+		Key:          hostKey(presenceKey(nickname)),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(output.Attributes) > 0, nil
+}
+
+func getPresences(ctx context.Context, args Args, nicknames []string) ([]presence, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(nicknames))
+	for i, nickname := range nicknames {
+		keys[i] = hostKey(presenceKey(nickname))
+	}
+
+	output, err := batchGetItems(ctx, args, keys, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var presences []presence
+	err = dynamodbattribute.UnmarshalListOfMaps(output, &presences)
+
+	return presences, err
+}
+
+func getMaintenance(ctx context.Context, args Args) (maintenance, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(maintenanceKey),
+	})
+	if err != nil {
+		return maintenance{}, err
+	}
+
+	var item struct {
+		Enabled   bool
+		Message   string
+		StartedAt int64
+		EndedAt   int64
+	}
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return maintenance{}, err
+	}
+
+	m := maintenance{Enabled: item.Enabled, Message: item.Message}
+	if item.StartedAt != 0 {
+		m.StartedAt = time.Unix(item.StartedAt, 0)
+	}
+	if item.EndedAt != 0 {
+		m.EndedAt = time.Unix(item.EndedAt, 0)
+	}
+
+	return m, nil
+}
+
+// updateMaintenance turns maintenance mode on or off. Unlike most items, the maintenance item
+// does not expire.
+func updateMaintenance(ctx context.Context, args Args, enabled bool, message string, now time.Time) error {
+	update := expression.
+		Set(expression.Name(attribEnabled), expression.Value(enabled)).
+		Set(expression.Name(attribMessage), expression.Value(message))
+
+	if enabled {
+		update = update.Set(expression.Name(attribStartedAt), expression.Value(now.Unix()))
+	} else {
+		update = update.Set(expression.Name(attribEndedAt), expression.Value(now.Unix()))
+	}
+
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, maintenanceKey, builder, false)
+	return err
+}
+
+// getRatings returns the ratings of players who have played a rated game. Players who haven't are
+// left out.
+func getRatings(ctx context.Context, args Args, nicknames []string) ([]rating, error) {
+	return getRatingsByKey(ctx, args, nicknames, ratingKey)
+}
+
+// getBotRatings returns the ladder ratings of bots who have played a ladder game. Bots that haven't
+// are left out.
+func getBotRatings(ctx context.Context, args Args, nicknames []string) ([]rating, error) {
+	return getRatingsByKey(ctx, args, nicknames, botRatingKey)
+}
+
+func getRatingsByKey(ctx context.Context, args Args, nicknames []string, key func(string) string) ([]rating, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(nicknames))
+	for i, nickname := range nicknames {
+		keys[i] = hostKey(key(nickname))
+	}
+
+	output, err := batchGetItems(ctx, args, keys, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ratings []rating
+	err = dynamodbattribute.UnmarshalListOfMaps(output, &ratings)
+
+	return ratings, err
+}
+
+// updateRating saves a player's rating. Ratings do not expire.
+func updateRating(ctx context.Context, args Args, r rating) error {
+	return updateRatingByKey(ctx, args, ratingKey(r.Nickname), r)
+}
+
+// updateBotRating saves a bot's ladder rating. Like players' ratings, it does not expire.
+func updateBotRating(ctx context.Context, args Args, r rating) error {
+	return updateRatingByKey(ctx, args, botRatingKey(r.Nickname), r)
+}
+
+func updateRatingByKey(ctx context.Context, args Args, key string, r rating) error {
+	update := expression.
+		Set(expression.Name(attribNickname), expression.Value(r.Nickname)).
+		Set(expression.Name(attribRating), expression.Value(r.Rating)).
+		Set(expression.Name(attribGames), expression.Value(r.Games))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, key, builder, false)
+	return err
+}
+
+func getLeaderboard(ctx context.Context, args Args) ([]rating, error) {
+	return getLeaderboardByKey(ctx, args, leaderboardKey)
+}
+
+func getBotLeaderboard(ctx context.Context, args Args) ([]rating, error) {
+	return getLeaderboardByKey(ctx, args, botLeaderboardKey)
+}
+
+func getLeaderboardByKey(ctx context.Context, args Args, key string) ([]rating, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct{ Leaderboard []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return nil, err
+	}
+
+	if item.Leaderboard == nil {
+		return nil, nil
+	}
+
+	var leaderboard []rating
+	err = json.Unmarshal(item.Leaderboard, &leaderboard)
+
+	return leaderboard, err
+}
+
+// updateLeaderboard saves the highest ratings. Like the ratings themselves, it does not expire.
+func updateLeaderboard(ctx context.Context, args Args, leaderboard []rating) error {
+	return updateLeaderboardByKey(ctx, args, leaderboardKey, leaderboard)
+}
+
+// updateBotLeaderboard saves the highest ladder ratings.
+func updateBotLeaderboard(ctx context.Context, args Args, leaderboard []rating) error {
+	return updateLeaderboardByKey(ctx, args, botLeaderboardKey, leaderboard)
+}
+
+func updateLeaderboardByKey(ctx context.Context, args Args, key string, leaderboard []rating) error {
+	leaderboardBytes, err := json.Marshal(leaderboard)
+	if err != nil {
+		return err
+	}
+
+	update := expression.Set(expression.Name(attribLeaderboard), expression.Value(leaderboardBytes))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err = updateItemWithBuilder(ctx, args, key, builder, false)
+	return err
+}
+
+// registerLadderBot registers a bot for the bot ladder, forgetting the connection it last joined
+// on. Registrations do not expire.
+func registerLadderBot(ctx context.Context, args Args, nickname string) error {
+	update := expression.
+		Set(expression.Name(attribNickname), expression.Value(nickname)).
+		Remove(expression.Name(attribConnectionID)).
+		Remove(expression.Name(attribDomainName)).
+		Remove(expression.Name(attribStage))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, ladderBotKey(nickname), builder, false)
+	return err
+}
+
+// updateLadderBotConnection saves the connection that a bot joined the ladder on. It is not ok if
+// the bot is not registered.
+func updateLadderBotConnection(ctx context.Context, args Args, bot ladderBot) (bool, error) {
+	update := expression.
+		Set(expression.Name(attribConnectionID), expression.Value(bot.ConnectionID)).
+		Set(expression.Name(attribDomainName), expression.Value(bot.DomainName)).
+		Set(expression.Name(attribStage), expression.Value(bot.Stage))
+	condition := expression.Name(attribHost).AttributeExists()
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+
+	_, err := updateItemWithBuilder(ctx, args, ladderBotKey(bot.Nickname), builder, false)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// getLadderBot returns a registered bot. It is not ok if the bot is not registered.
+func getLadderBot(ctx context.Context, args Args, nickname string) (ladderBot, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(ladderBotKey(nickname)),
+	})
+	if err != nil || output.Item == nil {
+		return ladderBot{}, false, err
+	}
+
+	var bot ladderBot
+	err = dynamodbattribute.UnmarshalMap(output.Item, &bot)
+
+	return bot, true, err
+}
+
+// putResult keeps the result of a player's rated game for resultTTL, along with the game's event
+// log, and adds it to the IDs of the player's results.
+func putResult(ctx context.Context, args Args, nickname string, result gameResult, gameLog []json.RawMessage) error {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	logBytes, err := json.Marshal(gameLog)
+	if err != nil {
+		return err
+	}
+
+	id := resultID(result)
+	ttl := aws.String(strconv.FormatInt(time.Now().Add(resultTTL).Unix(), 10))
+
+	_, err = args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attribHost:   {S: aws.String(resultKey(nickname, id))},
+			attribResult: {B: resultBytes},
+			attribLog:    {B: logBytes},
+			attribTTL:    {N: ttl},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	update := expression.
+		Add(expression.Name(attribResults), expression.Value(&dynamodb.AttributeValue{SS: []*string{aws.String(id)}})).
+		Set(expression.Name(attribTTL), expression.Value(&dynamodb.AttributeValue{N: ttl}))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err = updateItemWithBuilder(ctx, args, resultsKey(nickname), builder, false)
+	return err
+}
+
+// getResult returns the result of a player's rated game and its event log. It is not ok if the
+// result isn't kept anymore.
+func getResult(ctx context.Context, args Args, nickname, id string, now time.Time) (gameResult, []json.RawMessage, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(resultKey(nickname, id)),
+	})
+	if err != nil || output.Item == nil {
+		return gameResult{}, nil, false, err
+	}
+
+	var item struct {
+		Result []byte
+		Log    []byte
+		TTL    int64
+	}
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return gameResult{}, nil, false, err
+	}
+
+	// DynamoDB may take a while to delete expired items.
+	if item.TTL < now.Unix() {
+		return gameResult{}, nil, false, nil
+	}
+
+	var result gameResult
+	if err := json.Unmarshal(item.Result, &result); err != nil {
+		return gameResult{}, nil, false, err
+	}
+
+	var gameLog []json.RawMessage
+	err = json.Unmarshal(item.Log, &gameLog)
+
+	return result, gameLog, true, err
+}
+
+// getResults returns the results of a player's rated games that are still kept, in no particular
+// order.
+func getResults(ctx context.Context, args Args, nickname string, now time.Time) ([]gameResult, error) {
+	ids, err := getResultIDs(ctx, args, nickname)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(ids))
+	for i, id := range ids {
+		keys[i] = hostKey(resultKey(nickname, id))
+	}
+
+	output, err := batchGetItems(ctx, args, keys, aws.String("#result, #ttl"), map[string]*string{"#result": aws.String(attribResult), "#ttl": aws.String(attribTTL)})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		Result []byte
+		TTL    int64
+	}
+	if err := dynamodbattribute.UnmarshalListOfMaps(output, &items); err != nil {
+		return nil, err
+	}
+
+	var results []gameResult
+	for _, item := range items {
+		if item.TTL < now.Unix() {
+			continue
+		}
+
+		var result gameResult
+		if err := json.Unmarshal(item.Result, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// getResultIDs returns the IDs of a player's results, in no particular order. Some of them may
+// have expired.
+func getResultIDs(ctx context.Context, args Args, nickname string) ([]string, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(resultsKey(nickname)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct {
+		Results []string `dynamodbav:",stringset"`
+	}
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Results, err
+}
+
+// deleteResults deletes the results of a player's rated games.
+func deleteResults(ctx context.Context, args Args, nickname string) error {
+	ids, err := getResultIDs(ctx, args, nickname)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := deleteItem(ctx, args, resultKey(nickname, id)); err != nil {
+			return err
+		}
+	}
+
+	return deleteItem(ctx, args, resultsKey(nickname))
+}
+
+// createDispute stores a new dispute. It is not ok if a dispute with the same ID already exists.
+func createDispute(ctx context.Context, args Args, d dispute, now time.Time) (bool, error) {
+	disputeBytes, err := json.Marshal(d)
+	if err != nil {
+		return false, err
+	}
+
+	condition, err := expression.NewBuilder().WithCondition(expression.Name(attribHost).AttributeNotExists()).Build()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = args.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(args.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			attribHost:    {S: aws.String(disputeKey(d.ID))},
+			attribDispute: {B: disputeBytes},
+			attribTTL:     {N: aws.String(strconv.FormatInt(now.Add(disputeTTL).Unix(), 10))},
+		},
+		ConditionExpression:      condition.Condition(),
+		ExpressionAttributeNames: condition.Names(),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// getDispute returns a dispute. It is not ok if there is no dispute with the ID.
+func getDispute(ctx context.Context, args Args, id string) (dispute, bool, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(disputeKey(id)),
+	})
+	if err != nil || output.Item == nil {
+		return dispute{}, false, err
+	}
+
+	var item struct{ Dispute []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return dispute{}, false, err
+	}
+
+	var d dispute
+	err = json.Unmarshal(item.Dispute, &d)
+
+	return d, true, err
+}
+
+func deleteItem(ctx context.Context, args Args, host string) error {
+	_, err := args.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(host),
+	})
+	return err
+}
+
+// updateItem wraps dynamodb.UpdateItemWithContext.
+func updateItem(ctx context.Context, args Args, host string, update expression.UpdateBuilder, returnOldValues bool) (*dynamodb.UpdateItemOutput, error) {
+	update = update.Set(expression.Name(attribTTL), expression.Value(time.Now().Add(itemTTL).Unix()))
+	builder := expression.NewBuilder().WithUpdate(update)
+	return updateItemWithBuilder(ctx, args, host, builder, returnOldValues)
+}
+
+// updateItemWithCondition wraps dynamodb.UpdateItemWithContext.
+func updateItemWithCondition(ctx context.Context, args Args, host string, update expression.UpdateBuilder, condition expression.ConditionBuilder, returnOldValues bool) (*dynamodb.UpdateItemOutput, error) {
+	update = update.Set(expression.Name(attribTTL), expression.Value(time.Now().Add(itemTTL).Unix()))
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+	return updateItemWithBuilder(ctx, args, host, builder, returnOldValues)
+}
+
+// updateItemWithBuilder wraps dynamodb.UpdateItemWithContext.
+func updateItemWithBuilder(ctx context.Context, args Args, host string, builder expression.Builder, returnOldValues bool) (*dynamodb.UpdateItemOutput, error) {
+	exp, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(args.TableName),
+		Key:                       hostKey(host),
+		UpdateExpression:          exp.Update(),
+		ConditionExpression:       exp.Condition(),
+		ExpressionAttributeNames:  exp.Names(),
+		ExpressionAttributeValues: exp.Values(),
+	}
+
+	if returnOldValues {
+		input.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
+	}
+
+	return args.DB.UpdateItemWithContext(ctx, input)
+}
+
+// maxBatchGetKeys is the most keys that DynamoDB reads in one BatchGetItem request.
+const maxBatchGetKeys = 100
+
+// batchGetItems wraps dynamodb.BatchGetItemWithContext. The keys are read in batches of
+// maxBatchGetKeys, and keys that DynamoDB leaves unprocessed, such as when it is throttling, are
+// asked for again after a backoff. The projection may be nil to read whole items. The items are not
+// in any particular order.
+func batchGetItems(ctx context.Context, args Args, keys []map[string]*dynamodb.AttributeValue, projection *string, names map[string]*string) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxBatchGetKeys {
+			n = maxBatchGetKeys
+		}
+
+		pending := &dynamodb.KeysAndAttributes{
+			Keys:                     keys[:n],
+			ProjectionExpression:     projection,
+			ExpressionAttributeNames: names,
+		}
+		keys = keys[n:]
+
+		for backoff := 50 * time.Millisecond; ; backoff *= 2 {
+			output, err := args.DB.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]*dynamodb.KeysAndAttributes{args.TableName: pending},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, output.Responses[args.TableName]...)
+
+			pending = output.UnprocessedKeys[args.TableName]
+			if pending == nil || len(pending.Keys) == 0 {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+	}
+
+	return items, nil
+}
+
+func hostKey(host string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{attribHost: {S: aws.String(host)}}
+}
+
+// subscriptionKey is the primary key of a connection's subscription to a topic.
+func subscriptionKey(topic, connID string) string {
+	return "#sub#" + topic + "#" + connID
+}
+
+// claimKey is the primary key of a claimed nickname.
+func claimKey(nickname string) string {
+	return "#claim#" + nickname
+}
+
+// presenceKey is the primary key of a player's presence status.
+func presenceKey(nickname string) string {
+	return "#presence#" + nickname
+}
+
+// ratingKey is the primary key of a player's rating.
+func ratingKey(nickname string) string {
+	return "#rating#" + nickname
+}
+
+// moveQualityKey is the primary key of the quality of a player's moves in their latest games.
+func moveQualityKey(nickname string) string {
+	return "#moveQuality#" + nickname
+}
+
+// heatMapKey is the primary key of a player's heat map.
+func heatMapKey(nickname string) string {
+	return "#heatMap#" + nickname
+}
+
+// adaptiveKey is the primary key of the strength of a player's adaptive AI.
+func adaptiveKey(nickname string) string {
+	return "#adaptive#" + nickname
+}
+
+// dataJobKey is the primary key of a player's data job.
+func dataJobKey(nickname string) string {
+	return "#dataJob#" + nickname
+}
+
+// dataJobsKey is the primary key of the nicknames whose data jobs are waiting to run.
+const dataJobsKey = "#dataJobs"
+
+// resultKey is the primary key of the result of a player's rated game.
+func resultKey(nickname, id string) string {
+	return "#result#" + nickname + "#" + id
+}
+
+// resultsKey is the primary key of the IDs of a player's results.
+func resultsKey(nickname string) string {
+	return "#results#" + nickname
+}
+
+// disputeKey is the primary key of a dispute.
+func disputeKey(id string) string {
+	return "#dispute#" + id
+}
+
+// disputesKey is the primary key of the IDs of the disputes waiting to be reviewed.
+const disputesKey = "#disputes"
+
+// webhookQueueKey is the primary key of the events waiting to be posted to a tournament's webhook.
+func webhookQueueKey(name string) string {
+	return "#webhookQueue#" + name
+}
+
+// webhooksKey is the primary key of the names of the tournaments whose events are waiting to be
+// posted.
+const webhooksKey = "#webhooks"
+
+// leaderboardKey is the primary key of the highest ratings.
+const leaderboardKey = "#leaderboard"
+
+// botRatingKey is the primary key of a bot's ladder rating.
+func botRatingKey(nickname string) string {
+	return "#botRating#" + nickname
+}
+
+// botLeaderboardKey is the primary key of the highest ladder ratings.
+const botLeaderboardKey = "#botLeaderboard"
+
+// ladderBotKey is the primary key of a bot's registration for the bot ladder.
+func ladderBotKey(nickname string) string {
+	return "#ladderBot#" + nickname
+}
+
+// maintenanceKey is the primary key of the maintenance mode flag.
+const maintenanceKey = "#maintenance"
+
+// aiCalibrationKey is the primary key of the calibrated AI levels.
+const aiCalibrationKey = "#aiCalibration"
+
+// aiStatsKey is the primary key of the results of solo games at a difficulty.
+func aiStatsKey(difficulty int) string {
+	return "#aiStats#" + strconv.Itoa(difficulty)
+}
+
+// replayKey is the primary key of one of a host's replays. The "#" prefix keeps it from colliding
+// with nicknames and connection IDs. Replays without an ID were saved when each host only had
+// their latest replay, under the host's name alone.
+func replayKey(host, id string) string {
+	if id == "" {
+		return "#replay#" + host
+	}
+	return "#replay#" + host + "#" + id
+}
+
+// replaysKey is the primary key of the IDs of a host's replays.
+func replaysKey(host string) string {
+	return "#replays#" + host
+}
+
+// kibitzKey is the primary key of the kibitz about a host's game in progress.
+func kibitzKey(host string) string {
+	return "#kibitz#" + host
+}
+
+// outboxKey returns the primary key of the item that counts the messages sent to the players of a
+// host's game.
+func outboxKey(host string) string {
+	return "#outbox#" + host
+}
+
+// outboundKey returns the primary key of a message sent to the players of a host's game.
+func outboundKey(host string, seq int) string {
+	return outboxKey(host) + "#" + strconv.Itoa(seq)
+}
+
+// getAILevels returns the calibrated AI levels, indexed by difficulty. It is empty if the AI has
+// never been calibrated.
+func getAILevels(ctx context.Context, args Args) ([]ai.Level, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(aiCalibrationKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct{ Levels []ai.Level }
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Levels, err
+}
+
+// updateAILevels saves the calibrated AI levels. Like the maintenance item, it does not expire.
+func updateAILevels(ctx context.Context, args Args, levels []ai.Level) error {
+	update := expression.Set(expression.Name(attribLevels), expression.Value(levels))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, aiCalibrationKey, builder, false)
+	return err
+}
+
+func getAIStats(ctx context.Context, args Args, difficulty int) (aiStats, error) {
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(aiStatsKey(difficulty)),
+	})
+	if err != nil {
+		return aiStats{}, err
+	}
+
+	var stats aiStats
+	err = dynamodbattribute.UnmarshalMap(output.Item, &stats)
+
+	return stats, err
+}
+
+// addAIStats adds to the results of solo games at a difficulty. Negative values subtract results
+// that have been used for calibration. The stats do not expire.
+func addAIStats(ctx context.Context, args Args, difficulty int, stats aiStats) error {
+	update := expression.
+		Add(expression.Name(attribGames), expression.Value(stats.Games)).
+		Add(expression.Name(attribHumanWins), expression.Value(stats.HumanWins))
+	builder := expression.NewBuilder().WithUpdate(update)
+	_, err := updateItemWithBuilder(ctx, args, aiStatsKey(difficulty), builder, false)
+	return err
+}
+
+// EnsureTable creates the DynamoDB table if it does not exist. It is useful in test environments.
+func EnsureTable(ctx context.Context, db dynamodbiface.DynamoDBAPI, name string) error {
+	_, err := db.CreateTableWithContext(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(name),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(attribHost), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String(attribOpponent), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String(attribTopic), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(attribHost), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(indexByOpponent),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String(attribOpponent), KeyType: aws.String(dynamodb.KeyTypeHash)},
+					{AttributeName: aws.String(attribHost), KeyType: aws.String(dynamodb.KeyTypeRange)},
+				},
+				Projection: &dynamodb.Projection{
+					ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly),
+				},
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(2),
+					WriteCapacityUnits: aws.Int64(2),
+				},
+			},
+			{
+				IndexName: aws.String(indexByTopic),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String(attribTopic), KeyType: aws.String(dynamodb.KeyTypeHash)},
+					{AttributeName: aws.String(attribHost), KeyType: aws.String(dynamodb.KeyTypeRange)},
+				},
+				Projection: &dynamodb.Projection{
+					ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+					NonKeyAttributes: aws.StringSlice([]string{attribConnectionID, attribNickname}),
+				},
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(2),
+					WriteCapacityUnits: aws.Int64(2),
+				},
+			},
+		},
+		BillingMode: aws.String(dynamodb.BillingModeProvisioned),
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(2),
+			WriteCapacityUnits: aws.Int64(2),
+		},
+	})
+
+	if err != nil && !strings.HasPrefix(err.Error(), "ResourceInUseException") {
+		return err
+	}
+
+	_, err = db.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(name),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(attribTTL),
+			Enabled:       aws.Bool(true),
+		},
+	})
+
+	if err != nil && !strings.Contains(err.Error(), "TimeToLive is already enabled") {
+		return err
+	}
+
+	return nil
+}
+
+func defaultDB() *dynamodb.DynamoDB {
+	return dynamodb.New(session.Must(session.NewSession(aws.NewConfig().
+		WithRegion(os.Getenv("AWS_REGION")))))
+}
+
+// LocalDBEndpoint is the endpoint of the DynamoDB Local instance started by docker-compose.
+const LocalDBEndpoint = "http://127.0.0.1:8042"
+
+func LocalDB() *dynamodb.DynamoDB {
+	return NewDB(LocalDBEndpoint)
+}
+
+// NewDB returns a DynamoDB client for the specified endpoint. If the endpoint is empty, the client
+// connects to AWS using the default region and credentials. Otherwise, it is assumed to be a
+// DynamoDB Local instance, which accepts any credentials.
+func NewDB(endpoint string) *dynamodb.DynamoDB {
+	if endpoint == "" {
+		return defaultDB()
+	}
+
 	return dynamodb.New(session.Must(session.NewSession(aws.NewConfig().
 		WithRegion("us-west-2").
-		WithEndpoint("http://127.0.0.1:8042").
+		WithEndpoint(endpoint).
 		WithCredentials(credentials.NewStaticCredentials("foo", "bar", "")))))
 }
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+// throttledDB processes only a few of the keys of each BatchGetItem request, as DynamoDB does when
+// it is throttling, and leaves the rest unprocessed.
+type throttledDB struct {
+	*memdb.DB
+	requests int
+}
+
+func (db *throttledDB) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	db.requests++
+
+	unprocessed := make(map[string]*dynamodb.KeysAndAttributes)
+	for table, keys := range input.RequestItems {
+		if len(keys.Keys) > 30 {
+			rest := *keys
+			rest.Keys = keys.Keys[30:]
+			unprocessed[table] = &rest
+
+			processed := *keys
+			processed.Keys = keys.Keys[:30]
+			input.RequestItems[table] = &processed
+		}
+	}
+
+	output, err := db.DB.BatchGetItemWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	output.UnprocessedKeys = unprocessed
+
+	return output, nil
+}
+
+func TestBatchGetItems(t *testing.T) {
+	ctx := context.Background()
+
+	db := &throttledDB{DB: memdb.New()}
+	args := Args{DB: db, TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, db, args.TableName))
+
+	var nicknames []string
+	for i := 0; i < 250; i++ {
+		nickname := fmt.Sprintf("player%03d", i)
+		nicknames = append(nicknames, nickname)
+		require.NoError(t, updateRating(ctx, args, rating{Nickname: nickname, Rating: 1200 + i, Games: 1}))
+	}
+
+	// The last player hasn't played a rated game.
+	ratings, err := getRatings(ctx, args, append(nicknames, "newcomer"))
+	require.NoError(t, err)
+
+	sort.Slice(ratings, func(i, j int) bool { return ratings[i].Nickname < ratings[j].Nickname })
+	require.Len(t, ratings, 250)
+	for i, r := range ratings {
+		assert.Equal(t, rating{Nickname: nicknames[i], Rating: 1200 + i, Games: 1}, r)
+	}
+
+	// 251 keys are read in batches of 100, 100, and 51 keys, which take 4, 4, and 2 requests of at
+	// most 30 keys.
+	assert.Equal(t, 10, db.requests)
+}
+
+func TestTimeOutGame(t *testing.T) {
+	ctx := context.Background()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	g := newGame(0)
+	g.applyPreset(presets["bullet"])
+	require.NoError(t, createGame(ctx, args, "flame", g, "zinger", "flame", "1"))
+
+	g, _, _, err := getGame(ctx, args, "flame")
+	require.NoError(t, err)
+
+	g.TimedOut = common.Player1
+
+	// Only the first request to notice the flag fall ends the game.
+	ok, err := timeOutGame(ctx, args, "flame", g)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = timeOutGame(ctx, args, "flame", g)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	saved, opponent, _, err := getGame(ctx, args, "flame")
+	require.NoError(t, err)
+	assert.Equal(t, phaseFinished, saved.phase(opponent))
+	assert.Equal(t, common.Player1, saved.TimedOut)
+
+	// A game that is gone isn't brought back.
+	ok, err = timeOutGame(ctx, args, "nobody", g)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSaveGameChanged(t *testing.T) {
+	ctx := context.Background()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	g := newGame(0)
+	g.Takebacks = true
+	require.NoError(t, createGame(ctx, args, "flame", g, "zinger", "flame", "1"))
+
+	// Two requests load the game, and the first to save it moves it to another phase.
+	first, _, _, err := getGame(ctx, args, "flame")
+	require.NoError(t, err)
+	second := first
+
+	first.UndoRequest = common.Player1
+	require.NoError(t, updateGame(ctx, args, "flame", first, "flame", "1"))
+
+	second.Moves = append(second.Moves, [2]int{2, 4})
+	assert.True(t, errors.Is(updateGame(ctx, args, "flame", second, "flame", "1"), errGameChanged))
+
+	// Once it is abandoned, nothing else is saved.
+	loaded, _, _, err := getGame(ctx, args, "flame")
+	require.NoError(t, err)
+	require.NoError(t, abandonGame(ctx, args, "flame", loaded))
+	assert.True(t, errors.Is(updateGame(ctx, args, "flame", loaded, "flame", "1"), errGameChanged))
+}
+
+func TestCorrespondenceGameOutlivesItemTTL(t *testing.T) {
+	ctx := context.Background()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	g := newGame(0)
+	g.applyPreset(presets["correspondence"])
+	g.startClock(time.Now())
+	require.NoError(t, createGame(ctx, args, "flame", g, "zinger", "flame", "1"))
+
+	ttl := func() int64 {
+		output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(args.TableName),
+			Key:       hostKey("flame"),
+		})
+		require.NoError(t, err)
+
+		ttl, err := strconv.ParseInt(aws.StringValue(output.Item[attribTTL].N), 10, 64)
+		require.NoError(t, err)
+		return ttl
+	}
+
+	assert.Greater(t, ttl(), time.Now().Add(24*time.Hour).Unix())
+
+	// A connected player doesn't cut the game's life short.
+	require.NoError(t, touchGame(ctx, args, "flame", time.Now()))
+	assert.Greater(t, ttl(), time.Now().Add(24*time.Hour).Unix())
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Disputing the results of rated games. A player who thinks that a rated game ended wrongly, such
+// as by losing on time during a server outage, can dispute it for as long as its result is kept.
+// The result keeps the game's messages as they were when it was rated, and the dispute keeps them
+// as the game's event log, and waits for an admin to review it with ListDisputes. ResolveDispute upholds the result, voids it, or gives
+// the game to the other player or a draw, undoing the rating changes that the result made. The
+// leaderboard is updated, but tournament standings are not.
+
+// Outcomes of ResolveDispute, besides the nickname of a player to give the game to.
+const (
+	DisputeUphold = "uphold"
+	DisputeVoid   = "void"
+	DisputeDraw   = "draw"
+)
+
+// Dispute describes a dispute that is waiting to be reviewed. Log is the game's event log, which
+// is the JSON of each message that was sent to its players.
+type Dispute struct {
+	ID       string
+	Nickname string
+	Reason   string
+	FiledAt  time.Time
+	Host     string
+	Opponent string
+	Winner   string
+	Ending   string
+	Log      []string
+}
+
+func (d Dispute) String() string {
+	winner := strings.ToUpper(d.Winner)
+	if d.Winner == "" {
+		winner = "nobody (draw)"
+	}
+
+	reason := d.Reason
+	if reason == "" {
+		reason = "(no reason given)"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s disputes %s vs. %s, won by %s because %s\n", d.ID, d.Nickname, d.Host, d.Opponent, winner, d.Ending)
+	fmt.Fprintf(&b, "  filed %s: %s\n", d.FiledAt.Format(time.RFC3339), reason)
+	for _, line := range d.Log {
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ListDisputes returns the disputes that are waiting to be reviewed.
+func ListDisputes(ctx context.Context, args Args) ([]Dispute, error) {
+	ids, err := getPending(ctx, args, disputesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var disputes []Dispute
+
+	for _, id := range ids {
+		d, ok, err := getDispute(ctx, args, id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		gameLog := make([]string, len(d.Log))
+		for i, message := range d.Log {
+			gameLog[i] = string(message)
+		}
+
+		disputes = append(disputes, Dispute{
+			ID:       d.ID,
+			Nickname: d.Nickname,
+			Reason:   d.Reason,
+			FiledAt:  d.FiledAt,
+			Host:     d.Result.Host,
+			Opponent: d.Result.Opponent,
+			Winner:   d.Result.Winner,
+			Ending:   d.Result.Reason,
+			Log:      gameLog,
+		})
+	}
+
+	return disputes, nil
+}
+
+// ResolveDispute settles a dispute with an outcome, which is DisputeUphold, DisputeVoid,
+// DisputeDraw, or the nickname of the player to give the game to.
+func ResolveDispute(ctx context.Context, args Args, id, outcome string) error {
+	d, ok, err := getDispute(ctx, args, id)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("no dispute %q", id)
+	}
+
+	result := d.Result
+
+	switch outcome {
+	case DisputeUphold:
+	case DisputeVoid, DisputeDraw, result.Host, result.Opponent:
+		if err := overturnResult(ctx, args, result, outcome); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown outcome %q", outcome)
+	}
+
+	log.Printf("Resolved dispute %q: %s", id, outcome)
+
+	if err := deleteItem(ctx, args, disputeKey(id)); err != nil {
+		return err
+	}
+
+	return removePending(ctx, args, disputesKey, id)
+}
+
+// overturnResult saves the players' ratings after overturning a game's result.
+func overturnResult(ctx context.Context, args Args, result gameResult, outcome string) error {
+	ratings, err := ratingsOf(ctx, args, []string{result.Host, result.Opponent})
+	if err != nil {
+		return err
+	}
+
+	current := overturn([2]rating{ratings[result.Host], ratings[result.Opponent]}, result, outcome)
+
+	// A player whose data was deleted isn't rated again.
+	var kept []rating
+	for _, r := range current {
+		if r.Nickname != deletedPlayer {
+			kept = append(kept, r)
+		}
+	}
+
+	return saveRatings(ctx, args, kept...)
+}
+
+// overturn returns the current ratings of a game's host and opponent without the rating changes of
+// the game's result. Unless the outcome is DisputeVoid, the players are rated again as if the game
+// had ended with the outcome. Games that they played since are not affected.
+func overturn(current [2]rating, result gameResult, outcome string) [2]rating {
+	for i := range current {
+		current[i].Rating -= result.After[i].Rating - result.Before[i].Rating
+		current[i].Games--
+	}
+
+	if outcome != DisputeVoid {
+		winner := outcome
+		if outcome == DisputeDraw {
+			winner = ""
+		}
+
+		hostRating, opponentRating := rate(result.Before[0], result.Before[1], winner)
+		for i, r := range []rating{hostRating, opponentRating} {
+			current[i].Rating += r.Rating - result.Before[i].Rating
+			current[i].Games++
+		}
+	}
+
+	return current
+}
+
+// without returns the dispute with deletedPlayer in place of a player's name, for when the player's
+// data is deleted while the dispute of one of their games waits to be reviewed. Its ID changes too,
+// since the ID has the host's name.
+func (d dispute) without(nickname string) dispute {
+	rename := func(name *string) {
+		if *name == nickname {
+			*name = deletedPlayer
+		}
+	}
+
+	rename(&d.Result.Host)
+	rename(&d.Result.Opponent)
+	rename(&d.Result.Winner)
+	for i := range d.Result.Before {
+		rename(&d.Result.Before[i].Nickname)
+		rename(&d.Result.After[i].Nickname)
+	}
+	d.ID = disputeID(d.Result)
+
+	// Nicknames are strings of their own in the game's messages.
+	name, _ := json.Marshal(nickname)
+	replacement, _ := json.Marshal(deletedPlayer)
+
+	gameLog := make([]json.RawMessage, len(d.Log))
+	for i, message := range d.Log {
+		gameLog[i] = bytes.ReplaceAll(message, name, replacement)
+	}
+	d.Log = gameLog
+
+	return d
+}
+
+// resultMessage returns the message form of a game's result.
+func resultMessage(result gameResult) messages.RatedResult {
+	return messages.RatedResult{
+		Host:          result.Host,
+		Opponent:      result.Opponent,
+		Winner:        result.Winner,
+		Reason:        result.Reason,
+		EndedAt:       result.EndedAt.Unix(),
+		RatingsBefore: [2]int{result.Before[0].Rating, result.Before[1].Rating},
+		RatingsAfter:  [2]int{result.After[0].Rating, result.After[1].Rating},
+	}
+}
+
+// resultID identifies a player's result by when the game ended, in seconds since the Unix epoch,
+// like RatedResult.EndedAt.
+func resultID(result gameResult) string {
+	return strconv.FormatInt(result.EndedAt.Unix(), 10)
+}
+
+// keptResults returns the results of a player's rated games that can still be disputed, oldest
+// first.
+func keptResults(ctx context.Context, args Args, nickname string, now time.Time) ([]gameResult, error) {
+	results, err := getResults(ctx, args, nickname, now)
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].EndedAt.Before(results[j].EndedAt)
+	})
+
+	return results, err
+}
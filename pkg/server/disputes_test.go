@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+func TestOverturn(t *testing.T) {
+	// Andy beat Bob from even ratings, and then both played another game.
+	result := gameResult{
+		Host:     "andy",
+		Opponent: "bob",
+		Winner:   "andy",
+		Before:   [2]rating{{Nickname: "andy", Rating: 1200, Games: 3}, {Nickname: "bob", Rating: 1200, Games: 5}},
+		After:    [2]rating{{Nickname: "andy", Rating: 1216, Games: 4}, {Nickname: "bob", Rating: 1184, Games: 6}},
+	}
+	current := [2]rating{{Nickname: "andy", Rating: 1220, Games: 5}, {Nickname: "bob", Rating: 1180, Games: 7}}
+
+	tests := []struct {
+		outcome string
+		want    [2]rating
+	}{
+		{outcome: DisputeVoid, want: [2]rating{{Nickname: "andy", Rating: 1204, Games: 4}, {Nickname: "bob", Rating: 1196, Games: 6}}},
+		{outcome: DisputeDraw, want: [2]rating{{Nickname: "andy", Rating: 1204, Games: 5}, {Nickname: "bob", Rating: 1196, Games: 7}}},
+		{outcome: "bob", want: [2]rating{{Nickname: "andy", Rating: 1188, Games: 5}, {Nickname: "bob", Rating: 1212, Games: 7}}},
+		{outcome: "andy", want: current},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.outcome, func(t *testing.T) {
+			assert.Equal(t, tt.want, overturn(current, result, tt.outcome))
+		})
+	}
+}
+
+func TestDisputeWithout(t *testing.T) {
+	d := dispute{
+		ID:       "andy-1600000000",
+		Nickname: "bob",
+		Result: gameResult{
+			Host:     "andy",
+			Opponent: "bob",
+			Winner:   "andy",
+			EndedAt:  time.Unix(1600000000, 0),
+			Before:   [2]rating{{Nickname: "andy"}, {Nickname: "bob"}},
+			After:    [2]rating{{Nickname: "andy"}, {Nickname: "bob"}},
+		},
+		Log: []json.RawMessage{[]byte(`{"action":"joined","nickname":"andy","text":"andy and bob"}`)},
+	}
+
+	got := d.without("andy")
+
+	assert.Equal(t, "(deleted)-1600000000", got.ID)
+	assert.Equal(t, "bob", got.Nickname)
+	assert.Equal(t, "(deleted)", got.Result.Host)
+	assert.Equal(t, "bob", got.Result.Opponent)
+	assert.Equal(t, "(deleted)", got.Result.Winner)
+	assert.Equal(t, [2]rating{{Nickname: "(deleted)"}, {Nickname: "bob"}}, got.Result.Before)
+	assert.Equal(t, [2]rating{{Nickname: "(deleted)"}, {Nickname: "bob"}}, got.Result.After)
+	assert.JSONEq(t, `{"action":"joined","nickname":"(deleted)","text":"andy and bob"}`, string(got.Log[0]))
+
+	// The original is left alone.
+	assert.Equal(t, "andy", d.Result.Before[0].Nickname)
+	assert.Contains(t, string(d.Log[0]), `"andy"`)
+}
+
+func TestKeptResults(t *testing.T) {
+	ctx := context.Background()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	first := gameResult{Host: "andy", Opponent: "bob", Winner: "andy", EndedAt: time.Unix(1600000000, 0).UTC()}
+	second := gameResult{Host: "bob", Opponent: "andy", Winner: "andy", EndedAt: time.Unix(1600000600, 0).UTC()}
+	firstLog := []json.RawMessage{[]byte(`{"action":"joined"}`)}
+
+	// A later game doesn't replace the result of an earlier one.
+	require.NoError(t, putResult(ctx, args, "bob", second, nil))
+	require.NoError(t, putResult(ctx, args, "bob", first, firstLog))
+
+	results, err := keptResults(ctx, args, "bob", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []gameResult{first, second}, results)
+
+	result, gameLog, ok, err := getResult(ctx, args, "bob", resultID(first), time.Now())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, first, result)
+	assert.Equal(t, firstLog, gameLog)
+
+	// Results can be disputed for days, but not forever.
+	_, _, ok, err = getResult(ctx, args, "bob", resultID(first), time.Now().Add(resultTTL-time.Hour))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	results, err = keptResults(ctx, args, "bob", time.Now().Add(resultTTL+time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	require.NoError(t, deleteResults(ctx, args, "bob"))
+	_, _, ok, err = getResult(ctx, args, "bob", resultID(first), time.Now())
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Every game is in one of a few phases, which decide what players may do in it. Requests that the
+// phase doesn't accept, such as moves after the game is over or joins into a game that already has
+// an opponent, are refused in one place by refuse. The phase is saved with the game, which is only
+// saved if it is still in the phase that it was loaded in and if it went from there in a way that
+// phaseChanges allows. A request that loses that race is refused like the others.
+//
+// A game is paused while a player whose connection closed has reconnectGrace to resume it, and
+// the clock of their opponent, who can't move, stops. A game that a player leaves or drops out of
+// while it is underway is abandoned, and deleted once that is saved. Passes don't have a phase of
+// their own, since the server passes for a player who can't move. Neither does a pause for
+// maintenance, which pauses every game at once rather than one of them.
+
+type phase string
+
+const (
+	phaseAwaitingOpponent phase = "awaitingOpponent"
+	phaseInProgress       phase = "inProgress"
+	phaseAwaitingTakeback phase = "awaitingTakeback"
+	phasePaused           phase = "paused"
+	phaseFinished         phase = "finished"
+	phaseAbandoned        phase = "abandoned"
+)
+
+type gameEvent string
+
+const (
+	eventJoin            gameEvent = "join"
+	eventRejoin          gameEvent = "rejoin"
+	eventMove            gameEvent = "move"
+	eventRequestTakeback gameEvent = "requestTakeback"
+	eventRespondTakeback gameEvent = "respondTakeback"
+	eventTimeOut         gameEvent = "timeOut"
+	eventLeave           gameEvent = "leave"
+)
+
+// phaseEvents is the events that each phase accepts.
+var phaseEvents = map[phase][]gameEvent{
+	phaseAwaitingOpponent: {eventJoin, eventLeave},
+	phaseInProgress:       {eventRejoin, eventMove, eventRequestTakeback, eventTimeOut, eventLeave},
+	phaseAwaitingTakeback: {eventRejoin, eventMove, eventRespondTakeback, eventTimeOut, eventLeave},
+	phasePaused:           {eventRejoin, eventTimeOut, eventLeave},
+	phaseFinished:         {eventRejoin, eventLeave},
+	phaseAbandoned:        {eventLeave},
+}
+
+// phaseChanges is the phases that a game may go to from each phase, other than staying put.
+var phaseChanges = map[phase][]phase{
+	phaseAwaitingOpponent: {phaseInProgress},
+	phaseInProgress:       {phaseAwaitingTakeback, phasePaused, phaseFinished, phaseAbandoned},
+	phaseAwaitingTakeback: {phaseInProgress, phasePaused, phaseFinished, phaseAbandoned},
+	phasePaused:           {phaseInProgress, phaseAwaitingTakeback, phaseFinished, phaseAbandoned},
+}
+
+func (p phase) accepts(event gameEvent) bool {
+	for _, e := range phaseEvents[p] {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (p phase) canChangeTo(next phase) bool {
+	if next == p {
+		return true
+	}
+	for _, to := range phaseChanges[p] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// underway returns whether a game in the phase has started and not yet finished, so that a player
+// who leaves it abandons it.
+func (p phase) underway() bool {
+	return p == phaseInProgress || p == phaseAwaitingTakeback || p == phasePaused
+}
+
+// refusal explains to a player why the phase doesn't accept an event.
+func (p phase) refusal(event gameEvent) string {
+	switch {
+	case p == phaseFinished:
+		return "the game is over"
+	case p == phaseAbandoned:
+		return "the game was abandoned"
+	case p == phasePaused:
+		return "the game is paused until your opponent reconnects"
+	case p == phaseAwaitingOpponent:
+		return "the game hasn't started"
+	case event == eventJoin:
+		return "the game already has an opponent"
+	case event == eventRequestTakeback:
+		return "a takeback is already waiting for an answer"
+	case event == eventRespondTakeback:
+		return "there is no takeback to respond to"
+	default:
+		return fmt.Sprintf("can't %s now", event)
+	}
+}
+
+// phase returns the phase that a game is in. Games saved before phases were saved have theirs
+// worked out from the rest of the game.
+func (g *game) phase(opponent string) phase {
+	if g.Phase != "" {
+		return g.Phase
+	}
+	if opponent == waiting {
+		return phaseAwaitingOpponent
+	}
+	return g.settle()
+}
+
+// settle returns the phase that a game with an opponent is in, going by its board, clock, players,
+// and takeback request.
+func (g *game) settle() phase {
+	switch {
+	case common.GameOver(g.Board) || g.TimedOut != 0:
+		return phaseFinished
+	case g.Abandoned:
+		return phaseAbandoned
+	case !g.PausedAt.IsZero():
+		return phasePaused
+	case g.UndoRequest != 0:
+		return phaseAwaitingTakeback
+	default:
+		return phaseInProgress
+	}
+}
+
+// advance moves a game that was changed to the phase that it is now in. It fails if the game's
+// phase can't change that way, which would mean that a request was let through that shouldn't
+// have been.
+func (g *game) advance() error {
+	next := g.settle()
+
+	if g.Phase != "" && !g.Phase.canChangeTo(next) {
+		return fmt.Errorf("game can't go from %s to %s", g.Phase, next)
+	}
+
+	g.Phase = next
+
+	return nil
+}
+
+// pause pauses a game for a player whose connection closed, unless it is already paused.
+func (g *game) pause(player common.Disk, now time.Time) {
+	if !g.PausedAt.IsZero() {
+		return
+	}
+
+	g.PausedAt = now
+	g.PausedFor = player
+}
+
+// resume ends a pause, and gives back the time that the stopped clock was stopped for.
+func (g *game) resume(now time.Time) {
+	if g.clockStopped() {
+		pausedFrom := g.PausedAt
+		if g.TurnStartedAt.After(pausedFrom) {
+			pausedFrom = g.TurnStartedAt
+		}
+
+		if now.After(pausedFrom) {
+			g.TurnStartedAt = g.TurnStartedAt.Add(now.Sub(pausedFrom))
+		}
+	}
+
+	g.PausedAt = time.Time{}
+	g.PausedFor = 0
+}
+
+// refuse replies with an error and returns true if a game's phase doesn't accept an event.
+func refuse(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host, opponent string, game game, event gameEvent) (bool, error) {
+	p := game.phase(opponent)
+	if p.accepts(event) {
+		return false, nil
+	}
+
+	log.Printf("Refusing %s in user %q's game, which is %s", event, host, p)
+
+	return true, reply(ctx, reqCtx, args, messages.Error{Error: p.refusal(event), Code: messages.ErrorWrongPhase})
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestGamePhase(t *testing.T) {
+	g := newGame(0)
+
+	// Games saved before phases were saved have theirs worked out.
+	assert.Equal(t, phaseAwaitingOpponent, g.phase(waiting))
+	assert.Equal(t, phaseInProgress, g.phase("zinger"))
+	assert.Equal(t, phaseInProgress, g.phase(""))
+
+	g.UndoRequest = common.Player1
+	assert.Equal(t, phaseAwaitingTakeback, g.phase("zinger"))
+
+	g.PausedAt = time.Unix(1000, 0)
+	assert.Equal(t, phasePaused, g.phase("zinger"))
+
+	g.Abandoned = true
+	assert.Equal(t, phaseAbandoned, g.phase("zinger"))
+
+	g.TimedOut = common.Player2
+	assert.Equal(t, phaseFinished, g.phase("zinger"))
+
+	// A saved phase is used as it is.
+	g.Phase = phaseAwaitingOpponent
+	assert.Equal(t, phaseAwaitingOpponent, g.phase("zinger"))
+}
+
+func TestGamePhaseAccepts(t *testing.T) {
+	assert.True(t, phaseAwaitingOpponent.accepts(eventJoin))
+	assert.False(t, phaseAwaitingOpponent.accepts(eventMove))
+	assert.False(t, phaseInProgress.accepts(eventJoin))
+	assert.True(t, phaseInProgress.accepts(eventRejoin))
+	assert.False(t, phaseInProgress.accepts(eventRespondTakeback))
+	assert.False(t, phaseAwaitingTakeback.accepts(eventRequestTakeback))
+	assert.True(t, phaseAwaitingTakeback.accepts(eventMove))
+	assert.False(t, phaseFinished.accepts(eventMove))
+	assert.False(t, phaseFinished.accepts(eventTimeOut))
+	assert.True(t, phaseFinished.accepts(eventLeave))
+	assert.False(t, phasePaused.accepts(eventMove))
+	assert.True(t, phasePaused.accepts(eventRejoin))
+	assert.True(t, phasePaused.accepts(eventTimeOut))
+	assert.False(t, phaseAbandoned.accepts(eventMove))
+	assert.True(t, phaseAbandoned.accepts(eventLeave))
+
+	assert.Equal(t, "the game is over", phaseFinished.refusal(eventMove))
+	assert.Equal(t, "the game already has an opponent", phaseInProgress.refusal(eventJoin))
+}
+
+func TestGamePhaseAdvance(t *testing.T) {
+	g := newGame(0)
+	g.Phase = phaseAwaitingOpponent
+
+	assert.NoError(t, g.advance())
+	assert.Equal(t, phaseInProgress, g.Phase)
+
+	g.UndoRequest = common.Player1
+	assert.NoError(t, g.advance())
+	assert.Equal(t, phaseAwaitingTakeback, g.Phase)
+
+	g.pause(common.Player2, time.Unix(1000, 0))
+	assert.NoError(t, g.advance())
+	assert.Equal(t, phasePaused, g.Phase)
+
+	g.resume(time.Unix(1010, 0))
+	assert.NoError(t, g.advance())
+	assert.Equal(t, phaseAwaitingTakeback, g.Phase)
+
+	g.UndoRequest = 0
+	g.TimedOut = common.Player1
+	assert.NoError(t, g.advance())
+	assert.Equal(t, phaseFinished, g.Phase)
+
+	// A finished game can't be brought back.
+	g.TimedOut = 0
+	assert.Error(t, g.advance())
+	assert.Equal(t, phaseFinished, g.Phase)
+}
+
+func TestGamePhaseAbandon(t *testing.T) {
+	g := newGame(0)
+	g.Phase = phaseInProgress
+
+	g.Abandoned = true
+	assert.NoError(t, g.advance())
+	assert.Equal(t, phaseAbandoned, g.Phase)
+
+	// An abandoned game is over for good.
+	g.Abandoned = false
+	assert.Error(t, g.advance())
+
+	// A game that hasn't started can't be abandoned, only left.
+	g = newGame(0)
+	g.Phase = phaseAwaitingOpponent
+	g.Abandoned = true
+	assert.Error(t, g.advance())
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"sync"
@@ -31,7 +30,7 @@ type GatewayAdapter struct {
 	upgrader websocket.Upgrader
 
 	writersMu sync.Mutex
-	writers   map[string]io.Writer
+	writers   map[string]*wsTextWriter
 }
 
 // ServeHTTP upgrades the request from HTTP to WS and then continues to send and receive websocket
@@ -66,12 +65,14 @@ func (a *GatewayAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Register a hook for writing back to the connection, indexed by its connection ID.
+	// Register a hook for writing back to the connection, indexed by its connection ID. Every write
+	// to the connection goes through it.
+	writer := &wsTextWriter{ws: ws}
 	a.writersMu.Lock()
 	if a.writers == nil {
-		a.writers = make(map[string]io.Writer)
+		a.writers = make(map[string]*wsTextWriter)
 	}
-	a.writers[connID] = &wsTextWriter{ws: ws}
+	a.writers[connID] = writer
 	a.writersMu.Unlock()
 
 	defer func() {
@@ -102,7 +103,7 @@ func (a *GatewayAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		if err := json.Unmarshal(message, &messageAction); err != nil {
 			log.Println("unmarshal:", err)
-			if err := writeError(ws); err != nil {
+			if err := writeError(writer); err != nil {
 				log.Println("write:", err)
 				break
 			}
@@ -112,7 +113,7 @@ func (a *GatewayAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Invoke the Lambda handler
 		if err := a.invokeHandler(connID, "MESSAGE", string(message), r.Header); err != nil {
 			log.Println("handler:", err)
-			if err := writeError(ws); err != nil {
+			if err := writeError(writer); err != nil {
 				log.Println("write:", err)
 				break
 			}
@@ -144,12 +145,13 @@ func (a *GatewayAdapter) invokeHandler(connID, eventType, body string, header ht
 	return nil
 }
 
-func writeError(ws *websocket.Conn) error {
-	return ws.WriteMessage(websocket.TextMessage, []byte(`{"message": "Internal server error"}`))
+func writeError(w *wsTextWriter) error {
+	_, err := w.Write([]byte(`{"message": "Internal server error"}`))
+	return err
 }
 
 func (a *GatewayAdapter) PostToConnectionWithContext(_ aws.Context, input *apigatewaymanagementapi.PostToConnectionInput, _ ...request.Option) (*apigatewaymanagementapi.PostToConnectionOutput, error) {
-	var writer io.Writer
+	var writer *wsTextWriter
 
 	a.writersMu.Lock()
 	if a.writers != nil {
@@ -165,10 +167,34 @@ func (a *GatewayAdapter) PostToConnectionWithContext(_ aws.Context, input *apiga
 	return &apigatewaymanagementapi.PostToConnectionOutput{}, err
 }
 
+// wsTextWriter writes text messages to a websocket. Handlers of several connections, broadcasts,
+// and background jobs may post to the same connection at once, but a websocket allows only one
+// writer at a time, so writes are serialized.
 type wsTextWriter struct {
 	ws *websocket.Conn
+	mu sync.Mutex
 }
 
 func (w *wsTextWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	return len(p), w.ws.WriteMessage(websocket.TextMessage, p)
 }
+
+func (a *GatewayAdapter) DeleteConnectionWithContext(_ aws.Context, input *apigatewaymanagementapi.DeleteConnectionInput, _ ...request.Option) (*apigatewaymanagementapi.DeleteConnectionOutput, error) {
+	var writer *wsTextWriter
+
+	a.writersMu.Lock()
+	if a.writers != nil {
+		writer = a.writers[*input.ConnectionId]
+	}
+	a.writersMu.Unlock()
+
+	if writer == nil {
+		return nil, &apigatewaymanagementapi.GoneException{}
+	}
+
+	// Closing the websocket ends the read loop, which invokes the DISCONNECT handler.
+	return &apigatewaymanagementapi.DeleteConnectionOutput{}, writer.ws.Close()
+}
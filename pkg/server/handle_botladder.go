@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to the bot ladder, which RunBotLadder runs. Waiting bots send
+// JoinBotLadder again from time to time, because subscriptions expire like other items.
+
+func handleJoinBotLadder(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.JoinBotLadder) error {
+	_, claimed, err := getClaim(ctx, args, message.Nickname, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "claim your nickname before joining the bot ladder"})
+	}
+
+	registered, err := updateLadderBotConnection(ctx, args, ladderBot{
+		Nickname:     message.Nickname,
+		ConnectionID: req.RequestContext.ConnectionID,
+		DomainName:   req.RequestContext.DomainName,
+		Stage:        req.RequestContext.Stage,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !registered {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("%s is not registered for the bot ladder", strings.ToUpper(message.Nickname))})
+	}
+
+	// A bot that was paired while it joined again is already playing.
+	_, inGame, err := getInGame(ctx, args, req.RequestContext.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	if inGame != "" {
+		game, opponent, _, err := getGame(ctx, args, inGame)
+		if err != nil && !errors.Is(err, errNoGame) {
+			return err
+		}
+		if err == nil && game.Ladder && game.phase(opponent) != phaseFinished {
+			return nil
+		}
+	}
+
+	log.Printf("Bot %q is waiting on the ladder", message.Nickname)
+
+	// Joining the ladder leaves the bot's last game, like hosting a new one does.
+	prevNickname, prevInGame, err := updateInGame(ctx, args, req.RequestContext.ConnectionID, message.Nickname, "")
+	if err != nil {
+		return err
+	}
+
+	if prevInGame != "" {
+		err := handleLeaveGame(ctx, req, args, &messages.LeaveGame{
+			Nickname: prevNickname,
+			Host:     prevInGame,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := subscribe(ctx, args, botLadderTopic, req.RequestContext.ConnectionID, message.Nickname); err != nil {
+		return err
+	}
+
+	ratings, err := botRatingsOf(ctx, args, []string{message.Nickname})
+	if err != nil {
+		return err
+	}
+
+	own := ratings[message.Nickname]
+
+	return reply(ctx, req.RequestContext, args, messages.BotLadderJoined{Rating: own.Rating, Games: own.Games})
+}
+
+// handleGetBotLeaderboard replies with the highest ladder ratings. Registered bots are there to be
+// ranked, so privacy settings don't hide them.
+func handleGetBotLeaderboard(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
+	leaderboard, err := getBotLeaderboard(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]messages.LeaderboardEntry, len(leaderboard))
+	for i, r := range leaderboard {
+		entries[i] = messages.LeaderboardEntry{Nickname: r.Nickname, Rating: r.Rating, Games: r.Games}
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.Leaderboard{Bots: true, Entries: entries})
+}
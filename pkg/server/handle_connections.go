@@ -2,7 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 
@@ -14,21 +19,179 @@ import (
 func handleHello(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.Hello) error {
 	log.Printf("client version: %s", message.Version)
 
+	if !clientSupported(message.Version) {
+		return reply(ctx, req.RequestContext, args, messages.Error{
+			Error: upgradeRequiredMessage(message.Version),
+			Code:  messages.ErrorUpgradeRequired,
+		})
+	}
+
+	protocol := protocolOf(message.Version)
+
+	args.metrics().Count("Hellos", map[string]string{
+		"Protocol":      strconv.Itoa(protocol),
+		"ClientVersion": message.Version,
+	})
+
+	if err := updateHello(ctx, args, req.RequestContext.ConnectionID, protocol, message.Lite, message.BoardEncoding); err != nil {
+		return err
+	}
+
+	if message.Nickname != "" {
+		if err := authenticate(ctx, req, args, message.Nickname, message.Token); err != nil {
+			return err
+		}
+	}
+
+	maintenance, err := getMaintenance(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if err := reply(ctx, req.RequestContext, args, messages.HelloAck{Version: Version, Features: features, Maintenance: maintenance.notice(), Branding: args.branding()}); err != nil {
+		return err
+	}
+
+	// Decorations are only for show, so they aren't worth sending over a slow connection.
+	if message.Lite {
+		return nil
+	}
+
 	return reply(ctx, req.RequestContext, args, messages.Decorate{Decoration: "🎁🔔🔴🎄🧦🦌🌟🎅🍪"})
 }
 
-func handleDisconnect(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
-	nickname, inGame, err := getInGame(ctx, args, req.RequestContext.ConnectionID)
+// staleAfter is how long a connection can go without a ping before it is treated as disconnected.
+// Clients ping every minute.
+const staleAfter = 3 * time.Minute
+
+func handlePing(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, _ *messages.Ping) error {
+	now := time.Now()
+
+	nickname, inGame, err := touchConnection(ctx, args, req.RequestContext.ConnectionID, now)
 	if err != nil {
 		return err
 	}
 
+	maintenance, err := getMaintenance(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if err := reply(ctx, req.RequestContext, args, messages.Pong{Maintenance: maintenance.notice(), ServerTime: millis(now)}); err != nil {
+		return err
+	}
+
 	if inGame == "" {
 		return nil
 	}
 
-	return handleLeaveGame(ctx, req, args, &messages.LeaveGame{
-		Nickname: nickname,
-		Host:     inGame,
-	})
+	if err := touchGame(ctx, args, inGame, now); err != nil {
+		return err
+	}
+
+	return endGameIfOpponentStale(ctx, req, args, nickname, inGame, now)
+}
+
+// endGameIfOpponentStale ends a game if the other player's connection has stopped pinging, which
+// happens when a connection is dropped without a disconnect event. A game whose player to move ran
+// out of time is ended on time instead.
+func endGameIfOpponentStale(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, nickname, host string, now time.Time) error {
+	game, gameOpponent, connections, err := getGame(ctx, args, host)
+	if err != nil {
+		return err
+	}
+
+	if ended, err := endGameIfOutOfTime(ctx, req.RequestContext, args, host, game, gameOpponent, connections, now); err != nil || ended {
+		return err
+	}
+
+	// Players of correspondence games may be away for days between moves, and only lose on time.
+	if game.Clock.PerMove {
+		return nil
+	}
+
+	for opponent, connID := range connections {
+		if opponent == nickname {
+			continue
+		}
+
+		conn, ok, err := getConnection(ctx, args, connID)
+		if err != nil {
+			return err
+		}
+
+		if ok && !conn.stale(now) {
+			continue
+		}
+
+		log.Printf("User %q's connection is stale, ending user %q's game", opponent, host)
+
+		if game.phase(gameOpponent).underway() {
+			if err := abandonGame(ctx, args, host, game); err != nil && !errors.Is(err, errGameChanged) {
+				return err
+			}
+		}
+
+		game, gameOpponent, connections, err := deleteGameGetConnections(ctx, args, host, nickname, req.RequestContext.ConnectionID)
+		if err != nil {
+			return err
+		}
+
+		// Only the stale connection is forgotten. The player who is still connected keeps theirs,
+		// along with what it said in Hello.
+		for player, connID := range connections {
+			remove := removeInGame
+			if player == opponent {
+				remove = deleteItem
+			}
+			if err := remove(ctx, args, connID); err != nil {
+				return err
+			}
+		}
+
+		reason := fmt.Sprintf("%s disconnected", strings.ToUpper(opponent))
+
+		endForSpectators(ctx, req.RequestContext, args, host, reason)
+
+		guest := opponent
+		if host == opponent {
+			guest = nickname
+		}
+		recordTournamentResult(ctx, req.RequestContext, args, host, guest, nickname, true)
+
+		// Dropping out of a game that is underway loses it, like leaving it does.
+		if p := game.phase(gameOpponent); p.underway() || p == phaseAbandoned {
+			if len(game.Moves) > 0 {
+				if err := saveReplay(ctx, args, host, gameOpponent, game, reason); err != nil {
+					return err
+				}
+			}
+
+			recordRating(ctx, args, host, gameOpponent, nickname, reason, game.Ladder)
+		}
+
+		if err := reply(ctx, req.RequestContext, args, messages.GameOver{Message: reason}); err != nil {
+			return err
+		}
+
+		return setPlaying(ctx, req.RequestContext, args, false, nickname, opponent)
+	}
+
+	return nil
+}
+
+// stale returns whether a connection has gone long enough without a sign of life that its game
+// should end. A closed connection is stale after reconnectGrace, and an open one after staleAfter
+// without a ping. Connections that have never pinged may belong to older clients, so they are not
+// stale while they are open.
+func (c connection) stale(now time.Time) bool {
+	if c.DisconnectedAt != 0 {
+		return now.Sub(time.Unix(c.DisconnectedAt, 0)) >= reconnectGrace
+	}
+
+	return c.LastSeen != 0 && now.Sub(time.Unix(c.LastSeen, 0)) >= staleAfter
+}
+
+func handleDisconnect(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
+	return cleanUpConnection(ctx, req, args, time.Now())
 }
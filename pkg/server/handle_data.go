@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to players' requests to export or delete their data, which are
+// carried out by RunDataJobs.
+
+func handleExportData(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.ExportData) error {
+	return queueDataJob(ctx, req, args, message.Nickname, messages.DataJobExport)
+}
+
+func handleDeleteData(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.DeleteData) error {
+	return queueDataJob(ctx, req, args, message.Nickname, messages.DataJobDelete)
+}
+
+// queueDataJob queues a data job for a player, replacing any job of theirs that hasn't run yet.
+func queueDataJob(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, nickname, kind string) error {
+	now := time.Now()
+
+	tokenHash, claimed, err := getClaim(ctx, args, nickname, now)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("claim your nickname before asking to %s your data", kind)})
+	}
+
+	log.Printf("User %q asked to %s their data", nickname, kind)
+
+	err = putDataJob(ctx, args, dataJob{
+		Nickname:     nickname,
+		Kind:         kind,
+		ConnectionID: req.RequestContext.ConnectionID,
+		DomainName:   req.RequestContext.DomainName,
+		Stage:        req.RequestContext.Stage,
+		TokenHash:    tokenHash,
+	}, now)
+	if err != nil {
+		return err
+	}
+
+	if err := addPending(ctx, args, dataJobsKey, nickname); err != nil {
+		return err
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.DataJobQueued{Nickname: nickname, Kind: kind})
+}
+
+// deliverDataJob sends a player the result of their data job if it is done and waiting for them,
+// now that they have said hello with their token. It returns whether their data was deleted, in
+// which case the token no longer claims the nickname.
+func deliverDataJob(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, nickname, token string) (bool, error) {
+	job, ok, err := getDataJob(ctx, args, nickname)
+	if err != nil || !ok || !job.Done {
+		return false, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(job.TokenHash), []byte(hashToken(token))) != 1 {
+		return false, nil
+	}
+
+	message, err := dataJobDoneMessage(job)
+	if err != nil {
+		return false, err
+	}
+
+	if err := reply(ctx, req.RequestContext, args, message); err != nil {
+		return false, err
+	}
+
+	return job.Kind == messages.DataJobDelete, deleteItem(ctx, args, dataJobKey(nickname))
+}
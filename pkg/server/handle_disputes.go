@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to disputing the results of rated games, which admins review
+// with ListDisputes and ResolveDispute.
+
+func handleDisputeResult(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.DisputeResult) error {
+	now := time.Now()
+
+	_, claimed, err := getClaim(ctx, args, message.Nickname, now)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "claim your nickname before disputing a result"})
+	}
+
+	id := strconv.FormatInt(message.EndedAt, 10)
+	if message.EndedAt == 0 {
+		results, err := keptResults(ctx, args, message.Nickname, now)
+		if err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			return reply(ctx, req.RequestContext, args, messages.Error{Error: "you have no recent rated game to dispute"})
+		}
+
+		id = resultID(results[len(results)-1])
+	}
+
+	result, gameLog, ok, err := getResult(ctx, args, message.Nickname, id, now)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "you have no recent rated game that ended then"})
+	}
+
+	d := dispute{
+		ID:       disputeID(result),
+		Nickname: message.Nickname,
+		Reason:   message.Reason,
+		FiledAt:  now,
+		Result:   result,
+		Log:      gameLog,
+	}
+
+	created, err := createDispute(ctx, args, d, now)
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "the result is already disputed"})
+	}
+
+	if err := addPending(ctx, args, disputesKey, d.ID); err != nil {
+		return err
+	}
+
+	log.Printf("User %q disputed the result of user %q's game", message.Nickname, result.Host)
+
+	return reply(ctx, req.RequestContext, args, messages.DisputeFiled{ID: d.ID})
+}
+
+// disputeID identifies the dispute of a game's result. Both players' disputes of the same game
+// have the same ID, so a game is only disputed once.
+func disputeID(result gameResult) string {
+	return fmt.Sprintf("%s-%d", result.Host, result.EndedAt.Unix())
+}
+
+// gameLog returns the messages that are still kept of the ones sent to the players of a host's
+// games, in order.
+func gameLog(ctx context.Context, args Args, host string) ([]json.RawMessage, error) {
+	last, err := getSeq(ctx, args, host)
+	if err != nil {
+		return nil, err
+	}
+
+	first := last - outboxSize + 1
+	if first < 1 {
+		first = 1
+	}
+
+	outbox, err := getOutbound(ctx, args, host, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(outbox, func(i, j int) bool {
+		return outbox[i].Seq < outbox[j].Seq
+	})
+
+	gameLog := make([]json.RawMessage, len(outbox))
+	for i, o := range outbox {
+		gameLog[i] = o.Message
+	}
+
+	return gameLog, nil
+}
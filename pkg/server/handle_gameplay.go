@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/armsnyder/othelgo/pkg/ai"
 	"github.com/armsnyder/othelgo/pkg/common"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -17,50 +19,60 @@ import (
 
 // Handlers for messages pertaining to gameplay.
 
+// endingGameOver is why a game ended when it was played to the end.
+const endingGameOver = "the board is full or nobody can move"
+
 func handlePlaceDisk(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.PlaceDisk) error {
 	game, opponent, connections, err := getGame(ctx, args, message.Host)
 	if err != nil {
 		return fmt.Errorf("failed to load game state: %w", err)
 	}
 
-	authorized := false
-	for k, v := range connections {
-		if k == message.Nickname && v == req.RequestContext.ConnectionID {
-			authorized = true
-			break
-		}
+	if !isAuthorized(connections, message.Nickname, req.RequestContext.ConnectionID) {
+		return errUnauthorized
 	}
 
-	if !authorized {
-		return errors.New("unauthorized")
+	if refused, err := refuse(ctx, req.RequestContext, args, message.Host, opponent, game, eventMove); err != nil || refused {
+		return err
+	}
+
+	if game.Clock.timed() {
+		maintenance, err := getMaintenance(ctx, args)
+		if err != nil {
+			return err
+		}
+		game.pauseClock(maintenance)
 	}
 
-	var player common.Disk = 1
-	if message.Host != message.Nickname {
-		player = 2
+	now := time.Now()
+
+	if game.outOfTime(now) {
+		return handleOutOfTime(ctx, req.RequestContext, args, message.Host, game, opponent, connections)
 	}
+
+	player := game.diskOf(message.Host, message.Nickname)
 	if player != game.Player {
 		p1Score, p2Score := common.KeepScore(game.Board)
+		p1Clock, p2Clock := game.clocks(now)
 		return reply(ctx, req.RequestContext, args, messages.UpdateBoard{
-			Board:   game.Board,
-			Player:  game.Player,
-			X:       -1,
-			Y:       -1,
-			P1Score: p1Score,
-			P2Score: p2Score,
+			Board:     game.Board,
+			Player:    game.Player,
+			X:         -1,
+			Y:         -1,
+			P1Score:   p1Score,
+			P2Score:   p2Score,
+			P1Clock:   p1Clock,
+			P2Clock:   p2Clock,
+			ClockTime: game.clockTime(now),
+			Moves:     common.MovesNotation(game.Moves),
 		})
 	}
 
-	var connectionIDs []string
-	for _, v := range connections {
-		connectionIDs = append(connectionIDs, v)
-	}
-
 	if opponent == "" {
 		return handlePlaceDiskSolo(ctx, req.RequestContext, args, message, game)
 	}
 
-	return handlePlaceDiskMultiplayer(ctx, req.RequestContext, args, message, game, opponent, connectionIDs)
+	return handlePlaceDiskMultiplayer(ctx, req.RequestContext, args, message, game, opponent, connections)
 }
 
 func handlePlaceDiskSolo(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, message *messages.PlaceDisk, game game) error {
@@ -75,30 +87,35 @@ func handlePlaceDiskSolo(ctx context.Context, reqCtx events.APIGatewayWebsocketP
 			Y:       -1,
 			P1Score: p1Score,
 			P2Score: p2Score,
+			Moves:   common.MovesNotation(game.Moves),
 		})
 	}
 
+	before := game.Board
 	game.Board = board
-
-	if common.HasMoves(board, game.Player%2+1) {
-		game.Player = game.Player%2 + 1
-	}
+	game.Moves = append(game.Moves, [2]int{message.X, message.Y})
+	game.Player = common.WhoseTurn(board, game.Player)
 
 	if err := updateGame(ctx, args, message.Host, game, message.Nickname, reqCtx.ConnectionID); err != nil {
 		return fmt.Errorf("failed to save updated game state: %w", err)
 	}
 
-	if err := reply(ctx, reqCtx, args, messages.UpdateBoard{
+	connections := map[string]string{message.Nickname: reqCtx.ConnectionID}
+
+	if err := sendMove(ctx, reqCtx, args, message.Host, messages.UpdateBoard{
 		Board:   board,
 		Player:  game.Player,
 		X:       message.X,
 		Y:       message.Y,
 		P1Score: p1Score,
 		P2Score: p2Score,
-	}); err != nil {
+		Moves:   common.MovesNotation(game.Moves),
+	}, before, connections); err != nil {
 		return err
 	}
 
+	updateSpectators(ctx, reqCtx, args, message.Host, "", game)
+
 	for game.Player == 2 && common.HasMoves(game.Board, 2) {
 		log.Println("Taking AI turn")
 
@@ -106,7 +123,9 @@ func handlePlaceDiskSolo(ctx context.Context, reqCtx events.APIGatewayWebsocketP
 
 		var coordinates [2]int
 
-		game.Board, coordinates = doAIPlayerMove(game.Board, game.Difficulty)
+		before = game.Board
+		game.Board, coordinates = ai.Move(game.Board, game.Objective, game.aiLevel(), args.aiTimeBudget())
+		game.Moves = append(game.Moves, coordinates)
 
 		p1Score, p2Score = common.KeepScore(game.Board)
 
@@ -116,64 +135,460 @@ func handlePlaceDiskSolo(ctx context.Context, reqCtx events.APIGatewayWebsocketP
 			time.Sleep(time.Second - time.Since(turnStartedAt))
 		}
 
-		if common.HasMoves(game.Board, 1) {
-			game.Player = 1
-		}
+		game.Player = common.WhoseTurn(game.Board, 2)
 
 		if err := updateGame(ctx, args, message.Host, game, message.Nickname, reqCtx.ConnectionID); err != nil {
 			return fmt.Errorf("failed to save updated game state: %w", err)
 		}
 
-		if err := reply(ctx, reqCtx, args, messages.UpdateBoard{
+		if err := sendMove(ctx, reqCtx, args, message.Host, messages.UpdateBoard{
 			Board:   game.Board,
 			Player:  game.Player,
 			X:       coordinates[0],
 			Y:       coordinates[1],
 			P1Score: p1Score,
 			P2Score: p2Score,
-		}); err != nil {
+			Moves:   common.MovesNotation(game.Moves),
+		}, before, connections); err != nil {
 			return err
 		}
+
+		updateSpectators(ctx, reqCtx, args, message.Host, "", game)
 	}
 
-	return nil
-}
+	if err := recordAIResult(ctx, args, message.Host, game); err != nil {
+		return err
+	}
 
-func handlePlaceDiskMultiplayer(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, message *messages.PlaceDisk, game game, opponent string, connectionIDs []string) error {
-	player := common.Player1
-	if message.Nickname == opponent {
-		player = common.Player2
+	if !common.GameOver(game.Board) {
+		return nil
 	}
 
+	return saveReplay(ctx, args, message.Host, "", game, endingGameOver)
+}
+
+func handlePlaceDiskMultiplayer(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, message *messages.PlaceDisk, game game, opponent string, connections map[string]string) error {
+	player := game.diskOf(message.Host, message.Nickname)
+
+	now := time.Now()
+
 	board, updated := common.ApplyMove(game.Board, message.X, message.Y, player)
 	p1Score, p2Score := common.KeepScore(board)
 	if !updated {
+		p1Clock, p2Clock := game.clocks(now)
 		return reply(ctx, reqCtx, args, messages.UpdateBoard{
-			Board:   board,
-			Player:  game.Player,
-			X:       -1,
-			Y:       -1,
-			P1Score: p1Score,
-			P2Score: p2Score,
+			Board:     board,
+			Player:    game.Player,
+			X:         -1,
+			Y:         -1,
+			P1Score:   p1Score,
+			P2Score:   p2Score,
+			P1Clock:   p1Clock,
+			P2Clock:   p2Clock,
+			ClockTime: game.clockTime(now),
+			Moves:     common.MovesNotation(game.Moves),
 		})
 	}
 
+	before := game.Board
+	game.chargeClock(player, now)
+	game.UndoRequest = 0
 	game.Board = board
+	game.Moves = append(game.Moves, [2]int{message.X, message.Y})
+	game.Player = common.WhoseTurn(board, player)
 
-	if common.HasMoves(game.Board, player%2+1) {
-		game.Player = player%2 + 1
+	if err := updateGame(ctx, args, message.Host, game, message.Nickname, reqCtx.ConnectionID); err != nil {
+		return fmt.Errorf("failed to save updated game state: %w", err)
 	}
 
-	if err := updateGame(ctx, args, message.Host, game, message.Nickname, reqCtx.ConnectionID); err != nil {
+	if common.GameOver(board) {
+		if err := saveReplay(ctx, args, message.Host, opponent, game, endingGameOver); err != nil {
+			return err
+		}
+
+		winner := game.winner(message.Host, opponent)
+		recordTournamentResult(ctx, reqCtx, args, message.Host, opponent, winner, false)
+		recordRating(ctx, args, message.Host, opponent, winner, endingGameOver, game.Ladder)
+		recordMoveQuality(ctx, args, message.Host, opponent, game)
+	}
+
+	p1Clock, p2Clock := game.clocks(now)
+
+	updateSpectators(ctx, reqCtx, args, message.Host, opponent, game)
+
+	return sendMove(ctx, reqCtx, args, message.Host, messages.UpdateBoard{
+		Board:     board,
+		Player:    game.Player,
+		X:         message.X,
+		Y:         message.Y,
+		P1Score:   p1Score,
+		P2Score:   p2Score,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Moves:     common.MovesNotation(game.Moves),
+	}, before, connections)
+}
+
+// sendMove sends the board after a move to the players of a host's game. Lite connections are only
+// sent the squares that the move changed, and connections that asked for compact boards are sent
+// the board encoded with common.EncodeBoard.
+func sendMove(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host string, update messages.UpdateBoard, before common.Board, connections map[string]string) error {
+	seq, err := sequence(ctx, args, host, update, connections)
+	if err != nil {
+		return err
+	}
+
+	var full, compact, lite []string
+	for _, connID := range connections {
+		prefs, err := getUpdatePrefs(ctx, args, connID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case prefs.Lite:
+			lite = append(lite, connID)
+		case prefs.BoardEncoding == common.BoardEncodingCompact:
+			compact = append(compact, connID)
+		default:
+			full = append(full, connID)
+		}
+	}
+
+	if err := broadcast(ctx, reqCtx, args, messages.Wrapper{Message: update, Seq: seq}, full); err != nil {
+		return err
+	}
+
+	if len(compact) > 0 {
+		compactUpdate := update
+		compactUpdate.CompactBoard = common.EncodeBoard(update.Board)
+		compactUpdate.Board = common.Board{}
+
+		if err := broadcast(ctx, reqCtx, args, messages.Wrapper{Message: compactUpdate, Seq: seq}, compact); err != nil {
+			return err
+		}
+	}
+
+	if len(lite) == 0 {
+		return nil
+	}
+
+	update.Delta = boardDelta(before, update.Board, update.X, update.Y)
+	update.Board = common.Board{}
+	update.Moves = nil
+
+	return broadcast(ctx, reqCtx, args, messages.Wrapper{Message: update, Seq: seq}, lite)
+}
+
+// boardDelta returns the squares that differ between the boards before and after a move at x, y.
+func boardDelta(before, after common.Board, x, y int) *messages.BoardDelta {
+	delta := &messages.BoardDelta{Disk: after.Squares[x][y]}
+
+	for i := 0; i < after.Size; i++ {
+		for j := 0; j < after.Size; j++ {
+			if before.Squares[i][j] != after.Squares[i][j] {
+				delta.Squares = append(delta.Squares, [2]int{i, j})
+			}
+		}
+	}
+
+	return delta
+}
+
+// handleOutOfTime ends a game because the player to move has run out of time.
+func handleOutOfTime(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host string, game game, opponent string, connections map[string]string) error {
+	if refused, err := refuse(ctx, reqCtx, args, host, opponent, game, eventTimeOut); err != nil || refused {
+		return err
+	}
+
+	loser := game.nicknameOf(game.Player, host, opponent)
+	winner := otherPlayer(host, opponent, loser)
+
+	game.TimedOut = game.Player
+	game.Remaining[game.Player-1] = 0
+
+	// Another request may have noticed first, and ended the game already.
+	if ok, err := timeOutGame(ctx, args, host, game); err != nil {
 		return fmt.Errorf("failed to save updated game state: %w", err)
+	} else if !ok {
+		return nil
 	}
 
-	return broadcast(ctx, reqCtx, args, messages.UpdateBoard{
-		Board:   board,
-		Player:  game.Player,
-		X:       message.X,
-		Y:       message.Y,
-		P1Score: p1Score,
-		P2Score: p2Score,
-	}, connectionIDs)
+	log.Printf("User %q ran out of time in user %q's game", loser, host)
+
+	reason := fmt.Sprintf("%s ran out of time", strings.ToUpper(loser))
+
+	if err := saveReplay(ctx, args, host, opponent, game, reason); err != nil {
+		return err
+	}
+
+	endForSpectators(ctx, reqCtx, args, host, reason)
+
+	recordTournamentResult(ctx, reqCtx, args, host, opponent, winner, false)
+	recordRating(ctx, args, host, opponent, winner, reason, game.Ladder)
+	recordMoveQuality(ctx, args, host, opponent, game)
+
+	return broadcastToGame(ctx, reqCtx, args, host, messages.GameOver{Message: reason}, connections)
+}
+
+// endGameIfOutOfTime ends a game if the player to move has run out of time. Otherwise only their
+// own move would notice, so players' pings and requests for the game's state check the clock too,
+// and a player who stops moving loses on time.
+func endGameIfOutOfTime(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host string, game game, opponent string, connections map[string]string, now time.Time) (bool, error) {
+	if !game.Clock.timed() {
+		return false, nil
+	}
+
+	maintenance, err := getMaintenance(ctx, args)
+	if err != nil {
+		return false, err
+	}
+	game.pauseClock(maintenance)
+
+	if !game.outOfTime(now) {
+		return false, nil
+	}
+
+	return true, handleOutOfTime(ctx, reqCtx, args, host, game, opponent, connections)
+}
+
+func handleRequestUndo(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.RequestUndo) error {
+	game, opponent, connections, err := getGame(ctx, args, message.Host)
+	if err != nil {
+		return fmt.Errorf("failed to load game state: %w", err)
+	}
+
+	if !isAuthorized(connections, message.Nickname, req.RequestContext.ConnectionID) {
+		return errUnauthorized
+	}
+
+	if refused, err := refuse(ctx, req.RequestContext, args, message.Host, opponent, game, eventRequestTakeback); err != nil || refused {
+		return err
+	}
+
+	player := game.diskOf(message.Host, message.Nickname)
+
+	// Solo games can be undone as often as the player likes, without asking the AI.
+	if opponent == "" {
+		return undoAndUpdate(ctx, req.RequestContext, args, message.Host, opponent, message.Nickname, game, player, connections, nil)
+	}
+
+	if !game.Takebacks {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "takebacks are not allowed in this game"})
+	}
+
+	// The move is only taken back once the opponent accepts.
+	if undoable, err := game.canUndo(player); err != nil {
+		return err
+	} else if !undoable {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "there is no move to take back"})
+	}
+
+	log.Printf("User %q asked for a takeback in user %q's game", message.Nickname, message.Host)
+
+	game.UndoRequest = player
+
+	if err := updateGame(ctx, args, message.Host, game, message.Nickname, req.RequestContext.ConnectionID); err != nil {
+		return fmt.Errorf("failed to save updated game state: %w", err)
+	}
+
+	other := otherPlayer(message.Host, opponent, message.Nickname)
+
+	return broadcastToGame(ctx, req.RequestContext, args, message.Host, message, map[string]string{other: connections[other]})
+}
+
+func handleRespondUndo(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.RespondUndo) error {
+	game, opponent, connections, err := getGame(ctx, args, message.Host)
+	if err != nil {
+		return fmt.Errorf("failed to load game state: %w", err)
+	}
+
+	if !isAuthorized(connections, message.Nickname, req.RequestContext.ConnectionID) {
+		return errUnauthorized
+	}
+
+	if refused, err := refuse(ctx, req.RequestContext, args, message.Host, opponent, game, eventRespondTakeback); err != nil || refused {
+		return err
+	}
+
+	requester := game.UndoRequest
+	if requester == game.diskOf(message.Host, message.Nickname) {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "there is no takeback to respond to"})
+	}
+
+	game.UndoRequest = 0
+
+	if !message.Accept {
+		if err := updateGame(ctx, args, message.Host, game, message.Nickname, req.RequestContext.ConnectionID); err != nil {
+			return fmt.Errorf("failed to save updated game state: %w", err)
+		}
+
+		return broadcastToGame(ctx, req.RequestContext, args, message.Host, message, connections)
+	}
+
+	return undoAndUpdate(ctx, req.RequestContext, args, message.Host, opponent, message.Nickname, game, requester, connections, message)
+}
+
+// undoAndUpdate takes back a player's last move, saves the game, and sends the new board. The
+// acceptance of a takeback, if any, is sent to the players once the game is saved, before the board.
+func undoAndUpdate(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host, opponent, connName string, game game, player common.Disk, connections map[string]string, accepted *messages.RespondUndo) error {
+	now := time.Now()
+
+	undone, err := game.undo(player, now)
+	if err != nil {
+		return err
+	}
+	if !undone {
+		return reply(ctx, reqCtx, args, messages.Error{Error: "there is no move to take back"})
+	}
+
+	log.Printf("Taking back a move by player %d in user %q's game", player, host)
+
+	if err := updateGame(ctx, args, host, game, connName, reqCtx.ConnectionID); err != nil {
+		return fmt.Errorf("failed to save updated game state: %w", err)
+	}
+
+	if accepted != nil {
+		if err := broadcastToGame(ctx, reqCtx, args, host, accepted, connections); err != nil {
+			return err
+		}
+	}
+
+	x, y := -1, -1
+	if len(game.Moves) > 0 {
+		lastMove := game.Moves[len(game.Moves)-1]
+		x, y = lastMove[0], lastMove[1]
+	}
+
+	p1Score, p2Score := common.KeepScore(game.Board)
+	p1Clock, p2Clock := game.clocks(now)
+
+	updateSpectators(ctx, reqCtx, args, host, opponent, game)
+
+	return broadcastToGame(ctx, reqCtx, args, host, messages.UpdateBoard{
+		Board:     game.Board,
+		Player:    game.Player,
+		X:         x,
+		Y:         y,
+		P1Score:   p1Score,
+		P2Score:   p2Score,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Moves:     common.MovesNotation(game.Moves),
+	}, connections)
+}
+
+// undo reverts the game to just before the player's last move, along with any moves made after
+// it. It returns false if the player has not moved yet.
+func (g *game) undo(player common.Disk, now time.Time) (bool, error) {
+	for moves := g.Moves; len(moves) > 0; {
+		moves = moves[:len(moves)-1]
+
+		board, mover, err := common.ReplayTurn(g.startingBoard(), moves)
+		if err != nil {
+			return false, fmt.Errorf("failed to replay moves: %w", err)
+		}
+
+		if mover == player {
+			g.Board, g.Player, g.Moves = board, player, moves
+			if !g.TurnStartedAt.IsZero() {
+				g.TurnStartedAt = now
+			}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// canUndo returns whether the player has a move to take back, without taking it back.
+func (g *game) canUndo(player common.Disk) (bool, error) {
+	// undo changes the game, so it is tried on a copy. The board is copied with it, and the
+	// moves are only sliced.
+	c := *g
+	return c.undo(player, time.Time{})
+}
+
+// handleGetGameState sends the whole state of a game to one of its players or spectators, such as
+// a client that lost track of the game.
+func handleGetGameState(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.GetGameState) error {
+	connID := req.RequestContext.ConnectionID
+
+	game, opponent, connections, err := getGame(ctx, args, message.Host)
+	if errors.Is(err, errNoGame) {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("%s is not playing", strings.ToUpper(message.Host))})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load game state: %w", err)
+	}
+
+	// Only the players and spectators of a game may see it.
+	participant := false
+	for _, id := range connections {
+		participant = participant || id == connID
+	}
+
+	if !participant {
+		_, spectator, err := getSubscription(ctx, args, spectateTopic(message.Host), connID)
+		if err != nil {
+			return err
+		}
+
+		if !spectator {
+			return errUnauthorized
+		}
+	}
+
+	now := time.Now()
+
+	if ended, err := endGameIfOutOfTime(ctx, req.RequestContext, args, message.Host, game, opponent, connections, now); err != nil {
+		return err
+	} else if ended {
+		if game, opponent, _, err = getGame(ctx, args, message.Host); err != nil {
+			return fmt.Errorf("failed to load game state: %w", err)
+		}
+	}
+
+	return reply(ctx, req.RequestContext, args, gameState(message.Host, opponent, game, now))
+}
+
+// gameState describes the whole state of a game.
+func gameState(host, opponent string, game game, now time.Time) messages.GameState {
+	update := spectatorUpdate(host, opponent, game)
+	p1Clock, p2Clock := game.clocks(now)
+
+	return messages.GameState{
+		Host:      host,
+		Opponent:  update.Opponent,
+		Board:     game.Board,
+		Player:    game.Player,
+		X:         update.X,
+		Y:         update.Y,
+		P1Score:   update.P1Score,
+		P2Score:   update.P2Score,
+		Moves:     len(game.Moves),
+		Over:      game.phase(opponent) == phaseFinished,
+		HostDisk:  update.HostDisk,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Rules:     game.rules(),
+		MoveList:  common.MovesNotation(game.Moves),
+	}
+}
+
+// isAuthorized returns whether a connection belongs to the player with the nickname.
+func isAuthorized(connections map[string]string, nickname, connectionID string) bool {
+	v, ok := connections[nickname]
+	return ok && v == connectionID
+}
+
+func connectionIDList(connections map[string]string) []string {
+	var connectionIDs []string
+	for _, v := range connections {
+		connectionIDs = append(connectionIDs, v)
+	}
+	return connectionIDs
 }
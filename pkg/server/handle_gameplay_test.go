@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestUndo(t *testing.T) {
+	moves := [][2]int{{2, 4}, {2, 3}, {1, 2}}
+	board, player, err := common.ReplayTurn(common.NewBoard(common.DefaultBoardSize), moves)
+	require.NoError(t, err)
+
+	g := game{Board: board, Player: player, Moves: moves}
+
+	// Taking back player 2's move also takes back player 1's move that came after it.
+	undone, err := g.undo(common.Player2, time.Now())
+	require.NoError(t, err)
+	assert.True(t, undone)
+	assert.Equal(t, moves[:1], g.Moves)
+	assert.Equal(t, common.Player2, g.Player)
+
+	// Player 2 has no moves left to take back.
+	undone, err = g.undo(common.Player2, time.Now())
+	require.NoError(t, err)
+	assert.False(t, undone)
+
+	undone, err = g.undo(common.Player1, time.Now())
+	require.NoError(t, err)
+	assert.True(t, undone)
+	assert.Empty(t, g.Moves)
+	assert.Equal(t, common.NewBoard(common.DefaultBoardSize), g.Board)
+	assert.Equal(t, common.Player1, g.Player)
+}
+
+func TestCanUndo(t *testing.T) {
+	moves := [][2]int{{2, 4}, {2, 3}}
+	board, player, err := common.ReplayTurn(common.NewBoard(common.DefaultBoardSize), moves)
+	require.NoError(t, err)
+
+	g := game{Board: board, Player: player, Moves: moves}
+
+	ok, err := g.canUndo(common.Player2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The move is not taken back.
+	assert.Equal(t, game{Board: board, Player: player, Moves: moves}, g)
+}
+
+func TestBoardDelta(t *testing.T) {
+	before := common.NewBoard(common.DefaultBoardSize)
+	after, updated := common.ApplyMove(before, 2, 4, common.Player1)
+	require.True(t, updated)
+
+	delta := boardDelta(before, after, 2, 4)
+	assert.Equal(t, common.Player1, delta.Disk)
+	assert.ElementsMatch(t, [][2]int{{2, 4}, {3, 4}}, delta.Squares)
+
+	// Applying the delta gives the board after the move.
+	for _, square := range delta.Squares {
+		before.Squares[square[0]][square[1]] = delta.Disk
+	}
+	assert.Equal(t, after, before)
+}
+
+func TestBoardDeltaIsSmallerThanTheBoard(t *testing.T) {
+	before := common.NewBoard(common.DefaultBoardSize)
+	after, _ := common.ApplyMove(before, 2, 4, common.Player1)
+
+	full, err := json.Marshal(messages.UpdateBoard{Board: after})
+	require.NoError(t, err)
+
+	lite, err := json.Marshal(messages.UpdateBoard{Delta: boardDelta(before, after, 2, 4)})
+	require.NoError(t, err)
+
+	assert.Less(t, len(lite), len(full))
+}
+
+func TestGameState(t *testing.T) {
+	moves := [][2]int{{2, 4}, {2, 3}}
+	board, player, err := common.ReplayTurn(common.NewBoard(common.DefaultBoardSize), moves)
+	require.NoError(t, err)
+
+	g := game{Preset: presetSolo, Board: board, Player: player, Moves: moves}
+
+	state := gameState("flame", "", g, time.Now())
+	assert.Equal(t, "flame", state.Host)
+	assert.Equal(t, board, state.Board)
+	assert.Equal(t, common.Player1, state.Player)
+	assert.Equal(t, 2, state.X)
+	assert.Equal(t, 3, state.Y)
+	assert.Equal(t, 2, state.Moves)
+	assert.False(t, state.Over)
+	assert.Equal(t, presetSolo, state.Rules.Preset)
+
+	// A game that ended on time is over, even though moves remain.
+	g.TimedOut = common.Player1
+	assert.True(t, gameState("flame", "", g, time.Now()).Over)
+}
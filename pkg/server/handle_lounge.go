@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to the lounge, a chat room shared by players who opt in while
+// they are looking for a game.
+
+const (
+	loungeTopic = "lounge"
+
+	// loungeKey is the primary key of the item holding the lounge chat backlog.
+	loungeKey = "#lounge"
+
+	// loungeBacklog is the number of recent chat lines sent to players who join the lounge.
+	loungeBacklog = 20
+)
+
+// ChatModerator inspects a chat message before it is published. It may return modified text, or an
+// error to reject the message. The error text is shown to the sender.
+type ChatModerator func(nickname, text string) (string, error)
+
+// moderateChat runs the built-in moderation followed by any moderators configured in args.
+func moderateChat(args Args, nickname, text string) (string, error) {
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text))
+
+	if text == "" {
+		return "", errors.New("message is empty")
+	}
+
+	for _, moderator := range args.ChatModerators {
+		var err error
+		if text, err = moderator(nickname, text); err != nil {
+			return "", err
+		}
+	}
+
+	return text, nil
+}
+
+func handleJoinLounge(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.JoinLounge) error {
+	log.Printf("User %q is joining the lounge", message.Nickname)
+
+	if err := subscribe(ctx, args, loungeTopic, req.RequestContext.ConnectionID, message.Nickname); err != nil {
+		return err
+	}
+
+	// Lite clients only get chat that is sent after they join.
+	lite, err := getLite(ctx, args, req.RequestContext.ConnectionID)
+	if err != nil || lite {
+		return err
+	}
+
+	chat, err := getChat(ctx, args, loungeKey)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]messages.ChatLine, len(chat))
+	for i, line := range chat {
+		lines[i] = messages.ChatLine{Nickname: line.Nickname, Text: line.Text}
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.LoungeChat{Lines: lines})
+}
+
+func handleLeaveLounge(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, _ *messages.LeaveLounge) error {
+	return unsubscribe(ctx, args, loungeTopic, req.RequestContext.ConnectionID)
+}
+
+func handleSendLoungeChat(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.SendLoungeChat) error {
+	sub, ok, err := getSubscription(ctx, args, loungeTopic, req.RequestContext.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "join the lounge before chatting"})
+	}
+
+	text, err := moderateChat(args, sub.Nickname, message.Text)
+	if err != nil {
+		log.Printf("Rejected lounge chat from user %q: %v", sub.Nickname, err)
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: err.Error()})
+	}
+
+	if err := appendChat(ctx, args, loungeKey, chatLine{Nickname: sub.Nickname, Text: text}, loungeBacklog); err != nil {
+		return err
+	}
+
+	return publish(ctx, req.RequestContext, args, loungeTopic, messages.LoungeChat{
+		Lines: []messages.ChatLine{{Nickname: sub.Nickname, Text: text}},
+	})
+}
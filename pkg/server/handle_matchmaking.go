@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to matchmaking and the leaderboard. Players waiting for a match
+// subscribe to the matchmaking topic of the speed preset they asked for. A player who asks for a
+// match is paired with the waiting player whose rating is closest to theirs, as long as it is
+// within the preset's MatchRatingGap, and otherwise waits for somebody else to ask.
+
+const matchmakingTopic = "matchmaking"
+
+// matchmakingTopicOf returns the topic of the players waiting for a match with a speed preset.
+func matchmakingTopicOf(preset string) string {
+	if preset == "" {
+		return matchmakingTopic
+	}
+	return matchmakingTopic + "#" + preset
+}
+
+// leaveMatchmaking takes a connection out of the matchmaking queue of every preset.
+func leaveMatchmaking(ctx context.Context, args Args, connID string) error {
+	for name := range presets {
+		if err := unsubscribe(ctx, args, matchmakingTopicOf(name), connID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleFindMatch(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.FindMatch) error {
+	log.Printf("User %q is looking for a match with preset %q", message.Nickname, message.Preset)
+
+	full, err := tooManyGames(ctx, args, message.Nickname, "")
+	if err != nil {
+		return err
+	}
+
+	if full {
+		return reply(ctx, req.RequestContext, args, tooManyGamesError())
+	}
+
+	topic := matchmakingTopicOf(message.Preset)
+
+	queued, err := getSubscribers(ctx, args, topic)
+	if err != nil {
+		return err
+	}
+
+	nicknames := []string{message.Nickname}
+	for _, sub := range queued {
+		nicknames = append(nicknames, sub.Nickname)
+	}
+
+	ratings, err := ratingsOf(ctx, args, nicknames)
+	if err != nil {
+		return err
+	}
+
+	own := ratings[message.Nickname]
+
+	for _, sub := range closestMatches(own, queued, ratings, presets[message.Preset].MatchRatingGap) {
+		// Somebody else may have been matched with the same player in the meantime.
+		ok, err := takeSubscription(ctx, args, topic, sub.ConnectionID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		// Players who started another game while they waited are no longer looking.
+		_, inGame, err := getInGame(ctx, args, sub.ConnectionID)
+		if err != nil {
+			return err
+		}
+		if inGame != "" {
+			continue
+		}
+
+		// The player may have been waiting in a queue themselves.
+		if err := leaveMatchmaking(ctx, args, req.RequestContext.ConnectionID); err != nil {
+			return err
+		}
+
+		return startMatch(ctx, req, args, sub, message.Nickname, message.Preset)
+	}
+
+	// A player waits for one preset at a time.
+	if err := leaveMatchmaking(ctx, args, req.RequestContext.ConnectionID); err != nil {
+		return err
+	}
+
+	if err := subscribe(ctx, args, topic, req.RequestContext.ConnectionID, message.Nickname); err != nil {
+		return err
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.MatchQueued{Rating: own.Rating})
+}
+
+// closestMatches returns the queued players who may be matched with a player, closest rating first.
+// Their ratings may differ from the player's by at most gap.
+func closestMatches(own rating, queued []subscriber, ratings map[string]rating, gap int) []subscriber {
+	var matches []subscriber
+	for _, sub := range queued {
+		if sub.Nickname != own.Nickname && abs(ratings[sub.Nickname].Rating-own.Rating) <= gap {
+			matches = append(matches, sub)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return abs(ratings[matches[i].Nickname].Rating-own.Rating) < abs(ratings[matches[j].Nickname].Rating-own.Rating)
+	})
+
+	return matches
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// startMatch starts a game with a speed preset between a player who was waiting in the queue, who
+// hosts it, and the player who asked for a match. Colors are chosen at random.
+func startMatch(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, host subscriber, nickname, presetName string) error {
+	log.Printf("Matched user %q with user %q", nickname, host.Nickname)
+
+	prevNickname, prevInGame, err := updateInGame(ctx, args, req.RequestContext.ConnectionID, nickname, host.Nickname)
+	if err != nil {
+		return err
+	}
+
+	if prevInGame != "" {
+		err := handleLeaveGame(ctx, req, args, &messages.LeaveGame{
+			Nickname: prevNickname,
+			Host:     prevInGame,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := updateInGame(ctx, args, host.ConnectionID, host.Nickname, host.Nickname); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	startsAt := now.Add(startCountdown)
+
+	game := newGame(0)
+	game.Preset = presetName
+	game.applyPreset(presets[presetName])
+	game.Color = messages.ColorRandom
+	game.chooseColors("", rand.Intn(2) == 0)
+	game.startClock(startsAt)
+
+	if err := createGame(ctx, args, host.Nickname, game, nickname, host.Nickname, host.ConnectionID); err != nil {
+		return fmt.Errorf("failed to save new game state: %w", err)
+	}
+
+	_, connectionIDs, err := updateOpponentConnectionGetGameConnectionIDs(ctx, args, host.Nickname, nickname, nickname, req.RequestContext.ConnectionID, [2]string{nickname, nickname})
+	if err != nil {
+		return err
+	}
+
+	p1Clock, p2Clock := game.clocks(now)
+
+	board := messages.UpdateBoard{
+		Board:     game.Board,
+		Player:    game.Player,
+		X:         -1,
+		Y:         -1,
+		P1Score:   2,
+		P2Score:   2,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Rules:     game.rules(),
+	}
+
+	// GameStarted comes first, so that clients waiting for a match know which game the board is for.
+	if err := reply(ctx, req.RequestContext, args, gameStarted(host.Nickname, nickname, nickname, game)); err != nil {
+		return err
+	}
+
+	if err := reply(ctx, req.RequestContext, args, board); err != nil {
+		return err
+	}
+
+	if err := broadcast(ctx, req.RequestContext, args, gameStarted(host.Nickname, nickname, host.Nickname, game), connectionIDs); err != nil {
+		return err
+	}
+
+	if err := broadcast(ctx, req.RequestContext, args, board, connectionIDs); err != nil {
+		return err
+	}
+
+	countdown := gameStarting(host.Nickname, startsAt, now)
+
+	if err := reply(ctx, req.RequestContext, args, countdown); err != nil {
+		return err
+	}
+
+	if err := broadcast(ctx, req.RequestContext, args, countdown, connectionIDs); err != nil {
+		return err
+	}
+
+	return setPlaying(ctx, req.RequestContext, args, true, host.Nickname, nickname)
+}
+
+func handleCancelFindMatch(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, _ *messages.CancelFindMatch) error {
+	return leaveMatchmaking(ctx, args, req.RequestContext.ConnectionID)
+}
+
+func handleGetLeaderboard(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.GetLeaderboard) error {
+	if message.Bots {
+		return handleGetBotLeaderboard(ctx, req, args)
+	}
+
+	leaderboard, err := getLeaderboard(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	nicknames := make([]string, len(leaderboard))
+	for i, r := range leaderboard {
+		nicknames[i] = r.Nickname
+	}
+
+	privacies, err := getPrivacies(ctx, args, nicknames, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Players who hide their rating are left out, even from themselves, so that everyone sees the
+	// same leaderboard.
+	entries := []messages.LeaderboardEntry{}
+	for _, r := range leaderboard {
+		if !privacies[r.Nickname].HideRating {
+			entries = append(entries, messages.LeaderboardEntry{Nickname: r.Nickname, Rating: r.Rating, Games: r.Games})
+		}
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.Leaderboard{Entries: entries})
+}
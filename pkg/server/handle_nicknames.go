@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to claiming nicknames, so that players can't be impersonated.
+// A claim is proved with a random token, which only the client keeps. The server stores a hash of
+// the token, so that the table can't be used to impersonate anyone either. Nicknames that nobody
+// has claimed can be used by anyone, as they were before claims existed.
+
+func handleClaimNickname(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.ClaimNickname) error {
+	connID := req.RequestContext.ConnectionID
+
+	authenticated, err := getAuthenticated(ctx, args, connID)
+	if err != nil {
+		return err
+	}
+
+	if authenticated == message.Nickname {
+		return reply(ctx, req.RequestContext, args, messages.NicknameClaimed{Nickname: message.Nickname})
+	}
+
+	now := time.Now()
+
+	if message.Token != "" {
+		valid, err := proveClaim(ctx, args, message.Nickname, message.Token, now)
+		if err != nil {
+			return err
+		}
+
+		if valid {
+			if err := updateAuthenticated(ctx, args, connID, message.Nickname); err != nil {
+				return err
+			}
+			return reply(ctx, req.RequestContext, args, messages.NicknameClaimed{Nickname: message.Nickname})
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	ok, err := createClaim(ctx, args, message.Nickname, hashToken(token), now)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, nicknameTakenError(message.Nickname))
+	}
+
+	log.Printf("User %q claimed their nickname", message.Nickname)
+
+	if err := updateAuthenticated(ctx, args, connID, message.Nickname); err != nil {
+		return err
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.NicknameClaimed{Nickname: message.Nickname, Token: token})
+}
+
+// authenticate checks the nickname and token that a client sent in its hello.
+func authenticate(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, nickname, token string) error {
+	// Clients without a token haven't claimed their nickname yet.
+	if token == "" {
+		return nil
+	}
+
+	// A deleted player's token must not claim their nickname again.
+	if deleted, err := deliverDataJob(ctx, req, args, nickname, token); err != nil || deleted {
+		return err
+	}
+
+	valid, err := proveClaim(ctx, args, nickname, token, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		log.Printf("Rejected the token for user %q", nickname)
+		return reply(ctx, req.RequestContext, args, messages.Error{
+			Error: fmt.Sprintf("your token for %s is not valid", strings.ToUpper(nickname)),
+			Code:  messages.ErrorInvalidToken,
+		})
+	}
+
+	return updateAuthenticated(ctx, args, req.RequestContext.ConnectionID, nickname)
+}
+
+// proveClaim returns whether a token proves a claim to a nickname. A valid token also keeps the
+// claim from expiring. If the claim has already expired, the token claims the nickname again.
+func proveClaim(ctx context.Context, args Args, nickname, token string, now time.Time) (bool, error) {
+	tokenHash, ok, err := getClaim(ctx, args, nickname, now)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		return createClaim(ctx, args, nickname, hashToken(token), now)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(tokenHash), []byte(hashToken(token))) != 1 {
+		return false, nil
+	}
+
+	return true, refreshClaim(ctx, args, nickname, now)
+}
+
+// actingNickname returns the nickname that a message acts on behalf of, or an empty string if the
+// message isn't sent as any player.
+func actingNickname(message interface{}) string {
+	if m, ok := message.(messages.Acting); ok {
+		return m.ActingNickname()
+	}
+	return ""
+}
+
+// nicknameAllowed returns whether a connection may act as a nickname, which it may unless somebody
+// else has claimed it.
+func nicknameAllowed(ctx context.Context, args Args, connID, nickname string) (bool, error) {
+	_, claimed, err := getClaim(ctx, args, nickname, time.Now())
+	if err != nil || !claimed {
+		return true, err
+	}
+
+	authenticated, err := getAuthenticated(ctx, args, connID)
+
+	return authenticated == nickname, err
+}
+
+func nicknameTakenError(nickname string) messages.Error {
+	return messages.Error{
+		Error: fmt.Sprintf("the name %s belongs to another player", strings.ToUpper(nickname)),
+		Code:  messages.ErrorNicknameTaken,
+	}
+}
+
+// newToken returns a random token for a claim. Tokens are too long to guess, so they don't need to
+// be signed.
+func newToken() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
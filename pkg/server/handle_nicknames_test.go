@@ -0,0 +1,37 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestNewToken(t *testing.T) {
+	a, err := newToken()
+	require.NoError(t, err)
+	b, err := newToken()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 43)
+	assert.NotEqual(t, a, b)
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Equal(t, hashToken("secret"), hashToken("secret"))
+	assert.NotEqual(t, hashToken("secret"), hashToken("Secret"))
+	assert.NotContains(t, hashToken("secret"), "secret")
+}
+
+func TestActingNickname(t *testing.T) {
+	assert.Equal(t, "flame", actingNickname(&messages.HostGame{Nickname: "flame"}))
+	assert.Equal(t, "zinger", actingNickname(&messages.PlaceDisk{Nickname: "zinger", Host: "flame"}))
+	assert.Equal(t, "craig", actingNickname(&messages.JoinLounge{Nickname: "craig"}))
+
+	// Claiming a nickname and looking at games don't act as a player.
+	assert.Empty(t, actingNickname(&messages.ClaimNickname{Nickname: "flame"}))
+	assert.Empty(t, actingNickname(&messages.Spectate{Host: "flame"}))
+	assert.Empty(t, actingNickname(&messages.ListOpenGames{}))
+}
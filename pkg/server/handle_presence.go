@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to player presence statuses.
+
+// effectiveStatus is the status other players see. A status chosen by the player, other than
+// available, takes precedence over the playing status set by the server.
+func (p presence) effectiveStatus() string {
+	switch {
+	case p.Status != "" && p.Status != messages.StatusAvailable:
+		return p.Status
+	case p.Playing:
+		return messages.StatusPlaying
+	default:
+		return messages.StatusAvailable
+	}
+}
+
+func handleSetStatus(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.SetStatus) error {
+	log.Printf("User %q set their status to %q", message.Nickname, message.Status)
+
+	p, err := updatePresenceStatus(ctx, args, message.Nickname, message.Status)
+	if err != nil {
+		return err
+	}
+
+	return publishPresence(ctx, req.RequestContext, args, p)
+}
+
+// setPlaying updates the playing status of players in a multiplayer game as it starts or ends.
+func setPlaying(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, playing bool, nicknames ...string) error {
+	for _, nickname := range nicknames {
+		p, err := updatePresencePlaying(ctx, args, nickname, playing)
+		if err != nil {
+			return err
+		}
+
+		if err := publishPresence(ctx, reqCtx, args, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setOffline removes the presence of a player whose connection closed, and tells players in the
+// lounge that they went offline.
+func setOffline(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, nickname string) error {
+	ok, err := deletePresence(ctx, args, nickname)
+	if err != nil || !ok {
+		return err
+	}
+
+	return publish(ctx, reqCtx, args, loungeTopic, messages.PresenceUpdate{
+		Nickname: nickname,
+		Status:   messages.StatusOffline,
+	})
+}
+
+// publishPresence tells players in the lounge about a presence change. Players who appear offline
+// are published as offline.
+func publishPresence(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, p presence) error {
+	privacies, err := getPrivacies(ctx, args, []string{p.Nickname}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	status := p.effectiveStatus()
+	if privacies[p.Nickname].AppearOffline {
+		status = messages.StatusOffline
+	}
+
+	return publish(ctx, reqCtx, args, loungeTopic, messages.PresenceUpdate{
+		Nickname: p.Nickname,
+		Status:   status,
+	})
+}
+
+// getStatuses returns the effective status of each of the nicknames. Players without a presence
+// record, and players who appear offline, are offline.
+func getStatuses(ctx context.Context, args Args, nicknames []string) (map[string]string, error) {
+	presences, err := getPresences(ctx, args, nicknames)
+	if err != nil {
+		return nil, err
+	}
+
+	privacies, err := getPrivacies(ctx, args, nicknames, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(nicknames))
+	for _, nickname := range nicknames {
+		statuses[nickname] = messages.StatusOffline
+	}
+	for _, p := range presences {
+		if !privacies[p.Nickname].AppearOffline {
+			statuses[p.Nickname] = p.effectiveStatus()
+		}
+	}
+
+	return statuses, nil
+}
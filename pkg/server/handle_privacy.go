@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to privacy settings, which hide parts of a player from everyone
+// else: the history of their games, their rating, and whether they are online. Settings are kept
+// with the claim to a nickname, since anyone could change them for a nickname that nobody has
+// claimed, and are forgotten when the claim expires. Players always see their own profile in full.
+
+func handleSetPrivacy(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.SetPrivacy) error {
+	_, claimed, err := getClaim(ctx, args, message.Nickname, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "claim your nickname before changing your privacy settings"})
+	}
+
+	p := privacy{
+		HideHistory:   message.HideHistory,
+		HideRating:    message.HideRating,
+		AppearOffline: message.AppearOffline,
+	}
+
+	log.Printf("User %q changed their privacy settings to %+v", message.Nickname, p)
+
+	if err := updatePrivacy(ctx, args, message.Nickname, p); err != nil {
+		return err
+	}
+
+	// Players in the lounge see the player go offline, or come back, right away.
+	presences, err := getPresences(ctx, args, []string{message.Nickname})
+	if err != nil {
+		return err
+	}
+	for _, presence := range presences {
+		if err := publishPresence(ctx, req.RequestContext, args, presence); err != nil {
+			return err
+		}
+	}
+
+	return reply(ctx, req.RequestContext, args, privacyMessage(message.Nickname, p))
+}
+
+func handleGetPrivacy(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.GetPrivacy) error {
+	privacies, err := getPrivacies(ctx, args, []string{message.Nickname}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return reply(ctx, req.RequestContext, args, privacyMessage(message.Nickname, privacies[message.Nickname]))
+}
+
+func privacyMessage(nickname string, p privacy) messages.Privacy {
+	return messages.Privacy{
+		Nickname:      nickname,
+		HideHistory:   p.HideHistory,
+		HideRating:    p.HideRating,
+		AppearOffline: p.AppearOffline,
+	}
+}
+
+// privacyFrom returns what players hide from a connection, keyed by nickname. Players hide nothing
+// from a connection that has proved it is them.
+func privacyFrom(ctx context.Context, args Args, connID string, nicknames []string) (map[string]privacy, error) {
+	privacies, err := getPrivacies(ctx, args, nicknames, time.Now())
+	if err != nil || len(privacies) == 0 {
+		return privacies, err
+	}
+
+	viewer, err := getAuthenticated(ctx, args, connID)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(privacies, viewer)
+
+	return privacies, nil
+}
+
+// historyHiddenError is sent instead of a player's private game history.
+func historyHiddenError(nickname string) messages.Error {
+	return messages.Error{Error: fmt.Sprintf("%s keeps their game history private", strings.ToUpper(nickname))}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to players' profiles.
+
+func handleGetProfile(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.GetProfile) error {
+	ratings, err := ratingsOf(ctx, args, []string{message.Player})
+	if err != nil {
+		return err
+	}
+
+	games, err := getMoveQuality(ctx, args, message.Player)
+	if err != nil {
+		return err
+	}
+
+	heatMap, err := getHeatMap(ctx, args, message.Player)
+	if err != nil {
+		return err
+	}
+
+	replays, err := getReplayIDs(ctx, args, message.Player)
+	if err != nil {
+		return err
+	}
+
+	privacies, err := privacyFrom(ctx, args, req.RequestContext.ConnectionID, []string{message.Player})
+	if err != nil {
+		return err
+	}
+
+	r := ratings[message.Player]
+
+	profile := messages.Profile{
+		Player:  message.Player,
+		Rating:  r.Rating,
+		Games:   r.Games,
+		Phases:  summarizeMoveQuality(games),
+		HeatMap: heatMap.message(),
+		Replays: newestFirst(replays),
+	}
+
+	p := privacies[message.Player]
+	if p.HideRating {
+		profile.Rating, profile.Games, profile.RatingHidden = 0, 0, true
+	}
+	if p.HideHistory {
+		profile.Phases, profile.HeatMap, profile.Replays, profile.HistoryHidden = nil, nil, nil, true
+	}
+
+	return reply(ctx, req.RequestContext, args, profile)
+}
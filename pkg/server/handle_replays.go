@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to the history of finished games.
+
+// saveReplay stores the move list of a game that has ended among the host's replays, along with
+// why it ended. Each game is kept under its own ID, which is when it ended.
+func saveReplay(ctx context.Context, args Args, host, opponent string, game game, ending string) error {
+	now := time.Now()
+	id := strconv.FormatInt(millis(now), 10)
+
+	log.Printf("Saving replay %s of user %q's game", id, host)
+
+	kibitz, err := getChat(ctx, args, kibitzKey(host))
+	if err != nil {
+		return err
+	}
+
+	err = putReplay(ctx, args, replay{
+		ID:         id,
+		Host:       host,
+		Opponent:   opponent,
+		Difficulty: game.Difficulty,
+		Adaptive:   game.Adaptive,
+		BoardSize:  game.Board.Size,
+		Moves:      game.Moves,
+		HostDisk:   game.HostDisk,
+		Variant:    game.Objective,
+		Opening:    game.Opening,
+		Ending:     ending,
+		Kibitz:     kibitz,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save replay: %w", err)
+	}
+
+	if err := addReplayID(ctx, args, host, id); err != nil {
+		return fmt.Errorf("failed to list replay: %w", err)
+	}
+
+	if err := forgetExpiredReplays(ctx, args, host, now); err != nil {
+		return err
+	}
+
+	return deleteItem(ctx, args, kibitzKey(host))
+}
+
+// forgetExpiredReplays stops listing a host's replays that are old enough to have expired.
+func forgetExpiredReplays(ctx context.Context, args Args, host string, now time.Time) error {
+	ids, err := getReplayIDs(ctx, args, host)
+	if err != nil {
+		return err
+	}
+
+	var expired []string
+	for _, id := range ids {
+		if endedAt, err := strconv.ParseInt(id, 10, 64); err != nil || now.Sub(time.Unix(0, endedAt*int64(time.Millisecond))) > replayTTL {
+			expired = append(expired, id)
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	return removeReplayIDs(ctx, args, host, expired)
+}
+
+// newestFirst sorts replay IDs from the newest game to the oldest.
+func newestFirst(ids []string) []string {
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a > b
+	})
+	return ids
+}
+
+func handleGetReplay(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.GetReplay) error {
+	id := message.Game
+	if id == "" {
+		ids, err := getReplayIDs(ctx, args, message.Host)
+		if err != nil {
+			return err
+		}
+
+		// Hosts with no listed replays may have one from before each game was kept.
+		if len(ids) > 0 {
+			id = newestFirst(ids)[0]
+		}
+	}
+
+	replay, ok, err := getReplay(ctx, args, message.Host, id)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("no replay found for %s", message.Host)})
+	}
+
+	players := []string{replay.Host}
+	if replay.Opponent != "" {
+		players = append(players, replay.Opponent)
+	}
+
+	privacies, err := privacyFrom(ctx, args, req.RequestContext.ConnectionID, players)
+	if err != nil {
+		return err
+	}
+
+	for _, player := range players {
+		if privacies[player].HideHistory {
+			return reply(ctx, req.RequestContext, args, historyHiddenError(player))
+		}
+	}
+
+	return reply(ctx, req.RequestContext, args, replayMessage(replay))
+}
+
+func replayMessage(r replay) messages.Replay {
+	var endedAt int64
+	if r.ID != "" {
+		endedAt, _ = strconv.ParseInt(r.ID, 10, 64)
+	}
+
+	return messages.Replay{
+		Game:       r.ID,
+		EndedAt:    endedAt,
+		Host:       r.Host,
+		Opponent:   r.Opponent,
+		Difficulty: r.Difficulty,
+		Adaptive:   r.Adaptive,
+		BoardSize:  r.BoardSize,
+		Moves:      r.Moves,
+		HostDisk:   r.HostDisk,
+		Variant:    r.Variant,
+		Opening:    r.Opening,
+		Ending:     r.Ending,
+		Kibitz:     kibitzLines(r.Kibitz),
+	}
+}
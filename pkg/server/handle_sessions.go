@@ -2,9 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/armsnyder/othelgo/pkg/common"
 
@@ -36,19 +39,31 @@ func handleHostGame(ctx context.Context, req events.APIGatewayWebsocketProxyRequ
 		}
 	}
 
-	game := newGame()
+	game := newGame(message.BoardSize)
+	game.Preset = message.Preset
+	game.Color = message.Color
+	game.setVariant(message.Variant, message.Opening)
+	game.applyPreset(presets[message.Preset])
+	game.RatingRange = message.RatingRange
 
 	if err := createGame(ctx, args, message.Nickname, game, waiting, message.Nickname, req.RequestContext.ConnectionID); err != nil {
 		return fmt.Errorf("failed to save new game state: %w", err)
 	}
 
+	now := time.Now()
+	p1Clock, p2Clock := game.clocks(now)
+
 	return reply(ctx, req.RequestContext, args, messages.UpdateBoard{
-		Board:   game.Board,
-		Player:  game.Player,
-		X:       -1,
-		Y:       -1,
-		P1Score: 2,
-		P2Score: 2,
+		Board:     game.Board,
+		Player:    game.Player,
+		X:         -1,
+		Y:         -1,
+		P1Score:   2,
+		P2Score:   2,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Rules:     game.rules(),
 	})
 }
 
@@ -70,8 +85,27 @@ func handleStartSoloGame(ctx context.Context, req events.APIGatewayWebsocketProx
 		}
 	}
 
-	game := newGame()
-	game.Difficulty = message.Difficulty
+	game := newGame(message.BoardSize)
+	game.Preset = presetSolo
+	game.setVariant(message.Variant, message.Opening)
+
+	if message.Adaptive {
+		strength, err := adaptiveStrength(ctx, args, message.Nickname)
+		if err != nil {
+			return err
+		}
+
+		game.Adaptive = true
+		game.Strength = strength
+	} else {
+		levels, err := currentAILevels(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		game.Difficulty = message.Difficulty
+		game.AI = levels[message.Difficulty]
+	}
 
 	if err := createGame(ctx, args, message.Nickname, game, "", message.Nickname, req.RequestContext.ConnectionID); err != nil {
 		return fmt.Errorf("failed to save new game state: %w", err)
@@ -84,19 +118,37 @@ func handleStartSoloGame(ctx context.Context, req events.APIGatewayWebsocketProx
 		Y:       -1,
 		P1Score: 2,
 		P2Score: 2,
+		Rules:   game.rules(),
 	})
 }
 
-func newGame() game {
-	var board common.Board
+// gameStarted tells a player of a multiplayer game which disk they play.
+func gameStarted(host, opponent, nickname string, game game) messages.GameStarted {
+	return messages.GameStarted{
+		Host:     host,
+		Opponent: opponent,
+		Disk:     game.diskOf(host, nickname),
+		HostDisk: game.hostDisk(),
+	}
+}
+
+// gameStarting counts a player down to the start of a host's game.
+func gameStarting(host string, startsAt, now time.Time) messages.GameStarting {
+	return messages.GameStarting{
+		Host:       host,
+		StartsAt:   millis(startsAt),
+		ServerTime: millis(now),
+	}
+}
 
-	board[3][3] = 1
-	board[3][4] = 2
-	board[4][3] = 2
-	board[4][4] = 1
+// newGame returns a game that is ready to start. A size of 0 means the default board size.
+func newGame(size int) game {
+	if size == 0 {
+		size = common.DefaultBoardSize
+	}
 
 	return game{
-		Board:  board,
+		Board:  common.NewBoard(size),
 		Player: 1,
 	}
 }
@@ -104,6 +156,19 @@ func newGame() game {
 func handleJoinGame(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.JoinGame) error {
 	log.Printf("User %q is joining user %q's game", message.Nickname, message.Host)
 
+	full, err := tooManyGames(ctx, args, message.Nickname, message.Host)
+	if err != nil {
+		return err
+	}
+
+	if full {
+		return reply(ctx, req.RequestContext, args, tooManyGamesError())
+	}
+
+	if refused, err := refuseJoin(ctx, req, args, message.Host, message.Nickname); err != nil || refused {
+		return err
+	}
+
 	prevNickname, prevInGame, err := updateInGame(ctx, args, req.RequestContext.ConnectionID, message.Nickname, message.Host)
 	if err != nil {
 		return err
@@ -124,18 +189,117 @@ func handleJoinGame(ctx context.Context, req events.APIGatewayWebsocketProxyRequ
 		return err
 	}
 
+	// A spectator who joins the game stops seeing its kibitz.
+	if err := unsubscribe(ctx, args, kibitzTopic(message.Host), req.RequestContext.ConnectionID); err != nil {
+		return err
+	}
+
+	// The colors are settled as soon as there is an opponent, and the clock starts after the
+	// countdown. Saving the game also moves it out of awaiting an opponent.
+	now := time.Now()
+	startsAt := now.Add(startCountdown)
+	starting := game.Phase == phaseAwaitingOpponent || game.HostDisk == 0 || game.Clock.timed() && game.TurnStartedAt.IsZero()
+	if starting {
+		game.chooseColors(message.Color, rand.Intn(2) == 0)
+		if game.Clock.timed() && game.TurnStartedAt.IsZero() {
+			game.startClock(startsAt)
+		}
+		if err := updateGame(ctx, args, message.Host, game, message.Nickname, req.RequestContext.ConnectionID); err != nil {
+			return fmt.Errorf("failed to start game: %w", err)
+		}
+	}
+
+	p1Clock, p2Clock := game.clocks(now)
+	p1Score, p2Score := common.KeepScore(game.Board)
+
 	if err := reply(ctx, req.RequestContext, args, messages.UpdateBoard{
-		Board:   game.Board,
-		Player:  game.Player,
-		X:       -1,
-		Y:       -1,
-		P1Score: 2,
-		P2Score: 2,
+		Board:     game.Board,
+		Player:    game.Player,
+		X:         -1,
+		Y:         -1,
+		P1Score:   p1Score,
+		P2Score:   p2Score,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Rules:     game.rules(),
 	}); err != nil {
 		return err
 	}
 
-	return broadcast(ctx, req.RequestContext, args, messages.Joined{Nickname: message.Nickname}, connectionIDs)
+	if err := broadcast(ctx, req.RequestContext, args, messages.Joined{Nickname: message.Nickname}, connectionIDs); err != nil {
+		return err
+	}
+
+	if err := reply(ctx, req.RequestContext, args, gameStarted(message.Host, message.Nickname, message.Nickname, game)); err != nil {
+		return err
+	}
+
+	if err := broadcast(ctx, req.RequestContext, args, gameStarted(message.Host, message.Nickname, message.Host, game), connectionIDs); err != nil {
+		return err
+	}
+
+	if starting {
+		countdown := gameStarting(message.Host, startsAt, now)
+
+		if err := reply(ctx, req.RequestContext, args, countdown); err != nil {
+			return err
+		}
+
+		if err := broadcast(ctx, req.RequestContext, args, countdown, connectionIDs); err != nil {
+			return err
+		}
+	}
+
+	updateSpectators(ctx, req.RequestContext, args, message.Host, message.Nickname, game)
+
+	return setPlaying(ctx, req.RequestContext, args, true, message.Host, message.Nickname)
+}
+
+// refuseJoin replies with an error and returns true if a player can't join a game, because it
+// already has another opponent or the player is rated outside the range that the host allows.
+func refuseJoin(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, host, nickname string) (bool, error) {
+	game, opponent, _, err := getGame(ctx, args, host)
+	if errors.Is(err, errNoGame) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	event := eventJoin
+	if opponent == nickname {
+		event = eventRejoin
+	}
+
+	if refused, err := refuse(ctx, req.RequestContext, args, host, opponent, game, event); err != nil || refused {
+		return refused, err
+	}
+
+	return turnAway(ctx, req, args, host, nickname, game, opponent)
+}
+
+// turnAway replies with an error and returns true if a player is rated outside the range that the
+// host of an open game allows.
+func turnAway(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, host, nickname string, game game, opponent string) (bool, error) {
+	if opponent != waiting || game.RatingRange == 0 {
+		return false, nil
+	}
+
+	ratings, err := ratingsByNickname(ctx, args, []string{host, nickname})
+	if err != nil {
+		return false, err
+	}
+
+	if inRatingRange(ratings[host], ratings[nickname], game.RatingRange) {
+		return false, nil
+	}
+
+	log.Printf("User %q is rated outside user %q's range", nickname, host)
+
+	return true, reply(ctx, req.RequestContext, args, messages.Error{
+		Error: fmt.Sprintf("%s only plays opponents rated %d to %d", host, ratings[host]-game.RatingRange, ratings[host]+game.RatingRange),
+	})
 }
 
 func handleListOpenGames(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, _ *messages.ListOpenGames) error {
@@ -143,26 +307,224 @@ func handleListOpenGames(ctx context.Context, req events.APIGatewayWebsocketProx
 	if err != nil {
 		return err
 	}
+
+	hosts, err = getUnexpiredHosts(ctx, args, hosts, time.Now())
+	if err != nil {
+		return err
+	}
 	if hosts == nil {
 		hosts = []string{}
 	}
 
-	return reply(ctx, req.RequestContext, args, messages.OpenGames{Hosts: hosts})
+	statuses, err := getStatuses(ctx, args, hosts)
+	if err != nil {
+		return err
+	}
+
+	ratings, err := ratingsByNickname(ctx, args, hosts)
+	if err != nil {
+		return err
+	}
+
+	privacies, err := privacyFrom(ctx, args, req.RequestContext.ConnectionID, hosts)
+	if err != nil {
+		return err
+	}
+	for nickname, p := range privacies {
+		if p.HideRating {
+			delete(ratings, nickname)
+		}
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.OpenGames{Hosts: hosts, Statuses: statuses, Ratings: ratings})
 }
 
 func handleLeaveGame(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.LeaveGame) error {
 	log.Printf("User %q is leaving user %q's game", message.Nickname, message.Host)
 
-	connectionIDs, err := deleteGameGetConnectionIDs(ctx, args, message.Host, message.Nickname, req.RequestContext.ConnectionID)
+	// A game that is already gone has nothing to refuse. A game that is underway is abandoned before
+	// it is deleted, so that requests that raced the leave, such as a move, can't save it after.
+	if current, opponent, _, err := getGame(ctx, args, message.Host); err == nil {
+		if refused, err := refuse(ctx, req.RequestContext, args, message.Host, opponent, current, eventLeave); err != nil || refused {
+			return err
+		}
+
+		if current.phase(opponent).underway() {
+			if err := abandonGame(ctx, args, message.Host, current); err != nil && !errors.Is(err, errGameChanged) {
+				return err
+			}
+		}
+	} else if !errors.Is(err, errNoGame) {
+		return err
+	}
+
+	game, opponent, connections, err := deleteGameGetConnections(ctx, args, message.Host, message.Nickname, req.RequestContext.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	var nicknames []string
+	for nickname, connID := range connections {
+		if err := removeInGame(ctx, args, connID); err != nil {
+			return err
+		}
+		nicknames = append(nicknames, nickname)
+	}
+
+	reason := fmt.Sprintf("%s left the game", strings.ToUpper(message.Nickname))
+
+	endForSpectators(ctx, req.RequestContext, args, message.Host, reason)
+
+	// Leaving a tournament game forfeits it. Games that were already over were recorded when they
+	// ended, so they aren't recorded again.
+	for _, nickname := range nicknames {
+		if nickname != message.Host {
+			recordTournamentResult(ctx, req.RequestContext, args, message.Host, nickname, otherPlayer(message.Host, nickname, message.Nickname), true)
+		}
+	}
+
+	// Leaving a game that is underway loses it. Finished games were rated when they ended, and
+	// their replays were saved then.
+	if p := game.phase(opponent); p.underway() || p == phaseAbandoned {
+		if len(game.Moves) > 0 {
+			if err := saveReplay(ctx, args, message.Host, opponent, game, reason); err != nil {
+				return err
+			}
+		}
+
+		recordRating(ctx, args, message.Host, opponent, otherPlayer(message.Host, opponent, message.Nickname), reason, game.Ladder)
+	}
+
+	if err := broadcastToGame(ctx, req.RequestContext, args, message.Host, messages.GameOver{Message: reason}, connections); err != nil {
+		return err
+	}
+
+	if len(nicknames) < 2 {
+		return nil
+	}
+
+	return setPlaying(ctx, req.RequestContext, args, false, nicknames...)
+}
+
+func handleResumeGame(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.ResumeGame) error {
+	log.Printf("User %q is resuming user %q's game", message.Nickname, message.Host)
+
+	connID := req.RequestContext.ConnectionID
+
+	game, connections, ok, err := updateConnectionGetGame(ctx, args, message.Host, message.Nickname, connID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, messages.GameOver{Message: "The game is over"})
+	}
+
+	if _, _, err := updateInGame(ctx, args, connID, message.Nickname, message.Host); err != nil {
+		return err
+	}
+
+	// The old connection is gone, so forget it.
+	if prevConnID := connections[message.Nickname]; prevConnID != connID {
+		if err := deleteItem(ctx, args, prevConnID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+
+	// The game was paused for the player, and carries on now that they are back.
+	if game.Phase == phasePaused && game.PausedFor == game.diskOf(message.Host, message.Nickname) {
+		game.resume(now)
+		if err := updateGame(ctx, args, message.Host, game, message.Nickname, connID); err != nil {
+			return fmt.Errorf("failed to resume game: %w", err)
+		}
+	}
+
+	p1Clock, p2Clock := game.clocks(now)
+	p1Score, p2Score := common.KeepScore(game.Board)
+
+	if err := reply(ctx, req.RequestContext, args, messages.UpdateBoard{
+		Board:     game.Board,
+		Player:    game.Player,
+		X:         -1,
+		Y:         -1,
+		P1Score:   p1Score,
+		P2Score:   p2Score,
+		P1Clock:   p1Clock,
+		P2Clock:   p2Clock,
+		ClockTime: game.clockTime(now),
+		Rules:     game.rules(),
+		Moves:     common.MovesNotation(game.Moves),
+	}); err != nil {
+		return err
+	}
+
+	opponentConnections := make(map[string]string)
+	for nickname, opponentConnID := range connections {
+		if nickname != message.Nickname {
+			opponentConnections[nickname] = opponentConnID
+
+			opponent := nickname
+			if nickname == message.Host {
+				opponent = message.Nickname
+			}
+			if err := reply(ctx, req.RequestContext, args, gameStarted(message.Host, opponent, message.Nickname, game)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(opponentConnections) == 0 {
+		return nil
+	}
+
+	// The player went offline when their connection closed.
+	if err := setPlaying(ctx, req.RequestContext, args, true, message.Nickname); err != nil {
+		return err
+	}
+
+	return broadcastToGame(ctx, req.RequestContext, args, message.Host, messages.Joined{Nickname: message.Nickname}, opponentConnections)
+}
+
+func handleResumeFrom(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.ResumeFrom) error {
+	// Only the players of a game may read its messages, which include chat and takebacks.
+	_, _, connections, err := getGame(ctx, args, message.Host)
+	if err != nil && !errors.Is(err, errNoGame) {
+		return err
+	}
+
+	if !isAuthorized(connections, message.Nickname, req.RequestContext.ConnectionID) {
+		return errUnauthorized
+	}
+
+	lastSeq, err := getSeq(ctx, args, message.Host)
 	if err != nil {
 		return err
 	}
 
-	for _, connID := range connectionIDs {
-		if err := deleteItem(ctx, args, connID); err != nil {
+	first := message.LastSeq + 1
+	if oldest := lastSeq - outboxSize + 1; first < oldest {
+		first = oldest
+	}
+
+	outbox, err := getOutbound(ctx, args, message.Host, first, lastSeq)
+	if err != nil {
+		return err
+	}
+
+	missed, err := missedMessages(outbox, message.Nickname, message.LastSeq)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sending user %q %d missed messages from user %q's game", message.Nickname, len(missed), message.Host)
+
+	for _, wrapper := range missed {
+		if err := reply(ctx, req.RequestContext, args, wrapper); err != nil {
 			return err
 		}
 	}
 
-	return broadcast(ctx, req.RequestContext, args, messages.GameOver{Message: fmt.Sprintf("%s left the game", strings.ToUpper(message.Nickname))}, connectionIDs)
+	return nil
 }
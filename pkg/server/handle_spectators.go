@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Handlers for messages pertaining to watching other players' games.
+
+// kibitzBacklog is the number of lines of kibitz kept about a game.
+const kibitzBacklog = 100
+
+// spectateTopic is the topic that the spectators of a host's game subscribe to.
+func spectateTopic(host string) string {
+	return "spectate#" + host
+}
+
+// kibitzTopic is the topic that the spectators of a host's game who can kibitz subscribe to. The
+// players never subscribe to it, which keeps the kibitz hidden from them.
+func kibitzTopic(host string) string {
+	return "kibitz#" + host
+}
+
+func handleSpectate(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.Spectate) error {
+	log.Printf("Connection %s is spectating user %q's game", req.RequestContext.ConnectionID, message.Host)
+
+	game, opponent, _, err := getGame(ctx, args, message.Host)
+	if errors.Is(err, errNoGame) {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("%s is not playing", strings.ToUpper(message.Host))})
+	}
+	if err != nil {
+		return err
+	}
+
+	full, err := tooManySpectators(ctx, args, message.Host, req.RequestContext.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	if full {
+		return reply(ctx, req.RequestContext, args, tooManySpectatorsError(message.Host))
+	}
+
+	if err := subscribe(ctx, args, spectateTopic(message.Host), req.RequestContext.ConnectionID, ""); err != nil {
+		return err
+	}
+
+	update := spectatorUpdate(message.Host, opponent, game)
+	update.Rules = game.rules()
+
+	if err := reply(ctx, req.RequestContext, args, update); err != nil {
+		return err
+	}
+
+	// Players spectating their own game, such as from another connection, don't see the kibitz.
+	if message.Nickname == "" || message.Nickname == message.Host || message.Nickname == opponent {
+		return nil
+	}
+
+	if err := subscribe(ctx, args, kibitzTopic(message.Host), req.RequestContext.ConnectionID, message.Nickname); err != nil {
+		return err
+	}
+
+	// Lite clients only get kibitz that is sent after they start spectating.
+	lite, err := getLite(ctx, args, req.RequestContext.ConnectionID)
+	if err != nil || lite {
+		return err
+	}
+
+	chat, err := getChat(ctx, args, kibitzKey(message.Host))
+	if err != nil || len(chat) == 0 {
+		return err
+	}
+
+	return reply(ctx, req.RequestContext, args, messages.Kibitz{Host: message.Host, Lines: kibitzLines(chat)})
+}
+
+func handleStopSpectating(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.StopSpectating) error {
+	if err := unsubscribe(ctx, args, kibitzTopic(message.Host), req.RequestContext.ConnectionID); err != nil {
+		return err
+	}
+
+	return unsubscribe(ctx, args, spectateTopic(message.Host), req.RequestContext.ConnectionID)
+}
+
+func handleSendKibitz(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.SendKibitz) error {
+	sub, ok, err := getSubscription(ctx, args, kibitzTopic(message.Host), req.RequestContext.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "spectate with a nickname before kibitzing"})
+	}
+
+	game, opponent, _, err := getGame(ctx, args, message.Host)
+	if errors.Is(err, errNoGame) || err == nil && common.GameOver(game.Board) {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "the game is over"})
+	}
+	if err != nil {
+		return err
+	}
+
+	// The spectator may have joined the game since they started spectating.
+	if sub.Nickname == opponent {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "players can't kibitz"})
+	}
+
+	text, err := moderateChat(args, sub.Nickname, message.Text)
+	if err != nil {
+		log.Printf("Rejected kibitz from user %q: %v", sub.Nickname, err)
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: err.Error()})
+	}
+
+	line := chatLine{Nickname: sub.Nickname, Text: text, Move: len(game.Moves)}
+	if err := appendChat(ctx, args, kibitzKey(message.Host), line, kibitzBacklog); err != nil {
+		return err
+	}
+
+	return publish(ctx, req.RequestContext, args, kibitzTopic(message.Host), messages.Kibitz{
+		Host:  message.Host,
+		Lines: kibitzLines([]chatLine{line}),
+	})
+}
+
+func kibitzLines(chat []chatLine) []messages.KibitzLine {
+	if len(chat) == 0 {
+		return nil
+	}
+
+	lines := make([]messages.KibitzLine, len(chat))
+	for i, line := range chat {
+		lines[i] = messages.KibitzLine{Nickname: line.Nickname, Text: line.Text, Move: line.Move}
+	}
+
+	return lines
+}
+
+// updateSpectators sends the state of a game to its spectators. Spectators are not essential to
+// the game, so failures are only logged.
+func updateSpectators(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host, opponent string, game game) {
+	if err := publish(ctx, reqCtx, args, spectateTopic(host), spectatorUpdate(host, opponent, game)); err != nil {
+		log.Printf("Failed to update spectators of user %q's game: %v", host, err)
+	}
+}
+
+// endForSpectators tells the spectators of a game that it has ended early, and why.
+func endForSpectators(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host, reason string) {
+	update := messages.SpectatorUpdate{Host: host, Over: true, Message: reason}
+
+	if err := publish(ctx, reqCtx, args, spectateTopic(host), update); err != nil {
+		log.Printf("Failed to update spectators of user %q's game: %v", host, err)
+	}
+}
+
+func spectatorUpdate(host, opponent string, game game) messages.SpectatorUpdate {
+	if opponent == waiting {
+		opponent = ""
+	}
+
+	x, y := -1, -1
+	if len(game.Moves) > 0 {
+		lastMove := game.Moves[len(game.Moves)-1]
+		x, y = lastMove[0], lastMove[1]
+	}
+
+	p1Score, p2Score := common.KeepScore(game.Board)
+
+	var hostDisk common.Disk
+	if game.hostDisk() != common.Player1 {
+		hostDisk = game.hostDisk()
+	}
+
+	return messages.SpectatorUpdate{
+		Host:     host,
+		Opponent: opponent,
+		Board:    game.Board,
+		Player:   game.Player,
+		X:        x,
+		Y:        y,
+		P1Score:  p1Score,
+		P2Score:  p2Score,
+		Over:     common.GameOver(game.Board),
+		HostDisk: hostDisk,
+	}
+}
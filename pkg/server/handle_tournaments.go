@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server/tournament"
+)
+
+// Handlers for messages pertaining to tournaments. Players are paired by the tournament package,
+// and play their games as ordinary multiplayer games, with the host of each pairing hosting. When a
+// game between paired players ends, the result is recorded in their tournament.
+
+// tournamentTopic is the topic that the players of a tournament subscribe to.
+func tournamentTopic(name string) string {
+	return "tournament#" + name
+}
+
+func (args Args) tournaments() tournament.Store {
+	return tournament.Store{DB: args.DB, TableName: args.TableName}
+}
+
+func handleCreateTournament(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.CreateTournament) error {
+	log.Printf("User %q is creating tournament %q for %d players", message.Nickname, message.Name, message.Players)
+
+	if message.Webhook != "" {
+		if err := checkWebhook(ctx, message.Webhook); err != nil {
+			return reply(ctx, req.RequestContext, args, messages.Error{Error: err.Error()})
+		}
+	}
+
+	t := tournament.New(message.Name, message.Nickname, message.Players, message.Rounds)
+	if message.Points != nil {
+		t.Points = tournament.Points(*message.Points)
+	}
+	t.Webhook = message.Webhook
+
+	ok, err := args.tournaments().Create(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("there is already a tournament called %s", strings.ToUpper(message.Name))})
+	}
+
+	if err := subscribe(ctx, args, tournamentTopic(t.Name), req.RequestContext.ConnectionID, message.Nickname); err != nil {
+		return err
+	}
+
+	return reply(ctx, req.RequestContext, args, tournamentUpdate(t))
+}
+
+func handleJoinTournament(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.JoinTournament) error {
+	log.Printf("User %q is joining tournament %q", message.Nickname, message.Name)
+
+	t, err := updateTournament(ctx, args, message.Name, func(t *tournament.Tournament) error {
+		return t.Join(message.Nickname)
+	})
+
+	switch {
+	case errors.Is(err, tournament.ErrNotFound):
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("there is no tournament called %s", strings.ToUpper(message.Name))})
+	case errors.Is(err, tournament.ErrFull), errors.Is(err, tournament.ErrStarted):
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: err.Error()})
+	case err != nil:
+		return err
+	}
+
+	if err := args.tournaments().SetPlayerTournament(ctx, message.Nickname, t.Name); err != nil {
+		return err
+	}
+
+	if err := subscribe(ctx, args, tournamentTopic(t.Name), req.RequestContext.ConnectionID, message.Nickname); err != nil {
+		return err
+	}
+
+	return publish(ctx, req.RequestContext, args, tournamentTopic(t.Name), tournamentUpdate(t))
+}
+
+func handleWithdrawTournament(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args, message *messages.WithdrawTournament) error {
+	log.Printf("User %q is withdrawing from tournament %q", message.Nickname, message.Name)
+
+	t, err := updateTournament(ctx, args, message.Name, func(t *tournament.Tournament) error {
+		return t.Withdraw(message.Nickname)
+	})
+
+	switch {
+	case errors.Is(err, tournament.ErrNotFound):
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("there is no tournament called %s", strings.ToUpper(message.Name))})
+	case errors.Is(err, tournament.ErrNotRegistered):
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: fmt.Sprintf("you are not in %s", strings.ToUpper(message.Name))})
+	case err != nil:
+		return err
+	}
+
+	if err := unsubscribe(ctx, args, tournamentTopic(t.Name), req.RequestContext.ConnectionID); err != nil {
+		return err
+	}
+
+	update := tournamentUpdate(t)
+
+	if err := reply(ctx, req.RequestContext, args, update); err != nil {
+		return err
+	}
+
+	return publish(ctx, req.RequestContext, args, tournamentTopic(t.Name), update)
+}
+
+// recordTournamentResult records the result of a game in the players' tournament, if they are
+// paired in one, and tells the tournament's players. The winner is empty for a draw. If the game
+// was forfeited, the winner's opponent forfeited it. Tournaments are not essential to the game
+// itself, so failures are only logged.
+func recordTournamentResult(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host, opponent, winner string, forfeit bool) {
+	if opponent == "" || opponent == waiting {
+		return
+	}
+
+	if err := tryRecordTournamentResult(ctx, reqCtx, args, host, opponent, winner, forfeit); err != nil {
+		log.Printf("Failed to record the result of user %q's game in a tournament: %v", host, err)
+	}
+}
+
+func tryRecordTournamentResult(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host, opponent, winner string, forfeit bool) error {
+	name, err := args.tournaments().PlayerTournament(ctx, host)
+	if err != nil || name == "" {
+		return err
+	}
+
+	t, err := updateTournament(ctx, args, name, func(t *tournament.Tournament) error {
+		if forfeit {
+			return t.RecordForfeit(otherPlayer(host, opponent, winner), winner)
+		}
+		return t.RecordResult(host, opponent, winner)
+	})
+
+	// Players in a tournament can still play games that aren't part of it.
+	if errors.Is(err, tournament.ErrNotPaired) || errors.Is(err, tournament.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Recorded the result of user %q's game in tournament %q", host, name)
+
+	return publish(ctx, reqCtx, args, tournamentTopic(name), tournamentUpdate(t))
+}
+
+// otherPlayer returns whichever of a game's two players is not nickname.
+func otherPlayer(host, opponent, nickname string) string {
+	if nickname == host {
+		return opponent
+	}
+	return host
+}
+
+func tournamentUpdate(t *tournament.Tournament) messages.TournamentUpdate {
+	update := messages.TournamentUpdate{
+		Name:      t.Name,
+		Organizer: t.Organizer,
+		Status:    t.Status(),
+		Players:   t.Players,
+		Size:      t.Size,
+		Round:     t.Round,
+		Rounds:    t.Rounds,
+		Points:    messages.TournamentPoints(t.PointValues()),
+		Pairings:  []messages.TournamentPairing{},
+		Standings: []messages.TournamentStanding{},
+	}
+
+	if update.Players == nil {
+		update.Players = []string{}
+	}
+
+	for _, p := range t.CurrentPairings() {
+		update.Pairings = append(update.Pairings, *pairingMessage(p))
+	}
+
+	update.Standings = append(update.Standings, standingsMessage(t)...)
+
+	return update
+}
+
+func pairingMessage(p tournament.Pairing) *messages.TournamentPairing {
+	return &messages.TournamentPairing{
+		Host:    p.Host,
+		Guest:   p.Guest,
+		Done:    p.Done,
+		Winner:  p.Winner,
+		Forfeit: p.Forfeit,
+	}
+}
+
+func standingsMessage(t *tournament.Tournament) []messages.TournamentStanding {
+	var standings []messages.TournamentStanding
+	for _, s := range t.Standings() {
+		standings = append(standings, messages.TournamentStanding(s))
+	}
+	return standings
+}
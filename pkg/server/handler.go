@@ -3,11 +3,16 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/armsnyder/othelgo/pkg/messages"
@@ -17,23 +22,108 @@ import (
 
 // Args represent external dependencies of the server, which may be replaced in test environments.
 type Args struct {
-	DB                                   *dynamodb.DynamoDB
+	DB                                   dynamodbiface.DynamoDBAPI
 	TableName                            string
 	APIGatewayManagementAPIClientFactory APIGatewayManagementAPIClientFactory
+
+	// ChatModerators are optional hooks that inspect chat messages before they are published.
+	ChatModerators []ChatModerator
+
+	// AITimeBudget is how long the AI may think about each move in a solo game. Harder
+	// difficulties look fewer turns ahead when they run out of time. Zero means the default.
+	AITimeBudget time.Duration
+
+	// Metrics records measurements of the server. If nil, metrics are written to stdout in the
+	// CloudWatch embedded metric format.
+	Metrics Metrics
+
+	// RequestLog is where a JSON line about each handled event is written. If nil, request logs
+	// are written to stdout.
+	RequestLog io.Writer
+
+	// Branding is shown on the main menu of clients, so that self-hosted deployments can be told
+	// apart from the public server. The zero value shows no branding.
+	Branding messages.Branding
+
+	// HTTPClient posts the events of tournaments to their webhooks. If nil, a client with a short
+	// timeout is used.
+	HTTPClient *http.Client
+}
+
+// defaultAITimeBudget keeps AI turns well within the Lambda timeout.
+const defaultAITimeBudget = 2 * time.Second
+
+func (args Args) aiTimeBudget() time.Duration {
+	if args.AITimeBudget > 0 {
+		return args.AITimeBudget
+	}
+	return defaultAITimeBudget
+}
+
+// branding returns the Branding to send to clients, or nil if there is none. Invalid branding is
+// logged and not sent, since clients would reject it.
+func (args Args) branding() *messages.Branding {
+	if args.Branding == (messages.Branding{}) {
+		return nil
+	}
+
+	if err := validate.Struct(args.Branding); err != nil {
+		log.Printf("Ignoring invalid branding: %v", err)
+		return nil
+	}
+
+	return &args.Branding
 }
 
 // DefaultHandler is an AWS Lambda handler that uses default arguments, as it would in a real
 // deployment environment. It can be invoked with lambda.Start(server.DefaultHandler).
 func DefaultHandler(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (resp events.APIGatewayProxyResponse, err error) {
-	defaultArgs := Args{
+	return Handle(ctx, req, defaultArgs())
+}
+
+// defaultArgs are the arguments of a real deployment environment.
+func defaultArgs() Args {
+	return Args{
 		DB:                                   defaultDB(),
 		TableName:                            "Othelgo",
 		APIGatewayManagementAPIClientFactory: defaultAPIGatewayManagementAPIClientFactory(),
+		AITimeBudget:                         envAITimeBudget(),
+		Branding:                             envBranding(),
+	}
+}
+
+// envAITimeBudget reads the AI time budget from the AI_TIME_BUDGET environment variable, which is a
+// duration such as "1500ms". It returns zero, meaning the default, if the variable is unset or
+// invalid.
+func envAITimeBudget() time.Duration {
+	value := os.Getenv("AI_TIME_BUDGET")
+	if value == "" {
+		return 0
 	}
 
-	return Handle(ctx, req, defaultArgs)
+	budget, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Ignoring invalid AI_TIME_BUDGET %q: %v", value, err)
+		return 0
+	}
+
+	return budget
 }
 
+// envBranding reads the branding from the SERVER_NAME, SERVER_MOTD, and SERVER_ACCENT_COLOR
+// environment variables, which are unset on the public server.
+func envBranding() messages.Branding {
+	return messages.Branding{
+		Name:        os.Getenv("SERVER_NAME"),
+		MOTD:        os.Getenv("SERVER_MOTD"),
+		AccentColor: os.Getenv("SERVER_ACCENT_COLOR"),
+	}
+}
+
+// errUnauthorized is returned when a connection acts on behalf of a player in a game that it is not
+// part of.
+var errUnauthorized = errors.New("unauthorized")
+
 // validate is a single instance of Validate; it caches struct info.
 var validate *validator.Validate
 
@@ -48,6 +138,9 @@ func init() {
 func Handle(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) (resp events.APIGatewayProxyResponse, err error) {
 	log.Printf("Handling event type %q", req.RequestContext.EventType)
 
+	start := time.Now()
+	info := describeRequest(req)
+
 	switch req.RequestContext.EventType {
 	case "CONNECT":
 	case "DISCONNECT":
@@ -57,6 +150,8 @@ func Handle(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, arg
 	default:
 		err = fmt.Errorf("unrecognized event type %q", req.RequestContext.EventType)
 	}
+	recordRequest(args, info, time.Since(start), err)
+
 	if err != nil {
 		log.Printf("here's an error: %s", err)
 
@@ -69,6 +164,28 @@ func Handle(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, arg
 }
 
 func handleMessage(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
+	if ok, err := admitMessage(ctx, req, args); !ok || err != nil {
+		return err
+	}
+
+	route, err := getRouter(ctx, args, req.RequestContext.ConnectionID)
+	if err != nil {
+		return err
+	}
+
+	// A request whose game changed before it could save it is refused, like one that the game's
+	// phase doesn't accept.
+	err = route(ctx, req, args)
+	if errors.Is(err, errGameChanged) {
+		log.Printf("Refusing a request whose game changed before it was saved")
+		return reply(ctx, req.RequestContext, args, messages.Error{Error: "the game changed, so the request was not saved", Code: messages.ErrorWrongPhase})
+	}
+
+	return err
+}
+
+// routeMessage decodes and handles a message of the current protocol version.
+func routeMessage(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
 	var wrapper messages.Wrapper
 	if err := json.Unmarshal([]byte(req.Body), &wrapper); err != nil {
 		return err
@@ -82,6 +199,39 @@ func handleMessage(ctx context.Context, req events.APIGatewayWebsocketProxyReque
 		return err
 	}
 
+	if frozenDuringMaintenance(message) {
+		maintenance, err := getMaintenance(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		if maintenance.Enabled {
+			return reply(ctx, req.RequestContext, args, messages.Error{Error: maintenance.notice(), Code: messages.ErrorMaintenance})
+		}
+	}
+
+	if nickname := actingNickname(message); nickname != "" {
+		allowed, err := nicknameAllowed(ctx, args, req.RequestContext.ConnectionID, nickname)
+		if err != nil {
+			return err
+		}
+
+		if !allowed {
+			return reply(ctx, req.RequestContext, args, nicknameTakenError(nickname))
+		}
+	}
+
+	if topic := subscriptionTopic(message); topic != "" {
+		full, err := tooManySubscriptions(ctx, args, req.RequestContext.ConnectionID, topic)
+		if err != nil {
+			return err
+		}
+
+		if full {
+			return reply(ctx, req.RequestContext, args, tooManySubscriptionsError())
+		}
+	}
+
 	switch m := message.(type) {
 	case *messages.HostGame:
 		return handleHostGame(ctx, req, args, m)
@@ -91,12 +241,68 @@ func handleMessage(ctx context.Context, req events.APIGatewayWebsocketProxyReque
 		return handleJoinGame(ctx, req, args, m)
 	case *messages.LeaveGame:
 		return handleLeaveGame(ctx, req, args, m)
+	case *messages.ResumeGame:
+		return handleResumeGame(ctx, req, args, m)
+	case *messages.ResumeFrom:
+		return handleResumeFrom(ctx, req, args, m)
 	case *messages.ListOpenGames:
 		return handleListOpenGames(ctx, req, args, m)
 	case *messages.PlaceDisk:
 		return handlePlaceDisk(ctx, req, args, m)
+	case *messages.RequestUndo:
+		return handleRequestUndo(ctx, req, args, m)
+	case *messages.RespondUndo:
+		return handleRespondUndo(ctx, req, args, m)
+	case *messages.Spectate:
+		return handleSpectate(ctx, req, args, m)
+	case *messages.SendKibitz:
+		return handleSendKibitz(ctx, req, args, m)
+	case *messages.StopSpectating:
+		return handleStopSpectating(ctx, req, args, m)
 	case *messages.Hello:
 		return handleHello(ctx, req, args, m)
+	case *messages.Ping:
+		return handlePing(ctx, req, args, m)
+	case *messages.GetReplay:
+		return handleGetReplay(ctx, req, args, m)
+	case *messages.JoinLounge:
+		return handleJoinLounge(ctx, req, args, m)
+	case *messages.LeaveLounge:
+		return handleLeaveLounge(ctx, req, args, m)
+	case *messages.SendLoungeChat:
+		return handleSendLoungeChat(ctx, req, args, m)
+	case *messages.SetStatus:
+		return handleSetStatus(ctx, req, args, m)
+	case *messages.ClaimNickname:
+		return handleClaimNickname(ctx, req, args, m)
+	case *messages.GetGameState:
+		return handleGetGameState(ctx, req, args, m)
+	case *messages.CreateTournament:
+		return handleCreateTournament(ctx, req, args, m)
+	case *messages.JoinTournament:
+		return handleJoinTournament(ctx, req, args, m)
+	case *messages.WithdrawTournament:
+		return handleWithdrawTournament(ctx, req, args, m)
+	case *messages.FindMatch:
+		return handleFindMatch(ctx, req, args, m)
+	case *messages.CancelFindMatch:
+		return handleCancelFindMatch(ctx, req, args, m)
+	case *messages.GetLeaderboard:
+		return handleGetLeaderboard(ctx, req, args, m)
+	case *messages.GetProfile:
+		return handleGetProfile(ctx, req, args, m)
+	case *messages.SetPrivacy:
+		return handleSetPrivacy(ctx, req, args, m)
+	case *messages.GetPrivacy:
+		return handleGetPrivacy(ctx, req, args, m)
+	case *messages.ExportData:
+		return handleExportData(ctx, req, args, m)
+	case *messages.DeleteData:
+		return handleDeleteData(ctx, req, args, m)
+	case *messages.DisputeResult:
+		return handleDisputeResult(ctx, req, args, m)
+	case *messages.JoinBotLadder:
+		return handleJoinBotLadder(ctx, req, args, m)
 	}
 
 	log.Printf("No handler for message type %T", message)
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Counting where players play. RunHeatMaps, which is meant to be run nightly, plays back every
+// replay in the archive, and counts for each player the squares that they moved on and the squares
+// where their disks were flipped. The counts replace each player's heat map, which GetProfile
+// shows. Since the archive keeps the latest game of each host, a heat map covers the games that are
+// still in it. Only games on the default board are counted, so that the squares of different games
+// line up.
+
+// HeatMapsResult describes a run of RunHeatMaps.
+type HeatMapsResult struct {
+	Games   int
+	Players int
+	Skipped int
+}
+
+func (r HeatMapsResult) String() string {
+	return fmt.Sprintf("counted %d games of %d players (%d skipped)", r.Games, r.Players, r.Skipped)
+}
+
+// RunHeatMaps counts the squares that each player in the archive played and lost disks on, and
+// saves their heat maps. It reads the whole table.
+func RunHeatMaps(ctx context.Context, args Args) (HeatMapsResult, error) {
+	replays, err := scanReplays(ctx, args)
+	if err != nil {
+		return HeatMapsResult{}, err
+	}
+
+	var result HeatMapsResult
+	maps := make(map[string]*heatMap)
+
+	for _, r := range replays {
+		if r.BoardSize != common.DefaultBoardSize {
+			continue
+		}
+
+		if err := addToHeatMaps(maps, r); err != nil {
+			log.Printf("Skipping the replay of user %q in heat maps: %v", r.Host, err)
+			result.Skipped++
+			continue
+		}
+
+		result.Games++
+	}
+
+	for nickname, m := range maps {
+		if err := putHeatMap(ctx, args, nickname, *m); err != nil {
+			return result, err
+		}
+		result.Players++
+	}
+
+	return result, nil
+}
+
+// addToHeatMaps plays back a replay, and adds its moves and flipped disks to the heat maps of its
+// players. Deleted players and the AI have no heat map.
+func addToHeatMaps(maps map[string]*heatMap, r replay) error {
+	boards, err := common.ReplayMoves(common.NewBoardWithOpening(r.BoardSize, r.Opening), r.Moves)
+	if err != nil {
+		return err
+	}
+
+	players := make(map[common.Disk]*heatMap, 2)
+
+	g := game{HostDisk: r.HostDisk}
+	for _, nickname := range []string{r.Host, r.Opponent} {
+		if nickname == "" || nickname == deletedPlayer {
+			continue
+		}
+
+		m, ok := maps[nickname]
+		if !ok {
+			m = newHeatMap(r.BoardSize)
+			maps[nickname] = m
+		}
+		m.Games++
+
+		players[g.diskOf(r.Host, nickname)] = m
+	}
+
+	player := common.Player1
+	for i := 1; i < len(boards); i++ {
+		before, after := boards[i-1], boards[i]
+		move := r.Moves[i-1]
+
+		if m, ok := players[player]; ok {
+			m.Plays[move[0]][move[1]]++
+		}
+
+		// The mover's opponent loses every disk that changed color.
+		opponent := player%2 + 1
+		if m, ok := players[opponent]; ok {
+			for x := 0; x < after.Size; x++ {
+				for y := 0; y < after.Size; y++ {
+					if before.Squares[x][y] == opponent && after.Squares[x][y] == player {
+						m.Losses[x][y]++
+					}
+				}
+			}
+		}
+
+		player = common.WhoseTurn(after, player)
+	}
+
+	return nil
+}
+
+// heatMap counts the squares that a player moved on, and the squares where their disks were
+// flipped, in their games in the archive.
+type heatMap struct {
+	Games  int
+	Plays  [][]int
+	Losses [][]int
+}
+
+func newHeatMap(size int) *heatMap {
+	m := &heatMap{Plays: make([][]int, size), Losses: make([][]int, size)}
+	for x := 0; x < size; x++ {
+		m.Plays[x] = make([]int, size)
+		m.Losses[x] = make([]int, size)
+	}
+	return m
+}
+
+// message returns a heat map as sent in a Profile, or nil if the player has no games in it.
+func (m heatMap) message() *messages.HeatMap {
+	if m.Games == 0 {
+		return nil
+	}
+	return &messages.HeatMap{Games: m.Games, Plays: m.Plays, Losses: m.Losses}
+}
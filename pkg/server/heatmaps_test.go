@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestAddToHeatMaps(t *testing.T) {
+	moves := [][2]int{{2, 4}, {2, 5}, {2, 6}}
+	r := replay{Host: "flame", Opponent: "zinger", BoardSize: common.DefaultBoardSize, Moves: moves, HostDisk: common.Player2}
+
+	maps := make(map[string]*heatMap)
+	require.NoError(t, addToHeatMaps(maps, r))
+	require.NoError(t, addToHeatMaps(maps, replay{Host: "flame", BoardSize: common.DefaultBoardSize, Moves: moves[:1]}))
+
+	// Flame plays white in the first game and black in the solo game, where the AI has no heat map.
+	flame, zinger := maps["flame"], maps["zinger"]
+	require.Len(t, maps, 2)
+	assert.Equal(t, 2, flame.Games)
+	assert.Equal(t, 1, zinger.Games)
+	assert.Equal(t, 1, flame.Plays[2][5])
+	assert.Equal(t, 1, flame.Plays[2][4])
+	assert.Equal(t, 1, zinger.Plays[2][4])
+	assert.Equal(t, 1, zinger.Plays[2][6])
+
+	// Every disk that a move flips is lost by the mover's opponent. Black only gains disks in the
+	// solo game, so flame loses none there.
+	boards, err := common.ReplayMoves(common.NewBoard(common.DefaultBoardSize), moves)
+	require.NoError(t, err)
+	wantLosses := map[*heatMap]int{}
+	for i := 1; i < len(boards); i++ {
+		p1Before, p2Before := common.KeepScore(boards[i-1])
+		p1After, p2After := common.KeepScore(boards[i])
+		if p1After < p1Before {
+			wantLosses[zinger] += p1Before - p1After
+		}
+		if p2After < p2Before {
+			wantLosses[flame] += p2Before - p2After
+		}
+	}
+
+	for m, want := range wantLosses {
+		got := 0
+		for _, column := range m.Losses {
+			for _, losses := range column {
+				got += losses
+			}
+		}
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestAddToHeatMapsIllegal(t *testing.T) {
+	maps := make(map[string]*heatMap)
+	err := addToHeatMaps(maps, replay{Host: "flame", BoardSize: common.DefaultBoardSize, Moves: [][2]int{{0, 0}}})
+	assert.Error(t, err)
+}
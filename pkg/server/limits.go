@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Resource limits protect a small deployment from clients that, by accident or on purpose, would
+// otherwise fill its table and its broadcasts. A player may only be the opponent in so many games
+// at once, a game may only have so many spectators, and a connection may only subscribe to so many
+// topics. Requests over a limit are refused with an error that has a code of its own. The limits
+// are checked before the request is carried out, so concurrent requests may go slightly over them.
+
+const (
+	// maxGamesPerNickname is how many games a player may have joined at once, such as from several
+	// connections or in correspondence games. Hosting adds at most one more.
+	maxGamesPerNickname = 5
+
+	// maxSpectatorsPerGame is how many connections may spectate a game at once.
+	maxSpectatorsPerGame = 50
+
+	// maxSubscriptionsPerConnection is how many topics a connection may subscribe to at once, such
+	// as the lounge, tournaments, and games that it spectates.
+	maxSubscriptionsPerConnection = 16
+)
+
+// subscriptionTopic returns the topic that a message subscribes its connection to, or an empty
+// string if it doesn't subscribe.
+func subscriptionTopic(message interface{}) string {
+	switch m := message.(type) {
+	case *messages.JoinLounge:
+		return loungeTopic
+	case *messages.FindMatch:
+		return matchmakingTopicOf(m.Preset)
+	case *messages.Spectate:
+		return spectateTopic(m.Host)
+	case *messages.CreateTournament:
+		return tournamentTopic(m.Name)
+	case *messages.JoinTournament:
+		return tournamentTopic(m.Name)
+	case *messages.JoinBotLadder:
+		return botLadderTopic
+	default:
+		return ""
+	}
+}
+
+// tooManySubscriptions returns whether subscribing a connection to another topic would take it over
+// maxSubscriptionsPerConnection. Subscribing again to a topic that it is subscribed to is allowed.
+func tooManySubscriptions(ctx context.Context, args Args, connID, topic string) (bool, error) {
+	conn, _, err := getConnection(ctx, args, connID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, t := range conn.Topics {
+		if t == topic {
+			return false, nil
+		}
+	}
+
+	return len(conn.Topics) >= maxSubscriptionsPerConnection, nil
+}
+
+// tooManySpectators returns whether another connection spectating a host's game would take it over
+// maxSpectatorsPerGame. A connection that already spectates may do so again.
+func tooManySpectators(ctx context.Context, args Args, host, connID string) (bool, error) {
+	spectators, err := getSubscribers(ctx, args, spectateTopic(host))
+	if err != nil {
+		return false, err
+	}
+
+	for _, sub := range spectators {
+		if sub.ConnectionID == connID {
+			return false, nil
+		}
+	}
+
+	return len(spectators) >= maxSpectatorsPerGame, nil
+}
+
+// tooManyGames returns whether a player joining another game would take them over
+// maxGamesPerNickname. The game at host, which they may be joining again, isn't counted.
+func tooManyGames(ctx context.Context, args Args, nickname, host string) (bool, error) {
+	hosts, err := getHostsByOpponent(ctx, args, nickname)
+	if err != nil {
+		return false, err
+	}
+
+	hosts, err = getUnexpiredHosts(ctx, args, hosts, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	games := 0
+	for _, h := range hosts {
+		if h != host {
+			games++
+		}
+	}
+
+	return games >= maxGamesPerNickname, nil
+}
+
+func tooManySubscriptionsError() messages.Error {
+	return messages.Error{
+		Error: fmt.Sprintf("you can only follow %d things at once", maxSubscriptionsPerConnection),
+		Code:  messages.ErrorTooManySubscriptions,
+	}
+}
+
+func tooManySpectatorsError(host string) messages.Error {
+	return messages.Error{
+		Error: fmt.Sprintf("%s's game already has %d spectators", strings.ToUpper(host), maxSpectatorsPerGame),
+		Code:  messages.ErrorTooManySpectators,
+	}
+}
+
+func tooManyGamesError() messages.Error {
+	return messages.Error{
+		Error: fmt.Sprintf("you can only play %d games at once", maxGamesPerNickname),
+		Code:  messages.ErrorTooManyGames,
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestSubscriptionTopic(t *testing.T) {
+	assert.Equal(t, loungeTopic, subscriptionTopic(&messages.JoinLounge{}))
+	assert.Equal(t, matchmakingTopic, subscriptionTopic(&messages.FindMatch{}))
+	assert.Equal(t, "matchmaking#blitz", subscriptionTopic(&messages.FindMatch{Preset: "blitz"}))
+	assert.Equal(t, spectateTopic("andy"), subscriptionTopic(&messages.Spectate{Host: "andy"}))
+	assert.Equal(t, tournamentTopic("cup"), subscriptionTopic(&messages.CreateTournament{Name: "cup"}))
+	assert.Equal(t, tournamentTopic("cup"), subscriptionTopic(&messages.JoinTournament{Name: "cup"}))
+	assert.Equal(t, botLadderTopic, subscriptionTopic(&messages.JoinBotLadder{}))
+
+	// Messages that don't subscribe aren't limited.
+	assert.Empty(t, subscriptionTopic(&messages.PlaceDisk{}))
+	assert.Empty(t, subscriptionTopic(&messages.LeaveGame{}))
+}
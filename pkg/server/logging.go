@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/go-playground/validator/v10"
+)
+
+// Each event that the server handles is summarized by a structured JSON log line and by metrics,
+// so that operators can tell how the server is being used and why handlers fail.
+
+// requestLogOutput is where request logs are written. It is replaced in tests.
+var requestLogOutput io.Writer = os.Stdout
+
+func (args Args) requestLog() io.Writer {
+	if args.RequestLog != nil {
+		return args.RequestLog
+	}
+	return requestLogOutput
+}
+
+// requestInfo describes an event for logs and metrics.
+type requestInfo struct {
+	ConnectionID string `json:"connectionId"`
+	EventType    string `json:"eventType"`
+	Action       string `json:"action"`
+	Host         string `json:"host,omitempty"`
+}
+
+// describeRequest returns what an event is about. The body of a message is only peeked at, since
+// it is properly decoded and validated by the router.
+func describeRequest(req events.APIGatewayWebsocketProxyRequest) requestInfo {
+	info := requestInfo{
+		ConnectionID: req.RequestContext.ConnectionID,
+		EventType:    req.RequestContext.EventType,
+		Action:       req.RequestContext.RouteKey,
+	}
+
+	if req.RequestContext.EventType != "MESSAGE" {
+		return info
+	}
+
+	var body struct {
+		Action   string `json:"action"`
+		Host     string `json:"host"`
+		Nickname string `json:"nickname"`
+	}
+
+	if err := json.Unmarshal([]byte(req.Body), &body); err != nil || body.Action == "" {
+		info.Action = "unknown"
+		return info
+	}
+
+	info.Action = body.Action
+	info.Host = body.Host
+
+	// Players host their own games.
+	if body.Action == "hostGame" || body.Action == "startSoloGame" {
+		info.Host = body.Nickname
+	}
+
+	return info
+}
+
+// recordRequest logs and measures an event that was handled.
+func recordRequest(args Args, info requestInfo, latency time.Duration, err error) {
+	entry := struct {
+		requestInfo
+		Level      string  `json:"level"`
+		Message    string  `json:"message"`
+		LatencyMs  float64 `json:"latencyMs"`
+		ErrorClass string  `json:"errorClass,omitempty"`
+		Error      string  `json:"error,omitempty"`
+	}{
+		requestInfo: info,
+		Level:       "info",
+		Message:     "Handled event",
+		LatencyMs:   float64(latency) / float64(time.Millisecond),
+	}
+
+	dimensions := map[string]string{"Action": info.Action}
+
+	metrics := args.metrics()
+	metrics.Count("Requests", dimensions)
+	metrics.Latency("Latency", latency, dimensions)
+
+	if err != nil {
+		entry.Level = "error"
+		entry.Message = "Failed to handle event"
+		entry.ErrorClass = errorClass(err)
+		entry.Error = err.Error()
+
+		metrics.Count("Errors", map[string]string{"Action": info.Action, "ErrorClass": entry.ErrorClass})
+	}
+
+	b, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		log.Printf("Failed to log request: %v", jsonErr)
+		return
+	}
+
+	fmt.Fprintln(args.requestLog(), string(b))
+}
+
+// errorClass groups errors by their cause, so that failures can be counted and searched for
+// without the details that make each error message unique.
+func errorClass(err error) string {
+	var (
+		validationErrs validator.ValidationErrors
+		syntaxErr      *json.SyntaxError
+		typeErr        *json.UnmarshalTypeError
+		awsErr         awserr.Error
+	)
+
+	switch {
+	case errors.As(err, &validationErrs):
+		return "Validation"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "Decode"
+	case errors.Is(err, errUnauthorized):
+		return "Unauthorized"
+	case errors.Is(err, errNoGame):
+		return "NoGame"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "Timeout"
+	case errors.As(err, &awsErr):
+		return "AWS." + awsErr.Code()
+	default:
+		return "Internal"
+	}
+}
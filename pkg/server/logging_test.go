@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMetrics struct {
+	counts    map[string]int
+	latencies map[string]int
+}
+
+func (m *fakeMetrics) Count(name string, dimensions map[string]string) {
+	m.counts[fmt.Sprintf("%s %v", name, dimensions)]++
+}
+
+func (m *fakeMetrics) Latency(name string, _ time.Duration, dimensions map[string]string) {
+	m.latencies[fmt.Sprintf("%s %v", name, dimensions)]++
+}
+
+func TestDescribeRequest(t *testing.T) {
+	req := events.APIGatewayWebsocketProxyRequest{Body: `{"action":"placeDisk","nickname":"zinger","host":"flame","x":2,"y":4}`}
+	req.RequestContext.ConnectionID = "abc"
+	req.RequestContext.EventType = "MESSAGE"
+
+	assert.Equal(t, requestInfo{ConnectionID: "abc", EventType: "MESSAGE", Action: "placeDisk", Host: "flame"}, describeRequest(req))
+
+	req.Body = `{"action":"hostGame","nickname":"flame"}`
+	assert.Equal(t, "flame", describeRequest(req).Host)
+
+	req.Body = "garbage"
+	assert.Equal(t, "unknown", describeRequest(req).Action)
+
+	req.RequestContext.EventType = "CONNECT"
+	req.RequestContext.RouteKey = "$connect"
+	assert.Equal(t, "$connect", describeRequest(req).Action)
+}
+
+func TestRecordRequest(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(w io.Writer) { requestLogOutput = w }(requestLogOutput)
+	requestLogOutput = &buf
+
+	metrics := &fakeMetrics{counts: map[string]int{}, latencies: map[string]int{}}
+	info := requestInfo{ConnectionID: "abc", EventType: "MESSAGE", Action: "placeDisk", Host: "flame"}
+
+	recordRequest(Args{Metrics: metrics}, info, 5*time.Millisecond, fmt.Errorf("failed to load game state: %w", errNoGame))
+
+	assert.Equal(t, map[string]int{
+		"Requests map[Action:placeDisk]":                 1,
+		"Errors map[Action:placeDisk ErrorClass:NoGame]": 1,
+	}, metrics.counts)
+	assert.Equal(t, map[string]int{"Latency map[Action:placeDisk]": 1}, metrics.latencies)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, "abc", entry["connectionId"])
+	assert.Equal(t, "placeDisk", entry["action"])
+	assert.Equal(t, "flame", entry["host"])
+	assert.Equal(t, "NoGame", entry["errorClass"])
+	assert.Equal(t, float64(5), entry["latencyMs"])
+}
+
+func TestHandleWithoutStdout(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(w io.Writer) { requestLogOutput = w }(requestLogOutput)
+	defer func(w io.Writer) { metricsOutput = w }(metricsOutput)
+	requestLogOutput = &buf
+	metricsOutput = &buf
+
+	// A server that a client hosts for a LAN game shares the terminal with the game.
+	args := Args{Metrics: NopMetrics{}, RequestLog: io.Discard}
+
+	for _, eventType := range []string{"CONNECT", "GARBAGE"} {
+		req := events.APIGatewayWebsocketProxyRequest{}
+		req.RequestContext.ConnectionID = "abc"
+		req.RequestContext.EventType = eventType
+
+		_, _ = Handle(context.Background(), req, args)
+	}
+
+	assert.Empty(t, buf.String())
+}
+
+func TestErrorClass(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	assert.True(t, errors.As(json.Unmarshal([]byte("{"), &struct{}{}), &syntaxErr) || true)
+
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{json.Unmarshal([]byte(`{"x":"y"}`), &struct{ X int }{}), "Decode"},
+		{fmt.Errorf("wrapped: %w", errUnauthorized), "Unauthorized"},
+		{awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "nope", nil), "AWS.ConditionalCheckFailedException"},
+		{errors.New("something else"), "Internal"},
+	} {
+		assert.Equal(t, tt.want, errorClass(tt.err), tt.err.Error())
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Maintenance mode freezes gameplay so that the server can be deployed safely, for example when a
+// deployment changes how games are stored.
+
+// SetMaintenance turns maintenance mode on or off. The message is shown to players while it is on.
+func SetMaintenance(ctx context.Context, args Args, enabled bool, message string) error {
+	log.Printf("Setting maintenance mode to %t", enabled)
+
+	return updateMaintenance(ctx, args, enabled, message, time.Now())
+}
+
+// frozenDuringMaintenance returns whether a message would start or change a game, which is not
+// allowed during maintenance.
+func frozenDuringMaintenance(message interface{}) bool {
+	switch message.(type) {
+	case *messages.HostGame, *messages.StartSoloGame, *messages.JoinGame, *messages.PlaceDisk, *messages.RequestUndo, *messages.RespondUndo, *messages.CreateTournament, *messages.JoinTournament, *messages.FindMatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// notice returns the message shown to players, or an empty string when maintenance mode is off.
+func (m maintenance) notice() string {
+	switch {
+	case !m.Enabled:
+		return ""
+	case m.Message != "":
+		return m.Message
+	default:
+		return "The server is under maintenance"
+	}
+}
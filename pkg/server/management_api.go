@@ -2,7 +2,7 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
@@ -17,45 +17,105 @@ import (
 )
 
 func broadcast(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, message interface{}, connectionIDs []string) error {
+	wrapper, data, err := encode(message)
+	if err != nil {
+		return err
+	}
+
 	// Send message to all connections concurrently.
 	group, groupCtx := errgroup.WithContext(ctx)
 	for _, connectionID := range connectionIDs {
-		// sendMessage happens in the background.
-		group.Go(sendMessage(groupCtx, reqCtx, args, connectionID, message))
+		connectionID := connectionID
+
+		// post happens in the background.
+		group.Go(func() error {
+			return post(groupCtx, reqCtx, args, connectionID, wrapper, data)
+		})
 	}
 
 	// Wait for all messages to finish sending.
 	return group.Wait()
 }
 
+// publish sends a message to every connection subscribed to a topic. Subscriptions belonging to
+// connections that have gone away are removed.
+func publish(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, topic string, message interface{}) error {
+	subscribers, err := getSubscribers(ctx, args, topic)
+	if err != nil {
+		return err
+	}
+
+	wrapper, data, err := encode(message)
+	if err != nil {
+		return err
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, sub := range subscribers {
+		connectionID := sub.ConnectionID
+
+		group.Go(func() error {
+			err := post(groupCtx, reqCtx, args, connectionID, wrapper, data)
+
+			var gone *apigatewaymanagementapi.GoneException
+			if errors.As(err, &gone) {
+				log.Printf("Removing subscription of gone connection %s to topic %q", connectionID, topic)
+				return unsubscribe(groupCtx, args, topic, connectionID)
+			}
+
+			return err
+		})
+	}
+
+	return group.Wait()
+}
+
 func reply(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, message interface{}) error {
 	return sendMessage(ctx, reqCtx, args, reqCtx.ConnectionID, message)()
 }
 
 func sendMessage(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, connectionID string, message interface{}) func() error {
 	return func() error {
-		log.Printf("Sending message %T to connection %s", message, connectionID)
-
-		data, err := json.Marshal(messages.Wrapper{Message: message})
+		wrapper, data, err := encode(message)
 		if err != nil {
 			return err
 		}
 
-		client := args.APIGatewayManagementAPIClientFactory(reqCtx)
-
-		_, err = client.PostToConnectionWithContext(ctx, &apigatewaymanagementapi.PostToConnectionInput{
-			ConnectionId: &connectionID,
-			Data:         data,
-		})
+		return post(ctx, reqCtx, args, connectionID, wrapper, data)
+	}
+}
 
-		return err
+// encode wraps a message and encodes it. Messages that are already wrapped have been numbered.
+func encode(message interface{}) (messages.Wrapper, []byte, error) {
+	wrapper, ok := message.(messages.Wrapper)
+	if !ok {
+		wrapper = messages.Wrapper{Message: message}
 	}
+
+	data, err := messages.Marshal(wrapper)
+
+	return wrapper, data, err
+}
+
+// post sends an encoded message to a connection.
+func post(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, connectionID string, wrapper messages.Wrapper, data []byte) error {
+	log.Printf("Sending message %T to connection %s", wrapper.Message, connectionID)
+
+	client := args.APIGatewayManagementAPIClientFactory(reqCtx)
+
+	_, err := client.PostToConnectionWithContext(ctx, &apigatewaymanagementapi.PostToConnectionInput{
+		ConnectionId: &connectionID,
+		Data:         data,
+	})
+
+	return err
 }
 
 type APIGatewayManagementAPIClientFactory func(events.APIGatewayWebsocketProxyRequestContext) APIGatewayManagementAPIClient
 
 type APIGatewayManagementAPIClient interface {
 	PostToConnectionWithContext(ctx aws.Context, input *apigatewaymanagementapi.PostToConnectionInput, opts ...request.Option) (*apigatewaymanagementapi.PostToConnectionOutput, error)
+	DeleteConnectionWithContext(ctx aws.Context, input *apigatewaymanagementapi.DeleteConnectionInput, opts ...request.Option) (*apigatewaymanagementapi.DeleteConnectionOutput, error)
 }
 
 func defaultAPIGatewayManagementAPIClientFactory() func(reqCtx events.APIGatewayWebsocketProxyRequestContext) APIGatewayManagementAPIClient {
@@ -0,0 +1,1198 @@
+package memdb
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// This file parses and evaluates the expressions that requests carry: update, condition, filter,
+// and projection expressions. It understands the grammar that the expression package of the AWS
+// SDK writes, which is all that the server sends.
+
+type item = map[string]*dynamodb.AttributeValue
+
+// tokens
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenName
+	tokenValue
+	tokenWord
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '#' || c == ':' || isWordRune(c):
+			j := i + 1
+			for j < len(s) && isWordRune(rune(s[j])) {
+				j++
+			}
+
+			kind := tokenWord
+			switch {
+			case c == '#':
+				kind = tokenName
+			case c == ':':
+				kind = tokenValue
+			case unicode.IsDigit(c):
+				kind = tokenNumber
+			}
+
+			tokens = append(tokens, token{kind, s[i:j]})
+			i = j
+
+		case strings.HasPrefix(s[i:], "<>") || strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			tokens = append(tokens, token{tokenPunct, s[i : i+2]})
+			i += 2
+
+		case strings.ContainsRune(".[](),=<>+-", c):
+			tokens = append(tokens, token{tokenPunct, s[i : i+1]})
+			i++
+
+		default:
+			return nil, fmt.Errorf("unexpected %q in expression %q", c, s)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd}), nil
+}
+
+func isWordRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// parser
+
+type parser struct {
+	tokens []token
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+func newParser(s *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*parser, error) {
+	tokens, err := tokenize(aws.StringValue(s))
+	if err != nil {
+		return nil, validationError(err.Error())
+	}
+	return &parser{tokens: tokens, names: names, values: values}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the keyword, which is matched regardless of case.
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// punct consumes the next token if it is the punctuation.
+func (p *parser) punct(s string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.punct(s) {
+		return p.errorf("expected %q", s)
+	}
+	return nil
+}
+
+func (p *parser) end() error {
+	if p.peek().kind != tokenEnd {
+		return p.errorf("unexpected %q", p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return validationError(fmt.Sprintf("invalid expression at token %d: %s", p.pos, fmt.Sprintf(format, a...)))
+}
+
+// paths
+
+type pathElem struct {
+	name  string
+	index int
+}
+
+// path is a document path such as a.b[2]. Elements that are list indexes have an empty name.
+type path []pathElem
+
+func (p *parser) path() (path, error) {
+	first, err := p.attributeName()
+	if err != nil {
+		return nil, err
+	}
+
+	result := path{{name: first}}
+
+	for {
+		switch {
+		case p.punct("."):
+			name, err := p.attributeName()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, pathElem{name: name})
+
+		case p.punct("["):
+			t := p.next()
+			if t.kind != tokenNumber {
+				return nil, p.errorf("expected a list index")
+			}
+			index, err := strconv.Atoi(t.text)
+			if err != nil {
+				return nil, p.errorf("bad list index %q", t.text)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			result = append(result, pathElem{index: index})
+
+		default:
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) attributeName() (string, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenName:
+		name, ok := p.names[t.text]
+		if !ok {
+			return "", p.errorf("undefined attribute name %s", t.text)
+		}
+		return aws.StringValue(name), nil
+
+	case tokenWord:
+		return t.text, nil
+
+	default:
+		return "", p.errorf("expected an attribute name")
+	}
+}
+
+// get returns the value at a path, or nil if there isn't one.
+func (pa path) get(it item) *dynamodb.AttributeValue {
+	v := it[pa[0].name]
+
+	for _, e := range pa[1:] {
+		switch {
+		case v == nil:
+			return nil
+		case e.name != "":
+			v = v.M[e.name]
+		case e.index < len(v.L):
+			v = v.L[e.index]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+// set puts a value at a path. The parent of the path must already exist.
+func (pa path) set(it item, v *dynamodb.AttributeValue) error {
+	if len(pa) == 1 {
+		it[pa[0].name] = v
+		return nil
+	}
+
+	parent := pa[:len(pa)-1].get(it)
+	last := pa[len(pa)-1]
+
+	switch {
+	case parent == nil:
+		return validationError("The document path provided in the update expression is invalid for update")
+	case last.name != "" && parent.M != nil:
+		parent.M[last.name] = v
+	case last.name == "" && parent.L != nil:
+		if last.index < len(parent.L) {
+			parent.L[last.index] = v
+		} else {
+			parent.L = append(parent.L, v)
+		}
+	default:
+		return validationError("The document path provided in the update expression is invalid for update")
+	}
+
+	return nil
+}
+
+// remove deletes the value at a path, if there is one.
+func (pa path) remove(it item) {
+	if len(pa) == 1 {
+		delete(it, pa[0].name)
+		return
+	}
+
+	parent := pa[:len(pa)-1].get(it)
+	last := pa[len(pa)-1]
+
+	switch {
+	case parent == nil:
+	case last.name != "":
+		delete(parent.M, last.name)
+	case last.index < len(parent.L):
+		parent.L = append(parent.L[:last.index], parent.L[last.index+1:]...)
+	}
+}
+
+// operands
+
+type operand func(it item) (*dynamodb.AttributeValue, error)
+
+// operand parses a value, a path, or a function that returns a value.
+func (p *parser) operand() (operand, error) {
+	t := p.peek()
+
+	if t.kind == tokenValue {
+		p.next()
+		v, ok := p.values[t.text]
+		if !ok {
+			return nil, p.errorf("undefined attribute value %s", t.text)
+		}
+		return func(item) (*dynamodb.AttributeValue, error) { return v, nil }, nil
+	}
+
+	if t.kind == tokenWord && p.tokens[p.pos+1].text == "(" {
+		return p.function()
+	}
+
+	pa, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(it item) (*dynamodb.AttributeValue, error) { return pa.get(it), nil }, nil
+}
+
+func (p *parser) function() (operand, error) {
+	name := strings.ToLower(p.next().text)
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var f operand
+
+	switch name {
+	case "size":
+		pa, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		f = func(it item) (*dynamodb.AttributeValue, error) {
+			v := pa.get(it)
+			if v == nil {
+				return nil, nil
+			}
+			return numberValue(big.NewFloat(float64(size(v)))), nil
+		}
+
+	case "if_not_exists":
+		pa, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		fallback, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		f = func(it item) (*dynamodb.AttributeValue, error) {
+			if v := pa.get(it); v != nil {
+				return v, nil
+			}
+			return fallback(it)
+		}
+
+	case "list_append":
+		a, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		b, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		f = func(it item) (*dynamodb.AttributeValue, error) {
+			av, err := a(it)
+			if err != nil {
+				return nil, err
+			}
+			bv, err := b(it)
+			if err != nil {
+				return nil, err
+			}
+			if av == nil || bv == nil || av.L == nil || bv.L == nil {
+				return nil, validationError("An operand in the update expression has an incorrect data type")
+			}
+			l := append(append([]*dynamodb.AttributeValue{}, av.L...), bv.L...)
+			return &dynamodb.AttributeValue{L: l}, nil
+		}
+
+	default:
+		return nil, p.errorf("unsupported function %s", name)
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// conditions
+
+type condition func(it item) (bool, error)
+
+// parseCondition parses a condition or filter expression. An empty expression always holds.
+func parseCondition(s *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (condition, error) {
+	if aws.StringValue(s) == "" {
+		return func(item) (bool, error) { return true, nil }, nil
+	}
+
+	p, err := newParser(s, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, p.end()
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(it item) (bool, error) {
+			if ok, err := l(it); err != nil || ok {
+				return ok, err
+			}
+			return right(it)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(it item) (bool, error) {
+			if ok, err := l(it); err != nil || !ok {
+				return ok, err
+			}
+			return right(it)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if !p.keyword("NOT") {
+		return p.primary()
+	}
+
+	c, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(it item) (bool, error) {
+		ok, err := c(it)
+		return !ok, err
+	}, nil
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.punct("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+
+	t := p.peek()
+	if t.kind == tokenWord && p.tokens[p.pos+1].text == "(" {
+		switch strings.ToLower(t.text) {
+		case "attribute_exists", "attribute_not_exists", "begins_with", "contains":
+			return p.conditionFunction()
+		}
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.keyword("BETWEEN"):
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, p.errorf("expected AND")
+		}
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(it item) (bool, error) {
+			vs, err := evalAll(it, left, low, high)
+			if err != nil {
+				return false, err
+			}
+			lo, ok1 := compare(vs[0], vs[1])
+			hi, ok2 := compare(vs[0], vs[2])
+			return ok1 && ok2 && lo >= 0 && hi <= 0, nil
+		}, nil
+
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var list []operand
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, o)
+			if !p.punct(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(it item) (bool, error) {
+			v, err := left(it)
+			if err != nil || v == nil {
+				return false, err
+			}
+			for _, o := range list {
+				candidate, err := o(it)
+				if err != nil {
+					return false, err
+				}
+				if equal(v, candidate) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokenPunct {
+		return nil, p.errorf("expected a comparison")
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	var test func(a, b *dynamodb.AttributeValue) bool
+
+	switch op.text {
+	case "=":
+		test = func(a, b *dynamodb.AttributeValue) bool { return a != nil && equal(a, b) }
+	case "<>":
+		test = func(a, b *dynamodb.AttributeValue) bool { return !equal(a, b) }
+	case "<", "<=", ">", ">=":
+		test = func(a, b *dynamodb.AttributeValue) bool {
+			c, ok := compare(a, b)
+			if !ok {
+				return false
+			}
+			switch op.text {
+			case "<":
+				return c < 0
+			case "<=":
+				return c <= 0
+			case ">":
+				return c > 0
+			default:
+				return c >= 0
+			}
+		}
+	default:
+		return nil, p.errorf("unexpected %q", op.text)
+	}
+
+	return func(it item) (bool, error) {
+		vs, err := evalAll(it, left, right)
+		if err != nil {
+			return false, err
+		}
+		return test(vs[0], vs[1]), nil
+	}, nil
+}
+
+func (p *parser) conditionFunction() (condition, error) {
+	name := strings.ToLower(p.next().text)
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	pa, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	var c condition
+
+	switch name {
+	case "attribute_exists":
+		c = func(it item) (bool, error) { return pa.get(it) != nil, nil }
+
+	case "attribute_not_exists":
+		c = func(it item) (bool, error) { return pa.get(it) == nil, nil }
+
+	case "begins_with", "contains":
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		o, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		c = func(it item) (bool, error) {
+			v := pa.get(it)
+			arg, err := o(it)
+			if err != nil || v == nil || arg == nil {
+				return false, err
+			}
+			if name == "begins_with" {
+				return beginsWith(v, arg), nil
+			}
+			return contains(v, arg), nil
+		}
+	}
+
+	return c, p.expect(")")
+}
+
+func evalAll(it item, operands ...operand) ([]*dynamodb.AttributeValue, error) {
+	vs := make([]*dynamodb.AttributeValue, len(operands))
+	for i, o := range operands {
+		v, err := o(it)
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
+
+// updates
+
+type update func(it item) error
+
+// parseUpdate parses an update expression into the actions that it takes, in order.
+func parseUpdate(s *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (update, error) {
+	p, err := newParser(s, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []action
+
+	for p.peek().kind != tokenEnd {
+		var parse func() (action, error)
+
+		switch {
+		case p.keyword("SET"):
+			parse = p.setAction
+		case p.keyword("REMOVE"):
+			parse = p.removeAction
+		case p.keyword("ADD"):
+			parse = p.addAction
+		case p.keyword("DELETE"):
+			parse = p.deleteAction
+		default:
+			return nil, p.errorf("expected SET, REMOVE, ADD, or DELETE")
+		}
+
+		for {
+			a, err := parse()
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, a)
+			if !p.punct(",") {
+				break
+			}
+		}
+	}
+
+	// Every action sees the item as it was before the update, like in DynamoDB.
+	return func(it item) error {
+		before := copyItem(it)
+		for _, a := range actions {
+			if err := a(before, it); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// actions read from the item as it was before the update and write to the updated item.
+type action = func(before, after item) error
+
+func (p *parser) setAction() (action, error) {
+	return p.action(func(pa path) (action, error) {
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+
+		left, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		var sign int64
+		switch {
+		case p.punct("+"):
+			sign = 1
+		case p.punct("-"):
+			sign = -1
+		}
+
+		var right operand
+		if sign != 0 {
+			if right, err = p.operand(); err != nil {
+				return nil, err
+			}
+		}
+
+		return func(before, after item) error {
+			v, err := left(before)
+			if err != nil {
+				return err
+			}
+
+			if right != nil {
+				r, err := right(before)
+				if err != nil {
+					return err
+				}
+				a, ok1 := number(v)
+				b, ok2 := number(r)
+				if !ok1 || !ok2 {
+					return validationError("An operand in the update expression has an incorrect data type")
+				}
+				v = numberValue(a.Add(a, b.Mul(b, big.NewFloat(float64(sign)))))
+			}
+
+			if v == nil {
+				return validationError("The provided expression refers to an attribute that does not exist in the item")
+			}
+
+			return pa.set(after, copyValue(v))
+		}, nil
+	})
+}
+
+func (p *parser) removeAction() (action, error) {
+	return p.action(func(pa path) (action, error) {
+		return func(_, after item) error {
+			pa.remove(after)
+			return nil
+		}, nil
+	})
+}
+
+func (p *parser) addAction() (action, error) {
+	return p.action(func(pa path) (action, error) {
+		o, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(before, after item) error {
+			v, err := o(before)
+			if err != nil {
+				return err
+			}
+
+			old := pa.get(before)
+
+			if v.N != nil {
+				sum, _ := number(v)
+				if old != nil {
+					n, ok := number(old)
+					if !ok {
+						return validationError("An operand in the update expression has an incorrect data type")
+					}
+					sum.Add(sum, n)
+				}
+				return pa.set(after, numberValue(sum))
+			}
+
+			if old == nil {
+				return pa.set(after, copyValue(v))
+			}
+
+			union, ok := addToSet(old, v)
+			if !ok {
+				return validationError("An operand in the update expression has an incorrect data type")
+			}
+			return pa.set(after, union)
+		}, nil
+	})
+}
+
+func (p *parser) deleteAction() (action, error) {
+	return p.action(func(pa path) (action, error) {
+		o, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(before, after item) error {
+			v, err := o(before)
+			if err != nil {
+				return err
+			}
+
+			old := pa.get(before)
+			if old == nil {
+				return nil
+			}
+
+			difference, ok := deleteFromSet(old, v)
+			switch {
+			case !ok:
+				return validationError("An operand in the update expression has an incorrect data type")
+			case difference == nil:
+				pa.remove(after)
+				return nil
+			default:
+				return pa.set(after, difference)
+			}
+		}, nil
+	})
+}
+
+// action parses a path followed by the rest of an action, which parse parses.
+func (p *parser) action(parse func(pa path) (action, error)) (action, error) {
+	pa, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(pa)
+}
+
+// projections
+
+// project returns the top-level attributes of an item that a projection expression names. An empty
+// expression names every attribute.
+func project(it item, s *string, names map[string]*string) (item, error) {
+	if it == nil || aws.StringValue(s) == "" {
+		return it, nil
+	}
+
+	p, err := newParser(s, names, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	projected := item{}
+
+	for {
+		pa, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+
+		if v := it[pa[0].name]; v != nil {
+			projected[pa[0].name] = v
+		}
+
+		if !p.punct(",") {
+			break
+		}
+	}
+
+	return projected, p.end()
+}
+
+// values
+
+func number(v *dynamodb.AttributeValue) (*big.Float, bool) {
+	if v == nil || v.N == nil {
+		return nil, false
+	}
+	f, _, err := big.ParseFloat(*v.N, 10, 256, big.ToNearestEven)
+	return f, err == nil
+}
+
+func numberValue(f *big.Float) *dynamodb.AttributeValue {
+	if f.IsInt() {
+		i, _ := f.Int(nil)
+		return &dynamodb.AttributeValue{N: aws.String(i.String())}
+	}
+	return &dynamodb.AttributeValue{N: aws.String(f.Text('g', -1))}
+}
+
+// compare orders two scalars of the same type. It is not ok if they can't be ordered.
+func compare(a, b *dynamodb.AttributeValue) (int, bool) {
+	switch {
+	case a == nil || b == nil:
+		return 0, false
+	case a.N != nil && b.N != nil:
+		x, ok1 := number(a)
+		y, ok2 := number(b)
+		return x.Cmp(y), ok1 && ok2
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S), true
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B), true
+	default:
+		return 0, false
+	}
+}
+
+func equal(a, b *dynamodb.AttributeValue) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+
+	switch {
+	case a.BOOL != nil && b.BOOL != nil:
+		return *a.BOOL == *b.BOOL
+	case a.NULL != nil && b.NULL != nil:
+		return true
+	case a.SS != nil && b.SS != nil, a.NS != nil && b.NS != nil, a.BS != nil && b.BS != nil:
+		return equalStrings(setMembers(a), setMembers(b))
+	case a.L != nil && b.L != nil:
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for i := range a.L {
+			if !equal(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case a.M != nil && b.M != nil:
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for k, v := range a.M {
+			if !equal(v, b.M[k]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func size(v *dynamodb.AttributeValue) int {
+	switch {
+	case v.S != nil:
+		return len(*v.S)
+	case v.B != nil:
+		return len(v.B)
+	case v.L != nil:
+		return len(v.L)
+	case v.M != nil:
+		return len(v.M)
+	default:
+		return len(setMembers(v))
+	}
+}
+
+func beginsWith(v, prefix *dynamodb.AttributeValue) bool {
+	switch {
+	case v.S != nil && prefix.S != nil:
+		return strings.HasPrefix(*v.S, *prefix.S)
+	case v.B != nil && prefix.B != nil:
+		return bytes.HasPrefix(v.B, prefix.B)
+	default:
+		return false
+	}
+}
+
+func contains(v, member *dynamodb.AttributeValue) bool {
+	switch {
+	case v.S != nil && member.S != nil:
+		return strings.Contains(*v.S, *member.S)
+	case v.B != nil && member.B != nil:
+		return bytes.Contains(v.B, member.B)
+	case v.L != nil:
+		for _, e := range v.L {
+			if equal(e, member) {
+				return true
+			}
+		}
+		return false
+	default:
+		key, ok := scalarKey(member)
+		if !ok {
+			return false
+		}
+		for _, m := range setMembers(v) {
+			if m == key {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Sets are compared and combined by the keys of their members, which are the members as strings,
+// with numbers normalized.
+
+func setMembers(v *dynamodb.AttributeValue) []string {
+	var members []string
+
+	switch {
+	case v.SS != nil:
+		for _, s := range v.SS {
+			members = append(members, aws.StringValue(s))
+		}
+	case v.NS != nil:
+		for _, n := range v.NS {
+			key, _ := scalarKey(&dynamodb.AttributeValue{N: n})
+			members = append(members, key)
+		}
+	case v.BS != nil:
+		for _, b := range v.BS {
+			members = append(members, string(b))
+		}
+	}
+
+	sort.Strings(members)
+
+	return members
+}
+
+func scalarKey(v *dynamodb.AttributeValue) (string, bool) {
+	switch {
+	case v.S != nil:
+		return *v.S, true
+	case v.N != nil:
+		n, ok := number(v)
+		if !ok {
+			return "", false
+		}
+		return *numberValue(n).N, true
+	case v.B != nil:
+		return string(v.B), true
+	default:
+		return "", false
+	}
+}
+
+// setOf builds a set of the same type as like out of member keys. It returns nil if there are no
+// members, since sets can't be empty.
+func setOf(like *dynamodb.AttributeValue, members []string) *dynamodb.AttributeValue {
+	if len(members) == 0 {
+		return nil
+	}
+
+	switch {
+	case like.SS != nil:
+		return &dynamodb.AttributeValue{SS: aws.StringSlice(members)}
+	case like.NS != nil:
+		return &dynamodb.AttributeValue{NS: aws.StringSlice(members)}
+	default:
+		bs := make([][]byte, len(members))
+		for i, m := range members {
+			bs[i] = []byte(m)
+		}
+		return &dynamodb.AttributeValue{BS: bs}
+	}
+}
+
+func sameSetType(a, b *dynamodb.AttributeValue) bool {
+	return a.SS != nil && b.SS != nil || a.NS != nil && b.NS != nil || a.BS != nil && b.BS != nil
+}
+
+func addToSet(set, add *dynamodb.AttributeValue) (*dynamodb.AttributeValue, bool) {
+	if !sameSetType(set, add) {
+		return nil, false
+	}
+
+	members := setMembers(set)
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		seen[m] = true
+	}
+
+	for _, m := range setMembers(add) {
+		if !seen[m] {
+			seen[m] = true
+			members = append(members, m)
+		}
+	}
+
+	sort.Strings(members)
+
+	return setOf(set, members), true
+}
+
+func deleteFromSet(set, remove *dynamodb.AttributeValue) (*dynamodb.AttributeValue, bool) {
+	if !sameSetType(set, remove) {
+		return nil, false
+	}
+
+	removed := make(map[string]bool)
+	for _, m := range setMembers(remove) {
+		removed[m] = true
+	}
+
+	var members []string
+	for _, m := range setMembers(set) {
+		if !removed[m] {
+			members = append(members, m)
+		}
+	}
+
+	return setOf(set, members), true
+}
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	c := make(item, len(it))
+	for k, v := range it {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+func copyValue(v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if v == nil {
+		return nil
+	}
+
+	c := *v
+
+	if v.B != nil {
+		c.B = append([]byte{}, v.B...)
+	}
+	if v.SS != nil {
+		c.SS = aws.StringSlice(aws.StringValueSlice(v.SS))
+	}
+	if v.NS != nil {
+		c.NS = aws.StringSlice(aws.StringValueSlice(v.NS))
+	}
+	if v.BS != nil {
+		c.BS = make([][]byte, len(v.BS))
+		for i, b := range v.BS {
+			c.BS[i] = append([]byte{}, b...)
+		}
+	}
+	if v.L != nil {
+		c.L = make([]*dynamodb.AttributeValue, len(v.L))
+		for i, e := range v.L {
+			c.L[i] = copyValue(e)
+		}
+	}
+	if v.M != nil {
+		c.M = copyItem(v.M)
+	}
+
+	return &c
+}
@@ -0,0 +1,631 @@
+// Package memdb keeps DynamoDB tables in memory, so that the server can run on its own, such as for
+// a LAN game, without DynamoDB or DynamoDB Local. It implements the requests and expressions that
+// the server makes, and nothing more. Other requests panic.
+//
+// Items are lost when the process exits.
+package memdb
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// maxBatchGetKeys is the most keys that a BatchGetItem request may ask for, like in DynamoDB.
+const maxBatchGetKeys = 100
+
+// expiredGrace is how long an item is kept after its TTL passes. DynamoDB takes a while to delete
+// expired items too, and the server doesn't rely on them being gone.
+const expiredGrace = time.Hour
+
+// sweepEvery is how many writes to a table there are between sweeps of its expired items.
+const sweepEvery = 1000
+
+// DB is an in-memory DynamoDB. The zero value has no tables; create them with CreateTable.
+type DB struct {
+	// Methods of DynamoDBAPI that DB doesn't implement call the nil interface, which panics.
+	dynamodbiface.DynamoDBAPI
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+type table struct {
+	hashKey string
+	indexes map[string]index
+
+	// ttl is the attribute that holds the time an item expires, if TTL is enabled.
+	ttl string
+
+	items  map[string]item
+	writes int
+}
+
+type index struct {
+	hashKey, rangeKey string
+
+	// projection is the non-key attributes that the index has, or nil if it has all of them.
+	projection []string
+}
+
+// New returns an empty in-memory DynamoDB.
+func New() *DB {
+	return &DB{}
+}
+
+// CreateTableWithContext creates a table with a hash key and global secondary indexes.
+func (db *DB) CreateTableWithContext(_ aws.Context, input *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	name := aws.StringValue(input.TableName)
+	if _, ok := db.tables[name]; ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "Cannot create preexisting table", nil)
+	}
+
+	hashKey, rangeKey := keySchema(input.KeySchema)
+	if hashKey == "" || rangeKey != "" {
+		return nil, validationError("only tables with a hash key and no range key are supported")
+	}
+
+	t := &table{hashKey: hashKey, indexes: map[string]index{}, items: map[string]item{}}
+
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		var idx index
+		idx.hashKey, idx.rangeKey = keySchema(gsi.KeySchema)
+
+		switch aws.StringValue(gsi.Projection.ProjectionType) {
+		case dynamodb.ProjectionTypeKeysOnly:
+			idx.projection = []string{}
+		case dynamodb.ProjectionTypeInclude:
+			idx.projection = aws.StringValueSlice(gsi.Projection.NonKeyAttributes)
+		}
+
+		t.indexes[aws.StringValue(gsi.IndexName)] = idx
+	}
+
+	if db.tables == nil {
+		db.tables = map[string]*table{}
+	}
+	db.tables[name] = t
+
+	return &dynamodb.CreateTableOutput{TableDescription: &dynamodb.TableDescription{
+		TableName:   input.TableName,
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+	}}, nil
+}
+
+// CreateTable is like CreateTableWithContext.
+func (db *DB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	return db.CreateTableWithContext(aws.BackgroundContext(), input)
+}
+
+// DeleteTableWithContext deletes a table and its items.
+func (db *DB) DeleteTableWithContext(_ aws.Context, input *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.table(input.TableName); err != nil {
+		return nil, err
+	}
+
+	delete(db.tables, aws.StringValue(input.TableName))
+
+	return &dynamodb.DeleteTableOutput{}, nil
+}
+
+// DeleteTable is like DeleteTableWithContext.
+func (db *DB) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	return db.DeleteTableWithContext(aws.BackgroundContext(), input)
+}
+
+// UpdateTimeToLiveWithContext sets the attribute that holds the time an item expires.
+func (db *DB) UpdateTimeToLiveWithContext(_ aws.Context, input *dynamodb.UpdateTimeToLiveInput, _ ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	t.ttl = ""
+	if aws.BoolValue(input.TimeToLiveSpecification.Enabled) {
+		t.ttl = aws.StringValue(input.TimeToLiveSpecification.AttributeName)
+	}
+
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: input.TimeToLiveSpecification}, nil
+}
+
+// GetItemWithContext returns an item, or no item if there isn't one with the key.
+func (db *DB) GetItemWithContext(_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := project(t.items[key], input.ProjectionExpression, input.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: copyItem(it)}, nil
+}
+
+// GetItem is like GetItemWithContext.
+func (db *DB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return db.GetItemWithContext(aws.BackgroundContext(), input)
+}
+
+// PutItemWithContext replaces an item, if the condition holds.
+func (db *DB) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(map[string]*dynamodb.AttributeValue{t.hashKey: input.Item[t.hashKey]})
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+
+	if err := check(old, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	t.put(key, copyItem(input.Item))
+
+	output := &dynamodb.PutItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+
+	return output, nil
+}
+
+// PutItem is like PutItemWithContext.
+func (db *DB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return db.PutItemWithContext(aws.BackgroundContext(), input)
+}
+
+// UpdateItemWithContext changes an item, or creates it if there isn't one with the key, if the
+// condition holds.
+func (db *DB) UpdateItemWithContext(_ aws.Context, input *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := parseUpdate(input.UpdateExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+
+	if err := check(old, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	updated := copyItem(old)
+	if updated == nil {
+		updated = copyItem(input.Key)
+	}
+
+	if err := update(updated); err != nil {
+		return nil, err
+	}
+
+	for k, v := range input.Key {
+		if !equal(updated[k], v) {
+			return nil, validationError("Cannot update attribute " + k + ". This attribute is part of the key")
+		}
+	}
+
+	t.put(key, updated)
+
+	output := &dynamodb.UpdateItemOutput{}
+
+	switch returnValues := aws.StringValue(input.ReturnValues); returnValues {
+	case "", dynamodb.ReturnValueNone:
+	case dynamodb.ReturnValueAllOld:
+		output.Attributes = copyItem(old)
+	case dynamodb.ReturnValueAllNew:
+		output.Attributes = copyItem(updated)
+	default:
+		return nil, validationError("unsupported ReturnValues " + returnValues)
+	}
+
+	return output, nil
+}
+
+// UpdateItem is like UpdateItemWithContext.
+func (db *DB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return db.UpdateItemWithContext(aws.BackgroundContext(), input)
+}
+
+// DeleteItemWithContext deletes an item, if the condition holds.
+func (db *DB) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := t.key(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+
+	if err := check(old, input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+
+	delete(t.items, key)
+
+	output := &dynamodb.DeleteItemOutput{}
+	if aws.StringValue(input.ReturnValues) == dynamodb.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+
+	return output, nil
+}
+
+// DeleteItem is like DeleteItemWithContext.
+func (db *DB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return db.DeleteItemWithContext(aws.BackgroundContext(), input)
+}
+
+// BatchGetItemWithContext returns the items with the keys. Every key is always processed.
+func (db *DB) BatchGetItemWithContext(_ aws.Context, input *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	count := 0
+	for _, request := range input.RequestItems {
+		count += len(request.Keys)
+	}
+
+	if count > maxBatchGetKeys {
+		return nil, validationError("Too many items requested for the BatchGetItem call")
+	}
+
+	output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+
+	for name, request := range input.RequestItems {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		output.Responses[name] = []map[string]*dynamodb.AttributeValue{}
+
+		for _, k := range request.Keys {
+			key, err := t.key(k)
+			if err != nil {
+				return nil, err
+			}
+
+			it, err := project(t.items[key], request.ProjectionExpression, request.ExpressionAttributeNames)
+			if err != nil {
+				return nil, err
+			}
+
+			if it != nil {
+				output.Responses[name] = append(output.Responses[name], copyItem(it))
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// QueryWithContext returns the items of a table or index whose keys match the key conditions,
+// ordered by range key.
+func (db *DB) QueryWithContext(_ aws.Context, input *dynamodb.QueryInput, _ ...request.Option) (*dynamodb.QueryOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := index{hashKey: t.hashKey}
+	if input.IndexName != nil {
+		var ok bool
+		if idx, ok = t.indexes[*input.IndexName]; !ok {
+			return nil, validationError("The table does not have the specified index: " + *input.IndexName)
+		}
+	}
+
+	var match condition
+	if input.KeyConditionExpression != nil {
+		match, err = parseCondition(input.KeyConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	} else {
+		match, err = keyConditions(input.KeyConditions)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseCondition(input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []item
+
+	for _, it := range t.sorted() {
+		if it[idx.hashKey] == nil || idx.rangeKey != "" && it[idx.rangeKey] == nil {
+			continue
+		}
+
+		it = t.project(it, idx)
+
+		if ok, err := match(it); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if ok, err := filter(it); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if it, err = project(it, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+			return nil, err
+		}
+
+		items = append(items, copyItem(it))
+	}
+
+	if idx.rangeKey != "" {
+		sort.SliceStable(items, func(i, j int) bool {
+			c, _ := compare(items[i][idx.rangeKey], items[j][idx.rangeKey])
+			return c < 0
+		})
+	}
+
+	if input.ScanIndexForward != nil && !*input.ScanIndexForward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	return &dynamodb.QueryOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+// ScanWithContext returns the items of a table that match the filter.
+func (db *DB) ScanWithContext(_ aws.Context, input *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	t, err := db.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseCondition(input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []item
+
+	for _, it := range t.sorted() {
+		if ok, err := filter(it); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if it, err = project(it, input.ProjectionExpression, input.ExpressionAttributeNames); err != nil {
+			return nil, err
+		}
+
+		items = append(items, copyItem(it))
+	}
+
+	return &dynamodb.ScanOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+// Scan is like ScanWithContext.
+func (db *DB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return db.ScanWithContext(aws.BackgroundContext(), input)
+}
+
+// ScanPagesWithContext is like ScanWithContext, with every item on one page.
+func (db *DB) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, _ ...request.Option) error {
+	output, err := db.ScanWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	fn(output, true)
+
+	return nil
+}
+
+// table returns a table. The caller must hold the lock.
+func (db *DB) table(name *string) (*table, error) {
+	t, ok := db.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Cannot do operations on a non-existent table", nil)
+	}
+	return t, nil
+}
+
+// key returns the hash key of an item as a string, which is the key of the item in the table.
+func (t *table) key(key map[string]*dynamodb.AttributeValue) (string, error) {
+	v, ok := key[t.hashKey]
+	if !ok || len(key) != 1 {
+		return "", validationError("The provided key element does not match the schema")
+	}
+
+	switch {
+	case v.S != nil:
+		return "S" + *v.S, nil
+	case v.N != nil:
+		k, ok := scalarKey(v)
+		if !ok {
+			return "", validationError("The provided key element is not a number")
+		}
+		return "N" + k, nil
+	case v.B != nil:
+		return "B" + string(v.B), nil
+	default:
+		return "", validationError("The provided key element does not match the schema")
+	}
+}
+
+// put saves an item, and now and then deletes the items that have long since expired.
+func (t *table) put(key string, it item) {
+	t.items[key] = it
+
+	if t.writes++; t.writes%sweepEvery == 0 {
+		t.sweep(time.Now())
+	}
+}
+
+func (t *table) sweep(now time.Time) {
+	if t.ttl == "" {
+		return
+	}
+
+	for key, it := range t.items {
+		v := it[t.ttl]
+		if v == nil || v.N == nil {
+			continue
+		}
+
+		expires, err := strconv.ParseInt(*v.N, 10, 64)
+		if err == nil && now.Sub(time.Unix(expires, 0)) > expiredGrace {
+			delete(t.items, key)
+		}
+	}
+}
+
+// sorted returns the items of a table, ordered by key so that results don't change between calls.
+func (t *table) sorted() []item {
+	keys := make([]string, 0, len(t.items))
+	for k := range t.items {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	items := make([]item, len(keys))
+	for i, k := range keys {
+		items[i] = t.items[k]
+	}
+
+	return items
+}
+
+// project returns the attributes of an item that an index has.
+func (t *table) project(it item, idx index) item {
+	if idx.projection == nil {
+		return it
+	}
+
+	projected := item{}
+	for _, attrib := range append([]string{t.hashKey, idx.hashKey, idx.rangeKey}, idx.projection...) {
+		if v := it[attrib]; v != nil {
+			projected[attrib] = v
+		}
+	}
+
+	return projected
+}
+
+func keySchema(schema []*dynamodb.KeySchemaElement) (hashKey, rangeKey string) {
+	for _, e := range schema {
+		switch aws.StringValue(e.KeyType) {
+		case dynamodb.KeyTypeHash:
+			hashKey = aws.StringValue(e.AttributeName)
+		case dynamodb.KeyTypeRange:
+			rangeKey = aws.StringValue(e.AttributeName)
+		}
+	}
+	return hashKey, rangeKey
+}
+
+// keyConditions returns a condition that matches the legacy KeyConditions of a query.
+func keyConditions(conditions map[string]*dynamodb.Condition) (condition, error) {
+	for _, c := range conditions {
+		if aws.StringValue(c.ComparisonOperator) != dynamodb.ComparisonOperatorEq || len(c.AttributeValueList) != 1 {
+			return nil, validationError("only EQ key conditions are supported")
+		}
+	}
+
+	return func(it item) (bool, error) {
+		for attrib, c := range conditions {
+			if !equal(it[attrib], c.AttributeValueList[0]) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+// check returns a ConditionalCheckFailedException if a condition doesn't hold for an item, which is
+// nil if there is no item.
+func check(it item, expr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) error {
+	c, err := parseCondition(expr, names, values)
+	if err != nil {
+		return err
+	}
+
+	ok, err := c(it)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+
+	return nil
+}
+
+func validationError(message string) error {
+	return awserr.New("ValidationException", message, nil)
+}
@@ -0,0 +1,187 @@
+package memdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTable(t *testing.T) *DB {
+	db := New()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("T"),
+		KeySchema: []*dynamodb.KeySchemaElement{{AttributeName: aws.String("Host"), KeyType: aws.String(dynamodb.KeyTypeHash)}},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String("ByTopic"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("Topic"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				{AttributeName: aws.String("Host"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+			Projection: &dynamodb.Projection{
+				ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+				NonKeyAttributes: aws.StringSlice([]string{"Nickname"}),
+			},
+		}},
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func updateItem(db *DB, host string, update expression.UpdateBuilder, condition *expression.ConditionBuilder) (map[string]*dynamodb.AttributeValue, error) {
+	builder := expression.NewBuilder().WithUpdate(update)
+	if condition != nil {
+		builder = builder.WithCondition(*condition)
+	}
+
+	exp, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := db.UpdateItemWithContext(context.Background(), &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("T"),
+		Key:                       map[string]*dynamodb.AttributeValue{"Host": {S: aws.String(host)}},
+		UpdateExpression:          exp.Update(),
+		ConditionExpression:       exp.Condition(),
+		ExpressionAttributeNames:  exp.Names(),
+		ExpressionAttributeValues: exp.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Attributes, nil
+}
+
+func TestUpdate(t *testing.T) {
+	db := newTable(t)
+
+	item, err := updateItem(db, "a", expression.
+		Set(expression.Name("Connections"), expression.Value(map[string]string{"andy": "1"})).
+		Set(expression.Name("Events"), expression.ListAppend(
+			expression.IfNotExists(expression.Name("Events"), expression.Value(&dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}})),
+			expression.Value([]string{"x"}))).
+		Add(expression.Name("Seq"), expression.Value(1)).
+		Add(expression.Name("Topics"), expression.Value(&dynamodb.AttributeValue{SS: aws.StringSlice([]string{"t1", "t2"})})), nil)
+	require.NoError(t, err)
+
+	item, err = updateItem(db, "a", expression.
+		Set(expression.Name("Connections.bob"), expression.Value("2")).
+		Set(expression.Name("Events"), expression.ListAppend(
+			expression.IfNotExists(expression.Name("Events"), expression.Value(&dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}})),
+			expression.Value([]string{"y"}))).
+		Add(expression.Name("Seq"), expression.Value(2)).
+		Delete(expression.Name("Topics"), expression.Value(&dynamodb.AttributeValue{SS: aws.StringSlice([]string{"t1"})})).
+		Remove(expression.Name("Missing")), nil)
+	require.NoError(t, err)
+
+	var got struct {
+		Host        string
+		Connections map[string]string
+		Events      []string
+		Seq         int
+		Topics      []string `dynamodbav:",stringset"`
+	}
+	require.NoError(t, dynamodbattribute.UnmarshalMap(item, &got))
+
+	assert.Equal(t, "a", got.Host)
+	assert.Equal(t, map[string]string{"andy": "1", "bob": "2"}, got.Connections)
+	assert.Equal(t, []string{"x", "y"}, got.Events)
+	assert.Equal(t, 3, got.Seq)
+	assert.Equal(t, []string{"t2"}, got.Topics)
+}
+
+func TestUpdateCondition(t *testing.T) {
+	db := newTable(t)
+
+	notExists := expression.Name("Host").AttributeNotExists()
+
+	_, err := updateItem(db, "a", expression.Set(expression.Name("Opponent"), expression.Value("bob")), &notExists)
+	require.NoError(t, err)
+
+	_, err = updateItem(db, "a", expression.Set(expression.Name("Opponent"), expression.Value("carl")), &notExists)
+	assertConditionFailed(t, err)
+
+	in := expression.In(expression.Name("Opponent"), expression.Value("bob"), expression.Value("carl"))
+	_, err = updateItem(db, "a", expression.Set(expression.Name("Opponent"), expression.Value("dave")), &in)
+	require.NoError(t, err)
+
+	_, err = updateItem(db, "a", expression.Set(expression.Name("Opponent"), expression.Value("eve")), &in)
+	assertConditionFailed(t, err)
+
+	either := expression.Or(expression.Name("Connections.bob").Equal(expression.Value("1")), expression.Name("Opponent").Equal(expression.Value("dave")))
+	_, err = updateItem(db, "a", expression.Set(expression.Name("Opponent"), expression.Value("fay")), &either)
+	require.NoError(t, err)
+}
+
+func assertConditionFailed(t *testing.T, err error) {
+	var awsErr awserr.Error
+	if assert.True(t, errors.As(err, &awsErr)) {
+		assert.Equal(t, dynamodb.ErrCodeConditionalCheckFailedException, awsErr.Code())
+	}
+}
+
+func TestQueryIndex(t *testing.T) {
+	db := newTable(t)
+
+	for _, host := range []string{"c", "a", "b"} {
+		_, err := updateItem(db, host, expression.
+			Set(expression.Name("Topic"), expression.Value("lounge")).
+			Set(expression.Name("Nickname"), expression.Value("n"+host)).
+			Set(expression.Name("Secret"), expression.Value("s")), nil)
+		require.NoError(t, err)
+	}
+
+	_, err := updateItem(db, "d", expression.Set(expression.Name("Topic"), expression.Value("other")), nil)
+	require.NoError(t, err)
+
+	output, err := db.QueryWithContext(context.Background(), &dynamodb.QueryInput{
+		TableName: aws.String("T"),
+		IndexName: aws.String("ByTopic"),
+		KeyConditions: map[string]*dynamodb.Condition{
+			"Topic": {
+				ComparisonOperator: aws.String(dynamodb.ComparisonOperatorEq),
+				AttributeValueList: []*dynamodb.AttributeValue{{S: aws.String("lounge")}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var got []map[string]string
+	require.NoError(t, dynamodbattribute.UnmarshalListOfMaps(output.Items, &got))
+
+	assert.Equal(t, []map[string]string{
+		{"Host": "a", "Topic": "lounge", "Nickname": "na"},
+		{"Host": "b", "Topic": "lounge", "Nickname": "nb"},
+		{"Host": "c", "Topic": "lounge", "Nickname": "nc"},
+	}, got)
+}
+
+func TestBatchGetItemLimit(t *testing.T) {
+	db := newTable(t)
+
+	var keys []map[string]*dynamodb.AttributeValue
+	for i := 0; i <= maxBatchGetKeys; i++ {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"Host": {S: aws.String(fmt.Sprint(i))}})
+	}
+
+	_, err := db.BatchGetItemWithContext(context.Background(), &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"T": {Keys: keys[:maxBatchGetKeys]}},
+	})
+	assert.NoError(t, err)
+
+	_, err = db.BatchGetItemWithContext(context.Background(), &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"T": {Keys: keys}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Metrics are written to stdout in the CloudWatch embedded metric format, which Lambda turns into
+// CloudWatch metrics. See
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+
+const metricsNamespace = "Othelgo"
+
+// metricsOutput is where metrics are written. It is replaced in tests.
+var metricsOutput io.Writer = os.Stdout
+
+// Metrics records measurements of the server, for dashboards and alarms. Each metric is recorded
+// separately for each of its dimensions.
+type Metrics interface {
+	// Count records one occurrence of a metric.
+	Count(name string, dimensions map[string]string)
+
+	// Latency records how long something took. Many latencies of the same metric make up a
+	// histogram.
+	Latency(name string, d time.Duration, dimensions map[string]string)
+}
+
+// NopMetrics discards metrics, for servers whose metrics aren't collected, such as one that a
+// client hosts for a LAN game.
+type NopMetrics struct{}
+
+func (NopMetrics) Count(string, map[string]string) {}
+
+func (NopMetrics) Latency(string, time.Duration, map[string]string) {}
+
+// emfMetrics writes metrics in the CloudWatch embedded metric format.
+type emfMetrics struct {
+	now func() time.Time
+}
+
+func (args Args) metrics() Metrics {
+	if args.Metrics != nil {
+		return args.Metrics
+	}
+	return emfMetrics{now: time.Now}
+}
+
+func (m emfMetrics) Count(name string, dimensions map[string]string) {
+	m.write(name, "Count", 1, dimensions)
+}
+
+func (m emfMetrics) Latency(name string, d time.Duration, dimensions map[string]string) {
+	m.write(name, "Milliseconds", float64(d)/float64(time.Millisecond), dimensions)
+}
+
+func (m emfMetrics) write(name, unit string, value float64, dimensions map[string]string) {
+	var dimensionSets [][]string
+	for key := range dimensions {
+		dimensionSets = append(dimensionSets, []string{key})
+	}
+	sort.Slice(dimensionSets, func(i, j int) bool { return dimensionSets[i][0] < dimensionSets[j][0] })
+
+	doc := map[string]interface{}{
+		"_aws": map[string]interface{}{
+			"Timestamp": m.now().UnixNano() / int64(time.Millisecond),
+			"CloudWatchMetrics": []interface{}{
+				map[string]interface{}{
+					"Namespace":  metricsNamespace,
+					"Dimensions": dimensionSets,
+					"Metrics":    []interface{}{map[string]string{"Name": name, "Unit": unit}},
+				},
+			},
+		},
+		name: value,
+	}
+
+	for key, value := range dimensions {
+		doc[key] = value
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		log.Printf("Failed to record metric %q: %v", name, err)
+		return
+	}
+
+	fmt.Fprintln(metricsOutput, string(b))
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEMFMetricsCount(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(w io.Writer) { metricsOutput = w }(metricsOutput)
+	metricsOutput = &buf
+
+	metrics := emfMetrics{now: func() time.Time { return time.Unix(1600000000, 0) }}
+	metrics.Count("Hellos", map[string]string{"Protocol": "0", "ClientVersion": "1.2.3"})
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, float64(1), doc["Hellos"])
+	assert.Equal(t, "0", doc["Protocol"])
+	assert.Equal(t, "1.2.3", doc["ClientVersion"])
+
+	aws := doc["_aws"].(map[string]interface{})
+	assert.Equal(t, float64(1600000000000), aws["Timestamp"])
+
+	directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Othelgo", directive["Namespace"])
+	assert.Equal(t, []interface{}{[]interface{}{"ClientVersion"}, []interface{}{"Protocol"}}, directive["Dimensions"])
+}
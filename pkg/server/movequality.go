@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/ai"
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Judging the quality of players' moves. When a rated game ends, each move is compared with the
+// best move that the AI can find, and the difference between their evaluations is the move's loss,
+// in hundredths of a disk. The losses are summed for each phase of the game, and kept for a
+// player's latest moveQualityGames games, so that their recent games can be compared with the ones
+// before to see whether they are improving.
+
+const (
+	// qualityDepth is how many turns ahead the AI looks when judging a move.
+	qualityDepth = 3
+
+	// blunderLoss is the loss of a move that counts as a blunder.
+	blunderLoss = 500
+
+	// maxMoveLoss caps the loss of a move, so that one move that throws away a won game doesn't
+	// outweigh all the others.
+	maxMoveLoss = 6400
+
+	// moveQualityGames is how many of a player's latest games are kept. The newer half are their
+	// recent games.
+	moveQualityGames = 20
+)
+
+// Phases of a game, by how full the board is before a move.
+const (
+	phaseOpening = iota
+	phaseMidgame
+	phaseEndgame
+	phaseCount
+)
+
+var phaseNames = [phaseCount]string{"opening", "midgame", "endgame"}
+
+// gameQuality is the quality of a player's moves in one game.
+type gameQuality struct {
+	EndedAt time.Time
+	Phases  [phaseCount]phaseQuality
+}
+
+// phaseQuality sums up a player's moves in one phase of a game.
+type phaseQuality struct {
+	Moves    int
+	Blunders int
+	Loss     int
+}
+
+func (q *phaseQuality) add(loss int) {
+	q.Moves++
+	q.Loss += loss
+	if loss >= blunderLoss {
+		q.Blunders++
+	}
+}
+
+// phaseOf returns the phase of a game with a board before a move.
+func phaseOf(board common.Board) int {
+	p1, p2 := common.KeepScore(board)
+	full := float64(p1+p2) / float64(board.Size*board.Size)
+
+	switch {
+	case full < 1.0/3:
+		return phaseOpening
+	case full < 2.0/3:
+		return phaseMidgame
+	default:
+		return phaseEndgame
+	}
+}
+
+// moveLoss returns how much worse a player's move was than the best move, in hundredths of a disk.
+func moveLoss(before, after common.Board, variant string, player common.Disk) int {
+	best := ai.Analyze(before, variant, player, qualityDepth).Score
+	played := ai.Analyze(after, variant, common.WhoseTurn(after, player), qualityDepth-1).Score
+
+	// Scores favor player 1.
+	if player == common.Player2 {
+		best, played = -best, -played
+	}
+
+	// Equal scores include the AI seeing the same end of the game either way.
+	if best == played {
+		return 0
+	}
+
+	loss := (best - played) * 100
+	switch {
+	case math.IsNaN(loss) || loss < 0:
+		return 0
+	case loss > maxMoveLoss:
+		return maxMoveLoss
+	default:
+		return int(math.Round(loss))
+	}
+}
+
+// judgeMoves returns the quality of each player's moves in a game, indexed by disk.
+func judgeMoves(start common.Board, moves [][2]int, variant string) (map[common.Disk]*gameQuality, error) {
+	boards, err := common.ReplayMoves(start, moves)
+	if err != nil {
+		return nil, err
+	}
+
+	qualities := map[common.Disk]*gameQuality{common.Player1: {}, common.Player2: {}}
+
+	player := common.Player1
+	for i := 1; i < len(boards); i++ {
+		before, after := boards[i-1], boards[i]
+		qualities[player].Phases[phaseOf(before)].add(moveLoss(before, after, variant, player))
+		player = common.WhoseTurn(after, player)
+	}
+
+	return qualities, nil
+}
+
+// recordMoveQuality judges the moves of a multiplayer game that has ended. Like ratings, the
+// quality of moves is not essential to the game itself, so failures are only logged.
+func recordMoveQuality(ctx context.Context, args Args, host, opponent string, g game) {
+	if opponent == "" || opponent == waiting {
+		return
+	}
+
+	if err := tryRecordMoveQuality(ctx, args, host, opponent, g, time.Now()); err != nil {
+		log.Printf("Failed to judge the moves of user %q's game: %v", host, err)
+	}
+}
+
+func tryRecordMoveQuality(ctx context.Context, args Args, host, opponent string, g game, now time.Time) error {
+	qualities, err := judgeMoves(g.startingBoard(), g.Moves, g.Objective)
+	if err != nil {
+		return err
+	}
+
+	for disk, quality := range qualities {
+		quality.EndedAt = now
+
+		nickname := g.nicknameOf(disk, host, opponent)
+
+		games, err := getMoveQuality(ctx, args, nickname)
+		if err != nil {
+			return err
+		}
+
+		games = append(games, *quality)
+		if len(games) > moveQualityGames {
+			games = games[len(games)-moveQualityGames:]
+		}
+
+		if err := putMoveQuality(ctx, args, nickname, games); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// summarizeMoveQuality compares the quality of a player's moves in their recent games with the
+// games before those, for each phase. The games are oldest first.
+func summarizeMoveQuality(games []gameQuality) []messages.PhaseQuality {
+	split := len(games) - moveQualityGames/2
+	if split < 0 {
+		split = 0
+	}
+
+	phases := make([]messages.PhaseQuality, phaseCount)
+	for phase := range phases {
+		phases[phase] = messages.PhaseQuality{
+			Phase:   phaseNames[phase],
+			Recent:  moveStats(games[split:], phase),
+			Earlier: moveStats(games[:split], phase),
+		}
+	}
+
+	return phases
+}
+
+func moveStats(games []gameQuality, phase int) messages.MoveStats {
+	stats := messages.MoveStats{Games: len(games)}
+
+	loss := 0
+	for _, g := range games {
+		stats.Moves += g.Phases[phase].Moves
+		stats.Blunders += g.Phases[phase].Blunders
+		loss += g.Phases[phase].Loss
+	}
+
+	if stats.Moves > 0 {
+		stats.AverageLoss = int(math.Round(float64(loss) / float64(stats.Moves)))
+	}
+
+	return stats
+}
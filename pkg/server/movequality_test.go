@@ -0,0 +1,86 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+)
+
+func TestPhaseOf(t *testing.T) {
+	board := common.NewBoard(common.DefaultBoardSize)
+	assert.Equal(t, phaseOpening, phaseOf(board))
+
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < 4; y++ {
+			board.Squares[x][y] = common.Player1
+		}
+	}
+	assert.Equal(t, phaseMidgame, phaseOf(board))
+
+	for x := 0; x < board.Size; x++ {
+		board.Squares[x][4] = common.Player2
+		board.Squares[x][5] = common.Player2
+	}
+	assert.Equal(t, phaseEndgame, phaseOf(board))
+}
+
+func TestJudgeMoves(t *testing.T) {
+	moves := [][2]int{{2, 4}, {2, 5}, {2, 6}}
+
+	qualities, err := judgeMoves(common.NewBoard(common.DefaultBoardSize), moves, common.VariantClassic)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, qualities[common.Player1].Phases[phaseOpening].Moves)
+	assert.Equal(t, 1, qualities[common.Player2].Phases[phaseOpening].Moves)
+
+	for _, quality := range qualities {
+		opening := quality.Phases[phaseOpening]
+		assert.GreaterOrEqual(t, opening.Loss, 0)
+		assert.LessOrEqual(t, opening.Loss, opening.Moves*maxMoveLoss)
+	}
+}
+
+func TestJudgeMovesIllegal(t *testing.T) {
+	_, err := judgeMoves(common.NewBoard(common.DefaultBoardSize), [][2]int{{0, 0}}, common.VariantClassic)
+	assert.Error(t, err)
+}
+
+func TestSummarizeMoveQuality(t *testing.T) {
+	var games []gameQuality
+	for i := 0; i < moveQualityGames; i++ {
+		var g gameQuality
+		// The earlier games lose 100 per move and the recent games lose 50.
+		loss := 100
+		if i >= moveQualityGames/2 {
+			loss = 50
+		}
+		g.Phases[phaseMidgame].add(loss)
+		g.Phases[phaseMidgame].add(loss)
+		games = append(games, g)
+	}
+	games[0].Phases[phaseMidgame].add(blunderLoss)
+
+	phases := summarizeMoveQuality(games)
+	require.Len(t, phases, phaseCount)
+
+	midgame := phases[phaseMidgame]
+	assert.Equal(t, "midgame", midgame.Phase)
+	assert.Equal(t, moveQualityGames/2, midgame.Recent.Games)
+	assert.Equal(t, 20, midgame.Recent.Moves)
+	assert.Equal(t, 50, midgame.Recent.AverageLoss)
+	assert.Equal(t, 0, midgame.Recent.Blunders)
+	assert.Equal(t, 21, midgame.Earlier.Moves)
+	assert.Equal(t, 1, midgame.Earlier.Blunders)
+	assert.Equal(t, 119, midgame.Earlier.AverageLoss)
+
+	assert.Equal(t, 0, phases[phaseOpening].Recent.AverageLoss)
+}
+
+func TestSummarizeMoveQualityFewGames(t *testing.T) {
+	phases := summarizeMoveQuality([]gameQuality{{}})
+	assert.Equal(t, 1, phases[phaseOpening].Recent.Games)
+	assert.Equal(t, 0, phases[phaseOpening].Earlier.Games)
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Rate limiting protects the server from misbehaving clients. Each connection has a token bucket,
+// stored in its connection record, which refills at a steady rate. Each message costs a token, and
+// messages that find the bucket empty are refused, as are messages that are too large. A
+// connection that keeps sending messages that are refused is disconnected.
+
+const (
+	// rateLimitBurst is the number of messages that a connection can send at once.
+	rateLimitBurst = 20
+
+	// rateLimitPerSecond is the number of messages per second that a connection can keep sending.
+	rateLimitPerSecond = 5
+
+	// maxViolations is the number of refused messages in a row after which a connection is
+	// disconnected.
+	maxViolations = 10
+
+	// maxMessageSize is the size in bytes of the largest message that the server accepts.
+	maxMessageSize = 4096
+
+	// rateLimitAttempts is how many times a token is taken again when the bucket is changed by a
+	// concurrent message.
+	rateLimitAttempts = 3
+)
+
+// rateLimit is the token bucket of a connection.
+type rateLimit struct {
+	Tokens float64
+
+	// TokensAt is when Tokens was last updated, in milliseconds since the Unix epoch.
+	TokensAt int64
+
+	// Violations is the number of messages in a row that were refused.
+	Violations int
+}
+
+// take refills the bucket for the time that has passed and takes a token for a message. It returns
+// false if the bucket is empty or the message is too large, in which case the message is refused.
+func (r *rateLimit) take(now time.Time, size int) bool {
+	nowMillis := now.UnixNano() / int64(time.Millisecond)
+
+	if r.TokensAt == 0 {
+		r.Tokens = rateLimitBurst
+	} else if elapsed := nowMillis - r.TokensAt; elapsed > 0 {
+		r.Tokens = math.Min(rateLimitBurst, r.Tokens+float64(elapsed)*rateLimitPerSecond/1000)
+	}
+
+	r.TokensAt = nowMillis
+
+	if size > maxMessageSize || r.Tokens < 1 {
+		r.Violations++
+		return false
+	}
+
+	r.Tokens--
+	r.Violations = 0
+
+	return true
+}
+
+// admitMessage applies the rate limit and size limit to a message, replying with an error if the
+// message is refused. It returns whether the message should be handled.
+func admitMessage(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) (bool, error) {
+	connID := req.RequestContext.ConnectionID
+	size := len(req.Body)
+
+	for attempt := 0; attempt < rateLimitAttempts; attempt++ {
+		old, err := getRateLimit(ctx, args, connID)
+		if err != nil {
+			return false, err
+		}
+
+		limit := old
+		allowed := limit.take(time.Now(), size)
+
+		if ok, err := updateRateLimit(ctx, args, connID, old, limit); err != nil {
+			return false, err
+		} else if !ok {
+			continue
+		}
+
+		if allowed {
+			return true, nil
+		}
+
+		if limit.Violations >= maxViolations {
+			log.Printf("Disconnecting connection %s after %d refused messages", connID, limit.Violations)
+			return false, disconnect(ctx, req.RequestContext, args, connID)
+		}
+
+		if size > maxMessageSize {
+			log.Printf("Refusing message of %d bytes from connection %s", size, connID)
+			return false, reply(ctx, req.RequestContext, args, messages.Error{
+				Error: fmt.Sprintf("messages can't be larger than %d bytes", maxMessageSize),
+				Code:  messages.ErrorMessageTooLarge,
+			})
+		}
+
+		return false, replyRateLimited(ctx, req, args)
+	}
+
+	// So many messages are arriving at once that the bucket can't be updated, which is also too
+	// many messages.
+	return false, replyRateLimited(ctx, req, args)
+}
+
+func replyRateLimited(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
+	log.Printf("Rate limiting connection %s", req.RequestContext.ConnectionID)
+
+	return reply(ctx, req.RequestContext, args, messages.Error{
+		Error: "too many messages, slow down",
+		Code:  messages.ErrorRateLimited,
+	})
+}
+
+// disconnect closes a connection. The connection is cleaned up when the disconnect event arrives.
+func disconnect(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, connID string) error {
+	client := args.APIGatewayManagementAPIClientFactory(reqCtx)
+
+	_, err := client.DeleteConnectionWithContext(ctx, &apigatewaymanagementapi.DeleteConnectionInput{
+		ConnectionId: &connID,
+	})
+
+	var gone *apigatewaymanagementapi.GoneException
+	if errors.As(err, &gone) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+func TestRateLimit(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	var limit rateLimit
+
+	// A new connection can send a burst of messages.
+	for i := 0; i < rateLimitBurst; i++ {
+		assert.True(t, limit.take(start, 100), "message %d", i)
+	}
+
+	assert.False(t, limit.take(start, 100))
+	assert.False(t, limit.take(start, 100))
+	assert.Equal(t, 2, limit.Violations)
+
+	// Tokens are refilled over time, and the violations are forgiven.
+	assert.True(t, limit.take(start.Add(time.Second), 100))
+	assert.Equal(t, 0, limit.Violations)
+	assert.InDelta(t, rateLimitPerSecond-1, limit.Tokens, 0.001)
+
+	// The bucket never holds more than the burst.
+	limit.take(start.Add(time.Hour), 100)
+	assert.InDelta(t, rateLimitBurst-1, limit.Tokens, 0.001)
+}
+
+func TestRateLimitMessageSize(t *testing.T) {
+	var limit rateLimit
+
+	assert.False(t, limit.take(time.Unix(1000, 0), maxMessageSize+1))
+	assert.Equal(t, 1, limit.Violations)
+	assert.True(t, limit.take(time.Unix(1000, 0), maxMessageSize))
+}
+
+// contendedDB fails every conditional update, as if other messages always changed the item first.
+type contendedDB struct {
+	*memdb.DB
+}
+
+func (contendedDB) UpdateItemWithContext(aws.Context, *dynamodb.UpdateItemInput, ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "changed", nil)
+}
+
+// postRecorder keeps the messages posted to connections.
+type postRecorder struct {
+	posted []string
+}
+
+func (p *postRecorder) PostToConnectionWithContext(_ aws.Context, input *apigatewaymanagementapi.PostToConnectionInput, _ ...request.Option) (*apigatewaymanagementapi.PostToConnectionOutput, error) {
+	p.posted = append(p.posted, string(input.Data))
+	return &apigatewaymanagementapi.PostToConnectionOutput{}, nil
+}
+
+func (p *postRecorder) DeleteConnectionWithContext(aws.Context, *apigatewaymanagementapi.DeleteConnectionInput, ...request.Option) (*apigatewaymanagementapi.DeleteConnectionOutput, error) {
+	return &apigatewaymanagementapi.DeleteConnectionOutput{}, nil
+}
+
+func TestAdmitMessageContended(t *testing.T) {
+	ctx := context.Background()
+
+	db := contendedDB{memdb.New()}
+	require.NoError(t, EnsureTable(ctx, db, "Othelgo"))
+
+	var recorder postRecorder
+	args := Args{
+		DB:        db,
+		TableName: "Othelgo",
+		APIGatewayManagementAPIClientFactory: func(events.APIGatewayWebsocketProxyRequestContext) APIGatewayManagementAPIClient {
+			return &recorder
+		},
+	}
+
+	req := events.APIGatewayWebsocketProxyRequest{
+		Body:           `{"action":"ping"}`,
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{ConnectionID: "a"},
+	}
+
+	ok, err := admitMessage(ctx, req, args)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.Len(t, recorder.posted, 1)
+	assert.Contains(t, recorder.posted[0], `"code":"`+messages.ErrorRateLimited+`"`)
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// Rating players. Everybody starts at initialRating, and the players of a multiplayer game are
+// rated by the Elo system when the game ends on the board or on the clock. A player who leaves a
+// game that is underway, or drops out of it through a stale connection, is rated as losing it.
+// Bot ladder games are rated the same way, but on separate ladder ratings with their own
+// leaderboard, so that bots and players are not ranked against each other.
+
+const (
+	initialRating = 1200
+
+	// ratingK is the most that a player's rating can change in one game.
+	ratingK = 32
+
+	// leaderboardSize is how many players are kept on the leaderboard.
+	leaderboardSize = 20
+)
+
+// eloDelta returns how much a player's rating changes after a game against an opponent. The score
+// is 1 for a win, 0.5 for a draw, and 0 for a loss.
+func eloDelta(r, opponent int, score float64) int {
+	expected := 1 / (1 + math.Pow(10, float64(opponent-r)/400))
+	return int(math.Round(ratingK * (score - expected)))
+}
+
+// rate returns two players' ratings after a game between them. The winner is empty for a draw.
+func rate(p1, p2 rating, winner string) (rating, rating) {
+	score := 0.5
+	switch winner {
+	case p1.Nickname:
+		score = 1
+	case p2.Nickname:
+		score = 0
+	}
+
+	delta := eloDelta(p1.Rating, p2.Rating, score)
+
+	p1.Rating += delta
+	p2.Rating -= delta
+	p1.Games++
+	p2.Games++
+
+	return p1, p2
+}
+
+// ratingsOf returns players' ratings by nickname. Players who haven't played a rated game have the
+// initial rating.
+func ratingsOf(ctx context.Context, args Args, nicknames []string) (map[string]rating, error) {
+	ratings, err := getRatings(ctx, args, nicknames)
+	if err != nil {
+		return nil, err
+	}
+
+	return withInitialRatings(nicknames, ratings), nil
+}
+
+// botRatingsOf returns bots' ladder ratings by nickname. Bots that haven't played a ladder game
+// have the initial rating.
+func botRatingsOf(ctx context.Context, args Args, nicknames []string) (map[string]rating, error) {
+	ratings, err := getBotRatings(ctx, args, nicknames)
+	if err != nil {
+		return nil, err
+	}
+
+	return withInitialRatings(nicknames, ratings), nil
+}
+
+func withInitialRatings(nicknames []string, ratings []rating) map[string]rating {
+	byNickname := make(map[string]rating, len(nicknames))
+	for _, nickname := range nicknames {
+		byNickname[nickname] = rating{Nickname: nickname, Rating: initialRating}
+	}
+	for _, r := range ratings {
+		byNickname[r.Nickname] = r
+	}
+
+	return byNickname
+}
+
+// ratingsByNickname returns just the rating numbers of players.
+func ratingsByNickname(ctx context.Context, args Args, nicknames []string) (map[string]int, error) {
+	ratings, err := ratingsOf(ctx, args, nicknames)
+	if err != nil {
+		return nil, err
+	}
+
+	byNickname := make(map[string]int, len(ratings))
+	for nickname, r := range ratings {
+		byNickname[nickname] = r.Rating
+	}
+
+	return byNickname, nil
+}
+
+// inRatingRange returns whether an opponent is rated no more than ratingRange above or below the
+// host.
+func inRatingRange(host, opponent, ratingRange int) bool {
+	return opponent >= host-ratingRange && opponent <= host+ratingRange
+}
+
+// rankLeaderboard adds ratings to a leaderboard, replacing the same players' older ratings, and keeps
+// the highest leaderboardSize of them. Ties keep their order.
+func rankLeaderboard(leaderboard []rating, ratings ...rating) []rating {
+	ranked := make([]rating, 0, len(leaderboard)+len(ratings))
+
+outer:
+	for _, r := range leaderboard {
+		for _, newer := range ratings {
+			if newer.Nickname == r.Nickname {
+				continue outer
+			}
+		}
+		ranked = append(ranked, r)
+	}
+
+	ranked = append(ranked, ratings...)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Rating > ranked[j].Rating
+	})
+
+	if len(ranked) > leaderboardSize {
+		ranked = ranked[:leaderboardSize]
+	}
+
+	return ranked
+}
+
+// recordRating rates the players of a multiplayer game that has ended, on the bot ladder if it is a
+// ladder game. The winner is empty for a draw, and the reason says how the game ended. Ratings are
+// not essential to the game itself, so failures are only logged.
+func recordRating(ctx context.Context, args Args, host, opponent, winner, reason string, ladder bool) {
+	if opponent == "" || opponent == waiting {
+		return
+	}
+
+	var err error
+	if ladder {
+		err = tryRecordLadderRating(ctx, args, host, opponent, winner)
+	} else {
+		err = tryRecordRating(ctx, args, host, opponent, winner, reason)
+	}
+
+	if err != nil {
+		log.Printf("Failed to rate user %q's game: %v", host, err)
+	}
+}
+
+func tryRecordRating(ctx context.Context, args Args, host, opponent, winner, reason string) error {
+	ratings, err := ratingsOf(ctx, args, []string{host, opponent})
+	if err != nil {
+		return err
+	}
+
+	hostRating, opponentRating := rate(ratings[host], ratings[opponent], winner)
+
+	if err := saveRatings(ctx, args, hostRating, opponentRating); err != nil {
+		return err
+	}
+
+	log.Printf("Rated user %q's game: %q is %d, %q is %d", host, host, hostRating.Rating, opponent, opponentRating.Rating)
+
+	// The result is kept so that the players can dispute it, with the game's messages so far, since
+	// later games replace them.
+	result := gameResult{
+		Host:     host,
+		Opponent: opponent,
+		Winner:   winner,
+		Reason:   reason,
+		EndedAt:  time.Now(),
+		Before:   [2]rating{ratings[host], ratings[opponent]},
+		After:    [2]rating{hostRating, opponentRating},
+	}
+	messageLog, err := gameLog(ctx, args, host)
+	if err != nil {
+		return err
+	}
+	for _, nickname := range []string{host, opponent} {
+		if err := putResult(ctx, args, nickname, result, messageLog); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveRatings saves players' new ratings, and ranks them on the leaderboard.
+func saveRatings(ctx context.Context, args Args, ratings ...rating) error {
+	for _, r := range ratings {
+		if err := updateRating(ctx, args, r); err != nil {
+			return err
+		}
+	}
+
+	leaderboard, err := getLeaderboard(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	return updateLeaderboard(ctx, args, rankLeaderboard(leaderboard, ratings...))
+}
+
+// tryRecordLadderRating rates the bots of a ladder game. Ladder results can't be disputed, so they
+// aren't kept.
+func tryRecordLadderRating(ctx context.Context, args Args, host, opponent, winner string) error {
+	ratings, err := botRatingsOf(ctx, args, []string{host, opponent})
+	if err != nil {
+		return err
+	}
+
+	hostRating, opponentRating := rate(ratings[host], ratings[opponent], winner)
+
+	for _, r := range []rating{hostRating, opponentRating} {
+		if err := updateBotRating(ctx, args, r); err != nil {
+			return err
+		}
+	}
+
+	leaderboard, err := getBotLeaderboard(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if err := updateBotLeaderboard(ctx, args, rankLeaderboard(leaderboard, hostRating, opponentRating)); err != nil {
+		return err
+	}
+
+	log.Printf("Rated bot %q's ladder game: %q is %d, %q is %d", host, host, hostRating.Rating, opponent, opponentRating.Rating)
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRate(t *testing.T) {
+	tests := []struct {
+		name           string
+		p1, p2         int
+		winner         string
+		wantP1, wantP2 int
+	}{
+		{name: "even win", p1: 1200, p2: 1200, winner: "andy", wantP1: 1216, wantP2: 1184},
+		{name: "even draw", p1: 1200, p2: 1200, wantP1: 1200, wantP2: 1200},
+		{name: "favorite wins", p1: 1600, p2: 1200, winner: "andy", wantP1: 1603, wantP2: 1197},
+		{name: "upset", p1: 1600, p2: 1200, winner: "bob", wantP1: 1571, wantP2: 1229},
+		{name: "draw against a favorite", p1: 1200, p2: 1600, wantP1: 1213, wantP2: 1587},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p1, p2 := rate(rating{Nickname: "andy", Rating: tt.p1}, rating{Nickname: "bob", Rating: tt.p2}, tt.winner)
+			assert.Equal(t, tt.wantP1, p1.Rating)
+			assert.Equal(t, tt.wantP2, p2.Rating)
+			assert.Equal(t, 1, p1.Games)
+			assert.Equal(t, 1, p2.Games)
+		})
+	}
+}
+
+func TestRankLeaderboard(t *testing.T) {
+	leaderboard := []rating{
+		{Nickname: "andy", Rating: 1300},
+		{Nickname: "bob", Rating: 1250},
+		{Nickname: "carl", Rating: 1200},
+	}
+
+	got := rankLeaderboard(leaderboard, rating{Nickname: "carl", Rating: 1280}, rating{Nickname: "dave", Rating: 1100})
+
+	assert.Equal(t, []rating{
+		{Nickname: "andy", Rating: 1300},
+		{Nickname: "carl", Rating: 1280},
+		{Nickname: "bob", Rating: 1250},
+		{Nickname: "dave", Rating: 1100},
+	}, got)
+}
+
+func TestRankLeaderboardKeepsTheHighest(t *testing.T) {
+	var leaderboard []rating
+	for i := 0; i < leaderboardSize; i++ {
+		leaderboard = append(leaderboard, rating{Nickname: string(rune('a' + i)), Rating: 1500 - i})
+	}
+
+	got := rankLeaderboard(leaderboard, rating{Nickname: "low", Rating: 1000})
+
+	assert.Len(t, got, leaderboardSize)
+	assert.NotContains(t, got, rating{Nickname: "low", Rating: 1000})
+}
+
+func TestClosestMatches(t *testing.T) {
+	queued := []subscriber{{ConnectionID: "1", Nickname: "far"}, {ConnectionID: "2", Nickname: "near"}, {ConnectionID: "3", Nickname: "me"}, {ConnectionID: "4", Nickname: "close"}}
+	ratings := map[string]rating{
+		"far":   {Nickname: "far", Rating: 1500},
+		"near":  {Nickname: "near", Rating: 1210},
+		"me":    {Nickname: "me", Rating: 1200},
+		"close": {Nickname: "close", Rating: 1100},
+	}
+
+	got := closestMatches(ratings["me"], queued, ratings, 200)
+
+	assert.Equal(t, []subscriber{{ConnectionID: "2", Nickname: "near"}, {ConnectionID: "4", Nickname: "close"}}, got)
+
+	// A looser gap pairs players further apart.
+	got = closestMatches(ratings["me"], queued, ratings, 300)
+
+	assert.Equal(t, []subscriber{{ConnectionID: "2", Nickname: "near"}, {ConnectionID: "4", Nickname: "close"}, {ConnectionID: "1", Nickname: "far"}}, got)
+}
+
+func TestInRatingRange(t *testing.T) {
+	assert.True(t, inRatingRange(1200, 1200, 200))
+	assert.True(t, inRatingRange(1200, 1400, 200))
+	assert.True(t, inRatingRange(1200, 1000, 200))
+	assert.False(t, inRatingRange(1200, 1401, 200))
+	assert.False(t, inRatingRange(1200, 999, 200))
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Rules descriptors, which tell clients and bots how a game is played.
+
+// Names of the presets of games that weren't hosted with one of presets.
+const (
+	presetCasual = "casual"
+	presetSolo   = "solo"
+)
+
+// rules describes how a game is played.
+func (g *game) rules() *messages.Rules {
+	rules := &messages.Rules{
+		Preset:    g.Preset,
+		Variant:   common.VariantClassic,
+		Opening:   common.OpeningStandard,
+		Board:     g.startingBoard(),
+		Scoring:   messages.ScoringDisks,
+		Takebacks: g.Takebacks,
+	}
+
+	if g.Objective != "" {
+		rules.Variant = g.Objective
+	}
+	if g.Opening != "" {
+		rules.Opening = g.Opening
+	}
+	if g.Objective == common.VariantAnti {
+		rules.Scoring = messages.ScoringFewestDisks
+	}
+
+	switch g.Preset {
+	case "":
+		rules.Preset = presetCasual
+	case presetSolo:
+		// Solo games can be undone as often as the player likes.
+		rules.Takebacks = true
+	}
+
+	if g.Clock.timed() {
+		rules.Clock = &messages.ClockRules{
+			Initial:   int(g.Clock.Initial.Milliseconds()),
+			Increment: int(g.Clock.Increment.Milliseconds()),
+			PerMove:   g.Clock.PerMove,
+		}
+	}
+
+	return rules
+}
+
+// setVariant configures a new game to be played as a variant from an opening. Empty values are the
+// classic game and the standard opening.
+func (g *game) setVariant(variant, opening string) {
+	if variant == common.VariantClassic {
+		variant = ""
+	}
+	if opening == common.OpeningStandard {
+		opening = ""
+	}
+
+	g.Objective = variant
+	g.Opening = opening
+	g.Board = g.startingBoard()
+}
+
+// startingBoard returns the board that the game started with.
+func (g *game) startingBoard() common.Board {
+	return common.NewBoardWithOpening(g.Board.Size, g.Opening)
+}
+
+// winner returns the nickname of the player who won a finished game, or an empty string for a
+// draw.
+func (g *game) winner(host, opponent string) string {
+	disk := common.Winner(g.Board, g.Objective)
+	if disk == 0 {
+		return ""
+	}
+	return g.nicknameOf(disk, host, opponent)
+}
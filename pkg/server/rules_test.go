@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armsnyder/othelgo/pkg/common"
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestRules(t *testing.T) {
+	g := newGame(6)
+	g.applyPreset(presets[""])
+
+	assert.Equal(t, &messages.Rules{
+		Preset:    "casual",
+		Variant:   common.VariantClassic,
+		Opening:   common.OpeningStandard,
+		Board:     common.NewBoard(6),
+		Scoring:   messages.ScoringDisks,
+		Takebacks: true,
+	}, g.rules())
+
+	g = newGame(0)
+	g.Preset = "correspondence"
+	g.applyPreset(presets["correspondence"])
+
+	rules := g.rules()
+	assert.Equal(t, "correspondence", rules.Preset)
+	assert.Equal(t, &messages.ClockRules{Initial: 72 * 60 * 60 * 1000, PerMove: true}, rules.Clock)
+
+	g = newGame(0)
+	g.Preset = presetSolo
+
+	assert.True(t, g.rules().Takebacks)
+}
+
+func TestRulesOfAVariant(t *testing.T) {
+	g := newGame(0)
+	g.setVariant(common.VariantAnti, common.OpeningParallel)
+
+	rules := g.rules()
+	assert.Equal(t, common.VariantAnti, rules.Variant)
+	assert.Equal(t, common.OpeningParallel, rules.Opening)
+	assert.Equal(t, messages.ScoringFewestDisks, rules.Scoring)
+	assert.Equal(t, common.NewBoardWithOpening(common.DefaultBoardSize, common.OpeningParallel), rules.Board)
+	assert.Equal(t, rules.Board, g.Board)
+}
+
+func TestSetVariantDefaults(t *testing.T) {
+	g := newGame(0)
+	g.setVariant(common.VariantClassic, common.OpeningStandard)
+
+	assert.Empty(t, g.Objective)
+	assert.Empty(t, g.Opening)
+	assert.Equal(t, common.NewBoard(common.DefaultBoardSize), g.Board)
+}
+
+func TestWinner(t *testing.T) {
+	g := newGame(6)
+	g.HostDisk = common.Player2
+	g.Board.Squares[0][0] = common.Player1
+
+	assert.Equal(t, "zinger", g.winner("flame", "zinger"))
+
+	g.Objective = common.VariantAnti
+	assert.Equal(t, "flame", g.winner("flame", "zinger"))
+
+	g.Board = common.NewBoard(6)
+	assert.Empty(t, g.winner("flame", "zinger"))
+}
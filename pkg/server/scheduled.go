@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Names of the jobs that run on a schedule rather than in answer to a player. In AWS, EventBridge
+// rules invoke the scheduled Lambda function with a ScheduledJob naming one of them, and the
+// standalone server runs them itself.
+const (
+	JobBotLadder = "botLadder"
+	JobDataJobs  = "dataJobs"
+	JobHeatMaps  = "heatMaps"
+	JobWebhooks  = "webhooks"
+)
+
+// ScheduledJob is the input of the scheduled Lambda function.
+type ScheduledJob struct {
+	Job string `json:"job"`
+}
+
+// DefaultScheduledHandler is an AWS Lambda handler for the scheduled jobs that uses default
+// arguments, like DefaultHandler. It can be invoked with
+// lambda.Start(server.DefaultScheduledHandler).
+func DefaultScheduledHandler(ctx context.Context, job ScheduledJob) error {
+	return RunScheduledJob(ctx, defaultArgs(), job.Job)
+}
+
+// RunScheduledJob runs a job by name, and logs what it did.
+func RunScheduledJob(ctx context.Context, args Args, job string) error {
+	switch job {
+	case JobBotLadder:
+		games, err := RunBotLadder(ctx, args)
+		for _, g := range games {
+			log.Printf("Started ladder game: %s", g)
+		}
+		return err
+
+	case JobDataJobs:
+		results, err := RunDataJobs(ctx, args)
+		for _, r := range results {
+			log.Printf("Ran data job: %s", r)
+		}
+		return err
+
+	case JobHeatMaps:
+		result, err := RunHeatMaps(ctx, args)
+		log.Printf("Heat maps: %s", result)
+		return err
+
+	case JobWebhooks:
+		posted, err := RunWebhooks(ctx, args)
+		if posted > 0 {
+			log.Printf("Posted %d tournament events", posted)
+		}
+		return err
+
+	default:
+		return fmt.Errorf("unknown scheduled job %q", job)
+	}
+}
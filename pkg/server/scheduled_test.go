@@ -0,0 +1,23 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+func TestRunScheduledJob(t *testing.T) {
+	ctx := context.Background()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	// With nobody waiting, the bot ladder starts no games.
+	assert.NoError(t, RunScheduledJob(ctx, args, JobBotLadder))
+
+	assert.Error(t, RunScheduledJob(ctx, args, "nightly"))
+}
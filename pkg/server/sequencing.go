@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Numbering the messages of a game. Messages that change a game in progress are numbered, and kept
+// for itemTTL, so that a player whose connection dropped for a moment can ask for the ones they
+// missed with ResumeFrom. Numbers count up for as long as the host keeps playing, across games.
+
+// outboxSize is how many of a game's most recent messages can be sent again.
+const outboxSize = 50
+
+// broadcastToGame numbers a message and sends it to players of a host's game. The connections map
+// the players' nicknames to their connection IDs. A player whose connection has gone away is
+// skipped, since they can ask for the message when they resume.
+func broadcastToGame(ctx context.Context, reqCtx events.APIGatewayWebsocketProxyRequestContext, args Args, host string, message interface{}, connections map[string]string) error {
+	seq, err := sequence(ctx, args, host, message, connections)
+	if err != nil {
+		return err
+	}
+
+	wrapper, data, err := encode(messages.Wrapper{Message: message, Seq: seq})
+	if err != nil {
+		return err
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, connectionID := range connectionIDList(connections) {
+		connectionID := connectionID
+
+		group.Go(func() error {
+			err := post(groupCtx, reqCtx, args, connectionID, wrapper, data)
+
+			var gone *apigatewaymanagementapi.GoneException
+			if errors.As(err, &gone) {
+				return nil
+			}
+
+			return err
+		})
+	}
+
+	return group.Wait()
+}
+
+// sequence numbers a message to players of a host's game, and keeps it to be sent again.
+func sequence(ctx context.Context, args Args, host string, message interface{}, connections map[string]string) (int, error) {
+	seq, err := nextSeq(ctx, args, host)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := messages.Marshal(messages.Wrapper{Message: message, Seq: seq})
+	if err != nil {
+		return 0, err
+	}
+
+	var recipients []string
+	for nickname := range connections {
+		recipients = append(recipients, nickname)
+	}
+
+	if len(recipients) == 0 {
+		return seq, nil
+	}
+
+	return seq, putOutbound(ctx, args, host, outbound{Seq: seq, Recipients: recipients, Message: data})
+}
+
+// missedMessages returns the kept messages for a player that are numbered after lastSeq, in order.
+func missedMessages(outbox []outbound, nickname string, lastSeq int) ([]messages.Wrapper, error) {
+	sort.Slice(outbox, func(i, j int) bool {
+		return outbox[i].Seq < outbox[j].Seq
+	})
+
+	var missed []messages.Wrapper
+	for _, o := range outbox {
+		if o.Seq <= lastSeq || !contains(o.Recipients, nickname) {
+			continue
+		}
+
+		var wrapper messages.Wrapper
+		if err := json.Unmarshal(o.Message, &wrapper); err != nil {
+			return nil, err
+		}
+		missed = append(missed, wrapper)
+	}
+
+	return missed, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
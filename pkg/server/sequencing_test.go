@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+func TestMissedMessages(t *testing.T) {
+	kept := func(seq int, message interface{}, recipients ...string) outbound {
+		data, err := json.Marshal(messages.Wrapper{Message: message, Seq: seq})
+		require.NoError(t, err)
+		return outbound{Seq: seq, Recipients: recipients, Message: data}
+	}
+
+	outbox := []outbound{
+		kept(4, messages.GameOver{Message: "ANDY left the game"}, "andy", "bob"),
+		kept(2, messages.OpponentDisconnected{Nickname: "bob", Grace: 60}, "andy"),
+		kept(3, messages.Joined{Nickname: "bob"}, "andy"),
+		kept(1, messages.RequestUndo{Nickname: "andy", Host: "andy"}, "bob"),
+	}
+
+	missed, err := missedMessages(outbox, "bob", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []messages.Wrapper{
+		{Message: &messages.RequestUndo{Nickname: "andy", Host: "andy"}, Seq: 1},
+		{Message: &messages.GameOver{Message: "ANDY left the game"}, Seq: 4},
+	}, missed)
+
+	missed, err = missedMessages(outbox, "andy", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []messages.Wrapper{
+		{Message: &messages.Joined{Nickname: "bob"}, Seq: 3},
+		{Message: &messages.GameOver{Message: "ANDY left the game"}, Seq: 4},
+	}, missed)
+}
@@ -1,6 +1,8 @@
 package server_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -8,6 +10,7 @@ import (
 
 	"github.com/armsnyder/othelgo/pkg/common"
 	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server"
 	"github.com/armsnyder/othelgo/pkg/server/testutil"
 )
 
@@ -29,7 +32,8 @@ func TestServer(t *testing.T) {
 // This is a suite of BDD-style tests for the server, using the ginkgo test framework.
 //
 // These tests invoke the server.Handle function directly.
-// In order for the tests to pass, there must be a local dynamodb running.
+// In order for the tests to pass, there must be a local dynamodb running, or the OTHELGO_TEST_DB
+// environment variable must be "memory" to use an in-memory one instead.
 //
 // See: https://onsi.github.io/ginkgo/#getting-started-writing-your-first-test
 var _ = Describe("Server", func() {
@@ -67,6 +71,48 @@ var _ = Describe("Server", func() {
 			Expect(flame).To(HaveReceived(&messages.Decorate{}))
 		})
 
+		It("should have acknowledged the hello", func() {
+			var message messages.HelloAck
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Features).To(ContainElement("replays"))
+			Expect(message.Branding).To(BeNil())
+		})
+
+		When("the server is branded", func() {
+			branding := messages.Branding{Name: "Othelgo LAN", MOTD: "Pizza at noon", AccentColor: "cyan"}
+
+			BeforeEach(func() { tester.Branding = branding })
+			AfterEach(func() { tester.Branding = messages.Branding{} })
+
+			BeforeEach(Send(&flame, messages.Hello{Version: "0.0.0"}))
+
+			It("should send the branding with the hello acknowledgement", func() {
+				var message messages.HelloAck
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Branding).To(Equal(&branding))
+			})
+		})
+
+		When("flame says hello as a lite client", func() {
+			BeforeEach(Send(&flame, messages.Hello{Version: "0.0.0", Lite: true}))
+
+			It("should acknowledge the hello", func() {
+				Expect(flame).To(HaveReceived(&messages.HelloAck{}))
+			})
+
+			It("should not send decorations", func() {
+				Expect(flame).NotTo(HaveReceived(&messages.Decorate{}))
+			})
+		})
+
+		When("flame pings", func() {
+			BeforeEach(Send(&flame, messages.Ping{}))
+
+			It("should send a pong", func() {
+				Expect(flame).To(HaveReceived(&messages.Pong{}))
+			})
+		})
+
 		When("zinger lists open games", func() {
 			BeforeEach(Send(&zinger, messages.ListOpenGames{}))
 
@@ -74,6 +120,799 @@ var _ = Describe("Server", func() {
 		})
 	})
 
+	When("flame and zinger join the lounge", func() {
+		BeforeEach(Send(&flame, messages.JoinLounge{Nickname: "flame"}))
+		BeforeEach(Send(&zinger, messages.JoinLounge{Nickname: "zinger"}))
+
+		It("should send zinger an empty backlog", func() {
+			var message messages.LoungeChat
+			Expect(zinger).To(HaveReceived(&message))
+			Expect(message.Lines).To(BeEmpty())
+		})
+
+		When("flame chats", func() {
+			BeforeEach(Send(&flame, messages.SendLoungeChat{Text: "anyone up for blitz?"}))
+
+			It("should send the chat to zinger", func() {
+				var message messages.LoungeChat
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Lines).To(Equal([]messages.ChatLine{{Nickname: "flame", Text: "anyone up for blitz?"}}))
+			})
+
+			It("should not send the chat to craig", func() {
+				Expect(craig).NotTo(HaveReceived(&messages.LoungeChat{}))
+			})
+
+			When("craig joins the lounge", func() {
+				BeforeEach(Send(&craig, messages.JoinLounge{Nickname: "craig"}))
+
+				It("should send craig the backlog", func() {
+					var message messages.LoungeChat
+					Expect(craig).To(HaveReceived(&message))
+					Expect(message.Lines).To(HaveLen(1))
+				})
+			})
+
+			When("craig joins the lounge as a lite client", func() {
+				BeforeEach(Send(&craig, messages.Hello{Version: "0.0.0", Lite: true}))
+				BeforeEach(Send(&craig, messages.JoinLounge{Nickname: "craig"}))
+
+				It("should not send craig the backlog", func() {
+					Expect(craig).NotTo(HaveReceived(&messages.LoungeChat{}))
+				})
+			})
+		})
+
+		When("zinger leaves the lounge and flame chats", func() {
+			BeforeEach(Send(&zinger, messages.LeaveLounge{}))
+			BeforeEach(Send(&flame, messages.SendLoungeChat{Text: "hello?"}))
+
+			It("should not send the chat to zinger", func() {
+				Expect(zinger).NotTo(HaveReceived(&messages.LoungeChat{}))
+			})
+		})
+
+		When("craig chats without joining the lounge", func() {
+			BeforeEach(Send(&craig, messages.SendLoungeChat{Text: "hi"}))
+
+			It("should send craig an error", func() {
+				Expect(craig).To(HaveReceived(&messages.Error{}))
+			})
+		})
+	})
+
+	When("flame sends a message that is too large", func() {
+		BeforeEach(Send(&flame, messages.SendLoungeChat{Text: strings.Repeat("spam", 2000)}))
+
+		It("should refuse the message", func() {
+			var message messages.Error
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Code).To(Equal(messages.ErrorMessageTooLarge))
+		})
+	})
+
+	When("flame sends too many messages at once", func() {
+		BeforeEach(func() {
+			for i := 0; i < 20; i++ {
+				flame.Send(messages.Ping{})
+			}
+		})
+
+		When("flame sends another", func() {
+			BeforeEach(Send(&flame, messages.Ping{}))
+
+			It("should refuse the message", func() {
+				var message messages.Error
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorRateLimited))
+				Expect(flame).NotTo(HaveReceived(&messages.Pong{}))
+			})
+
+			It("should not disconnect flame yet", func() {
+				Expect(flame.DisconnectedByServer()).To(BeFalse())
+			})
+		})
+
+		When("flame keeps sending messages", func() {
+			BeforeEach(func() {
+				for i := 0; i < 10; i++ {
+					flame.Send(messages.Ping{})
+				}
+			})
+
+			It("should disconnect flame", func() {
+				Expect(flame.DisconnectedByServer()).To(BeTrue())
+			})
+		})
+	})
+
+	When("flame follows as many things as a connection may", func() {
+		BeforeEach(func() {
+			flame.Send(messages.JoinLounge{Nickname: "flame"})
+			for i := 0; i < 15; i++ {
+				flame.Send(messages.CreateTournament{Nickname: "flame", Name: fmt.Sprintf("cup %d", i), Players: 2})
+			}
+		})
+
+		When("flame creates another tournament", func() {
+			BeforeEach(Send(&flame, messages.CreateTournament{Nickname: "flame", Name: "cup 15", Players: 2}))
+
+			It("should refuse", func() {
+				var message messages.Error
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorTooManySubscriptions))
+				Expect(flame).NotTo(HaveReceived(&messages.TournamentUpdate{}))
+			})
+		})
+
+		When("flame joins the lounge again", func() {
+			BeforeEach(Send(&flame, messages.JoinLounge{Nickname: "flame"}))
+
+			It("should not refuse", func() {
+				Expect(flame).NotTo(HaveReceived(&messages.Error{}))
+			})
+		})
+	})
+
+	When("zinger plays as many games as a player may", func() {
+		var hosts, zingers []*testutil.Client
+
+		BeforeEach(func() {
+			hosts, zingers = nil, nil
+			for i := 0; i < 6; i++ {
+				host := tester.NewClient()
+				host.Connect()
+				host.Send(messages.Hello{Version: "0.0.0"})
+				host.Send(messages.HostGame{Nickname: fmt.Sprintf("host%d", i)})
+				hosts = append(hosts, host)
+
+				z := tester.NewClient()
+				z.Connect()
+				z.Send(messages.Hello{Version: "0.0.0"})
+				zingers = append(zingers, z)
+			}
+
+			for i := 0; i < 5; i++ {
+				zingers[i].Send(messages.JoinGame{Nickname: "zinger", Host: fmt.Sprintf("host%d", i)})
+			}
+		})
+
+		AfterEach(func() {
+			for _, c := range append(hosts, zingers...) {
+				c.Disconnect()
+			}
+		})
+
+		When("zinger joins another game", func() {
+			BeforeEach(func() {
+				zingers[5].Send(messages.JoinGame{Nickname: "zinger", Host: "host5"})
+			})
+
+			It("should refuse", func() {
+				var message messages.Error
+				Expect(zingers[5]).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorTooManyGames))
+				Expect(zingers[5]).NotTo(HaveReceived(&messages.UpdateBoard{}))
+			})
+		})
+
+		When("zinger joins one of their games again from another connection", func() {
+			BeforeEach(func() {
+				zingers[5].Send(messages.JoinGame{Nickname: "zinger", Host: "host0"})
+			})
+
+			It("should let zinger back in", func() {
+				Expect(zingers[5]).NotTo(HaveReceived(&messages.Error{}))
+				Expect(zingers[5]).To(HaveReceived(&messages.UpdateBoard{}))
+			})
+		})
+	})
+
+	When("flame hosts a game with as many spectators as a game may have", func() {
+		var spectators []*testutil.Client
+
+		BeforeEach(func() {
+			flame.Send(messages.HostGame{Nickname: "flame"})
+
+			spectators = nil
+			for i := 0; i < 50; i++ {
+				spectator := tester.NewClient()
+				spectator.Connect()
+				spectator.Send(messages.Hello{Version: "0.0.0"})
+				spectator.Send(messages.Spectate{Host: "flame"})
+				spectators = append(spectators, spectator)
+			}
+		})
+
+		AfterEach(func() {
+			for _, c := range spectators {
+				c.Disconnect()
+			}
+		})
+
+		When("craig spectates too", func() {
+			BeforeEach(Send(&craig, messages.Spectate{Host: "flame"}))
+
+			It("should refuse", func() {
+				var message messages.Error
+				Expect(craig).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorTooManySpectators))
+				Expect(craig).NotTo(HaveReceived(&messages.SpectatorUpdate{}))
+			})
+		})
+
+		When("a spectator spectates again", func() {
+			BeforeEach(func() {
+				spectators[0].Send(messages.Spectate{Host: "flame"})
+			})
+
+			It("should not refuse", func() {
+				Expect(spectators[0]).NotTo(HaveReceived(&messages.Error{}))
+				Expect(spectators[0]).To(HaveReceived(&messages.SpectatorUpdate{}))
+			})
+		})
+	})
+
+	When("flame creates a tournament", func() {
+		BeforeEach(Send(&flame, messages.CreateTournament{Nickname: "flame", Name: "cup", Players: 2}))
+
+		It("should send flame the open tournament", func() {
+			var message messages.TournamentUpdate
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Status).To(Equal(messages.TournamentRegistering))
+			Expect(message.Players).To(BeEmpty())
+			Expect(message.Rounds).To(Equal(1))
+		})
+
+		When("zinger creates a tournament with the same name", func() {
+			BeforeEach(Send(&zinger, messages.CreateTournament{Nickname: "zinger", Name: "cup", Players: 4}))
+
+			It("should refuse zinger", func() {
+				Expect(zinger).To(HaveReceived(&messages.Error{}))
+				Expect(zinger).NotTo(HaveReceived(&messages.TournamentUpdate{}))
+			})
+		})
+
+		When("flame and zinger join the tournament", func() {
+			BeforeEach(func() {
+				flame.Send(messages.JoinTournament{Nickname: "flame", Name: "cup"})
+				zinger.Send(messages.JoinTournament{Nickname: "zinger", Name: "cup"})
+			})
+
+			It("should pair flame and zinger", func() {
+				var message messages.TournamentUpdate
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Status).To(Equal(messages.TournamentPlaying))
+				Expect(message.Round).To(Equal(1))
+				Expect(message.Pairings).To(Equal([]messages.TournamentPairing{{Host: "flame", Guest: "zinger"}}))
+			})
+
+			When("craig joins the full tournament", func() {
+				BeforeEach(Send(&craig, messages.JoinTournament{Nickname: "craig", Name: "cup"}))
+
+				It("should refuse craig", func() {
+					Expect(craig).To(HaveReceived(&messages.Error{}))
+				})
+			})
+
+			When("zinger leaves their tournament game", func() {
+				BeforeEach(func() {
+					flame.Send(messages.HostGame{Nickname: "flame"})
+					zinger.Send(messages.JoinGame{Nickname: "zinger", Host: "flame"})
+					zinger.Send(messages.LeaveGame{Nickname: "zinger", Host: "flame"})
+				})
+
+				It("should record the game as forfeited by zinger", func() {
+					var message messages.TournamentUpdate
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.Status).To(Equal(messages.TournamentFinished))
+					Expect(message.Pairings[0].Forfeit).To(BeTrue())
+					Expect(message.Standings[0]).To(Equal(messages.TournamentStanding{Nickname: "flame", Points: 2, Wins: 1}))
+					Expect(message.Standings[1]).To(Equal(messages.TournamentStanding{Nickname: "zinger", Forfeits: 1}))
+				})
+			})
+
+			When("zinger withdraws from the tournament", func() {
+				BeforeEach(Send(&zinger, messages.WithdrawTournament{Nickname: "zinger", Name: "cup"}))
+
+				It("should tell flame that zinger withdrew", func() {
+					var message messages.TournamentUpdate
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.Status).To(Equal(messages.TournamentFinished))
+					Expect(message.Standings[1].Withdrawn).To(BeTrue())
+				})
+			})
+		})
+	})
+
+	When("flame looks for a match", func() {
+		BeforeEach(Send(&flame, messages.FindMatch{Nickname: "flame"}))
+
+		It("should queue flame at the initial rating", func() {
+			var message messages.MatchQueued
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Rating).To(Equal(1200))
+		})
+
+		When("zinger looks for a match", func() {
+			BeforeEach(Send(&zinger, messages.FindMatch{Nickname: "zinger"}))
+
+			It("should start a game hosted by flame", func() {
+				var message messages.GameStarted
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Host).To(Equal("flame"))
+				Expect(message.Opponent).To(Equal("zinger"))
+			})
+
+			It("should send zinger a new game board", testutil.ExpectNewGameBoard(&zinger))
+
+			It("should tell flame that the game started", func() {
+				var message messages.GameStarted
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Opponent).To(Equal("zinger"))
+			})
+
+			It("should count both players down to the start", func() {
+				Expect(flame).To(HaveReceived(&messages.GameStarting{}))
+				Expect(zinger).To(HaveReceived(&messages.GameStarting{}))
+			})
+
+			When("craig looks for a match", func() {
+				BeforeEach(Send(&craig, messages.FindMatch{Nickname: "craig"}))
+
+				It("should queue craig", func() {
+					Expect(craig).To(HaveReceived(&messages.MatchQueued{}))
+					Expect(craig).NotTo(HaveReceived(&messages.GameStarted{}))
+				})
+			})
+		})
+
+		When("flame stops looking and zinger looks for a match", func() {
+			BeforeEach(func() {
+				flame.Send(messages.CancelFindMatch{})
+				zinger.Send(messages.FindMatch{Nickname: "zinger"})
+			})
+
+			It("should queue zinger", func() {
+				Expect(zinger).To(HaveReceived(&messages.MatchQueued{}))
+			})
+		})
+
+		When("zinger looks for a blitz match", func() {
+			BeforeEach(Send(&zinger, messages.FindMatch{Nickname: "zinger", Preset: "blitz"}))
+
+			It("should queue zinger rather than pair them with flame", func() {
+				Expect(zinger).To(HaveReceived(&messages.MatchQueued{}))
+				Expect(zinger).NotTo(HaveReceived(&messages.GameStarted{}))
+			})
+
+			When("flame looks for a blitz match too", func() {
+				BeforeEach(Send(&flame, messages.FindMatch{Nickname: "flame", Preset: "blitz"}))
+
+				It("should start a blitz game hosted by zinger", func() {
+					var started messages.GameStarted
+					Expect(flame).To(HaveReceived(&started))
+					Expect(started.Host).To(Equal("zinger"))
+
+					var board messages.UpdateBoard
+					Expect(flame).To(HaveReceived(&board))
+					Expect(board.Rules.Preset).To(Equal("blitz"))
+					Expect(board.P1Clock).To(Equal(180000))
+				})
+			})
+		})
+	})
+
+	When("craig gets the leaderboard", func() {
+		BeforeEach(Send(&craig, messages.GetLeaderboard{}))
+
+		It("should be empty", func() {
+			var message messages.Leaderboard
+			Expect(craig).To(HaveReceived(&message))
+			Expect(message.Entries).To(BeEmpty())
+		})
+	})
+
+	When("craig gets flame's profile", func() {
+		BeforeEach(Send(&craig, messages.GetProfile{Player: "flame"}))
+
+		It("should have the initial rating and no judged moves", func() {
+			var message messages.Profile
+			Expect(craig).To(HaveReceived(&message))
+			Expect(message.Player).To(Equal("flame"))
+			Expect(message.Rating).To(Equal(1200))
+			Expect(message.HeatMap).To(BeNil())
+			Expect(message.Phases).To(HaveLen(3))
+			for _, phase := range message.Phases {
+				Expect(phase.Recent.Moves).To(BeZero())
+				Expect(phase.Earlier.Moves).To(BeZero())
+			}
+		})
+	})
+
+	When("craig hides their rating without claiming their nickname", func() {
+		BeforeEach(Send(&craig, messages.SetPrivacy{Nickname: "craig", HideRating: true}))
+
+		It("should tell craig to claim their nickname", func() {
+			Expect(craig).To(HaveReceived(&messages.Error{}))
+			Expect(craig).NotTo(HaveReceived(&messages.Privacy{}))
+		})
+	})
+
+	When("craig joins a tournament that doesn't exist", func() {
+		BeforeEach(Send(&craig, messages.JoinTournament{Nickname: "craig", Name: "cup"}))
+
+		It("should tell craig", func() {
+			Expect(craig).To(HaveReceived(&messages.Error{}))
+		})
+	})
+
+	When("flame claims their nickname", func() {
+		var token string
+
+		BeforeEach(func() {
+			flame.Send(messages.ClaimNickname{Nickname: "flame"})
+
+			var message messages.NicknameClaimed
+			Expect(flame).To(HaveReceived(&message))
+			token = message.Token
+		})
+
+		It("should send flame a token", func() {
+			Expect(token).NotTo(BeEmpty())
+		})
+
+		When("flame hosts a game", func() {
+			BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame"}))
+
+			It("should send a new game board to flame", testutil.ExpectNewGameBoard(&flame))
+		})
+
+		When("zinger hosts a game as flame", func() {
+			BeforeEach(Send(&zinger, messages.HostGame{Nickname: "flame"}))
+
+			It("should refuse zinger", func() {
+				var message messages.Error
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorNicknameTaken))
+				Expect(zinger).NotTo(HaveReceived(&messages.UpdateBoard{}))
+			})
+		})
+
+		When("zinger claims the same nickname", func() {
+			BeforeEach(Send(&zinger, messages.ClaimNickname{Nickname: "flame"}))
+
+			It("should refuse zinger", func() {
+				var message messages.Error
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorNicknameTaken))
+			})
+		})
+
+		When("flame hides their rating and history", func() {
+			BeforeEach(Send(&flame, messages.SetPrivacy{Nickname: "flame", HideRating: true, HideHistory: true}))
+
+			It("should send flame their privacy settings", func() {
+				var message messages.Privacy
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.HideRating).To(BeTrue())
+				Expect(message.HideHistory).To(BeTrue())
+				Expect(message.AppearOffline).To(BeFalse())
+			})
+
+			When("craig gets flame's profile", func() {
+				BeforeEach(Send(&craig, messages.GetProfile{Player: "flame"}))
+
+				It("should hide flame's rating and history", func() {
+					var message messages.Profile
+					Expect(craig).To(HaveReceived(&message))
+					Expect(message.RatingHidden).To(BeTrue())
+					Expect(message.HistoryHidden).To(BeTrue())
+					Expect(message.Rating).To(BeZero())
+					Expect(message.Phases).To(BeEmpty())
+				})
+			})
+
+			When("flame gets their own profile", func() {
+				BeforeEach(Send(&flame, messages.GetProfile{Player: "flame"}))
+
+				It("should show flame everything", func() {
+					var message messages.Profile
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.RatingHidden).To(BeFalse())
+					Expect(message.Rating).To(Equal(1200))
+					Expect(message.Phases).To(HaveLen(3))
+				})
+			})
+		})
+
+		When("zinger changes flame's privacy settings", func() {
+			BeforeEach(Send(&zinger, messages.SetPrivacy{Nickname: "flame", AppearOffline: true}))
+
+			It("should refuse zinger", func() {
+				var message messages.Error
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorNicknameTaken))
+				Expect(zinger).NotTo(HaveReceived(&messages.Privacy{}))
+			})
+		})
+
+		When("flame asks to export their data", func() {
+			BeforeEach(Send(&flame, messages.ExportData{Nickname: "flame"}))
+
+			It("should queue the export", func() {
+				var message messages.DataJobQueued
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Kind).To(Equal(messages.DataJobExport))
+			})
+
+			When("the data jobs run", func() {
+				BeforeEach(testutil.RunDataJobs(&tester))
+
+				It("should send flame their data", func() {
+					var message messages.DataJobDone
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.Kind).To(Equal(messages.DataJobExport))
+					Expect(message.Export).NotTo(BeNil())
+					Expect(message.Export.Nickname).To(Equal("flame"))
+					Expect(message.Export.Rating).To(Equal(1200))
+					Expect(message.Export.BotRating).To(BeNil())
+				})
+			})
+		})
+
+		When("flame joins the bot ladder without being registered", func() {
+			BeforeEach(Send(&flame, messages.JoinBotLadder{Nickname: "flame"}))
+
+			It("should refuse flame", func() {
+				Expect(flame).To(HaveReceived(&messages.Error{}))
+				Expect(flame).NotTo(HaveReceived(&messages.BotLadderJoined{}))
+			})
+		})
+
+		When("flame and zinger are registered bots waiting on the ladder", func() {
+			BeforeEach(testutil.RegisterBot(&tester, "flame"))
+			BeforeEach(testutil.RegisterBot(&tester, "zinger"))
+			BeforeEach(Send(&zinger, messages.ClaimNickname{Nickname: "zinger"}))
+			BeforeEach(Send(&flame, messages.JoinBotLadder{Nickname: "flame"}))
+			BeforeEach(Send(&zinger, messages.JoinBotLadder{Nickname: "zinger"}))
+
+			It("should tell them their ladder ratings", func() {
+				var message messages.BotLadderJoined
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Rating).To(Equal(1200))
+				Expect(zinger).To(HaveReceived(&messages.BotLadderJoined{}))
+			})
+
+			When("the bot ladder runs", func() {
+				BeforeEach(testutil.RunBotLadder(&tester))
+
+				It("should start a game between them", func() {
+					Expect(flame).To(HaveReceived(&messages.GameStarted{}))
+					Expect(zinger).To(HaveReceived(&messages.GameStarted{}))
+				})
+			})
+		})
+
+		When("flame is a registered bot and deletes their data", func() {
+			BeforeEach(testutil.RegisterBot(&tester, "flame"))
+			BeforeEach(Send(&flame, messages.DeleteData{Nickname: "flame"}))
+			BeforeEach(testutil.RunDataJobs(&tester))
+
+			When("flame claims their nickname again and joins the bot ladder", func() {
+				BeforeEach(Send(&flame, messages.ClaimNickname{Nickname: "flame"}))
+				BeforeEach(Send(&flame, messages.JoinBotLadder{Nickname: "flame"}))
+
+				It("should refuse flame, since the registration was deleted", func() {
+					Expect(flame).To(HaveReceived(&messages.Error{}))
+					Expect(flame).NotTo(HaveReceived(&messages.BotLadderJoined{}))
+				})
+			})
+		})
+
+		When("flame disputes a result without having played a rated game", func() {
+			BeforeEach(Send(&flame, messages.DisputeResult{Nickname: "flame", Reason: "the server went down"}))
+
+			It("should refuse flame", func() {
+				Expect(flame).To(HaveReceived(&messages.Error{}))
+				Expect(flame).NotTo(HaveReceived(&messages.DisputeFiled{}))
+			})
+		})
+
+		When("zinger disputes a result as flame", func() {
+			BeforeEach(Send(&zinger, messages.DisputeResult{Nickname: "flame"}))
+
+			It("should refuse zinger", func() {
+				var message messages.Error
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorNicknameTaken))
+			})
+		})
+
+		When("flame asks to delete their data and the data jobs run", func() {
+			BeforeEach(Send(&flame, messages.DeleteData{Nickname: "flame"}))
+			BeforeEach(testutil.RunDataJobs(&tester))
+
+			It("should tell flame", func() {
+				var message messages.DataJobDone
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Kind).To(Equal(messages.DataJobDelete))
+				Expect(message.Export).To(BeNil())
+			})
+
+			When("zinger claims flame's old nickname", func() {
+				BeforeEach(Send(&zinger, messages.ClaimNickname{Nickname: "flame"}))
+
+				It("should give it to zinger", func() {
+					Expect(zinger).To(HaveReceived(&messages.NicknameClaimed{}))
+				})
+			})
+		})
+
+		When("zinger leaves flame's game after it started", func() {
+			BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame"}))
+			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame"}))
+			BeforeEach(Send(&zinger, messages.LeaveGame{Nickname: "zinger", Host: "flame"}))
+
+			When("flame disputes the result and exports their data", func() {
+				BeforeEach(Send(&flame, messages.DisputeResult{Nickname: "flame", Reason: "zinger's connection dropped"}))
+				BeforeEach(Send(&flame, messages.ExportData{Nickname: "flame"}))
+				BeforeEach(testutil.RunDataJobs(&tester))
+
+				It("should export the result and the dispute", func() {
+					var message messages.DataJobDone
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.Export).NotTo(BeNil())
+					Expect(message.Export.Results).To(HaveLen(1))
+					Expect(message.Export.Results[0].Winner).To(Equal("flame"))
+					Expect(message.Export.Disputes).To(HaveLen(1))
+					Expect(message.Export.Disputes[0].Reason).To(Equal("zinger's connection dropped"))
+				})
+			})
+
+			When("flame disputes the result and deletes their data", func() {
+				var disputes []server.Dispute
+
+				BeforeEach(Send(&flame, messages.DisputeResult{Nickname: "flame"}))
+				BeforeEach(Send(&flame, messages.DeleteData{Nickname: "flame"}))
+				BeforeEach(testutil.RunDataJobs(&tester))
+				BeforeEach(testutil.ListDisputes(&tester, &disputes))
+
+				It("should delete the dispute", func() {
+					Expect(disputes).To(BeEmpty())
+				})
+			})
+
+			When("zinger disputes the result and flame deletes their data", func() {
+				var disputes []server.Dispute
+
+				BeforeEach(Send(&zinger, messages.ClaimNickname{Nickname: "zinger"}))
+				BeforeEach(Send(&zinger, messages.DisputeResult{Nickname: "zinger"}))
+				BeforeEach(Send(&flame, messages.DeleteData{Nickname: "flame"}))
+				BeforeEach(testutil.RunDataJobs(&tester))
+				BeforeEach(testutil.ListDisputes(&tester, &disputes))
+
+				It("should keep zinger's dispute without flame's name", func() {
+					Expect(disputes).To(HaveLen(1))
+					Expect(disputes[0].Nickname).To(Equal("zinger"))
+					Expect(disputes[0].Host).To(Equal("(deleted)"))
+					Expect(disputes[0].Winner).To(Equal("(deleted)"))
+					Expect(disputes[0].ID).NotTo(ContainSubstring("flame"))
+					Expect(strings.Join(disputes[0].Log, "\n")).NotTo(ContainSubstring(`"flame"`))
+				})
+			})
+		})
+
+		When("flame asks to delete their data and disconnects before the data jobs run", func() {
+			BeforeEach(Send(&flame, messages.DeleteData{Nickname: "flame"}))
+			BeforeEach(func() { flame.Disconnect() })
+			BeforeEach(testutil.RunDataJobs(&tester))
+
+			When("flame reconnects with the token", func() {
+				BeforeEach(func() {
+					flame.Connect()
+					flame.Send(messages.Hello{Version: "0.0.0", Nickname: "flame", Token: token})
+				})
+
+				It("should tell flame that their data was deleted", func() {
+					var message messages.DataJobDone
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.Kind).To(Equal(messages.DataJobDelete))
+				})
+
+				When("zinger claims flame's old nickname", func() {
+					BeforeEach(Send(&zinger, messages.ClaimNickname{Nickname: "flame"}))
+
+					It("should give it to zinger", func() {
+						Expect(zinger).To(HaveReceived(&messages.NicknameClaimed{}))
+					})
+				})
+			})
+		})
+
+		When("flame reconnects with the token and hosts a game", func() {
+			BeforeEach(func() {
+				flame.Disconnect()
+				flame.Connect()
+				flame.Send(messages.Hello{Version: "0.0.0", Nickname: "flame", Token: token})
+				flame.Send(messages.HostGame{Nickname: "flame"})
+			})
+
+			It("should send a new game board to flame", testutil.ExpectNewGameBoard(&flame))
+		})
+
+		When("flame reconnects with the wrong token", func() {
+			BeforeEach(func() {
+				flame.Disconnect()
+				flame.Connect()
+				flame.Send(messages.Hello{Version: "0.0.0", Nickname: "flame", Token: "guess"})
+			})
+
+			It("should tell flame that the token is not valid", func() {
+				var message messages.Error
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorInvalidToken))
+			})
+
+			When("flame hosts a game", func() {
+				BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame"}))
+
+				It("should not send any board to flame", func() {
+					Expect(flame).NotTo(HaveReceived(&messages.UpdateBoard{}))
+				})
+			})
+		})
+	})
+
+	When("flame hosts a game for opponents rated within 100", func() {
+		BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame", RatingRange: 100}))
+
+		When("zinger, who has the same rating, joins", func() {
+			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame"}))
+
+			It("should let zinger in", func() {
+				Expect(zinger).To(HaveReceived(&messages.GameStarted{}))
+				Expect(zinger).NotTo(HaveReceived(&messages.Error{}))
+			})
+		})
+	})
+
+	When("the server is under maintenance", func() {
+		BeforeEach(testutil.SetMaintenance(true, "back soon"))
+
+		When("flame starts a solo game", func() {
+			BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame"}))
+
+			It("should refuse with the maintenance notice", func() {
+				var message messages.Error
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Code).To(Equal(messages.ErrorMaintenance))
+				Expect(message.Error).To(Equal("back soon"))
+			})
+
+			It("should not send a board", func() {
+				Expect(flame).NotTo(HaveReceived(&messages.UpdateBoard{}))
+			})
+		})
+
+		When("flame pings", func() {
+			BeforeEach(Send(&flame, messages.Ping{}))
+
+			It("should include the maintenance notice", func() {
+				var message messages.Pong
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Maintenance).To(Equal("back soon"))
+			})
+		})
+
+		When("maintenance ends and flame starts a solo game", func() {
+			BeforeEach(testutil.SetMaintenance(false, ""))
+			BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame"}))
+
+			It("should send a new game board to flame", testutil.ExpectNewGameBoard(&flame))
+		})
+	})
+
 	When("flame starts a solo game", func() {
 		BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame"}))
 
@@ -134,23 +973,267 @@ var _ = Describe("Server", func() {
 				Expect(message.P2Score).To(BeNumerically("==", 3))
 			})
 
-			It("should be flame's turn", testutil.ExpectTurn(&flame, 1))
+			It("should be flame's turn", testutil.ExpectTurn(&flame, 1))
+
+			It("should not send zinger any board updates", func() {
+				Expect(zinger).NotTo(HaveReceived(&messages.UpdateBoard{}))
+			})
+
+			When("flame takes back the move", func() {
+				BeforeEach(Send(&flame, messages.RequestUndo{Nickname: "flame", Host: "flame"}))
+
+				It("should take back both flame and the AI's moves", testutil.ExpectNewGameBoard(&flame))
+
+				It("should be flame's turn", testutil.ExpectTurn(&flame, 1))
+			})
+		})
+
+		When("flame says hello as a lite client and moves", func() {
+			BeforeEach(Send(&flame, messages.Hello{Version: "0.0.0", Lite: true}))
+			BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+			It("should send only the squares that the AI's move changed", func() {
+				var message messages.UpdateBoard
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Board.Size).To(BeZero())
+				Expect(message.Delta).NotTo(BeNil())
+				Expect(message.Delta.Disk).To(Equal(common.Player2))
+				Expect(message.Delta.Squares).To(ContainElement([2]int{message.X, message.Y}))
+				Expect(message.Delta.Squares).To(HaveLen(2))
+			})
+		})
+
+		When("flame says hello asking for compact boards and moves", func() {
+			BeforeEach(Send(&flame, messages.Hello{Version: "0.0.0", BoardEncoding: common.BoardEncodingCompact}))
+			BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+			It("should send the board after the AI's move as a compact board", func() {
+				var message messages.UpdateBoard
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.CompactBoard).NotTo(BeEmpty())
+				Expect(message.Delta).To(BeNil())
+				p1, p2 := common.KeepScore(message.Board)
+				Expect(p1 + p2).To(Equal(6))
+			})
+		})
+
+		When("flame disconnects and reconnects", func() {
+			BeforeEach(func() {
+				flame.Disconnect()
+				flame.Connect()
+			})
+
+			When("flame starts another solo game", func() {
+				BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame"}))
+
+				It("should send a new game board to flame", testutil.ExpectNewGameBoard(&flame))
+			})
+		})
+	})
+
+	When("flame starts a solo game on a 10x10 board", func() {
+		BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame", BoardSize: 10}))
+
+		It("should send flame a 10x10 board", func() {
+			var message messages.UpdateBoard
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Board).To(Equal(common.NewBoard(10)))
+		})
+
+		When("flame moves", func() {
+			BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 3, Y: 5}))
+
+			It("should accept the move", func() {
+				var message messages.UpdateBoard
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Board.Squares[3][5]).To(Equal(common.Player1))
+			})
+		})
+	})
+
+	When("flame starts an adaptive solo game", func() {
+		BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame", Adaptive: true}))
+
+		It("should send a new game board to flame", testutil.ExpectNewGameBoard(&flame))
+
+		When("flame moves", func() {
+			BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+			It("should send the AI's move", func() {
+				var message messages.UpdateBoard
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Player).To(Equal(common.Player1))
+			})
+		})
+	})
+
+	When("flame hosts a game as white", func() {
+		BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame", Color: messages.ColorWhite}))
+
+		When("zinger joins the game", func() {
+			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame"}))
+
+			It("should tell zinger to play black", func() {
+				var message messages.GameStarted
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message).To(Equal(messages.GameStarted{Host: "flame", Opponent: "zinger", Disk: common.Player1, HostDisk: common.Player2}))
+			})
+
+			It("should tell flame to play white", func() {
+				var message messages.GameStarted
+				Expect(flame).To(HaveReceived(&message))
+				Expect(message.Disk).To(Equal(common.Player2))
+			})
+
+			When("zinger makes the first move", func() {
+				BeforeEach(Send(&zinger, messages.PlaceDisk{Nickname: "zinger", Host: "flame", X: 2, Y: 4}))
+
+				It("should accept the move", func() {
+					var message messages.UpdateBoard
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.Board.Squares[2][4]).To(Equal(common.Player1))
+					Expect(message.Player).To(Equal(common.Player2))
+				})
+			})
+
+			When("flame makes the first move", func() {
+				BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+				It("should refuse the move", func() {
+					var message messages.UpdateBoard
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.X).To(Equal(-1))
+					Expect(message.Player).To(Equal(common.Player1))
+				})
+			})
+
+			When("zinger resumes the game", func() {
+				BeforeEach(Send(&zinger, messages.Ping{}))
+
+				BeforeEach(func() {
+					zinger.Disconnect()
+					zinger.Connect()
+					zinger.Send(messages.ResumeGame{Nickname: "zinger", Host: "flame"})
+				})
+
+				It("should tell zinger to play black again", func() {
+					var message messages.GameStarted
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.Disk).To(Equal(common.Player1))
+				})
+			})
+		})
+	})
+
+	When("flame hosts an anti-reversi game with the parallel opening", func() {
+		BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame", Variant: common.VariantAnti, Opening: common.OpeningParallel}))
+
+		It("should send flame the parallel opening", func() {
+			var message messages.UpdateBoard
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Board).To(Equal(common.NewBoardWithOpening(common.DefaultBoardSize, common.OpeningParallel)))
+		})
+
+		It("should tell flame that the fewest disks win", func() {
+			var message messages.UpdateBoard
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.Rules.Scoring).To(Equal(messages.ScoringFewestDisks))
+		})
+	})
+
+	When("flame lets the opponent pick a color", func() {
+		BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame", Color: messages.ColorJoiner}))
+
+		When("zinger joins the game as black", func() {
+			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame", Color: messages.ColorBlack}))
+
+			It("should tell zinger to play black", func() {
+				var message messages.GameStarted
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Disk).To(Equal(common.Player1))
+			})
+		})
+	})
+
+	When("flame hosts a correspondence game and zinger joins", func() {
+		BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame", Preset: "correspondence"}))
+		BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame"}))
+
+		When("flame moves, pings, and then loses the connection", func() {
+			BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+			BeforeEach(Send(&flame, messages.Ping{}))
+
+			BeforeEach(func() {
+				flame.Disconnect()
+			})
+
+			It("should tell zinger that flame can come back later", func() {
+				var message messages.OpponentDisconnected
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Nickname).To(Equal("flame"))
+				Expect(message.Grace).To(BeZero())
+			})
+
+			When("zinger moves while flame is away", func() {
+				BeforeEach(Send(&zinger, messages.PlaceDisk{Nickname: "zinger", Host: "flame", X: 2, Y: 3}))
+
+				It("should not end the game", func() {
+					Expect(zinger).NotTo(HaveReceived(&messages.GameOver{}))
+				})
+
+				It("should be flame's turn", func() {
+					var message messages.UpdateBoard
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.Player).To(Equal(common.Player1))
+				})
+			})
+		})
+	})
+
+	When("flame hosts a blitz game", func() {
+		BeforeEach(Send(&flame, messages.HostGame{Nickname: "flame", Preset: "blitz"}))
 
-			It("should not send zinger any board updates", func() {
-				Expect(zinger).NotTo(HaveReceived(&messages.UpdateBoard{}))
-			})
+		It("should send flame full clocks", func() {
+			var message messages.UpdateBoard
+			Expect(flame).To(HaveReceived(&message))
+			Expect(message.P1Clock).To(Equal(180000))
+			Expect(message.P2Clock).To(Equal(180000))
 		})
 
-		When("flame disconnects and reconnects", func() {
-			BeforeEach(func() {
-				flame.Disconnect()
-				flame.Connect()
+		When("zinger joins the game", func() {
+			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame"}))
+
+			It("should send zinger the rules of the game", func() {
+				var message messages.UpdateBoard
+				Expect(zinger).To(HaveReceived(&message))
+				Expect(message.Rules).To(Equal(&messages.Rules{
+					Preset:  "blitz",
+					Variant: common.VariantClassic,
+					Opening: common.OpeningStandard,
+					Board:   common.NewBoard(common.DefaultBoardSize),
+					Scoring: messages.ScoringDisks,
+					Clock:   &messages.ClockRules{Initial: 180000, Increment: 2000},
+				}))
 			})
 
-			When("flame starts another solo game", func() {
-				BeforeEach(Send(&flame, messages.StartSoloGame{Nickname: "flame"}))
+			When("flame makes the first move", func() {
+				BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
 
-				It("should send a new game board to flame", testutil.ExpectNewGameBoard(&flame))
+				It("should credit flame with the increment", func() {
+					var message messages.UpdateBoard
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.P1Clock).To(BeNumerically(">", 180000))
+					Expect(message.P2Clock).To(BeNumerically("<=", 180000))
+				})
+
+				When("flame asks for a takeback", func() {
+					BeforeEach(Send(&flame, messages.RequestUndo{Nickname: "flame", Host: "flame"}))
+
+					It("should refuse because blitz games have no takebacks", func() {
+						Expect(flame).To(HaveReceived(&messages.Error{}))
+						Expect(zinger).NotTo(HaveReceived(&messages.RequestUndo{}))
+					})
+				})
 			})
 		})
 	})
@@ -189,6 +1272,56 @@ var _ = Describe("Server", func() {
 			})
 		})
 
+		When("flame sets their status to away", func() {
+			BeforeEach(Send(&flame, messages.SetStatus{Nickname: "flame", Status: messages.StatusAway}))
+
+			When("zinger lists open games", func() {
+				BeforeEach(Send(&zinger, messages.ListOpenGames{}))
+
+				It("should show flame is away", func() {
+					var message messages.OpenGames
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.Statuses).To(HaveKeyWithValue("flame", messages.StatusAway))
+				})
+			})
+		})
+
+		When("craig is in the lounge and flame sets their status and disconnects", func() {
+			BeforeEach(Send(&craig, messages.JoinLounge{Nickname: "craig"}))
+			BeforeEach(Send(&flame, messages.SetStatus{Nickname: "flame", Status: messages.StatusAway}))
+			BeforeEach(func() {
+				flame.Disconnect()
+			})
+
+			It("should tell craig that flame is offline", func() {
+				var message messages.PresenceUpdate
+				Expect(craig).To(HaveReceived(&message))
+				Expect(message.Nickname).To(Equal("flame"))
+				Expect(message.Status).To(Equal(messages.StatusOffline))
+			})
+		})
+
+		When("flame pings and then loses the connection before anybody joins", func() {
+			BeforeEach(Send(&flame, messages.Ping{}))
+			BeforeEach(func() {
+				flame.Disconnect()
+			})
+			BeforeEach(Send(&zinger, messages.ListOpenGames{}))
+
+			It("should have no open games", testutil.ExpectNoOpenGames(&zinger))
+		})
+
+		When("craig is in the lounge and zinger joins flame's game", func() {
+			BeforeEach(Send(&craig, messages.JoinLounge{Nickname: "craig"}))
+			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "zinger", Host: "flame"}))
+
+			It("should tell craig that zinger is playing", func() {
+				var message messages.PresenceUpdate
+				Expect(craig).To(HaveReceived(&message))
+				Expect(message.Status).To(Equal(messages.StatusPlaying))
+			})
+		})
+
 		When("craig impersonates flame and leaves the game", func() {
 			BeforeEach(Send(&craig, messages.LeaveGame{Nickname: "flame", Host: "flame"}))
 
@@ -221,6 +1354,21 @@ var _ = Describe("Server", func() {
 			It("should show flame's game is open", testutil.ExpectOpenGames(&craig, "flame"))
 		})
 
+		When("flame's game expires and zinger lists open games", func() {
+			BeforeEach(testutil.ExpireItem("flame"))
+			BeforeEach(Send(&zinger, messages.ListOpenGames{}))
+
+			It("should have no open games", testutil.ExpectNoOpenGames(&zinger))
+		})
+
+		When("flame pings after the game expires", func() {
+			BeforeEach(testutil.ExpireItem("flame"))
+			BeforeEach(Send(&flame, messages.Ping{}))
+			BeforeEach(Send(&zinger, messages.ListOpenGames{}))
+
+			It("should keep flame's game open", testutil.ExpectOpenGames(&zinger, "flame"))
+		})
+
 		When("zinger joins the game with an illegal nickname", func() {
 			BeforeEach(Send(&zinger, messages.JoinGame{Nickname: "#waiting", Host: "flame"}))
 
@@ -250,6 +1398,15 @@ var _ = Describe("Server", func() {
 				Expect(message.Nickname).To(Equal("zinger"))
 			})
 
+			It("should count both players down to the start", func() {
+				for _, player := range []*testutil.Client{flame, zinger} {
+					var message messages.GameStarting
+					Expect(player).To(HaveReceived(&message))
+					Expect(message.Host).To(Equal("flame"))
+					Expect(message.StartsAt - message.ServerTime).To(BeEquivalentTo(3000))
+				}
+			})
+
 			When("craig lists open games", func() {
 				BeforeEach(Send(&craig, messages.ListOpenGames{}))
 
@@ -262,6 +1419,12 @@ var _ = Describe("Server", func() {
 				It("should not send any board to craig", func() {
 					Expect(craig).NotTo(HaveReceived(&messages.UpdateBoard{}))
 				})
+
+				It("should tell craig the game already has an opponent", func() {
+					var message messages.Error
+					Expect(craig).To(HaveReceived(&message))
+					Expect(message.Code).To(Equal(messages.ErrorWrongPhase))
+				})
 			})
 
 			When("craig hosts a game using flame's nickname", func() {
@@ -310,6 +1473,223 @@ var _ = Describe("Server", func() {
 				})
 			})
 
+			When("flame pings and then loses the connection", func() {
+				BeforeEach(Send(&flame, messages.Ping{}))
+
+				BeforeEach(func() {
+					flame.Disconnect()
+				})
+
+				It("should not end zinger's game", func() {
+					Expect(zinger).NotTo(HaveReceived(&messages.GameOver{}))
+				})
+
+				It("should tell zinger that flame may come back", func() {
+					var message messages.OpponentDisconnected
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.Nickname).To(Equal("flame"))
+					Expect(message.Grace).To(Equal(60))
+				})
+
+				When("flame reconnects and resumes the game", func() {
+					BeforeEach(func() {
+						flame.Connect()
+					})
+
+					BeforeEach(Send(&flame, messages.ResumeGame{Nickname: "flame", Host: "flame"}))
+
+					It("should send the game board to flame", testutil.ExpectNewGameBoard(&flame))
+
+					It("should notify zinger that flame is back", func() {
+						var message messages.Joined
+						Expect(zinger).To(HaveReceived(&message))
+						Expect(message.Nickname).To(Equal("flame"))
+					})
+
+					When("flame makes the first move", func() {
+						BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+						It("should be zinger's turn", testutil.ExpectTurn(&zinger, 2))
+					})
+				})
+			})
+
+			When("flame moves, pings, and then loses the connection", func() {
+				BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+				BeforeEach(Send(&flame, messages.Ping{}))
+
+				BeforeEach(func() {
+					flame.Disconnect()
+				})
+
+				When("zinger moves while flame is away", func() {
+					BeforeEach(Send(&zinger, messages.PlaceDisk{Nickname: "zinger", Host: "flame", X: 2, Y: 3}))
+
+					It("should tell zinger that the game is paused", func() {
+						var message messages.Error
+						Expect(zinger).To(HaveReceived(&message))
+						Expect(message.Code).To(Equal(messages.ErrorWrongPhase))
+					})
+				})
+
+				When("flame resumes the game and zinger moves", func() {
+					BeforeEach(func() {
+						flame.Connect()
+					})
+
+					BeforeEach(Send(&flame, messages.ResumeGame{Nickname: "flame", Host: "flame"}))
+					BeforeEach(Send(&zinger, messages.PlaceDisk{Nickname: "zinger", Host: "flame", X: 2, Y: 3}))
+
+					It("should be flame's turn", testutil.ExpectTurn(&flame, 1))
+				})
+			})
+
+			When("craig spectates flame's game", func() {
+				BeforeEach(Send(&craig, messages.Spectate{Host: "flame"}))
+
+				It("should send craig the game", func() {
+					var message messages.SpectatorUpdate
+					Expect(craig).To(HaveReceived(&message))
+					Expect(message.Host).To(Equal("flame"))
+					Expect(message.Opponent).To(Equal("zinger"))
+					Expect(message.Board).To(Equal(common.NewBoard(common.DefaultBoardSize)))
+				})
+
+				When("flame makes the first move", func() {
+					BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+					It("should send craig the move", func() {
+						var message messages.SpectatorUpdate
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.X).To(Equal(2))
+						Expect(message.Y).To(Equal(4))
+						Expect(message.Player).To(Equal(common.Player2))
+					})
+				})
+
+				When("flame makes the first move and craig asks for the game state", func() {
+					BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+					BeforeEach(Send(&craig, messages.GetGameState{Host: "flame"}))
+
+					It("should send craig the whole game", func() {
+						var message messages.GameState
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Host).To(Equal("flame"))
+						Expect(message.Opponent).To(Equal("zinger"))
+						Expect(message.Player).To(Equal(common.Player2))
+						Expect(message.X).To(Equal(2))
+						Expect(message.Y).To(Equal(4))
+						Expect(message.Moves).To(Equal(1))
+						Expect(message.P1Score).To(Equal(4))
+						Expect(message.Rules).NotTo(BeNil())
+					})
+				})
+
+				When("craig stops spectating and flame makes the first move", func() {
+					BeforeEach(Send(&craig, messages.StopSpectating{Host: "flame"}))
+					BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+
+					It("should not send craig the move", func() {
+						Expect(craig).NotTo(HaveReceived(&messages.SpectatorUpdate{}))
+					})
+				})
+
+				When("zinger leaves the game", func() {
+					BeforeEach(Send(&zinger, messages.LeaveGame{Nickname: "zinger", Host: "flame"}))
+
+					It("should tell craig the game is over", func() {
+						var message messages.SpectatorUpdate
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Over).To(BeTrue())
+						Expect(message.Message).To(Equal("ZINGER left the game"))
+					})
+				})
+			})
+
+			When("craig spectates flame's game with a nickname and kibitzes", func() {
+				BeforeEach(Send(&craig, messages.Spectate{Host: "flame", Nickname: "craig"}))
+				BeforeEach(Send(&craig, messages.SendKibitz{Host: "flame", Text: "watch the corners"}))
+
+				It("should send craig the kibitz", func() {
+					var message messages.Kibitz
+					Expect(craig).To(HaveReceived(&message))
+					Expect(message.Host).To(Equal("flame"))
+					Expect(message.Lines).To(Equal([]messages.KibitzLine{{Nickname: "craig", Text: "watch the corners", Move: 0}}))
+				})
+
+				It("should not send the players the kibitz", func() {
+					Expect(flame).NotTo(HaveReceived(&messages.Kibitz{}))
+					Expect(zinger).NotTo(HaveReceived(&messages.Kibitz{}))
+				})
+
+				When("craig spectates again", func() {
+					BeforeEach(Send(&craig, messages.Spectate{Host: "flame", Nickname: "craig"}))
+
+					It("should send craig the kibitz backlog", func() {
+						var message messages.Kibitz
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Lines).To(HaveLen(1))
+					})
+				})
+			})
+
+			When("craig spectates flame's game without a nickname and kibitzes", func() {
+				BeforeEach(Send(&craig, messages.Spectate{Host: "flame"}))
+				BeforeEach(Send(&craig, messages.SendKibitz{Host: "flame", Text: "watch the corners"}))
+
+				It("should send craig an error", func() {
+					Expect(craig).To(HaveReceived(&messages.Error{}))
+				})
+			})
+
+			When("zinger asks for the game state", func() {
+				BeforeEach(Send(&zinger, messages.GetGameState{Host: "flame"}))
+
+				It("should send zinger a new game", func() {
+					var message messages.GameState
+					Expect(zinger).To(HaveReceived(&message))
+					Expect(message.Board).To(Equal(common.NewBoard(common.DefaultBoardSize)))
+					Expect(message.Player).To(Equal(common.Player1))
+					Expect(message.X).To(Equal(-1))
+				})
+			})
+
+			When("craig asks for the game state without spectating", func() {
+				BeforeEach(Send(&craig, messages.GetGameState{Host: "flame"}))
+
+				It("should not send craig the game", func() {
+					Expect(craig).NotTo(HaveReceived(&messages.GameState{}))
+				})
+			})
+
+			When("craig spectates a player who is not playing", func() {
+				BeforeEach(Send(&craig, messages.Spectate{Host: "bob"}))
+
+				It("should send craig an error", func() {
+					Expect(craig).To(HaveReceived(&messages.Error{}))
+				})
+			})
+
+			When("craig tries to resume flame's game", func() {
+				BeforeEach(Send(&craig, messages.ResumeGame{Nickname: "craig", Host: "flame"}))
+
+				It("should tell craig the game is over", func() {
+					Expect(craig).To(HaveReceived(&messages.GameOver{}))
+				})
+
+				It("should not end flame's game", func() {
+					Expect(flame).NotTo(HaveReceived(&messages.GameOver{}))
+				})
+			})
+
+			When("flame pings while zinger is still connected", func() {
+				BeforeEach(Send(&flame, messages.Ping{}))
+
+				It("should not end the game", func() {
+					Expect(flame).NotTo(HaveReceived(&messages.GameOver{}))
+				})
+			})
+
 			When("zinger disconnects", func() {
 				BeforeEach(func() {
 					zinger.Disconnect()
@@ -355,6 +1735,47 @@ var _ = Describe("Server", func() {
 					Expect(message.Y).To(Equal(4))
 				})
 
+				It("should include the moves so far in the UpdateBoard message", func() {
+					var message messages.UpdateBoard
+					Expect(flame).To(HaveReceived(&message))
+					Expect(message.Moves).To(Equal([]string{"c5"}))
+				})
+
+				When("zinger asks for the messages it missed", func() {
+					BeforeEach(Send(&zinger, messages.ResumeFrom{Nickname: "zinger", Host: "flame"}))
+
+					It("should send zinger the move again", func() {
+						var message messages.UpdateBoard
+						Expect(zinger).To(HaveReceived(&message))
+						Expect(message.X).To(Equal(2))
+						Expect(message.Y).To(Equal(4))
+					})
+				})
+
+				When("zinger asks for the messages after the move", func() {
+					BeforeEach(Send(&zinger, messages.ResumeFrom{Nickname: "zinger", Host: "flame", LastSeq: 1}))
+
+					It("should not send zinger anything", func() {
+						Expect(zinger).NotTo(HaveReceived(&messages.UpdateBoard{}))
+					})
+				})
+
+				When("craig asks for the messages of flame's game", func() {
+					BeforeEach(Send(&craig, messages.ResumeFrom{Nickname: "craig", Host: "flame"}))
+
+					It("should not send craig the move", func() {
+						Expect(craig).NotTo(HaveReceived(&messages.UpdateBoard{}))
+					})
+				})
+
+				When("craig asks for the messages of flame's game as zinger", func() {
+					BeforeEach(Send(&craig, messages.ResumeFrom{Nickname: "zinger", Host: "flame"}))
+
+					It("should not send craig the move", func() {
+						Expect(craig).NotTo(HaveReceived(&messages.UpdateBoard{}))
+					})
+				})
+
 				It("should include the board score in the UpdateBoard message", func() {
 					var message messages.UpdateBoard
 					Expect(flame).To(HaveReceived(&message))
@@ -386,6 +1807,70 @@ var _ = Describe("Server", func() {
 
 				It("should show zinger it is player 2's turn", testutil.ExpectTurn(&zinger, 2))
 
+				When("flame asks for a takeback", func() {
+					BeforeEach(Send(&flame, messages.RequestUndo{Nickname: "flame", Host: "flame"}))
+
+					It("should ask zinger", func() {
+						var message messages.RequestUndo
+						Expect(zinger).To(HaveReceived(&message))
+						Expect(message.Nickname).To(Equal("flame"))
+					})
+
+					When("zinger asks for the game state", func() {
+						BeforeEach(Send(&zinger, messages.GetGameState{Host: "flame"}))
+
+						It("should not have taken back the move yet", func() {
+							var message messages.GameState
+							Expect(zinger).To(HaveReceived(&message))
+							Expect(message.Board).To(Equal(expectedBoardAfterFirstMove))
+							Expect(message.Player).To(Equal(common.Player2))
+						})
+					})
+
+					When("zinger accepts", func() {
+						BeforeEach(Send(&zinger, messages.RespondUndo{Nickname: "zinger", Host: "flame", Accept: true}))
+
+						It("should send flame the board from before the move", testutil.ExpectNewGameBoard(&flame))
+
+						It("should send zinger the board from before the move", testutil.ExpectNewGameBoard(&zinger))
+
+						It("should be flame's turn", testutil.ExpectTurn(&zinger, 1))
+					})
+
+					When("zinger declines", func() {
+						BeforeEach(Send(&zinger, messages.RespondUndo{Nickname: "zinger", Host: "flame"}))
+
+						It("should tell flame", func() {
+							var message messages.RespondUndo
+							Expect(flame).To(HaveReceived(&message))
+							Expect(message.Accept).To(BeFalse())
+						})
+
+						It("should not change the board", func() {
+							Expect(zinger).NotTo(HaveReceived(&messages.UpdateBoard{}))
+						})
+
+						When("zinger asks for the game state", func() {
+							BeforeEach(Send(&zinger, messages.GetGameState{Host: "flame"}))
+
+							It("should still have flame's move on the board", func() {
+								var message messages.GameState
+								Expect(zinger).To(HaveReceived(&message))
+								Expect(message.Board).To(Equal(expectedBoardAfterFirstMove))
+								Expect(message.Player).To(Equal(common.Player2))
+							})
+						})
+					})
+
+					When("flame accepts their own takeback", func() {
+						BeforeEach(Send(&flame, messages.RespondUndo{Nickname: "flame", Host: "flame", Accept: true}))
+
+						It("should refuse", func() {
+							Expect(flame).To(HaveReceived(&messages.Error{}))
+						})
+					})
+				})
+
 				When("flame moves when it isn't his turn", func() {
 					BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 5, Y: 3}))
 
@@ -397,6 +1882,132 @@ var _ = Describe("Server", func() {
 				})
 			})
 
+			When("flame and zinger play a game to the end", func() {
+				BeforeEach(func() {
+					for i, move := range testutil.QuickestGame {
+						player := &flame
+						nickname := "flame"
+						if i%2 == 1 {
+							player = &zinger
+							nickname = "zinger"
+						}
+						(*player).Send(messages.PlaceDisk{Nickname: nickname, Host: "flame", X: move[0], Y: move[1]})
+					}
+				})
+
+				When("craig requests the replay of flame's game", func() {
+					BeforeEach(Send(&craig, messages.GetReplay{Host: "flame"}))
+
+					It("should send craig every move of the game", func() {
+						var message messages.Replay
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Opponent).To(Equal("zinger"))
+						Expect(message.Moves).To(HaveLen(len(testutil.QuickestGame)))
+						Expect(message.Ending).To(Equal("the board is full or nobody can move"))
+					})
+				})
+
+				When("craig gets flame's profile", func() {
+					BeforeEach(Send(&craig, messages.GetProfile{Player: "flame"}))
+
+					It("should list the game among flame's replays", func() {
+						var message messages.Profile
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Replays).To(HaveLen(1))
+					})
+				})
+
+				When("flame and zinger play another game", func() {
+					var first string
+
+					BeforeEach(func() {
+						craig.Send(messages.GetReplay{Host: "flame"})
+						var message messages.Replay
+						Expect(craig).To(HaveReceived(&message))
+						first = message.Game
+
+						flame.Send(messages.HostGame{Nickname: "flame"})
+						zinger.Send(messages.JoinGame{Nickname: "zinger", Host: "flame"})
+						for i, move := range testutil.QuickestGame {
+							player := &flame
+							nickname := "flame"
+							if i%2 == 1 {
+								player = &zinger
+								nickname = "zinger"
+							}
+							(*player).Send(messages.PlaceDisk{Nickname: nickname, Host: "flame", X: move[0], Y: move[1]})
+						}
+
+					})
+
+					When("craig gets flame's profile", func() {
+						BeforeEach(Send(&craig, messages.GetProfile{Player: "flame"}))
+
+						It("should keep both games", func() {
+							var message messages.Profile
+							Expect(craig).To(HaveReceived(&message))
+							Expect(message.Replays).To(HaveLen(2))
+							Expect(message.Replays[1]).To(Equal(first))
+						})
+					})
+
+					When("craig requests the first game", func() {
+						BeforeEach(func() {
+							craig.Send(messages.GetReplay{Host: "flame", Game: first})
+						})
+
+						It("should send craig the first game", func() {
+							var message messages.Replay
+							Expect(craig).To(HaveReceived(&message))
+							Expect(message.Game).To(Equal(first))
+							Expect(message.Opponent).To(Equal("zinger"))
+						})
+					})
+				})
+
+				When("the heat maps are counted and craig gets zinger's profile", func() {
+					BeforeEach(testutil.RunHeatMaps(&tester))
+					BeforeEach(Send(&craig, messages.GetProfile{Player: "zinger"}))
+
+					It("should count zinger's moves", func() {
+						var message messages.Profile
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.HeatMap).NotTo(BeNil())
+						Expect(message.HeatMap.Games).To(Equal(1))
+						Expect(message.HeatMap.Plays).To(HaveLen(8))
+
+						plays := 0
+						for _, column := range message.HeatMap.Plays {
+							for _, n := range column {
+								plays += n
+							}
+						}
+						Expect(plays).To(Equal(len(testutil.QuickestGame) / 2))
+					})
+				})
+			})
+
+			When("craig requests the replay of flame's unfinished game", func() {
+				BeforeEach(Send(&craig, messages.GetReplay{Host: "flame"}))
+
+				It("should send craig an error", func() {
+					Expect(craig).To(HaveReceived(&messages.Error{}))
+				})
+			})
+
+			When("zinger leaves after flame moves and craig requests the replay", func() {
+				BeforeEach(Send(&flame, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
+				BeforeEach(Send(&zinger, messages.LeaveGame{Nickname: "zinger", Host: "flame"}))
+				BeforeEach(Send(&craig, messages.GetReplay{Host: "flame"}))
+
+				It("should send craig the game and why it ended", func() {
+					var message messages.Replay
+					Expect(craig).To(HaveReceived(&message))
+					Expect(message.Moves).To(Equal([][2]int{{2, 4}}))
+					Expect(message.Ending).To(Equal("ZINGER left the game"))
+				})
+			})
+
 			When("zinger impersonates flame to take flame's turn", func() {
 				BeforeEach(Send(&zinger, messages.PlaceDisk{Nickname: "flame", Host: "flame", X: 2, Y: 4}))
 
@@ -421,6 +2032,26 @@ var _ = Describe("Server", func() {
 				BeforeEach(Send(&zinger, messages.LeaveGame{Nickname: "zinger", Host: "flame"}))
 
 				It("should notify flame that zinger left", testutil.ExpectPlayerLeft(&flame, "zinger"))
+
+				When("craig gets flame's profile", func() {
+					BeforeEach(Send(&craig, messages.GetProfile{Player: "flame"}))
+
+					It("should rate the game as a win for flame", func() {
+						var message messages.Profile
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Rating).To(BeNumerically(">", 1200))
+					})
+				})
+
+				When("craig gets zinger's profile", func() {
+					BeforeEach(Send(&craig, messages.GetProfile{Player: "zinger"}))
+
+					It("should rate the game as a loss for zinger", func() {
+						var message messages.Profile
+						Expect(craig).To(HaveReceived(&message))
+						Expect(message.Rating).To(BeNumerically("<", 1200))
+					})
+				})
 			})
 		})
 	})
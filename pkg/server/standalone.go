@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/armsnyder/othelgo/pkg/server/gatewayadapter"
+)
+
+// ListenAndServe runs the server as a standalone websocket server, without API Gateway or Lambda.
+// Connections are served by the same Handle function that runs in Lambda. The
+// APIGatewayManagementAPIClientFactory in args is ignored, since replies are written directly to
+// the websocket connections. The table is created if it doesn't exist, so args.DB can be an empty
+// memdb.DB for a server that needs no DynamoDB.
+func ListenAndServe(ctx context.Context, addr string, args Args) error {
+	if err := ensureTable(ctx, args); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return serve(ctx, ln, args, ServeOptions{})
+}
+
+// ServeOptions change how Serve runs the server.
+type ServeOptions struct {
+	// NoBackgroundJobs stops the server from running data jobs, the bot ladder, heat maps, and
+	// webhooks, such as when a client hosts a short LAN game and has no use for them.
+	NoBackgroundJobs bool
+}
+
+// Serve is like ListenAndServe, but serves connections from a listener, which it closes when ctx
+// is done. It lets a client host a server for a LAN game and connect once the listener is ready.
+func Serve(ctx context.Context, ln net.Listener, args Args, opts ServeOptions) error {
+	if err := ensureTable(ctx, args); err != nil {
+		ln.Close()
+		return err
+	}
+
+	return serve(ctx, ln, args, opts)
+}
+
+func ensureTable(ctx context.Context, args Args) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	return EnsureTable(ctx, args.DB, args.TableName)
+}
+
+func serve(ctx context.Context, ln net.Listener, args Args, opts ServeOptions) error {
+	var adapter gatewayadapter.GatewayAdapter
+
+	args.APIGatewayManagementAPIClientFactory = func(_ events.APIGatewayWebsocketProxyRequestContext) APIGatewayManagementAPIClient {
+		return &adapter
+	}
+
+	adapter.LambdaHandler = func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return Handle(ctx, req, args)
+	}
+
+	server := &http.Server{Handler: &adapter}
+
+	go func() {
+		<-ctx.Done()
+		if err := server.Close(); err != nil {
+			log.Printf("Error closing server: %v", err)
+		}
+	}()
+
+	// Nothing schedules data jobs, the bot ladder, heat maps, or webhooks outside of AWS, so the
+	// standalone server runs them itself.
+	if !opts.NoBackgroundJobs {
+		go runJobEvery(ctx, args, JobDataJobs, standaloneDataJobsInterval)
+		go runJobEvery(ctx, args, JobWebhooks, standaloneWebhooksInterval)
+		go runJobEvery(ctx, args, JobBotLadder, standaloneBotLadderInterval)
+		go runJobEvery(ctx, args, JobHeatMaps, standaloneHeatMapsInterval)
+	}
+
+	log.Print("Listening on ", ln.Addr())
+
+	if err := server.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// standaloneDataJobsInterval is how often the standalone server runs data jobs.
+const standaloneDataJobsInterval = time.Minute
+
+// standaloneWebhooksInterval is how often the standalone server posts tournament events.
+const standaloneWebhooksInterval = 10 * time.Second
+
+// standaloneBotLadderInterval is how often the standalone server runs the bot ladder.
+const standaloneBotLadderInterval = 24 * time.Hour
+
+// standaloneHeatMapsInterval is how often the standalone server counts heat maps.
+const standaloneHeatMapsInterval = 24 * time.Hour
+
+// runJobEvery runs a scheduled job at an interval until ctx is done.
+func runJobEvery(ctx context.Context, args Args, job string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := RunScheduledJob(ctx, args, job); err != nil {
+				log.Printf("Error running scheduled job %s: %v", job, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -5,17 +5,32 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
+	"os"
 	"time"
 	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/onsi/ginkgo"
 
 	"github.com/armsnyder/othelgo/pkg/server"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
 )
 
+// memoryDB is the in-memory database that tests share when OTHELGO_TEST_DB is "memory".
+var memoryDB = memdb.New()
+
+// DB returns the database that tests run against. It is the local dynamodb, unless the
+// OTHELGO_TEST_DB environment variable is "memory", in which case it is an in-memory one.
+func DB() dynamodbiface.DynamoDBAPI {
+	if os.Getenv("OTHELGO_TEST_DB") == "memory" {
+		return memoryDB
+	}
+	return server.LocalDB()
+}
+
 // testTableName returns a table name that is unique for the ginkgo test node, allowing tests to
 // run in parallel using different tables.
 func testTableName() string {
@@ -24,7 +39,7 @@ func testTableName() string {
 
 // clearOthelgoTable deletes and recreates the othelgo dynamodb table.
 func clearOthelgoTable() {
-	db := server.LocalDB()
+	db := DB()
 	tableName := testTableName()
 
 	_, _ = db.DeleteTable(&dynamodb.DeleteTableInput{
@@ -42,7 +57,7 @@ func dumpTable() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	output, err := server.LocalDB().ScanWithContext(ctx, &dynamodb.ScanInput{
+	output, err := DB().ScanWithContext(ctx, &dynamodb.ScanInput{
 		TableName: aws.String(testTableName()),
 	})
 
@@ -79,3 +94,32 @@ func dumpTable() {
 		log.Printf("testutil: item #%d: %v", i, itemFields)
 	}
 }
+
+// SetMaintenance returns a function that turns the server's maintenance mode on or off, which can
+// be used directly as an argument to ginkgo.BeforeEach.
+func SetMaintenance(enabled bool, message string) func() {
+	return func() {
+		args := server.Args{DB: DB(), TableName: testTableName()}
+		if err := server.SetMaintenance(context.Background(), args, enabled, message); err != nil {
+			panic(fmt.Errorf("testutil: Failed to set maintenance mode: %w", err))
+		}
+	}
+}
+
+// ExpireItem returns a function that sets an item's TTL to the past, as if it had been abandoned,
+// which can be used directly as an argument to ginkgo.BeforeEach. Expired items are not deleted
+// right away, so the item is still there afterwards.
+func ExpireItem(host string) func() {
+	return func() {
+		_, err := DB().UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:                 aws.String(testTableName()),
+			Key:                       map[string]*dynamodb.AttributeValue{"Host": {S: aws.String(host)}},
+			UpdateExpression:          aws.String("SET #ttl = :ttl"),
+			ExpressionAttributeNames:  map[string]*string{"#ttl": aws.String("TTL")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":ttl": {N: aws.String(fmt.Sprint(time.Now().Add(-time.Minute).Unix()))}},
+		})
+		if err != nil {
+			panic(fmt.Errorf("testutil: Failed to expire item: %w", err))
+		}
+	}
+}
@@ -17,18 +17,24 @@ func DumpTableOnFailure() {
 type Move [2]int
 
 func BuildBoard(p1, p2 []Move) (board common.Board) {
+	board.Size = common.DefaultBoardSize
+
 	for i, moves := range [][]Move{p1, p2} {
 		player := common.Disk(i + 1)
 
 		for _, move := range moves {
 			x, y := move[0], move[1]
-			board[x][y] = player
+			board.Squares[x][y] = player
 		}
 	}
 
 	return board
 }
 
+// QuickestGame is a sequence of moves, alternating between player 1 and player 2, that ends the
+// game after only 9 moves.
+var QuickestGame = [][2]int{{2, 4}, {2, 3}, {1, 2}, {1, 5}, {1, 4}, {2, 5}, {4, 2}, {1, 3}, {1, 6}}
+
 // Send is a convenience wrapper around Client.Send which can be used directly as an argument to
 // ginkgo.BeforeEach.
 func Send(client **Client, messageToSend interface{}) func() {
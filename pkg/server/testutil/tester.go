@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -28,6 +29,9 @@ func Init() *Tester {
 
 type Tester struct {
 	clients []*Client
+
+	// Branding is passed to the server as its branding.
+	Branding messages.Branding
 }
 
 // NewClient registers and returns a new Client, which has methods for sending messages to the
@@ -40,7 +44,65 @@ func (h *Tester) NewClient() *Client {
 	return client
 }
 
-func (h *Tester) invokeHandler(eventType, body, connectionID string) {
+// RunDataJobs returns a function that runs the server's data jobs, as if it was their scheduled
+// time, which can be used directly as an argument to ginkgo.BeforeEach.
+func RunDataJobs(tester **Tester) func() {
+	return func() {
+		args, _ := (*tester).args()
+		if _, err := server.RunDataJobs(context.Background(), args); err != nil {
+			panic(fmt.Errorf("testutil: Failed to run data jobs: %w", err))
+		}
+	}
+}
+
+// RegisterBot returns a function that registers a bot for the bot ladder, which can be used
+// directly as an argument to ginkgo.BeforeEach.
+func RegisterBot(tester **Tester, nickname string) func() {
+	return func() {
+		args, _ := (*tester).args()
+		if err := server.RegisterBot(context.Background(), args, nickname); err != nil {
+			panic(fmt.Errorf("testutil: Failed to register bot: %w", err))
+		}
+	}
+}
+
+// RunBotLadder returns a function that runs the bot ladder, as if it was its scheduled time, which
+// can be used directly as an argument to ginkgo.BeforeEach.
+func RunBotLadder(tester **Tester) func() {
+	return func() {
+		args, _ := (*tester).args()
+		if _, err := server.RunBotLadder(context.Background(), args); err != nil {
+			panic(fmt.Errorf("testutil: Failed to run the bot ladder: %w", err))
+		}
+	}
+}
+
+// RunHeatMaps returns a function that counts the players' heat maps, as if it was their scheduled
+// time, which can be used directly as an argument to ginkgo.BeforeEach.
+func RunHeatMaps(tester **Tester) func() {
+	return func() {
+		args, _ := (*tester).args()
+		if _, err := server.RunHeatMaps(context.Background(), args); err != nil {
+			panic(fmt.Errorf("testutil: Failed to run heat maps: %w", err))
+		}
+	}
+}
+
+// ListDisputes returns a function that lists the disputes waiting to be reviewed into disputes,
+// as an admin would, which can be used directly as an argument to ginkgo.BeforeEach.
+func ListDisputes(tester **Tester, disputes *[]server.Dispute) func() {
+	return func() {
+		args, _ := (*tester).args()
+		var err error
+		if *disputes, err = server.ListDisputes(context.Background(), args); err != nil {
+			panic(fmt.Errorf("testutil: Failed to list disputes: %w", err))
+		}
+	}
+}
+
+// args returns the server arguments, which route replies to the connected clients, keyed by
+// connection ID.
+func (h *Tester) args() (server.Args, map[string]*Client) {
 	clients := make(map[string]*Client)
 
 	for _, client := range h.clients {
@@ -49,6 +111,19 @@ func (h *Tester) invokeHandler(eventType, body, connectionID string) {
 		}
 	}
 
+	return server.Args{
+		DB:        DB(),
+		TableName: testTableName(),
+		APIGatewayManagementAPIClientFactory: func(_ events.APIGatewayWebsocketProxyRequestContext) server.APIGatewayManagementAPIClient {
+			return &responseRouter{clients: clients}
+		},
+		Branding: h.Branding,
+	}, clients
+}
+
+func (h *Tester) invokeHandler(eventType, body, connectionID string) {
+	args, clients := h.args()
+
 	sendingClient, ok := clients[connectionID]
 	if !ok {
 		panic("can't get here")
@@ -64,14 +139,6 @@ func (h *Tester) invokeHandler(eventType, body, connectionID string) {
 		},
 	}
 
-	args := server.Args{
-		DB:        server.LocalDB(),
-		TableName: testTableName(),
-		APIGatewayManagementAPIClientFactory: func(_ events.APIGatewayWebsocketProxyRequestContext) server.APIGatewayManagementAPIClient {
-			return &responseRouter{clients: clients}
-		},
-	}
-
 	log.Printf("testutil: invoking handler (eventType=%q, connectionID=%q)", eventType, connectionID)
 	_, err := server.Handle(context.Background(), req, args)
 	if err != nil {
@@ -97,10 +164,24 @@ func (r *responseRouter) PostToConnectionWithContext(_ aws.Context, input *apiga
 	return &apigatewaymanagementapi.PostToConnectionOutput{}, nil
 }
 
+func (r *responseRouter) DeleteConnectionWithContext(_ aws.Context, input *apigatewaymanagementapi.DeleteConnectionInput, _ ...request.Option) (*apigatewaymanagementapi.DeleteConnectionOutput, error) {
+	client, ok := r.clients[*input.ConnectionId]
+	if !ok {
+		return nil, &apigatewaymanagementapi.GoneException{}
+	}
+
+	client.disconnectedByServer = true
+
+	return &apigatewaymanagementapi.DeleteConnectionOutput{}, nil
+}
+
 type Client struct {
 	tester                *Tester
 	connectionID          string
 	messagesSinceLastSend []interface{}
+
+	// disconnectedByServer is set when the server closes the connection.
+	disconnectedByServer bool
 }
 
 // Connect sends a CONNECT message to server.Handle and waits for server.Handle to return.
@@ -146,6 +227,11 @@ func (c *Client) Send(message interface{}) {
 	c.tester.invokeHandler("MESSAGE", string(raw), c.connectionID)
 }
 
+// DisconnectedByServer returns whether the server has closed the client's connection.
+func (c *Client) DisconnectedByServer() bool {
+	return c.disconnectedByServer
+}
+
 func (c *Client) resetReceivedMessages() {
 	c.messagesSinceLastSend = nil
 }
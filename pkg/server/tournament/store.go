@@ -0,0 +1,192 @@
+package tournament
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// A tournament is stored as JSON in an item of its own, and each player has an item that names the
+// tournament they are playing in, so that the server can find the tournament when a game ends.
+// Both live in the server's table, next to its other items, with keys that can't be nicknames.
+
+const (
+	attribHost       = "Host"
+	attribTournament = "Tournament"
+	attribVersion    = "Version"
+	attribTTL        = "TTL"
+)
+
+// itemTTL is how long a tournament is kept after it last changed.
+const itemTTL = 7 * 24 * time.Hour
+
+// maxAttempts is how many times Update tries to save a tournament that other requests keep
+// changing at the same time.
+const maxAttempts = 5
+
+var (
+	ErrNotFound  = errors.New("no such tournament")
+	ErrContended = errors.New("the tournament is being changed by too many requests")
+)
+
+// Store keeps tournaments in a DynamoDB table.
+type Store struct {
+	DB        dynamodbiface.DynamoDBAPI
+	TableName string
+}
+
+// Create saves a new tournament. It is not ok if a tournament with the same name already exists.
+func (s Store) Create(ctx context.Context, t *Tournament) (bool, error) {
+	err := s.put(ctx, t, expression.Name(attribHost).AttributeNotExists())
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Get returns a tournament, or ErrNotFound.
+func (s Store) Get(ctx context.Context, name string) (*Tournament, error) {
+	output, err := s.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.TableName),
+		Key:       hostKey(tournamentKey(name)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct{ Tournament []byte }
+	if err := dynamodbattribute.UnmarshalMap(output.Item, &item); err != nil {
+		return nil, err
+	}
+
+	if item.Tournament == nil {
+		return nil, ErrNotFound
+	}
+
+	var t Tournament
+	err = json.Unmarshal(item.Tournament, &t)
+
+	return &t, err
+}
+
+// Update changes a tournament with fn and saves it. If another request saves the tournament first,
+// fn is called again with the newer tournament. Errors from fn are returned without saving.
+func (s Store) Update(ctx context.Context, name string, fn func(t *Tournament) error) (*Tournament, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		t, err := s.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		version := t.Version
+		if err := fn(t); err != nil {
+			return nil, err
+		}
+
+		err = s.put(ctx, t, expression.Name(attribVersion).Equal(expression.Value(version)))
+
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			continue
+		}
+
+		return t, err
+	}
+
+	return nil, ErrContended
+}
+
+// SetPlayerTournament records the tournament that a player is playing in.
+func (s Store) SetPlayerTournament(ctx context.Context, nickname, name string) error {
+	update := expression.
+		Set(expression.Name(attribTournament), expression.Value(name)).
+		Set(expression.Name(attribTTL), expression.Value(time.Now().Add(itemTTL).Unix()))
+
+	exp, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.TableName),
+		Key:                       hostKey(playerKey(nickname)),
+		UpdateExpression:          exp.Update(),
+		ExpressionAttributeNames:  exp.Names(),
+		ExpressionAttributeValues: exp.Values(),
+	})
+
+	return err
+}
+
+// PlayerTournament returns the name of the tournament that a player is playing in, or an empty
+// string if they aren't playing in one.
+func (s Store) PlayerTournament(ctx context.Context, nickname string) (string, error) {
+	output, err := s.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.TableName),
+		Key:       hostKey(playerKey(nickname)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var item struct{ Tournament string }
+	err = dynamodbattribute.UnmarshalMap(output.Item, &item)
+
+	return item.Tournament, err
+}
+
+// put saves a tournament with the next version, if the condition holds.
+func (s Store) put(ctx context.Context, t *Tournament, condition expression.ConditionBuilder) error {
+	t.Version++
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	update := expression.
+		Set(expression.Name(attribTournament), expression.Value(b)).
+		Set(expression.Name(attribVersion), expression.Value(t.Version)).
+		Set(expression.Name(attribTTL), expression.Value(time.Now().Add(itemTTL).Unix()))
+
+	exp, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.TableName),
+		Key:                       hostKey(tournamentKey(t.Name)),
+		UpdateExpression:          exp.Update(),
+		ConditionExpression:       exp.Condition(),
+		ExpressionAttributeNames:  exp.Names(),
+		ExpressionAttributeValues: exp.Values(),
+	})
+
+	return err
+}
+
+func hostKey(host string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{attribHost: {S: aws.String(host)}}
+}
+
+// tournamentKey is the primary key of a tournament.
+func tournamentKey(name string) string {
+	return "#tournament#" + name
+}
+
+// playerKey is the primary key of the record of which tournament a player is playing in.
+func playerKey(nickname string) string {
+	return "#tournamentPlayer#" + nickname
+}
@@ -0,0 +1,99 @@
+package tournament_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/armsnyder/othelgo/pkg/server"
+	"github.com/armsnyder/othelgo/pkg/server/testutil"
+	"github.com/armsnyder/othelgo/pkg/server/tournament"
+)
+
+// These tests need a local dynamodb, like the server suite.
+var _ = Describe("Store", func() {
+	var (
+		ctx   context.Context
+		store tournament.Store
+	)
+
+	BeforeEach(func() {
+		if testing.Short() {
+			Skip("skipping in short mode")
+		}
+
+		ctx = context.Background()
+		store = tournament.Store{
+			DB:        testutil.DB(),
+			TableName: fmt.Sprintf("Othelgo-Tournament-%d", GinkgoParallelNode()),
+		}
+
+		_, _ = store.DB.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(store.TableName)})
+		Expect(server.EnsureTable(ctx, store.DB, store.TableName)).To(Succeed())
+	})
+
+	It("should get a created tournament", func() {
+		Expect(store.Create(ctx, tournament.New("cup", "andy", 4, 0))).To(BeTrue())
+
+		t, err := store.Get(ctx, "cup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t.Organizer).To(Equal("andy"))
+		Expect(t.Size).To(Equal(4))
+	})
+
+	It("should not create a tournament twice", func() {
+		Expect(store.Create(ctx, tournament.New("cup", "andy", 4, 0))).To(BeTrue())
+		Expect(store.Create(ctx, tournament.New("cup", "bob", 2, 0))).To(BeFalse())
+	})
+
+	It("should not find a tournament that was never created", func() {
+		_, err := store.Get(ctx, "cup")
+		Expect(err).To(MatchError(tournament.ErrNotFound))
+	})
+
+	It("should save an update", func() {
+		Expect(store.Create(ctx, tournament.New("cup", "andy", 4, 0))).To(BeTrue())
+
+		_, err := store.Update(ctx, "cup", func(t *tournament.Tournament) error {
+			return t.Join("bob")
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		t, err := store.Get(ctx, "cup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t.Players).To(Equal([]string{"bob"}))
+	})
+
+	It("should apply an update again to a tournament that changed in the meantime", func() {
+		Expect(store.Create(ctx, tournament.New("cup", "andy", 4, 0))).To(BeTrue())
+
+		var attempts int
+		_, err := store.Update(ctx, "cup", func(t *tournament.Tournament) error {
+			attempts++
+			if attempts == 1 {
+				_, err := store.Update(ctx, "cup", func(t *tournament.Tournament) error {
+					return t.Join("carl")
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			return t.Join("bob")
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts).To(Equal(2))
+
+		t, err := store.Get(ctx, "cup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(t.Players).To(Equal([]string{"carl", "bob"}))
+	})
+
+	It("should remember which tournament a player is playing in", func() {
+		Expect(store.SetPlayerTournament(ctx, "bob", "cup")).To(Succeed())
+		Expect(store.PlayerTournament(ctx, "bob")).To(Equal("cup"))
+		Expect(store.PlayerTournament(ctx, "carl")).To(BeEmpty())
+	})
+})
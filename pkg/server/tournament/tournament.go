@@ -0,0 +1,430 @@
+// Package tournament runs Swiss-system tournaments. Every player plays in every round, against a
+// player with a similar score whom they haven't played yet, so a tournament needs far fewer rounds
+// than a round robin while still ranking the players.
+//
+// The package only knows the rules of a tournament. The server decides when games start and end,
+// and keeps tournaments in its table with a Store.
+package tournament
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+)
+
+// Points are what each result is worth in the standings. The winner of a forfeited game gets Win,
+// and the player who forfeited gets Forfeit.
+type Points struct {
+	Win     int
+	Draw    int
+	Loss    int
+	Bye     int
+	Forfeit int
+}
+
+// DefaultPoints count a bye as a win.
+var DefaultPoints = Points{Win: 2, Draw: 1, Bye: 2}
+
+var (
+	ErrFull          = errors.New("the tournament is full")
+	ErrStarted       = errors.New("the tournament has already started")
+	ErrNotPaired     = errors.New("the players are not paired in the current round")
+	ErrNotRegistered = errors.New("the player is not in the tournament")
+)
+
+// Tournament is the state of a tournament, from registration until the last round is over.
+type Tournament struct {
+	Name      string
+	Organizer string
+
+	// Size is the number of players. The tournament starts as soon as it is full.
+	Size   int
+	Rounds int
+
+	// Points are zero for tournaments from before points could be configured, which use
+	// DefaultPoints.
+	Points Points
+
+	// Players are in the order that they joined, which breaks ties in the standings. Players who
+	// withdraw after the tournament has started stay in the standings, but aren't paired again.
+	Players   []string
+	Withdrawn []string
+
+	// Round is the current round, starting at 1. It is 0 until the tournament starts.
+	Round    int
+	Pairings []Pairing
+
+	// Webhook is the URL that the tournament's events are posted to, or empty.
+	Webhook string
+
+	// Version is incremented by the Store whenever the tournament is saved.
+	Version int
+}
+
+// Pairing is a game between two players in a round. Guest is empty for a bye. Forfeit means that
+// the player who isn't the winner forfeited the game.
+type Pairing struct {
+	Round   int
+	Host    string
+	Guest   string
+	Done    bool
+	Winner  string
+	Forfeit bool
+}
+
+// Standing is a player's record in a tournament. Byes and forfeits are counted apart from wins and
+// losses, but a win by forfeit is a win.
+type Standing struct {
+	Nickname  string
+	Points    int
+	Wins      int
+	Draws     int
+	Losses    int
+	Byes      int
+	Forfeits  int
+	Withdrawn bool
+}
+
+// New returns a tournament that is open for registration. A number of rounds of 0 means enough
+// rounds to leave a single undefeated player.
+func New(name, organizer string, size, rounds int) *Tournament {
+	if rounds <= 0 {
+		rounds = DefaultRounds(size)
+	}
+
+	// With more rounds than opponents, somebody would have to play the same player twice.
+	if rounds > size-1 {
+		rounds = size - 1
+	}
+
+	return &Tournament{
+		Name:      name,
+		Organizer: organizer,
+		Size:      size,
+		Rounds:    rounds,
+	}
+}
+
+// DefaultRounds returns the number of rounds for a number of players, which is log2 of the number
+// of players, rounded up.
+func DefaultRounds(size int) int {
+	if size < 2 {
+		return 1
+	}
+	return bits.Len(uint(size - 1))
+}
+
+// Status returns one of the messages.Tournament* statuses.
+func (t *Tournament) Status() string {
+	switch {
+	case t.Round == 0:
+		return messages.TournamentRegistering
+	case t.finished():
+		return messages.TournamentFinished
+	default:
+		return messages.TournamentPlaying
+	}
+}
+
+// PointValues returns what each result is worth in the tournament.
+func (t *Tournament) PointValues() Points {
+	if t.Points == (Points{}) {
+		return DefaultPoints
+	}
+	return t.Points
+}
+
+// Join registers a player, and starts the tournament once it is full. Joining again is not an
+// error, so that players can rejoin a tournament after reconnecting.
+func (t *Tournament) Join(nickname string) error {
+	if t.hasPlayer(nickname) {
+		return nil
+	}
+
+	if t.Round > 0 {
+		return ErrStarted
+	}
+
+	if len(t.Players) >= t.Size {
+		return ErrFull
+	}
+
+	t.Players = append(t.Players, nickname)
+
+	if len(t.Players) == t.Size {
+		t.nextRound()
+	}
+
+	return nil
+}
+
+// CurrentPairings returns the pairings of the current round.
+func (t *Tournament) CurrentPairings() []Pairing {
+	var pairings []Pairing
+	for _, p := range t.Pairings {
+		if p.Round == t.Round {
+			pairings = append(pairings, p)
+		}
+	}
+	return pairings
+}
+
+// RecordResult records the result of a game between two players, in either role. The winner is
+// empty for a draw. Once every game in the round is over, the next round is paired.
+func (t *Tournament) RecordResult(host, guest, winner string) error {
+	i := t.pendingPairing(host, guest)
+	if i < 0 {
+		return ErrNotPaired
+	}
+
+	t.Pairings[i].Done = true
+	t.Pairings[i].Winner = winner
+	t.advance()
+
+	return nil
+}
+
+// RecordForfeit records that a player forfeited their game against an opponent.
+func (t *Tournament) RecordForfeit(nickname, opponent string) error {
+	i := t.pendingPairing(nickname, opponent)
+	if i < 0 {
+		return ErrNotPaired
+	}
+
+	t.Pairings[i].Done = true
+	t.Pairings[i].Winner = opponent
+	t.Pairings[i].Forfeit = true
+	t.advance()
+
+	return nil
+}
+
+// Withdraw takes a player out of the tournament. Before the tournament starts, the player is simply
+// unregistered. Afterwards, the player forfeits their game in the current round, if it isn't over,
+// and isn't paired in later rounds.
+func (t *Tournament) Withdraw(nickname string) error {
+	if !t.hasPlayer(nickname) {
+		return ErrNotRegistered
+	}
+
+	if t.Round == 0 {
+		for i, p := range t.Players {
+			if p == nickname {
+				t.Players = append(t.Players[:i], t.Players[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+
+	if t.withdrawn(nickname) {
+		return nil
+	}
+
+	t.Withdrawn = append(t.Withdrawn, nickname)
+
+	for i, p := range t.Pairings {
+		if p.Round == t.Round && !p.Done && (p.Host == nickname || p.Guest == nickname) {
+			t.Pairings[i].Done = true
+			t.Pairings[i].Winner = otherPlayer(p, nickname)
+			t.Pairings[i].Forfeit = true
+		}
+	}
+
+	t.advance()
+
+	return nil
+}
+
+// advance pairs the next round once every game in the current round is over. Rounds in which
+// nobody is left to play are skipped.
+func (t *Tournament) advance() {
+	for t.Round > 0 && t.roundOver() && t.Round < t.Rounds {
+		t.nextRound()
+	}
+}
+
+// Standings returns every player's record, with the leader first. Ties are broken by wins, and
+// then by the order that the players joined.
+func (t *Tournament) Standings() []Standing {
+	standings := make([]Standing, len(t.Players))
+	index := make(map[string]int, len(t.Players))
+	for i, nickname := range t.Players {
+		standings[i].Nickname = nickname
+		standings[i].Withdrawn = t.withdrawn(nickname)
+		index[nickname] = i
+	}
+
+	for _, p := range t.Pairings {
+		if !p.Done {
+			continue
+		}
+
+		if p.Guest == "" {
+			standings[index[p.Host]].Byes++
+			continue
+		}
+
+		host, guest := &standings[index[p.Host]], &standings[index[p.Guest]]
+
+		switch {
+		case p.Winner == "":
+			host.Draws++
+			guest.Draws++
+		case p.Forfeit:
+			standings[index[p.Winner]].Wins++
+			standings[index[otherPlayer(p, p.Winner)]].Forfeits++
+		case p.Winner == p.Host:
+			host.Wins++
+			guest.Losses++
+		default:
+			host.Losses++
+			guest.Wins++
+		}
+	}
+
+	points := t.PointValues()
+	for i := range standings {
+		s := &standings[i]
+		s.Points = s.Wins*points.Win + s.Draws*points.Draw + s.Losses*points.Loss + s.Byes*points.Bye + s.Forfeits*points.Forfeit
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Points != standings[j].Points {
+			return standings[i].Points > standings[j].Points
+		}
+		return standings[i].Wins > standings[j].Wins
+	})
+
+	return standings
+}
+
+// nextRound pairs the players for the next round. The players are ranked by their standings, and
+// each player is paired with the highest ranked player below them that they haven't played. With
+// an odd number of players, the lowest ranked player that hasn't had a bye sits the round out.
+func (t *Tournament) nextRound() {
+	t.Round++
+
+	var unpaired []string
+	for _, s := range t.Standings() {
+		if !s.Withdrawn {
+			unpaired = append(unpaired, s.Nickname)
+		}
+	}
+
+	if len(unpaired)%2 == 1 {
+		bye := len(unpaired) - 1
+		for i := len(unpaired) - 1; i >= 0; i-- {
+			if !t.hadBye(unpaired[i]) {
+				bye = i
+				break
+			}
+		}
+
+		nickname := unpaired[bye]
+		unpaired = append(unpaired[:bye], unpaired[bye+1:]...)
+		t.Pairings = append(t.Pairings, Pairing{Round: t.Round, Host: nickname, Done: true, Winner: nickname})
+	}
+
+	for len(unpaired) > 0 {
+		a := unpaired[0]
+
+		// Settle for a rematch if everybody left has already played a.
+		j := 1
+		for k := 1; k < len(unpaired); k++ {
+			if !t.played(a, unpaired[k]) {
+				j = k
+				break
+			}
+		}
+
+		b := unpaired[j]
+		unpaired = append(unpaired[1:j], unpaired[j+1:]...)
+
+		// Players take turns hosting, since the host moves first.
+		host, guest := a, b
+		if t.hosted(b) < t.hosted(a) {
+			host, guest = b, a
+		}
+
+		t.Pairings = append(t.Pairings, Pairing{Round: t.Round, Host: host, Guest: guest})
+	}
+}
+
+func (t *Tournament) pendingPairing(a, b string) int {
+	for i, p := range t.Pairings {
+		if p.Round == t.Round && !p.Done && (p.Host == a && p.Guest == b || p.Host == b && p.Guest == a) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Tournament) roundOver() bool {
+	for _, p := range t.Pairings {
+		if p.Round == t.Round && !p.Done {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tournament) finished() bool {
+	return t.Round == t.Rounds && t.roundOver()
+}
+
+func (t *Tournament) hasPlayer(nickname string) bool {
+	for _, p := range t.Players {
+		if p == nickname {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tournament) played(a, b string) bool {
+	for _, p := range t.Pairings {
+		if p.Host == a && p.Guest == b || p.Host == b && p.Guest == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tournament) withdrawn(nickname string) bool {
+	for _, w := range t.Withdrawn {
+		if w == nickname {
+			return true
+		}
+	}
+	return false
+}
+
+// otherPlayer returns the player in a pairing who isn't nickname.
+func otherPlayer(p Pairing, nickname string) string {
+	if p.Host == nickname {
+		return p.Guest
+	}
+	return p.Host
+}
+
+func (t *Tournament) hadBye(nickname string) bool {
+	for _, p := range t.Pairings {
+		if p.Host == nickname && p.Guest == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tournament) hosted(nickname string) int {
+	var n int
+	for _, p := range t.Pairings {
+		if p.Host == nickname && p.Guest != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,262 @@
+package tournament_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server/tournament"
+)
+
+var _ = Describe("Tournament", func() {
+	var t *tournament.Tournament
+
+	// joinAll registers the players in order.
+	joinAll := func(nicknames ...string) {
+		for _, nickname := range nicknames {
+			Expect(t.Join(nickname)).To(Succeed())
+		}
+	}
+
+	// playRound records a result for every game in the current round, which the host wins.
+	playRound := func() {
+		for _, p := range t.CurrentPairings() {
+			if !p.Done {
+				Expect(t.RecordResult(p.Host, p.Guest, p.Host)).To(Succeed())
+			}
+		}
+	}
+
+	Describe("New", func() {
+		It("should default to enough rounds to find a single winner", func() {
+			Expect(tournament.New("cup", "andy", 8, 0).Rounds).To(Equal(3))
+			Expect(tournament.New("cup", "andy", 5, 0).Rounds).To(Equal(3))
+			Expect(tournament.New("cup", "andy", 2, 0).Rounds).To(Equal(1))
+		})
+
+		It("should not have more rounds than opponents", func() {
+			Expect(tournament.New("cup", "andy", 4, 10).Rounds).To(Equal(3))
+		})
+	})
+
+	When("registering", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 4, 0)
+		})
+
+		It("should be registering", func() {
+			joinAll("andy", "bob")
+			Expect(t.Status()).To(Equal(messages.TournamentRegistering))
+			Expect(t.Round).To(BeZero())
+			Expect(t.CurrentPairings()).To(BeEmpty())
+		})
+
+		It("should not register a player twice", func() {
+			joinAll("andy", "andy")
+			Expect(t.Players).To(Equal([]string{"andy"}))
+		})
+
+		It("should start once it is full", func() {
+			joinAll("andy", "bob", "carl", "dave")
+			Expect(t.Status()).To(Equal(messages.TournamentPlaying))
+			Expect(t.Round).To(Equal(1))
+			Expect(t.CurrentPairings()).To(HaveLen(2))
+		})
+
+		It("should refuse players once it has started", func() {
+			joinAll("andy", "bob", "carl", "dave")
+			Expect(t.Join("ed")).To(MatchError(tournament.ErrStarted))
+		})
+
+		It("should let registered players join again once it has started", func() {
+			joinAll("andy", "bob", "carl", "dave")
+			Expect(t.Join("bob")).To(Succeed())
+		})
+	})
+
+	When("playing with an even number of players", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 4, 0)
+			joinAll("andy", "bob", "carl", "dave")
+		})
+
+		It("should pair everybody in the first round", func() {
+			Expect(t.CurrentPairings()).To(ConsistOf(
+				tournament.Pairing{Round: 1, Host: "andy", Guest: "bob"},
+				tournament.Pairing{Round: 1, Host: "carl", Guest: "dave"},
+			))
+		})
+
+		It("should record a result in either role", func() {
+			Expect(t.RecordResult("bob", "andy", "bob")).To(Succeed())
+			Expect(t.CurrentPairings()).To(ContainElement(tournament.Pairing{Round: 1, Host: "andy", Guest: "bob", Done: true, Winner: "bob"}))
+		})
+
+		It("should refuse results for players that aren't paired", func() {
+			Expect(t.RecordResult("andy", "carl", "andy")).To(MatchError(tournament.ErrNotPaired))
+		})
+
+		It("should refuse a second result for the same game", func() {
+			Expect(t.RecordResult("andy", "bob", "andy")).To(Succeed())
+			Expect(t.RecordResult("andy", "bob", "bob")).To(MatchError(tournament.ErrNotPaired))
+		})
+
+		It("should not start the next round until every game is over", func() {
+			Expect(t.RecordResult("andy", "bob", "andy")).To(Succeed())
+			Expect(t.Round).To(Equal(1))
+		})
+
+		It("should pair the winners and the losers in the next round", func() {
+			playRound()
+			Expect(t.Round).To(Equal(2))
+			Expect(t.CurrentPairings()).To(ConsistOf(
+				tournament.Pairing{Round: 2, Host: "andy", Guest: "carl"},
+				tournament.Pairing{Round: 2, Host: "bob", Guest: "dave"},
+			))
+		})
+
+		It("should let players who were guests host in the next round", func() {
+			Expect(t.RecordResult("andy", "bob", "bob")).To(Succeed())
+			Expect(t.RecordResult("carl", "dave", "dave")).To(Succeed())
+			Expect(t.CurrentPairings()).To(ConsistOf(
+				tournament.Pairing{Round: 2, Host: "bob", Guest: "dave"},
+				tournament.Pairing{Round: 2, Host: "andy", Guest: "carl"},
+			))
+		})
+
+		It("should not pair players who have already played each other", func() {
+			playRound()
+			playRound()
+			for _, p := range t.CurrentPairings() {
+				Expect([]string{p.Host, p.Guest}).NotTo(ConsistOf("andy", "bob"))
+				Expect([]string{p.Host, p.Guest}).NotTo(ConsistOf("carl", "dave"))
+			}
+		})
+
+		It("should finish after the last round", func() {
+			playRound()
+			playRound()
+			Expect(t.Status()).To(Equal(messages.TournamentFinished))
+			Expect(t.Round).To(Equal(2))
+		})
+
+		It("should rank the players by points", func() {
+			Expect(t.RecordResult("andy", "bob", "andy")).To(Succeed())
+			Expect(t.RecordResult("carl", "dave", "")).To(Succeed())
+			Expect(t.Standings()).To(Equal([]tournament.Standing{
+				{Nickname: "andy", Points: 2, Wins: 1},
+				{Nickname: "carl", Points: 1, Draws: 1},
+				{Nickname: "dave", Points: 1, Draws: 1},
+				{Nickname: "bob", Points: 0, Losses: 1},
+			}))
+		})
+	})
+
+	When("playing with an odd number of players", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 3, 0)
+			joinAll("andy", "bob", "carl")
+		})
+
+		It("should give the lowest ranked player a bye", func() {
+			Expect(t.CurrentPairings()).To(ConsistOf(
+				tournament.Pairing{Round: 1, Host: "carl", Done: true, Winner: "carl"},
+				tournament.Pairing{Round: 1, Host: "andy", Guest: "bob"},
+			))
+		})
+
+		It("should count a bye as worth a win", func() {
+			Expect(t.Standings()[0]).To(Equal(tournament.Standing{Nickname: "carl", Points: 2, Byes: 1}))
+		})
+
+		It("should not give anybody a second bye", func() {
+			playRound()
+			Expect(t.CurrentPairings()).To(ContainElement(tournament.Pairing{Round: 2, Host: "bob", Done: true, Winner: "bob"}))
+		})
+	})
+
+	When("a player forfeits", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 4, 0)
+			joinAll("andy", "bob", "carl", "dave")
+			Expect(t.RecordForfeit("bob", "andy")).To(Succeed())
+		})
+
+		It("should record the game as forfeited", func() {
+			Expect(t.CurrentPairings()).To(ContainElement(tournament.Pairing{Round: 1, Host: "andy", Guest: "bob", Done: true, Winner: "andy", Forfeit: true}))
+		})
+
+		It("should count the forfeit apart from losses", func() {
+			Expect(t.Standings()).To(ContainElement(tournament.Standing{Nickname: "andy", Points: 2, Wins: 1}))
+			Expect(t.Standings()).To(ContainElement(tournament.Standing{Nickname: "bob", Forfeits: 1}))
+		})
+	})
+
+	When("a player withdraws", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 4, 0)
+			joinAll("andy", "bob", "carl", "dave")
+			Expect(t.Withdraw("bob")).To(Succeed())
+		})
+
+		It("should forfeit the player's game", func() {
+			Expect(t.CurrentPairings()).To(ContainElement(tournament.Pairing{Round: 1, Host: "andy", Guest: "bob", Done: true, Winner: "andy", Forfeit: true}))
+		})
+
+		It("should keep the player in the standings", func() {
+			Expect(t.Standings()).To(ContainElement(tournament.Standing{Nickname: "bob", Forfeits: 1, Withdrawn: true}))
+		})
+
+		It("should not pair the player in later rounds", func() {
+			playRound()
+			Expect(t.Round).To(Equal(2))
+			for _, p := range t.CurrentPairings() {
+				Expect(p.Host).NotTo(Equal("bob"))
+				Expect(p.Guest).NotTo(Equal("bob"))
+			}
+			Expect(t.CurrentPairings()).To(HaveLen(2), "one game and a bye")
+		})
+	})
+
+	When("a player withdraws before the tournament starts", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 4, 0)
+			joinAll("andy", "bob")
+			Expect(t.Withdraw("bob")).To(Succeed())
+		})
+
+		It("should unregister the player", func() {
+			Expect(t.Players).To(Equal([]string{"andy"}))
+		})
+	})
+
+	It("should refuse to withdraw a player who isn't registered", func() {
+		t = tournament.New("cup", "andy", 4, 0)
+		Expect(t.Withdraw("bob")).To(MatchError(tournament.ErrNotRegistered))
+	})
+
+	It("should finish early when everybody withdraws", func() {
+		t = tournament.New("cup", "andy", 2, 0)
+		joinAll("andy", "bob")
+		Expect(t.Withdraw("andy")).To(Succeed())
+		Expect(t.Withdraw("bob")).To(Succeed())
+		Expect(t.Status()).To(Equal(messages.TournamentFinished))
+	})
+
+	When("the points are configured", func() {
+		BeforeEach(func() {
+			t = tournament.New("cup", "andy", 3, 1)
+			t.Points = tournament.Points{Win: 3, Draw: 1, Loss: 1, Bye: 1}
+			joinAll("andy", "bob", "carl")
+			Expect(t.RecordResult("andy", "bob", "bob")).To(Succeed())
+		})
+
+		It("should use them in the standings", func() {
+			Expect(t.Standings()).To(Equal([]tournament.Standing{
+				{Nickname: "bob", Points: 3, Wins: 1},
+				{Nickname: "andy", Points: 1, Losses: 1},
+				{Nickname: "carl", Points: 1, Byes: 1},
+			}))
+		})
+	})
+})
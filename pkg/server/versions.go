@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Version is the server version, which is reported to clients. It is set at build time using
+// ldflags.
+var Version = "0.0.0"
+
+// minClientVersion is the oldest client release that understands the current protocol. Raise it
+// when a change to the messages would confuse older clients. Development builds, which are version
+// 0.0.0, are always allowed.
+var minClientVersion = "0.0.0"
+
+// features lists the optional parts of the protocol that this server supports, so that clients
+// can hide options that an older server does not have.
+var features = []string{"replays", "presets", "lounge", "presence", "boardSizes", "resume", "lite", "nicknames", "tournaments", "colors", "ratings", "sequencing", "profiles", "compactBoards", "privacy", "dataJobs", "branding", "kibitz", "disputes", "botLadder", "countdown", "adaptive", "webhooks"}
+
+// currentProtocol is the version of the message protocol handled by routeMessage.
+const currentProtocol = 0
+
+// router decodes and handles messages for one version of the protocol.
+type router func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error
+
+// protocol is a version of the message protocol that the server can serve.
+type protocol struct {
+	version int
+	// since is the oldest client release that speaks this version of the protocol.
+	since string
+	route router
+}
+
+// protocols lists the protocol versions that the server serves, newest first. When making a
+// breaking change to the messages, add a protocol for the new clients and keep the previous one
+// until its clients have upgraded, so that both are served side by side. Hello is routed before the
+// client's protocol is known, so it must stay compatible across versions.
+var protocols []protocol
+
+func init() {
+	// Protocols are assigned in init because the handlers refer back to protocols.
+	protocols = []protocol{
+		{version: currentProtocol, since: "0.0.0", route: routeMessage},
+	}
+}
+
+// clientSupported returns whether a client of the specified version can talk to this server.
+func clientSupported(clientVersion string) bool {
+	return clientVersion == "0.0.0" || compareVersions(clientVersion, minClientVersion) >= 0
+}
+
+// protocolOf returns the newest protocol version that a client of the specified version speaks.
+// Development builds speak the newest protocol.
+func protocolOf(clientVersion string) int {
+	if clientVersion == "0.0.0" {
+		return protocols[0].version
+	}
+
+	for _, p := range protocols {
+		if compareVersions(clientVersion, p.since) >= 0 {
+			return p.version
+		}
+	}
+
+	return protocols[len(protocols)-1].version
+}
+
+// getRouter returns the router for the protocol of a connection. Connections that have not said
+// hello use the newest protocol.
+func getRouter(ctx context.Context, args Args, connID string) (router, error) {
+	// There is nothing to look up unless more than one protocol is being served.
+	if len(protocols) == 1 {
+		return protocols[0].route, nil
+	}
+
+	version, ok, err := getProtocol(ctx, args, connID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		for _, p := range protocols {
+			if p.version == version {
+				return p.route, nil
+			}
+		}
+	}
+
+	return protocols[0].route, nil
+}
+
+// compareVersions compares two semantic versions, returning -1, 0, or 1. Build metadata is
+// ignored, and any pre-release sorts before the release itself.
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	for i := range aCore {
+		switch {
+		case aCore[i] < bCore[i]:
+			return -1
+		case aCore[i] > bCore[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func splitVersion(version string) (core [3]int, pre string) {
+	version = strings.TrimPrefix(version, "v")
+
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version, pre = version[:i], version[i+1:]
+	}
+
+	for i, part := range strings.SplitN(version, ".", 3) {
+		core[i], _ = strconv.Atoi(part)
+	}
+
+	return core, pre
+}
+
+func upgradeRequiredMessage(clientVersion string) string {
+	return fmt.Sprintf("client version %s is no longer supported, please upgrade to %s or later", clientVersion, minClientVersion)
+}
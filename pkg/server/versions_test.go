@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("1.2.3", "1.2.3"))
+	assert.Equal(t, 0, compareVersions("1.2.3+build.5", "1.2.3"))
+	assert.Equal(t, -1, compareVersions("1.2.3", "1.10.0"))
+	assert.Equal(t, 1, compareVersions("2.0.0", "1.99.99"))
+	assert.Equal(t, -1, compareVersions("1.0.0-rc.1", "1.0.0"))
+	assert.Equal(t, 1, compareVersions("1.0.0", "1.0.0-rc.1"))
+	assert.Equal(t, -1, compareVersions("1.0.0-alpha", "1.0.0-beta"))
+}
+
+func TestClientSupported(t *testing.T) {
+	defer func(v string) { minClientVersion = v }(minClientVersion)
+	minClientVersion = "1.4.0"
+
+	assert.True(t, clientSupported("0.0.0"))
+	assert.True(t, clientSupported("1.4.0"))
+	assert.True(t, clientSupported("2.0.0"))
+	assert.False(t, clientSupported("1.3.9"))
+	assert.False(t, clientSupported("1.4.0-rc.1"))
+}
+
+func TestProtocolOf(t *testing.T) {
+	defer func(p []protocol) { protocols = p }(protocols)
+	protocols = []protocol{
+		{version: 1, since: "2.0.0", route: routeMessage},
+		{version: 0, since: "0.0.0", route: routeMessage},
+	}
+
+	assert.Equal(t, 1, protocolOf("0.0.0"))
+	assert.Equal(t, 1, protocolOf("2.0.0"))
+	assert.Equal(t, 1, protocolOf("2.3.1"))
+	assert.Equal(t, 0, protocolOf("1.9.9"))
+	assert.Equal(t, 0, protocolOf("2.0.0-rc.1"))
+}
+
+func TestRouteByProtocol(t *testing.T) {
+	ctx := context.Background()
+
+	// Both protocols are served by routeMessage, and only record which of them was used.
+	var routed []int
+	stub := func(version int) router {
+		return func(ctx context.Context, req events.APIGatewayWebsocketProxyRequest, args Args) error {
+			routed = append(routed, version)
+			return routeMessage(ctx, req, args)
+		}
+	}
+
+	defer func(p []protocol) { protocols = p }(protocols)
+	protocols = []protocol{
+		{version: 1, since: "2.0.0", route: stub(1)},
+		{version: 0, since: "0.0.0", route: stub(0)},
+	}
+
+	var recorder postRecorder
+	args := Args{
+		DB:        memdb.New(),
+		TableName: "Othelgo",
+		APIGatewayManagementAPIClientFactory: func(events.APIGatewayWebsocketProxyRequestContext) APIGatewayManagementAPIClient {
+			return &recorder
+		},
+	}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	send := func(connID string, message interface{}) {
+		body, err := messages.Marshal(messages.Wrapper{Message: message})
+		require.NoError(t, err)
+		require.NoError(t, handleMessage(ctx, events.APIGatewayWebsocketProxyRequest{
+			Body:           string(body),
+			RequestContext: events.APIGatewayWebsocketProxyRequestContext{ConnectionID: connID},
+		}, args))
+	}
+
+	// Hello is routed by the newest protocol, since the client's protocol isn't known yet.
+	send("old", messages.Hello{Version: "1.9.9"})
+	send("new", messages.Hello{Version: "2.0.0"})
+	assert.Equal(t, []int{1, 1}, routed)
+
+	routed = nil
+	send("old", messages.Ping{})
+	send("new", messages.Ping{})
+	assert.Equal(t, []int{0, 1}, routed)
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server/tournament"
+)
+
+// Tournament webhooks. An organizer may give a tournament a webhook when they create it, and the
+// server posts a messages.TournamentEvent to it whenever a game finishes, the standings change, a
+// round starts, or the tournament ends, so that broadcasting tools and websites can follow the
+// tournament without polling. The events are worked out by comparing the tournament before and
+// after each change. The events are queued, and RunWebhooks posts them later, so that a slow
+// webhook doesn't hold up the players whose game changed the tournament. Each event is posted once,
+// and failures are only logged, since a broken webhook must not hold up the tournament.
+//
+// Anybody may create a tournament, so webhooks may only point to the public internet. Otherwise a
+// webhook could make the server post to itself, to the cloud metadata service, or to other hosts on
+// its private network. The address is checked when the tournament is created, and again when each
+// event is posted, in case the webhook's name has since been pointed somewhere else.
+
+// webhookTimeout is how long the server waits for a webhook to answer.
+const webhookTimeout = 3 * time.Second
+
+var defaultHTTPClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: webhookTimeout, Control: checkWebhookDial}).DialContext,
+	},
+}
+
+func (args Args) httpClient() *http.Client {
+	if args.HTTPClient != nil {
+		return args.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
+// updateTournament is like tournament.Store.Update, and then posts the events of the change to the
+// tournament's webhook.
+func updateTournament(ctx context.Context, args Args, name string, fn func(t *tournament.Tournament) error) (*tournament.Tournament, error) {
+	var before tournament.Tournament
+
+	t, err := args.tournaments().Update(ctx, name, func(t *tournament.Tournament) error {
+		// Pairings are changed in place, so the copy needs its own.
+		before = *t
+		before.Pairings = append([]tournament.Pairing(nil), t.Pairings...)
+		before.Withdrawn = append([]string(nil), t.Withdrawn...)
+		return fn(t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Webhook != "" {
+		if err := queueTournamentEvents(ctx, args, t.Name, t.Webhook, tournamentEvents(&before, t, time.Now())); err != nil {
+			log.Printf("Failed to queue the events of tournament %q for its webhook: %v", t.Name, err)
+		}
+	}
+
+	return t, nil
+}
+
+// tournamentEvents returns the events of a change to a tournament, in the order that they happened.
+func tournamentEvents(before, after *tournament.Tournament, now time.Time) []messages.TournamentEvent {
+	var events []messages.TournamentEvent
+
+	event := func(name string, round int) messages.TournamentEvent {
+		return messages.TournamentEvent{Event: name, Tournament: after.Name, Round: round, Time: millis(now)}
+	}
+
+	// Pairings are only ever added, so the ones that were there before have the same index.
+	for i, p := range after.Pairings {
+		if p.Guest == "" || !p.Done || i < len(before.Pairings) && before.Pairings[i].Done {
+			continue
+		}
+
+		e := event(messages.TournamentBoardFinished, p.Round)
+		e.Board = pairingMessage(p)
+		events = append(events, e)
+	}
+
+	if len(events) > 0 || len(after.Withdrawn) > len(before.Withdrawn) {
+		e := event(messages.TournamentStandingsUpdated, after.Round)
+		e.Standings = standingsMessage(after)
+		events = append(events, e)
+	}
+
+	for round := before.Round + 1; round <= after.Round; round++ {
+		e := event(messages.TournamentRoundStarted, round)
+		for _, p := range after.Pairings {
+			if p.Round == round {
+				e.Pairings = append(e.Pairings, *pairingMessage(p))
+			}
+		}
+		events = append(events, e)
+	}
+
+	if before.Status() != messages.TournamentFinished && after.Status() == messages.TournamentFinished {
+		e := event(messages.TournamentEnded, after.Round)
+		e.Standings = standingsMessage(after)
+		events = append(events, e)
+	}
+
+	return events
+}
+
+// queueTournamentEvents queues events for RunWebhooks to post to a tournament's webhook.
+func queueTournamentEvents(ctx context.Context, args Args, name, webhook string, events []messages.TournamentEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	encoded := make([]string, len(events))
+	for i, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		encoded[i] = string(b)
+	}
+
+	if err := appendWebhookEvents(ctx, args, name, webhook, encoded); err != nil {
+		return err
+	}
+
+	return addPending(ctx, args, webhooksKey, name)
+}
+
+// RunWebhooks posts the tournament events that are waiting to their webhooks, and returns how many
+// it posted. It is meant to be run often, such as every minute, so that the events are timely.
+func RunWebhooks(ctx context.Context, args Args) (int, error) {
+	names, err := getPending(ctx, args, webhooksKey)
+	if err != nil {
+		return 0, err
+	}
+
+	var posted int
+
+	for _, name := range names {
+		// The tournament stops waiting before its events are taken, so that events queued in the
+		// meantime make it wait again.
+		if err := removePending(ctx, args, webhooksKey, name); err != nil {
+			return posted, err
+		}
+
+		webhook, encoded, err := takeWebhookEvents(ctx, args, name)
+		if err != nil {
+			return posted, err
+		}
+
+		events := make([]messages.TournamentEvent, 0, len(encoded))
+		for _, e := range encoded {
+			var event messages.TournamentEvent
+			if err := json.Unmarshal([]byte(e), &event); err != nil {
+				log.Printf("Dropping an unreadable event of tournament %q: %v", name, err)
+				continue
+			}
+			events = append(events, event)
+		}
+
+		postTournamentEvents(ctx, args, webhook, events)
+		posted += len(events)
+	}
+
+	return posted, nil
+}
+
+// postTournamentEvents posts events to a webhook, one at a time.
+func postTournamentEvents(ctx context.Context, args Args, webhook string, events []messages.TournamentEvent) {
+	for _, e := range events {
+		if err := postTournamentEvent(ctx, args, webhook, e); err != nil {
+			log.Printf("Failed to post event %q of tournament %q to its webhook: %v", e.Event, e.Tournament, err)
+		}
+	}
+}
+
+func postTournamentEvent(ctx context.Context, args Args, webhook string, e messages.TournamentEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := args.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+
+	return nil
+}
+
+// checkWebhook returns an error for players if a webhook isn't an http or https URL on the public
+// internet.
+func checkWebhook(ctx context.Context, webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+		return errors.New("the webhook must be an http or https URL")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("the webhook's host %s can't be found", u.Hostname())
+	}
+
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return errors.New("the webhook must be on the public internet")
+		}
+	}
+
+	return nil
+}
+
+// checkWebhookDial refuses to connect a webhook to an address that isn't public. It runs after the
+// webhook's name is resolved, so it catches names that point to private addresses too.
+func checkWebhookDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("refusing to post to %s, which isn't a public address", host)
+	}
+
+	return nil
+}
+
+// nonPublicNets are the networks that aren't on the public internet, other than the loopback,
+// link-local, and multicast ones that net.IP knows about.
+var nonPublicNets = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// publicIP returns whether an IP address is on the public internet.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armsnyder/othelgo/pkg/messages"
+	"github.com/armsnyder/othelgo/pkg/server/memdb"
+	"github.com/armsnyder/othelgo/pkg/server/tournament"
+)
+
+func TestTournamentEvents(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cup := tournament.New("cup", "flame", 2, 1)
+
+	// Changes are made to a copy, as in updateTournament.
+	change := func(fn func(t *tournament.Tournament) error) []messages.TournamentEvent {
+		before := *cup
+		before.Pairings = append([]tournament.Pairing(nil), cup.Pairings...)
+		require.NoError(t, fn(cup))
+		return tournamentEvents(&before, cup, now)
+	}
+
+	events := change(func(t *tournament.Tournament) error { return t.Join("flame") })
+	assert.Empty(t, events)
+
+	events = change(func(t *tournament.Tournament) error { return t.Join("zinger") })
+	assert.Equal(t, []messages.TournamentEvent{{
+		Event:      messages.TournamentRoundStarted,
+		Tournament: "cup",
+		Round:      1,
+		Pairings:   []messages.TournamentPairing{{Host: "flame", Guest: "zinger"}},
+		Time:       millis(now),
+	}}, events)
+
+	events = change(func(t *tournament.Tournament) error { return t.RecordResult("flame", "zinger", "zinger") })
+	require.Len(t, events, 3)
+	assert.Equal(t, messages.TournamentBoardFinished, events[0].Event)
+	assert.Equal(t, &messages.TournamentPairing{Host: "flame", Guest: "zinger", Done: true, Winner: "zinger"}, events[0].Board)
+	assert.Equal(t, messages.TournamentStandingsUpdated, events[1].Event)
+	assert.Equal(t, "zinger", events[1].Standings[0].Nickname)
+	assert.Equal(t, messages.TournamentEnded, events[2].Event)
+	assert.Equal(t, events[1].Standings, events[2].Standings)
+}
+
+func TestPostTournamentEvent(t *testing.T) {
+	var got messages.TournamentEvent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer webhook.Close()
+
+	event := messages.TournamentEvent{Event: messages.TournamentRoundStarted, Tournament: "cup", Round: 2}
+	args := Args{HTTPClient: webhook.Client()}
+
+	require.NoError(t, postTournamentEvent(context.Background(), args, webhook.URL, event))
+	assert.Equal(t, event, got)
+}
+
+func TestRunWebhooks(t *testing.T) {
+	ctx := context.Background()
+
+	var got []messages.TournamentEvent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event messages.TournamentEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		got = append(got, event)
+	}))
+	defer webhook.Close()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo", HTTPClient: webhook.Client()}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	first := messages.TournamentEvent{Event: messages.TournamentRoundStarted, Tournament: "cup", Round: 1}
+	second := messages.TournamentEvent{Event: messages.TournamentEnded, Tournament: "cup"}
+	require.NoError(t, queueTournamentEvents(ctx, args, "cup", webhook.URL, []messages.TournamentEvent{first}))
+	require.NoError(t, queueTournamentEvents(ctx, args, "cup", webhook.URL, []messages.TournamentEvent{second}))
+
+	posted, err := RunWebhooks(ctx, args)
+	require.NoError(t, err)
+	assert.Equal(t, 2, posted)
+	assert.Equal(t, []messages.TournamentEvent{first, second}, got)
+}
+
+func TestQueuedTournamentEventsOutliveGames(t *testing.T) {
+	ctx := context.Background()
+
+	args := Args{DB: memdb.New(), TableName: "Othelgo"}
+	require.NoError(t, EnsureTable(ctx, args.DB, args.TableName))
+
+	event := messages.TournamentEvent{Event: messages.TournamentEnded, Tournament: "cup"}
+	require.NoError(t, queueTournamentEvents(ctx, args, "cup", "https://93.184.216.34/hook", []messages.TournamentEvent{event}))
+
+	output, err := args.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(args.TableName),
+		Key:       hostKey(webhookQueueKey("cup")),
+	})
+	require.NoError(t, err)
+
+	ttl, err := strconv.ParseInt(aws.StringValue(output.Item[attribTTL].N), 10, 64)
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Now().Add(itemTTL).Unix())
+}
+
+func TestPostTournamentEventRejected(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer webhook.Close()
+
+	err := postTournamentEvent(context.Background(), Args{HTTPClient: webhook.Client()}, webhook.URL, messages.TournamentEvent{})
+	assert.Error(t, err)
+}
+
+func TestPostTournamentEventPrivate(t *testing.T) {
+	var posted bool
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+	}))
+	defer webhook.Close()
+
+	// The default client refuses to post to the loopback address that the test server listens on.
+	err := postTournamentEvent(context.Background(), Args{}, webhook.URL, messages.TournamentEvent{})
+	assert.Error(t, err)
+	assert.False(t, posted)
+}
+
+func TestCheckWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, checkWebhook(ctx, "https://93.184.216.34/hook"))
+	assert.NoError(t, checkWebhook(ctx, "http://[2606:2800:220:1::]/hook"))
+
+	for _, webhook := range []string{
+		"ftp://93.184.216.34/hook",
+		"https:///hook",
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://172.20.1.1/hook",
+		"http://192.168.1.5/hook",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		assert.Error(t, checkWebhook(ctx, webhook), webhook)
+	}
+}
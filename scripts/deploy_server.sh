@@ -1,11 +1,37 @@
 #!/usr/bin/env bash
 
+REGION=us-west-2
+
 # Build
 mkdir -p bin
-GOOS=linux go build -o bin/server ./cmd/server || exit 1
+GOOS=linux go build -ldflags "-X github.com/armsnyder/othelgo/pkg/server.Version=$(git describe --tags --always)" -o bin/server ./cmd/server || exit 1
+GOOS=linux go build -o bin/jobs ./cmd/jobs || exit 1
 
 # Zip
-(cd bin && zip server.zip server) || exit 1
+(cd bin && zip server.zip server && zip jobs.zip jobs) || exit 1
 
 # Deploy
-AWS_PAGER="" aws lambda update-function-code --region us-west-2 --function-name othelgoServer --zip-file fileb://bin/server.zip --publish
+AWS_PAGER="" aws lambda update-function-code --region $REGION --function-name othelgoServer --zip-file fileb://bin/server.zip --publish || exit 1
+AWS_PAGER="" aws lambda update-function-code --region $REGION --function-name othelgoJobs --zip-file fileb://bin/jobs.zip --publish || exit 1
+
+# Schedule the jobs. Each EventBridge rule invokes othelgoJobs with the name of its job. Rules and
+# targets are replaced if they exist, and the permission is only added the first time.
+JOBS_ARN=$(aws lambda get-function --region $REGION --function-name othelgoJobs --query Configuration.FunctionArn --output text) || exit 1
+
+schedule() {
+  local job=$1 expression=$2 rule="othelgo-$1"
+
+  RULE_ARN=$(aws events put-rule --region $REGION --name "$rule" --schedule-expression "$expression" --query RuleArn --output text) || exit 1
+
+  AWS_PAGER="" aws events put-targets --region $REGION --rule "$rule" \
+    --targets "[{\"Id\":\"othelgoJobs\",\"Arn\":\"$JOBS_ARN\",\"Input\":\"{\\\"job\\\":\\\"$job\\\"}\"}]" || exit 1
+
+  AWS_PAGER="" aws lambda add-permission --region $REGION --function-name othelgoJobs \
+    --statement-id "$rule" --action lambda:InvokeFunction --principal events.amazonaws.com \
+    --source-arn "$RULE_ARN" >/dev/null 2>&1 || true
+}
+
+schedule botLadder "cron(0 4 * * ? *)"
+schedule heatMaps "cron(0 5 * * ? *)"
+schedule dataJobs "rate(1 minute)"
+schedule webhooks "rate(1 minute)"
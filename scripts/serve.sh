@@ -2,4 +2,4 @@
 
 docker-compose up -d || exit 1
 trap 'docker-compose down' EXIT
-go run ./cmd/localserver
+go run ./cmd/localserver -memory=false